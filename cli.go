@@ -0,0 +1,582 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"chirpy/internal/api"
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+	"chirpy/internal/migrate"
+	"chirpy/internal/tenant"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+//go:embed sql/schema/*.sql
+var schemaFS embed.FS
+
+const usage = `Usage: chirpy [command]
+
+Commands:
+  serve                    run the server (the default)
+  migrate [up|down|status] apply, roll back or list schema migrations
+  seed                     create demo users and chirps (PLATFORM=dev only)
+  create-admin --email E [--password P] [--tenant SLUG]
+                           create an admin user, or promote an existing one
+  tenant create|update --slug S --name N [--hostname H] [--config JSON]
+                           add or change a community for MULTI_TENANT to serve
+  tenant list              print the tenants
+  routes                   print the HTTP routes the current config registers
+  token mint --user-id ID [--ttl D] [--actor-id ID]
+                           print a signed access token
+  token inspect JWT        print a token's claims and whether it is valid
+  loadtest [--rps R --duration D --mix M ...]
+                           drive synthetic traffic at a running server
+  healthcheck [--url U | --db]
+                           exit non-zero unless the server (or database) is ready
+  replay [--url U --token T -v] (--rule ID | CAPTURE_ID...)
+                           resend captured requests and compare the responses
+`
+
+type command func(cfg *config.Config, args []string) error
+
+var commands = map[string]command{
+	"serve":        cmdServe,
+	"migrate":      cmdMigrate,
+	"seed":         cmdSeed,
+	"create-admin": cmdCreateAdmin,
+	"tenant":       cmdTenant,
+	"routes":       cmdRoutes,
+	"token":        cmdToken,
+	"loadtest":     cmdLoadtest,
+	"healthcheck":  cmdHealthcheck,
+	"replay":       cmdReplay,
+}
+
+func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		fmt.Print(usage)
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "chirpy: unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "chirpy: loading config:", err)
+		os.Exit(1)
+	}
+	if err := cmd(cfg, args); err != nil {
+		fmt.Fprintf(os.Stderr, "chirpy %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+func cmdServe(cfg *config.Config, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %q", args)
+	}
+	return serve(cfg)
+}
+
+func openDB(cfg *config.Config) (*sql.DB, error) {
+	if cfg.DBURL == "" {
+		return nil, errors.New("DB_URL is not set")
+	}
+	db, err := sql.Open("postgres", cfg.DBURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func cmdMigrate(cfg *config.Config, args []string) error {
+	direction := "up"
+	if len(args) > 0 {
+		direction = args[0]
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("unexpected arguments %q", args[1:])
+	}
+
+	schema, err := fs.Sub(schemaFS, "sql/schema")
+	if err != nil {
+		return err
+	}
+	migrations, err := migrate.Load(schema)
+	if err != nil {
+		return err
+	}
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch direction {
+	case "up":
+		ran, err := migrate.Up(ctx, db, migrations)
+		for _, m := range ran {
+			fmt.Println("applied", m.Name)
+		}
+		if err == nil && len(ran) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		return err
+	case "down":
+		m, ok, err := migrate.Down(ctx, db, migrations)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("no migrations to roll back")
+			return nil
+		}
+		fmt.Println("rolled back", m.Name)
+		return nil
+	case "status":
+		applied, err := migrate.Applied(ctx, db)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(os.Stdout, migrations, applied)
+		return nil
+	}
+	return fmt.Errorf("unknown direction %q (want up, down or status)", direction)
+}
+
+func printMigrationStatus(w io.Writer, migrations []migrate.Migration, applied map[int64]bool) {
+	for _, m := range migrations {
+		state := "pending"
+		if applied[m.Version] {
+			state = "applied"
+		}
+		fmt.Fprintf(w, "%-8s %s\n", state, m.Name)
+	}
+}
+
+// seedPassword is the password of every seeded user.
+const seedPassword = "password"
+
+var seedUsers = []struct {
+	handle string
+	chirps []string
+}{
+	{"alice", []string{"Hello, Chirpy!", "Just set up my profile. What should I chirp about?"}},
+	{"bob", []string{"Coffee first, code second.", "Anyone else think tabs are underrated?"}},
+	{"carol", []string{"Shipping a new feature today.", "Weekend plans: hiking and no laptops."}},
+}
+
+// cmdSeed fills a development database with a few users and chirps. Users
+// that already exist are left alone, so it is safe to run repeatedly.
+func cmdSeed(cfg *config.Config, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %q", args)
+	}
+	if cfg.Platform != "dev" {
+		return errors.New("seeding is only allowed when PLATFORM=dev")
+	}
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	hash, err := auth.HashPassword(seedPassword)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	q := database.New(db)
+	for _, u := range seedUsers {
+		email := u.handle + "@example.com"
+		user, err := q.CreateUser(ctx, database.CreateUserParams{
+			ID:             uuid.New(),
+			Email:          email,
+			HashedPassword: hash,
+			Handle:         sql.NullString{String: u.handle, Valid: true},
+		})
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			fmt.Println("skipped", email, "(already exists)")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, body := range u.chirps {
+			_, err := q.CreateChirp(ctx, database.CreateChirpParams{
+				ID:     uuid.New(),
+				Body:   body,
+				UserID: user.ID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		fmt.Printf("created %s with %d chirps\n", email, len(u.chirps))
+	}
+	fmt.Printf("seeded users sign in with the password %q\n", seedPassword)
+	return nil
+}
+
+// cmdCreateAdmin bootstraps an administrator. A new user needs a password;
+// promoting an existing user leaves their password as it is.
+func cmdCreateAdmin(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := flags.String("email", "", "the admin's email address")
+	password := flags.String("password", "", "password for a new user")
+	tenantSlug := flags.String("tenant", "", "make the admin in this tenant rather than the default community")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %q", flags.Args())
+	}
+	if !api.IsValidEmail(*email) {
+		return errors.New("--email must be a valid email address")
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	q := database.New(db)
+	if *tenantSlug != "" {
+		t, err := q.GetTenantBySlug(ctx, *tenantSlug)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no tenant %q", *tenantSlug)
+		}
+		if err != nil {
+			return err
+		}
+		pools, err := tenant.NewPools(db, cfg.DBURL)
+		if err != nil {
+			return err
+		}
+		defer pools.Close()
+		// The user is written through the tenant's own connection, so it
+		// lands in that tenant like a signup there would.
+		ctx = tenant.NewContext(ctx, tenant.Tenant{ID: t.ID, Slug: t.Slug})
+		q = database.New(pools)
+	}
+	user, err := q.GetUserByEmail(ctx, *email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if *password == "" {
+			return errors.New("--password is required to create a new user")
+		}
+		hash, err := auth.HashPassword(*password)
+		if err != nil {
+			return err
+		}
+		user, err = q.CreateUser(ctx, database.CreateUserParams{
+			ID:             uuid.New(),
+			Email:          *email,
+			HashedPassword: hash,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println("created user", user.ID)
+	case err != nil:
+		return err
+	case *password != "":
+		fmt.Println("user already exists; leaving the password unchanged")
+	}
+
+	if user.Role == api.RoleAdmin {
+		fmt.Println(*email, "is already an admin")
+		return nil
+	}
+	if _, err := q.SetUserRole(ctx, database.SetUserRoleParams{ID: user.ID, Role: api.RoleAdmin}); err != nil {
+		return err
+	}
+	fmt.Println(*email, "is now an admin")
+	return nil
+}
+
+// cmdTenant manages the tenants table. Running servers pick up changes
+// without a restart.
+func cmdTenant(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("want create, update or list")
+	}
+	sub, args := args[0], args[1:]
+	if sub != "create" && sub != "update" && sub != "list" {
+		return fmt.Errorf("unknown tenant command %q (want create, update or list)", sub)
+	}
+
+	flags := flag.NewFlagSet("tenant "+sub, flag.ContinueOnError)
+	slug := flags.String("slug", "", "the tenant's path under /t/, which can't change")
+	name := flags.String("name", "", "the community's name")
+	hostname := flags.String("hostname", "", "a hostname of its own, if it has one")
+	configJSON := flags.String("config", "{}", `overrides such as {"logo_url":"...","max_chirp_length":280}`)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %q", flags.Args())
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	q := database.New(db)
+
+	if sub == "list" {
+		tenants, err := q.ListTenants(ctx)
+		if err != nil {
+			return err
+		}
+		for _, t := range tenants {
+			fmt.Printf("%s  %-20s %-30s %s\n", t.ID, t.Slug, t.Hostname.String, t.Name)
+		}
+		return nil
+	}
+
+	if *slug == "" || *name == "" {
+		return errors.New("--slug and --name are required")
+	}
+	var overrides tenant.Config
+	if err := json.Unmarshal([]byte(*configJSON), &overrides); err != nil {
+		return fmt.Errorf("--config: %w", err)
+	}
+	raw, err := json.Marshal(overrides)
+	if err != nil {
+		return err
+	}
+	host := sql.NullString{String: strings.ToLower(*hostname), Valid: *hostname != ""}
+
+	var t database.Tenant
+	if sub == "create" {
+		t, err = q.CreateTenant(ctx, database.CreateTenantParams{
+			ID:       uuid.New(),
+			Slug:     *slug,
+			Hostname: host,
+			Name:     *name,
+			Config:   raw,
+		})
+	} else {
+		t, err = q.UpdateTenant(ctx, database.UpdateTenantParams{
+			Slug:     *slug,
+			Hostname: host,
+			Name:     *name,
+			Config:   raw,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no tenant %q", *slug)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(sub+"d tenant", t.Slug, t.ID)
+	return nil
+}
+
+// cmdRoutes prints the routes serve would register with the current
+// config. It doesn't connect to the database.
+func cmdRoutes(cfg *config.Config, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %q", args)
+	}
+	db, err := sql.Open("postgres", cfg.DBURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	srv := api.NewServer(cfg, api.Deps{Store: api.NewSQLStore(db, nil, nil)})
+	patterns := api.NewRouter(srv).Patterns()
+	sort.Slice(patterns, func(i, j int) bool {
+		return routePath(patterns[i]) < routePath(patterns[j]) ||
+			routePath(patterns[i]) == routePath(patterns[j]) && patterns[i] < patterns[j]
+	})
+	for _, p := range patterns {
+		method, path, ok := strings.Cut(p, " ")
+		if !ok {
+			method, path = "*", p
+		}
+		fmt.Printf("%-7s %s\n", method, path)
+	}
+	return nil
+}
+
+// cmdToken mints and inspects access tokens with JWT_SECRET, to reproduce
+// auth problems without writing throwaway programs.
+func cmdToken(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New("want mint or inspect")
+	}
+	if cfg.JWTSecret == "" {
+		return errors.New("JWT_SECRET is not set")
+	}
+	switch args[0] {
+	case "mint":
+		return tokenMint(cfg, args[1:])
+	case "inspect":
+		return tokenInspect(cfg, args[1:])
+	}
+	return fmt.Errorf("unknown token command %q (want mint or inspect)", args[0])
+}
+
+func tokenMint(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("token mint", flag.ContinueOnError)
+	userIDFlag := flags.String("user-id", "", "the token's subject")
+	ttl := flags.Duration("ttl", api.AccessTokenTTL, "how long the token is valid")
+	actorIDFlag := flags.String("actor-id", "", "mint an impersonation token on behalf of this admin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %q", flags.Args())
+	}
+	userID, err := uuid.Parse(*userIDFlag)
+	if err != nil {
+		return errors.New("--user-id must be a UUID")
+	}
+
+	var token string
+	if *actorIDFlag == "" {
+		token, err = auth.MakeJWT(userID, cfg.JWTSecret, *ttl)
+	} else {
+		actorID, parseErr := uuid.Parse(*actorIDFlag)
+		if parseErr != nil {
+			return errors.New("--actor-id must be a UUID")
+		}
+		token, err = auth.MakeImpersonationJWT(userID, actorID, cfg.JWTSecret, *ttl)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
+
+// tokenInspect prints the claims even when the token doesn't validate,
+// since that is usually when you want to see them. It fails if the token is
+// invalid.
+func tokenInspect(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("want exactly one token")
+	}
+	token := strings.TrimPrefix(strings.TrimSpace(args[0]), "Bearer ")
+
+	claims, err := auth.DecodeJWTClaims(token)
+	if err != nil {
+		return fmt.Errorf("malformed token: %w", err)
+	}
+	out, err := json.MarshalIndent(claims, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	for _, name := range []string{"iat", "nbf", "exp"} {
+		if v, ok := claims[name].(float64); ok {
+			fmt.Printf("%s: %s\n", name, time.Unix(int64(v), 0).UTC().Format(time.RFC3339))
+		}
+	}
+
+	userID, actorID, err := auth.ValidateJWTWithActor(token, cfg.JWTSecret, cfg.JWTLeeway)
+	if err != nil {
+		return fmt.Errorf("invalid: %w", err)
+	}
+	if actorID != uuid.Nil {
+		fmt.Printf("valid: user %s, impersonated by %s\n", userID, actorID)
+	} else {
+		fmt.Printf("valid: user %s\n", userID)
+	}
+	return nil
+}
+
+// healthcheckTimeout bounds the whole check, so a hung server fails the
+// probe rather than stalling it.
+const healthcheckTimeout = 5 * time.Second
+
+// cmdHealthcheck is meant for container HEALTHCHECK and orchestrator
+// probes, so images don't need curl. By default it asks the local server's
+// /readyz; --db pings the database directly instead.
+func cmdHealthcheck(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	url := flags.String("url", "http://127.0.0.1:"+cfg.Port+"/readyz", "readiness endpoint to check")
+	dbOnly := flags.Bool("db", false, "ping the database instead of the server")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %q", flags.Args())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthcheckTimeout)
+	defer cancel()
+
+	if *dbOnly {
+		if cfg.DBURL == "" {
+			return errors.New("DB_URL is not set")
+		}
+		db, err := sql.Open("postgres", cfg.DBURL)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.PingContext(ctx)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", *url, resp.Status)
+	}
+	return nil
+}
+
+// routePath drops the method from a ServeMux pattern.
+func routePath(pattern string) string {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	return pattern
+}
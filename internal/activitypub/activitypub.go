@@ -0,0 +1,187 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ContentType is the media type ActivityPub servers exchange.
+const ContentType = "application/activity+json"
+
+const (
+	contextActivityStreams = "https://www.w3.org/ns/activitystreams"
+	contextSecurity        = "https://w3id.org/security/v1"
+
+	// Public is the special collection that marks an object as public.
+	Public = contextActivityStreams + "#Public"
+)
+
+// maxBodySize caps documents read from remote servers.
+const maxBodySize = 1 << 20
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type Actor struct {
+	Context           []string   `json:"@context,omitempty"`
+	ID                string     `json:"id"`
+	Type              string     `json:"type"`
+	PreferredUsername string     `json:"preferredUsername"`
+	Name              string     `json:"name,omitempty"`
+	URL               string     `json:"url,omitempty"`
+	Inbox             string     `json:"inbox"`
+	Outbox            string     `json:"outbox,omitempty"`
+	Followers         string     `json:"followers,omitempty"`
+	PublicKey         PublicKey  `json:"publicKey"`
+	Endpoints         *Endpoints `json:"endpoints,omitempty"`
+}
+
+// PreferredInbox returns the shared inbox when the server offers one, so a
+// chirp is delivered once per server instead of once per follower.
+func (a *Actor) PreferredInbox() string {
+	if a.Endpoints != nil && a.Endpoints.SharedInbox != "" {
+		return a.Endpoints.SharedInbox
+	}
+	return a.Inbox
+}
+
+type Note struct {
+	Context      []string `json:"@context,omitempty"`
+	ID           string   `json:"id"`
+	Type         string   `json:"type"`
+	AttributedTo string   `json:"attributedTo"`
+	Content      string   `json:"content"`
+	Published    string   `json:"published"`
+	URL          string   `json:"url,omitempty"`
+	To           []string `json:"to"`
+	Cc           []string `json:"cc,omitempty"`
+}
+
+// Activity is an outgoing or incoming activity. Object is kept raw because
+// it may be either an ID string or an embedded object.
+type Activity struct {
+	Context   []string        `json:"@context,omitempty"`
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Actor     string          `json:"actor"`
+	Object    json.RawMessage `json:"object"`
+	Published string          `json:"published,omitempty"`
+	To        []string        `json:"to,omitempty"`
+	Cc        []string        `json:"cc,omitempty"`
+}
+
+type OrderedCollection struct {
+	Context      []string      `json:"@context,omitempty"`
+	ID           string        `json:"id"`
+	Type         string        `json:"type"`
+	TotalItems   int           `json:"totalItems"`
+	OrderedItems []interface{} `json:"orderedItems,omitempty"`
+}
+
+// DefaultContext is the JSON-LD context for documents served by Chirpy.
+func DefaultContext() []string {
+	return []string{contextActivityStreams, contextSecurity}
+}
+
+// ObjectID returns the ID of an activity's object whether it was sent as a
+// bare string or an embedded object.
+func (a *Activity) ObjectID() string {
+	var id string
+	if err := json.Unmarshal(a.Object, &id); err == nil {
+		return id
+	}
+	var obj struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(a.Object, &obj); err == nil {
+		return obj.ID
+	}
+	return ""
+}
+
+// ObjectType returns the type of an embedded object, or "" for references.
+func (a *Activity) ObjectType() string {
+	var obj struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(a.Object, &obj); err != nil {
+		return ""
+	}
+	return obj.Type
+}
+
+// FetchActor dereferences a remote actor document.
+func FetchActor(ctx context.Context, client *http.Client, uri string) (*Actor, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ContentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching actor %s: status %d", uri, resp.StatusCode)
+	}
+
+	var actor Actor
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(&actor); err != nil {
+		return nil, err
+	}
+	if actor.ID == "" || actor.Inbox == "" || actor.PublicKey.PublicKeyPem == "" {
+		return nil, errors.New("actor document is incomplete")
+	}
+	return &actor, nil
+}
+
+// Deliver POSTs a signed activity to a remote inbox.
+func Deliver(ctx context.Context, client *http.Client, inbox string, activity interface{}, keyID string, key *rsa.PrivateKey) error {
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("Accept", ContentType)
+
+	if err := SignRequest(req, body, keyID, key); err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodySize))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("delivering to %s: status %d", inbox, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxClockSkew bounds how old (or far in the future) a signed request's Date
+// header may be.
+const maxClockSkew = time.Hour
+
+var signedHeaders = []string{"(request-target)", "host", "date", "digest"}
+
+// GenerateKey creates an RSA key pair for an actor, PEM encoded.
+func GenerateKey() (privatePEM, publicPEM string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: pub,
+	}))
+	return privatePEM, publicPEM, nil
+}
+
+func ParsePrivateKey(privatePEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, errors.New("invalid private key PEM")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+func ParsePublicKey(publicPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil {
+		return nil, errors.New("invalid public key PEM")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		// Some servers still publish PKCS#1 keys.
+		if rsaKey, pkcs1Err := x509.ParsePKCS1PublicKey(block.Bytes); pkcs1Err == nil {
+			return rsaKey, nil
+		}
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not RSA")
+	}
+	return rsaKey, nil
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func signingString(r *http.Request, headers []string) (string, error) {
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		switch h {
+		case "(request-target)":
+			lines = append(lines, fmt.Sprintf("(request-target): %s %s", strings.ToLower(r.Method), r.URL.RequestURI()))
+		case "host":
+			host := r.Host
+			if host == "" {
+				host = r.URL.Host
+			}
+			lines = append(lines, "host: "+host)
+		default:
+			v := r.Header.Get(h)
+			if v == "" {
+				return "", fmt.Errorf("signed header %q missing", h)
+			}
+			lines = append(lines, h+": "+v)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// SignRequest adds Date, Digest and Signature headers to r using the
+// draft-cavage HTTP Signatures scheme Mastodon expects.
+func SignRequest(r *http.Request, body []byte, keyID string, key *rsa.PrivateKey) error {
+	r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	r.Header.Set("Digest", digest(body))
+	if r.Host == "" {
+		r.Host = r.URL.Host
+	}
+
+	toSign, err := signingString(r, signedHeaders)
+	if err != nil {
+		return err
+	}
+	hashed := sha256.Sum256([]byte(toSign))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set("Signature", fmt.Sprintf(
+		`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(signedHeaders, " "), base64.StdEncoding.EncodeToString(sig),
+	))
+	return nil
+}
+
+type signatureParams struct {
+	keyID     string
+	headers   []string
+	signature []byte
+}
+
+func parseSignature(header string) (signatureParams, error) {
+	var p signatureParams
+	if header == "" {
+		return p, errors.New("signature header missing")
+	}
+
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		v = strings.Trim(v, `"`)
+		switch k {
+		case "keyId":
+			p.keyID = v
+		case "headers":
+			p.headers = strings.Fields(v)
+		case "signature":
+			sig, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return p, errors.New("signature is not valid base64")
+			}
+			p.signature = sig
+		}
+	}
+
+	if p.keyID == "" || len(p.signature) == 0 {
+		return p, errors.New("signature header incomplete")
+	}
+	if len(p.headers) == 0 {
+		p.headers = []string{"date"}
+	}
+	return p, nil
+}
+
+// SignatureKeyID returns the keyId a request claims to be signed with, so
+// the caller can fetch the matching public key.
+func SignatureKeyID(r *http.Request) (string, error) {
+	p, err := parseSignature(r.Header.Get("Signature"))
+	if err != nil {
+		return "", err
+	}
+	return p.keyID, nil
+}
+
+// VerifyRequest checks r's signature against key. The signature must cover
+// the request target, host, date and digest, the digest must match body and
+// the date must be recent.
+func VerifyRequest(r *http.Request, body []byte, key *rsa.PublicKey) error {
+	p, err := parseSignature(r.Header.Get("Signature"))
+	if err != nil {
+		return err
+	}
+
+	covered := make(map[string]bool, len(p.headers))
+	for _, h := range p.headers {
+		covered[strings.ToLower(h)] = true
+	}
+	for _, h := range signedHeaders {
+		if !covered[h] {
+			return fmt.Errorf("signature does not cover %q", h)
+		}
+	}
+
+	if r.Header.Get("Digest") != digest(body) {
+		return errors.New("digest does not match body")
+	}
+
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return errors.New("date header invalid")
+	}
+	if skew := time.Since(date); skew > maxClockSkew || skew < -maxClockSkew {
+		return errors.New("date header outside allowed window")
+	}
+
+	toVerify, err := signingString(r, p.headers)
+	if err != nil {
+		return err
+	}
+	hashed := sha256.Sum256([]byte(toVerify))
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], p.signature)
+}
@@ -0,0 +1,96 @@
+package activitypub
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newSignedRequest(t *testing.T, body []byte, privatePEM string) *http.Request {
+	t.Helper()
+
+	key, err := ParsePrivateKey(privatePEM)
+	if err != nil {
+		t.Fatalf("ParsePrivateKey failed: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://chirpy.example/ap/users/abc/inbox", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest failed: %v", err)
+	}
+	if err := SignRequest(req, body, "https://remote.example/users/bob#main-key", key); err != nil {
+		t.Fatalf("SignRequest failed: %v", err)
+	}
+
+	// Rebuild it as the server would see it.
+	incoming := httptest.NewRequest(http.MethodPost, "/ap/users/abc/inbox", bytes.NewReader(body))
+	incoming.Host = "chirpy.example"
+	incoming.Header = req.Header.Clone()
+	return incoming
+}
+
+func TestSignAndVerifyRequest(t *testing.T) {
+	privatePEM, publicPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	pub, err := ParsePublicKey(publicPEM)
+	if err != nil {
+		t.Fatalf("ParsePublicKey failed: %v", err)
+	}
+
+	body := []byte(`{"type":"Follow"}`)
+	req := newSignedRequest(t, body, privatePEM)
+
+	keyID, err := SignatureKeyID(req)
+	if err != nil || keyID != "https://remote.example/users/bob#main-key" {
+		t.Fatalf("unexpected keyId %q (err %v)", keyID, err)
+	}
+	if err := VerifyRequest(req, body, pub); err != nil {
+		t.Fatalf("VerifyRequest failed: %v", err)
+	}
+}
+
+func TestVerifyRequest_Rejects(t *testing.T) {
+	privatePEM, publicPEM, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	pub, _ := ParsePublicKey(publicPEM)
+	_, otherPEM, _ := GenerateKey()
+	otherPub, _ := ParsePublicKey(otherPEM)
+
+	body := []byte(`{"type":"Follow"}`)
+
+	t.Run("tampered body", func(t *testing.T) {
+		req := newSignedRequest(t, body, privatePEM)
+		if err := VerifyRequest(req, []byte(`{"type":"Undo"}`), pub); err == nil {
+			t.Fatalf("expected digest mismatch")
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		req := newSignedRequest(t, body, privatePEM)
+		if err := VerifyRequest(req, body, otherPub); err == nil {
+			t.Fatalf("expected signature mismatch")
+		}
+	})
+
+	t.Run("stale date", func(t *testing.T) {
+		req := newSignedRequest(t, body, privatePEM)
+		req.Header.Set("Date", time.Now().Add(-2*time.Hour).UTC().Format(http.TimeFormat))
+		if err := VerifyRequest(req, body, pub); err == nil {
+			t.Fatalf("expected stale date to be rejected")
+		}
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		req := newSignedRequest(t, body, privatePEM)
+		req.Header.Del("Signature")
+		if err := VerifyRequest(req, body, pub); err == nil {
+			t.Fatalf("expected missing signature to be rejected")
+		}
+	})
+}
@@ -0,0 +1,125 @@
+// Package analytics records product events, such as signups and new chirps,
+// for growth analysis. Handlers hand events to a Recorder; a Buffer batches
+// them in memory and writes them to a pluggable Sink in the background, so
+// recording never waits on the sink.
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event names.
+const (
+	Signup       = "signup"
+	Login        = "login"
+	ChirpCreated = "chirp_created"
+)
+
+// Event is something a user did.
+type Event struct {
+	Name string `json:"name"`
+	// UserID is uuid.Nil for anonymous events.
+	UserID     uuid.UUID         `json:"user_id"`
+	Time       time.Time         `json:"time"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// Recorder takes events from handlers. Record must not block.
+type Recorder interface {
+	Record(e Event)
+}
+
+// Discard is a Recorder that drops every event, for when analytics are off.
+type Discard struct{}
+
+func (Discard) Record(Event) {}
+
+// Sink stores batches of events.
+type Sink interface {
+	Write(ctx context.Context, events []Event) error
+}
+
+const (
+	// maxBatch is the most events a Buffer hands its sink at once.
+	maxBatch = 500
+	// flushTimeout bounds a single write to the sink.
+	flushTimeout = 30 * time.Second
+)
+
+// Buffer is a Recorder that queues up to size events and writes them to a
+// Sink from Run. Events recorded while the queue is full are dropped and
+// counted rather than slowing down requests.
+type Buffer struct {
+	sink    Sink
+	events  chan Event
+	dropped atomic.Int64
+}
+
+func NewBuffer(sink Sink, size int) *Buffer {
+	return &Buffer{sink: sink, events: make(chan Event, size)}
+}
+
+func (b *Buffer) Record(e Event) {
+	select {
+	case b.events <- e:
+	default:
+		b.dropped.Add(1)
+	}
+}
+
+// Dropped is how many events were lost to a full queue.
+func (b *Buffer) Dropped() int64 {
+	return b.dropped.Load()
+}
+
+// Run writes queued events to the sink every interval, or sooner once
+// maxBatch are waiting. When ctx is done it writes what is left and returns.
+func (b *Buffer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var batch []Event
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case e := <-b.events:
+					batch = append(batch, e)
+					if len(batch) == maxBatch {
+						b.flush(context.WithoutCancel(ctx), batch)
+						batch = nil
+					}
+				default:
+					b.flush(context.WithoutCancel(ctx), batch)
+					return
+				}
+			}
+		case e := <-b.events:
+			batch = append(batch, e)
+			if len(batch) == maxBatch {
+				b.flush(ctx, batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			b.flush(ctx, batch)
+			batch = nil
+		}
+	}
+}
+
+func (b *Buffer) flush(ctx context.Context, batch []Event) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, flushTimeout)
+	defer cancel()
+	if err := b.sink.Write(ctx, batch); err != nil {
+		fmt.Printf("Error writing %d analytics events: %v\n", len(batch), err)
+	}
+}
@@ -0,0 +1,183 @@
+package analytics
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// memorySink keeps every batch it is given.
+type memorySink struct {
+	mu      sync.Mutex
+	batches [][]Event
+}
+
+func (m *memorySink) Write(ctx context.Context, events []Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches = append(m.batches, append([]Event(nil), events...))
+	return nil
+}
+
+func (m *memorySink) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, b := range m.batches {
+		n += len(b)
+	}
+	return n
+}
+
+func TestBuffer_FlushesOnIntervalAndShutdown(t *testing.T) {
+	sink := &memorySink{}
+	buf := NewBuffer(sink, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		buf.Run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	buf.Record(Event{Name: Signup})
+	deadline := time.Now().Add(5 * time.Second)
+	for sink.count() < 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("event was never flushed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Whatever is queued at shutdown is still written.
+	buf.Record(Event{Name: Login})
+	buf.Record(Event{Name: ChirpCreated})
+	cancel()
+	<-done
+	if n := sink.count(); n != 3 {
+		t.Errorf("expected 3 events written, got %d", n)
+	}
+}
+
+func TestBuffer_DropsWhenFull(t *testing.T) {
+	buf := NewBuffer(&memorySink{}, 2)
+	for range 5 {
+		buf.Record(Event{Name: Login})
+	}
+	if got := buf.Dropped(); got != 3 {
+		t.Errorf("expected 3 dropped events, got %d", got)
+	}
+}
+
+func TestJSONL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink := &JSONL{Path: path}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, name := range []string{Signup, Login} {
+		if err := sink.Write(context.Background(), []Event{{Name: name, Time: now}}); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		names = append(names, e.Name)
+	}
+	if len(names) != 2 || names[0] != Signup || names[1] != Login {
+		t.Errorf("expected signup then login appended, got %v", names)
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	var got []Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding batch: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	sink := &HTTP{URL: srv.URL, Client: srv.Client()}
+	err := sink.Write(context.Background(), []Event{{Name: ChirpCreated, Properties: map[string]string{"batch": "true"}}})
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != ChirpCreated || got[0].Properties["batch"] != "true" {
+		t.Errorf("collector received %+v", got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	sink = &HTTP{URL: failing.URL, Client: failing.Client()}
+	if err := sink.Write(context.Background(), []Event{{Name: Login}}); err == nil {
+		t.Error("expected an error from a failing collector")
+	}
+}
+
+type storeFunc func(ctx context.Context, arg database.RecordAnalyticsEventParams) error
+
+func (f storeFunc) RecordAnalyticsEvent(ctx context.Context, arg database.RecordAnalyticsEventParams) error {
+	return f(ctx, arg)
+}
+
+func TestPostgres(t *testing.T) {
+	var rows []database.RecordAnalyticsEventParams
+	sink := &Postgres{Store: storeFunc(func(ctx context.Context, arg database.RecordAnalyticsEventParams) error {
+		rows = append(rows, arg)
+		return nil
+	})}
+	userID := uuid.New()
+	err := sink.Write(context.Background(), []Event{
+		{Name: Signup, UserID: userID, Properties: map[string]string{"handle": "true"}},
+		{Name: Login},
+	})
+	if err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	if !rows[0].UserID.Valid || rows[0].UserID.UUID != userID || string(rows[0].Properties) != `{"handle":"true"}` {
+		t.Errorf("unexpected first row: %+v", rows[0])
+	}
+	if rows[1].UserID.Valid || string(rows[1].Properties) != "{}" {
+		t.Errorf("anonymous event should have a NULL user and empty properties: %+v", rows[1])
+	}
+}
+
+func TestNew(t *testing.T) {
+	if sink, err := New(Config{}, nil); sink != nil || err != nil {
+		t.Errorf("expected analytics off by default, got %v, %v", sink, err)
+	}
+	for _, cfg := range []Config{{Sink: "jsonl"}, {Sink: "http"}, {Sink: "kafka"}} {
+		if _, err := New(cfg, nil); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// Config selects and configures a Sink.
+type Config struct {
+	// Sink is "postgres", "jsonl" or "http". Analytics are off when it is
+	// empty.
+	Sink string `json:"sink"`
+	// File is the JSON Lines file the jsonl sink appends to.
+	File string `json:"file"`
+	// URL is where the http sink posts batches.
+	URL string `json:"url"`
+}
+
+// Store is the query the postgres sink runs.
+type Store interface {
+	RecordAnalyticsEvent(ctx context.Context, arg database.RecordAnalyticsEventParams) error
+}
+
+// New builds the Sink described by cfg, or returns nil when analytics are
+// off. store is only used by the postgres sink.
+func New(cfg Config, store Store) (Sink, error) {
+	switch cfg.Sink {
+	case "":
+		return nil, nil
+	case "postgres":
+		return &Postgres{Store: store}, nil
+	case "jsonl":
+		if cfg.File == "" {
+			return nil, errors.New("analytics: the jsonl sink needs a file")
+		}
+		return &JSONL{Path: cfg.File}, nil
+	case "http":
+		if cfg.URL == "" {
+			return nil, errors.New("analytics: the http sink needs a URL")
+		}
+		return &HTTP{URL: cfg.URL, Client: &http.Client{Timeout: 15 * time.Second}}, nil
+	default:
+		return nil, fmt.Errorf("analytics: unknown sink %q", cfg.Sink)
+	}
+}
+
+// Postgres stores events in the analytics_events table.
+type Postgres struct {
+	Store Store
+}
+
+func (p *Postgres) Write(ctx context.Context, events []Event) error {
+	for _, e := range events {
+		props := []byte("{}")
+		if len(e.Properties) > 0 {
+			var err error
+			if props, err = json.Marshal(e.Properties); err != nil {
+				return err
+			}
+		}
+		err := p.Store.RecordAnalyticsEvent(ctx, database.RecordAnalyticsEventParams{
+			ID:         uuid.New(),
+			Name:       e.Name,
+			UserID:     uuid.NullUUID{UUID: e.UserID, Valid: e.UserID != uuid.Nil},
+			Properties: props,
+			OccurredAt: e.Time.UTC(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSONL appends events to a file, one JSON object per line. The file is
+// reopened for every batch, so it can be rotated by renaming it.
+type JSONL struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (j *JSONL) Write(ctx context.Context, events []Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f, err := os.OpenFile(j.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// HTTP posts each batch to a collector as a JSON array.
+type HTTP struct {
+	URL    string
+	Client *http.Client
+}
+
+func (h *HTTP) Write(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("analytics: collector responded %s", resp.Status)
+	}
+	return nil
+}
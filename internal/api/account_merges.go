@@ -0,0 +1,354 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// accountMergeTTL is how long the owners of both accounts have to confirm
+// a merge an admin proposed.
+const accountMergeTTL = 7 * 24 * time.Hour
+
+type accountMergeRequest struct {
+	Into   uuid.UUID `json:"into"`
+	Reason string    `json:"reason"`
+}
+
+type accountMergeResponse struct {
+	ID                uuid.UUID  `json:"id"`
+	SourceID          uuid.UUID  `json:"source_id"`
+	TargetID          uuid.UUID  `json:"target_id"`
+	CreatedAt         Timestamp  `json:"created_at"`
+	ExpiresAt         Timestamp  `json:"expires_at"`
+	SourceConfirmedAt *Timestamp `json:"source_confirmed_at"`
+	TargetConfirmedAt *Timestamp `json:"target_confirmed_at"`
+	CompletedAt       *Timestamp `json:"completed_at"`
+}
+
+func newAccountMergeResponse(m database.AccountMerge) accountMergeResponse {
+	return accountMergeResponse{
+		ID:                m.ID,
+		SourceID:          m.SourceID,
+		TargetID:          m.TargetID,
+		CreatedAt:         Timestamp{m.CreatedAt},
+		ExpiresAt:         Timestamp{m.ExpiresAt},
+		SourceConfirmedAt: nullTimestamp(m.SourceConfirmedAt),
+		TargetConfirmedAt: nullTimestamp(m.TargetConfirmedAt),
+		CompletedAt:       nullTimestamp(m.CompletedAt),
+	}
+}
+
+// handlerAdminUserMerge proposes folding the user in the path into
+// another. Nothing moves until both account owners confirm through
+// handlerAccountMergeConfirm.
+func (s *Server) handlerAdminUserMerge(w http.ResponseWriter, r *http.Request) {
+	sourceID, ok := pathUUID(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	var req accountMergeRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	if req.Into == uuid.Nil {
+		jsonResponse(w, http.StatusBadRequest, "The account to merge into is required")
+		return
+	}
+	if req.Into == sourceID {
+		jsonResponse(w, http.StatusBadRequest, "An account can't be merged into itself")
+		return
+	}
+
+	ctx := r.Context()
+	for _, id := range []uuid.UUID{sourceID, req.Into} {
+		user, err := s.db.GetUserByID(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			jsonResponse(w, http.StatusNotFound, "User was not found.")
+			return
+		}
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		if user.BannedAt.Valid {
+			jsonResponse(w, http.StatusConflict, "Banned accounts can't be merged")
+			return
+		}
+		if id == sourceID && user.Role == RoleAdmin {
+			jsonResponse(w, http.StatusForbidden, "Admin accounts can't be merged away")
+			return
+		}
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	admin := adminFromContext(ctx)
+	now := s.clock.Now().UTC()
+	merge, err := tx.CreateAccountMerge(ctx, database.CreateAccountMergeParams{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(accountMergeTTL),
+		RequestedBy: uuid.NullUUID{UUID: admin.ID, Valid: true},
+		SourceID:    sourceID,
+		TargetID:    req.Into,
+	})
+	if err != nil {
+		fmt.Println("Error creating account merge:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	err = recordAudit(ctx, tx, admin.ID, "user.merge_request", sourceID, map[string]interface{}{
+		"merge_id": merge.ID,
+		"into":     req.Into,
+		"reason":   req.Reason,
+	})
+	if err != nil {
+		fmt.Println("Error recording audit entry:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, newAccountMergeResponse(merge))
+}
+
+// handlerAccountMergesList lists the merges waiting on the signed-in
+// user, as either account.
+func (s *Server) handlerAccountMergesList(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Couldn't validate token")
+		return
+	}
+
+	merges, err := s.db.ListPendingAccountMerges(r.Context(), database.ListPendingAccountMergesParams{
+		SourceID:  userID,
+		ExpiresAt: s.clock.Now().UTC(),
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	resp := make([]accountMergeResponse, 0, len(merges))
+	for _, m := range merges {
+		resp = append(resp, newAccountMergeResponse(m))
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+// handlerAccountMergeConfirm records the signed-in user's consent to a
+// merge. The second confirmation carries it out in the same transaction.
+func (s *Server) handlerAccountMergeConfirm(w http.ResponseWriter, r *http.Request) {
+	userID, actorID, err := s.authenticateWithActor(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Couldn't validate token")
+		return
+	}
+	if actorID != uuid.Nil {
+		// The admin proposed the merge, so they can't also consent to it.
+		jsonResponse(w, http.StatusForbidden, "Impersonation tokens can't confirm a merge")
+		return
+	}
+	mergeID, ok := pathUUID(w, r, "mergeID")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	merge, err := tx.GetAccountMergeForUpdate(ctx, mergeID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && userID != merge.SourceID && userID != merge.TargetID) {
+		jsonResponse(w, http.StatusNotFound, "Merge was not found.")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	now := s.clock.Now().UTC()
+	if merge.CompletedAt.Valid {
+		jsonResponse(w, http.StatusConflict, "This merge has already been carried out")
+		return
+	}
+	if !merge.ExpiresAt.After(now) {
+		jsonResponse(w, http.StatusGone, "This merge request has expired")
+		return
+	}
+
+	confirmed := sql.NullTime{Time: now, Valid: true}
+	params := database.ConfirmAccountMergeParams{
+		ID:                merge.ID,
+		SourceConfirmedAt: merge.SourceConfirmedAt,
+		TargetConfirmedAt: merge.TargetConfirmedAt,
+	}
+	if userID == merge.SourceID && !params.SourceConfirmedAt.Valid {
+		params.SourceConfirmedAt = confirmed
+	}
+	if userID == merge.TargetID && !params.TargetConfirmedAt.Valid {
+		params.TargetConfirmedAt = confirmed
+	}
+	merge, err = tx.ConfirmAccountMerge(ctx, params)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	completed := merge.SourceConfirmedAt.Valid && merge.TargetConfirmedAt.Valid
+	if completed {
+		merge, err = s.mergeAccounts(ctx, tx, merge, userID)
+		if err != nil {
+			fmt.Println("Error merging accounts:", err)
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if completed {
+		// Chirps changed authors.
+		s.responseCache.invalidate()
+	}
+
+	jsonResponse(w, http.StatusOK, newAccountMergeResponse(merge))
+}
+
+// accountMergeCounts is how much of the source account moved, as
+// recorded in the audit log.
+type accountMergeCounts struct {
+	Chirps               int64 `json:"chirps"`
+	Media                int64 `json:"media"`
+	Reactions            int64 `json:"reactions"`
+	RemoteFollowers      int64 `json:"remote_followers"`
+	Lists                int64 `json:"lists"`
+	ListMemberships      int64 `json:"list_memberships"`
+	Communities          int64 `json:"communities"`
+	CommunityMemberships int64 `json:"community_memberships"`
+}
+
+// mergeAccounts moves the source account's chirps, media, reactions,
+// followers, lists, communities and community memberships to the target,
+// then tombstones it. Where both accounts had the same reaction, follower
+// or list membership the target's is kept; where both were in the same
+// community the target keeps the higher of the two roles. The old handle
+// redirects to the target for handleRedirectPeriod, like a handle change.
+//
+// Access tokens aren't stored, so there are no sessions to move: the
+// tombstone is banned, which stops it signing in, and its outstanding
+// tokens run out within AccessTokenTTL.
+func (s *Server) mergeAccounts(ctx context.Context, tx Tx, merge database.AccountMerge, actorID uuid.UUID) (database.AccountMerge, error) {
+	source, err := tx.GetUserByID(ctx, merge.SourceID)
+	if err != nil {
+		return merge, err
+	}
+
+	var counts accountMergeCounts
+	from, to := merge.SourceID, merge.TargetID
+	if counts.Chirps, err = tx.MoveUserChirps(ctx, database.MoveUserChirpsParams{SourceID: from, TargetID: to}); err != nil {
+		return merge, err
+	}
+	if counts.Media, err = tx.MoveUserMedia(ctx, database.MoveUserMediaParams{SourceID: from, TargetID: to}); err != nil {
+		return merge, err
+	}
+	if counts.Reactions, err = tx.MoveUserReactions(ctx, database.MoveUserReactionsParams{SourceID: from, TargetID: to}); err != nil {
+		return merge, err
+	}
+	if counts.RemoteFollowers, err = tx.MoveUserRemoteFollowers(ctx, database.MoveUserRemoteFollowersParams{SourceID: from, TargetID: to}); err != nil {
+		return merge, err
+	}
+	if counts.Lists, err = tx.MoveUserLists(ctx, database.MoveUserListsParams{SourceID: from, TargetID: to}); err != nil {
+		return merge, err
+	}
+	if counts.ListMemberships, err = tx.MoveUserListMemberships(ctx, database.MoveUserListMembershipsParams{SourceID: from, TargetID: to}); err != nil {
+		return merge, err
+	}
+	if counts.Communities, err = tx.MoveUserCommunities(ctx, database.MoveUserCommunitiesParams{
+		SourceID: uuid.NullUUID{UUID: from, Valid: true},
+		TargetID: uuid.NullUUID{UUID: to, Valid: true},
+	}); err != nil {
+		return merge, err
+	}
+	// Roles first, while the source's memberships are still there to
+	// compare against.
+	if err := tx.MergeUserCommunityRoles(ctx, database.MergeUserCommunityRolesParams{SourceID: from, TargetID: to}); err != nil {
+		return merge, err
+	}
+	if counts.CommunityMemberships, err = tx.MoveUserCommunityMemberships(ctx, database.MoveUserCommunityMembershipsParams{SourceID: from, TargetID: to}); err != nil {
+		return merge, err
+	}
+
+	// What's left duplicated something the target already had.
+	for _, drop := range []func(context.Context, uuid.UUID) error{
+		tx.DeleteUserReactions,
+		tx.DeleteUserRemoteFollowers,
+		tx.DeleteUserListMemberships,
+		tx.DeleteUserCommunityMemberships,
+	} {
+		if err := drop(ctx, from); err != nil {
+			return merge, err
+		}
+	}
+
+	now := s.clock.Now().UTC()
+	if source.Handle.Valid {
+		err := tx.RecordHandleChange(ctx, database.RecordHandleChangeParams{
+			Handle:    source.Handle.String,
+			UserID:    merge.TargetID,
+			ExpiresAt: now.Add(handleRedirectPeriod),
+		})
+		if err != nil {
+			return merge, err
+		}
+	}
+	_, err = tx.TombstoneUser(ctx, database.TombstoneUserParams{
+		ID:               source.ID,
+		Email:            fmt.Sprintf("merged-%s@invalid", source.ID),
+		ModerationReason: "Merged into " + merge.TargetID.String(),
+	})
+	if err != nil {
+		return merge, err
+	}
+
+	merge, err = tx.CompleteAccountMerge(ctx, database.CompleteAccountMergeParams{
+		ID:          merge.ID,
+		CompletedAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		return merge, err
+	}
+	err = recordAudit(ctx, tx, actorID, "user.merge", source.ID, map[string]interface{}{
+		"merge_id":     merge.ID,
+		"into":         merge.TargetID,
+		"requested_by": merge.RequestedBy,
+		"email":        source.Email,
+		"handle":       source.Handle.String,
+		"moved":        counts,
+	})
+	return merge, err
+}
@@ -0,0 +1,299 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type mergeStore struct {
+	fakeStore
+	merges  map[uuid.UUID]database.AccountMerge
+	moved   []database.MoveUserChirpsParams
+	history []database.RecordHandleChangeParams
+	audits  []string
+	// creators and roles are keyed by community ID.
+	creators map[uuid.UUID]uuid.NullUUID
+	roles    map[uuid.UUID]map[uuid.UUID]string
+}
+
+type mergeTx struct {
+	*mergeStore
+}
+
+func (s *mergeStore) Begin(ctx context.Context) (Tx, error) {
+	return mergeTx{s}, nil
+}
+
+func (tx mergeTx) Commit() error   { return nil }
+func (tx mergeTx) Rollback() error { return nil }
+
+func (s *mergeStore) CreateAccountMerge(ctx context.Context, arg database.CreateAccountMergeParams) (database.AccountMerge, error) {
+	m := database.AccountMerge{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		ExpiresAt:   arg.ExpiresAt,
+		RequestedBy: arg.RequestedBy,
+		SourceID:    arg.SourceID,
+		TargetID:    arg.TargetID,
+	}
+	s.merges[m.ID] = m
+	return m, nil
+}
+
+func (s *mergeStore) GetAccountMergeForUpdate(ctx context.Context, id uuid.UUID) (database.AccountMerge, error) {
+	m, ok := s.merges[id]
+	if !ok {
+		return database.AccountMerge{}, sql.ErrNoRows
+	}
+	return m, nil
+}
+
+func (s *mergeStore) ConfirmAccountMerge(ctx context.Context, arg database.ConfirmAccountMergeParams) (database.AccountMerge, error) {
+	m := s.merges[arg.ID]
+	m.SourceConfirmedAt = arg.SourceConfirmedAt
+	m.TargetConfirmedAt = arg.TargetConfirmedAt
+	s.merges[m.ID] = m
+	return m, nil
+}
+
+func (s *mergeStore) CompleteAccountMerge(ctx context.Context, arg database.CompleteAccountMergeParams) (database.AccountMerge, error) {
+	m := s.merges[arg.ID]
+	m.CompletedAt = arg.CompletedAt
+	s.merges[m.ID] = m
+	return m, nil
+}
+
+func (s *mergeStore) MoveUserChirps(ctx context.Context, arg database.MoveUserChirpsParams) (int64, error) {
+	s.moved = append(s.moved, arg)
+	return 3, nil
+}
+
+func (s *mergeStore) MoveUserMedia(ctx context.Context, arg database.MoveUserMediaParams) (int64, error) {
+	return 0, nil
+}
+
+func (s *mergeStore) MoveUserReactions(ctx context.Context, arg database.MoveUserReactionsParams) (int64, error) {
+	return 0, nil
+}
+
+func (s *mergeStore) MoveUserRemoteFollowers(ctx context.Context, arg database.MoveUserRemoteFollowersParams) (int64, error) {
+	return 0, nil
+}
+
+func (s *mergeStore) MoveUserLists(ctx context.Context, arg database.MoveUserListsParams) (int64, error) {
+	return 0, nil
+}
+
+func (s *mergeStore) MoveUserListMemberships(ctx context.Context, arg database.MoveUserListMembershipsParams) (int64, error) {
+	return 0, nil
+}
+
+func (s *mergeStore) MoveUserCommunities(ctx context.Context, arg database.MoveUserCommunitiesParams) (int64, error) {
+	var n int64
+	for id, creator := range s.creators {
+		if creator == arg.SourceID {
+			s.creators[id] = arg.TargetID
+			n++
+		}
+	}
+	return n, nil
+}
+
+var communityRoleRank = map[string]int{communityRoleMember: 1, communityRoleModerator: 2, communityRoleOwner: 3}
+
+func (s *mergeStore) MergeUserCommunityRoles(ctx context.Context, arg database.MergeUserCommunityRolesParams) error {
+	for _, members := range s.roles {
+		theirs, ok := members[arg.SourceID]
+		mine, both := members[arg.TargetID]
+		if ok && both && communityRoleRank[theirs] > communityRoleRank[mine] {
+			members[arg.TargetID] = theirs
+		}
+	}
+	return nil
+}
+
+func (s *mergeStore) MoveUserCommunityMemberships(ctx context.Context, arg database.MoveUserCommunityMembershipsParams) (int64, error) {
+	var n int64
+	for _, members := range s.roles {
+		role, ok := members[arg.SourceID]
+		if _, both := members[arg.TargetID]; ok && !both {
+			members[arg.TargetID] = role
+			delete(members, arg.SourceID)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *mergeStore) DeleteUserCommunityMemberships(ctx context.Context, userID uuid.UUID) error {
+	for _, members := range s.roles {
+		delete(members, userID)
+	}
+	return nil
+}
+
+func (s *mergeStore) DeleteUserReactions(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+func (s *mergeStore) DeleteUserRemoteFollowers(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+func (s *mergeStore) DeleteUserListMemberships(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+func (s *mergeStore) RecordHandleChange(ctx context.Context, arg database.RecordHandleChangeParams) error {
+	s.history = append(s.history, arg)
+	return nil
+}
+
+func (s *mergeStore) TombstoneUser(ctx context.Context, arg database.TombstoneUserParams) (database.User, error) {
+	for email, u := range s.users {
+		if u.ID == arg.ID {
+			delete(s.users, email)
+			u.Email = arg.Email
+			u.HashedPassword = ""
+			u.Handle = sql.NullString{}
+			u.BannedAt = sql.NullTime{Time: testNow, Valid: true}
+			u.ModerationReason = arg.ModerationReason
+			s.users[u.Email] = u
+			return u, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (s *mergeStore) CreateAuditLogEntry(ctx context.Context, arg database.CreateAuditLogEntryParams) error {
+	s.audits = append(s.audits, arg.Action)
+	return nil
+}
+
+func TestAccountMerge(t *testing.T) {
+	admin := newTestUser(t, "admin@example.com", "pa55word")
+	admin.Role = RoleAdmin
+	alt := newTestUser(t, "alt@example.com", "pa55word")
+	alt.Handle = nullString("alice_alt")
+	main := newTestUser(t, "alice@example.com", "pa55word")
+	stranger := newTestUser(t, "bob@example.com", "pa55word")
+	store := &mergeStore{
+		fakeStore: fakeStore{users: map[string]database.User{
+			admin.Email: admin, alt.Email: alt, main.Email: main, stranger.Email: stranger,
+		}},
+		merges: map[uuid.UUID]database.AccountMerge{},
+	}
+	// The alt account started one community, which the main account
+	// joined, and moderates another.
+	founded, moderated := uuid.New(), uuid.New()
+	store.creators = map[uuid.UUID]uuid.NullUUID{
+		founded:   {UUID: alt.ID, Valid: true},
+		moderated: {UUID: stranger.ID, Valid: true},
+	}
+	store.roles = map[uuid.UUID]map[uuid.UUID]string{
+		founded:   {alt.ID: communityRoleOwner, main.ID: communityRoleMember},
+		moderated: {stranger.ID: communityRoleOwner, alt.ID: communityRoleModerator},
+	}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)}))
+	sendToken := func(token, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	send := func(id uuid.UUID, method, path, body string) *httptest.ResponseRecorder {
+		token, err := auth.MakeJWT(id, cfg.JWTSecret, time.Hour)
+		if err != nil {
+			t.Fatalf("MakeJWT returned error: %v", err)
+		}
+		return sendToken(token, method, path, body)
+	}
+
+	if rec := send(admin.ID, http.MethodPost, "/admin/users/"+alt.ID.String()+"/merge", `{"into":"`+alt.ID.String()+`"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("merging into itself: expected 400, got %d", rec.Code)
+	}
+	if rec := send(alt.ID, http.MethodPost, "/admin/users/"+alt.ID.String()+"/merge", `{"into":"`+main.ID.String()+`"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin: expected 403, got %d", rec.Code)
+	}
+	rec := send(admin.ID, http.MethodPost, "/admin/users/"+alt.ID.String()+"/merge", `{"into":"`+main.ID.String()+`","reason":"same person"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var merge accountMergeResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &merge); err != nil {
+		t.Fatal(err)
+	}
+	confirm := "/api/users/me/merges/" + merge.ID.String() + "/confirm"
+
+	if rec := send(stranger.ID, http.MethodPost, confirm, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("someone else confirming: expected 404, got %d", rec.Code)
+	}
+	if rec := send(alt.ID, http.MethodPost, confirm, ""); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"completed_at":"`) {
+		t.Fatalf("first confirmation: expected 200 and still pending, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(store.moved) != 0 {
+		t.Fatal("nothing may move before both accounts confirm")
+	}
+
+	token, err := auth.MakeImpersonationJWT(main.ID, admin.ID, cfg.JWTSecret, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := sendToken(token, http.MethodPost, confirm, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("impersonating the other account: expected 403, got %d", rec.Code)
+	}
+
+	rec = send(main.ID, http.MethodPost, confirm, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"completed_at":"`) {
+		t.Fatalf("second confirmation: expected 200 and completed, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(store.moved) != 1 || store.moved[0].SourceID != alt.ID || store.moved[0].TargetID != main.ID {
+		t.Errorf("expected chirps moved from the alt to the main account, got %+v", store.moved)
+	}
+	if creator := store.creators[founded]; creator.UUID != main.ID {
+		t.Errorf("expected the alt's community to be credited to the main account, got %v", creator.UUID)
+	}
+	if role := store.roles[founded][main.ID]; role != communityRoleOwner {
+		t.Errorf("expected the main account to take over ownership, got %q", role)
+	}
+	if role := store.roles[moderated][main.ID]; role != communityRoleModerator {
+		t.Errorf("expected the main account to take over moderation, got %q", role)
+	}
+	for id, members := range store.roles {
+		if role, ok := members[alt.ID]; ok {
+			t.Errorf("expected the alt account to leave community %s, still %q", id, role)
+		}
+	}
+	tombstone, _ := store.GetUserByID(context.Background(), alt.ID)
+	if !tombstone.BannedAt.Valid || tombstone.Handle.Valid || tombstone.Email == alt.Email {
+		t.Errorf("expected the alt account tombstoned, got %+v", tombstone)
+	}
+	if len(store.history) != 1 || store.history[0].Handle != "alice_alt" || store.history[0].UserID != main.ID {
+		t.Errorf("expected the old handle to lead to the main account, got %+v", store.history)
+	}
+	// The impersonated request is audited too.
+	if want := []string{"user.merge_request", "impersonation.request", "user.merge"}; strings.Join(store.audits, ",") != strings.Join(want, ",") {
+		t.Errorf("expected audit entries %v, got %v", want, store.audits)
+	}
+
+	if rec := do(h, http.MethodPost, "/api/login", `{"email":"alt@example.com","password":"pa55word"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("logging in to the merged account: expected 401, got %d", rec.Code)
+	}
+	if rec := send(main.ID, http.MethodPost, confirm, ""); rec.Code != http.StatusConflict {
+		t.Errorf("confirming again: expected 409, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+type auditEntryResponse struct {
+	ID        uuid.UUID       `json:"id"`
+	CreatedAt Timestamp       `json:"created_at"`
+	ActorID   uuid.UUID       `json:"actor_id"`
+	Action    string          `json:"action"`
+	TargetID  *uuid.UUID      `json:"target_id"`
+	Details   json.RawMessage `json:"details"`
+}
+
+type auditPageResponse struct {
+	Entries    []auditEntryResponse `json:"entries"`
+	NextOffset *int                 `json:"next_offset"`
+}
+
+// handlerAdminAuditList pages through the audit log, newest first. Supported
+// filters: actor_id, action, target_id, since and until (RFC 3339), plus
+// limit and offset for paging.
+func (s *Server) handlerAdminAuditList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	params := database.ListAuditLogParams{
+		RowLimit: defaultAuditPageSize,
+	}
+
+	var err error
+	if params.ActorID, err = parseOptionalUUID(query.Get("actor_id")); err != nil {
+		jsonResponse(w, http.StatusBadRequest, "Invalid actor_id")
+		return
+	}
+	if params.TargetID, err = parseOptionalUUID(query.Get("target_id")); err != nil {
+		jsonResponse(w, http.StatusBadRequest, "Invalid target_id")
+		return
+	}
+	if params.Since, err = parseOptionalTime(query.Get("since")); err != nil {
+		jsonResponse(w, http.StatusBadRequest, "since must be an RFC 3339 timestamp")
+		return
+	}
+	if params.Until, err = parseOptionalTime(query.Get("until")); err != nil {
+		jsonResponse(w, http.StatusBadRequest, "until must be an RFC 3339 timestamp")
+		return
+	}
+	if action := query.Get("action"); action != "" {
+		params.Action = sql.NullString{String: action, Valid: true}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditPageSize {
+			jsonResponse(w, http.StatusBadRequest, "limit must be between 1 and 200")
+			return
+		}
+		params.RowLimit = int32(n)
+	}
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			jsonResponse(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = n
+	}
+	params.RowOffset = int32(offset)
+
+	// Fetch one extra row to learn whether another page exists.
+	pageSize := int(params.RowLimit)
+	params.RowLimit++
+
+	entries, err := s.db.ListAuditLog(r.Context(), params)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	resp := auditPageResponse{Entries: make([]auditEntryResponse, 0, len(entries))}
+	if len(entries) > pageSize {
+		entries = entries[:pageSize]
+		next := offset + pageSize
+		resp.NextOffset = &next
+	}
+	for _, e := range entries {
+		entry := auditEntryResponse{
+			ID:        e.ID,
+			CreatedAt: Timestamp{e.CreatedAt},
+			ActorID:   e.ActorID,
+			Action:    e.Action,
+			Details:   e.Details,
+		}
+		if e.TargetID.Valid {
+			entry.TargetID = &e.TargetID.UUID
+		}
+		resp.Entries = append(resp.Entries, entry)
+	}
+
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func parseOptionalUUID(s string) (uuid.NullUUID, error) {
+	if s == "" {
+		return uuid.NullUUID{}, nil
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: id, Valid: true}, nil
+}
+
+func parseOptionalTime(s string) (sql.NullTime, error) {
+	if s == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"chirpy/internal/contentfilter"
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultContentFlagsLimit = 100
+	maxContentFlagsLimit     = 1000
+)
+
+// reloadContentRules rebuilds the in-memory content filter from the
+// database and swaps it in atomically.
+func (s *Server) reloadContentRules(ctx context.Context) error {
+	rows, err := s.db.ListContentRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules := make([]contentfilter.Rule, 0, len(rows))
+	for _, row := range rows {
+		rules = append(rules, contentfilter.Rule{
+			ID:      row.ID,
+			Kind:    contentfilter.Kind(row.Kind),
+			Pattern: row.Pattern,
+			Action:  contentfilter.Action(row.Action),
+		})
+	}
+
+	filter, invalid := contentfilter.New(rules)
+	for _, r := range invalid {
+		fmt.Println("Skipping invalid content rule:", r.ID)
+	}
+	s.contentFilter.Store(filter)
+	return nil
+}
+
+// watchContentRules reloads the content filter whenever any instance changes
+// the rules, using the events hub fed by Postgres NOTIFY.
+func (s *Server) watchContentRules(ctx context.Context, hub *events.Hub) {
+	ch, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if e.Type != "content_rules.changed" {
+				continue
+			}
+			if err := s.reloadContentRules(ctx); err != nil {
+				fmt.Println("Error reloading content rules:", err)
+			}
+		}
+	}
+}
+
+// flagChirp queues a chirp for moderator review for each matching rule.
+func (s *Server) flagChirp(ctx context.Context, chirpID uuid.UUID, rules []contentfilter.Rule) {
+	for _, rule := range rules {
+		err := s.db.CreateContentFlag(ctx, database.CreateContentFlagParams{
+			ID:      uuid.New(),
+			ChirpID: chirpID,
+			RuleID:  uuid.NullUUID{UUID: rule.ID, Valid: true},
+			Pattern: rule.Pattern,
+		})
+		if err != nil {
+			fmt.Println("Error flagging chirp:", err)
+		}
+	}
+}
+
+type contentRuleResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt Timestamp  `json:"created_at"`
+	Kind      string     `json:"kind"`
+	Pattern   string     `json:"pattern"`
+	Action    string     `json:"action"`
+	CreatedBy *uuid.UUID `json:"created_by"`
+}
+
+func newContentRuleResponse(r database.ContentRule) contentRuleResponse {
+	resp := contentRuleResponse{
+		ID:        r.ID,
+		CreatedAt: Timestamp{r.CreatedAt},
+		Kind:      r.Kind,
+		Pattern:   r.Pattern,
+		Action:    r.Action,
+	}
+	if r.CreatedBy.Valid {
+		resp.CreatedBy = &r.CreatedBy.UUID
+	}
+	return resp
+}
+
+func (s *Server) handlerAdminContentRulesList(w http.ResponseWriter, r *http.Request) {
+	rules, err := s.db.ListContentRules(r.Context())
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	resp := make([]contentRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		resp = append(resp, newContentRuleResponse(rule))
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+type contentRuleRequest struct {
+	Kind    string `json:"kind"`
+	Pattern string `json:"pattern"`
+	Action  string `json:"action"`
+}
+
+func (s *Server) handlerAdminContentRulesCreate(w http.ResponseWriter, r *http.Request) {
+	var req contentRuleRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+
+	err := contentfilter.Validate(contentfilter.Rule{
+		Kind:    contentfilter.Kind(req.Kind),
+		Pattern: req.Pattern,
+		Action:  contentfilter.Action(req.Action),
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, "Invalid rule: "+err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	admin := adminFromContext(ctx)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	rule, err := tx.CreateContentRule(ctx, database.CreateContentRuleParams{
+		ID:        uuid.New(),
+		Kind:      req.Kind,
+		Pattern:   req.Pattern,
+		Action:    req.Action,
+		CreatedBy: uuid.NullUUID{UUID: admin.ID, Valid: true},
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	err = recordAudit(ctx, tx, admin.ID, "content_rule.create", rule.ID, req)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := s.reloadContentRules(ctx); err != nil {
+		fmt.Println("Error reloading content rules:", err)
+	}
+	jsonResponse(w, http.StatusCreated, newContentRuleResponse(rule))
+}
+
+func (s *Server) handlerAdminContentRulesDelete(w http.ResponseWriter, r *http.Request) {
+	ruleID, ok := pathUUID(w, r, "ruleID")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	admin := adminFromContext(ctx)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	rule, err := tx.DeleteContentRule(ctx, ruleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "Rule was not found.")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	err = recordAudit(ctx, tx, admin.ID, "content_rule.delete", rule.ID, map[string]string{
+		"kind":    rule.Kind,
+		"pattern": rule.Pattern,
+		"action":  rule.Action,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := s.reloadContentRules(ctx); err != nil {
+		fmt.Println("Error reloading content rules:", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type contentFlagResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt Timestamp  `json:"created_at"`
+	ChirpID   uuid.UUID  `json:"chirp_id"`
+	ChirpBody string     `json:"chirp_body"`
+	AuthorID  uuid.UUID  `json:"author_id"`
+	RuleID    *uuid.UUID `json:"rule_id"`
+	Pattern   string     `json:"pattern"`
+}
+
+// handlerAdminContentFlagsList returns the chirps queued for review by flag
+// rules, newest first.
+func (s *Server) handlerAdminContentFlagsList(w http.ResponseWriter, r *http.Request) {
+	limit := defaultContentFlagsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxContentFlagsLimit {
+			jsonResponse(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		limit = n
+	}
+
+	flags, err := s.db.ListContentFlags(r.Context(), int32(limit))
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	resp := make([]contentFlagResponse, 0, len(flags))
+	for _, f := range flags {
+		flag := contentFlagResponse{
+			ID:        f.ID,
+			CreatedAt: Timestamp{f.CreatedAt},
+			ChirpID:   f.ChirpID,
+			ChirpBody: f.ChirpBody,
+			AuthorID:  f.AuthorID,
+			Pattern:   f.Pattern,
+		}
+		if f.RuleID.Valid {
+			flag.RuleID = &f.RuleID.UUID
+		}
+		resp = append(resp, flag)
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
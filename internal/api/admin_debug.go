@@ -0,0 +1,93 @@
+package api
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+// debugHandlers mounts net/http/pprof and expvar under /admin/debug/. They
+// are registered on the router rather than http.DefaultServeMux so nothing
+// is reachable without admin credentials.
+func (s *Server) debugHandlers(mux *Router) {
+	// pprof.Index only serves paths under /debug/pprof/.
+	index := http.StripPrefix("/admin", http.HandlerFunc(pprof.Index))
+	mux.HandleFunc("GET /admin/debug/pprof/", s.middlewareRequireAdmin(index.ServeHTTP))
+	mux.HandleFunc("GET /admin/debug/pprof/cmdline", s.middlewareRequireAdmin(pprof.Cmdline))
+	mux.HandleFunc("GET /admin/debug/pprof/profile", s.middlewareRequireAdmin(pprof.Profile))
+	mux.HandleFunc("GET /admin/debug/pprof/symbol", s.middlewareRequireAdmin(pprof.Symbol))
+	mux.HandleFunc("POST /admin/debug/pprof/symbol", s.middlewareRequireAdmin(pprof.Symbol))
+	mux.HandleFunc("GET /admin/debug/pprof/trace", s.middlewareRequireAdmin(pprof.Trace))
+	mux.HandleFunc("GET /admin/debug/vars", s.middlewareRequireAdmin(expvar.Handler().ServeHTTP))
+}
+
+type runtimeMemory struct {
+	HeapAlloc    uint64 `json:"heap_alloc_bytes"`
+	HeapInuse    uint64 `json:"heap_inuse_bytes"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	StackInuse   uint64 `json:"stack_inuse_bytes"`
+	Sys          uint64 `json:"sys_bytes"`
+	TotalAlloc   uint64 `json:"total_alloc_bytes"`
+	Mallocs      uint64 `json:"mallocs"`
+	Frees        uint64 `json:"frees"`
+	NextGCTarget uint64 `json:"next_gc_bytes"`
+}
+
+type runtimeGC struct {
+	NumGC       uint32     `json:"num_gc"`
+	NumForcedGC uint32     `json:"num_forced_gc"`
+	LastGC      *Timestamp `json:"last_gc"`
+	PauseTotal  string     `json:"pause_total"`
+	LastPause   string     `json:"last_pause,omitempty"`
+	CPUFraction float64    `json:"cpu_fraction"`
+}
+
+type runtimeResponse struct {
+	GoVersion  string        `json:"go_version"`
+	Goroutines int           `json:"goroutines"`
+	GOMAXPROCS int           `json:"gomaxprocs"`
+	NumCPU     int           `json:"num_cpu"`
+	Uptime     string        `json:"uptime"`
+	Memory     runtimeMemory `json:"memory"`
+	GC         runtimeGC     `json:"gc"`
+}
+
+// handlerAdminRuntime reports goroutine, memory and GC figures for this
+// instance. runtime.ReadMemStats briefly stops the world, so this is meant
+// for occasional use while investigating, not for scraping.
+func (s *Server) handlerAdminRuntime(w http.ResponseWriter, r *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	resp := runtimeResponse{
+		GoVersion:  runtime.Version(),
+		Goroutines: runtime.NumGoroutine(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		Uptime:     s.clock.Now().Sub(s.startedAt).Round(time.Second).String(),
+		Memory: runtimeMemory{
+			HeapAlloc:    m.HeapAlloc,
+			HeapInuse:    m.HeapInuse,
+			HeapObjects:  m.HeapObjects,
+			StackInuse:   m.StackInuse,
+			Sys:          m.Sys,
+			TotalAlloc:   m.TotalAlloc,
+			Mallocs:      m.Mallocs,
+			Frees:        m.Frees,
+			NextGCTarget: m.NextGC,
+		},
+		GC: runtimeGC{
+			NumGC:       m.NumGC,
+			NumForcedGC: m.NumForcedGC,
+			PauseTotal:  time.Duration(m.PauseTotalNs).String(),
+			CPUFraction: m.GCCPUFraction,
+		},
+	}
+	if m.NumGC > 0 {
+		resp.GC.LastGC = &Timestamp{time.Unix(0, int64(m.LastGC))}
+		resp.GC.LastPause = time.Duration(m.PauseNs[(m.NumGC+255)%256]).String()
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+)
+
+func TestAdminDebug_RequiresAdmin(t *testing.T) {
+	admin := newTestUser(t, "admin@example.com", "pa55word")
+	admin.Role = RoleAdmin
+	member := newTestUser(t, "member@example.com", "pa55word")
+	store := &contractStore{fakeStore{users: map[string]database.User{
+		admin.Email:  admin,
+		member.Email: member,
+	}}}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewRouter(NewServer(cfg, Deps{Store: store}))
+
+	get := func(path string, user database.User) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if user.Email != "" {
+			token, err := auth.MakeJWT(user.ID, cfg.JWTSecret, time.Hour)
+			if err != nil {
+				t.Fatalf("MakeJWT returned error: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	paths := []string{"/admin/runtime", "/admin/debug/pprof/", "/admin/debug/pprof/goroutine?debug=1", "/admin/debug/vars"}
+	for _, path := range paths {
+		if rec := get(path, database.User{}); rec.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s without a token: expected 401, got %d", path, rec.Code)
+		}
+		if rec := get(path, member); rec.Code != http.StatusForbidden {
+			t.Errorf("GET %s as a member: expected 403, got %d", path, rec.Code)
+		}
+		if rec := get(path, admin); rec.Code != http.StatusOK {
+			t.Errorf("GET %s as an admin: expected 200, got %d: %s", path, rec.Code, rec.Body)
+		}
+	}
+
+	if body := get("/admin/debug/pprof/goroutine?debug=1", admin).Body.String(); !strings.Contains(body, "goroutine profile") {
+		t.Errorf("unexpected goroutine profile: %.200s", body)
+	}
+	if body := get("/admin/debug/vars", admin).Body.String(); !strings.Contains(body, `"memstats"`) {
+		t.Errorf("expvar output lacks memstats: %.200s", body)
+	}
+
+	var resp runtimeResponse
+	if err := json.NewDecoder(get("/admin/runtime", admin).Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding runtime response: %v", err)
+	}
+	if resp.Goroutines < 1 || resp.Memory.HeapAlloc == 0 || resp.GoVersion == "" {
+		t.Errorf("implausible runtime stats: %+v", resp)
+	}
+}
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+	"chirpy/internal/ipblock"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultIPActivityLimit = 100
+	maxIPActivityLimit     = 1000
+)
+
+// clientIP returns the address the request came from. X-Forwarded-For is only
+// honoured when the server is configured to sit behind a trusted proxy.
+func (s *Server) clientIP(r *http.Request) netip.Addr {
+	if s.config.TrustProxyHeaders {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			if addr, err := netip.ParseAddr(strings.TrimSpace(first)); err == nil {
+				return addr.Unmap()
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+// middlewareBlockIPs rejects every request from a blocked network before it
+// reaches the router.
+func (s *Server) middlewareBlockIPs(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip := s.clientIP(r); ip.IsValid() && s.ipBlocks.Blocked(ip) {
+			jsonResponse(w, http.StatusForbidden, "Access from your network has been blocked")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reloadIPBlocks refreshes the in-memory blocklist from the database.
+func (s *Server) reloadIPBlocks(ctx context.Context) error {
+	blocks, err := s.db.ListIPBlocks(ctx)
+	if err != nil {
+		return err
+	}
+
+	cidrs := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		cidrs = append(cidrs, b.Cidr)
+	}
+	if invalid := s.ipBlocks.Set(cidrs); len(invalid) > 0 {
+		fmt.Println("Ignoring invalid IP blocks:", invalid)
+	}
+	return nil
+}
+
+// watchIPBlocks reloads the blocklist whenever any instance changes the
+// blocks, using the events hub fed by Postgres NOTIFY.
+func (s *Server) watchIPBlocks(ctx context.Context, hub *events.Hub) {
+	ch, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if e.Type != "ip_blocks.changed" {
+				continue
+			}
+			if err := s.reloadIPBlocks(ctx); err != nil {
+				fmt.Println("Error reloading IP blocks:", err)
+			}
+		}
+	}
+}
+
+// recordIPActivity bumps a per-IP counter. Failures are logged rather than
+// surfaced: reputation tracking must never break signup or login.
+func (s *Server) recordIPActivity(r *http.Request, record func(context.Context, string) error) {
+	ip := s.clientIP(r)
+	if !ip.IsValid() {
+		return
+	}
+	if err := record(r.Context(), ip.String()); err != nil {
+		fmt.Println("Error recording IP activity:", err)
+	}
+}
+
+type ipActivityResponse struct {
+	IP            string    `json:"ip"`
+	Signups       int32     `json:"signups"`
+	LoginFailures int32     `json:"login_failures"`
+	FirstSeenAt   Timestamp `json:"first_seen_at"`
+	LastSeenAt    Timestamp `json:"last_seen_at"`
+	Blocked       bool      `json:"blocked"`
+}
+
+func (s *Server) handlerAdminIPsList(w http.ResponseWriter, r *http.Request) {
+	limit := defaultIPActivityLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxIPActivityLimit {
+			jsonResponse(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		limit = n
+	}
+
+	rows, err := s.db.ListIPActivity(r.Context(), int32(limit))
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	resp := make([]ipActivityResponse, 0, len(rows))
+	for _, row := range rows {
+		addr, _ := netip.ParseAddr(row.Ip)
+		resp = append(resp, ipActivityResponse{
+			IP:            row.Ip,
+			Signups:       row.Signups,
+			LoginFailures: row.LoginFailures,
+			FirstSeenAt:   Timestamp{row.FirstSeenAt},
+			LastSeenAt:    Timestamp{row.LastSeenAt},
+			Blocked:       addr.IsValid() && s.ipBlocks.Blocked(addr),
+		})
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+type ipBlockResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt Timestamp  `json:"created_at"`
+	CIDR      string     `json:"cidr"`
+	Reason    string     `json:"reason"`
+	CreatedBy *uuid.UUID `json:"created_by"`
+}
+
+func newIPBlockResponse(b database.IpBlock) ipBlockResponse {
+	resp := ipBlockResponse{
+		ID:        b.ID,
+		CreatedAt: Timestamp{b.CreatedAt},
+		CIDR:      b.Cidr,
+		Reason:    b.Reason,
+	}
+	if b.CreatedBy.Valid {
+		resp.CreatedBy = &b.CreatedBy.UUID
+	}
+	return resp
+}
+
+func (s *Server) handlerAdminIPBlocksList(w http.ResponseWriter, r *http.Request) {
+	blocks, err := s.db.ListIPBlocks(r.Context())
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	resp := make([]ipBlockResponse, 0, len(blocks))
+	for _, b := range blocks {
+		resp = append(resp, newIPBlockResponse(b))
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+type ipBlockRequest struct {
+	CIDR   string `json:"cidr"`
+	Reason string `json:"reason"`
+}
+
+func (s *Server) handlerAdminIPBlocksCreate(w http.ResponseWriter, r *http.Request) {
+	var req ipBlockRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+
+	prefix, err := ipblock.ParseCIDR(strings.TrimSpace(req.CIDR))
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, "Invalid CIDR")
+		return
+	}
+
+	ctx := r.Context()
+	admin := adminFromContext(ctx)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	block, err := tx.CreateIPBlock(ctx, database.CreateIPBlockParams{
+		ID:        uuid.New(),
+		Cidr:      prefix.String(),
+		Reason:    req.Reason,
+		CreatedBy: uuid.NullUUID{UUID: admin.ID, Valid: true},
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		jsonResponse(w, http.StatusConflict, "That range is already blocked")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	err = recordAudit(ctx, tx, admin.ID, "ip.block", block.ID, map[string]interface{}{
+		"cidr":   block.Cidr,
+		"reason": block.Reason,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := s.reloadIPBlocks(ctx); err != nil {
+		fmt.Println("Error reloading IP blocks:", err)
+	}
+	jsonResponse(w, http.StatusCreated, newIPBlockResponse(block))
+}
+
+func (s *Server) handlerAdminIPBlocksDelete(w http.ResponseWriter, r *http.Request) {
+	blockID, ok := pathUUID(w, r, "blockID")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	admin := adminFromContext(ctx)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	block, err := tx.DeleteIPBlock(ctx, blockID)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "Block was not found.")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	err = recordAudit(ctx, tx, admin.ID, "ip.unblock", block.ID, map[string]interface{}{
+		"cidr": block.Cidr,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := s.reloadIPBlocks(ctx); err != nil {
+		fmt.Println("Error reloading IP blocks:", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// resetScopes maps each /admin/reset/{scope} to the data it wipes. Metrics
+// are reset separately.
+var resetScopes = map[string]func(context.Context, database.Querier) error{
+	"users": func(ctx context.Context, q database.Querier) error {
+		return q.DeleteAllUsers(ctx)
+	},
+	"chirps": func(ctx context.Context, q database.Querier) error {
+		return q.DeleteAllChirps(ctx)
+	},
+	"metrics": func(ctx context.Context, q database.Querier) error {
+		return nil
+	},
+	"all": func(ctx context.Context, q database.Querier) error {
+		if err := q.DeleteAllIPActivity(ctx); err != nil {
+			return err
+		}
+		// Chirps, audit entries and IP blocks go with their users.
+		return q.DeleteAllUsers(ctx)
+	},
+}
+
+type resetRequest struct {
+	Confirm string `json:"confirm"`
+}
+
+// handlerAdminResetScoped wipes one category of data. The body must repeat
+// the scope as {"confirm": "reset-<scope>"} so a stray request can't wipe
+// the wrong thing. Outside the dev platform only admins may call it.
+func (s *Server) handlerAdminResetScoped(w http.ResponseWriter, r *http.Request) {
+	scope := r.PathValue("scope")
+	reset, ok := resetScopes[scope]
+	if !ok {
+		jsonResponse(w, http.StatusNotFound, "Unknown reset scope")
+		return
+	}
+
+	actorID, allowed := s.resetAllowed(r)
+	if !allowed {
+		jsonResponse(w, http.StatusForbidden, "Reset requires the dev platform or an admin")
+		return
+	}
+
+	var req resetRequest
+	if err := s.decodeJSON(r, &req); err != nil || req.Confirm != "reset-"+scope {
+		confirm := fmt.Sprintf(`Confirm by sending {"confirm": "reset-%s"}`, scope)
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, confirm))
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	if actorID != uuid.Nil {
+		err := recordAudit(ctx, tx, actorID, "admin.reset", uuid.Nil, map[string]interface{}{
+			"scope": scope,
+		})
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	}
+
+	if err := reset(ctx, tx); err != nil {
+		fmt.Println("Error resetting", scope+":", err)
+		jsonResponse(w, http.StatusInternalServerError, "Failed to reset "+scope)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Failed to commit transaction")
+		return
+	}
+	s.responseCache.invalidate()
+
+	if scope == "metrics" || scope == "all" {
+		if err := s.hits.Reset(ctx); err != nil {
+			fmt.Println("Error resetting hits:", err)
+		}
+		s.statsCache.clear()
+	}
+
+	jsonResponse(w, http.StatusOK, map[string]string{"reset": scope})
+}
+
+// resetAllowed reports whether the caller may reset data, and who they are
+// when they presented a token. Admin tokens work on every platform; on the
+// dev platform anyone may reset.
+func (s *Server) resetAllowed(r *http.Request) (uuid.UUID, bool) {
+	userID, actorID, err := s.authenticateWithActor(r)
+	if err == nil && actorID == uuid.Nil {
+		user, err := s.db.GetUserByID(r.Context(), userID)
+		if err == nil && user.Role == RoleAdmin {
+			return user.ID, true
+		}
+	}
+	return uuid.Nil, s.config.Platform == "dev"
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"chirpy/internal/database"
+)
+
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 365
+	statsCacheTTL    = 5 * time.Minute
+)
+
+type statsTotals struct {
+	Users  int64 `json:"users"`
+	Chirps int64 `json:"chirps"`
+}
+
+type dailyStats struct {
+	Date        string `json:"date"`
+	Signups     int64  `json:"signups"`
+	ActiveUsers int64  `json:"active_users"`
+	Chirps      int64  `json:"chirps"`
+}
+
+// weeklyRevenue is how Chirpy Red did over a week starting on a Monday,
+// from the subscriptions Stripe's events described. Amounts are in the
+// smallest unit of the price's currency; MRR is what paying subscriptions
+// bring in a month, as at the end of the week.
+type weeklyRevenue struct {
+	Week             string `json:"week"`
+	TrialsStarted    int64  `json:"trials_started"`
+	TrialsConverted  int64  `json:"trials_converted"`
+	NewSubscriptions int64  `json:"new_subscriptions"`
+	Churned          int64  `json:"churned"`
+	Trialing         int64  `json:"trialing"`
+	Active           int64  `json:"active"`
+	MRR              int64  `json:"mrr"`
+	// ConversionRate is the share of trials ending in the week that
+	// turned into paid subscriptions, and ChurnRate the share of those
+	// paying at its start that ended. Both are null when there was
+	// nothing to divide by.
+	ConversionRate *float64 `json:"conversion_rate"`
+	ChurnRate      *float64 `json:"churn_rate"`
+}
+
+type statsResponse struct {
+	GeneratedAt Timestamp    `json:"generated_at"`
+	Days        int          `json:"days"`
+	Totals      statsTotals  `json:"totals"`
+	Daily       []dailyStats `json:"daily"`
+	// Revenue covers the weeks the window touches, when billing is on.
+	Revenue []weeklyRevenue `json:"revenue,omitempty"`
+}
+
+// statsCache keeps computed dashboards around for statsCacheTTL, keyed by the
+// tenant and requested window, so refreshing the dashboard doesn't rerun the
+// aggregates.
+type statsCache struct {
+	mu      sync.Mutex
+	entries map[string]statsResponse
+}
+
+func statsCacheKey(ctx context.Context, days int) string {
+	return cacheKey(ctx, strconv.Itoa(days))
+}
+
+func (c *statsCache) get(key string) (statsResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, ok := c.entries[key]
+	if !ok || time.Since(resp.GeneratedAt.Time) > statsCacheTTL {
+		return statsResponse{}, false
+	}
+	return resp, true
+}
+
+func (c *statsCache) put(key string, resp statsResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]statsResponse)
+	}
+	c.entries[key] = resp
+}
+
+func (c *statsCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = nil
+}
+
+// handlerAdminStats returns platform totals plus a per-day series covering
+// the last ?days= days (default 30), and with billing on a per-week
+// series of Chirpy Red trials, subscriptions, churn and MRR.
+func (s *Server) handlerAdminStats(w http.ResponseWriter, r *http.Request) {
+	days := defaultStatsDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxStatsDays {
+			jsonResponse(w, http.StatusBadRequest, "days must be between 1 and 365")
+			return
+		}
+		days = n
+	}
+
+	key := statsCacheKey(r.Context(), days)
+	if resp, ok := s.statsCache.get(key); ok {
+		jsonResponse(w, http.StatusOK, resp)
+		return
+	}
+
+	resp, err := s.computeStats(r.Context(), days)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	s.statsCache.put(key, resp)
+
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (s *Server) computeStats(ctx context.Context, days int) (statsResponse, error) {
+	now := s.clock.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	since := today.AddDate(0, 0, -(days - 1))
+
+	users, err := s.db.CountUsers(ctx)
+	if err != nil {
+		return statsResponse{}, err
+	}
+	chirps, err := s.db.CountChirps(ctx)
+	if err != nil {
+		return statsResponse{}, err
+	}
+	signups, err := s.db.DailySignups(ctx, since)
+	if err != nil {
+		return statsResponse{}, err
+	}
+	activity, err := s.db.DailyChirpActivity(ctx, since)
+	if err != nil {
+		return statsResponse{}, err
+	}
+
+	// Build every day in the window so days without activity report zeros.
+	daily := make([]dailyStats, days)
+	index := make(map[string]int, days)
+	for i := range daily {
+		date := since.AddDate(0, 0, i).Format(time.DateOnly)
+		daily[i].Date = date
+		index[date] = i
+	}
+	for _, row := range signups {
+		if i, ok := index[row.Day.Format(time.DateOnly)]; ok {
+			daily[i].Signups = row.Signups
+		}
+	}
+	for _, row := range activity {
+		if i, ok := index[row.Day.Format(time.DateOnly)]; ok {
+			daily[i].Chirps = row.Chirps
+			daily[i].ActiveUsers = row.ActiveUsers
+		}
+	}
+
+	resp := statsResponse{
+		GeneratedAt: Timestamp{now},
+		Days:        days,
+		Totals:      statsTotals{Users: users, Chirps: chirps},
+		Daily:       daily,
+	}
+	if s.billing != nil {
+		rows, err := s.db.WeeklyBillingMetrics(ctx, database.WeeklyBillingMetricsParams{Since: since, Until: now})
+		if err != nil {
+			return statsResponse{}, err
+		}
+		resp.Revenue = newWeeklyRevenue(rows)
+	}
+	return resp, nil
+}
+
+func newWeeklyRevenue(rows []database.WeeklyBillingMetricsRow) []weeklyRevenue {
+	ratio := func(n, d int64) *float64 {
+		if d == 0 {
+			return nil
+		}
+		r := float64(n) / float64(d)
+		return &r
+	}
+	weeks := make([]weeklyRevenue, 0, len(rows))
+	for _, row := range rows {
+		weeks = append(weeks, weeklyRevenue{
+			Week:             row.Week.Format(time.DateOnly),
+			TrialsStarted:    row.TrialsStarted,
+			TrialsConverted:  row.TrialsConverted,
+			NewSubscriptions: row.NewSubscriptions,
+			Churned:          row.Churned,
+			Trialing:         row.Trialing,
+			Active:           row.Paying,
+			MRR:              row.Mrr,
+			ConversionRate:   ratio(row.TrialsConverted, row.TrialsEnded),
+			ChurnRate:        ratio(row.Churned, row.PayingAtStart),
+		})
+	}
+	return weeks
+}
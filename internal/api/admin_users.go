@@ -0,0 +1,344 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const defaultSuspension = 24 * time.Hour
+
+type contextKey string
+
+const adminUserKey contextKey = "adminUser"
+
+// middlewareRequireAdmin rejects requests that aren't made with an access
+// token belonging to an admin. The admin is available to the wrapped handler
+// through adminFromContext.
+func (s *Server) middlewareRequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := s.authenticateClaims(r)
+		if err != nil {
+			jsonResponse(w, http.StatusUnauthorized, "Couldn't validate token")
+			return
+		}
+		if claims.ActorID != uuid.Nil {
+			jsonResponse(w, http.StatusForbidden, "Impersonation tokens can't access admin endpoints")
+			return
+		}
+		// Tokens saying their user isn't an admin are turned away without a
+		// lookup, so someone just promoted signs in again. Ones saying they
+		// are get checked, as handlers need the user anyway and a demotion
+		// should take effect at once.
+		if claims.Profile != nil && claims.Profile.Role != RoleAdmin {
+			jsonResponse(w, http.StatusForbidden, "Admin access required")
+			return
+		}
+
+		user, err := s.db.GetUserByID(r.Context(), claims.UserID)
+		if err != nil || user.Role != RoleAdmin {
+			jsonResponse(w, http.StatusForbidden, "Admin access required")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), adminUserKey, user)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+func adminFromContext(ctx context.Context) database.User {
+	user, _ := ctx.Value(adminUserKey).(database.User)
+	return user
+}
+
+type adminUserResponse struct {
+	ID               uuid.UUID  `json:"id"`
+	Email            string     `json:"email"`
+	Handle           string     `json:"handle,omitempty"`
+	Role             string     `json:"role"`
+	CreatedAt        Timestamp  `json:"created_at"`
+	UpdatedAt        Timestamp  `json:"updated_at"`
+	BannedAt         *Timestamp `json:"banned_at"`
+	SuspendedUntil   *Timestamp `json:"suspended_until"`
+	ModerationReason string     `json:"moderation_reason"`
+	Shadowbanned     bool       `json:"shadowbanned"`
+	Verified         bool       `json:"verified"`
+	IsChirpyRed      bool       `json:"is_chirpy_red"`
+}
+
+func newAdminUserResponse(u database.User) adminUserResponse {
+	return adminUserResponse{
+		ID:               u.ID,
+		Email:            u.Email,
+		Handle:           u.Handle.String,
+		Role:             u.Role,
+		CreatedAt:        Timestamp{u.CreatedAt},
+		UpdatedAt:        Timestamp{u.UpdatedAt},
+		BannedAt:         nullTimestamp(u.BannedAt),
+		SuspendedUntil:   nullTimestamp(u.SuspendedUntil),
+		ModerationReason: u.ModerationReason,
+		Shadowbanned:     u.Shadowbanned,
+		Verified:         u.Verified,
+		IsChirpyRed:      u.IsChirpyRed,
+	}
+}
+
+func (s *Server) handlerAdminUsersList(w http.ResponseWriter, r *http.Request) {
+	users, err := s.db.ListUsers(r.Context())
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	resp := make([]adminUserResponse, 0, len(users))
+	for _, u := range users {
+		resp = append(resp, newAdminUserResponse(u))
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+type moderationRequest struct {
+	Reason   string `json:"reason"`
+	Duration string `json:"duration"`
+}
+
+func (s *Server) handlerAdminUserBan(w http.ResponseWriter, r *http.Request) {
+	s.moderateUser(w, r, "user.ban", func(q database.Querier, id uuid.UUID, req moderationRequest) (database.User, error) {
+		return q.BanUser(r.Context(), database.BanUserParams{
+			ID:               id,
+			ModerationReason: req.Reason,
+		})
+	})
+}
+
+func (s *Server) handlerAdminUserSuspend(w http.ResponseWriter, r *http.Request) {
+	s.moderateUser(w, r, "user.suspend", func(q database.Querier, id uuid.UUID, req moderationRequest) (database.User, error) {
+		duration := defaultSuspension
+		if req.Duration != "" {
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil || d <= 0 {
+				return database.User{}, errInvalidDuration
+			}
+			duration = d
+		}
+
+		return q.SuspendUser(r.Context(), database.SuspendUserParams{
+			ID:               id,
+			SuspendedUntil:   sql.NullTime{Time: s.clock.Now().UTC().Add(duration), Valid: true},
+			ModerationReason: req.Reason,
+		})
+	})
+}
+
+func (s *Server) handlerAdminUserUnban(w http.ResponseWriter, r *http.Request) {
+	s.moderateUser(w, r, "user.unban", func(q database.Querier, id uuid.UUID, req moderationRequest) (database.User, error) {
+		return q.UnbanUser(r.Context(), id)
+	})
+}
+
+// handlerAdminUserShadowban hides a user's chirps from everyone but
+// themselves, without telling them.
+func (s *Server) handlerAdminUserShadowban(w http.ResponseWriter, r *http.Request) {
+	s.moderateUser(w, r, "user.shadowban", func(q database.Querier, id uuid.UUID, req moderationRequest) (database.User, error) {
+		return q.SetUserShadowbanned(r.Context(), database.SetUserShadowbannedParams{
+			ID:           id,
+			Shadowbanned: true,
+		})
+	})
+}
+
+func (s *Server) handlerAdminUserUnshadowban(w http.ResponseWriter, r *http.Request) {
+	s.moderateUser(w, r, "user.unshadowban", func(q database.Querier, id uuid.UUID, req moderationRequest) (database.User, error) {
+		return q.SetUserShadowbanned(r.Context(), database.SetUserShadowbannedParams{
+			ID:           id,
+			Shadowbanned: false,
+		})
+	})
+}
+
+func (s *Server) handlerAdminUserVerify(w http.ResponseWriter, r *http.Request) {
+	s.moderateUser(w, r, "user.verify", func(q database.Querier, id uuid.UUID, req moderationRequest) (database.User, error) {
+		return q.SetUserVerified(r.Context(), database.SetUserVerifiedParams{
+			ID:       id,
+			Verified: true,
+		})
+	})
+}
+
+func (s *Server) handlerAdminUserUnverify(w http.ResponseWriter, r *http.Request) {
+	s.moderateUser(w, r, "user.unverify", func(q database.Querier, id uuid.UUID, req moderationRequest) (database.User, error) {
+		return q.SetUserVerified(r.Context(), database.SetUserVerifiedParams{
+			ID:       id,
+			Verified: false,
+		})
+	})
+}
+
+// handlerAdminUserUpgrade grants a user Chirpy Red.
+func (s *Server) handlerAdminUserUpgrade(w http.ResponseWriter, r *http.Request) {
+	s.moderateUser(w, r, "user.upgrade", func(q database.Querier, id uuid.UUID, req moderationRequest) (database.User, error) {
+		return q.SetUserChirpyRed(r.Context(), database.SetUserChirpyRedParams{
+			ID:          id,
+			IsChirpyRed: true,
+		})
+	})
+}
+
+func (s *Server) handlerAdminUserDowngrade(w http.ResponseWriter, r *http.Request) {
+	s.moderateUser(w, r, "user.downgrade", func(q database.Querier, id uuid.UUID, req moderationRequest) (database.User, error) {
+		return q.SetUserChirpyRed(r.Context(), database.SetUserChirpyRedParams{
+			ID:          id,
+			IsChirpyRed: false,
+		})
+	})
+}
+
+var errInvalidDuration = errors.New("invalid duration")
+
+// moderateUser applies a moderation change to the user in the {userID} path
+// parameter and records it in the audit log within the same transaction.
+func (s *Server) moderateUser(w http.ResponseWriter, r *http.Request, action string, apply func(database.Querier, uuid.UUID, moderationRequest) (database.User, error)) {
+	userID, ok := pathUUID(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	var req moderationRequest
+	if err := s.decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	user, err := apply(tx, userID, req)
+	if errors.Is(err, errInvalidDuration) {
+		jsonResponse(w, http.StatusBadRequest, "Invalid suspension duration")
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "User was not found.")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	admin := adminFromContext(ctx)
+	err = recordAudit(ctx, tx, admin.ID, action, user.ID, map[string]interface{}{
+		"reason":          req.Reason,
+		"suspended_until": nullTimestamp(user.SuspendedUntil),
+	})
+	if err != nil {
+		fmt.Println("Error recording audit entry:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	// Bans, shadowbans and badges all change what listings show.
+	s.responseCache.invalidate()
+
+	jsonResponse(w, http.StatusOK, newAdminUserResponse(user))
+}
+
+const purgeBatchSize = 500
+
+type purgeProgress struct {
+	Purged int64  `json:"purged"`
+	Done   bool   `json:"done"`
+	Error  string `json:"error,omitempty"`
+}
+
+// handlerAdminUserChirpsPurge soft-deletes every chirp by a user in batches
+// of purgeBatchSize, each in its own short transaction so the table is never
+// locked for long. Progress is streamed as one JSON object per line.
+func (s *Server) handlerAdminUserChirpsPurge(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathUUID(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	var req moderationRequest
+	if err := s.decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+
+	ctx := r.Context()
+	if _, err := s.db.GetUserByID(ctx, userID); errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "User was not found.")
+		return
+	} else if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	noWriteDeadline(w)
+	noRequestTimeout(r)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	var purged int64
+	var purgeErr error
+	for {
+		n, err := s.db.SoftDeleteUserChirpsBatch(ctx, database.SoftDeleteUserChirpsBatchParams{
+			UserID: userID,
+			Limit:  purgeBatchSize,
+		})
+		if err != nil {
+			purgeErr = err
+			break
+		}
+		if n == 0 {
+			break
+		}
+		purged += n
+		s.responseCache.invalidate()
+
+		enc.Encode(purgeProgress{Purged: purged})
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	// Record what was actually purged even if the client went away or a
+	// batch failed part-way through.
+	admin := adminFromContext(ctx)
+	err := recordAudit(context.WithoutCancel(ctx), s.db, admin.ID, "user.chirps_purge", userID, map[string]interface{}{
+		"reason":   req.Reason,
+		"purged":   purged,
+		"complete": purgeErr == nil,
+	})
+	if err != nil {
+		fmt.Println("Error recording audit entry:", err)
+	}
+
+	if purgeErr != nil {
+		fmt.Println("Error purging chirps:", purgeErr)
+		enc.Encode(purgeProgress{Purged: purged, Error: "purge stopped before completion"})
+		return
+	}
+	enc.Encode(purgeProgress{Purged: purged, Done: true})
+}
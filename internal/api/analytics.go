@@ -0,0 +1,17 @@
+package api
+
+import (
+	"chirpy/internal/analytics"
+
+	"github.com/google/uuid"
+)
+
+// track records the product event name for userID.
+func (s *Server) track(name string, userID uuid.UUID, props map[string]string) {
+	s.analytics.Record(analytics.Event{
+		Name:       name,
+		UserID:     userID,
+		Time:       s.clock.Now(),
+		Properties: props,
+	})
+}
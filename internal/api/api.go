@@ -0,0 +1,917 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"chirpy/internal/analytics"
+	"chirpy/internal/auth"
+	"chirpy/internal/contentfilter"
+	"chirpy/internal/database"
+	"chirpy/internal/fieldset"
+	"chirpy/internal/jsonstream"
+	"chirpy/internal/linkscan"
+	"chirpy/internal/mail"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type UserResponse struct {
+	ID          string    `json:"id"`
+	Email       string    `json:"email"`
+	CreatedAt   Timestamp `json:"created_at"`
+	UpdatedAt   Timestamp `json:"updated_at"`
+	Handle      string    `json:"handle,omitempty"`
+	Verified    bool      `json:"verified"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	NoAds       bool      `json:"no_ads"`
+	AvatarURL   string    `json:"avatar_url"`
+	Token       string    `json:"token,omitempty"`
+}
+
+const RoleAdmin = "admin"
+
+// AccessTokenTTL is how long the tokens issued at login last.
+const AccessTokenTTL = time.Hour
+
+// authenticate returns the ID of the user the request's bearer token was
+// issued to.
+func (s *Server) authenticate(r *http.Request) (uuid.UUID, error) {
+	userID, _, err := s.authenticateWithActor(r)
+	return userID, err
+}
+
+// authenticateWithActor is authenticate plus the admin behind an
+// impersonation token, or uuid.Nil for ordinary tokens.
+func (s *Server) authenticateWithActor(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	claims, err := s.authenticateClaims(r)
+	return claims.UserID, claims.ActorID, err
+}
+
+// authenticateClaims returns everything the request's bearer token says,
+// including the profile of the user it was issued to, if it has one.
+func (s *Server) authenticateClaims(r *http.Request) (auth.Claims, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+	return s.tokenIssuer(r.Context()).Validate(token)
+}
+
+// tokenProfile is what the tokens issued to u say about them.
+func tokenProfile(u database.User) auth.Profile {
+	return auth.Profile{
+		Email:       u.Email,
+		Role:        u.Role,
+		IsChirpyRed: planFor(u).ChirpyRed,
+	}
+}
+
+// viewerID returns the authenticated user's ID, or uuid.Nil for anonymous
+// requests. Use it on public endpoints whose results depend on who is asking.
+func (s *Server) viewerID(r *http.Request) uuid.UUID {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userID
+}
+
+// recordAudit writes an audit_log entry for an action performed by actorID.
+// Pass a transaction-scoped q so the entry commits with the action itself.
+func recordAudit(ctx context.Context, q database.Querier, actorID uuid.UUID, action string, targetID uuid.UUID, details interface{}) error {
+	raw, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	return q.CreateAuditLogEntry(ctx, database.CreateAuditLogEntryParams{
+		ID:       uuid.New(),
+		ActorID:  actorID,
+		Action:   action,
+		TargetID: uuid.NullUUID{UUID: targetID, Valid: targetID != uuid.Nil},
+		Details:  raw,
+	})
+}
+
+type UserRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Handle   string `json:"handle"`
+	// TermsVersion and PrivacyVersion are the versions of the legal
+	// documents accepted at signup. They are required once published.
+	TermsVersion   int32 `json:"terms_version,omitempty"`
+	PrivacyVersion int32 `json:"privacy_version,omitempty"`
+}
+
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+
+// normalizeHandle lower-cases a requested handle and reports whether it is
+// acceptable: 3-30 letters, digits or underscores.
+func normalizeHandle(handle string) (string, bool) {
+	handle = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+	return handle, handlePattern.MatchString(handle)
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// checkCredentials returns the user signing in with email and password, or
+// the status and message to turn them away with.
+func (s *Server) checkCredentials(r *http.Request, email, password string) (database.User, int, string) {
+	user, err := s.db.GetUserByEmail(r.Context(), email)
+	if err != nil {
+		s.recordIPActivity(r, s.db.RecordIPLoginFailure)
+		return database.User{}, http.StatusUnauthorized, "Incorrect email or password"
+	}
+
+	passwordValid, err := auth.CheckPasswordHash(password, user.HashedPassword)
+	if err != nil || passwordValid == false {
+		s.recordIPActivity(r, s.db.RecordIPLoginFailure)
+		return database.User{}, http.StatusUnauthorized, "Incorrect email or password"
+	}
+
+	if user.BannedAt.Valid {
+		return database.User{}, http.StatusForbidden, "This account has been banned"
+	}
+	if user.SuspendedUntil.Valid && user.SuspendedUntil.Time.After(s.clock.Now().UTC()) {
+		return database.User{}, http.StatusForbidden, "This account is suspended until " + user.SuspendedUntil.Time.Format(time.RFC3339)
+	}
+	return user, 0, ""
+}
+
+func (s *Server) handlerLogin(w http.ResponseWriter, r *http.Request) {
+	var req UserRequest
+	err := s.decodeJSON(r, &req)
+	if err != nil {
+		http.Error(w, decodeErrorMessage(err, "Invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	if req.Email == "" || !IsValidEmail(req.Email) {
+		http.Error(w, "Invalid or missing email address", http.StatusBadRequest)
+		return
+	}
+
+	if req.Password == "" {
+		http.Error(w, "Invalid or missing password", http.StatusBadRequest)
+		return
+	}
+	user, code, msg := s.checkCredentials(r, req.Email, req.Password)
+	if code != 0 {
+		http.Error(w, msg, code)
+		return
+	}
+
+	token, err := s.tokenIssuer(r.Context()).Issue(user.ID, tokenProfile(user), AccessTokenTTL)
+	if err != nil {
+		http.Error(w, "Couldn't create access token", http.StatusInternalServerError)
+		return
+	}
+	s.track(analytics.Login, user.ID, nil)
+
+	response := UserResponse{
+		ID:          user.ID.String(),
+		Email:       user.Email,
+		CreatedAt:   Timestamp{user.CreatedAt},
+		UpdatedAt:   Timestamp{user.UpdatedAt},
+		Handle:      user.Handle.String,
+		Verified:    user.Verified,
+		IsChirpyRed: planFor(user).ChirpyRed,
+		NoAds:       planFor(user).NoAds,
+		AvatarURL:   s.avatarURL(s.config.PublicURL, user),
+		Token:       token,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) adminResetHandler(w http.ResponseWriter, r *http.Request) {
+	if s.config.Platform != "dev" {
+		http.Error(w, "Forbidden: This endpoint is only accessible in development environments.", http.StatusForbidden)
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Failed to delete users: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.DeleteAllUsers(ctx)
+	if err != nil {
+		tx.Rollback()
+		http.Error(w, "Failed to delete users: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		http.Error(w, "Failed to commit transaction: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.responseCache.invalidate()
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("All users deleted successfully."))
+}
+
+func (s *Server) createUserHandler(w http.ResponseWriter, r *http.Request) {
+	var req UserRequest
+	err := s.decodeJSON(r, &req)
+	if err != nil {
+		http.Error(w, decodeErrorMessage(err, "Invalid request body"), http.StatusBadRequest)
+		return
+	}
+
+	if req.Email == "" || !IsValidEmail(req.Email) {
+		http.Error(w, "Invalid or missing email address", http.StatusBadRequest)
+		return
+	}
+
+	if req.Password == "" {
+		http.Error(w, "Invalid or missing password", http.StatusBadRequest)
+		return
+	}
+	var handle string
+	if req.Handle != "" {
+		var ok bool
+		if handle, ok = normalizeHandle(req.Handle); !ok {
+			http.Error(w, "Handle must be 3-30 letters, digits or underscores", http.StatusBadRequest)
+			return
+		}
+		// Old handles stay with their users for a while after a change.
+		reserved, err := s.db.IsHandleReserved(r.Context(), handle)
+		if err != nil {
+			http.Error(w, "Failed to create user", http.StatusInternalServerError)
+			return
+		}
+		if reserved {
+			http.Error(w, "Handle is already taken", http.StatusConflict)
+			return
+		}
+	}
+	legal := s.legal.Load()
+	accepted := legalAcceptance{TermsVersion: req.TermsVersion, PrivacyVersion: req.PrivacyVersion}.versions()
+	if legal != nil && !checkLegalAcceptance(legal, legal.versions, accepted) {
+		jsonResponse(w, http.StatusConflict, legalRequiredResponse{
+			Error:    "Accept the current terms of service and privacy policy to sign up",
+			Required: legal.versions,
+		})
+		return
+	}
+
+	// Generate UUID
+
+	userID := uuid.New()
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		http.Error(w, "Error validating password", http.StatusBadRequest)
+		return
+	}
+
+	// Actually save to database!
+	user, err := s.db.CreateUser(r.Context(), database.CreateUserParams{
+		ID:             userID,
+		Email:          req.Email,
+		HashedPassword: hash,
+		Handle:         nullString(handle),
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "users_handle_key" {
+		http.Error(w, "Handle is already taken", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error creating user:", err)
+		http.Error(w, "Failed to create user", http.StatusInternalServerError)
+		return
+	}
+	s.recordIPActivity(r, s.db.RecordIPSignup)
+	if legal != nil {
+		// Without a record the user is asked to accept again, so this
+		// doesn't fail the signup.
+		if err := s.recordLegalAcceptance(r.Context(), r, user.ID, accepted); err != nil {
+			fmt.Println("Error recording legal acceptance:", err)
+		}
+	}
+	s.track(analytics.Signup, user.ID, map[string]string{"handle": strconv.FormatBool(handle != "")})
+	err = s.enqueueEmail(r.Context(), s.db, mail.TemplateWelcome, user.Email, mail.TemplateData{
+		Name: preferredUsername(user),
+	})
+	if err != nil {
+		fmt.Println("Error queueing welcome email:", err)
+	}
+
+	response := UserResponse{
+		ID:          user.ID.String(),
+		Email:       user.Email,
+		CreatedAt:   Timestamp{user.CreatedAt},
+		UpdatedAt:   Timestamp{user.UpdatedAt},
+		Handle:      user.Handle.String,
+		Verified:    user.Verified,
+		IsChirpyRed: planFor(user).ChirpyRed,
+		NoAds:       planFor(user).NoAds,
+		AvatarURL:   s.avatarURL(s.config.PublicURL, user),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// IsValidEmail is a loose sanity check of an email address: something
+// before an "@" and a "." somewhere after it.
+func IsValidEmail(email string) bool {
+	// Check for presence of '@' and at least one '.' after '@'
+	atIndex := -1
+	for i, r := range email {
+		if r == '@' {
+			atIndex = i
+			break
+		}
+	}
+	if atIndex == -1 || atIndex == 0 || atIndex == len(email)-1 {
+		return false // No '@', or '@' at start/end
+	}
+
+	dotIndex := -1
+	for i := atIndex + 1; i < len(email); i++ {
+		if email[i] == '.' {
+			dotIndex = i
+			break
+		}
+	}
+	// Dot must exist after '@' and not be the last character
+	return dotIndex != -1 && dotIndex < len(email)-1 && dotIndex > atIndex
+}
+
+func (s *Server) middlewareMetricsInc(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+type poolStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMS     int64 `json:"wait_duration_ms"`
+}
+
+func (s *Server) dbPoolStats() poolStats {
+	stats := s.db.Stats()
+	return poolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMS:     stats.WaitDuration.Milliseconds(),
+	}
+}
+
+func (s *Server) adminMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	hits, err := s.hits.Value(r.Context())
+	if err != nil {
+		fmt.Println("Error reading hits:", err)
+	}
+	s.renderPage(w, "metrics.html", map[string]interface{}{
+		"Hits":         hits,
+		"Pool":         s.dbPoolStats(),
+		"Cache":        s.responseCache.stats(),
+		"CacheEnabled": s.responseCache != nil,
+		"Retention":    s.retention.snapshot(),
+		"Breakers":     s.dbBreakerStats(),
+		"Deprecations": s.deprecations.snapshot(),
+		"SlowQueries":  s.slowQueries.snapshot(),
+		"SlowEnabled":  s.slowQueries != nil,
+	})
+}
+
+type readinessResponse struct {
+	Status   string                  `json:"status"`
+	Database poolStats               `json:"database"`
+	Breakers map[string]breakerStats `json:"breakers,omitempty"`
+}
+
+// handlerReadiness reports whether the database is reachable, along with the
+// connection pool counters so pool exhaustion shows up before requests fail,
+// and the state of the database breakers.
+func (s *Server) handlerReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	resp := readinessResponse{Status: "ok"}
+	statusCode := http.StatusOK
+	if err := s.db.Ping(ctx); err != nil {
+		resp.Status = "unavailable"
+		statusCode = http.StatusServiceUnavailable
+	}
+	resp.Database = s.dbPoolStats()
+	resp.Breakers = s.dbBreakerStats()
+
+	jsonResponse(w, statusCode, resp)
+}
+
+func (s *Server) resetHandler(w http.ResponseWriter, r *http.Request) {
+	if err := s.hits.Reset(r.Context()); err != nil {
+		fmt.Println("Error resetting hits:", err)
+		http.Error(w, "Failed to reset hits", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "Hits reset to 0")
+}
+
+type chirpRequest struct {
+	Body   string    `json:"body"`
+	UserID uuid.UUID `json:"user_id"`
+	// Lat and Lon optionally geotag the chirp. Both or neither must be set.
+	Lat *float64 `json:"lat,omitempty"`
+	Lon *float64 `json:"lon,omitempty"`
+	// Sensitive marks the chirp so readers see it collapsed. A content
+	// warning is shown in its place and implies Sensitive.
+	Sensitive      bool   `json:"sensitive,omitempty"`
+	ContentWarning string `json:"content_warning,omitempty"`
+	// Media attaches files the author uploaded to /api/media, in order.
+	Media []mediaAttachment `json:"media,omitempty"`
+}
+
+type chirpResponse struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      Timestamp `json:"created_at"`
+	UpdatedAt      Timestamp `json:"updated_at"`
+	Body           string    `json:"body"`
+	UserID         uuid.UUID `json:"user_id"`
+	AuthorVerified bool      `json:"author_verified"`
+	// ShortURL redirects to the chirp, counting the click.
+	ShortURL string `json:"short_url,omitempty"`
+	// Location is only set on responses that read it.
+	Location *chirpLocation `json:"location,omitempty"`
+	// Sensitive asks clients to collapse the chirp, showing ContentWarning
+	// in its place.
+	Sensitive      bool   `json:"sensitive,omitempty"`
+	ContentWarning string `json:"content_warning,omitempty"`
+	// Media and Reactions are included in single-chirp responses and on
+	// paged timelines, but not in the full chirp list.
+	Media     []mediaResponse  `json:"media,omitempty"`
+	Reactions map[string]int64 `json:"reactions,omitempty"`
+	// PostedBy is the member who posted an organization's chirp. It's only
+	// shown to the organization's members.
+	PostedBy *uuid.UUID `json:"posted_by,omitempty"`
+}
+
+// handlerChirpsList streams every visible chirp, writing each one as its
+// row is scanned so memory use doesn't grow with the table.
+func (s *Server) handlerChirpsList(w http.ResponseWriter, r *http.Request) {
+	fields, ok := requestedFields(w, r, chirpResponse{})
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	chirps := jsonstream.NewArray(w)
+	err := s.db.EachChirp(r.Context(), s.viewerID(r), func(c database.GetChirpsRow) error {
+		chirp, err := fieldset.Project(chirpResponse{
+			ID:             c.ID,
+			CreatedAt:      Timestamp{c.CreatedAt},
+			UpdatedAt:      Timestamp{c.UpdatedAt},
+			Body:           c.Body,
+			UserID:         c.UserID,
+			AuthorVerified: c.AuthorVerified,
+			ShortURL:       s.shortURL(r.Context(), c.ID),
+			Sensitive:      c.Sensitive,
+			ContentWarning: c.ContentWarning,
+		}, fields)
+		if err != nil {
+			return err
+		}
+		return chirps.Write(chirp)
+	})
+	if err != nil {
+		if chirps.Len() == 0 {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		// The status is already sent, so all we can do is cut the array
+		// short; clients see a JSON syntax error rather than a partial list.
+		if !clientGone(r) {
+			fmt.Println("Error listing chirps:", err)
+		}
+		skipResponseCache(w)
+		return
+	}
+	chirps.Close() // [] on empty, not null
+}
+
+func (s *Server) handlerGetChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	chirp, err := s.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: s.viewerID(r),
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusNotFound, "Chirp was not found.")
+		return
+	}
+	// Reactions don't touch updated_at, so a 304 can carry stale counts;
+	// clients wanting them fresh should skip If-Modified-Since.
+	if notModified(w, r, chirp.UpdatedAt) {
+		return
+	}
+	attached, err := s.chirpMedia(r.Context(), chirp.ID)
+	if err != nil {
+		fmt.Println("Error listing chirp media:", err)
+	}
+	reactions, err := s.reactionCounts(r.Context(), chirp.ID)
+	if err != nil {
+		fmt.Println("Error counting reactions:", err)
+	}
+
+	response := chirpResponse{
+		ID:             chirp.ID,
+		CreatedAt:      Timestamp{chirp.CreatedAt},
+		UpdatedAt:      Timestamp{chirp.UpdatedAt},
+		Body:           chirp.Body,
+		UserID:         chirp.UserID,
+		AuthorVerified: chirp.AuthorVerified,
+		ShortURL:       s.shortURL(r.Context(), chirp.ID),
+		Sensitive:      chirp.Sensitive,
+		ContentWarning: chirp.ContentWarning,
+		Media:          attached,
+		Reactions:      reactions,
+	}
+	jsonFieldsResponse(w, r, http.StatusOK, response)
+}
+
+var (
+	errChirpTooLong = errors.New("chirp is too long")
+	errChirpBlocked = errors.New("chirp contains blocked content")
+)
+
+// prepareChirpBody validates body against the author's maxLen and applies
+// profanity masking and the content rules. It returns the text to store and
+// any rules that flagged it.
+func (s *Server) prepareChirpBody(body string, maxLen int) (string, []contentfilter.Rule, error) {
+	// Validate chirp length
+	if len(body) > maxLen {
+		return "", nil, errChirpTooLong
+	}
+
+	profane := map[string]struct{}{
+		"kerfuffle": {},
+		"sharbert":  {},
+		"fornax":    {},
+	}
+
+	// split on a single space so punctuation tokens (e.g., "Sharbert!") are NOT matched
+	parts := strings.Split(body, " ")
+	for i, tok := range parts {
+		if _, bad := profane[strings.ToLower(tok)]; bad {
+			parts[i] = "****"
+		}
+	}
+	cleaned := strings.Join(parts, " ")
+
+	filtered := s.contentFilter.Load().Apply(cleaned)
+	if filtered.Rejected {
+		return "", nil, errChirpBlocked
+	}
+	return filtered.Body, filtered.Flagged, nil
+}
+
+// createChirp stores body as a chirp by userID and queues its federation.
+// Both the REST and GraphQL APIs create chirps through here so they apply
+// the same rules.
+func (s *Server) createChirp(ctx context.Context, userID uuid.UUID, body string) (database.Chirp, database.User, error) {
+	// The author decides the length limit and the badge, so look them up
+	// rather than joining in the insert. An unknown author gets the free plan.
+	author, _ := s.db.GetUserByID(ctx, userID)
+	cleaned, flagged, err := s.prepareChirpBody(body, s.maxChirpLength(author))
+	if err != nil {
+		return database.Chirp{}, database.User{}, err
+	}
+
+	chirp, err := s.insertChirp(ctx, author, database.CreateChirpParams{
+		ID:     uuid.New(),
+		Body:   cleaned,
+		UserID: userID,
+	})
+	if err != nil {
+		return database.Chirp{}, database.User{}, err
+	}
+	s.flagChirp(ctx, chirp.ID, flagged)
+	s.responseCache.invalidate()
+	s.track(analytics.ChirpCreated, userID, nil)
+	return chirp, author, nil
+}
+
+// insertChirp stores the chirp and, in the same transaction, its links and
+// the outbox events to scan them and, when the author federates, to
+// federate it.
+func (s *Server) insertChirp(ctx context.Context, author database.User, params database.CreateChirpParams) (database.Chirp, error) {
+	federate := s.federationEnabled() && !author.Shadowbanned && !author.BannedAt.Valid
+	links := linkscan.ExtractURLs(params.Body)
+	if !federate && len(links) == 0 {
+		return s.db.CreateChirp(ctx, params)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	defer tx.Rollback()
+
+	chirp, err := tx.CreateChirp(ctx, params)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if federate {
+		if err := recordChirpCreated(ctx, tx, chirp); err != nil {
+			return database.Chirp{}, err
+		}
+	}
+	if err := s.recordChirpLinks(ctx, tx, chirp.ID, links); err != nil {
+		return database.Chirp{}, err
+	}
+	return chirp, tx.Commit()
+}
+
+func (s *Server) handlerChirpsCreate(w http.ResponseWriter, r *http.Request) {
+	var request chirpRequest
+
+	if err := s.decodeJSON(r, &request); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Something went wrong"))
+		return
+	}
+
+	var location *chirpLocation
+	if request.Lat != nil || request.Lon != nil {
+		if request.Lat == nil || request.Lon == nil {
+			jsonResponse(w, http.StatusBadRequest, "lat and lon must be set together")
+			return
+		}
+		location = &chirpLocation{Lat: *request.Lat, Lon: *request.Lon}
+		if !location.valid() {
+			jsonResponse(w, http.StatusBadRequest, "lat and lon must be a valid latitude and longitude")
+			return
+		}
+		switch err := s.checkLocationSharing(r, request.UserID); {
+		case errors.Is(err, errLocationNotShared):
+			jsonResponse(w, http.StatusBadRequest, "Turn on location sharing to geotag chirps")
+			return
+		case err != nil:
+			fmt.Println("Error checking location sharing:", err)
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	}
+
+	cw, err := newContentWarning(request.Sensitive, request.ContentWarning)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, "Content warning is too long")
+		return
+	}
+	switch err := s.checkAttachments(r.Context(), request.UserID, request.Media); {
+	case errors.Is(err, errTooManyMedia):
+		jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("A chirp can have at most %d media attachments", maxChirpMedia))
+		return
+	case errors.Is(err, errAltTextRequired):
+		jsonResponse(w, http.StatusBadRequest, "Describe each attachment with alt_text")
+		return
+	case errors.Is(err, errAltTextTooLong):
+		jsonResponse(w, http.StatusBadRequest, "Alt text is too long")
+		return
+	case errors.Is(err, errMediaNotFound):
+		jsonResponse(w, http.StatusBadRequest, "Media was not found or is already attached")
+		return
+	case err != nil:
+		fmt.Println("Error checking media:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	chirp, author, err := s.createChirp(r.Context(), request.UserID, request.Body)
+	switch {
+	case errors.Is(err, errChirpTooLong):
+		jsonResponse(w, http.StatusBadRequest, "Chirp is too long")
+		return
+	case errors.Is(err, errChirpBlocked):
+		jsonResponse(w, http.StatusBadRequest, "Chirp contains blocked content")
+		return
+	case err != nil:
+		// Log the actual error to see what's wrong
+		fmt.Println("Error creating chirp:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if location != nil {
+		err := s.db.CreateChirpLocation(r.Context(), database.CreateChirpLocationParams{
+			ChirpID:   chirp.ID,
+			Latitude:  location.Lat,
+			Longitude: location.Lon,
+		})
+		if err != nil {
+			fmt.Println("Error saving chirp location:", err)
+			location = nil
+		}
+	}
+	if !s.markSensitive(r.Context(), chirp.ID, cw) {
+		cw = contentWarning{}
+	}
+	attached := s.attachMedia(r.Context(), chirp.ID, request.UserID, request.Media)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	response := chirpResponse{
+		ID:             chirp.ID,
+		CreatedAt:      Timestamp{chirp.CreatedAt},
+		UpdatedAt:      Timestamp{chirp.UpdatedAt},
+		Body:           chirp.Body,
+		UserID:         chirp.UserID,
+		AuthorVerified: author.Verified,
+		ShortURL:       s.shortURL(r.Context(), chirp.ID),
+		Location:       location,
+		Sensitive:      cw.Sensitive,
+		ContentWarning: cw.Warning,
+		Media:          attached,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// handlerChirpsDelete deletes a chirp. Authors may delete their own chirps;
+// admins may delete anyone's, which is recorded in the audit log. Apps
+// acting for an admin only get the author's rights: moderating is
+// first-party only, like the rest of /admin/.
+func (s *Server) handlerChirpsDelete(w http.ResponseWriter, r *http.Request) {
+	claims, err := s.authenticateClaims(r)
+	userID := claims.UserID
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Couldn't validate token")
+		return
+	}
+
+	chirpID, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	chirp, err := s.db.GetChirp(ctx, chirpID)
+	if err != nil {
+		jsonResponse(w, http.StatusNotFound, "Chirp was not found.")
+		return
+	}
+
+	// An organization's owners can delete its chirps as if they wrote them.
+	ownChirp := chirp.UserID == userID
+	if !ownChirp {
+		role, err := s.orgRole(ctx, s.db, chirp.UserID, userID)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		ownChirp = role == orgRoleOwner
+	}
+	if ownChirp {
+		if err := s.db.DeleteChirp(ctx, chirpID); err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		s.responseCache.invalidate()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if claims.ClientID != "" {
+		jsonResponse(w, http.StatusForbidden, "You can't delete this chirp")
+		return
+	}
+	// As in middlewareRequireAdmin, a token saying its user isn't an admin
+	// is believed, but one saying they are is checked, so a demotion takes
+	// effect at once.
+	if claims.Profile != nil && claims.Profile.Role != RoleAdmin {
+		jsonResponse(w, http.StatusForbidden, "You can't delete this chirp")
+		return
+	}
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Couldn't validate token")
+		return
+	}
+	if user.Role != RoleAdmin {
+		jsonResponse(w, http.StatusForbidden, "You can't delete this chirp")
+		return
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	if err := tx.DeleteChirp(ctx, chirpID); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	err = recordAudit(ctx, tx, userID, "chirp.delete", chirpID, map[string]interface{}{
+		"author_id": chirp.UserID,
+		"body":      chirp.Body,
+		"reason":    r.URL.Query().Get("reason"),
+	})
+	if err != nil {
+		fmt.Println("Error recording audit entry:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	s.responseCache.invalidate()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerChirpsStream streams chirp events to the client as Server-Sent
+// Events. Events arrive through Postgres NOTIFY, so chirps written by any
+// instance are delivered.
+func (s *Server) handlerChirpsStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		jsonResponse(w, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+
+	events, unsubscribe := s.hub.Subscribe()
+	defer unsubscribe()
+
+	noWriteDeadline(w)
+	noRequestTimeout(r)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if !strings.HasPrefix(e.Type, "chirp.") || !inTenant(r.Context(), e) {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, e.Data)
+			flusher.Flush()
+		}
+	}
+}
+
+// noWriteDeadline lifts the server's write timeout for a long-lived
+// streaming response. Writers that can't set deadlines are left alone.
+func noWriteDeadline(w http.ResponseWriter) {
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+}
+
+func jsonResponse(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	jsonResponseBody, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(jsonResponseBody)
+}
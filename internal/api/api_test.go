@@ -0,0 +1,552 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"chirpy/internal/analytics"
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// fakeStore serves the queries a test stubs; any other query panics on the
+// nil embedded Querier, which points straight at the missing stub.
+type fakeStore struct {
+	database.Querier
+	users         map[string]database.User
+	pingErr       error
+	chirps        []database.GetChirpsRow
+	chirpsErr     error
+	loginFailures []string
+	// redirects maps old handles to current ones.
+	redirects map[string]string
+}
+
+func (f *fakeStore) EachChirp(ctx context.Context, viewerID uuid.UUID, fn func(database.GetChirpsRow) error) error {
+	for _, c := range f.chirps {
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	return f.chirpsErr
+}
+
+func (f *fakeStore) Begin(ctx context.Context) (Tx, error) {
+	return nil, errors.New("fakeStore: transactions are not supported")
+}
+
+func (f *fakeStore) Ping(ctx context.Context) error {
+	return f.pingErr
+}
+
+func (f *fakeStore) Stats() sql.DBStats {
+	return sql.DBStats{OpenConnections: 1}
+}
+
+func (f *fakeStore) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	user, ok := f.users[email]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (f *fakeStore) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	for _, u := range f.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetUserByHandle(ctx context.Context, handle sql.NullString) (database.User, error) {
+	for _, u := range f.users {
+		if u.Handle.Valid && u.Handle == handle {
+			return u, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (f *fakeStore) GetHandleRedirect(ctx context.Context, handle string) (sql.NullString, error) {
+	current, ok := f.redirects[handle]
+	if !ok {
+		return sql.NullString{}, sql.ErrNoRows
+	}
+	return sql.NullString{String: current, Valid: true}, nil
+}
+
+func (f *fakeStore) RecordIPLoginFailure(ctx context.Context, ip string) error {
+	f.loginFailures = append(f.loginFailures, ip)
+	return nil
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+var testNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestServer(t *testing.T, store *fakeStore) http.Handler {
+	t.Helper()
+	cfg := &config.Config{Platform: "dev", JWTSecret: "test-secret"}
+	return NewRouter(NewServer(cfg, Deps{
+		Store: store,
+		Clock: fixedClock(testNow),
+	}))
+}
+
+func newTestUser(t *testing.T, email, password string) database.User {
+	t.Helper()
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		t.Fatalf("HashPassword returned error: %v", err)
+	}
+	return database.User{ID: uuid.New(), Email: email, HashedPassword: hash}
+}
+
+func do(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHealthz(t *testing.T) {
+	rec := do(newTestServer(t, &fakeStore{}), http.MethodGet, "/api/healthz", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+}
+
+func TestRouter_JSONErrors(t *testing.T) {
+	h := newTestServer(t, &fakeStore{})
+
+	rec := do(h, http.MethodDelete, "/api/healthz", "")
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("expected 405 allowing GET, HEAD, got %d allowing %q", rec.Code, rec.Header().Get("Allow"))
+	}
+	if rec.Body.String() != `{"error":"Method not allowed"}` || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected 405 body %s (%s)", rec.Body, rec.Header().Get("Content-Type"))
+	}
+
+	rec = do(h, http.MethodGet, "/nowhere", "")
+	if rec.Code != http.StatusNotFound || rec.Body.String() != `{"error":"Not found"}` {
+		t.Errorf("unexpected 404: %d %s", rec.Code, rec.Body)
+	}
+
+	// Handlers' own 404s are left alone.
+	rec = do(h, http.MethodGet, "/app/main.js", "")
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") == "application/json" {
+		t.Errorf("expected the static handler's plain 404, got %d (%s)", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	rec := do(newTestServer(t, &fakeStore{}), http.MethodGet, "/readyz", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var resp readinessResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Status != "ok" || resp.Database.OpenConnections != 1 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	rec = do(newTestServer(t, &fakeStore{pingErr: errors.New("down")}), http.MethodGet, "/readyz", "")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 when the database is down, got %d", rec.Code)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	user := newTestUser(t, "saul@example.com", "04234")
+	store := &fakeStore{users: map[string]database.User{user.Email: user}}
+	h := newTestServer(t, store)
+
+	rec := do(h, http.MethodPost, "/api/login", `{"email":"saul@example.com","password":"04234"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp UserResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	claims, err := auth.ValidateJWT(resp.Token, "test-secret", 0)
+	if err != nil || claims.UserID != user.ID {
+		t.Errorf("token does not authenticate the user: id=%s err=%v", claims.UserID, err)
+	}
+	if want := (auth.Profile{Email: user.Email}); claims.Profile == nil || *claims.Profile != want {
+		t.Errorf("expected the token to carry %+v, got %+v", want, claims.Profile)
+	}
+
+	rec = do(h, http.MethodPost, "/api/login", `{"email":"saul@example.com","password":"wrong"}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong password, got %d", rec.Code)
+	}
+	if len(store.loginFailures) != 1 {
+		t.Errorf("expected the failure to be recorded once, got %v", store.loginFailures)
+	}
+}
+
+// lookupStore counts user lookups by ID.
+type lookupStore struct {
+	fakeStore
+	lookups int
+}
+
+func (l *lookupStore) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	l.lookups++
+	return l.fakeStore.GetUserByID(ctx, id)
+}
+
+func TestAdminTokenProfile(t *testing.T) {
+	member := newTestUser(t, "member@example.com", "04234")
+	demoted := newTestUser(t, "demoted@example.com", "04234")
+	store := &lookupStore{fakeStore: fakeStore{users: map[string]database.User{member.Email: member, demoted.Email: demoted}}}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store}))
+	get := func(user database.User, role string) int {
+		token, err := auth.MakeJWT(user.ID, "test-secret", time.Hour, auth.WithProfile(auth.Profile{Email: user.Email, Role: role}))
+		if err != nil {
+			t.Fatalf("MakeJWT returned error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/admin/runtime", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := get(member, ""); code != http.StatusForbidden || store.lookups != 0 {
+		t.Errorf("a member's token: expected 403 without a lookup, got %d after %d lookups", code, store.lookups)
+	}
+	// The token still says admin, but the database has the final word.
+	if code := get(demoted, RoleAdmin); code != http.StatusForbidden || store.lookups != 1 {
+		t.Errorf("a demoted admin's token: expected 403 after a lookup, got %d after %d lookups", code, store.lookups)
+	}
+}
+
+// chirpDeleteStore holds one chirp for admins to delete.
+type chirpDeleteStore struct {
+	lookupStore
+	chirp   database.Chirp
+	deleted bool
+}
+
+type chirpDeleteTx struct {
+	*chirpDeleteStore
+}
+
+func (c *chirpDeleteStore) Begin(ctx context.Context) (Tx, error) {
+	return chirpDeleteTx{c}, nil
+}
+
+func (tx chirpDeleteTx) Commit() error   { return nil }
+func (tx chirpDeleteTx) Rollback() error { return nil }
+
+func (c *chirpDeleteStore) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	if id != c.chirp.ID {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return c.chirp, nil
+}
+
+func (c *chirpDeleteStore) GetOrganizationRole(ctx context.Context, arg database.GetOrganizationRoleParams) (string, error) {
+	return "", sql.ErrNoRows
+}
+
+func (c *chirpDeleteStore) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	c.deleted = true
+	return nil
+}
+
+func (c *chirpDeleteStore) CreateAuditLogEntry(ctx context.Context, arg database.CreateAuditLogEntryParams) error {
+	return nil
+}
+
+func TestChirpDelete_DemotedAdmin(t *testing.T) {
+	author := newTestUser(t, "author@example.com", "04234")
+	admin := newTestUser(t, "admin@example.com", "04234")
+	admin.Role = RoleAdmin
+	store := &chirpDeleteStore{
+		lookupStore: lookupStore{fakeStore: fakeStore{users: map[string]database.User{author.Email: author, admin.Email: admin}}},
+		chirp:       database.Chirp{ID: uuid.New(), UserID: author.ID, Body: "hello"},
+	}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store}))
+
+	// The token was issued while the user was still an admin.
+	token, err := auth.MakeJWT(admin.ID, "test-secret", time.Hour, auth.WithProfile(auth.Profile{Email: admin.Email, Role: RoleAdmin}))
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	del := func() int {
+		req := httptest.NewRequest(http.MethodDelete, "/api/chirps/"+store.chirp.ID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	admin.Role = ""
+	store.users[admin.Email] = admin
+	if code := del(); code != http.StatusForbidden || store.deleted {
+		t.Fatalf("expected a demoted admin's token to be refused, got %d (deleted=%v)", code, store.deleted)
+	}
+
+	admin.Role = RoleAdmin
+	store.users[admin.Email] = admin
+	if code := del(); code != http.StatusNoContent || !store.deleted {
+		t.Fatalf("expected an admin to delete the chirp, got %d", code)
+	}
+}
+
+func TestChirpDelete_AdminAppToken(t *testing.T) {
+	author := newTestUser(t, "author@example.com", "04234")
+	admin := newTestUser(t, "admin@example.com", "04234")
+	admin.Role = RoleAdmin
+	store := &chirpDeleteStore{
+		lookupStore: lookupStore{fakeStore: fakeStore{users: map[string]database.User{author.Email: author, admin.Email: admin}}},
+		chirp:       database.Chirp{ID: uuid.New(), UserID: author.ID, Body: "hello"},
+	}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store}))
+
+	token, err := auth.MakeJWT(admin.ID, "test-secret", time.Hour, auth.WithScopes("app", []string{scopeRead, scopeWrite}))
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodDelete, "/api/chirps/"+store.chirp.ID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden || store.deleted {
+		t.Fatalf("expected an app acting for an admin to be refused someone else's chirp, got %d (deleted=%v)", rec.Code, store.deleted)
+	}
+}
+
+// recordedEvents is an analytics.Recorder that keeps what it is given.
+type recordedEvents []analytics.Event
+
+func (r *recordedEvents) Record(e analytics.Event) {
+	*r = append(*r, e)
+}
+
+func TestAnalytics(t *testing.T) {
+	user := newTestUser(t, "saul@example.com", "04234")
+	store := &contractStore{fakeStore{users: map[string]database.User{user.Email: user}}}
+	events := &recordedEvents{}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow), Analytics: events}))
+
+	do(h, http.MethodPost, "/api/users", `{"email":"kim@example.com","password":"pa55word","handle":"kim"}`)
+	do(h, http.MethodPost, "/api/login", `{"email":"saul@example.com","password":"wrong"}`)
+	do(h, http.MethodPost, "/api/login", `{"email":"saul@example.com","password":"04234"}`)
+	do(h, http.MethodPost, "/api/chirps", `{"body":"hello","user_id":"`+user.ID.String()+`"}`)
+
+	want := []analytics.Event{
+		{Name: analytics.Signup, UserID: contractUserID, Time: testNow, Properties: map[string]string{"handle": "true"}},
+		{Name: analytics.Login, UserID: user.ID, Time: testNow},
+		{Name: analytics.ChirpCreated, UserID: user.ID, Time: testNow},
+	}
+	if !reflect.DeepEqual([]analytics.Event(*events), want) {
+		t.Errorf("recorded %+v, want %+v", *events, want)
+	}
+}
+
+func TestLogin_Suspended(t *testing.T) {
+	user := newTestUser(t, "kim@example.com", "pa55word")
+	user.SuspendedUntil = sql.NullTime{Time: testNow.Add(time.Hour), Valid: true}
+	store := &fakeStore{users: map[string]database.User{user.Email: user}}
+
+	rec := do(newTestServer(t, store), http.MethodPost, "/api/login", `{"email":"kim@example.com","password":"pa55word"}`)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 while suspended, got %d", rec.Code)
+	}
+
+	// The suspension is over by the fixed clock's reckoning.
+	user.SuspendedUntil.Time = testNow.Add(-time.Minute)
+	store.users[user.Email] = user
+	rec = do(newTestServer(t, store), http.MethodPost, "/api/login", `{"email":"kim@example.com","password":"pa55word"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 after the suspension, got %d", rec.Code)
+	}
+}
+
+func TestCreateUser_InvalidEmail(t *testing.T) {
+	rec := do(newTestServer(t, &fakeStore{}), http.MethodPost, "/api/users", `{"email":"not-an-email","password":"pw"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestChirpsCreate_TooLong(t *testing.T) {
+	body := `{"body":"` + strings.Repeat("a", 141) + `","user_id":"` + uuid.NewString() + `"}`
+	rec := do(newTestServer(t, &fakeStore{}), http.MethodPost, "/api/chirps", body)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestDigestUnsubscribe_BadSignature(t *testing.T) {
+	rec := do(newTestServer(t, &fakeStore{}), http.MethodPost, "/api/digests/unsubscribe?user="+uuid.NewString()+"&sig=bogus", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func FuzzIsValidEmail(f *testing.F) {
+	for _, seed := range []string{"a@b.c", "@b.c", "a@b.", "a@.", "a@@b.c", "ü@ñ.de", "a@b\xff.c", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, email string) {
+		if !IsValidEmail(email) {
+			return
+		}
+		local, domain, ok := strings.Cut(email, "@")
+		if !ok || local == "" {
+			t.Fatalf("accepted %q without a local part", email)
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 || dot == len(domain)-1 {
+			t.Fatalf("accepted %q without a dot inside the domain", email)
+		}
+	})
+}
+
+func FuzzNormalizeHandle(f *testing.F) {
+	for _, seed := range []string{"@Saul_G", "ab", "  kim  ", "ſaul", "\u0130stanbul", "a\xffb"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, handle string) {
+		got, ok := normalizeHandle(handle)
+		if !ok {
+			return
+		}
+		if len(got) < 3 || len(got) > 30 {
+			t.Fatalf("accepted %q as %q", handle, got)
+		}
+		for _, r := range got {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_') {
+				t.Fatalf("accepted %q as %q", handle, got)
+			}
+		}
+	})
+}
+
+func FuzzPrepareChirpBody(f *testing.F) {
+	for _, seed := range []string{"hello", "a kerfuffle b", "Sharbert!", "FORNAX fornax", "\xff\xfe kerfuffle", strings.Repeat("é", 71)} {
+		f.Add(seed)
+	}
+	s := NewServer(&config.Config{}, Deps{Store: &fakeStore{}})
+	f.Fuzz(func(t *testing.T, body string) {
+		cleaned, _, err := s.prepareChirpBody(body, maxChirpLength)
+		if len(body) > 140 {
+			if err == nil {
+				t.Fatalf("accepted a %d byte chirp", len(body))
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("prepareChirpBody(%q) returned error: %v", body, err)
+		}
+		words := strings.Split(cleaned, " ")
+		if len(words) != len(strings.Split(body, " ")) {
+			t.Fatalf("cleaning %q changed the word count: %q", body, cleaned)
+		}
+		for _, w := range words {
+			switch strings.ToLower(w) {
+			case "kerfuffle", "sharbert", "fornax":
+				t.Fatalf("cleaning %q left %q", body, w)
+			}
+		}
+		if utf8.ValidString(body) && !utf8.ValidString(cleaned) {
+			t.Fatalf("cleaning %q produced invalid UTF-8", body)
+		}
+	})
+}
+
+// fuzzStore fails every write the fuzzed handlers can reach, so a 500 is
+// only acceptable once a request got that far.
+type fuzzStore struct {
+	fakeStore
+	reached bool
+}
+
+func (f *fuzzStore) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	f.reached = true
+	return database.Chirp{}, errors.New("fuzzStore: writes fail")
+}
+
+func FuzzRequestDecoding(f *testing.F) {
+	for _, seed := range []string{
+		`{"email":"a@b.c","password":"pw"}`,
+		`{"body":"hello","user_id":"00000000-0000-0000-0000-000000000000"}`,
+		`{"email":"\ud800@b.c","password":"\u0000"}`,
+		`{"body":"\xff\xfe"}`,
+		`{"user_id":"not-a-uuid"}`,
+		`[1,2,3]`,
+		`{"email":{"nested":true}}`,
+		`{`,
+		"",
+	} {
+		f.Add("/api/login", seed)
+		f.Add("/api/chirps", seed)
+	}
+	f.Fuzz(func(t *testing.T, path, body string) {
+		if path != "/api/login" && path != "/api/chirps" {
+			return
+		}
+		store := &fuzzStore{}
+		s := NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store})
+		rec := do(NewRouter(s), http.MethodPost, path, body)
+		if rec.Code >= 500 && !store.reached {
+			t.Fatalf("POST %s %q: %d before reaching the store", path, body, rec.Code)
+		}
+	})
+}
+
+func TestChirpsList_Streams(t *testing.T) {
+	rec := do(newTestServer(t, &fakeStore{}), http.MethodGet, "/api/chirps", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "[]\n" {
+		t.Fatalf("expected an empty array, got %d %q", rec.Code, rec.Body)
+	}
+
+	rows := []database.GetChirpsRow{{ID: uuid.New(), Body: "one"}, {ID: uuid.New(), Body: "two"}}
+	rec = do(newTestServer(t, &fakeStore{chirps: rows}), http.MethodGet, "/api/chirps", "")
+	var got []chirpResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(got) != 2 || got[0].Body != "one" || got[1].Body != "two" {
+		t.Errorf("unexpected chirps: %+v", got)
+	}
+
+	// A failure before the first row can still become an error response.
+	rec = do(newTestServer(t, &fakeStore{chirpsErr: errors.New("boom")}), http.MethodGet, "/api/chirps", "")
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+
+	// After it, the array is cut short so clients can't mistake it for the
+	// whole list.
+	rec = do(newTestServer(t, &fakeStore{chirps: rows, chirpsErr: errors.New("boom")}), http.MethodGet, "/api/chirps", "")
+	if json.Valid(rec.Body.Bytes()) {
+		t.Errorf("expected a truncated array, got %q", rec.Body)
+	}
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAppUsageDays = 30
+	maxAppUsageDays     = 90
+)
+
+// utcDay is the UTC date t falls on, as midnight.
+func utcDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// appQuota is how many requests an app may make a day, given its own
+// quota if an admin set one. Zero means no limit.
+func (s *Server) appQuota(own sql.NullInt32) int {
+	if own.Valid {
+		return int(own.Int32)
+	}
+	return s.config.AppDailyQuota
+}
+
+// middlewareAppQuota counts the requests made with app tokens and turns
+// them away with a 429 once the app has used up its quota for the UTC
+// day. Each request of a batch counts. Quotas are best effort: when the
+// count can't be kept, requests go ahead.
+func (s *Server) middlewareAppQuota(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := s.authenticateClaims(r)
+		if err != nil || claims.ClientID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		clientID, err := uuid.Parse(claims.ClientID)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := s.clock.Now().UTC()
+		day := utcDay(now)
+		usage, err := s.db.RecordOAuthClientRequest(r.Context(), database.RecordOAuthClientRequestParams{
+			ClientID: clientID,
+			Day:      day,
+		})
+		if err != nil {
+			fmt.Println("Error counting app request:", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		quota := s.appQuota(usage.DailyQuota)
+		if quota == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reset := day.AddDate(0, 0, 1)
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(quota))
+		w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(max(int64(quota)-usage.Requests, 0), 10))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		if usage.Requests <= int64(quota) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		err = s.db.RecordOAuthClientRejection(r.Context(), database.RecordOAuthClientRejectionParams{
+			ClientID: clientID,
+			Day:      day,
+		})
+		if err != nil {
+			fmt.Println("Error counting rejected app request:", err)
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(int(reset.Sub(now).Seconds()+0.5)))
+		jsonResponse(w, http.StatusTooManyRequests, errorResponse{
+			Error: fmt.Sprintf("This app has used its quota of %d requests for today", quota),
+		})
+	})
+}
+
+type appUsageDay struct {
+	Date     string `json:"date"`
+	Requests int64  `json:"requests"`
+	Rejected int64  `json:"rejected"`
+}
+
+type appUsageResponse struct {
+	// DailyQuota is zero when the app has no limit.
+	DailyQuota    int           `json:"daily_quota"`
+	TotalRequests int64         `json:"total_requests"`
+	TotalRejected int64         `json:"total_rejected"`
+	Days          []appUsageDay `json:"days"`
+}
+
+// handlerAppUsage reports how many requests an app made each UTC day,
+// oldest first and including today, over the last ?days (30 by default).
+// Days without requests are included as zeros.
+func (s *Server) handlerAppUsage(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	app, ok := s.loadApp(w, r, userID)
+	if !ok {
+		return
+	}
+	days := defaultAppUsageDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAppUsageDays {
+			jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("days must be between 1 and %d", maxAppUsageDays))
+			return
+		}
+		days = n
+	}
+
+	since := utcDay(s.clock.Now()).AddDate(0, 0, 1-days)
+	rows, err := s.db.ListOAuthClientUsage(r.Context(), database.ListOAuthClientUsageParams{
+		ClientID: app.ID,
+		Since:    since,
+	})
+	if err != nil {
+		fmt.Println("Error listing app usage:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	byDay := make(map[string]database.OauthClientUsage, len(rows))
+	for _, row := range rows {
+		byDay[row.Day.Format(time.DateOnly)] = row
+	}
+
+	resp := appUsageResponse{DailyQuota: s.appQuota(app.DailyQuota), Days: make([]appUsageDay, 0, days)}
+	for i := range days {
+		date := since.AddDate(0, 0, i).Format(time.DateOnly)
+		row := byDay[date]
+		resp.Days = append(resp.Days, appUsageDay{Date: date, Requests: row.Requests, Rejected: row.Rejected})
+		resp.TotalRequests += row.Requests
+		resp.TotalRejected += row.Rejected
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+type appQuotaRequest struct {
+	// DailyQuota is the app's own quota; null goes back to
+	// APP_DAILY_QUOTA and zero means no limit.
+	DailyQuota *int32 `json:"daily_quota"`
+}
+
+// handlerAdminOAuthClientQuota sets or clears an app's own daily quota.
+func (s *Server) handlerAdminOAuthClientQuota(w http.ResponseWriter, r *http.Request) {
+	clientID, ok := pathUUID(w, r, "clientID")
+	if !ok {
+		return
+	}
+	var req appQuotaRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	quota := sql.NullInt32{}
+	if req.DailyQuota != nil {
+		if *req.DailyQuota < 0 {
+			jsonResponse(w, http.StatusBadRequest, "daily_quota can't be negative")
+			return
+		}
+		quota = sql.NullInt32{Int32: *req.DailyQuota, Valid: true}
+	}
+
+	ctx := r.Context()
+	admin := adminFromContext(ctx)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	client, err := tx.SetOAuthClientQuota(ctx, database.SetOAuthClientQuotaParams{ID: clientID, DailyQuota: quota})
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "App was not found.")
+		return
+	}
+	if err != nil {
+		fmt.Println("Error setting app quota:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := recordAudit(ctx, tx, admin.ID, "oauth_client.quota", client.ID, req); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, newOAuthClientResponse(client))
+}
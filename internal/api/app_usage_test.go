@@ -0,0 +1,92 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func TestAppQuota(t *testing.T) {
+	user := newTestUser(t, "saul@example.com", "04234")
+	developer := uuid.New()
+	app := database.OauthClient{
+		ID:         uuid.New(),
+		Name:       "Chirpdeck",
+		OwnerID:    uuid.NullUUID{UUID: developer, Valid: true},
+		DailyQuota: sql.NullInt32{Int32: 2, Valid: true},
+	}
+	store := &oauthStore{
+		fakeStore: fakeStore{users: map[string]database.User{user.Email: user}},
+		clients:   map[uuid.UUID]database.OauthClient{app.ID: app},
+	}
+	clock := &manualClock{now: testNow}
+	cfg := &config.Config{JWTSecret: "test-secret", AppDailyQuota: 1000}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: clock}))
+
+	appToken, err := auth.MakeJWT(user.ID, cfg.JWTSecret, 48*time.Hour, auth.WithScopes(app.ID.String(), []string{scopeRead}))
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	get := func(token, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	userPath := "/api/users/" + user.ID.String()
+
+	for i, remaining := range []string{"1", "0"} {
+		rec := get(appToken, userPath)
+		if rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "2" || rec.Header().Get("X-RateLimit-Remaining") != remaining {
+			t.Fatalf("request %d: expected 200 with %s remaining, got %d %v", i, remaining, rec.Code, rec.Header())
+		}
+	}
+	rec := get(appToken, userPath)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "43200" {
+		t.Fatalf("expected a 429 until midnight, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := get(mustMakeJWT(t, user.ID, cfg.JWTSecret, time.Hour), userPath); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "" {
+		t.Fatalf("expected first-party tokens not to be counted, got %d %v", rec.Code, rec.Header())
+	}
+
+	clock.now = testNow.Add(24 * time.Hour)
+	if rec := get(appToken, userPath); rec.Code != http.StatusOK {
+		t.Fatalf("expected the quota to reset the next day, got %d", rec.Code)
+	}
+
+	rec = get(mustMakeJWT(t, developer, cfg.JWTSecret, time.Hour), "/api/apps/"+app.ID.String()+"/usage?days=3")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var usage appUsageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&usage); err != nil {
+		t.Fatalf("decoding usage: %v", err)
+	}
+	want := []appUsageDay{
+		{Date: "2025-05-31"},
+		{Date: "2025-06-01", Requests: 2, Rejected: 1},
+		{Date: "2025-06-02", Requests: 1},
+	}
+	if usage.DailyQuota != 2 || usage.TotalRequests != 3 || usage.TotalRejected != 1 || len(usage.Days) != len(want) {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+	for i := range want {
+		if usage.Days[i] != want[i] {
+			t.Errorf("day %d: got %+v, want %+v", i, usage.Days[i], want[i])
+		}
+	}
+
+	if rec := get(mustMakeJWT(t, user.ID, cfg.JWTSecret, time.Hour), "/api/apps/"+app.ID.String()+"/usage"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected someone else's app usage to 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// appsPath is where developers register and manage their apps.
+const appsPath = "/api/apps"
+
+const (
+	// maxAppsPerUser is how many apps one developer can register.
+	maxAppsPerUser = 20
+	// appSecretGrace is how long a rotated-out secret keeps working, so the
+	// app can be redeployed with the new one.
+	appSecretGrace = 24 * time.Hour
+
+	defaultAppTokenPageSize = 50
+	maxAppTokenPageSize     = 200
+)
+
+// appResponse is an app as its developer sees it. ClientSecret is only
+// ever set in the response that creates it.
+type appResponse struct {
+	oauthClientResponse
+	ClientSecret string `json:"client_secret,omitempty"`
+	// PreviousSecretExpiresAt is when the secrets a rotation replaced stop
+	// working.
+	PreviousSecretExpiresAt *Timestamp `json:"previous_secret_expires_at,omitempty"`
+}
+
+type appTokenResponse struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Scopes    []string  `json:"scopes"`
+	CreatedAt Timestamp `json:"created_at"`
+	ExpiresAt Timestamp `json:"expires_at"`
+}
+
+// newClientSecret returns a secret for an app and the hash it's kept as.
+func newClientSecret() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	return secret, hashOAuthSecret(secret), nil
+}
+
+// loadApp looks up the app in the {clientID} path parameter. Apps belong
+// to whoever registered them; anyone else gets the same 404 as for a
+// missing app.
+func (s *Server) loadApp(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.OauthClient, bool) {
+	clientID, ok := pathUUID(w, r, "clientID")
+	if !ok {
+		return database.OauthClient{}, false
+	}
+	app, err := s.db.GetOAuthClient(r.Context(), clientID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && app.OwnerID != uuid.NullUUID{UUID: userID, Valid: true}) {
+		jsonResponse(w, http.StatusNotFound, "App was not found.")
+		return database.OauthClient{}, false
+	}
+	if err != nil {
+		fmt.Println("Error getting app:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return database.OauthClient{}, false
+	}
+	return app, true
+}
+
+// handlerAppsCreate registers an app for the authenticated developer. The
+// client secret is in the response and can't be seen again, only rotated.
+func (s *Server) handlerAppsCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req oauthClientRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		jsonResponse(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := r.Context()
+	owner := uuid.NullUUID{UUID: userID, Valid: true}
+	existing, err := s.db.ListOAuthClientsByOwner(ctx, owner)
+	if err != nil {
+		fmt.Println("Error listing apps:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if len(existing) >= maxAppsPerUser {
+		jsonResponse(w, http.StatusConflict, fmt.Sprintf("You can register at most %d apps", maxAppsPerUser))
+		return
+	}
+
+	secret, secretHash, err := newClientSecret()
+	if err != nil {
+		fmt.Println("Error generating client secret:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	app, err := tx.CreateOAuthClient(ctx, database.CreateOAuthClientParams{
+		ID:           uuid.New(),
+		Name:         req.Name,
+		RedirectUris: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		OwnerID:      owner,
+	})
+	if err != nil {
+		fmt.Println("Error creating app:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	err = tx.CreateOAuthClientSecret(ctx, database.CreateOAuthClientSecretParams{
+		ID:         uuid.New(),
+		ClientID:   app.ID,
+		SecretHash: secretHash,
+	})
+	if err != nil {
+		fmt.Println("Error creating client secret:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	jsonResponse(w, http.StatusCreated, appResponse{
+		oauthClientResponse: newOAuthClientResponse(app),
+		ClientSecret:        secret,
+	})
+}
+
+// handlerAppsMine lists the authenticated developer's apps.
+func (s *Server) handlerAppsMine(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	apps, err := s.db.ListOAuthClientsByOwner(r.Context(), uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		fmt.Println("Error listing apps:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	resp := make([]appResponse, 0, len(apps))
+	for _, app := range apps {
+		resp = append(resp, appResponse{oauthClientResponse: newOAuthClientResponse(app)})
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (s *Server) handlerAppsGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	app, ok := s.loadApp(w, r, userID)
+	if !ok {
+		return
+	}
+	jsonResponse(w, http.StatusOK, appResponse{oauthClientResponse: newOAuthClientResponse(app)})
+}
+
+// handlerAppSecretRotate issues an app a new secret. The old ones keep
+// working for appSecretGrace, or stop at once with ?immediate=true for a
+// secret that has leaked.
+func (s *Server) handlerAppSecretRotate(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	app, ok := s.loadApp(w, r, userID)
+	if !ok {
+		return
+	}
+	immediate := false
+	if v := r.URL.Query().Get("immediate"); v != "" {
+		if immediate, err = strconv.ParseBool(v); err != nil {
+			jsonResponse(w, http.StatusBadRequest, "immediate must be true or false")
+			return
+		}
+	}
+
+	secret, secretHash, err := newClientSecret()
+	if err != nil {
+		fmt.Println("Error generating client secret:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	expiresAt := s.clock.Now().UTC()
+	if !immediate {
+		expiresAt = expiresAt.Add(appSecretGrace)
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.ExpireOAuthClientSecrets(ctx, database.ExpireOAuthClientSecretsParams{
+		ClientID:  app.ID,
+		ExpiresAt: sql.NullTime{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		fmt.Println("Error expiring client secrets:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	err = tx.CreateOAuthClientSecret(ctx, database.CreateOAuthClientSecretParams{
+		ID:         uuid.New(),
+		ClientID:   app.ID,
+		SecretHash: secretHash,
+	})
+	if err != nil {
+		fmt.Println("Error creating client secret:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, appResponse{
+		oauthClientResponse:     newOAuthClientResponse(app),
+		ClientSecret:            secret,
+		PreviousSecretExpiresAt: &Timestamp{expiresAt},
+	})
+}
+
+// handlerAppTokensList lists the access tokens issued to an app, newest
+// first.
+func (s *Server) handlerAppTokensList(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	app, ok := s.loadApp(w, r, userID)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	params := database.ListOAuthTokensParams{ClientID: app.ID, RowLimit: defaultAppTokenPageSize}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAppTokenPageSize {
+			jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxAppTokenPageSize))
+			return
+		}
+		params.RowLimit = int32(n)
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			jsonResponse(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		params.RowOffset = int32(n)
+	}
+
+	tokens, err := s.db.ListOAuthTokens(r.Context(), params)
+	if err != nil {
+		fmt.Println("Error listing app tokens:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	resp := make([]appTokenResponse, 0, len(tokens))
+	for _, t := range tokens {
+		resp = append(resp, appTokenResponse{
+			ID:        t.ID,
+			UserID:    t.UserID,
+			Scopes:    t.Scopes,
+			CreatedAt: Timestamp{t.CreatedAt},
+			ExpiresAt: Timestamp{t.ExpiresAt},
+		})
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func TestApps(t *testing.T) {
+	developer, other := uuid.New(), uuid.New()
+	store := &oauthStore{
+		clients: map[uuid.UUID]database.OauthClient{},
+		codes:   map[string]database.OauthAuthorizationCode{},
+	}
+	clock := &manualClock{now: testNow}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: clock}))
+
+	as := func(userID uuid.UUID, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+mustMakeJWT(t, userID, cfg.JWTSecret, time.Hour))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := as(developer, http.MethodPost, "/api/apps", `{"name":"Chirpdeck","redirect_uris":["http://example.com/cb"],"scopes":["read"]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a plain-http redirect URI to be refused, got %d", rec.Code)
+	}
+	rec := as(developer, http.MethodPost, "/api/apps", `{"name":"Chirpdeck","redirect_uris":["https://chirpdeck.example/cb"],"scopes":["read","write"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var app appResponse
+	if err := json.NewDecoder(rec.Body).Decode(&app); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if app.ClientSecret == "" || app.OwnerID == nil || *app.OwnerID != developer {
+		t.Fatalf("expected an owned app with a secret, got %+v", app)
+	}
+	appPath := "/api/apps/" + app.ID.String()
+
+	if rec := as(developer, http.MethodGet, appPath, ""); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), app.ClientSecret) {
+		t.Fatalf("expected the app without its secret, got %d: %s", rec.Code, rec.Body)
+	}
+	for _, path := range []string{appPath, appPath + "/tokens"} {
+		if rec := as(other, http.MethodGet, path, ""); rec.Code != http.StatusNotFound {
+			t.Fatalf("expected 404 for someone else's app at %s, got %d", path, rec.Code)
+		}
+	}
+	if rec := as(other, http.MethodPost, appPath+"/secret", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected someone else's secret rotation to 404, got %d", rec.Code)
+	}
+
+	// exchange trades a fresh code for a token, authenticating as the app
+	// with secret.
+	verifier := strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	exchange := func(secret string) *httptest.ResponseRecorder {
+		code := uuid.NewString()
+		store.codes[hashOAuthSecret(code)] = database.OauthAuthorizationCode{
+			ClientID:      app.ID,
+			UserID:        other,
+			RedirectUri:   "https://chirpdeck.example/cb",
+			Scopes:        []string{scopeRead},
+			CodeChallenge: base64.RawURLEncoding.EncodeToString(sum[:]),
+			ExpiresAt:     clock.now.Add(time.Minute),
+		}
+		form := url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {"https://chirpdeck.example/cb"},
+			"code_verifier": {verifier},
+		}
+		req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(app.ID.String(), secret)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := exchange("wrong"); rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "invalid_client") {
+		t.Fatalf("expected invalid_client for a wrong secret, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := exchange(app.ClientSecret); rec.Code != http.StatusOK {
+		t.Fatalf("expected the secret to work, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = as(developer, http.MethodGet, appPath+"/tokens", "")
+	var tokens []appTokenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&tokens); err != nil {
+		t.Fatalf("decoding tokens: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].UserID != other || !tokens[0].ExpiresAt.Equal(testNow.Add(AccessTokenTTL)) {
+		t.Fatalf("expected the issued token to be listed, got %+v", tokens)
+	}
+
+	rec = as(developer, http.MethodPost, appPath+"/secret", "")
+	var rotated appResponse
+	if err := json.NewDecoder(rec.Body).Decode(&rotated); err != nil {
+		t.Fatalf("decoding rotation: %v", err)
+	}
+	if rotated.ClientSecret == "" || rotated.ClientSecret == app.ClientSecret {
+		t.Fatalf("expected a new secret, got %+v", rotated)
+	}
+	if rec := exchange(app.ClientSecret); rec.Code != http.StatusOK {
+		t.Fatalf("expected the old secret to work during the grace period, got %d", rec.Code)
+	}
+	clock.now = testNow.Add(appSecretGrace)
+	if rec := exchange(app.ClientSecret); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the old secret to stop working after the grace period, got %d", rec.Code)
+	}
+	if rec := exchange(rotated.ClientSecret); rec.Code != http.StatusOK {
+		t.Fatalf("expected the new secret to work, got %d", rec.Code)
+	}
+
+	rec = as(developer, http.MethodPost, appPath+"/secret?immediate=true", "")
+	if err := json.NewDecoder(rec.Body).Decode(&app); err != nil {
+		t.Fatalf("decoding rotation: %v", err)
+	}
+	if rec := exchange(rotated.ClientSecret); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an immediate rotation to stop the old secret, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"strings"
+
+	"chirpy/internal/avatar"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// avatarURL is the picture to show for u, under base. Users can't upload
+// one yet, so it is their identicon or, with GRAVATAR set, their Gravatar.
+func (s *Server) avatarURL(base string, u database.User) string {
+	identicon := base + "/api/avatars/" + u.ID.String() + ".png"
+	if !s.config.Gravatar {
+		return identicon
+	}
+	// Gravatar fetches the fallback itself, so it has to be absolute.
+	if !strings.HasPrefix(identicon, "http") {
+		identicon = ""
+	}
+	return avatar.GravatarURL(u.Email, identicon)
+}
+
+// handlerAvatar draws the identicon for /api/avatars/{userID}.png. It
+// depends on nothing but the ID, so it is cached for good.
+func (s *Server) handlerAvatar(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(r.PathValue("file"), ".png")
+	id, err := uuid.Parse(name)
+	if !ok || err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, avatar.Identicon(id[:])); err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", immutableCacheControl)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+
+	"chirpy/internal/avatar"
+	"chirpy/internal/config"
+
+	"github.com/google/uuid"
+)
+
+func TestAvatars(t *testing.T) {
+	h := NewRouter(NewServer(&config.Config{}, Deps{Store: &fakeStore{}}))
+	id := uuid.New()
+
+	rec := do(h, http.MethodGet, "/api/avatars/"+id.String()+".png", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected a PNG, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	img, err := png.Decode(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil || img.Bounds().Dx() != avatar.Size {
+		t.Fatalf("expected a %dpx identicon, got %v", avatar.Size, err)
+	}
+	if again := do(h, http.MethodGet, "/api/avatars/"+id.String()+".png", ""); !bytes.Equal(again.Body.Bytes(), rec.Body.Bytes()) {
+		t.Error("expected the same identicon every time")
+	}
+	for _, path := range []string{"/api/avatars/" + id.String(), "/api/avatars/nobody.png"} {
+		if rec := do(h, http.MethodGet, path, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestAvatarURL(t *testing.T) {
+	user := newTestUser(t, "user@example.com", "pa55word")
+	s := NewServer(&config.Config{Gravatar: true, PublicURL: "https://chirpy.example"}, Deps{Store: &fakeStore{}})
+
+	got := s.avatarURL(s.config.PublicURL, user)
+	if !strings.HasPrefix(got, "https://gravatar.com/avatar/") || !strings.Contains(got, "chirpy.example%2Fapi%2Favatars%2F"+user.ID.String()) {
+		t.Errorf("expected a Gravatar falling back to the identicon, got %s", got)
+	}
+	s.config.Gravatar = false
+	if got := s.avatarURL(s.config.PublicURL, user); got != "https://chirpy.example/api/avatars/"+user.ID.String()+".png" {
+		t.Errorf("expected the identicon, got %s", got)
+	}
+}
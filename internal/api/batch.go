@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// maxBatchRequests is how many sub-requests one batch may hold.
+	maxBatchRequests = 20
+	// maxBatchResponseBytes caps each sub-request's response body.
+	maxBatchResponseBytes = 1 << 20
+	batchPath             = "/api/batch"
+)
+
+type batchRequest struct {
+	Requests []batchSubrequest `json:"requests"`
+}
+
+type batchSubrequest struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// batchResult is the response to one sub-request. Body is the response
+// body as JSON, or as a JSON string when it isn't JSON.
+type batchResult struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// handlerBatch runs up to maxBatchRequests API requests, in order, with the
+// caller's headers, so clients can react to ten chirps in one round trip.
+// Each sub-request goes through the usual middleware and handler; a failing
+// one doesn't stop the rest.
+func (s *Server) handlerBatch(mux *Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req batchRequest
+		if err := s.decodeJSON(r, &req); err != nil {
+			jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+			return
+		}
+		if len(req.Requests) == 0 || len(req.Requests) > maxBatchRequests {
+			jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("A batch must hold between 1 and %d requests", maxBatchRequests))
+			return
+		}
+		for i, sub := range req.Requests {
+			if err := validBatchSubrequest(sub); err != "" {
+				jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("Request %d: %s", i, err))
+				return
+			}
+		}
+
+		results := make([]batchResult, len(req.Requests))
+		for i, sub := range req.Requests {
+			results[i] = s.runSubrequest(mux.subrequests, r, sub)
+		}
+		jsonResponse(w, http.StatusOK, struct {
+			Results []batchResult `json:"results"`
+		}{results})
+	}
+}
+
+// validBatchSubrequest returns why sub can't be run, or "".
+func validBatchSubrequest(sub batchSubrequest) string {
+	switch sub.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return "method must be GET, POST, PUT, PATCH or DELETE"
+	}
+	u, err := url.Parse(sub.Path)
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, "/api/") {
+		return "path must be an /api/ path on this site"
+	}
+	if u.Path == batchPath {
+		return "batches can't be nested"
+	}
+	return ""
+}
+
+// runSubrequest serves sub as though it had arrived alongside parent, with
+// the parent's headers and context.
+func (s *Server) runSubrequest(h http.Handler, parent *http.Request, sub batchSubrequest) batchResult {
+	var body bytes.Reader
+	if len(sub.Body) > 0 && string(sub.Body) != "null" {
+		body.Reset(sub.Body)
+	}
+	req, err := http.NewRequestWithContext(parent.Context(), sub.Method, sub.Path, &body)
+	if err != nil {
+		return batchResult{Status: http.StatusBadRequest, Error: "Invalid request"}
+	}
+	req.Header = parent.Header.Clone()
+	req.Header.Del("Content-Length")
+	req.Header.Set("Content-Type", "application/json")
+	req.Host = parent.Host
+	req.RemoteAddr = parent.RemoteAddr
+	req.Proto, req.ProtoMajor, req.ProtoMinor = parent.Proto, parent.ProtoMajor, parent.ProtoMinor
+
+	rec := &batchWriter{header: make(http.Header)}
+	h.ServeHTTP(rec, req)
+
+	result := batchResult{Status: rec.statusCode()}
+	switch {
+	case rec.truncated:
+		result.Error = "Response is too large for a batch"
+	case rec.body.Len() == 0:
+	case json.Valid(rec.body.Bytes()):
+		result.Body = bytes.TrimSpace(rec.body.Bytes())
+	default:
+		result.Body, _ = json.Marshal(rec.body.String())
+	}
+	return result
+}
+
+// batchWriter keeps a sub-request's response. It isn't an http.Flusher, so
+// streaming endpoints refuse to run in a batch.
+type batchWriter struct {
+	header    http.Header
+	status    int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (bw *batchWriter) Header() http.Header {
+	return bw.header
+}
+
+func (bw *batchWriter) WriteHeader(code int) {
+	if bw.status == 0 {
+		bw.status = code
+	}
+}
+
+func (bw *batchWriter) Write(p []byte) (int, error) {
+	if bw.status == 0 {
+		bw.status = http.StatusOK
+	}
+	if bw.body.Len()+len(p) > maxBatchResponseBytes {
+		bw.truncated = true
+		return 0, fmt.Errorf("batch response is over %d bytes", maxBatchResponseBytes)
+	}
+	return bw.body.Write(p)
+}
+
+func (bw *batchWriter) statusCode() int {
+	if bw.status == 0 {
+		return http.StatusOK
+	}
+	return bw.status
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+)
+
+func TestBatch(t *testing.T) {
+	user := newTestUser(t, "saul@example.com", "04234")
+	store := &batchStore{contractStore: contractStore{fakeStore{users: map[string]database.User{user.Email: user}}}}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store}))
+	token, err := auth.MakeJWT(user.ID, "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	post := func(body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/batch", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	body := `{"requests":[
+		{"method":"GET","path":"/api/chirps/` + contractChirpID.String() + `?fields=id"},
+		{"method":"POST","path":"/api/chirps/batch","body":{"chirps":[{"body":"one"},{"body":"two"}]}},
+		{"method":"DELETE","path":"/api/nowhere"},
+		{"method":"GET","path":"/api/healthz"}
+	]}`
+	var resp struct {
+		Results []batchResult `json:"results"`
+	}
+	rec := post(body, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := []batchResult{
+		{Status: http.StatusOK, Body: json.RawMessage(`{"id":"` + contractChirpID.String() + `"}`)},
+		{Status: http.StatusOK},
+		{Status: http.StatusNotFound, Body: json.RawMessage(`{"error":"Not found"}`)},
+		{Status: http.StatusOK, Body: json.RawMessage(`"OK"`)},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("expected %d results, got %s", len(want), rec.Body)
+	}
+	for i, w := range want {
+		got := resp.Results[i]
+		if got.Status != w.Status || (w.Body != nil && string(got.Body) != string(w.Body)) {
+			t.Errorf("result %d: got %d %s, want %d %s", i, got.Status, got.Body, w.Status, w.Body)
+		}
+	}
+	if len(store.calls) != 1 || len(store.calls[0].Bodies) != 2 {
+		t.Errorf("expected the sub-request to create two chirps, got %+v", store.calls)
+	}
+
+	// Sub-requests carry the caller's credentials, or lack of them.
+	rec = post(`{"requests":[{"method":"POST","path":"/api/chirps/batch","body":{"chirps":[{"body":"one"}]}}]}`, "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || len(resp.Results) != 1 || resp.Results[0].Status != http.StatusUnauthorized {
+		t.Errorf("expected a 401 result without a token, got %d %s", rec.Code, rec.Body)
+	}
+
+	for _, bad := range []string{
+		`{"requests":[]}`,
+		`{"requests":[{"method":"POST","path":"/api/batch"}]}`,
+		`{"requests":[{"method":"GET","path":"https://example.com/api/chirps"}]}`,
+		`{"requests":[{"method":"GET","path":"/admin/users"}]}`,
+		`{"requests":[{"method":"TRACE","path":"/api/chirps"}]}`,
+		`{"requests":[` + strings.Repeat(`{"method":"GET","path":"/api/healthz"},`, maxBatchRequests) + `{"method":"GET","path":"/api/healthz"}]}`,
+	} {
+		if rec := post(bad, token); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", bad, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"chirpy/internal/database"
+	"chirpy/internal/stripe"
+	"chirpy/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+// billingPath is where users manage their Chirpy Red subscription.
+const billingPath = "/api/billing"
+
+// maxStripeWebhookSize bounds webhook bodies. Stripe's events are a few
+// kilobytes.
+const maxStripeWebhookSize = 1 << 20
+
+// Billing sells Chirpy Red subscriptions. *stripe.Client implements it.
+type Billing interface {
+	CreateCheckoutSession(ctx context.Context, p stripe.CheckoutParams) (stripe.CheckoutSession, error)
+	SetCancelAtPeriodEnd(ctx context.Context, subscriptionID string, cancel bool) (stripe.Subscription, error)
+}
+
+type checkoutResponse struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// handlerBillingCheckout starts a Stripe Checkout session for Chirpy Red.
+// The frontend sends the user to its URL to pay; the webhook upgrades
+// them once the subscription starts.
+func (s *Server) handlerBillingCheckout(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	ctx := r.Context()
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	_, err = s.db.GetCurrentBillingSubscription(ctx, userID)
+	if err == nil {
+		jsonResponse(w, http.StatusConflict, "You already subscribe to Chirpy Red")
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		fmt.Println("Error getting subscription:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	params := stripe.CheckoutParams{
+		PriceID:    s.config.Stripe.PriceID,
+		UserID:     userID.String(),
+		SuccessURL: s.config.Stripe.SuccessURL,
+		CancelURL:  s.config.Stripe.CancelURL,
+	}
+	// Returning subscribers keep their customer, and with it their
+	// invoices and payment methods.
+	customer, err := s.db.GetBillingCustomer(ctx, userID)
+	switch {
+	case err == nil:
+		params.CustomerID = customer.StripeCustomerID
+	case errors.Is(err, sql.ErrNoRows):
+		params.CustomerEmail = user.Email
+	default:
+		fmt.Println("Error getting billing customer:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	session, err := s.billing.CreateCheckoutSession(ctx, params)
+	if err != nil {
+		fmt.Println("Error creating checkout session:", err)
+		jsonResponse(w, http.StatusBadGateway, "Couldn't reach the payment provider")
+		return
+	}
+	jsonResponse(w, http.StatusCreated, checkoutResponse{ID: session.ID, URL: session.URL})
+}
+
+// handlerStripeWebhook applies Stripe's events to Chirpy Red. Events are
+// recorded so a redelivered one is only applied once, and anything that
+// fails answers 500 so Stripe delivers it again.
+//
+// Stripe calls one URL for every community, so the events are applied
+// outside any tenant's scope: each names its user by ID, whichever tenant
+// they belong to.
+func (s *Server) handlerStripeWebhook(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxStripeWebhookSize)
+	payload, err := io.ReadAll(r.Body)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, "Couldn't read the request body")
+		return
+	}
+	event, err := stripe.ConstructEvent(payload, r.Header.Get("Stripe-Signature"), s.config.Stripe.WebhookSecret, s.clock.Now(), stripe.DefaultWebhookTolerance)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, "Invalid signature")
+		return
+	}
+
+	ctx := tenant.WithoutTenant(r.Context())
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	recorded, err := tx.RecordStripeEvent(ctx, database.RecordStripeEventParams{ID: event.ID, Type: event.Type})
+	if err != nil {
+		fmt.Println("Error recording stripe event:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if recorded == 0 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	switch event.Type {
+	case "checkout.session.completed":
+		err = applyCheckoutSession(ctx, tx, event)
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		err = applySubscription(ctx, tx, event)
+	}
+	if err != nil {
+		fmt.Printf("Error applying stripe event %s: %v\n", event.ID, err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// applyCheckoutSession remembers the customer a completed Checkout made
+// for the user, so their next checkout reuses it.
+func applyCheckoutSession(ctx context.Context, q database.Querier, event stripe.Event) error {
+	var session stripe.CheckoutSession
+	if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+		return err
+	}
+	userID, err := uuid.Parse(session.ClientReferenceID)
+	if err != nil || session.Customer == "" {
+		// Not a session this server started.
+		return nil
+	}
+	return q.UpsertBillingCustomer(ctx, database.UpsertBillingCustomerParams{
+		UserID:           userID,
+		StripeCustomerID: session.Customer,
+	})
+}
+
+// applySubscription stores the subscription in event for the user it
+// belongs to.
+func applySubscription(ctx context.Context, q database.Querier, event stripe.Event) error {
+	var sub stripe.Subscription
+	if err := json.Unmarshal(event.Data.Object, &sub); err != nil {
+		return err
+	}
+	userID, err := uuid.Parse(sub.Metadata["user_id"])
+	if err != nil {
+		customer, err := q.GetBillingCustomerByStripeID(ctx, sub.Customer)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Not a subscription this server started.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		userID = customer.UserID
+	}
+	return storeSubscription(ctx, q, userID, sub, time.Unix(event.Created, 0))
+}
+
+// storeSubscription stores sub, as Stripe had it at asOf, and brings the
+// user's Chirpy Red in line: they keep it while any of their
+// subscriptions is current. Stripe's events can arrive out of order, so a
+// state older than the stored one is ignored. A user that can't be found
+// is an error, so the event is delivered again rather than lost.
+func storeSubscription(ctx context.Context, q database.Querier, userID uuid.UUID, sub stripe.Subscription, asOf time.Time) error {
+	if _, err := q.GetUserByID(ctx, userID); err != nil {
+		return fmt.Errorf("looking up user %s: %w", userID, err)
+	}
+	err := q.UpsertBillingCustomer(ctx, database.UpsertBillingCustomerParams{
+		UserID:           userID,
+		StripeCustomerID: sub.Customer,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = q.UpsertBillingSubscription(ctx, database.UpsertBillingSubscriptionParams{
+		ID:                sub.ID,
+		UserID:            userID,
+		StripeCustomerID:  sub.Customer,
+		Status:            sub.Status,
+		PriceID:           sub.PriceID(),
+		CancelAtPeriodEnd: sub.CancelAtPeriodEnd,
+		CurrentPeriodEnd:  stripeTime(sub.CurrentPeriodEnd),
+		TrialEnd:          stripeTime(sub.TrialEnd),
+		CanceledAt:        stripeTime(sub.CanceledAt),
+		EndedAt:           stripeTime(sub.EndedAt),
+		MonthlyAmount:     sub.MonthlyAmount(),
+		CreatedAt:         time.Unix(sub.Created, 0),
+		EventCreatedAt:    asOf,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = q.GetCurrentBillingSubscription(ctx, userID)
+	entitled := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if _, err := q.SetUserChirpyRed(ctx, database.SetUserChirpyRedParams{ID: userID, IsChirpyRed: entitled}); err != nil {
+		return fmt.Errorf("updating user %s: %w", userID, err)
+	}
+	return nil
+}
+
+// stripeTime converts one of Stripe's Unix timestamps, which are zero when
+// unset.
+func stripeTime(sec int64) sql.NullTime {
+	if sec == 0 {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: time.Unix(sec, 0), Valid: true}
+}
+
+// subscriptionResponse is a user's Chirpy Red subscription.
+type subscriptionResponse struct {
+	Status string `json:"status"`
+	// Plan is the Stripe price subscribed to.
+	Plan              string     `json:"plan"`
+	CancelAtPeriodEnd bool       `json:"cancel_at_period_end"`
+	CurrentPeriodEnd  *Timestamp `json:"current_period_end"`
+	// RenewsAt is when the user is next charged, and null once they've
+	// cancelled.
+	RenewsAt *Timestamp `json:"renews_at"`
+	TrialEnd *Timestamp `json:"trial_end,omitempty"`
+}
+
+func newSubscriptionResponse(sub database.BillingSubscription) subscriptionResponse {
+	resp := subscriptionResponse{
+		Status:            sub.Status,
+		Plan:              sub.PriceID,
+		CancelAtPeriodEnd: sub.CancelAtPeriodEnd,
+	}
+	if sub.CurrentPeriodEnd.Valid {
+		resp.CurrentPeriodEnd = &Timestamp{sub.CurrentPeriodEnd.Time}
+		if !sub.CancelAtPeriodEnd {
+			resp.RenewsAt = resp.CurrentPeriodEnd
+		}
+	}
+	if sub.TrialEnd.Valid {
+		resp.TrialEnd = &Timestamp{sub.TrialEnd.Time}
+	}
+	return resp
+}
+
+// loadSubscription looks up the caller's current subscription, answering
+// 404 when they have none.
+func (s *Server) loadSubscription(w http.ResponseWriter, r *http.Request) (database.BillingSubscription, bool) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return database.BillingSubscription{}, false
+	}
+	sub, err := s.db.GetCurrentBillingSubscription(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "You don't subscribe to Chirpy Red")
+		return database.BillingSubscription{}, false
+	}
+	if err != nil {
+		fmt.Println("Error getting subscription:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return database.BillingSubscription{}, false
+	}
+	return sub, true
+}
+
+func (s *Server) handlerBillingSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, ok := s.loadSubscription(w, r)
+	if !ok {
+		return
+	}
+	jsonResponse(w, http.StatusOK, newSubscriptionResponse(sub))
+}
+
+// handlerBillingCancel ends the caller's subscription when the period
+// they've paid for does. They keep Chirpy Red until then.
+func (s *Server) handlerBillingCancel(w http.ResponseWriter, r *http.Request) {
+	s.setCancelAtPeriodEnd(w, r, true)
+}
+
+// handlerBillingResume takes back a cancellation before it takes effect.
+func (s *Server) handlerBillingResume(w http.ResponseWriter, r *http.Request) {
+	s.setCancelAtPeriodEnd(w, r, false)
+}
+
+// setCancelAtPeriodEnd updates the subscription at Stripe and stores what
+// Stripe answers straight away rather than waiting for the webhook.
+// Asking for what's already the case changes nothing.
+func (s *Server) setCancelAtPeriodEnd(w http.ResponseWriter, r *http.Request, cancel bool) {
+	sub, ok := s.loadSubscription(w, r)
+	if !ok {
+		return
+	}
+	if sub.CancelAtPeriodEnd == cancel {
+		jsonResponse(w, http.StatusOK, newSubscriptionResponse(sub))
+		return
+	}
+
+	ctx := r.Context()
+	updated, err := s.billing.SetCancelAtPeriodEnd(ctx, sub.ID, cancel)
+	if err != nil {
+		fmt.Println("Error updating subscription:", err)
+		jsonResponse(w, http.StatusBadGateway, "Couldn't reach the payment provider")
+		return
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	if err := storeSubscription(ctx, tx, sub.UserID, updated, s.clock.Now()); err != nil {
+		fmt.Println("Error storing subscription:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	resp := newSubscriptionResponse(database.BillingSubscription{
+		Status:            updated.Status,
+		PriceID:           updated.PriceID(),
+		CancelAtPeriodEnd: updated.CancelAtPeriodEnd,
+		CurrentPeriodEnd:  stripeTime(updated.CurrentPeriodEnd),
+		TrialEnd:          stripeTime(updated.TrialEnd),
+	})
+	jsonResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+	"chirpy/internal/stripe"
+	"chirpy/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+type billingStore struct {
+	fakeStore
+	customers     map[uuid.UUID]string
+	subscriptions map[string]database.BillingSubscription
+	events        map[string]bool
+}
+
+type billingTx struct {
+	*billingStore
+}
+
+func (s *billingStore) Begin(ctx context.Context) (Tx, error) {
+	return billingTx{s}, nil
+}
+
+func (tx billingTx) Commit() error   { return nil }
+func (tx billingTx) Rollback() error { return nil }
+
+func (s *billingStore) GetBillingCustomer(ctx context.Context, userID uuid.UUID) (database.BillingCustomer, error) {
+	id, ok := s.customers[userID]
+	if !ok {
+		return database.BillingCustomer{}, sql.ErrNoRows
+	}
+	return database.BillingCustomer{UserID: userID, StripeCustomerID: id}, nil
+}
+
+func (s *billingStore) GetBillingCustomerByStripeID(ctx context.Context, stripeCustomerID string) (database.BillingCustomer, error) {
+	for userID, id := range s.customers {
+		if id == stripeCustomerID {
+			return database.BillingCustomer{UserID: userID, StripeCustomerID: id}, nil
+		}
+	}
+	return database.BillingCustomer{}, sql.ErrNoRows
+}
+
+func (s *billingStore) UpsertBillingCustomer(ctx context.Context, arg database.UpsertBillingCustomerParams) error {
+	s.customers[arg.UserID] = arg.StripeCustomerID
+	return nil
+}
+
+func (s *billingStore) GetCurrentBillingSubscription(ctx context.Context, userID uuid.UUID) (database.BillingSubscription, error) {
+	for _, sub := range s.subscriptions {
+		if sub.UserID == userID && (sub.Status == "active" || sub.Status == "trialing" || sub.Status == "past_due") {
+			return sub, nil
+		}
+	}
+	return database.BillingSubscription{}, sql.ErrNoRows
+}
+
+func (s *billingStore) UpsertBillingSubscription(ctx context.Context, arg database.UpsertBillingSubscriptionParams) (database.BillingSubscription, error) {
+	if old, ok := s.subscriptions[arg.ID]; ok && old.EventCreatedAt.After(arg.EventCreatedAt) {
+		return database.BillingSubscription{}, sql.ErrNoRows
+	}
+	sub := database.BillingSubscription{
+		ID:                arg.ID,
+		UserID:            arg.UserID,
+		StripeCustomerID:  arg.StripeCustomerID,
+		Status:            arg.Status,
+		PriceID:           arg.PriceID,
+		CancelAtPeriodEnd: arg.CancelAtPeriodEnd,
+		CurrentPeriodEnd:  arg.CurrentPeriodEnd,
+		EndedAt:           arg.EndedAt,
+		MonthlyAmount:     arg.MonthlyAmount,
+		CreatedAt:         arg.CreatedAt,
+		EventCreatedAt:    arg.EventCreatedAt,
+	}
+	s.subscriptions[arg.ID] = sub
+	return sub, nil
+}
+
+func (s *billingStore) RecordStripeEvent(ctx context.Context, arg database.RecordStripeEventParams) (int64, error) {
+	if s.events[arg.ID] {
+		return 0, nil
+	}
+	s.events[arg.ID] = true
+	return 1, nil
+}
+
+// inScope reports whether the row-level security policies would show u to
+// a connection for ctx: every user when it has no tenant.
+func inScope(ctx context.Context, u database.User) bool {
+	t, ok := tenant.FromContext(ctx)
+	return !ok || u.TenantID.UUID == t.ID
+}
+
+func (s *billingStore) GetUserByID(ctx context.Context, id uuid.UUID) (database.User, error) {
+	u, err := s.fakeStore.GetUserByID(ctx, id)
+	if err == nil && !inScope(ctx, u) {
+		return database.User{}, sql.ErrNoRows
+	}
+	return u, err
+}
+
+func (s *billingStore) SetUserChirpyRed(ctx context.Context, arg database.SetUserChirpyRedParams) (database.User, error) {
+	for email, u := range s.users {
+		if u.ID == arg.ID && inScope(ctx, u) {
+			u.IsChirpyRed = arg.IsChirpyRed
+			s.users[email] = u
+			return u, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+type fakeBilling struct {
+	params []stripe.CheckoutParams
+	// subscriptions are what Stripe has, by ID.
+	subscriptions map[string]stripe.Subscription
+}
+
+func (b *fakeBilling) CreateCheckoutSession(ctx context.Context, p stripe.CheckoutParams) (stripe.CheckoutSession, error) {
+	b.params = append(b.params, p)
+	return stripe.CheckoutSession{ID: "cs_1", URL: "https://checkout.stripe.com/c/cs_1"}, nil
+}
+
+func (b *fakeBilling) SetCancelAtPeriodEnd(ctx context.Context, subscriptionID string, cancel bool) (stripe.Subscription, error) {
+	sub, ok := b.subscriptions[subscriptionID]
+	if !ok {
+		return stripe.Subscription{}, &stripe.Error{Status: http.StatusNotFound, Message: "No such subscription"}
+	}
+	sub.CancelAtPeriodEnd = cancel
+	b.subscriptions[subscriptionID] = sub
+	return sub, nil
+}
+
+func TestBillingCheckout(t *testing.T) {
+	user := newTestUser(t, "kim@example.com", "04234")
+	store := &billingStore{
+		fakeStore:     fakeStore{users: map[string]database.User{user.Email: user}},
+		customers:     map[uuid.UUID]string{},
+		subscriptions: map[string]database.BillingSubscription{},
+	}
+	billing := &fakeBilling{}
+	cfg := &config.Config{JWTSecret: "test-secret", Stripe: stripe.Config{PriceID: "price_red"}}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow), Billing: billing}))
+
+	checkout := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/billing/checkout", nil)
+		req.Header.Set("Authorization", "Bearer "+mustMakeJWT(t, user.ID, cfg.JWTSecret, time.Hour))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := checkout()
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp checkoutResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.URL != "https://checkout.stripe.com/c/cs_1" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if p := billing.params[0]; p.PriceID != "price_red" || p.UserID != user.ID.String() || p.CustomerEmail != user.Email || p.CustomerID != "" {
+		t.Fatalf("unexpected checkout params %+v", p)
+	}
+
+	store.customers[user.ID] = "cus_1"
+	if rec := checkout(); rec.Code != http.StatusCreated || billing.params[1].CustomerID != "cus_1" {
+		t.Fatalf("expected a returning subscriber's customer to be reused, got %d %+v", rec.Code, billing.params[1])
+	}
+
+	store.subscriptions["sub_1"] = database.BillingSubscription{ID: "sub_1", UserID: user.ID, Status: "active"}
+	if rec := checkout(); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a subscriber, got %d", rec.Code)
+	}
+	if rec := do(h, http.MethodPost, "/api/billing/checkout", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", rec.Code)
+	}
+}
+
+func TestStripeWebhook(t *testing.T) {
+	user := newTestUser(t, "kim@example.com", "04234")
+	store := &billingStore{
+		fakeStore:     fakeStore{users: map[string]database.User{user.Email: user}},
+		customers:     map[uuid.UUID]string{},
+		subscriptions: map[string]database.BillingSubscription{},
+		events:        map[string]bool{},
+	}
+	cfg := &config.Config{JWTSecret: "test-secret", Stripe: stripe.Config{WebhookSecret: "whsec_test"}}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow), Billing: &fakeBilling{}}))
+
+	send := func(secret, eventID, eventType string, created time.Time, object string) int {
+		payload := fmt.Sprintf(`{"id":%q,"type":%q,"created":%d,"data":{"object":%s}}`, eventID, eventType, created.Unix(), object)
+		req := httptest.NewRequest(http.MethodPost, "/api/stripe/webhooks", strings.NewReader(payload))
+		req.Header.Set("Stripe-Signature", fmt.Sprintf("t=%d,v1=%s", testNow.Unix(), stripe.Sign([]byte(payload), secret, testNow.Unix())))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	subscription := func(status string) string {
+		return fmt.Sprintf(`{"id":"sub_1","customer":"cus_1","status":%q,"metadata":{"user_id":%q},"created":%d,"items":{"data":[{"quantity":1,"price":{"id":"price_red","unit_amount":4800,"recurring":{"interval":"year","interval_count":1}}}]}}`,
+			status, user.ID, testNow.Add(-time.Hour).Unix())
+	}
+	isRed := func() bool { return store.users[user.Email].IsChirpyRed }
+
+	if code := send("whsec_other", "evt_0", "customer.subscription.created", testNow, subscription("active")); code != http.StatusBadRequest || isRed() {
+		t.Fatalf("expected a forged event to be refused, got %d", code)
+	}
+
+	if code := send("whsec_test", "evt_1", "customer.subscription.created", testNow.Add(-time.Minute), subscription("active")); code != http.StatusOK || !isRed() {
+		t.Fatalf("expected the user to be upgraded, got %d", code)
+	}
+	if sub := store.subscriptions["sub_1"]; store.customers[user.ID] != "cus_1" || sub.PriceID != "price_red" || sub.MonthlyAmount != 400 {
+		t.Fatalf("expected the customer and subscription to be stored, got %v %+v", store.customers, store.subscriptions)
+	}
+
+	// An older event delivered late doesn't undo a newer one.
+	if code := send("whsec_test", "evt_2", "customer.subscription.updated", testNow.Add(-2*time.Minute), subscription("incomplete")); code != http.StatusOK || !isRed() {
+		t.Fatalf("expected a stale event to be ignored, got %d", code)
+	}
+
+	if code := send("whsec_test", "evt_3", "customer.subscription.deleted", testNow, subscription("canceled")); code != http.StatusOK || isRed() {
+		t.Fatalf("expected the user to be downgraded, got %d", code)
+	}
+
+	// A redelivered event is only applied once.
+	if code := send("whsec_test", "evt_1", "customer.subscription.created", testNow.Add(-time.Minute), subscription("active")); code != http.StatusOK || isRed() {
+		t.Fatalf("expected a redelivered event to be skipped, got %d", code)
+	}
+}
+
+func TestStripeWebhookTenantUser(t *testing.T) {
+	user := newTestUser(t, "kim@example.com", "04234")
+	user.TenantID = uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	store := &billingStore{
+		fakeStore:     fakeStore{users: map[string]database.User{user.Email: user}},
+		customers:     map[uuid.UUID]string{},
+		subscriptions: map[string]database.BillingSubscription{},
+		events:        map[string]bool{},
+	}
+	cfg := &config.Config{JWTSecret: "test-secret", MultiTenant: true, Stripe: stripe.Config{WebhookSecret: "whsec_test"}}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow), Billing: &fakeBilling{}}))
+
+	send := func(eventID string, userID uuid.UUID) int {
+		object := fmt.Sprintf(`{"id":"sub_%s","customer":"cus_1","status":"active","metadata":{"user_id":%q},"created":%d}`, eventID, userID, testNow.Unix())
+		payload := fmt.Sprintf(`{"id":%q,"type":"customer.subscription.created","created":%d,"data":{"object":%s}}`, eventID, testNow.Unix(), object)
+		req := httptest.NewRequest(http.MethodPost, "/api/stripe/webhooks", strings.NewReader(payload))
+		req.Header.Set("Stripe-Signature", fmt.Sprintf("t=%d,v1=%s", testNow.Unix(), stripe.Sign([]byte(payload), "whsec_test", testNow.Unix())))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Stripe calls the default community's URL for every tenant's users.
+	if code := send("evt_1", user.ID); code != http.StatusOK || !store.users[user.Email].IsChirpyRed {
+		t.Errorf("expected another tenant's user to be upgraded, got %d", code)
+	}
+	if code := send("evt_2", uuid.New()); code != http.StatusInternalServerError {
+		t.Errorf("unknown user: expected 500 so Stripe retries, got %d", code)
+	}
+	if _, ok := store.subscriptions["sub_evt_1"]; !ok || len(store.subscriptions) != 1 {
+		t.Errorf("expected only the known user's subscription stored, got %+v", store.subscriptions)
+	}
+}
+
+func TestBillingSubscription(t *testing.T) {
+	user := newTestUser(t, "kim@example.com", "04234")
+	user.IsChirpyRed = true
+	periodEnd := testNow.Add(20 * 24 * time.Hour).Truncate(time.Second)
+	store := &billingStore{
+		fakeStore: fakeStore{users: map[string]database.User{user.Email: user}},
+		customers: map[uuid.UUID]string{user.ID: "cus_1"},
+		subscriptions: map[string]database.BillingSubscription{"sub_1": {
+			ID:               "sub_1",
+			UserID:           user.ID,
+			StripeCustomerID: "cus_1",
+			Status:           "active",
+			PriceID:          "price_red",
+			CurrentPeriodEnd: sql.NullTime{Time: periodEnd, Valid: true},
+			EventCreatedAt:   testNow.Add(-time.Hour),
+		}},
+	}
+	billing := &fakeBilling{subscriptions: map[string]stripe.Subscription{"sub_1": {
+		ID:               "sub_1",
+		Customer:         "cus_1",
+		Status:           "active",
+		CurrentPeriodEnd: periodEnd.Unix(),
+	}}}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow), Billing: billing}))
+
+	as := func(userID uuid.UUID, method, path string) (int, subscriptionResponse) {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+mustMakeJWT(t, userID, cfg.JWTSecret, time.Hour))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var resp subscriptionResponse
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, resp
+	}
+
+	code, resp := as(user.ID, http.MethodGet, "/api/billing/subscription")
+	if code != http.StatusOK || resp.Status != "active" || resp.Plan != "price_red" || resp.RenewsAt == nil || !resp.RenewsAt.Equal(periodEnd) {
+		t.Fatalf("unexpected subscription %d %+v", code, resp)
+	}
+
+	code, resp = as(user.ID, http.MethodPost, "/api/billing/cancel")
+	if code != http.StatusOK || !resp.CancelAtPeriodEnd || resp.RenewsAt != nil || !resp.CurrentPeriodEnd.Equal(periodEnd) {
+		t.Fatalf("unexpected cancellation %d %+v", code, resp)
+	}
+	if !billing.subscriptions["sub_1"].CancelAtPeriodEnd || !store.subscriptions["sub_1"].CancelAtPeriodEnd {
+		t.Fatal("expected the cancellation to reach Stripe and be stored")
+	}
+	if !store.users[user.Email].IsChirpyRed {
+		t.Fatal("expected Chirpy Red to last until the end of the period")
+	}
+
+	code, resp = as(user.ID, http.MethodPost, "/api/billing/resume")
+	if code != http.StatusOK || resp.CancelAtPeriodEnd || resp.RenewsAt == nil {
+		t.Fatalf("unexpected resumption %d %+v", code, resp)
+	}
+	if billing.subscriptions["sub_1"].CancelAtPeriodEnd || store.subscriptions["sub_1"].CancelAtPeriodEnd {
+		t.Fatal("expected the resumption to reach Stripe and be stored")
+	}
+
+	if code, _ := as(uuid.New(), http.MethodPost, "/api/billing/cancel"); code != http.StatusNotFound {
+		t.Fatalf("expected 404 without a subscription, got %d", code)
+	}
+}
+
+func TestNewWeeklyRevenue(t *testing.T) {
+	week := time.Date(2025, 5, 26, 0, 0, 0, 0, time.UTC)
+	weeks := newWeeklyRevenue([]database.WeeklyBillingMetricsRow{
+		{Week: week},
+		{Week: week.AddDate(0, 0, 7), TrialsEnded: 4, TrialsConverted: 3, PayingAtStart: 10, Churned: 1, Paying: 12, Mrr: 5988},
+	})
+	if len(weeks) != 2 || weeks[0].ConversionRate != nil || weeks[0].ChurnRate != nil {
+		t.Fatalf("expected no rates for an empty week, got %+v", weeks)
+	}
+	w := weeks[1]
+	if w.Week != "2025-06-02" || w.Active != 12 || w.MRR != 5988 || *w.ConversionRate != 0.75 || *w.ChurnRate != 0.1 {
+		t.Fatalf("unexpected week %+v", w)
+	}
+}
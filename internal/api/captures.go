@@ -0,0 +1,611 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+
+	"github.com/google/uuid"
+)
+
+// Request capture records sanitized request/response pairs for a route or
+// a user while a capture rule is active, so `chirpy replay` can send them
+// to a local instance to reproduce a bug.
+
+const (
+	// maxCaptureBodyBytes is how much of each body is kept.
+	maxCaptureBodyBytes = 64 << 10
+
+	defaultCaptureDuration = time.Hour
+	maxCaptureDuration     = 24 * time.Hour
+	defaultMaxCaptures     = 100
+	maxMaxCaptures         = 1000
+
+	defaultCapturePageSize = 50
+	maxCapturePageSize     = 200
+
+	// captureAdminPath is never captured, since its responses hold other
+	// captures.
+	captureAdminPath = "/admin/captures"
+
+	redactedValue = "[REDACTED]"
+)
+
+// captureRedactedHeaders carry credentials. Their names are kept but not
+// their values.
+var captureRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// captureRedactedFields are JSON body fields, at any depth, that carry
+// credentials.
+var captureRedactedFields = []string{"password", "token", "access_token", "refresh_token"}
+
+// reloadCaptureRules refreshes the in-memory capture rules from the
+// database.
+func (s *Server) reloadCaptureRules(ctx context.Context) error {
+	rules, err := s.db.ListActiveCaptureRules(ctx)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		s.captureRules.Store(nil)
+	} else {
+		s.captureRules.Store(&rules)
+	}
+	return nil
+}
+
+// watchCaptureRules reloads the capture rules whenever any instance
+// changes them, using the events hub fed by Postgres NOTIFY.
+func (s *Server) watchCaptureRules(ctx context.Context, hub *events.Hub) {
+	ch, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if e.Type != "capture_rules.changed" {
+				continue
+			}
+			if err := s.reloadCaptureRules(ctx); err != nil {
+				fmt.Println("Error reloading capture rules:", err)
+			}
+		}
+	}
+}
+
+// captureRuleFor returns the first unexpired rule that covers a request to
+// pattern by userID, who is uuid.Nil for anonymous requests.
+func (s *Server) captureRuleFor(pattern string, userID uuid.UUID) (database.CaptureRule, bool) {
+	rules := s.captureRules.Load()
+	if rules == nil || pattern == "" {
+		return database.CaptureRule{}, false
+	}
+	if strings.HasPrefix(routePath(pattern), captureAdminPath) {
+		return database.CaptureRule{}, false
+	}
+	now := s.clock.Now()
+	for _, rule := range *rules {
+		if !rule.ExpiresAt.After(now) {
+			continue
+		}
+		if rule.Route.Valid && rule.Route.String != pattern {
+			continue
+		}
+		if rule.UserID.Valid && rule.UserID.UUID != userID {
+			continue
+		}
+		return rule, true
+	}
+	return database.CaptureRule{}, false
+}
+
+// routePath drops the method from a ServeMux pattern.
+func routePath(pattern string) string {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+	return pattern
+}
+
+// middlewareCapture records the requests covered by a capture rule. Each
+// capture is stored after the response is written, so only requests being
+// captured pay for it.
+func (s *Server) middlewareCapture(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.captureRules.Load() == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		_, pattern := mux.Handler(r)
+		userID, _ := s.authenticate(r)
+		rule, ok := s.captureRuleFor(pattern, userID)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		reqBody, reqTruncated, err := peekBody(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &captureWriter{ResponseWriter: w}
+		start := time.Now()
+		next.ServeHTTP(cw, r)
+		elapsed := time.Since(start)
+
+		params := database.CreateRequestCaptureParams{
+			ID:                    uuid.New(),
+			RuleID:                rule.ID,
+			Method:                r.Method,
+			Url:                   r.URL.RequestURI(),
+			Route:                 pattern,
+			RequestHeaders:        sanitizeHeaders(r.Header),
+			RequestBody:           sanitizeBody(reqBody, reqTruncated),
+			RequestBodyTruncated:  reqTruncated,
+			Status:                int32(cw.statusCode()),
+			ResponseHeaders:       sanitizeHeaders(cw.Header()),
+			ResponseBody:          sanitizeBody(cw.body.Bytes(), cw.truncated),
+			ResponseBodyTruncated: cw.truncated,
+			DurationMs:            int32(elapsed.Milliseconds()),
+		}
+		if userID != uuid.Nil {
+			params.UserID = uuid.NullUUID{UUID: userID, Valid: true}
+		}
+		// The request context may already be cancelled once the response
+		// is written, but the capture must still land.
+		if _, err := s.db.CreateRequestCapture(context.WithoutCancel(r.Context()), params); err != nil {
+			fmt.Println("Error storing request capture:", err)
+		}
+	})
+}
+
+// peekBody reads up to maxCaptureBodyBytes of r's body and puts it back,
+// so the handler still sees all of it.
+func peekBody(r *http.Request) (body []byte, truncated bool, err error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, false, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxCaptureBodyBytes+1))
+	if err != nil {
+		return nil, false, err
+	}
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+	if len(data) > maxCaptureBodyBytes {
+		return data[:maxCaptureBodyBytes], true, nil
+	}
+	return data, false, nil
+}
+
+// captureWriter keeps the status and the start of the body written
+// through it.
+type captureWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (cw *captureWriter) WriteHeader(code int) {
+	if cw.status == 0 {
+		cw.status = code
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *captureWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if room := maxCaptureBodyBytes - cw.body.Len(); len(p) > room {
+		cw.body.Write(p[:room])
+		cw.truncated = true
+	} else {
+		cw.body.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+func (cw *captureWriter) Flush() {
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *captureWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *captureWriter) statusCode() int {
+	if cw.status == 0 {
+		return http.StatusOK
+	}
+	return cw.status
+}
+
+// sanitizeHeaders is h as JSON, with the values of
+// captureRedactedHeaders replaced.
+func sanitizeHeaders(h http.Header) json.RawMessage {
+	clean := make(http.Header, len(h))
+	for name, values := range h {
+		if slices.Contains(captureRedactedHeaders, http.CanonicalHeaderKey(name)) {
+			clean[name] = []string{redactedValue}
+		} else {
+			clean[name] = values
+		}
+	}
+	data, err := json.Marshal(clean)
+	if err != nil {
+		return json.RawMessage(`{}`)
+	}
+	return data
+}
+
+// sanitizeBody redacts captureRedactedFields in a JSON body. A body that
+// mentions one of them but can't be parsed, such as a truncated one, is
+// dropped.
+func sanitizeBody(body []byte, truncated bool) []byte {
+	if len(body) == 0 {
+		return []byte{}
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if truncated || dec.Decode(&v) != nil {
+		for _, field := range captureRedactedFields {
+			if bytes.Contains(body, []byte(field)) {
+				return []byte{}
+			}
+		}
+		return body
+	}
+	if !redactJSON(v) {
+		return body
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return []byte{}
+	}
+	return data
+}
+
+// redactJSON replaces captureRedactedFields in v, reporting whether it
+// found any.
+func redactJSON(v interface{}) bool {
+	found := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if slices.Contains(captureRedactedFields, k) {
+				v[k] = redactedValue
+				found = true
+			} else if redactJSON(child) {
+				found = true
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if redactJSON(child) {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+type captureRuleRequest struct {
+	Route  string     `json:"route"`
+	UserID *uuid.UUID `json:"user_id"`
+	// Duration is how long to capture for, such as "30m".
+	Duration    string `json:"duration"`
+	MaxCaptures int32  `json:"max_captures"`
+}
+
+type captureRuleResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   Timestamp  `json:"created_at"`
+	CreatedBy   *uuid.UUID `json:"created_by"`
+	Route       string     `json:"route,omitempty"`
+	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	ExpiresAt   Timestamp  `json:"expires_at"`
+	MaxCaptures int32      `json:"max_captures"`
+}
+
+func newCaptureRuleResponse(rule database.CaptureRule) captureRuleResponse {
+	resp := captureRuleResponse{
+		ID:          rule.ID,
+		CreatedAt:   Timestamp{rule.CreatedAt},
+		Route:       rule.Route.String,
+		ExpiresAt:   Timestamp{rule.ExpiresAt},
+		MaxCaptures: rule.MaxCaptures,
+	}
+	if rule.CreatedBy.Valid {
+		resp.CreatedBy = &rule.CreatedBy.UUID
+	}
+	if rule.UserID.Valid {
+		resp.UserID = &rule.UserID.UUID
+	}
+	return resp
+}
+
+// handlerAdminCaptureRulesCreate starts capturing requests to a route, by
+// a user, or both. A rule's route must be one of mux's patterns.
+func (s *Server) handlerAdminCaptureRulesCreate(mux *Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req captureRuleRequest
+		if err := s.decodeJSON(r, &req); err != nil {
+			jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+			return
+		}
+		if req.Route == "" && req.UserID == nil {
+			jsonResponse(w, http.StatusBadRequest, "A capture rule needs a route, a user_id or both")
+			return
+		}
+		if req.Route != "" && (!slices.Contains(mux.Patterns(), req.Route) || strings.HasPrefix(routePath(req.Route), captureAdminPath)) {
+			jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("Unknown route %q: use a pattern from the route list", req.Route))
+			return
+		}
+		duration := defaultCaptureDuration
+		if req.Duration != "" {
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil || d <= 0 || d > maxCaptureDuration {
+				jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("duration must be a positive duration of at most %s, such as 30m", maxCaptureDuration))
+				return
+			}
+			duration = d
+		}
+		if req.MaxCaptures == 0 {
+			req.MaxCaptures = defaultMaxCaptures
+		}
+		if req.MaxCaptures < 1 || req.MaxCaptures > maxMaxCaptures {
+			jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("max_captures must be between 1 and %d", maxMaxCaptures))
+			return
+		}
+
+		ctx := r.Context()
+		admin := adminFromContext(ctx)
+		params := database.CreateCaptureRuleParams{
+			ID:          uuid.New(),
+			CreatedBy:   uuid.NullUUID{UUID: admin.ID, Valid: true},
+			Route:       nullString(req.Route),
+			ExpiresAt:   s.clock.Now().UTC().Add(duration),
+			MaxCaptures: req.MaxCaptures,
+		}
+		if req.UserID != nil {
+			if _, err := s.db.GetUserByID(ctx, *req.UserID); errors.Is(err, sql.ErrNoRows) {
+				jsonResponse(w, http.StatusNotFound, "User not found")
+				return
+			} else if err != nil {
+				jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+				return
+			}
+			params.UserID = uuid.NullUUID{UUID: *req.UserID, Valid: true}
+		}
+
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		defer tx.Rollback()
+
+		rule, err := tx.CreateCaptureRule(ctx, params)
+		if err != nil {
+			fmt.Println("Error creating capture rule:", err)
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		err = recordAudit(ctx, tx, admin.ID, "capture.start", rule.ID, map[string]interface{}{
+			"route":        req.Route,
+			"user_id":      req.UserID,
+			"expires_at":   rule.ExpiresAt,
+			"max_captures": rule.MaxCaptures,
+		})
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+
+		if err := s.reloadCaptureRules(ctx); err != nil {
+			fmt.Println("Error reloading capture rules:", err)
+		}
+		jsonResponse(w, http.StatusCreated, newCaptureRuleResponse(rule))
+	}
+}
+
+// handlerAdminCaptureRulesList lists the rules still capturing.
+func (s *Server) handlerAdminCaptureRulesList(w http.ResponseWriter, r *http.Request) {
+	rules, err := s.db.ListActiveCaptureRules(r.Context())
+	if err != nil {
+		fmt.Println("Error listing capture rules:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	response := make([]captureRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		response = append(response, newCaptureRuleResponse(rule))
+	}
+	jsonResponse(w, http.StatusOK, response)
+}
+
+// handlerAdminCaptureRuleStop stops a rule early. What it captured is kept
+// until retention removes it.
+func (s *Server) handlerAdminCaptureRuleStop(w http.ResponseWriter, r *http.Request) {
+	ruleID, ok := pathUUID(w, r, "ruleID")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	admin := adminFromContext(ctx)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.StopCaptureRule(ctx, ruleID); errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "No active capture rule with that ID")
+		return
+	} else if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := recordAudit(ctx, tx, admin.ID, "capture.stop", ruleID, nil); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := s.reloadCaptureRules(ctx); err != nil {
+		fmt.Println("Error reloading capture rules:", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type captureSummaryResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  Timestamp  `json:"created_at"`
+	RuleID     uuid.UUID  `json:"rule_id"`
+	UserID     *uuid.UUID `json:"user_id,omitempty"`
+	Method     string     `json:"method"`
+	URL        string     `json:"url"`
+	Route      string     `json:"route"`
+	Status     int32      `json:"status"`
+	DurationMS int32      `json:"duration_ms"`
+}
+
+type captureResponse struct {
+	captureSummaryResponse
+	RequestHeaders        json.RawMessage `json:"request_headers"`
+	RequestBody           string          `json:"request_body"`
+	RequestBodyTruncated  bool            `json:"request_body_truncated"`
+	ResponseHeaders       json.RawMessage `json:"response_headers"`
+	ResponseBody          string          `json:"response_body"`
+	ResponseBodyTruncated bool            `json:"response_body_truncated"`
+}
+
+// handlerAdminCapturesList lists captures, newest first, optionally for
+// one rule.
+func (s *Server) handlerAdminCapturesList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	params := database.ListRequestCapturesParams{RowLimit: defaultCapturePageSize}
+	if v := query.Get("rule_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			jsonResponse(w, http.StatusBadRequest, "rule_id must be a UUID")
+			return
+		}
+		params.RuleID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxCapturePageSize {
+			jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxCapturePageSize))
+			return
+		}
+		params.RowLimit = int32(n)
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			jsonResponse(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		params.RowOffset = int32(n)
+	}
+
+	captures, err := s.db.ListRequestCaptures(r.Context(), params)
+	if err != nil {
+		fmt.Println("Error listing request captures:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	response := make([]captureSummaryResponse, 0, len(captures))
+	for _, c := range captures {
+		response = append(response, captureSummaryResponse{
+			ID:         c.ID,
+			CreatedAt:  Timestamp{c.CreatedAt},
+			RuleID:     c.RuleID,
+			UserID:     nullUUIDPtr(c.UserID),
+			Method:     c.Method,
+			URL:        c.Url,
+			Route:      c.Route,
+			Status:     c.Status,
+			DurationMS: c.DurationMs,
+		})
+	}
+	jsonResponse(w, http.StatusOK, response)
+}
+
+// handlerAdminCaptureGet shows one capture in full.
+func (s *Server) handlerAdminCaptureGet(w http.ResponseWriter, r *http.Request) {
+	captureID, ok := pathUUID(w, r, "captureID")
+	if !ok {
+		return
+	}
+	c, err := s.db.GetRequestCapture(r.Context(), captureID)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "Capture not found")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, captureResponse{
+		captureSummaryResponse: captureSummaryResponse{
+			ID:         c.ID,
+			CreatedAt:  Timestamp{c.CreatedAt},
+			RuleID:     c.RuleID,
+			UserID:     nullUUIDPtr(c.UserID),
+			Method:     c.Method,
+			URL:        c.Url,
+			Route:      c.Route,
+			Status:     c.Status,
+			DurationMS: c.DurationMs,
+		},
+		RequestHeaders:        c.RequestHeaders,
+		RequestBody:           string(c.RequestBody),
+		RequestBodyTruncated:  c.RequestBodyTruncated,
+		ResponseHeaders:       c.ResponseHeaders,
+		ResponseBody:          string(c.ResponseBody),
+		ResponseBodyTruncated: c.ResponseBodyTruncated,
+	})
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
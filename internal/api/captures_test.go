@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type captureStore struct {
+	fakeStore
+	rules    []database.CaptureRule
+	captures []database.CreateRequestCaptureParams
+	audits   []string
+}
+
+type captureTx struct {
+	*captureStore
+}
+
+func (s *captureStore) Begin(ctx context.Context) (Tx, error) {
+	return captureTx{s}, nil
+}
+
+func (tx captureTx) Commit() error   { return nil }
+func (tx captureTx) Rollback() error { return nil }
+
+func (s *captureStore) CreateCaptureRule(ctx context.Context, arg database.CreateCaptureRuleParams) (database.CaptureRule, error) {
+	rule := database.CaptureRule{
+		ID:          arg.ID,
+		CreatedAt:   testNow,
+		CreatedBy:   arg.CreatedBy,
+		Route:       arg.Route,
+		UserID:      arg.UserID,
+		ExpiresAt:   arg.ExpiresAt,
+		MaxCaptures: arg.MaxCaptures,
+	}
+	s.rules = append(s.rules, rule)
+	return rule, nil
+}
+
+func (s *captureStore) ListActiveCaptureRules(ctx context.Context) ([]database.CaptureRule, error) {
+	return s.rules, nil
+}
+
+func (s *captureStore) CreateRequestCapture(ctx context.Context, arg database.CreateRequestCaptureParams) (int64, error) {
+	s.captures = append(s.captures, arg)
+	return 1, nil
+}
+
+func (s *captureStore) CreateAuditLogEntry(ctx context.Context, arg database.CreateAuditLogEntryParams) error {
+	s.audits = append(s.audits, arg.Action)
+	return nil
+}
+
+func TestCapture_RecordsSanitizedRequests(t *testing.T) {
+	admin := newTestUser(t, "admin@example.com", "pa55word")
+	admin.Role = RoleAdmin
+	store := &captureStore{fakeStore: fakeStore{users: map[string]database.User{admin.Email: admin}}}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)}))
+
+	token, err := auth.MakeJWT(admin.ID, cfg.JWTSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(http.MethodPost, "/admin/captures/rules", `{"route":"POST /api/login","duration":"30m","max_captures":5}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("creating a rule: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var rule captureRuleResponse
+	if err := json.NewDecoder(rec.Body).Decode(&rule); err != nil {
+		t.Fatalf("decoding rule: %v", err)
+	}
+	if !rule.ExpiresAt.Equal(testNow.Add(30*time.Minute)) || rule.MaxCaptures != 5 {
+		t.Errorf("expected a rule for 30m and 5 captures, got %+v", rule)
+	}
+	if len(store.audits) != 1 || store.audits[0] != "capture.start" {
+		t.Errorf("expected the rule to be audited, got %v", store.audits)
+	}
+
+	if rec := send(http.MethodPost, "/api/login", `{"email":"admin@example.com","password":"wrong"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("login: expected 401, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := send(http.MethodGet, "/api/healthz", ""); rec.Code != http.StatusOK {
+		t.Fatalf("healthz: expected 200, got %d", rec.Code)
+	}
+	if len(store.captures) != 1 {
+		t.Fatalf("expected only the login to be captured, got %d captures", len(store.captures))
+	}
+	c := store.captures[0]
+	if c.RuleID != rule.ID || c.Route != "POST /api/login" || c.Status != http.StatusUnauthorized || c.UserID.UUID != admin.ID {
+		t.Errorf("unexpected capture %+v", c)
+	}
+	if got := string(c.RequestBody); !strings.Contains(got, `"password":"[REDACTED]"`) || strings.Contains(got, "wrong") {
+		t.Errorf("expected the password redacted, got %s", got)
+	}
+	if got := string(c.RequestHeaders); !strings.Contains(got, `"Authorization":["[REDACTED]"]`) || strings.Contains(got, token) {
+		t.Errorf("expected the token redacted, got %s", got)
+	}
+}
+
+func TestCapture_RejectsBadRules(t *testing.T) {
+	admin := newTestUser(t, "admin@example.com", "pa55word")
+	admin.Role = RoleAdmin
+	store := &captureStore{fakeStore: fakeStore{users: map[string]database.User{admin.Email: admin}}}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)}))
+	token, err := auth.MakeJWT(admin.ID, cfg.JWTSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+
+	for name, body := range map[string]string{
+		"no route or user": `{"duration":"1h"}`,
+		"unknown route":    `{"route":"GET /nowhere"}`,
+		"capture route":    `{"route":"GET /admin/captures"}`,
+		"duration":         `{"route":"POST /api/login","duration":"48h"}`,
+		"max captures":     `{"route":"POST /api/login","max_captures":5000}`,
+		"unknown user":     `{"user_id":"` + uuid.NewString() + `"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/admin/captures/rules", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest && rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 400 or 404, got %d", name, rec.Code)
+		}
+	}
+	if len(store.rules) != 0 {
+		t.Errorf("expected no rules, got %d", len(store.rules))
+	}
+}
+
+func TestSanitizeBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		truncated bool
+		want      string
+	}{
+		{"no secrets", `{"body":"hello"}`, false, `{"body":"hello"}`},
+		{"nested", `{"user":{"token":"abc","n":12345678901234567890}}`, false, `{"user":{"n":12345678901234567890,"token":"[REDACTED]"}}`},
+		{"truncated with secrets", `{"password":"hun`, true, ``},
+		{"truncated without", `{"body":"hel`, true, `{"body":"hel`},
+		{"form", `email=a&password=b`, false, ``},
+		{"plain text", `hello`, false, `hello`},
+	}
+	for _, tt := range tests {
+		if got := string(sanitizeBody([]byte(tt.body), tt.truncated)); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"chirpy/internal/database"
+)
+
+// Chaos mode injects faults into requests on the dev platform, so client
+// retry logic, our timeouts and the database breakers can be tried against
+// realistic failures. Its rules are set through /admin/chaos and kept in
+// memory by the server that receives them.
+
+// chaosPath is where chaos mode is configured. Its own routes are never
+// faulted, so it can always be turned off.
+const chaosPath = "/admin/chaos"
+
+// chaosAllRoutes is the route of a rule that applies to every route
+// without a rule of its own.
+const chaosAllRoutes = "*"
+
+const maxChaosLatency = time.Minute
+
+// errChaosDBDropped is the cause of the cancelled context that the queries
+// of a request picked to lose its database connection run with.
+var errChaosDBDropped = errors.New("chaos: database connection dropped")
+
+type chaosDBDropKey struct{}
+
+// chaosRule says which faults to inject into a route, and into what
+// percentage of its requests. Each fault is rolled for separately.
+type chaosRule struct {
+	// Route is a registered pattern such as "GET /api/chirps", or "*".
+	Route     string `json:"route"`
+	LatencyMS int    `json:"latency_ms"`
+	// LatencyPercent of requests wait LatencyMS before being handled.
+	LatencyPercent float64 `json:"latency_percent"`
+	// ErrorPercent of requests are answered with a 500.
+	ErrorPercent float64 `json:"error_percent"`
+	// DBDropPercent of requests have every query fail as though the
+	// connection to the database had dropped.
+	DBDropPercent float64 `json:"db_drop_percent"`
+}
+
+type chaosConfig struct {
+	Rules []chaosRule `json:"rules"`
+}
+
+// chaosHit rolls for a fault injected into percent of requests.
+func chaosHit(percent float64) bool {
+	return percent > 0 && rand.Float64()*100 < percent
+}
+
+// chaosRuleFor returns the rule for pattern: its own, or the rule for
+// every route.
+func (s *Server) chaosRuleFor(pattern string) (chaosRule, bool) {
+	rules := s.chaosRules.Load()
+	if rules == nil || pattern == "" {
+		return chaosRule{}, false
+	}
+	if _, path, _ := strings.Cut(pattern, " "); path == chaosPath {
+		return chaosRule{}, false
+	}
+	var fallback *chaosRule
+	for i, rule := range *rules {
+		switch rule.Route {
+		case pattern:
+			return rule, true
+		case chaosAllRoutes:
+			fallback = &(*rules)[i]
+		}
+	}
+	if fallback == nil {
+		return chaosRule{}, false
+	}
+	return *fallback, true
+}
+
+// middlewareChaos injects the faults of the rule for each request's route.
+// It only exists on the dev platform.
+func (s *Server) middlewareChaos(mux *http.ServeMux, next http.Handler) http.Handler {
+	if s.config.Platform != "dev" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		rule, ok := s.chaosRuleFor(pattern)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if rule.LatencyMS > 0 && chaosHit(rule.LatencyPercent) {
+			timer := time.NewTimer(time.Duration(rule.LatencyMS) * time.Millisecond)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+				return
+			}
+		}
+		if chaosHit(rule.ErrorPercent) {
+			jsonResponse(w, http.StatusInternalServerError, errorResponse{Error: "Injected fault"})
+			return
+		}
+		if chaosHit(rule.DBDropPercent) {
+			r = r.WithContext(context.WithValue(r.Context(), chaosDBDropKey{}, true))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// chaosContext is ctx, cancelled with errChaosDBDropped if its request was
+// picked to lose its database connection.
+func chaosContext(ctx context.Context) context.Context {
+	if ctx.Value(chaosDBDropKey{}) == nil {
+		return ctx
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	cancel(errChaosDBDropped)
+	return ctx
+}
+
+// chaosDB runs the queries of requests picked to lose their database
+// connection with a cancelled context, so they fail the way they would on
+// a real drop, and the breakers count them.
+type chaosDB struct {
+	db database.DBTX
+}
+
+func (c chaosDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.db.ExecContext(chaosContext(ctx), query, args...)
+}
+
+func (c chaosDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.db.PrepareContext(chaosContext(ctx), query)
+}
+
+func (c chaosDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.db.QueryContext(chaosContext(ctx), query, args...)
+}
+
+func (c chaosDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return c.db.QueryRowContext(chaosContext(ctx), query, args...)
+}
+
+// handlerAdminChaosGet shows the chaos rules in force.
+func (s *Server) handlerAdminChaosGet(w http.ResponseWriter, r *http.Request) {
+	resp := chaosConfig{Rules: []chaosRule{}}
+	if rules := s.chaosRules.Load(); rules != nil {
+		resp.Rules = *rules
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+// handlerAdminChaosSet replaces the chaos rules. A rule's route must be
+// one of mux's patterns.
+func (s *Server) handlerAdminChaosSet(mux *Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req chaosConfig
+		if err := s.decodeJSON(r, &req); err != nil {
+			jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+			return
+		}
+
+		patterns := mux.Patterns()
+		seen := make(map[string]bool, len(req.Rules))
+		for _, rule := range req.Rules {
+			if rule.Route != chaosAllRoutes && !slices.Contains(patterns, rule.Route) {
+				jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("Unknown route %q: use a pattern from the route list, or %q", rule.Route, chaosAllRoutes))
+				return
+			}
+			if _, path, _ := strings.Cut(rule.Route, " "); path == chaosPath {
+				jsonResponse(w, http.StatusBadRequest, "Chaos mode's own routes can't be faulted")
+				return
+			}
+			if seen[rule.Route] {
+				jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("More than one rule for %q", rule.Route))
+				return
+			}
+			seen[rule.Route] = true
+			if rule.LatencyMS < 0 || time.Duration(rule.LatencyMS)*time.Millisecond > maxChaosLatency {
+				jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("latency_ms must be between 0 and %d", maxChaosLatency.Milliseconds()))
+				return
+			}
+			for _, p := range []float64{rule.LatencyPercent, rule.ErrorPercent, rule.DBDropPercent} {
+				if p < 0 || p > 100 {
+					jsonResponse(w, http.StatusBadRequest, "Percentages must be between 0 and 100")
+					return
+				}
+			}
+		}
+
+		if len(req.Rules) == 0 {
+			s.chaosRules.Store(nil)
+			req.Rules = []chaosRule{}
+		} else {
+			s.chaosRules.Store(&req.Rules)
+			fmt.Printf("chaos: %d fault rules in force\n", len(req.Rules))
+		}
+		jsonResponse(w, http.StatusOK, req)
+	}
+}
+
+// handlerAdminChaosClear turns chaos mode off.
+func (s *Server) handlerAdminChaosClear(w http.ResponseWriter, r *http.Request) {
+	s.chaosRules.Store(nil)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"chirpy/internal/config"
+)
+
+func TestChaos_InjectsFaults(t *testing.T) {
+	h := newTestServer(t, &fakeStore{})
+
+	rec := do(h, http.MethodPut, "/admin/chaos", `{"rules":[{"route":"GET /api/healthz","error_percent":100}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("setting rules: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(h, http.MethodGet, "/api/healthz", ""); rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected an injected 500, got %d", rec.Code)
+	}
+	if rec := do(h, http.MethodGet, "/readyz", ""); rec.Code == http.StatusInternalServerError {
+		t.Errorf("expected other routes to be left alone, got %d", rec.Code)
+	}
+
+	do(h, http.MethodPut, "/admin/chaos", `{"rules":[{"route":"*","latency_ms":50,"latency_percent":100}]}`)
+	start := time.Now()
+	if rec := do(h, http.MethodGet, "/api/healthz", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected a delayed 200, got %d", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected the request to be delayed by 50ms, took %v", elapsed)
+	}
+	// Chaos mode can always be turned off.
+	start = time.Now()
+	if rec := do(h, http.MethodDelete, "/admin/chaos", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("clearing rules: expected 204, got %d", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+		t.Errorf("expected /admin/chaos not to be delayed, took %v", elapsed)
+	}
+	if rec := do(h, http.MethodGet, "/admin/chaos", ""); rec.Body.String() != `{"rules":[]}` {
+		t.Errorf("expected no rules, got %s", rec.Body)
+	}
+}
+
+func TestChaos_RejectsBadRules(t *testing.T) {
+	h := newTestServer(t, &fakeStore{})
+	for name, body := range map[string]string{
+		"unknown route":   `{"rules":[{"route":"GET /nowhere","error_percent":10}]}`,
+		"chaos route":     `{"rules":[{"route":"DELETE /admin/chaos","error_percent":10}]}`,
+		"duplicate route": `{"rules":[{"route":"*","error_percent":10},{"route":"*","db_drop_percent":10}]}`,
+		"percentage":      `{"rules":[{"route":"*","error_percent":101}]}`,
+		"latency":         `{"rules":[{"route":"*","latency_ms":600000,"latency_percent":10}]}`,
+	} {
+		if rec := do(h, http.MethodPut, "/admin/chaos", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+}
+
+func TestChaos_DevOnly(t *testing.T) {
+	cfg := &config.Config{Platform: "production", JWTSecret: "test-secret"}
+	h := NewRouter(NewServer(cfg, Deps{Store: &fakeStore{}, Clock: fixedClock(testNow)}))
+	if rec := do(h, http.MethodPut, "/admin/chaos", `{"rules":[{"route":"*","error_percent":100}]}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected chaos mode to be missing outside dev, got %d", rec.Code)
+	}
+}
+
+func TestChaos_DropsDBConnections(t *testing.T) {
+	// Nothing listens here, but a dropped query never gets as far as
+	// connecting.
+	db, err := sql.Open("postgres", "postgres://localhost:1/chirpy?sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	breakers := NewDBBreakers(config.BreakerConfig{Threshold: 1, Cooldown: time.Minute}, fixedClock(testNow))
+	store := NewSQLStore(db, breakers, nil)
+
+	ctx := context.WithValue(context.Background(), chaosDBDropKey{}, true)
+	if _, err := store.GetUserByID(ctx, [16]byte{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the query to fail, got %v", err)
+	}
+	if got := breakers.read.Stats().Trips; got != 1 {
+		t.Errorf("expected the dropped query to trip the read breaker, got %d trips", got)
+	}
+}
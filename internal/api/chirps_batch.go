@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"chirpy/internal/analytics"
+	"chirpy/internal/contentfilter"
+	"chirpy/internal/database"
+	"chirpy/internal/linkscan"
+
+	"github.com/google/uuid"
+)
+
+// maxChirpBatch is how many chirps one batch request may create.
+const maxChirpBatch = 50
+
+type chirpBatchRequest struct {
+	Chirps []struct {
+		Body string `json:"body"`
+	} `json:"chirps"`
+}
+
+// chirpBatchResult reports what happened to one item of a batch, in the
+// order the items were sent.
+type chirpBatchResult struct {
+	Status int            `json:"status"`
+	Chirp  *chirpResponse `json:"chirp,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// handlerChirpsBatch creates up to maxChirpBatch chirps for the caller with
+// a single insert. Each item is validated on its own: invalid items are
+// reported in their result and the rest are still created.
+func (s *Server) handlerChirpsBatch(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Couldn't validate token")
+		return
+	}
+
+	var request chirpBatchRequest
+	if err := s.decodeJSON(r, &request); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	if len(request.Chirps) == 0 || len(request.Chirps) > maxChirpBatch {
+		jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("A batch must hold between 1 and %d chirps", maxChirpBatch))
+		return
+	}
+
+	ctx := r.Context()
+	author, _ := s.db.GetUserByID(ctx, userID)
+	maxLen := s.maxChirpLength(author)
+	results := make([]chirpBatchResult, len(request.Chirps))
+	params := database.CreateChirpsParams{UserID: userID}
+	positions := make(map[uuid.UUID]int)
+	flags := make(map[uuid.UUID][]contentfilter.Rule)
+	for i, item := range request.Chirps {
+		body, flagged, err := s.prepareChirpBody(item.Body, maxLen)
+		switch {
+		case errors.Is(err, errChirpTooLong):
+			results[i] = chirpBatchResult{Status: http.StatusBadRequest, Error: "Chirp is too long"}
+			continue
+		case errors.Is(err, errChirpBlocked):
+			results[i] = chirpBatchResult{Status: http.StatusBadRequest, Error: "Chirp contains blocked content"}
+			continue
+		}
+
+		id := uuid.New()
+		params.Ids = append(params.Ids, id)
+		params.Bodies = append(params.Bodies, body)
+		positions[id] = i
+		if len(flagged) > 0 {
+			flags[id] = flagged
+		}
+	}
+
+	if len(params.Ids) > 0 {
+		chirps, err := s.createChirpBatch(ctx, author, params)
+		if err != nil {
+			fmt.Println("Error creating chirps:", err)
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		s.responseCache.invalidate()
+
+		for _, chirp := range chirps {
+			s.flagChirp(ctx, chirp.ID, flags[chirp.ID])
+			s.track(analytics.ChirpCreated, userID, map[string]string{"batch": "true"})
+			results[positions[chirp.ID]] = chirpBatchResult{
+				Status: http.StatusCreated,
+				Chirp: &chirpResponse{
+					ID:             chirp.ID,
+					CreatedAt:      Timestamp{chirp.CreatedAt},
+					UpdatedAt:      Timestamp{chirp.UpdatedAt},
+					Body:           chirp.Body,
+					UserID:         chirp.UserID,
+					AuthorVerified: author.Verified,
+					ShortURL:       s.shortURL(r.Context(), chirp.ID),
+				},
+			}
+		}
+	}
+
+	jsonResponse(w, http.StatusOK, struct {
+		Results []chirpBatchResult `json:"results"`
+	}{results})
+}
+
+// createChirpBatch inserts the batch and, like insertChirp, records each
+// chirp's links and outbox events in the same transaction.
+func (s *Server) createChirpBatch(ctx context.Context, author database.User, params database.CreateChirpsParams) ([]database.Chirp, error) {
+	federate := s.federationEnabled() && !author.Shadowbanned && !author.BannedAt.Valid
+	hasLinks := slices.ContainsFunc(params.Bodies, func(body string) bool {
+		return len(linkscan.ExtractURLs(body)) > 0
+	})
+	if !federate && !hasLinks {
+		return s.db.CreateChirps(ctx, params)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	chirps, err := tx.CreateChirps(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	for _, chirp := range chirps {
+		if federate {
+			if err := recordChirpCreated(ctx, tx, chirp); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.recordChirpLinks(ctx, tx, chirp.ID, linkscan.ExtractURLs(chirp.Body)); err != nil {
+			return nil, err
+		}
+	}
+	return chirps, tx.Commit()
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+)
+
+// batchStore records CreateChirps calls.
+type batchStore struct {
+	contractStore
+	calls []database.CreateChirpsParams
+}
+
+func (b *batchStore) CreateChirps(ctx context.Context, arg database.CreateChirpsParams) ([]database.Chirp, error) {
+	b.calls = append(b.calls, arg)
+	chirps := make([]database.Chirp, len(arg.Ids))
+	// Postgres doesn't promise to return rows in insertion order.
+	for i := range arg.Ids {
+		j := len(arg.Ids) - 1 - i
+		chirps[i] = database.Chirp{ID: arg.Ids[j], Body: arg.Bodies[j], UserID: arg.UserID, CreatedAt: testNow, UpdatedAt: testNow}
+	}
+	return chirps, nil
+}
+
+func TestChirpsBatch(t *testing.T) {
+	user := newTestUser(t, "saul@example.com", "04234")
+	store := &batchStore{contractStore: contractStore{fakeStore{users: map[string]database.User{user.Email: user}}}}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store}))
+	token, err := auth.MakeJWT(user.ID, "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	post := func(body string, authenticated bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/chirps/batch", strings.NewReader(body))
+		if authenticated {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	body := `{"chirps":[{"body":"first"},{"body":"` + strings.Repeat("a", 141) + `"},{"body":"a kerfuffle"}]}`
+	if rec := post(body, false); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", rec.Code)
+	}
+
+	rec := post(body, true)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Results []chirpBatchResult `json:"results"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(store.calls) != 1 || len(store.calls[0].Ids) != 2 || store.calls[0].UserID != user.ID {
+		t.Fatalf("expected one insert of two chirps by the caller, got %+v", store.calls)
+	}
+	if len(resp.Results) != 3 {
+		t.Fatalf("expected 3 results, got %+v", resp.Results)
+	}
+	if r := resp.Results[0]; r.Status != http.StatusCreated || r.Chirp == nil || r.Chirp.Body != "first" {
+		t.Errorf("result 0: %+v", r)
+	}
+	if r := resp.Results[1]; r.Status != http.StatusBadRequest || r.Chirp != nil || r.Error != "Chirp is too long" {
+		t.Errorf("result 1: %+v", r)
+	}
+	if r := resp.Results[2]; r.Status != http.StatusCreated || r.Chirp == nil || r.Chirp.Body != "a ****" {
+		t.Errorf("result 2: %+v", r)
+	}
+
+	for _, body := range []string{`{"chirps":[]}`, `{`, `{"chirps":[` + strings.Repeat(`{"body":"x"},`, maxChirpBatch) + `{"body":"x"}]}`} {
+		if rec := post(body, true); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %.40s, got %d", body, rec.Code)
+		}
+	}
+	if len(store.calls) != 1 {
+		t.Errorf("rejected batches reached the database: %d calls", len(store.calls))
+	}
+
+	// A batch of nothing but invalid chirps doesn't touch the database.
+	rec = post(fmt.Sprintf(`{"chirps":[{"body":%q}]}`, strings.Repeat("a", 141)), true)
+	if rec.Code != http.StatusOK || len(store.calls) != 1 {
+		t.Errorf("expected 200 without an insert, got %d after %d calls", rec.Code, len(store.calls))
+	}
+}
@@ -0,0 +1,421 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Community roles. Owners appoint moderators; moderators and owners can
+// remove chirps from the community.
+const (
+	communityRoleMember    = "member"
+	communityRoleModerator = "moderator"
+	communityRoleOwner     = "owner"
+)
+
+const (
+	maxCommunityNameLength        = 64
+	maxCommunityDescriptionLength = 500
+	communityTimelinePageSize     = 50
+)
+
+type communityResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	Slug        string     `json:"slug"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	CreatedBy   *uuid.UUID `json:"created_by"`
+	CreatedAt   Timestamp  `json:"created_at"`
+	UpdatedAt   Timestamp  `json:"updated_at"`
+	MemberCount int64      `json:"member_count"`
+}
+
+func newCommunityResponse(c database.Community, members int64) communityResponse {
+	resp := communityResponse{
+		ID:          c.ID,
+		Slug:        c.Slug,
+		Name:        c.Name,
+		Description: c.Description,
+		CreatedAt:   Timestamp{c.CreatedAt},
+		UpdatedAt:   Timestamp{c.UpdatedAt},
+		MemberCount: members,
+	}
+	if c.CreatedBy.Valid {
+		resp.CreatedBy = &c.CreatedBy.UUID
+	}
+	return resp
+}
+
+// loadCommunity looks up the community in the {slug} path parameter.
+func (s *Server) loadCommunity(w http.ResponseWriter, r *http.Request) (database.Community, bool) {
+	community, err := s.db.GetCommunityBySlug(r.Context(), strings.ToLower(r.PathValue("slug")))
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "Community was not found.")
+		return database.Community{}, false
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return database.Community{}, false
+	}
+	return community, true
+}
+
+// communityRole returns userID's role in the community, or "" if they
+// aren't a member.
+func (s *Server) communityRole(r *http.Request, communityID, userID uuid.UUID) (string, error) {
+	role, err := s.db.GetCommunityRole(r.Context(), database.GetCommunityRoleParams{
+		CommunityID: communityID,
+		UserID:      userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+type communityRequest struct {
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// handlerCommunitiesCreate creates a community owned by the caller. Slugs
+// follow the same rules as handles.
+func (s *Server) handlerCommunitiesCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req communityRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	slug, ok := normalizeHandle(req.Slug)
+	if !ok {
+		jsonResponse(w, http.StatusBadRequest, "Slugs must be 3-30 letters, digits or underscores")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	switch {
+	case req.Name == "":
+		jsonResponse(w, http.StatusBadRequest, "A community needs a name")
+		return
+	case len(req.Name) > maxCommunityNameLength:
+		jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("Community names can be at most %d characters", maxCommunityNameLength))
+		return
+	case len(req.Description) > maxCommunityDescriptionLength:
+		jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("Community descriptions can be at most %d characters", maxCommunityDescriptionLength))
+		return
+	}
+
+	row, err := s.db.CreateCommunity(r.Context(), database.CreateCommunityParams{
+		ID:          uuid.New(),
+		Slug:        slug,
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedBy:   uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "communities_slug_key" {
+		jsonResponse(w, http.StatusConflict, "Slug is already taken")
+		return
+	}
+	if err != nil {
+		fmt.Println("Error creating community:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusCreated, newCommunityResponse(database.Community(row), 1))
+}
+
+func (s *Server) handlerCommunitiesGet(w http.ResponseWriter, r *http.Request) {
+	community, ok := s.loadCommunity(w, r)
+	if !ok {
+		return
+	}
+	members, err := s.db.CountCommunityMembers(r.Context(), community.ID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, newCommunityResponse(community, members))
+}
+
+// handlerCommunitiesJoin makes the caller a member. Joining again changes
+// nothing.
+func (s *Server) handlerCommunitiesJoin(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	community, ok := s.loadCommunity(w, r)
+	if !ok {
+		return
+	}
+
+	err = s.db.JoinCommunity(r.Context(), database.JoinCommunityParams{
+		CommunityID: community.ID,
+		UserID:      userID,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerCommunitiesLeave ends the caller's membership. Owners can't leave
+// their own community.
+func (s *Server) handlerCommunitiesLeave(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	community, ok := s.loadCommunity(w, r)
+	if !ok {
+		return
+	}
+
+	role, err := s.communityRole(r, community.ID, userID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	switch role {
+	case "":
+		jsonResponse(w, http.StatusNotFound, "You aren't a member of this community")
+		return
+	case communityRoleOwner:
+		jsonResponse(w, http.StatusBadRequest, "Owners can't leave their community")
+		return
+	}
+
+	_, err = s.db.LeaveCommunity(r.Context(), database.LeaveCommunityParams{
+		CommunityID: community.ID,
+		UserID:      userID,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerCommunityChirpsCreate posts a chirp into a community. Only members
+// can post, and the chirp goes through the same checks as any other.
+func (s *Server) handlerCommunityChirpsCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	community, ok := s.loadCommunity(w, r)
+	if !ok {
+		return
+	}
+
+	role, err := s.communityRole(r, community.ID, userID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if role == "" {
+		jsonResponse(w, http.StatusForbidden, "Join the community to post in it")
+		return
+	}
+
+	var req struct {
+		Body           string `json:"body"`
+		Sensitive      bool   `json:"sensitive"`
+		ContentWarning string `json:"content_warning"`
+	}
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	cw, err := newContentWarning(req.Sensitive, req.ContentWarning)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, "Content warning is too long")
+		return
+	}
+
+	ctx := r.Context()
+	chirp, author, err := s.createChirp(ctx, userID, req.Body)
+	switch {
+	case errors.Is(err, errChirpTooLong):
+		jsonResponse(w, http.StatusBadRequest, "Chirp is too long")
+		return
+	case errors.Is(err, errChirpBlocked):
+		jsonResponse(w, http.StatusBadRequest, "Chirp contains blocked content")
+		return
+	case err != nil:
+		fmt.Println("Error creating chirp:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	err = s.db.AddCommunityChirp(ctx, database.AddCommunityChirpParams{
+		ChirpID:     chirp.ID,
+		CommunityID: community.ID,
+	})
+	if err != nil {
+		fmt.Println("Error adding chirp to community:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if !s.markSensitive(ctx, chirp.ID, cw) {
+		cw = contentWarning{}
+	}
+
+	jsonResponse(w, http.StatusCreated, chirpResponse{
+		ID:             chirp.ID,
+		CreatedAt:      Timestamp{chirp.CreatedAt},
+		UpdatedAt:      Timestamp{chirp.UpdatedAt},
+		Body:           chirp.Body,
+		UserID:         chirp.UserID,
+		AuthorVerified: author.Verified,
+		ShortURL:       s.shortURL(r.Context(), chirp.ID),
+		Sensitive:      cw.Sensitive,
+		ContentWarning: cw.Warning,
+	})
+}
+
+// handlerCommunityChirpsList is a community's timeline, newest first.
+func (s *Server) handlerCommunityChirpsList(w http.ResponseWriter, r *http.Request) {
+	community, ok := s.loadCommunity(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := s.db.ListCommunityChirps(r.Context(), database.ListCommunityChirpsParams{
+		CommunityID: community.ID,
+		ViewerID:    s.viewerID(r),
+		RowLimit:    communityTimelinePageSize,
+	})
+	if err != nil {
+		fmt.Println("Error listing community chirps:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	resp := make([]chirpResponse, 0, len(rows))
+	for _, row := range rows {
+		resp = append(resp, chirpResponse{
+			ID:             row.ID,
+			CreatedAt:      Timestamp{row.CreatedAt},
+			UpdatedAt:      Timestamp{row.UpdatedAt},
+			Body:           row.Body,
+			UserID:         row.UserID,
+			AuthorVerified: row.AuthorVerified,
+			ShortURL:       s.shortURL(r.Context(), row.ID),
+			Sensitive:      row.Sensitive,
+			ContentWarning: row.ContentWarning,
+		})
+	}
+	if err := s.withChirpDetails(r.Context(), resp); err != nil {
+		fmt.Println("Error loading community chirp details:", err)
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+// handlerCommunityChirpsRemove takes a chirp out of a community's timeline.
+// The chirp itself stays; its author can still delete it as usual.
+func (s *Server) handlerCommunityChirpsRemove(w http.ResponseWriter, r *http.Request) {
+	community, ok := s.requireCommunityRole(w, r, communityRoleModerator, communityRoleOwner)
+	if !ok {
+		return
+	}
+	chirpID, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	removed, err := s.db.RemoveCommunityChirp(r.Context(), database.RemoveCommunityChirpParams{
+		ChirpID:     chirpID,
+		CommunityID: community.ID,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if removed == 0 {
+		jsonResponse(w, http.StatusNotFound, "Chirp was not found.")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handlerCommunityModeratorsAdd(w http.ResponseWriter, r *http.Request) {
+	s.setCommunityRole(w, r, communityRoleModerator)
+}
+
+func (s *Server) handlerCommunityModeratorsRemove(w http.ResponseWriter, r *http.Request) {
+	s.setCommunityRole(w, r, communityRoleMember)
+}
+
+// setCommunityRole gives the member in the {userID} path parameter role.
+// Only the owner can appoint or remove moderators.
+func (s *Server) setCommunityRole(w http.ResponseWriter, r *http.Request, role string) {
+	community, ok := s.requireCommunityRole(w, r, communityRoleOwner)
+	if !ok {
+		return
+	}
+	memberID, ok := pathUUID(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	updated, err := s.db.SetCommunityRole(r.Context(), database.SetCommunityRoleParams{
+		CommunityID: community.ID,
+		UserID:      memberID,
+		Role:        role,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if updated == 0 {
+		jsonResponse(w, http.StatusNotFound, "User isn't a member of this community")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireCommunityRole loads the community in the {slug} path parameter and
+// checks that the caller holds one of roles in it.
+func (s *Server) requireCommunityRole(w http.ResponseWriter, r *http.Request, roles ...string) (database.Community, bool) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return database.Community{}, false
+	}
+	community, ok := s.loadCommunity(w, r)
+	if !ok {
+		return database.Community{}, false
+	}
+
+	role, err := s.communityRole(r, community.ID, userID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return database.Community{}, false
+	}
+	for _, allowed := range roles {
+		if role == allowed {
+			return community, true
+		}
+	}
+	jsonResponse(w, http.StatusForbidden, "You don't have permission to do that in this community")
+	return database.Community{}, false
+}
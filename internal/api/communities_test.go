@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// communityStore keeps one community's members and chirps in memory.
+type communityStore struct {
+	contractStore
+	community database.Community
+	roles     map[uuid.UUID]string
+	chirps    map[uuid.UUID]bool
+}
+
+func (c *communityStore) GetCommunityBySlug(ctx context.Context, slug string) (database.Community, error) {
+	if slug != c.community.Slug {
+		return database.Community{}, sql.ErrNoRows
+	}
+	return c.community, nil
+}
+
+func (c *communityStore) GetCommunityRole(ctx context.Context, arg database.GetCommunityRoleParams) (string, error) {
+	role, ok := c.roles[arg.UserID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return role, nil
+}
+
+func (c *communityStore) JoinCommunity(ctx context.Context, arg database.JoinCommunityParams) error {
+	if _, ok := c.roles[arg.UserID]; !ok {
+		c.roles[arg.UserID] = communityRoleMember
+	}
+	return nil
+}
+
+func (c *communityStore) SetCommunityRole(ctx context.Context, arg database.SetCommunityRoleParams) (int64, error) {
+	if role, ok := c.roles[arg.UserID]; !ok || role == communityRoleOwner {
+		return 0, nil
+	}
+	c.roles[arg.UserID] = arg.Role
+	return 1, nil
+}
+
+func (c *communityStore) AddCommunityChirp(ctx context.Context, arg database.AddCommunityChirpParams) error {
+	c.chirps[arg.ChirpID] = true
+	return nil
+}
+
+func (c *communityStore) RemoveCommunityChirp(ctx context.Context, arg database.RemoveCommunityChirpParams) (int64, error) {
+	if !c.chirps[arg.ChirpID] {
+		return 0, nil
+	}
+	delete(c.chirps, arg.ChirpID)
+	return 1, nil
+}
+
+func TestCommunities(t *testing.T) {
+	owner := newTestUser(t, "owner@example.com", "04234")
+	member := newTestUser(t, "member@example.com", "04234")
+	member.ID = contractUserID
+	store := &communityStore{
+		contractStore: contractStore{fakeStore{users: map[string]database.User{owner.Email: owner, member.Email: member}}},
+		community:     database.Community{ID: uuid.New(), Slug: "gophers", Name: "Gophers"},
+		roles:         map[uuid.UUID]string{owner.ID: communityRoleOwner},
+		chirps:        map[uuid.UUID]bool{},
+	}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store}))
+	send := func(user database.User, method, path, body string) int {
+		t.Helper()
+		token, err := auth.MakeJWT(user.ID, "test-secret", time.Hour)
+		if err != nil {
+			t.Fatalf("MakeJWT returned error: %v", err)
+		}
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	steps := []struct {
+		name   string
+		user   database.User
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"non-members can't post", member, http.MethodPost, "/api/communities/gophers/chirps", `{"body":"hi"}`, http.StatusForbidden},
+		{"join", member, http.MethodPost, "/api/communities/gophers/join", "", http.StatusNoContent},
+		{"members can post", member, http.MethodPost, "/api/communities/gophers/chirps", `{"body":"hi"}`, http.StatusCreated},
+		{"members can't moderate", member, http.MethodDelete, "/api/communities/gophers/chirps/" + contractChirpID.String(), "", http.StatusForbidden},
+		{"members can't appoint moderators", member, http.MethodPut, "/api/communities/gophers/moderators/" + member.ID.String(), "", http.StatusForbidden},
+		{"owner appoints a moderator", owner, http.MethodPut, "/api/communities/gophers/moderators/" + member.ID.String(), "", http.StatusNoContent},
+		{"moderators remove chirps", member, http.MethodDelete, "/api/communities/gophers/chirps/" + contractChirpID.String(), "", http.StatusNoContent},
+		{"owners can't leave", owner, http.MethodPost, "/api/communities/gophers/leave", "", http.StatusBadRequest},
+		{"unknown community", member, http.MethodPost, "/api/communities/rustaceans/join", "", http.StatusNotFound},
+	}
+	for _, step := range steps {
+		if got := send(step.user, step.method, step.path, step.body); got != step.want {
+			t.Fatalf("%s: expected %d, got %d", step.name, step.want, got)
+		}
+	}
+	if store.roles[member.ID] != communityRoleModerator {
+		t.Errorf("expected the member to be a moderator, got %q", store.roles[member.ID])
+	}
+}
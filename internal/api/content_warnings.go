@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// How a reader wants sensitive chirps shown. Expanding and collapsing are
+// up to clients; hidden chirps are left out of listings by the server.
+const (
+	sensitiveExpand   = "expand"
+	sensitiveCollapse = "collapse"
+	sensitiveHide     = "hide"
+)
+
+const maxContentWarningLength = 100
+
+var errContentWarningTooLong = errors.New("content warning is too long")
+
+// contentWarning is what an author sets when marking a chirp sensitive.
+// Warning text implies Sensitive.
+type contentWarning struct {
+	Sensitive bool
+	Warning   string
+}
+
+func newContentWarning(sensitive bool, warning string) (contentWarning, error) {
+	warning = strings.TrimSpace(warning)
+	if len(warning) > maxContentWarningLength {
+		return contentWarning{}, errContentWarningTooLong
+	}
+	return contentWarning{Sensitive: sensitive || warning != "", Warning: warning}, nil
+}
+
+// flaggedChirp is a chirp as the listings return it, with its author's
+// mark, for the APIs that render chirps in their own format.
+type flaggedChirp struct {
+	database.Chirp
+	contentWarning
+}
+
+// newFlaggedChirp takes a row from GetVisibleChirp, or from a listing with
+// the same columns.
+func newFlaggedChirp(row database.GetVisibleChirpRow) flaggedChirp {
+	return flaggedChirp{
+		Chirp: database.Chirp{
+			ID:        row.ID,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Body:      row.Body,
+			UserID:    row.UserID,
+		},
+		contentWarning: contentWarning{Sensitive: row.Sensitive, Warning: row.ContentWarning},
+	}
+}
+
+// hidesSensitive reports whether viewer has chosen to hide sensitive
+// chirps, for the streams, which can't filter in a query the way the
+// listings do.
+func (s *Server) hidesSensitive(ctx context.Context, viewer uuid.UUID) (bool, error) {
+	if viewer == uuid.Nil {
+		return false, nil
+	}
+	pref, err := s.db.GetSensitiveContentPreference(ctx, viewer)
+	return pref == sensitiveHide, err
+}
+
+// markSensitive records cw on a chirp that has just been created. It
+// reports whether the chirp is now marked; a failure is logged rather than
+// returned because the chirp is already posted.
+func (s *Server) markSensitive(ctx context.Context, chirpID uuid.UUID, cw contentWarning) bool {
+	if !cw.Sensitive {
+		return false
+	}
+	err := s.db.CreateChirpContentWarning(ctx, database.CreateChirpContentWarningParams{
+		ChirpID: chirpID,
+		Warning: cw.Warning,
+	})
+	if err != nil {
+		fmt.Println("Error saving content warning:", err)
+		return false
+	}
+	return true
+}
+
+type contentPreferences struct {
+	SensitiveContent string `json:"sensitive_content"`
+}
+
+func (s *Server) handlerContentPreferencesGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	pref, err := s.db.GetSensitiveContentPreference(r.Context(), userID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, contentPreferences{SensitiveContent: pref})
+}
+
+// handlerContentPreferencesUpdate sets how the user sees sensitive chirps.
+// Choosing hide drops other authors' sensitive chirps from the listings the
+// user reads; a chirp fetched by ID is still returned, flagged.
+func (s *Server) handlerContentPreferencesUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req contentPreferences
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	switch req.SensitiveContent {
+	case sensitiveExpand, sensitiveCollapse, sensitiveHide:
+	default:
+		jsonResponse(w, http.StatusBadRequest, "sensitive_content must be expand, collapse or hide")
+		return
+	}
+
+	err = s.db.SetSensitiveContentPreference(r.Context(), database.SetSensitiveContentPreferenceParams{
+		UserID:           userID,
+		SensitiveContent: req.SensitiveContent,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, req)
+}
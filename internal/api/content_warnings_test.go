@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/chirpypb"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+)
+
+// contentWarningStore keeps content warnings and preferences in memory.
+type contentWarningStore struct {
+	contractStore
+	warnings    []database.CreateChirpContentWarningParams
+	preferences map[uuid.UUID]string
+}
+
+func (c *contentWarningStore) CreateChirpContentWarning(ctx context.Context, arg database.CreateChirpContentWarningParams) error {
+	c.warnings = append(c.warnings, arg)
+	return nil
+}
+
+func (c *contentWarningStore) GetSensitiveContentPreference(ctx context.Context, userID uuid.UUID) (string, error) {
+	if pref, ok := c.preferences[userID]; ok {
+		return pref, nil
+	}
+	return sensitiveCollapse, nil
+}
+
+func (c *contentWarningStore) SetSensitiveContentPreference(ctx context.Context, arg database.SetSensitiveContentPreferenceParams) error {
+	c.preferences[arg.UserID] = arg.SensitiveContent
+	return nil
+}
+
+func TestChirpsCreate_ContentWarning(t *testing.T) {
+	store := &contentWarningStore{}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store, Clock: fixedClock(testNow)}))
+	user := `"user_id":"` + contractUserID.String() + `"`
+
+	tooLong := strings.Repeat("x", maxContentWarningLength+1)
+	if rec := do(h, http.MethodPost, "/api/chirps", `{"body":"Spoilers",`+user+`,"content_warning":"`+tooLong+`"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a long content warning, got %d", rec.Code)
+	}
+
+	rec := do(h, http.MethodPost, "/api/chirps", `{"body":"Spoilers",`+user+`,"content_warning":" Film ending "}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp chirpResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Sensitive || resp.ContentWarning != "Film ending" {
+		t.Errorf("expected a warning to mark the chirp sensitive, got %+v", resp)
+	}
+	if len(store.warnings) != 1 || store.warnings[0].Warning != "Film ending" {
+		t.Errorf("expected the warning to be stored, got %+v", store.warnings)
+	}
+
+	rec = do(h, http.MethodPost, "/api/chirps", `{"body":"Plain",`+user+`}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "sensitive") || len(store.warnings) != 1 {
+		t.Errorf("expected an unmarked chirp to stay unmarked, got %s", rec.Body)
+	}
+}
+
+func TestContentPreferences(t *testing.T) {
+	store := &contentWarningStore{preferences: map[uuid.UUID]string{}}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store}))
+	user := uuid.New()
+	token, err := auth.MakeJWT(user, "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	send := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/users/me/content", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send(http.MethodGet, ""); !strings.Contains(rec.Body.String(), `"collapse"`) {
+		t.Errorf("expected sensitive chirps to be collapsed by default, got %s", rec.Body)
+	}
+	if rec := send(http.MethodPut, `{"sensitive_content":"blur"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown setting, got %d", rec.Code)
+	}
+	if rec := send(http.MethodPut, `{"sensitive_content":"hide"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if store.preferences[user] != sensitiveHide {
+		t.Errorf("expected the preference to be stored, got %q", store.preferences[user])
+	}
+}
+
+// sensitiveListStore lists its chirps as ListRecentChirps would, leaving
+// out other authors' sensitive ones for readers who hide them.
+type sensitiveListStore struct {
+	contentWarningStore
+	rows []database.ListRecentChirpsRow
+}
+
+func (c *sensitiveListStore) ListRecentChirps(ctx context.Context, arg database.ListRecentChirpsParams) ([]database.ListRecentChirpsRow, error) {
+	rows := []database.ListRecentChirpsRow{}
+	for _, row := range c.rows {
+		if row.Sensitive && row.UserID != arg.ViewerID && c.preferences[arg.ViewerID] == sensitiveHide {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (c *sensitiveListStore) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]database.User, error) {
+	var users []database.User
+	for _, id := range ids {
+		if u, err := c.GetUserByID(ctx, id); err == nil {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+func (c *sensitiveListStore) CountChirpsByUsers(ctx context.Context, ids []uuid.UUID) ([]database.CountChirpsByUsersRow, error) {
+	return nil, nil
+}
+
+func (c *sensitiveListStore) CountRemoteFollowersByUsers(ctx context.Context, ids []uuid.UUID) ([]database.CountRemoteFollowersByUsersRow, error) {
+	return nil, nil
+}
+
+// TestSensitiveChirpsOtherAPIs checks the Mastodon, GraphQL and gRPC
+// timelines flag sensitive chirps and leave them out for readers who hide
+// them, like the REST listings.
+func TestSensitiveChirpsOtherAPIs(t *testing.T) {
+	author := newTestUser(t, "author@example.com", "04234")
+	reader := newTestUser(t, "reader@example.com", "04234")
+	store := &sensitiveListStore{
+		contentWarningStore: contentWarningStore{
+			contractStore: contractStore{fakeStore{users: map[string]database.User{author.Email: author, reader.Email: reader}}},
+			preferences:   map[uuid.UUID]string{reader.ID: sensitiveHide},
+		},
+		rows: []database.ListRecentChirpsRow{{
+			ID:             uuid.New(),
+			Body:           "The butler did it",
+			UserID:         author.ID,
+			Sensitive:      true,
+			ContentWarning: "Film ending",
+		}},
+	}
+	s := NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store, Clock: fixedClock(testNow)})
+	h := NewRouter(s)
+	readerToken := mustMakeJWT(t, reader.ID, "test-secret", time.Hour)
+	send := func(method, path, token, body string) string {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected 200, got %d: %s", method, path, rec.Code, rec.Body)
+		}
+		return rec.Body.String()
+	}
+
+	var statuses []mastodonStatus
+	if err := json.Unmarshal([]byte(send(http.MethodGet, "/api/v1/timelines/public", "", "")), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || !statuses[0].Sensitive || statuses[0].SpoilerText != "Film ending" {
+		t.Errorf("mastodon: expected a sensitive status with spoiler text, got %+v", statuses)
+	}
+	if body := send(http.MethodGet, "/api/v1/timelines/home", readerToken, ""); strings.TrimSpace(body) != "[]" {
+		t.Errorf("mastodon: expected the chirp hidden from the reader, got %s", body)
+	}
+
+	timeline := `{"query":"{ timeline { sensitive contentWarning } }"}`
+	if body := send(http.MethodPost, "/api/graphql", "", timeline); !strings.Contains(body, `"timeline":[{"sensitive":true,"contentWarning":"Film ending"}]`) {
+		t.Errorf("graphql: expected a flagged chirp, got %s", body)
+	}
+	if body := send(http.MethodPost, "/api/graphql", readerToken, timeline); !strings.Contains(body, `"timeline":[]`) {
+		t.Errorf("graphql: expected the chirp hidden from the reader, got %s", body)
+	}
+
+	g := &grpcServer{srv: s}
+	resp, err := g.GetTimeline(context.Background(), &chirpypb.GetTimelineRequest{})
+	if err != nil || len(resp.Chirps) != 1 || !resp.Chirps[0].Sensitive || resp.Chirps[0].ContentWarning != "Film ending" {
+		t.Errorf("grpc: expected a flagged chirp, got %v %v", resp, err)
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+readerToken))
+	if resp, err := g.GetTimeline(ctx, &chirpypb.GetTimelineRequest{}); err != nil || len(resp.Chirps) != 0 {
+		t.Errorf("grpc: expected the chirp hidden from the reader, got %v %v", resp, err)
+	}
+}
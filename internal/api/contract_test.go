@@ -0,0 +1,241 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+var (
+	contractUserID  = uuid.MustParse("6f1a2b3c-0000-4000-8000-000000000001")
+	contractChirpID = uuid.MustParse("6f1a2b3c-0000-4000-8000-000000000002")
+)
+
+// contractStore returns the same fixtures on every call, so responses are
+// byte-for-byte repeatable.
+type contractStore struct {
+	fakeStore
+}
+
+func (c *contractStore) fixtureChirp(body string) database.Chirp {
+	return database.Chirp{
+		ID:        contractChirpID,
+		CreatedAt: testNow,
+		UpdatedAt: testNow,
+		Body:      body,
+		UserID:    contractUserID,
+	}
+}
+
+func (c *contractStore) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	return c.fixtureChirp(arg.Body), nil
+}
+
+func (c *contractStore) EachChirp(ctx context.Context, viewerID uuid.UUID, fn func(database.GetChirpsRow) error) error {
+	ch := c.fixtureChirp("The first chirp")
+	return fn(database.GetChirpsRow{
+		ID:             ch.ID,
+		CreatedAt:      ch.CreatedAt,
+		UpdatedAt:      ch.UpdatedAt,
+		Body:           ch.Body,
+		UserID:         ch.UserID,
+		AuthorVerified: true,
+	})
+}
+
+func (c *contractStore) GetVisibleChirp(ctx context.Context, arg database.GetVisibleChirpParams) (database.GetVisibleChirpRow, error) {
+	if arg.ID != contractChirpID {
+		return database.GetVisibleChirpRow{}, sql.ErrNoRows
+	}
+	ch := c.fixtureChirp("The first chirp")
+	return database.GetVisibleChirpRow{
+		ID:             ch.ID,
+		CreatedAt:      ch.CreatedAt,
+		UpdatedAt:      ch.UpdatedAt,
+		Body:           ch.Body,
+		UserID:         ch.UserID,
+		AuthorVerified: true,
+	}, nil
+}
+
+func (c *contractStore) ListChirpReactionCounts(ctx context.Context, chirpID uuid.UUID) ([]database.ListChirpReactionCountsRow, error) {
+	return nil, nil
+}
+
+func (c *contractStore) ListChirpMedia(ctx context.Context, chirpID uuid.NullUUID) ([]database.Medium, error) {
+	return nil, nil
+}
+
+func (c *contractStore) ListReactionCountsForChirps(ctx context.Context, chirpIDs []uuid.UUID) ([]database.ListReactionCountsForChirpsRow, error) {
+	return nil, nil
+}
+
+func (c *contractStore) ListMediaForChirps(ctx context.Context, chirpIDs []uuid.UUID) ([]database.Medium, error) {
+	return nil, nil
+}
+
+func (c *contractStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	return database.User{
+		ID:        contractUserID,
+		CreatedAt: testNow,
+		UpdatedAt: testNow,
+		Email:     arg.Email,
+		Handle:    arg.Handle,
+	}, nil
+}
+
+func (c *contractStore) IsHandleReserved(ctx context.Context, handle string) (bool, error) {
+	return false, nil
+}
+
+func (c *contractStore) RecordIPSignup(ctx context.Context, ip string) error {
+	return nil
+}
+
+func (c *contractStore) EnqueueEmail(ctx context.Context, arg database.EnqueueEmailParams) error {
+	return nil
+}
+
+func (c *contractStore) GetDigestFrequency(ctx context.Context, userID uuid.UUID) (string, error) {
+	return "weekly", nil
+}
+
+// golden is what a contract pins down about a response.
+type golden struct {
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	Text        string          `json:"text,omitempty"`
+}
+
+// redactedFields vary between runs however the fixtures are chosen.
+var redactedFields = map[string]bool{"token": true}
+
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if redactedFields[k] {
+				v[k] = "REDACTED"
+			} else {
+				v[k] = redact(field)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return v
+}
+
+func newGolden(t *testing.T, rec *httptest.ResponseRecorder) golden {
+	t.Helper()
+	g := golden{Status: rec.Code, ContentType: rec.Header().Get("Content-Type")}
+	var body interface{}
+	if strings.HasPrefix(g.ContentType, "application/json") && json.Unmarshal(rec.Body.Bytes(), &body) == nil {
+		raw, err := json.Marshal(redact(body))
+		if err != nil {
+			t.Fatalf("re-encoding body: %v", err)
+		}
+		g.Body = raw
+	} else {
+		g.Text = rec.Body.String()
+	}
+	return g
+}
+
+func TestContracts(t *testing.T) {
+	user := newTestUser(t, "saul@example.com", "04234")
+	user.ID = contractUserID
+	user.CreatedAt = testNow
+	user.UpdatedAt = testNow
+	user.Handle = sql.NullString{String: "saul", Valid: true}
+	store := &contractStore{fakeStore{users: map[string]database.User{user.Email: user}}}
+
+	cfg := &config.Config{Platform: "dev", JWTSecret: "test-secret"}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)}))
+	token, err := auth.MakeJWT(user.ID, cfg.JWTSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		method, path  string
+		body          string
+		authenticated bool
+	}{
+		{name: "healthz", method: http.MethodGet, path: "/api/healthz"},
+		{name: "unknown_route", method: http.MethodGet, path: "/api/nope"},
+		{name: "method_not_allowed", method: http.MethodPut, path: "/api/login"},
+		{name: "readyz", method: http.MethodGet, path: "/readyz"},
+		{name: "login", method: http.MethodPost, path: "/api/login", body: `{"email":"saul@example.com","password":"04234"}`},
+		{name: "login_wrong_password", method: http.MethodPost, path: "/api/login", body: `{"email":"saul@example.com","password":"nope"}`},
+		{name: "login_malformed", method: http.MethodPost, path: "/api/login", body: `{`},
+		{name: "users_create", method: http.MethodPost, path: "/api/users", body: `{"email":"kim@example.com","password":"pa55word","handle":"Kim"}`},
+		{name: "users_create_invalid_email", method: http.MethodPost, path: "/api/users", body: `{"email":"kim","password":"pa55word"}`},
+		{name: "chirps_create", method: http.MethodPost, path: "/api/chirps", body: `{"body":"I had a kerfuffle today","user_id":"` + contractUserID.String() + `"}`},
+		{name: "chirps_create_too_long", method: http.MethodPost, path: "/api/chirps", body: `{"body":"` + strings.Repeat("a", 141) + `"}`},
+		{name: "chirps_list", method: http.MethodGet, path: "/api/chirps"},
+		{name: "chirps_get", method: http.MethodGet, path: "/api/chirps/" + contractChirpID.String()},
+		{name: "chirps_get_not_found", method: http.MethodGet, path: "/api/chirps/" + uuid.Nil.String()},
+		{name: "chirps_get_invalid_id", method: http.MethodGet, path: "/api/chirps/garbage"},
+		{name: "chirps_delete_invalid_id", method: http.MethodDelete, path: "/api/chirps/garbage", authenticated: true},
+		{name: "chirps_delete_unauthenticated", method: http.MethodDelete, path: "/api/chirps/" + contractChirpID.String()},
+		{name: "digest_get", method: http.MethodGet, path: "/api/users/me/digest", authenticated: true},
+		{name: "digest_get_unauthenticated", method: http.MethodGet, path: "/api/users/me/digest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.authenticated {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			got, err := json.MarshalIndent(newGolden(t, rec), "", "  ")
+			if err != nil {
+				t.Fatalf("encoding golden: %v", err)
+			}
+			got = append(got, '\n')
+
+			path := filepath.Join("testdata", "golden", tt.name+".json")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("response for %s %s drifted from %s (run with -update if intended):\ngot:\n%s\nwant:\n%s",
+					tt.method, tt.path, path, got, want)
+			}
+		})
+	}
+}
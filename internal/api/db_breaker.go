@@ -0,0 +1,193 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"chirpy/internal/breaker"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/lib/pq"
+)
+
+// Query classes. Each has its own breaker, so a database that still
+// answers reads but refuses writes only turns writes away.
+const (
+	queryRead  = "read"
+	queryWrite = "write"
+)
+
+// DBBreakers are the circuit breakers around database queries, one per
+// query class. The SQL store reports every query's outcome to them, and
+// requests are turned away with a 503 while the breaker for their class
+// is open, rather than each waiting out the request timeout.
+type DBBreakers struct {
+	read  *breaker.Breaker
+	write *breaker.Breaker
+}
+
+func NewDBBreakers(cfg config.BreakerConfig, clock Clock) *DBBreakers {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return &DBBreakers{
+		read:  breaker.New(cfg.Threshold, cfg.Cooldown, clock.Now),
+		write: breaker.New(cfg.Threshold, cfg.Cooldown, clock.Now),
+	}
+}
+
+func (b *DBBreakers) breaker(class string) *breaker.Breaker {
+	if class == queryRead {
+		return b.read
+	}
+	return b.write
+}
+
+// record reports the outcome of a query of class. A query the client gave
+// up on says nothing about the database, so it isn't counted, but one
+// cancelled for taking too long is.
+func (b *DBBreakers) record(ctx context.Context, class string, err error) {
+	if b == nil {
+		return
+	}
+	if errors.Is(err, context.Canceled) && errors.Is(context.Cause(ctx), context.Canceled) {
+		return
+	}
+	if dbUnavailable(err) {
+		b.breaker(class).Failure()
+	} else {
+		b.breaker(class).Success()
+	}
+}
+
+// dbUnavailable reports whether err means the database couldn't do the
+// work, rather than that it refused it, as it does a duplicate key.
+func dbUnavailable(err error) bool {
+	if err == nil || errors.Is(err, sql.ErrNoRows) || errors.Is(err, sql.ErrTxDone) {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		// Connection exceptions, insufficient resources, operator
+		// intervention (which covers statement timeouts and shutdowns) and
+		// system errors.
+		case "08", "53", "57", "58":
+			return true
+		}
+		// A primary that has become read-only.
+		return pqErr.Code.Name() == "read_only_sql_transaction"
+	}
+	// Network, pool and driver errors: the database didn't answer.
+	return true
+}
+
+// queryClass is read for a SELECT and write for anything else, which
+// includes this repo's CTEs: they all modify rows.
+func queryClass(query string) string {
+	// sqlc queries start with a "-- name:" comment.
+	for strings.HasPrefix(query, "--") {
+		_, query, _ = strings.Cut(query, "\n")
+	}
+	query = strings.TrimSpace(query)
+	if len(query) >= 6 && strings.EqualFold(query[:6], "SELECT") {
+		return queryRead
+	}
+	return queryWrite
+}
+
+// requestClass is the class of query a request mostly makes.
+func requestClass(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return queryRead
+	default:
+		return queryWrite
+	}
+}
+
+// breakerDB reports the outcome of each query run on db to breakers.
+type breakerDB struct {
+	db       database.DBTX
+	breakers *DBBreakers
+}
+
+func (b breakerDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := b.db.ExecContext(ctx, query, args...)
+	b.breakers.record(ctx, queryClass(query), err)
+	return res, err
+}
+
+func (b breakerDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := b.db.PrepareContext(ctx, query)
+	b.breakers.record(ctx, queryClass(query), err)
+	return stmt, err
+}
+
+func (b breakerDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := b.db.QueryContext(ctx, query, args...)
+	b.breakers.record(ctx, queryClass(query), err)
+	return rows, err
+}
+
+func (b breakerDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := b.db.QueryRowContext(ctx, query, args...)
+	b.breakers.record(ctx, queryClass(query), row.Err())
+	return row
+}
+
+// middlewareDBBreaker answers a request with a 503 while the breaker for
+// its class is open, unless its route is in dbFree. Once the breaker has
+// cooled down, the next request through is its probe.
+func (s *Server) middlewareDBBreaker(mux *http.ServeMux, dbFree []string, next http.Handler) http.Handler {
+	if s.breakers == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if pattern == "" || slices.Contains(dbFree, pattern) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		retryAfter, err := s.breakers.breaker(requestClass(r)).Allow()
+		if err != nil {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			jsonResponse(w, http.StatusServiceUnavailable, errorResponse{Error: "The database is unavailable, try again shortly"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type breakerStats struct {
+	State    string `json:"state"`
+	Failures int    `json:"failures"`
+	Trips    int64  `json:"trips"`
+	Rejected int64  `json:"rejected"`
+}
+
+// dbBreakerStats describes each breaker by query class, or is nil without
+// breakers.
+func (s *Server) dbBreakerStats() map[string]breakerStats {
+	if s.breakers == nil {
+		return nil
+	}
+	stats := make(map[string]breakerStats, 2)
+	for _, class := range []string{queryRead, queryWrite} {
+		st := s.breakers.breaker(class).Stats()
+		stats[class] = breakerStats{
+			State:    st.State.String(),
+			Failures: st.Failures,
+			Trips:    st.Trips,
+			Rejected: st.Rejected,
+		}
+	}
+	return stats
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"chirpy/internal/config"
+
+	"github.com/lib/pq"
+)
+
+// downDB fails every query as a database that has gone away would.
+type downDB struct {
+	err error
+}
+
+func (d downDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, d.err
+}
+
+func (d downDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, d.err
+}
+
+func (d downDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, d.err
+}
+
+func (d downDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	panic("not used")
+}
+
+const testReadQuery = "-- name: GetChirps :many\nSELECT id FROM chirps\n"
+
+func TestDBBreaker_TurnsRequestsAway(t *testing.T) {
+	clock := &manualClock{now: testNow}
+	breakers := NewDBBreakers(config.BreakerConfig{Threshold: 2, Cooldown: 10 * time.Second}, clock)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewRouter(NewServer(cfg, Deps{Store: &fakeStore{}, Clock: clock, Breakers: breakers}))
+
+	db := breakerDB{db: downDB{err: errors.New("dial tcp: connection refused")}, breakers: breakers}
+	for range 2 {
+		db.QueryContext(context.Background(), testReadQuery)
+	}
+
+	rec := do(h, http.MethodGet, "/api/chirps", "")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "10" {
+		t.Fatalf("expected a 503 to retry in 10s, got %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := do(h, http.MethodGet, "/api/healthz", ""); rec.Code != http.StatusOK {
+		t.Errorf("health check: expected 200, got %d", rec.Code)
+	}
+	// Writes have their own breaker.
+	if rec := do(h, http.MethodPost, "/api/login", "{"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected writes to go through and fail to decode, got %d", rec.Code)
+	}
+
+	rec = do(h, http.MethodGet, "/readyz", "")
+	var ready readinessResponse
+	if err := json.NewDecoder(rec.Body).Decode(&ready); err != nil {
+		t.Fatalf("decoding readiness: %v", err)
+	}
+	if read := ready.Breakers[queryRead]; read.State != "open" || read.Trips != 1 || read.Rejected != 1 {
+		t.Errorf("expected the read breaker open after one trip and one rejection, got %+v", read)
+	}
+
+	// After the cooldown one request probes the database.
+	clock.now = clock.now.Add(10 * time.Second)
+	if rec := do(h, http.MethodGet, "/api/chirps", ""); rec.Code != http.StatusOK {
+		t.Fatalf("probe: expected 200, got %d", rec.Code)
+	}
+	if rec := do(h, http.MethodGet, "/api/chirps", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected one probe at a time, got %d", rec.Code)
+	}
+	db.db = downDB{err: sql.ErrNoRows}
+	db.QueryContext(context.Background(), testReadQuery)
+	if rec := do(h, http.MethodGet, "/api/chirps", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected the breaker closed once the database answers, got %d", rec.Code)
+	}
+}
+
+func TestDBBreaker_IgnoresClientsThatLeave(t *testing.T) {
+	breakers := NewDBBreakers(config.BreakerConfig{Threshold: 1, Cooldown: time.Minute}, fixedClock(testNow))
+	db := breakerDB{db: downDB{err: context.Canceled}, breakers: breakers}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	db.QueryContext(ctx, testReadQuery)
+	if got := breakers.read.Stats().Failures; got != 0 {
+		t.Errorf("a client going away counted as %d failures", got)
+	}
+
+	ctx, cancelCause := context.WithCancelCause(context.Background())
+	cancelCause(errRequestTimeout)
+	db.QueryContext(ctx, testReadQuery)
+	if got := breakers.read.Stats().Trips; got != 1 {
+		t.Errorf("expected a request timeout to trip the breaker, got %d trips", got)
+	}
+}
+
+func TestDBUnavailable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{sql.ErrNoRows, false},
+		{&pq.Error{Code: "23505"}, false}, // unique_violation
+		{&pq.Error{Code: "42P01"}, false}, // undefined_table
+		{&pq.Error{Code: "57014"}, true},  // query_canceled, as by statement_timeout
+		{&pq.Error{Code: "08006"}, true},  // connection_failure
+		{&pq.Error{Code: "53300"}, true},  // too_many_connections
+		{&pq.Error{Code: "25006"}, true},  // read_only_sql_transaction
+		{context.DeadlineExceeded, true},
+		{errors.New("driver: bad connection"), true},
+	}
+	for _, tt := range tests {
+		if got := dbUnavailable(tt.err); got != tt.want {
+			t.Errorf("dbUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestQueryClass(t *testing.T) {
+	tests := map[string]string{
+		testReadQuery: queryRead,
+		"select 1":    queryRead,
+		"-- name: CreateChirp :one\nINSERT INTO chirps (id) VALUES ($1) RETURNING id":                      queryWrite,
+		"-- name: RefreshUserSuggestions :execrows\nWITH candidates AS (SELECT 1)\nINSERT INTO x SELECT 1": queryWrite,
+	}
+	for query, want := range tests {
+		if got := queryClass(query); got != want {
+			t.Errorf("queryClass(%q) = %s, want %s", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// Dead letter sources.
+const (
+	deadLetterEmail       = "email"
+	deadLetterOutboxEvent = "outbox_event"
+	deadLetterJob         = "job"
+)
+
+const (
+	defaultDeadLetterPageSize = 50
+	maxDeadLetterPageSize     = 200
+)
+
+// recordDeadLetter opens a dead letter for sourceID through q, or adds
+// attempts to the one already open.
+func recordDeadLetter(ctx context.Context, q database.Querier, source, sourceID, summary string, attempts int32, lastError string) error {
+	return q.RecordDeadLetter(ctx, database.RecordDeadLetterParams{
+		ID:        uuid.New(),
+		Source:    source,
+		SourceID:  sourceID,
+		Summary:   summary,
+		Attempts:  attempts,
+		LastError: lastError,
+	})
+}
+
+// recordJobOutcome keeps a failing job's dead letter up to date, and
+// closes it once the job succeeds again.
+func (s *Server) recordJobOutcome(ctx context.Context, name string, jobErr error) {
+	var err error
+	if jobErr != nil {
+		err = recordDeadLetter(ctx, s.db, deadLetterJob, name, name+" job", 1, jobErr.Error())
+	} else {
+		err = s.db.ResolveDeadLetter(ctx, database.ResolveDeadLetterParams{Source: deadLetterJob, SourceID: name})
+	}
+	if err != nil {
+		fmt.Println("Error recording job outcome:", err)
+	}
+}
+
+type deadLetterResponse struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt Timestamp `json:"created_at"`
+	UpdatedAt Timestamp `json:"updated_at"`
+	Source    string    `json:"source"`
+	SourceID  string    `json:"source_id"`
+	Summary   string    `json:"summary"`
+	Attempts  int32     `json:"attempts"`
+	LastError string    `json:"last_error"`
+}
+
+// handlerAdminDeadLettersList lists open dead letters, most recently
+// failed first, optionally for one source.
+func (s *Server) handlerAdminDeadLettersList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	params := database.ListDeadLettersParams{RowLimit: defaultDeadLetterPageSize}
+	if v := query.Get("source"); v != "" {
+		if v != deadLetterEmail && v != deadLetterOutboxEvent && v != deadLetterJob {
+			jsonResponse(w, http.StatusBadRequest, "source must be email, outbox_event or job")
+			return
+		}
+		params.Source = sql.NullString{String: v, Valid: true}
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDeadLetterPageSize {
+			jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxDeadLetterPageSize))
+			return
+		}
+		params.RowLimit = int32(n)
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			jsonResponse(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		params.RowOffset = int32(n)
+	}
+
+	letters, err := s.db.ListDeadLetters(r.Context(), params)
+	if err != nil {
+		fmt.Println("Error listing dead letters:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	response := make([]deadLetterResponse, 0, len(letters))
+	for _, l := range letters {
+		response = append(response, deadLetterResponse{
+			ID:        l.ID,
+			CreatedAt: Timestamp{l.CreatedAt},
+			UpdatedAt: Timestamp{l.UpdatedAt},
+			Source:    l.Source,
+			SourceID:  l.SourceID,
+			Summary:   l.Summary,
+			Attempts:  l.Attempts,
+			LastError: l.LastError,
+		})
+	}
+	jsonResponse(w, http.StatusOK, response)
+}
+
+// handlerAdminDeadLetterRetry closes a dead letter and tries its work
+// again: an email or outbox event goes back in its queue with a fresh set
+// of attempts, and a job runs now.
+func (s *Server) handlerAdminDeadLetterRetry(w http.ResponseWriter, r *http.Request) {
+	letterID, ok := pathUUID(w, r, "deadLetterID")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	letter, err := tx.ClaimDeadLetter(ctx, letterID)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "No open dead letter with that ID")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	switch letter.Source {
+	case deadLetterEmail, deadLetterOutboxEvent:
+		sourceID, err := uuid.Parse(letter.SourceID)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		retry := tx.RetryEmail
+		if letter.Source == deadLetterOutboxEvent {
+			retry = tx.RetryOutboxEvent
+		}
+		n, err := retry(ctx, sourceID)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		// Retention may have purged it since it failed.
+		if n == 0 {
+			jsonResponse(w, http.StatusGone, "The failed "+letter.Source+" no longer exists")
+			return
+		}
+	case deadLetterJob:
+		if err := s.jobs.Trigger(letter.SourceID); err != nil {
+			jsonResponse(w, http.StatusConflict, "Couldn't run the job: "+err.Error())
+			return
+		}
+	default:
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	admin := adminFromContext(ctx)
+	err = recordAudit(ctx, tx, admin.ID, "dead_letter.retry", letterID, map[string]interface{}{
+		"source":    letter.Source,
+		"source_id": letter.SourceID,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+	"chirpy/internal/scheduler"
+
+	"github.com/google/uuid"
+)
+
+type deadLetterStore struct {
+	fakeStore
+	letters       map[uuid.UUID]database.DeadLetter
+	deadEmails    map[uuid.UUID]bool
+	retriedEmails []uuid.UUID
+	audits        []string
+}
+
+type deadLetterTx struct {
+	*deadLetterStore
+}
+
+func (s *deadLetterStore) Begin(ctx context.Context) (Tx, error) {
+	return deadLetterTx{s}, nil
+}
+
+func (tx deadLetterTx) Commit() error   { return nil }
+func (tx deadLetterTx) Rollback() error { return nil }
+
+func (s *deadLetterStore) ListDeadLetters(ctx context.Context, arg database.ListDeadLettersParams) ([]database.DeadLetter, error) {
+	var letters []database.DeadLetter
+	for _, l := range s.letters {
+		if !l.RetriedAt.Valid && (!arg.Source.Valid || l.Source == arg.Source.String) {
+			letters = append(letters, l)
+		}
+	}
+	return letters, nil
+}
+
+func (s *deadLetterStore) ClaimDeadLetter(ctx context.Context, id uuid.UUID) (database.DeadLetter, error) {
+	l, ok := s.letters[id]
+	if !ok || l.RetriedAt.Valid {
+		return database.DeadLetter{}, sql.ErrNoRows
+	}
+	l.RetriedAt = sql.NullTime{Time: testNow, Valid: true}
+	s.letters[id] = l
+	return l, nil
+}
+
+func (s *deadLetterStore) RetryEmail(ctx context.Context, id uuid.UUID) (int64, error) {
+	if !s.deadEmails[id] {
+		return 0, nil
+	}
+	s.retriedEmails = append(s.retriedEmails, id)
+	return 1, nil
+}
+
+func (s *deadLetterStore) CreateAuditLogEntry(ctx context.Context, arg database.CreateAuditLogEntryParams) error {
+	s.audits = append(s.audits, arg.Action)
+	return nil
+}
+
+func TestAdminDeadLetters(t *testing.T) {
+	admin := newTestUser(t, "admin@example.com", "pa55word")
+	admin.Role = RoleAdmin
+	emailID, purgedID := uuid.New(), uuid.New()
+	emailLetter := database.DeadLetter{ID: uuid.New(), Source: deadLetterEmail, SourceID: emailID.String(), Summary: "welcome email to a@example.com", Attempts: 8, LastError: "connection refused"}
+	purgedLetter := database.DeadLetter{ID: uuid.New(), Source: deadLetterEmail, SourceID: purgedID.String(), Attempts: 8}
+	jobLetter := database.DeadLetter{ID: uuid.New(), Source: deadLetterJob, SourceID: "retention", Attempts: 3}
+	store := &deadLetterStore{
+		fakeStore:  fakeStore{users: map[string]database.User{admin.Email: admin}},
+		letters:    map[uuid.UUID]database.DeadLetter{emailLetter.ID: emailLetter, purgedLetter.ID: purgedLetter, jobLetter.ID: jobLetter},
+		deadEmails: map[uuid.UUID]bool{emailID: true},
+	}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	s := NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)})
+	ran := make(chan struct{}, 1)
+	if err := s.jobs.Add(scheduler.Job{Name: "retention", Spec: scheduler.Off, Run: func(ctx context.Context) error {
+		ran <- struct{}{}
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.jobs.Start(ctx)
+	h := NewRouter(s)
+
+	token, err := auth.MakeJWT(admin.ID, cfg.JWTSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(http.MethodGet, "/admin/dead-letters?source=job")
+	var letters []deadLetterResponse
+	if err := json.NewDecoder(rec.Body).Decode(&letters); err != nil {
+		t.Fatalf("decoding dead letters: %v", err)
+	}
+	if len(letters) != 1 || letters[0].SourceID != "retention" || letters[0].Attempts != 3 {
+		t.Errorf("expected the retention job's dead letter, got %+v", letters)
+	}
+	if rec := send(http.MethodGet, "/admin/dead-letters?source=webhook"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown source: expected 400, got %d", rec.Code)
+	}
+
+	if rec := send(http.MethodPost, "/admin/dead-letters/"+emailLetter.ID.String()+"/retry"); rec.Code != http.StatusNoContent {
+		t.Fatalf("retrying an email: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(store.retriedEmails) != 1 || store.retriedEmails[0] != emailID {
+		t.Errorf("expected the email to be requeued, got %v", store.retriedEmails)
+	}
+	if rec := send(http.MethodPost, "/admin/dead-letters/"+emailLetter.ID.String()+"/retry"); rec.Code != http.StatusNotFound {
+		t.Errorf("retrying twice: expected 404, got %d", rec.Code)
+	}
+	if rec := send(http.MethodPost, "/admin/dead-letters/"+purgedLetter.ID.String()+"/retry"); rec.Code != http.StatusGone {
+		t.Errorf("retrying a purged email: expected 410, got %d", rec.Code)
+	}
+
+	if rec := send(http.MethodPost, "/admin/dead-letters/"+jobLetter.ID.String()+"/retry"); rec.Code != http.StatusNoContent {
+		t.Fatalf("retrying a job: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the retried job didn't run")
+	}
+	if len(store.audits) != 2 || store.audits[0] != "dead_letter.retry" {
+		t.Errorf("expected each retry to be audited, got %v", store.audits)
+	}
+}
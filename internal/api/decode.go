@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"chirpy/internal/config"
+)
+
+// strictJSONError is a request body that strict decoding refused. Its
+// message is meant for the client.
+type strictJSONError struct {
+	msg string
+}
+
+func (e *strictJSONError) Error() string {
+	return e.msg
+}
+
+// strictJSON reports whether r's body is decoded strictly, per STRICT_JSON.
+func (s *Server) strictJSON(r *http.Request) bool {
+	switch s.config.StrictJSON {
+	case config.StrictJSONAll:
+		return true
+	case config.StrictJSONOff:
+		return false
+	default:
+		return strings.HasPrefix(r.URL.Path, "/api/v1/")
+	}
+}
+
+// decodeJSON decodes r's body into v. When r is decoded strictly, fields v
+// doesn't have and anything after the first value are rejected with a
+// *strictJSONError, so a typo like "bodyy" is reported instead of quietly
+// leaving a field empty.
+func (s *Server) decodeJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	if !s.strictJSON(r) {
+		return dec.Decode(v)
+	}
+
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		// encoding/json has no error type for unknown fields.
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return &strictJSONError{msg: fmt.Sprintf("Unknown field %s", field)}
+		}
+		return err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return &strictJSONError{msg: "Request body must contain a single JSON value"}
+	}
+	return nil
+}
+
+// decodeErrorMessage is what to tell a client whose body decodeJSON
+// rejected: the reason for strict rejections, fallback otherwise.
+func decodeErrorMessage(err error, fallback string) string {
+	var strictErr *strictJSONError
+	if errors.As(err, &strictErr) {
+		return strictErr.msg
+	}
+	return fallback
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+)
+
+func TestStrictJSON(t *testing.T) {
+	user := newTestUser(t, "saul@example.com", "04234")
+	store := &contractStore{fakeStore{users: map[string]database.User{user.Email: user}}}
+	token, err := auth.MakeJWT(user.ID, "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		path     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{name: "v1 unknown field", mode: config.StrictJSONV1, path: "/api/v1/statuses", body: `{"statuss":"hi"}`, wantCode: http.StatusBadRequest, wantBody: `Unknown field \"statuss\"`},
+		{name: "v1 trailing data", mode: config.StrictJSONV1, path: "/api/v1/statuses", body: `{"status":"hi"} {}`, wantCode: http.StatusBadRequest, wantBody: "single JSON value"},
+		{name: "v1 client parameters", mode: config.StrictJSONV1, path: "/api/v1/statuses", body: `{"status":"","visibility":"public","media_ids":[]}`, wantCode: http.StatusUnprocessableEntity, wantBody: "can't be blank"},
+		{name: "legacy lenient by default", mode: config.StrictJSONV1, path: "/api/chirps", body: `{"body":"hi","bodyy":"typo"}`, wantCode: http.StatusCreated},
+		{name: "legacy strict when all", mode: config.StrictJSONAll, path: "/api/chirps", body: `{"bodyy":"hi"}`, wantCode: http.StatusBadRequest, wantBody: `Unknown field \"bodyy\"`},
+		{name: "off", mode: config.StrictJSONOff, path: "/api/v1/statuses", body: `{"statuss":"hi"}`, wantCode: http.StatusUnprocessableEntity, wantBody: "can't be blank"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{JWTSecret: "test-secret", StrictJSON: tt.mode}
+			h := NewRouter(NewServer(cfg, Deps{Store: store}))
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("got %d %s, want %d containing %q", rec.Code, rec.Body, tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
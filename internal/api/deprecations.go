@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// deprecation marks a route clients should stop using.
+type deprecation struct {
+	// Since is when the route was deprecated.
+	Since time.Time
+	// Sunset is when the route may stop working. Zero if not decided.
+	Sunset time.Time
+	// Successor is the path of the route replacing it, if any.
+	Successor string
+}
+
+// deprecatedRoutes are the deprecated route patterns.
+var deprecatedRoutes = map[string]deprecation{
+	// Anyone can chirp as anyone by naming them in the body; the Mastodon
+	// endpoint takes the author from the access token instead.
+	"POST /api/chirps": {
+		Since:     time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC),
+		Sunset:    time.Date(2027, time.April, 16, 0, 0, 0, 0, time.UTC),
+		Successor: "/api/v1/statuses",
+	},
+}
+
+// middlewareDeprecation announces that a route is deprecated with the
+// Deprecation (RFC 9745), Sunset (RFC 8594) and Link headers, and counts
+// its use so we know when it's safe to remove.
+func (s *Server) middlewareDeprecation(mux *http.ServeMux, routes map[string]deprecation, next http.Handler) http.Handler {
+	if len(routes) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		d, ok := routes[pattern]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h := w.Header()
+		h.Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+		if !d.Sunset.IsZero() {
+			h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.Successor != "" {
+			h.Add("Link", "<"+tenantPath(r, currentTenant(r.Context()))+d.Successor+`>; rel="successor-version"`)
+		}
+		s.deprecations.record(pattern, s.clock.Now().UTC())
+		next.ServeHTTP(w, r)
+	})
+}
+
+// deprecationUsage counts requests to deprecated routes since the server
+// started, for the admin metrics page.
+type deprecationUsage struct {
+	mu     sync.Mutex
+	routes map[string]*deprecatedRouteUsage
+}
+
+type deprecatedRouteUsage struct {
+	Route    string
+	Requests int64
+	LastSeen time.Time
+}
+
+func (du *deprecationUsage) record(pattern string, now time.Time) {
+	du.mu.Lock()
+	defer du.mu.Unlock()
+	if du.routes == nil {
+		du.routes = make(map[string]*deprecatedRouteUsage)
+	}
+	u, ok := du.routes[pattern]
+	if !ok {
+		u = &deprecatedRouteUsage{Route: pattern}
+		du.routes[pattern] = u
+	}
+	u.Requests++
+	u.LastSeen = now
+}
+
+// snapshot lists the deprecated routes that have been used, by route.
+func (du *deprecationUsage) snapshot() []deprecatedRouteUsage {
+	du.mu.Lock()
+	defer du.mu.Unlock()
+	usage := make([]deprecatedRouteUsage, 0, len(du.routes))
+	for _, u := range du.routes {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Route < usage[j].Route })
+	return usage
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"chirpy/internal/config"
+)
+
+func TestDeprecatedRoutes(t *testing.T) {
+	s := NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: &contractStore{}, Clock: fixedClock(testNow)})
+	h := NewRouter(s)
+
+	rec := do(h, http.MethodPost, "/api/chirps", `{"body":"hi","user_id":"`+contractUserID.String()+`"}`)
+	if got := rec.Header().Get("Deprecation"); got != "@1792108800" {
+		t.Errorf("Deprecation = %q", got)
+	}
+	if got := rec.Header().Get("Sunset"); got != "Fri, 16 Apr 2027 00:00:00 GMT" {
+		t.Errorf("Sunset = %q", got)
+	}
+	if got := rec.Header().Get("Link"); got != `</api/v1/statuses>; rel="successor-version"` {
+		t.Errorf("Link = %q", got)
+	}
+
+	rec = do(h, http.MethodGet, "/api/chirps", "")
+	if got := rec.Header().Get("Deprecation"); got != "" {
+		t.Errorf("routes that aren't deprecated shouldn't be marked, got %q", got)
+	}
+
+	usage := s.deprecations.snapshot()
+	if len(usage) != 1 || usage[0].Route != "POST /api/chirps" || usage[0].Requests != 1 || !usage[0].LastSeen.Equal(testNow) {
+		t.Errorf("usage = %+v", usage)
+	}
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/mail"
+
+	"github.com/google/uuid"
+)
+
+const (
+	digestDaily  = "daily"
+	digestWeekly = "weekly"
+	digestOff    = "off"
+
+	digestBatchSize    = 100
+	digestMaxFollowers = 10
+	digestMaxChirps    = 5
+
+	// digestUnsubscribePurpose scopes unsubscribe signatures so they can't
+	// be used for anything else signed with the JWT secret.
+	digestUnsubscribePurpose = "digest-unsubscribe"
+)
+
+func validDigestFrequency(f string) bool {
+	return f == digestDaily || f == digestWeekly || f == digestOff
+}
+
+func digestPeriod(frequency string) time.Duration {
+	if frequency == digestDaily {
+		return 24 * time.Hour
+	}
+	return 7 * 24 * time.Hour
+}
+
+// digestUnsubscribeURL is a link that turns digests off for userID without
+// signing in.
+func (s *Server) digestUnsubscribeURL(ctx context.Context, userID uuid.UUID) string {
+	q := url.Values{}
+	q.Set("user", userID.String())
+	q.Set("sig", auth.SignValue(userID.String(), digestUnsubscribePurpose, s.config.JWTSecret))
+	return s.siteURL(ctx) + "/api/digests/unsubscribe?" + q.Encode()
+}
+
+// sendDueDigestsEachTenant runs sendDueDigests for one community at a
+// time, so digests only show chirps from the recipient's own and link back
+// to it.
+func (s *Server) sendDueDigestsEachTenant(ctx context.Context) error {
+	return s.eachTenant(ctx, s.sendDueDigests)
+}
+
+// sendDueDigests queues a digest for everyone whose last one is older than
+// their chosen frequency. Each recipient is claimed by moving last_sent_at
+// first, so running more than one server doesn't double-send.
+func (s *Server) sendDueDigests(ctx context.Context) error {
+	for {
+		due, err := s.db.ListDueDigests(ctx, digestBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range due {
+			claimed, err := s.db.ClaimDigest(ctx, database.ClaimDigestParams{
+				UserID:         d.ID,
+				PreviousSentAt: d.LastSentAt,
+			})
+			if err != nil {
+				return err
+			}
+			if claimed == 0 {
+				continue
+			}
+			if err := s.queueDigest(ctx, d); err != nil {
+				fmt.Println("Error queueing digest:", err)
+			}
+		}
+
+		if len(due) < digestBatchSize {
+			return nil
+		}
+	}
+}
+
+// queueDigest assembles one user's digest. Nothing is sent when there is
+// nothing to report.
+func (s *Server) queueDigest(ctx context.Context, d database.ListDueDigestsRow) error {
+	since := s.clock.Now().UTC().Add(-digestPeriod(d.Frequency))
+	if d.LastSentAt.Valid && d.LastSentAt.Time.After(since) {
+		since = d.LastSentAt.Time
+	}
+
+	followers, err := s.db.ListRemoteFollowersSince(ctx, database.ListRemoteFollowersSinceParams{
+		UserID:    d.ID,
+		CreatedAt: since,
+		Limit:     digestMaxFollowers,
+	})
+	if err != nil {
+		return err
+	}
+	chirps, err := s.db.ListDigestChirps(ctx, database.ListDigestChirpsParams{
+		Since:       since,
+		RecipientID: d.ID,
+		RowLimit:    digestMaxChirps,
+	})
+	if err != nil {
+		return err
+	}
+	if len(followers) == 0 && len(chirps) == 0 {
+		return nil
+	}
+
+	data := mail.TemplateData{
+		Name:           preferredUsername(database.User{ID: d.ID, Handle: d.Handle}),
+		Period:         d.Frequency,
+		Followers:      followers,
+		UnsubscribeURL: s.digestUnsubscribeURL(ctx, d.ID),
+	}
+	for _, c := range chirps {
+		data.Chirps = append(data.Chirps, mail.DigestChirp{
+			Author: preferredUsername(database.User{ID: c.UserID, Handle: c.AuthorHandle}),
+			Body:   c.Body,
+			URL:    s.chirpPermalink(ctx, c.ID.String()),
+		})
+	}
+	return s.enqueueEmail(ctx, s.db, mail.TemplateDigest, d.Email, data)
+}
+
+type digestPreferences struct {
+	Frequency string `json:"frequency"`
+}
+
+func (s *Server) handlerDigestPreferencesGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	frequency, err := s.db.GetDigestFrequency(r.Context(), userID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, digestPreferences{Frequency: frequency})
+}
+
+func (s *Server) handlerDigestPreferencesUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req digestPreferences
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	if !validDigestFrequency(req.Frequency) {
+		jsonResponse(w, http.StatusBadRequest, "frequency must be daily, weekly or off")
+		return
+	}
+
+	err = s.db.SetDigestFrequency(r.Context(), database.SetDigestFrequencyParams{
+		UserID:    userID,
+		Frequency: req.Frequency,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, req)
+}
+
+var unsubscribeTemplate = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Unsubscribe from Chirpy digests</title>
+  <style>
+    body { font-family: sans-serif; max-width: 36rem; margin: 3rem auto; padding: 0 1rem; color: #222; }
+  </style>
+</head>
+<body>
+{{if .Done}}
+  <p>You won't receive any more digests. You can turn them back on from your settings.</p>
+{{else}}
+  <p>Stop receiving Chirpy digest emails?</p>
+  <form method="post" action="{{.Action}}">
+    <button type="submit">Unsubscribe</button>
+  </form>
+{{end}}
+</body>
+</html>
+`))
+
+// digestUnsubscriber checks the signed user and sig query parameters of an
+// unsubscribe link.
+func (s *Server) digestUnsubscriber(r *http.Request) (uuid.UUID, bool) {
+	query := r.URL.Query()
+	userID, err := uuid.Parse(query.Get("user"))
+	if err != nil {
+		return uuid.Nil, false
+	}
+	if !auth.CheckSignedValue(userID.String(), digestUnsubscribePurpose, query.Get("sig"), s.config.JWTSecret) {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// handlerDigestUnsubscribe answers both halves of an unsubscribe link. GET
+// only shows a confirmation form, since mail scanners follow links; POST
+// unsubscribes, which also serves one-click unsubscribe (RFC 8058).
+func (s *Server) handlerDigestUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.digestUnsubscriber(r)
+	if !ok {
+		http.Error(w, "Invalid unsubscribe link", http.StatusBadRequest)
+		return
+	}
+
+	done := r.Method == http.MethodPost
+	if done {
+		err := s.db.SetDigestFrequency(r.Context(), database.SetDigestFrequencyParams{
+			UserID:    userID,
+			Frequency: digestOff,
+		})
+		if err != nil {
+			http.Error(w, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	err := unsubscribeTemplate.Execute(&buf, map[string]interface{}{
+		"Done":   done,
+		"Action": r.URL.RequestURI(),
+	})
+	if err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
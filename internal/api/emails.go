@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chirpy/internal/database"
+	"chirpy/internal/mail"
+
+	"github.com/google/uuid"
+)
+
+const (
+	emailStatusPending = "pending"
+	emailStatusDead    = "dead"
+
+	emailBatchSize    = 20
+	emailPollInterval = 5 * time.Second
+	// maxEmailAttempts is how many sends are tried before an email is
+	// dead-lettered. With the backoff below that spans about a day.
+	maxEmailAttempts = 8
+	emailBaseBackoff = time.Minute
+	emailMaxBackoff  = 6 * time.Hour
+)
+
+// enqueueEmail renders a template into the emails table through q. The
+// worker sends it, so callers never wait on the mail server and a send
+// survives a restart. Pass a transaction to queue the email only if the
+// rest of it commits.
+func (s *Server) enqueueEmail(ctx context.Context, q database.Querier, template, to string, data mail.TemplateData) error {
+	if data.SiteURL == "" {
+		data.SiteURL = s.siteURL(ctx)
+	}
+	msg, err := mail.Render(template, to, data)
+	if err != nil {
+		return err
+	}
+	return q.EnqueueEmail(ctx, database.EnqueueEmailParams{
+		ID:        uuid.New(),
+		Template:  template,
+		ToAddress: to,
+		Subject:   msg.Subject,
+		TextBody:  msg.Text,
+		HtmlBody:  msg.HTML,
+	})
+}
+
+// emailBackoff is the delay before the next attempt after attempts failed
+// sends.
+func emailBackoff(attempts int32) time.Duration {
+	d := emailBaseBackoff
+	for i := int32(1); i < attempts && d < emailMaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, emailMaxBackoff)
+}
+
+// runEmailWorker sends due emails until ctx is done. Rows are claimed with
+// SKIP LOCKED, so running more than one server doesn't double-send.
+func (s *Server) runEmailWorker(ctx context.Context) {
+	ticker := time.NewTicker(emailPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := s.sendEmailBatch(ctx)
+			if err != nil {
+				fmt.Println("Error sending emails:", err)
+				break
+			}
+			if n < emailBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) sendEmailBatch(ctx context.Context) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	emails, err := tx.ClaimDueEmails(ctx, emailBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range emails {
+		sendErr := s.mailer.Send(ctx, mail.Message{
+			To:      []string{e.ToAddress},
+			Subject: e.Subject,
+			Text:    e.TextBody,
+			HTML:    e.HtmlBody,
+		})
+		if sendErr == nil {
+			err = tx.MarkEmailSent(ctx, e.ID)
+		} else {
+			attempts := e.Attempts + 1
+			status := emailStatusPending
+			if attempts >= maxEmailAttempts {
+				status = emailStatusDead
+			}
+			err = tx.MarkEmailFailed(ctx, database.MarkEmailFailedParams{
+				ID:            e.ID,
+				Status:        status,
+				LastError:     sendErr.Error(),
+				NextAttemptAt: s.clock.Now().UTC().Add(emailBackoff(attempts)),
+			})
+			if err == nil && status == emailStatusDead {
+				err = recordDeadLetter(ctx, tx, deadLetterEmail, e.ID.String(), e.Template+" email to "+e.ToAddress, attempts, sendErr.Error())
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(emails), tx.Commit()
+}
+
+type failedEmailResponse struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt Timestamp `json:"created_at"`
+	UpdatedAt Timestamp `json:"updated_at"`
+	Template  string    `json:"template"`
+	To        string    `json:"to"`
+	Subject   string    `json:"subject"`
+	Attempts  int32     `json:"attempts"`
+	LastError string    `json:"last_error"`
+}
+
+// handlerAdminEmailsFailed lists dead-lettered emails, most recent first.
+func (s *Server) handlerAdminEmailsFailed(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := 50
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 200 {
+			jsonResponse(w, http.StatusBadRequest, "limit must be between 1 and 200")
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			jsonResponse(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = n
+	}
+
+	emails, err := s.db.ListDeadEmails(r.Context(), database.ListDeadEmailsParams{
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	response := make([]failedEmailResponse, 0, len(emails))
+	for _, e := range emails {
+		response = append(response, failedEmailResponse{
+			ID:        e.ID,
+			CreatedAt: Timestamp{e.CreatedAt},
+			UpdatedAt: Timestamp{e.UpdatedAt},
+			Template:  e.Template,
+			To:        e.ToAddress,
+			Subject:   e.Subject,
+			Attempts:  e.Attempts,
+			LastError: e.LastError,
+		})
+	}
+	jsonResponse(w, http.StatusOK, response)
+}
+
+// handlerAdminEmailRetry puts a dead-lettered email back in the queue with
+// a fresh set of attempts.
+func (s *Server) handlerAdminEmailRetry(w http.ResponseWriter, r *http.Request) {
+	emailID, ok := pathUUID(w, r, "emailID")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	n, err := tx.RetryEmail(ctx, emailID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if n == 0 {
+		jsonResponse(w, http.StatusNotFound, "No failed email with that ID")
+		return
+	}
+	err = tx.ResolveDeadLetter(ctx, database.ResolveDeadLetterParams{
+		Source:   deadLetterEmail,
+		SourceID: emailID.String(),
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	admin := adminFromContext(ctx)
+	if err := recordAudit(ctx, tx, admin.ID, "email.retry", emailID, map[string]interface{}{}); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
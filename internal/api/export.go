@@ -0,0 +1,141 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const exportBatchSize = 500
+
+// exportWriter writes chirps in one export format.
+type exportWriter interface {
+	write(c database.Chirp) error
+	flush() error
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (e *csvExportWriter) write(c database.Chirp) error {
+	return e.w.Write([]string{
+		c.ID.String(),
+		c.CreatedAt.UTC().Format(timestampFormat),
+		c.UpdatedAt.UTC().Format(timestampFormat),
+		csvSafe(c.Body),
+	})
+}
+
+func (e *csvExportWriter) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// csvSafe stops spreadsheets from treating a chirp as a formula.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+type jsonlExportWriter struct {
+	enc *json.Encoder
+}
+
+func (e *jsonlExportWriter) write(c database.Chirp) error {
+	return e.enc.Encode(chirpResponse{
+		ID:        c.ID,
+		CreatedAt: Timestamp{c.CreatedAt},
+		UpdatedAt: Timestamp{c.UpdatedAt},
+		Body:      c.Body,
+		UserID:    c.UserID,
+	})
+}
+
+func (e *jsonlExportWriter) flush() error {
+	return nil
+}
+
+// handlerChirpsExport streams all of the authenticated user's chirps,
+// oldest first, as CSV or JSON Lines. Rows are read in keyset-paginated
+// batches and flushed as they go, so large accounts don't have to fit in
+// memory.
+func (s *Server) handlerChirpsExport(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "jsonl"
+	}
+
+	var out exportWriter
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="chirps.csv"`)
+		if err := cw.Write([]string{"id", "created_at", "updated_at", "body"}); err != nil {
+			return
+		}
+		out = &csvExportWriter{w: cw}
+	case "jsonl":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="chirps.jsonl"`)
+		out = &jsonlExportWriter{enc: json.NewEncoder(w)}
+	default:
+		jsonResponse(w, http.StatusBadRequest, "format must be csv or jsonl")
+		return
+	}
+
+	noWriteDeadline(w)
+	noRequestTimeout(r)
+	flusher, _ := w.(http.Flusher)
+	ctx := r.Context()
+	var afterCreatedAt time.Time
+	afterID := uuid.Nil
+	for {
+		chirps, err := s.db.ListUserChirpsAfter(ctx, database.ListUserChirpsAfterParams{
+			UserID:         userID,
+			AfterCreatedAt: afterCreatedAt,
+			AfterID:        afterID,
+			RowLimit:       exportBatchSize,
+		})
+		if err != nil {
+			// Headers may already be out, so all we can do is cut the
+			// stream short.
+			fmt.Println("Error exporting chirps:", err)
+			return
+		}
+
+		for _, c := range chirps {
+			if err := out.write(c); err != nil {
+				return
+			}
+		}
+		if err := out.flush(); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if len(chirps) < exportBatchSize {
+			return
+		}
+		last := chirps[len(chirps)-1]
+		afterCreatedAt, afterID = last.CreatedAt, last.ID
+	}
+}
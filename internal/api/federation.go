@@ -0,0 +1,397 @@
+package api
+
+import (
+	"context"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"chirpy/internal/activitypub"
+	"chirpy/internal/database"
+	"chirpy/internal/mail"
+
+	"github.com/google/uuid"
+)
+
+const (
+	outboxPageSize  = 20
+	maxInboxBodyLen = 1 << 20
+	// federationTaskTimeout bounds the queries and delivery of background
+	// federation work, which has no request to inherit a deadline from.
+	federationTaskTimeout = 30 * time.Second
+)
+
+// federationEnabled reports whether ActivityPub is switched on. It needs a
+// public base URL because every actor and object ID is an absolute URL.
+func (s *Server) federationEnabled() bool {
+	return s.config.PublicURL != ""
+}
+
+func (s *Server) actorURI(userID uuid.UUID) string {
+	return s.config.PublicURL + "/ap/users/" + userID.String()
+}
+
+func (s *Server) noteURI(chirpID uuid.UUID) string {
+	return s.config.PublicURL + "/ap/chirps/" + chirpID.String()
+}
+
+// actorKey returns the user's signing key, creating one on first use.
+func (s *Server) actorKey(ctx context.Context, userID uuid.UUID) (database.ActorKey, error) {
+	key, err := s.db.GetActorKey(ctx, userID)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.ActorKey{}, err
+	}
+
+	privatePEM, publicPEM, err := activitypub.GenerateKey()
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+	// Another request may have raced us; the insert is a no-op then and we
+	// read back whichever key won.
+	err = s.db.CreateActorKey(ctx, database.CreateActorKeyParams{
+		UserID:        userID,
+		PublicKeyPem:  publicPEM,
+		PrivateKeyPem: privatePEM,
+	})
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+	return s.db.GetActorKey(ctx, userID)
+}
+
+func activityResponse(w http.ResponseWriter, statusCode int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", activitypub.ContentType)
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// federatedUser loads the user in the {userID} path parameter, treating
+// banned and shadowbanned accounts as absent from the fediverse.
+func (s *Server) federatedUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		http.NotFound(w, r)
+		return database.User{}, false
+	}
+
+	user, err := s.db.GetUserByID(r.Context(), userID)
+	if err != nil || user.BannedAt.Valid || user.Shadowbanned {
+		http.NotFound(w, r)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func (s *Server) newNote(c database.Chirp) activitypub.Note {
+	return activitypub.Note{
+		ID:           s.noteURI(c.ID),
+		Type:         "Note",
+		AttributedTo: s.actorURI(c.UserID),
+		Content:      "<p>" + html.EscapeString(c.Body) + "</p>",
+		Published:    c.CreatedAt.UTC().Format(time.RFC3339),
+		To:           []string{activitypub.Public},
+		Cc:           []string{s.actorURI(c.UserID) + "/followers"},
+	}
+}
+
+func (s *Server) newCreateActivity(c database.Chirp) (activitypub.Activity, error) {
+	note := s.newNote(c)
+	object, err := json.Marshal(note)
+	if err != nil {
+		return activitypub.Activity{}, err
+	}
+	return activitypub.Activity{
+		ID:        note.ID + "/activity",
+		Type:      "Create",
+		Actor:     note.AttributedTo,
+		Object:    object,
+		Published: note.Published,
+		To:        note.To,
+		Cc:        note.Cc,
+	}, nil
+}
+
+func (s *Server) handlerAPActor(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.federatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := s.actorKey(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	id := s.actorURI(user.ID)
+	activityResponse(w, http.StatusOK, activitypub.Actor{
+		Context:           activitypub.DefaultContext(),
+		ID:                id,
+		Type:              "Person",
+		PreferredUsername: preferredUsername(user),
+		Inbox:             id + "/inbox",
+		Outbox:            id + "/outbox",
+		Followers:         id + "/followers",
+		PublicKey: activitypub.PublicKey{
+			ID:           id + "#main-key",
+			Owner:        id,
+			PublicKeyPem: key.PublicKeyPem,
+		},
+	})
+}
+
+func (s *Server) handlerAPOutbox(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.federatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	chirps, err := s.db.ListUserChirps(r.Context(), database.ListUserChirpsParams{
+		UserID: user.ID,
+		Limit:  outboxPageSize,
+	})
+	if err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]interface{}, 0, len(chirps))
+	for _, c := range chirps {
+		activity, err := s.newCreateActivity(c)
+		if err != nil {
+			http.Error(w, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		items = append(items, activity)
+	}
+
+	activityResponse(w, http.StatusOK, activitypub.OrderedCollection{
+		Context:      activitypub.DefaultContext(),
+		ID:           s.actorURI(user.ID) + "/outbox",
+		Type:         "OrderedCollection",
+		TotalItems:   len(items),
+		OrderedItems: items,
+	})
+}
+
+func (s *Server) handlerAPFollowers(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.federatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := s.db.CountRemoteFollowers(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	// Follower identities aren't published, only the count.
+	activityResponse(w, http.StatusOK, activitypub.OrderedCollection{
+		Context:    activitypub.DefaultContext(),
+		ID:         s.actorURI(user.ID) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: int(count),
+	})
+}
+
+func (s *Server) handlerAPNote(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	row, err := s.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{ID: chirpID})
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	note := s.newNote(database.Chirp{
+		ID:        row.ID,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+		Body:      row.Body,
+		UserID:    row.UserID,
+	})
+	note.Context = activitypub.DefaultContext()
+	activityResponse(w, http.StatusOK, note)
+}
+
+// handlerAPInbox accepts Follow and Undo{Follow} activities for a local
+// user. Every delivery must carry a valid HTTP Signature from the actor it
+// claims to come from.
+func (s *Server) handlerAPInbox(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.federatedUser(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxInboxBodyLen))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	remote, err := s.verifyInboxSignature(r, body)
+	if err != nil {
+		fmt.Println("Rejected inbox delivery:", err)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var activity activitypub.Activity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		http.Error(w, "Invalid activity", http.StatusBadRequest)
+		return
+	}
+	if activity.Actor != remote.ID {
+		http.Error(w, "Activity actor does not match signature", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	localActor := s.actorURI(user.ID)
+
+	switch activity.Type {
+	case "Follow":
+		if activity.ObjectID() != localActor {
+			http.Error(w, "Follow is not for this actor", http.StatusBadRequest)
+			return
+		}
+		// The follow, its Accept and the email to the followed user commit
+		// together, so a crash can't leave a follower who was never
+		// accepted.
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			http.Error(w, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		err = tx.UpsertRemoteFollower(ctx, database.UpsertRemoteFollowerParams{
+			ID:       uuid.New(),
+			UserID:   user.ID,
+			ActorUri: remote.ID,
+			InboxUri: remote.PreferredInbox(),
+		})
+		if err != nil {
+			http.Error(w, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		accept := activitypub.Activity{
+			Context: activitypub.DefaultContext(),
+			ID:      localActor + "#accepts/" + uuid.NewString(),
+			Type:    "Accept",
+			Actor:   localActor,
+			Object:  json.RawMessage(body),
+		}
+		if err := recordDelivery(ctx, tx, user.ID, remote.Inbox, accept); err != nil {
+			fmt.Println("Error recording Accept delivery:", err)
+			http.Error(w, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+		prefs, err := tx.GetUserPreferences(ctx, user.ID)
+		if err != nil {
+			http.Error(w, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		if prefs.EmailNewFollower {
+			follower := remote.PreferredUsername
+			if u, err := url.Parse(remote.ID); err == nil && follower != "" {
+				follower = "@" + follower + "@" + u.Host
+			} else {
+				follower = remote.ID
+			}
+			link := remote.URL
+			if link == "" {
+				link = remote.ID
+			}
+			err = s.enqueueEmail(ctx, tx, mail.TemplateNewFollower, user.Email, mail.TemplateData{
+				Name:     preferredUsername(user),
+				Link:     link,
+				Follower: follower,
+			})
+			if err != nil {
+				fmt.Println("Error queueing follower email:", err)
+				http.Error(w, "Something went wrong", http.StatusInternalServerError)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+
+	case "Undo":
+		if activity.ObjectType() == "Follow" {
+			err := s.db.DeleteRemoteFollower(ctx, database.DeleteRemoteFollowerParams{
+				UserID:   user.ID,
+				ActorUri: remote.ID,
+			})
+			if err != nil {
+				http.Error(w, "Something went wrong", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// verifyInboxSignature fetches the signing actor named by the request's
+// keyId and checks the signature against its published key.
+func (s *Server) verifyInboxSignature(r *http.Request, body []byte) (*activitypub.Actor, error) {
+	keyID, err := activitypub.SignatureKeyID(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(keyID, "https://") && s.config.Platform != "dev" {
+		return nil, errors.New("keyId must be an https URL")
+	}
+
+	actorURL, _, _ := strings.Cut(keyID, "#")
+	remote, err := activitypub.FetchActor(r.Context(), s.federationClient, actorURL)
+	if err != nil {
+		return nil, err
+	}
+	if remote.PublicKey.ID != keyID {
+		return nil, errors.New("keyId does not belong to actor")
+	}
+
+	pub, err := activitypub.ParsePublicKey(remote.PublicKey.PublicKeyPem)
+	if err != nil {
+		return nil, err
+	}
+	if err := activitypub.VerifyRequest(r, body, pub); err != nil {
+		return nil, err
+	}
+	return remote, nil
+}
+
+func (s *Server) signingKey(ctx context.Context, userID uuid.UUID) (*rsa.PrivateKey, error) {
+	key, err := s.actorKey(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return activitypub.ParsePrivateKey(key.PrivateKeyPem)
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+
+	"chirpy/internal/fieldset"
+)
+
+// requestedFields parses the ?fields= parameter, checking it against a
+// zero example of the response so typos are caught even when there is
+// nothing to return. When it's invalid, requestedFields answers 400 and
+// returns false; the caller should just return. A nil Set means every
+// field.
+func requestedFields(w http.ResponseWriter, r *http.Request, example interface{}) (fieldset.Set, bool) {
+	fields, err := fieldset.Parse(r.URL.Query().Get("fields"))
+	if err == nil && fields != nil {
+		_, err = fieldset.Project(example, fields)
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, paramError{
+			Error: "Invalid fields: " + err.Error(),
+			Param: "fields",
+			Value: r.URL.Query().Get("fields"),
+		})
+		return nil, false
+	}
+	return fields, true
+}
+
+// jsonFieldsResponse is jsonResponse with the response trimmed to the
+// fields the client asked for with ?fields=.
+func jsonFieldsResponse(w http.ResponseWriter, r *http.Request, statusCode int, response interface{}) {
+	fields, ok := requestedFields(w, r, response)
+	if !ok {
+		return
+	}
+	projected, err := fieldset.Project(response, fields)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, paramError{
+			Error: "Invalid fields: " + err.Error(),
+			Param: "fields",
+			Value: r.URL.Query().Get("fields"),
+		})
+		return
+	}
+	jsonResponse(w, statusCode, projected)
+}
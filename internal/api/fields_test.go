@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"chirpy/internal/config"
+)
+
+func TestSparseFieldsets(t *testing.T) {
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: &contractStore{}, Clock: fixedClock(testNow)}))
+
+	for _, tc := range []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/api/chirps/" + contractChirpID.String() + "?fields=body,id", http.StatusOK, `{"id":"` + contractChirpID.String() + `","body":"The first chirp"}`},
+		{"/api/chirps?fields=id,author_verified", http.StatusOK, `[{"id":"` + contractChirpID.String() + `","author_verified":true}]` + "\n"},
+		{"/api/chirps?fields=id,preview_card", http.StatusBadRequest, `{"error":"Invalid fields: unknown field \"preview_card\"","param":"fields","value":"id,preview_card"}`},
+		{"/api/chirps/" + contractChirpID.String() + "?fields=created_at.year", http.StatusBadRequest, ""},
+	} {
+		rec := do(h, http.MethodGet, tc.path, "")
+		if rec.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d: %s", tc.path, tc.code, rec.Code, rec.Body)
+			continue
+		}
+		if tc.body != "" && rec.Body.String() != tc.body {
+			t.Errorf("%s:\n got %s\nwant %s", tc.path, rec.Body, tc.body)
+		}
+	}
+}
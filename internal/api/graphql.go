@@ -0,0 +1,328 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const graphqlSchema = `
+scalar Time
+
+schema {
+  query: Query
+  mutation: Mutation
+}
+
+type Query {
+  # Look a user up by ID or handle.
+  user(id: ID, handle: String): User
+  chirp(id: ID!): Chirp
+  # The public timeline, newest first.
+  timeline(limit: Int = 20): [Chirp!]!
+  # The authenticated user.
+  me: User
+}
+
+type Mutation {
+  createChirp(body: String!): Chirp!
+}
+
+type User {
+  id: ID!
+  handle: String
+  verified: Boolean!
+  createdAt: Time!
+  chirpCount: Int!
+  chirps(limit: Int = 20): [Chirp!]!
+}
+
+type Chirp {
+  id: ID!
+  body: String!
+  createdAt: Time!
+  updatedAt: Time!
+  # Sensitive chirps should be shown collapsed, behind contentWarning when
+  # the author gave one.
+  sensitive: Boolean!
+  contentWarning: String
+  # Null when the author is banned.
+  author: User
+}
+`
+
+const (
+	graphqlViewerKey contextKey = "graphqlViewer"
+
+	graphqlMaxLimit = 100
+)
+
+var errGraphQLInternal = errors.New("something went wrong")
+
+func graphqlViewer(ctx context.Context) uuid.UUID {
+	id, _ := ctx.Value(graphqlViewerKey).(uuid.UUID)
+	return id
+}
+
+// handlerGraphQL serves POST /api/graphql. Authentication is optional and
+// uses the same bearer tokens as the REST API; mutations require it.
+func (s *Server) handlerGraphQL(schema *graphql.Schema) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var params struct {
+			Query         string                 `json:"query"`
+			OperationName string                 `json:"operationName"`
+			Variables     map[string]interface{} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			jsonResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), graphqlViewerKey, s.viewerID(r))
+
+		response := schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
+		jsonResponse(w, http.StatusOK, response)
+	}
+}
+
+func (s *Server) newGraphQLSchema() *graphql.Schema {
+	return graphql.MustParseSchema(graphqlSchema, &graphqlResolver{srv: s}, graphql.MaxDepth(8))
+}
+
+type graphqlResolver struct {
+	srv *Server
+}
+
+func clampLimit(limit int32) int32 {
+	if limit < 1 {
+		return 1
+	}
+	if limit > graphqlMaxLimit {
+		return graphqlMaxLimit
+	}
+	return limit
+}
+
+func (r *graphqlResolver) User(ctx context.Context, args struct {
+	ID     *graphql.ID
+	Handle *string
+}) (*userResolver, error) {
+	var (
+		user database.User
+		err  error
+	)
+	switch {
+	case args.ID != nil:
+		id, parseErr := uuid.Parse(string(*args.ID))
+		if parseErr != nil {
+			return nil, nil
+		}
+		user, err = r.srv.db.GetUserByID(ctx, id)
+	case args.Handle != nil:
+		handle, ok := normalizeHandle(*args.Handle)
+		if !ok {
+			return nil, nil
+		}
+		user, _, err = r.srv.userByHandle(ctx, handle)
+	default:
+		return nil, errors.New("user requires an id or a handle")
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("graphql: looking up user: %v", err)
+		return nil, errGraphQLInternal
+	}
+	return newUserResolver(r.srv, user), nil
+}
+
+func (r *graphqlResolver) Me(ctx context.Context) (*userResolver, error) {
+	viewer := graphqlViewer(ctx)
+	if viewer == uuid.Nil {
+		return nil, nil
+	}
+	user, err := r.srv.db.GetUserByID(ctx, viewer)
+	if err != nil {
+		return nil, nil
+	}
+	return newUserResolver(r.srv, user), nil
+}
+
+func (r *graphqlResolver) Chirp(ctx context.Context, args struct{ ID graphql.ID }) (*chirpResolver, error) {
+	id, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return nil, nil
+	}
+	chirp, err := r.srv.db.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+		ID:       id,
+		ViewerID: graphqlViewer(ctx),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Printf("graphql: looking up chirp: %v", err)
+		return nil, errGraphQLInternal
+	}
+	return &chirpResolver{srv: r.srv, c: newFlaggedChirp(chirp)}, nil
+}
+
+func (r *graphqlResolver) Timeline(ctx context.Context, args struct{ Limit int32 }) ([]*chirpResolver, error) {
+	rows, err := r.srv.db.ListRecentChirps(ctx, database.ListRecentChirpsParams{
+		ViewerID: graphqlViewer(ctx),
+		RowLimit: clampLimit(args.Limit),
+	})
+	if err != nil {
+		log.Printf("graphql: listing timeline: %v", err)
+		return nil, errGraphQLInternal
+	}
+	chirps := make([]*chirpResolver, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, &chirpResolver{srv: r.srv, c: newFlaggedChirp(database.GetVisibleChirpRow(row))})
+	}
+	return chirps, nil
+}
+
+func (r *graphqlResolver) CreateChirp(ctx context.Context, args struct{ Body string }) (*chirpResolver, error) {
+	viewer := graphqlViewer(ctx)
+	if viewer == uuid.Nil {
+		return nil, errors.New("authentication required")
+	}
+	chirp, _, err := r.srv.createChirp(ctx, viewer, args.Body)
+	if errors.Is(err, errChirpTooLong) || errors.Is(err, errChirpBlocked) {
+		return nil, err
+	}
+	if err != nil {
+		log.Printf("graphql: creating chirp: %v", err)
+		return nil, errGraphQLInternal
+	}
+	return &chirpResolver{srv: r.srv, c: flaggedChirp{Chirp: chirp}}, nil
+}
+
+// newUserResolver hides banned accounts the same way the REST API hides
+// their chirps. Every field returning a user goes through it.
+func newUserResolver(srv *Server, u database.User) *userResolver {
+	if u.BannedAt.Valid {
+		return nil
+	}
+	return &userResolver{srv: srv, u: u}
+}
+
+type userResolver struct {
+	srv *Server
+	u   database.User
+}
+
+func (r *userResolver) ID() graphql.ID {
+	return graphql.ID(r.u.ID.String())
+}
+
+func (r *userResolver) Handle() *string {
+	if !r.u.Handle.Valid {
+		return nil
+	}
+	return &r.u.Handle.String
+}
+
+func (r *userResolver) Verified() bool {
+	return r.u.Verified
+}
+
+func (r *userResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.u.CreatedAt}
+}
+
+// visibleTo reports whether the user's chirps are visible to viewer:
+// never a banned user's, and a shadowbanned one's only to themselves.
+func (r *userResolver) visibleTo(viewer uuid.UUID) bool {
+	if r.u.BannedAt.Valid {
+		return false
+	}
+	return !r.u.Shadowbanned || r.u.ID == viewer
+}
+
+func (r *userResolver) ChirpCount(ctx context.Context) (int32, error) {
+	if !r.visibleTo(graphqlViewer(ctx)) {
+		return 0, nil
+	}
+	count, _, err := r.srv.loaders(ctx).chirpCounts.Load(ctx, r.u.ID)
+	if err != nil {
+		log.Printf("graphql: counting chirps: %v", err)
+		return 0, errGraphQLInternal
+	}
+	return int32(count), nil
+}
+
+func (r *userResolver) Chirps(ctx context.Context, args struct{ Limit int32 }) ([]*chirpResolver, error) {
+	viewer := graphqlViewer(ctx)
+	if !r.visibleTo(viewer) {
+		return []*chirpResolver{}, nil
+	}
+	rows, err := r.srv.db.ListVisibleUserChirps(ctx, database.ListVisibleUserChirpsParams{
+		UserID:   r.u.ID,
+		ViewerID: viewer,
+		RowLimit: clampLimit(args.Limit),
+	})
+	if err != nil {
+		log.Printf("graphql: listing user chirps: %v", err)
+		return nil, errGraphQLInternal
+	}
+	chirps := make([]*chirpResolver, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, &chirpResolver{srv: r.srv, c: newFlaggedChirp(database.GetVisibleChirpRow(row))})
+	}
+	return chirps, nil
+}
+
+type chirpResolver struct {
+	srv *Server
+	c   flaggedChirp
+}
+
+func (r *chirpResolver) ID() graphql.ID {
+	return graphql.ID(r.c.ID.String())
+}
+
+func (r *chirpResolver) Body() string {
+	return r.c.Body
+}
+
+func (r *chirpResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.c.CreatedAt}
+}
+
+func (r *chirpResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: r.c.UpdatedAt}
+}
+
+func (r *chirpResolver) Sensitive() bool {
+	return r.c.Sensitive
+}
+
+func (r *chirpResolver) ContentWarning() *string {
+	if r.c.Warning == "" {
+		return nil
+	}
+	return &r.c.Warning
+}
+
+func (r *chirpResolver) Author(ctx context.Context) (*userResolver, error) {
+	user, found, err := r.srv.loaders(ctx).users.Load(ctx, r.c.UserID)
+	if err != nil {
+		log.Printf("graphql: loading author: %v", err)
+		return nil, errGraphQLInternal
+	}
+	if !found {
+		return nil, errGraphQLInternal
+	}
+	return newUserResolver(r.srv, user), nil
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// graphqlStore returns chirps whoever wrote them, so what the resolvers
+// hide themselves is what gets tested.
+type graphqlStore struct {
+	fakeStore
+	userChirps []database.Chirp
+	// viewers are the ViewerIDs ListVisibleUserChirps was called with.
+	viewers []uuid.UUID
+}
+
+func (g *graphqlStore) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]database.User, error) {
+	var users []database.User
+	for _, id := range ids {
+		if u, err := g.GetUserByID(ctx, id); err == nil {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+func (g *graphqlStore) GetVisibleChirp(ctx context.Context, arg database.GetVisibleChirpParams) (database.GetVisibleChirpRow, error) {
+	for _, c := range g.userChirps {
+		if c.ID == arg.ID {
+			return database.GetVisibleChirpRow{ID: c.ID, Body: c.Body, UserID: c.UserID}, nil
+		}
+	}
+	return database.GetVisibleChirpRow{}, sql.ErrNoRows
+}
+
+func (g *graphqlStore) ListVisibleUserChirps(ctx context.Context, arg database.ListVisibleUserChirpsParams) ([]database.ListVisibleUserChirpsRow, error) {
+	g.viewers = append(g.viewers, arg.ViewerID)
+	var rows []database.ListVisibleUserChirpsRow
+	for _, c := range g.userChirps {
+		if c.UserID == arg.UserID {
+			rows = append(rows, database.ListVisibleUserChirpsRow{ID: c.ID, Body: c.Body, UserID: c.UserID})
+		}
+	}
+	return rows, nil
+}
+
+func TestGraphQLHidesBannedAndShadowbannedUsers(t *testing.T) {
+	viewer := newTestUser(t, "viewer@example.com", "04234")
+	banned := newTestUser(t, "banned@example.com", "04234")
+	banned.BannedAt = sql.NullTime{Time: testNow, Valid: true}
+	shadowbanned := newTestUser(t, "shadow@example.com", "04234")
+	shadowbanned.Shadowbanned = true
+	bannedChirp := database.Chirp{ID: uuid.New(), UserID: banned.ID, Body: "banned"}
+	store := &graphqlStore{
+		fakeStore: fakeStore{users: map[string]database.User{
+			viewer.Email:       viewer,
+			banned.Email:       banned,
+			shadowbanned.Email: shadowbanned,
+		}},
+		userChirps: []database.Chirp{
+			bannedChirp,
+			{ID: uuid.New(), UserID: shadowbanned.ID, Body: "shadow"},
+		},
+	}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)}))
+
+	query := func(user database.User, q string) map[string]json.RawMessage {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"query": q})
+		req := httptest.NewRequest(http.MethodPost, "/api/graphql", strings.NewReader(string(body)))
+		req.Header.Set("Authorization", "Bearer "+mustMakeJWT(t, user.ID, cfg.JWTSecret, time.Hour))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var resp struct {
+			Data   map[string]json.RawMessage `json:"data"`
+			Errors []json.RawMessage          `json:"errors"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || len(resp.Errors) != 0 {
+			t.Fatalf("%s: got %d %v %s", q, rec.Code, err, resp.Errors)
+		}
+		return resp.Data
+	}
+
+	if data := query(viewer, `{ chirp(id: "`+bannedChirp.ID.String()+`") { author { id } } }`); string(data["chirp"]) != `{"author":null}` {
+		t.Errorf("expected a banned author to be null, got %s", data["chirp"])
+	}
+	if data := query(banned, `{ me { id } }`); string(data["me"]) != "null" {
+		t.Errorf("expected me to be null for a banned user, got %s", data["me"])
+	}
+
+	shadowChirps := `{ user(id: "` + shadowbanned.ID.String() + `") { chirps { body } } }`
+	if data := query(viewer, shadowChirps); string(data["user"]) != `{"chirps":[]}` {
+		t.Errorf("expected a shadowbanned user's chirps to be hidden from others, got %s", data["user"])
+	}
+	if data := query(shadowbanned, shadowChirps); string(data["user"]) != `{"chirps":[{"body":"shadow"}]}` {
+		t.Errorf("expected a shadowbanned user to see their own chirps, got %s", data["user"])
+	}
+	if len(store.viewers) != 1 || store.viewers[0] != shadowbanned.ID {
+		t.Errorf("expected chirps to be listed for the viewer, got %v", store.viewers)
+	}
+
+	r := &userResolver{u: banned}
+	if r.visibleTo(banned.ID) || r.visibleTo(viewer.ID) {
+		t.Error("expected a banned user's chirps to be visible to nobody")
+	}
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"strings"
+
+	"chirpy/internal/chirpypb"
+	"chirpy/internal/database"
+	"chirpy/internal/tenant"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const grpcDefaultTimelineLimit = 20
+
+// grpcTenantMetadata names the tenant a call is for by slug, for tenants
+// without a hostname of their own, which HTTP reaches under /t/{slug}/.
+const grpcTenantMetadata = "chirpy-tenant"
+
+// grpcServer implements chirpypb.ChirpyService on top of the same queries
+// and chirp creation path as the HTTP API.
+type grpcServer struct {
+	chirpypb.UnimplementedChirpyServiceServer
+	srv *Server
+}
+
+// ServeGRPC listens on addr until the listener fails. It is only started
+// when GRPC_PORT is set.
+func (s *Server) ServeGRPC(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	log.Printf("Serving gRPC on %s", addr)
+	return s.newGRPCServer().Serve(lis)
+}
+
+func (s *Server) newGRPCServer() *grpc.Server {
+	gs := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := s.grpcTenant(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := s.grpcTenant(stream.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, tenantStream{stream, ctx})
+		}),
+	)
+	chirpypb.RegisterChirpyServiceServer(gs, &grpcServer{srv: s})
+	return gs
+}
+
+// grpcTenant scopes ctx to the community a call is for, as
+// middlewareTenant does for HTTP: the tenant with the call's :authority as
+// its hostname, else the one named by chirpy-tenant metadata, else the
+// default community.
+func (s *Server) grpcTenant(ctx context.Context) (context.Context, error) {
+	if !s.config.MultiTenant {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	var host, path string
+	if values := md.Get(":authority"); len(values) > 0 {
+		host = values[0]
+	}
+	if values := md.Get(grpcTenantMetadata); len(values) > 0 {
+		path = tenant.PathPrefix + values[0] + "/"
+	}
+	t, _, ok := s.tenants.Load().Resolve(host, path)
+	if !ok {
+		return nil, status.Error(codes.NotFound, "community not found")
+	}
+	return tenant.NewContext(ctx, t), nil
+}
+
+// tenantStream is a stream whose context has been scoped by grpcTenant.
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s tenantStream) Context() context.Context { return s.ctx }
+
+// grpcViewer returns the user the call's "authorization" metadata was
+// issued to, or uuid.Nil when there is none. An invalid token, or one
+// issued by another community, is an error rather than an anonymous call.
+func (g *grpcServer) grpcViewer(ctx context.Context) (uuid.UUID, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return uuid.Nil, nil
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "malformed authorization metadata")
+	}
+	claims, err := g.srv.tokenIssuer(ctx).Validate(strings.TrimSpace(token))
+	if err != nil {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	// gRPC methods have no scopes yet, so apps can't use it.
+	if claims.ClientID != "" {
+		return uuid.Nil, status.Error(codes.PermissionDenied, "app tokens can't be used over gRPC")
+	}
+	return claims.UserID, nil
+}
+
+func newPBChirp(c database.Chirp, authorVerified bool) *chirpypb.Chirp {
+	return &chirpypb.Chirp{
+		Id:             c.ID.String(),
+		Body:           c.Body,
+		UserId:         c.UserID.String(),
+		AuthorVerified: authorVerified,
+		CreatedAt:      timestamppb.New(c.CreatedAt),
+		UpdatedAt:      timestamppb.New(c.UpdatedAt),
+	}
+}
+
+func pbChirpFromRow(row database.GetVisibleChirpRow) *chirpypb.Chirp {
+	return &chirpypb.Chirp{
+		Id:             row.ID.String(),
+		Body:           row.Body,
+		UserId:         row.UserID.String(),
+		AuthorVerified: row.AuthorVerified,
+		CreatedAt:      timestamppb.New(row.CreatedAt),
+		UpdatedAt:      timestamppb.New(row.UpdatedAt),
+		Sensitive:      row.Sensitive,
+		ContentWarning: row.ContentWarning,
+	}
+}
+
+func (g *grpcServer) CreateChirp(ctx context.Context, req *chirpypb.CreateChirpRequest) (*chirpypb.Chirp, error) {
+	viewer, err := g.grpcViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if viewer == uuid.Nil {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	chirp, author, err := g.srv.createChirp(ctx, viewer, req.GetBody())
+	switch {
+	case errors.Is(err, errChirpTooLong), errors.Is(err, errChirpBlocked):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		log.Printf("grpc: creating chirp: %v", err)
+		return nil, status.Error(codes.Internal, "something went wrong")
+	}
+	return newPBChirp(chirp, author.Verified), nil
+}
+
+func (g *grpcServer) GetTimeline(ctx context.Context, req *chirpypb.GetTimelineRequest) (*chirpypb.GetTimelineResponse, error) {
+	viewer, err := g.grpcViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	limit := req.GetLimit()
+	if limit == 0 {
+		limit = grpcDefaultTimelineLimit
+	}
+
+	rows, err := g.srv.db.ListRecentChirps(ctx, database.ListRecentChirpsParams{
+		ViewerID: viewer,
+		RowLimit: clampLimit(limit),
+	})
+	if err != nil {
+		log.Printf("grpc: listing timeline: %v", err)
+		return nil, status.Error(codes.Internal, "something went wrong")
+	}
+
+	resp := &chirpypb.GetTimelineResponse{Chirps: make([]*chirpypb.Chirp, 0, len(rows))}
+	for _, row := range rows {
+		resp.Chirps = append(resp.Chirps, pbChirpFromRow(database.GetVisibleChirpRow(row)))
+	}
+	return resp, nil
+}
+
+func (g *grpcServer) GetUser(ctx context.Context, req *chirpypb.GetUserRequest) (*chirpypb.User, error) {
+	var (
+		user database.User
+		err  error
+	)
+	switch lookup := req.GetLookup().(type) {
+	case *chirpypb.GetUserRequest_Id:
+		id, parseErr := uuid.Parse(lookup.Id)
+		if parseErr != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+		}
+		user, err = g.srv.db.GetUserByID(ctx, id)
+	case *chirpypb.GetUserRequest_Handle:
+		handle, ok := normalizeHandle(lookup.Handle)
+		if !ok {
+			return nil, status.Error(codes.InvalidArgument, "invalid handle")
+		}
+		user, _, err = g.srv.userByHandle(ctx, handle)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "id or handle is required")
+	}
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.BannedAt.Valid) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		log.Printf("grpc: looking up user: %v", err)
+		return nil, status.Error(codes.Internal, "something went wrong")
+	}
+
+	return &chirpypb.User{
+		Id:        user.ID.String(),
+		Handle:    user.Handle.String,
+		Verified:  user.Verified,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+	}, nil
+}
+
+// StreamChirps forwards chirp.created events from the hub. The event only
+// carries the raw row, so each chirp is re-read to apply visibility rules
+// and pick up the author's badge and content warning.
+func (g *grpcServer) StreamChirps(req *chirpypb.StreamChirpsRequest, stream grpc.ServerStreamingServer[chirpypb.Chirp]) error {
+	ctx := stream.Context()
+	viewer, err := g.grpcViewer(ctx)
+	if err != nil {
+		return err
+	}
+	hideSensitive, err := g.srv.hidesSensitive(ctx, viewer)
+	if err != nil {
+		log.Printf("grpc: reading content preferences: %v", err)
+		return status.Error(codes.Internal, "something went wrong")
+	}
+
+	events, unsubscribe := g.srv.hub.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if e.Type != "chirp.created" || !inTenant(ctx, e) {
+				continue
+			}
+			var data struct {
+				ID uuid.UUID `json:"id"`
+			}
+			if err := json.Unmarshal(e.Data, &data); err != nil {
+				continue
+			}
+			row, err := g.srv.db.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+				ID:       data.ID,
+				ViewerID: viewer,
+			})
+			if err != nil || (hideSensitive && row.Sensitive && row.UserID != viewer) {
+				continue
+			}
+			if err := stream.Send(pbChirpFromRow(row)); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/chirpypb"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+	"chirpy/internal/tenant"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// tenantChirpStore lists chirps as the row-level security policies would
+// show them: a connection scoped to a tenant sees that tenant's, and an
+// unscoped one sees everyone's.
+type tenantChirpStore struct {
+	tenantStore
+	chirps map[string][]database.ListRecentChirpsRow
+}
+
+func (s *tenantChirpStore) ListRecentChirps(ctx context.Context, arg database.ListRecentChirpsParams) ([]database.ListRecentChirpsRow, error) {
+	t, ok := tenant.FromContext(ctx)
+	if ok {
+		return s.chirps[t.Key()], nil
+	}
+	var all []database.ListRecentChirpsRow
+	for _, rows := range s.chirps {
+		all = append(all, rows...)
+	}
+	return all, nil
+}
+
+func TestGRPCTenantScope(t *testing.T) {
+	acme := database.Tenant{
+		ID:       uuid.New(),
+		Slug:     "acme",
+		Hostname: sql.NullString{String: "chirps.acme.example", Valid: true},
+		Name:     "Acme",
+		Config:   json.RawMessage(`{}`),
+	}
+	birds := database.Tenant{ID: uuid.New(), Slug: "birds", Name: "Birds", Config: json.RawMessage(`{}`)}
+	user := newTestUser(t, "saul@example.com", "04234")
+	store := &tenantChirpStore{
+		tenantStore: tenantStore{tenants: []database.Tenant{acme, birds}},
+		chirps: map[string][]database.ListRecentChirpsRow{
+			acme.ID.String():  {{ID: uuid.New(), Body: "acme", UserID: user.ID}},
+			birds.ID.String(): {{ID: uuid.New(), Body: "birds", UserID: uuid.New()}},
+		},
+	}
+	cfg := &config.Config{JWTSecret: "test-secret", MultiTenant: true, PublicURL: "https://chirpy.example"}
+	s := NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)})
+	if err := s.reloadTenants(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 16)
+	gs := s.newGRPCServer()
+	go gs.Serve(lis)
+	defer gs.Stop()
+	conn, err := grpc.NewClient("passthrough:///chirpy.example",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := chirpypb.NewChirpyServiceClient(conn)
+
+	token, err := s.tokenIssuer(tenant.NewContext(context.Background(), newTenant(acme))).Issue(user.ID, auth.Profile{}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeline := func(authority string, md ...string) (*chirpypb.GetTimelineResponse, error) {
+		md = append(md, "authorization", "Bearer "+token)
+		ctx := metadata.AppendToOutgoingContext(context.Background(), md...)
+		return client.GetTimeline(ctx, &chirpypb.GetTimelineRequest{}, grpc.CallAuthority(authority))
+	}
+
+	resp, err := timeline("chirps.acme.example")
+	if err != nil {
+		t.Fatalf("own tenant: %v", err)
+	}
+	if len(resp.Chirps) != 1 || resp.Chirps[0].Body != "acme" {
+		t.Errorf("expected only the tenant's own chirps, got %v", resp.Chirps)
+	}
+	if _, err := timeline("chirpy.example", grpcTenantMetadata, "birds"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("another tenant's token: expected Unauthenticated, got %v", err)
+	}
+	if _, err := timeline("chirpy.example"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("a tenant's token in the default community: expected Unauthenticated, got %v", err)
+	}
+	if _, err := timeline("chirpy.example", grpcTenantMetadata, "nobody"); status.Code(err) != codes.NotFound {
+		t.Errorf("unknown tenant: expected NotFound, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// handleChangeInterval is how long a user waits between handle changes.
+	handleChangeInterval = 30 * 24 * time.Hour
+	// handleRedirectPeriod is how long an old handle leads to its user's
+	// profile. Until then nobody else can take it.
+	handleRedirectPeriod = 90 * 24 * time.Hour
+)
+
+// userByHandle finds the user with handle, or the user who changed away
+// from it within handleRedirectPeriod. moved reports the latter.
+func (s *Server) userByHandle(ctx context.Context, handle string) (user database.User, moved bool, err error) {
+	user, err = s.db.GetUserByHandle(ctx, nullString(handle))
+	if !errors.Is(err, sql.ErrNoRows) {
+		return user, false, err
+	}
+	current, err := s.db.GetHandleRedirect(ctx, handle)
+	if err != nil {
+		return database.User{}, false, err
+	}
+	user, err = s.db.GetUserByHandle(ctx, current)
+	return user, true, err
+}
+
+type handleRequest struct {
+	Handle string `json:"handle"`
+}
+
+// handlerHandleChange sets the user's handle. A user with a handle can
+// change it once per handleChangeInterval. The old one keeps leading to
+// them, and stays theirs, for handleRedirectPeriod.
+func (s *Server) handlerHandleChange(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var req handleRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	handle, ok := normalizeHandle(req.Handle)
+	if !ok {
+		jsonResponse(w, http.StatusBadRequest, "Handle must be 3-30 letters, digits or underscores")
+		return
+	}
+
+	ctx := r.Context()
+	user, err := s.db.GetUserByID(ctx, userID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if user.Handle.Valid && user.Handle.String == handle {
+		jsonResponse(w, http.StatusOK, s.newUserResponse(user))
+		return
+	}
+	if user.Handle.Valid {
+		last, err := s.db.GetLastHandleChange(ctx, userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		if wait := last.Add(handleChangeInterval).Sub(s.clock.Now().UTC()); err == nil && wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			jsonResponse(w, http.StatusTooManyRequests, fmt.Sprintf("You can change your handle again in %d days", int(math.Ceil(wait.Hours()/24))))
+			return
+		}
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	updated, err := tx.ChangeUserHandle(ctx, database.ChangeUserHandleParams{
+		Handle: nullString(handle),
+		ID:     userID,
+	})
+	var pqErr *pq.Error
+	if errors.Is(err, sql.ErrNoRows) || (errors.As(err, &pqErr) && pqErr.Constraint == "users_handle_key") {
+		jsonResponse(w, http.StatusConflict, "Handle is already taken")
+		return
+	}
+	if err != nil {
+		fmt.Println("Error changing handle:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if user.Handle.Valid {
+		err = tx.RecordHandleChange(ctx, database.RecordHandleChangeParams{
+			Handle:    user.Handle.String,
+			UserID:    userID,
+			ExpiresAt: s.clock.Now().UTC().Add(handleRedirectPeriod),
+		})
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	}
+	// Taking back an old handle ends its redirect.
+	if err := tx.ReleaseHandle(ctx, database.ReleaseHandleParams{Handle: handle, UserID: userID}); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	s.typeaheadCache.invalidate()
+	jsonResponse(w, http.StatusOK, s.newUserResponse(updated))
+}
+
+// handlerProfilePage serves the frontend's profile pages, first sending
+// visitors to an old handle on to the new one.
+func (s *Server) handlerProfilePage(app http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, ok := strings.CutPrefix(r.PathValue("handle"), "@")
+		handle, valid := normalizeHandle(name)
+		if !ok || !valid {
+			app.ServeHTTP(w, r)
+			return
+		}
+		user, moved, err := s.userByHandle(r.Context(), handle)
+		if err != nil || !moved {
+			app.ServeHTTP(w, r)
+			return
+		}
+		http.Redirect(w, r, s.appURL("", "profile/@"+user.Handle.String), http.StatusMovedPermanently)
+	}
+}
+
+func (s *Server) newUserResponse(user database.User) UserResponse {
+	return UserResponse{
+		ID:          user.ID.String(),
+		Email:       user.Email,
+		CreatedAt:   Timestamp{user.CreatedAt},
+		UpdatedAt:   Timestamp{user.UpdatedAt},
+		Handle:      user.Handle.String,
+		Verified:    user.Verified,
+		IsChirpyRed: planFor(user).ChirpyRed,
+		NoAds:       planFor(user).NoAds,
+		AvatarURL:   s.avatarURL(s.config.PublicURL, user),
+	}
+}
+
+// profileResponse is what anyone can see of a user.
+type profileResponse struct {
+	ID        string    `json:"id"`
+	Handle    string    `json:"handle,omitempty"`
+	CreatedAt Timestamp `json:"created_at"`
+	UpdatedAt Timestamp `json:"updated_at"`
+	Verified  bool      `json:"verified"`
+	AvatarURL string    `json:"avatar_url"`
+}
+
+// handlerUsersGet serves a user's public profile. Banned users are gone.
+func (s *Server) handlerUsersGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathUUID(w, r, "userID")
+	if !ok {
+		return
+	}
+	user, err := s.db.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.BannedAt.Valid) {
+		jsonResponse(w, http.StatusNotFound, "User was not found.")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if notModified(w, r, user.UpdatedAt) {
+		return
+	}
+	jsonFieldsResponse(w, r, http.StatusOK, profileResponse{
+		ID:        user.ID.String(),
+		Handle:    user.Handle.String,
+		CreatedAt: Timestamp{user.CreatedAt},
+		UpdatedAt: Timestamp{user.UpdatedAt},
+		Verified:  user.Verified,
+		AvatarURL: s.avatarURL(s.config.PublicURL, user),
+	})
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type handlesStore struct {
+	fakeStore
+	history []database.RecordHandleChangeParams
+}
+
+type handlesTx struct {
+	*handlesStore
+}
+
+func (s *handlesStore) Begin(ctx context.Context) (Tx, error) {
+	return handlesTx{s}, nil
+}
+
+func (tx handlesTx) Commit() error   { return nil }
+func (tx handlesTx) Rollback() error { return nil }
+
+func (s *handlesStore) GetLastHandleChange(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	var last time.Time
+	for _, h := range s.history {
+		if h.UserID == userID {
+			last = h.ExpiresAt.Add(-handleRedirectPeriod)
+		}
+	}
+	if last.IsZero() {
+		return time.Time{}, sql.ErrNoRows
+	}
+	return last, nil
+}
+
+func (s *handlesStore) reservedBy(handle string) (uuid.UUID, bool) {
+	for _, h := range s.history {
+		if h.Handle == handle {
+			return h.UserID, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+func (s *handlesStore) ChangeUserHandle(ctx context.Context, arg database.ChangeUserHandleParams) (database.User, error) {
+	if _, err := s.GetUserByHandle(ctx, arg.Handle); err == nil {
+		return database.User{}, sql.ErrNoRows
+	}
+	if owner, ok := s.reservedBy(arg.Handle.String); ok && owner != arg.ID {
+		return database.User{}, sql.ErrNoRows
+	}
+	for email, u := range s.users {
+		if u.ID == arg.ID {
+			u.Handle = arg.Handle
+			s.users[email] = u
+			return u, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (s *handlesStore) RecordHandleChange(ctx context.Context, arg database.RecordHandleChangeParams) error {
+	s.history = append(s.history, arg)
+	for _, u := range s.users {
+		if u.ID == arg.UserID {
+			s.redirects[arg.Handle] = u.Handle.String
+		}
+	}
+	return nil
+}
+
+func (s *handlesStore) ReleaseHandle(ctx context.Context, arg database.ReleaseHandleParams) error {
+	delete(s.redirects, arg.Handle)
+	return nil
+}
+
+func TestHandles_Change(t *testing.T) {
+	user := newTestUser(t, "user@example.com", "pa55word")
+	user.Handle = nullString("alice")
+	other := newTestUser(t, "other@example.com", "pa55word")
+	other.Handle = nullString("bob")
+	store := &handlesStore{fakeStore: fakeStore{
+		users:     map[string]database.User{user.Email: user, other.Email: other},
+		redirects: map[string]string{},
+	}}
+	cfg := &config.Config{JWTSecret: "test-secret", StaticDir: t.TempDir()}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)}))
+	send := func(id uuid.UUID, body string) *httptest.ResponseRecorder {
+		token, err := auth.MakeJWT(id, cfg.JWTSecret, time.Hour)
+		if err != nil {
+			t.Fatalf("MakeJWT returned error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPut, "/api/users/me/handle", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send(user.ID, `{"handle":"bob"}`); rec.Code != http.StatusConflict {
+		t.Errorf("taking another user's handle: expected 409, got %d", rec.Code)
+	}
+	if rec := send(user.ID, `{"handle":"Alice_2"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(store.history) != 1 || store.history[0].Handle != "alice" || !store.history[0].ExpiresAt.Equal(testNow.Add(handleRedirectPeriod)) {
+		t.Errorf("expected the old handle recorded, got %+v", store.history)
+	}
+
+	rec := send(user.ID, `{"handle":"alice_3"}`)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("changing again: expected 429 with Retry-After, got %d", rec.Code)
+	}
+	if rec := send(other.ID, `{"handle":"alice"}`); rec.Code != http.StatusConflict {
+		t.Errorf("taking a reserved handle: expected 409, got %d", rec.Code)
+	}
+
+	rec = do(h, http.MethodGet, "/app/profile/@alice", "")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/app/profile/@alice_2" {
+		t.Errorf("old profile: expected a 301 to the new handle, got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := do(h, http.MethodGet, "/app/profile/@alice_2", ""); rec.Code == http.StatusMovedPermanently {
+		t.Error("current profile must not redirect")
+	}
+}
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"chirpy/internal/contentfilter"
+	"chirpy/internal/database"
+	"chirpy/internal/twitterarchive"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxImportSize   = 256 << 20
+	importBatchSize = 100
+	// importReadTimeout replaces the server's read and request timeouts
+	// for uploads, which may be large archives on slow connections.
+	importReadTimeout = 10 * time.Minute
+)
+
+const (
+	importStatusRunning   = "running"
+	importStatusCompleted = "completed"
+	importStatusFailed    = "failed"
+)
+
+type importJobResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Source    string    `json:"source"`
+	Status    string    `json:"status"`
+	Total     int32     `json:"total"`
+	Imported  int32     `json:"imported"`
+	Skipped   int32     `json:"skipped"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt Timestamp `json:"created_at"`
+	UpdatedAt Timestamp `json:"updated_at"`
+}
+
+func newImportJobResponse(j database.ImportJob) importJobResponse {
+	return importJobResponse{
+		ID:        j.ID,
+		Source:    j.Source,
+		Status:    j.Status,
+		Total:     j.Total,
+		Imported:  j.Imported,
+		Skipped:   j.Skipped,
+		Error:     j.Error.String,
+		CreatedAt: Timestamp{j.CreatedAt},
+		UpdatedAt: Timestamp{j.UpdatedAt},
+	}
+}
+
+// readTwitterUpload parses the request body as either a whole archive ZIP
+// or just its tweets.js file.
+func readTwitterUpload(r *http.Request) ([]twitterarchive.Tweet, error) {
+	tmp, err := os.CreateTemp("", "chirpy-import-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, 4)
+	if _, err := tmp.ReadAt(magic, 0); err == nil && bytes.Equal(magic, []byte("PK\x03\x04")) {
+		return twitterarchive.ParseZip(tmp, size)
+	}
+
+	data, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	tweets, err := twitterarchive.ParseTweetsJS(data)
+	if err != nil {
+		return nil, err
+	}
+	twitterarchive.SortOldestFirst(tweets)
+	return tweets, nil
+}
+
+// handlerImportTwitter accepts a Twitter/X archive and imports its tweets
+// as chirps in the background. The response is the job to poll.
+func (s *Server) handlerImportTwitter(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	http.NewResponseController(w).SetReadDeadline(time.Now().Add(importReadTimeout))
+	noRequestTimeout(r)
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	tweets, err := readTwitterUpload(r)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		jsonResponse(w, http.StatusRequestEntityTooLarge, "Archive is too large")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, "Could not read Twitter archive")
+		return
+	}
+
+	job, err := s.db.CreateImportJob(r.Context(), database.CreateImportJobParams{
+		ID:     uuid.New(),
+		UserID: userID,
+		Source: "twitter",
+		Total:  int32(len(tweets)),
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	go s.runTwitterImport(context.Background(), job, tweets)
+
+	w.Header().Set("Location", "/api/import/jobs/"+job.ID.String())
+	jsonResponse(w, http.StatusAccepted, newImportJobResponse(job))
+}
+
+// runTwitterImport turns tweets into chirps with their original
+// timestamps. Retweets, tweets that break chirp rules and tweets imported
+// before are skipped.
+func (s *Server) runTwitterImport(ctx context.Context, job database.ImportJob, tweets []twitterarchive.Tweet) {
+	var imported, skipped int32
+	var importErr error
+
+	for start := 0; start < len(tweets); start += importBatchSize {
+		end := min(start+importBatchSize, len(tweets))
+		n, skip, err := s.importTweetBatch(ctx, job.UserID, tweets[start:end])
+		if err != nil {
+			importErr = err
+			break
+		}
+		imported += n
+		skipped += skip
+
+		err = s.db.UpdateImportJobProgress(ctx, database.UpdateImportJobProgressParams{
+			ID:       job.ID,
+			Imported: imported,
+			Skipped:  skipped,
+		})
+		if err != nil {
+			fmt.Println("Error updating import progress:", err)
+		}
+	}
+
+	status, errMsg := importStatusCompleted, sql.NullString{}
+	if importErr != nil {
+		fmt.Println("Error importing tweets:", importErr)
+		status, errMsg = importStatusFailed, nullString(importErr.Error())
+	}
+	err := s.db.FinishImportJob(ctx, database.FinishImportJobParams{
+		ID:       job.ID,
+		Status:   status,
+		Imported: imported,
+		Skipped:  skipped,
+		Error:    errMsg,
+	})
+	if err != nil {
+		fmt.Println("Error finishing import job:", err)
+	}
+}
+
+// importTweetBatch imports tweets in one transaction, returning how many
+// were imported and skipped.
+func (s *Server) importTweetBatch(ctx context.Context, userID uuid.UUID, tweets []twitterarchive.Tweet) (int32, int32, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.MarkImportTransaction(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	author, _ := s.db.GetUserByID(ctx, userID)
+	maxLen := s.maxChirpLength(author)
+	var imported, skipped int32
+	flags := make(map[uuid.UUID][]contentfilter.Rule)
+	for _, t := range tweets {
+		if t.Retweet {
+			skipped++
+			continue
+		}
+		body, flagged, err := s.prepareChirpBody(t.Text, maxLen)
+		if err != nil {
+			skipped++
+			continue
+		}
+
+		chirpID := uuid.New()
+		n, err := tx.RecordImportedTweet(ctx, database.RecordImportedTweetParams{
+			UserID:  userID,
+			TweetID: t.ID,
+			ChirpID: chirpID,
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+		if n == 0 {
+			skipped++
+			continue
+		}
+
+		err = tx.ImportChirp(ctx, database.ImportChirpParams{
+			ID:        chirpID,
+			CreatedAt: t.CreatedAt,
+			Body:      body,
+			UserID:    userID,
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+		imported++
+		if len(flagged) > 0 {
+			flags[chirpID] = flagged
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	if imported > 0 {
+		s.responseCache.invalidate()
+	}
+	for chirpID, rules := range flags {
+		s.flagChirp(ctx, chirpID, rules)
+	}
+	return imported, skipped, nil
+}
+
+func (s *Server) handlerImportJobGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	jobID, ok := pathUUID(w, r, "jobID")
+	if !ok {
+		return
+	}
+
+	job, err := s.db.GetImportJob(r.Context(), jobID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && job.UserID != userID) {
+		jsonResponse(w, http.StatusNotFound, "Import job not found")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	jsonResponse(w, http.StatusOK, newImportJobResponse(job))
+}
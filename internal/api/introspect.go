@@ -0,0 +1,137 @@
+package api
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"chirpy/internal/auth"
+
+	"github.com/google/uuid"
+)
+
+// introspectionResponse is an RFC 7662 introspection response. Inactive
+// tokens get only Active, so nothing is said about them.
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	TokenType string `json:"token_type,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	// Actor is the admin behind an impersonation token.
+	Actor       *introspectionActor `json:"act,omitempty"`
+	Email       string              `json:"email,omitempty"`
+	Role        string              `json:"role,omitempty"`
+	IsChirpyRed bool                `json:"is_chirpy_red,omitempty"`
+	// ClientID and Scope are set for tokens issued to third-party apps.
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+type introspectionActor struct {
+	Subject string `json:"sub"`
+}
+
+// introspectionAuthorized reports whether r carries the introspection key,
+// either as a bearer token or, as OAuth clients send their credentials, as
+// the password of HTTP Basic auth.
+func (s *Server) introspectionAuthorized(r *http.Request) bool {
+	key, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		_, key, _ = r.BasicAuth()
+	}
+	return key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(s.config.IntrospectionKey)) == 1
+}
+
+// handlerIntrospect tells services holding the introspection key whether
+// an access token is active, and what it says, so they can check tokens
+// without JWT_SECRET. The token is sent as the form field "token", per
+// RFC 7662, or in a JSON body.
+//
+// Access tokens can't be revoked one by one, so a token is also inactive
+// once its user is deleted, banned or suspended.
+func (s *Server) handlerIntrospect(w http.ResponseWriter, r *http.Request) {
+	if !s.introspectionAuthorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+		jsonResponse(w, http.StatusUnauthorized, "Invalid introspection key")
+		return
+	}
+
+	var token string
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "application/json" {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := s.decodeJSON(r, &req); err != nil {
+			jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+			return
+		}
+		token = req.Token
+	} else {
+		if err := r.ParseForm(); err != nil {
+			jsonResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		token = r.PostForm.Get("token")
+	}
+	if token == "" {
+		jsonResponse(w, http.StatusBadRequest, "A token is required")
+		return
+	}
+
+	// The answer is about this moment, so it mustn't be reused.
+	w.Header().Set("Cache-Control", "no-store")
+	inactive := introspectionResponse{Active: false}
+
+	claims, err := s.tokenIssuer(r.Context()).Validate(token)
+	if err != nil {
+		jsonResponse(w, http.StatusOK, inactive)
+		return
+	}
+	user, err := s.db.GetUserByID(r.Context(), claims.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusOK, inactive)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error introspecting token:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if user.BannedAt.Valid || (user.SuspendedUntil.Valid && user.SuspendedUntil.Time.After(s.clock.Now().UTC())) {
+		jsonResponse(w, http.StatusOK, inactive)
+		return
+	}
+
+	resp := introspectionResponse{
+		Active:    true,
+		TokenType: "Bearer",
+		Issuer:    "chirpy",
+		Subject:   claims.UserID.String(),
+	}
+	if !claims.IssuedAt.IsZero() {
+		resp.IssuedAt = claims.IssuedAt.Unix()
+	}
+	if !claims.ExpiresAt.IsZero() {
+		resp.ExpiresAt = claims.ExpiresAt.Unix()
+	}
+	if claims.ActorID != uuid.Nil {
+		resp.Actor = &introspectionActor{Subject: claims.ActorID.String()}
+	}
+	// Tokens carrying a profile get it as the user is now, not as it was
+	// when the token was issued, so a demotion or a lapsed subscription
+	// shows at once.
+	if claims.Profile != nil {
+		p := tokenProfile(user)
+		resp.Email, resp.Role, resp.IsChirpyRed = p.Email, p.Role, p.IsChirpyRed
+	}
+	if claims.ClientID != "" {
+		resp.ClientID, resp.Scope = claims.ClientID, strings.Join(claims.Scopes, " ")
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+func TestIntrospect(t *testing.T) {
+	user := newTestUser(t, "saul@example.com", "04234")
+	user.IsChirpyRed = true
+	banned := newTestUser(t, "banned@example.com", "04234")
+	banned.BannedAt = sql.NullTime{Time: testNow, Valid: true}
+	store := &fakeStore{users: map[string]database.User{user.Email: user, banned.Email: banned}}
+	cfg := &config.Config{JWTSecret: "test-secret", IntrospectionKey: "sidecar-key"}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)}))
+
+	introspect := func(setAuth func(*http.Request), token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		t.Helper()
+		form := url.Values{"token": {token}}
+		req := httptest.NewRequest(http.MethodPost, "/api/introspect", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		setAuth(req)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var resp map[string]interface{}
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+		}
+		return rec, resp
+	}
+	bearer := func(key string) func(*http.Request) {
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+key) }
+	}
+	basic := func(r *http.Request) { r.SetBasicAuth("sidecar", "sidecar-key") }
+	token := func(u database.User, opts ...auth.TokenOption) string {
+		tok, err := auth.MakeJWT(u.ID, cfg.JWTSecret, time.Hour, opts...)
+		if err != nil {
+			t.Fatalf("MakeJWT returned error: %v", err)
+		}
+		return tok
+	}
+	good := token(user, auth.WithProfile(auth.Profile{Email: user.Email, IsChirpyRed: true}))
+
+	if rec, _ := introspect(bearer("wrong"), good); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong key, got %d", rec.Code)
+	}
+	if rec, _ := introspect(bearer(good), good); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a user token in place of the key, got %d", rec.Code)
+	}
+
+	for name, setAuth := range map[string]func(*http.Request){"bearer": bearer("sidecar-key"), "basic": basic} {
+		rec, resp := introspect(setAuth, good)
+		if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-store" {
+			t.Fatalf("%s: expected an uncacheable 200, got %d (%q)", name, rec.Code, rec.Header().Get("Cache-Control"))
+		}
+		if resp["active"] != true || resp["sub"] != user.ID.String() || resp["email"] != user.Email || resp["is_chirpy_red"] != true || resp["exp"] == nil {
+			t.Errorf("%s: unexpected response %v", name, resp)
+		}
+	}
+
+	inactive := map[string]string{
+		"garbage":      "not-a-token",
+		"wrong secret": mustMakeJWT(t, user.ID, "other-secret", time.Hour),
+		"expired":      mustMakeJWT(t, user.ID, cfg.JWTSecret, -time.Hour),
+		"banned user":  token(banned),
+		"unknown user": token(database.User{ID: uuid.New()}),
+	}
+	for name, tok := range inactive {
+		rec, resp := introspect(basic, tok)
+		if rec.Code != http.StatusOK || len(resp) != 1 || resp["active"] != false {
+			t.Errorf("%s: expected only active=false, got %d %v", name, rec.Code, resp)
+		}
+	}
+}
+
+func TestIntrospect_ReportsCurrentProfile(t *testing.T) {
+	user := newTestUser(t, "saul@example.com", "04234")
+	store := &fakeStore{users: map[string]database.User{user.Email: user}}
+	cfg := &config.Config{JWTSecret: "test-secret", IntrospectionKey: "sidecar-key"}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)}))
+
+	// The token was issued while the user was an admin with Chirpy Red.
+	token, err := auth.MakeJWT(user.ID, cfg.JWTSecret, time.Hour, auth.WithProfile(auth.Profile{Email: user.Email, Role: RoleAdmin, IsChirpyRed: true}))
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/introspect", strings.NewReader(url.Values{"token": {token}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("sidecar", "sidecar-key")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp["active"] != true || resp["email"] != user.Email || resp["role"] != nil || resp["is_chirpy_red"] != nil {
+		t.Errorf("expected the demoted user's current profile, got %v", resp)
+	}
+}
+
+func TestIntrospect_DisabledWithoutKey(t *testing.T) {
+	rec := do(newTestServer(t, &fakeStore{}), http.MethodPost, "/api/introspect", "")
+	if rec.Code == http.StatusOK || rec.Code == http.StatusUnauthorized {
+		t.Fatalf("expected no introspection route, got %d", rec.Code)
+	}
+}
+
+func mustMakeJWT(t *testing.T, userID uuid.UUID, secret string, ttl time.Duration) string {
+	t.Helper()
+	tok, err := auth.MakeJWT(userID, secret, ttl)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	return tok
+}
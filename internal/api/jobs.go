@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"chirpy/internal/scheduler"
+)
+
+// scheduleJobs registers the periodic jobs with their configured
+// schedules. An empty schedule, as in tests that build the config by
+// hand, means the job is off. A failing job gets a dead letter, which
+// closes when it next succeeds.
+func (s *Server) scheduleJobs() {
+	sched := s.config.Schedules
+	jobs := []scheduler.Job{
+		// Sitemaps are served from memory, so every server builds its own,
+		// before the first crawler asks.
+		{Name: "sitemaps", Spec: sched.Sitemaps, RunAtStart: true, PerInstance: true, Run: s.refreshSitemaps},
+		{Name: "digests", Spec: sched.Digests, Run: s.sendDueDigestsEachTenant},
+		{Name: "suggestions", Spec: sched.Suggestions, RunAtStart: true, Run: s.refreshSuggestions},
+		{Name: "retention", Spec: sched.Retention, Run: s.applyRetention},
+		{Name: "hashtag_counts", Spec: sched.HashtagCounts, Run: s.reconcileHashtagCounts},
+	}
+	for _, job := range jobs {
+		// Sitemaps and digests link back to the site, so they need the
+		// public URL.
+		if (job.Name == "sitemaps" || job.Name == "digests") && s.config.PublicURL == "" {
+			continue
+		}
+		if job.Spec == "" {
+			job.Spec = scheduler.Off
+		}
+		job.Jitter = sched.Jitter
+		run := job.Run
+		job.Run = func(ctx context.Context) error {
+			err := run(ctx)
+			s.recordJobOutcome(ctx, job.Name, err)
+			return err
+		}
+		if err := s.jobs.Add(job); err != nil {
+			fmt.Println("Error scheduling job:", err)
+		}
+	}
+}
+
+func (s *Server) reconcileHashtagCounts(ctx context.Context) error {
+	_, err := s.db.ReconcileHashtagCounts(ctx)
+	return err
+}
+
+type jobResponse struct {
+	Name           string     `json:"name"`
+	Schedule       string     `json:"schedule"`
+	Running        bool       `json:"running"`
+	Runs           int64      `json:"runs"`
+	Failures       int64      `json:"failures"`
+	Skips          int64      `json:"skips"`
+	LastStartedAt  *Timestamp `json:"last_started_at"`
+	LastDurationMS int64      `json:"last_duration_ms"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextRunAt      *Timestamp `json:"next_run_at"`
+}
+
+// handlerAdminJobs lists the periodic jobs with how their last run went
+// and when they run next.
+func (s *Server) handlerAdminJobs(w http.ResponseWriter, r *http.Request) {
+	statuses := s.jobs.Status()
+	resp := make([]jobResponse, 0, len(statuses))
+	for _, st := range statuses {
+		job := jobResponse{
+			Name:           st.Name,
+			Schedule:       st.Spec,
+			Running:        st.Running,
+			Runs:           st.Runs,
+			Failures:       st.Failures,
+			Skips:          st.Skips,
+			LastDurationMS: st.LastDuration.Milliseconds(),
+			LastError:      st.LastError,
+		}
+		if !st.LastStarted.IsZero() {
+			job.LastStartedAt = &Timestamp{st.LastStarted}
+		}
+		if !st.NextRun.IsZero() {
+			job.NextRunAt = &Timestamp{st.NextRun}
+		}
+		resp = append(resp, job)
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
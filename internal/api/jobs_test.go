@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+)
+
+func TestAdminJobs(t *testing.T) {
+	admin := newTestUser(t, "admin@example.com", "pa55word")
+	admin.Role = RoleAdmin
+	store := &contractStore{fakeStore{users: map[string]database.User{admin.Email: admin}}}
+	cfg := &config.Config{
+		JWTSecret: "test-secret",
+		Schedules: config.Schedules{
+			Suggestions:   "@every 6h",
+			Retention:     "0 3 * * *",
+			HashtagCounts: "@daily",
+		},
+	}
+	s := NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)})
+	s.scheduleJobs()
+	h := NewRouter(s)
+
+	token, err := auth.MakeJWT(admin.ID, cfg.JWTSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/admin/jobs", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var jobs []jobResponse
+	if err := json.NewDecoder(rec.Body).Decode(&jobs); err != nil {
+		t.Fatalf("decoding jobs: %v", err)
+	}
+	// Without a public URL there are no sitemaps or digests to schedule.
+	want := []struct{ name, schedule string }{
+		{"suggestions", "@every 6h"},
+		{"retention", "0 3 * * *"},
+		{"hashtag_counts", "@daily"},
+	}
+	if len(jobs) != len(want) {
+		t.Fatalf("expected %d jobs, got %+v", len(want), jobs)
+	}
+	for i, w := range want {
+		if jobs[i].Name != w.name || jobs[i].Schedule != w.schedule || jobs[i].Runs != 0 || jobs[i].LastStartedAt != nil {
+			t.Errorf("job %d: expected %s on %q, not yet run, got %+v", i, w.name, w.schedule, jobs[i])
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+
+	"github.com/google/uuid"
+)
+
+// Legal documents are the terms of service and the privacy policy. Once
+// either has been published, users must accept the version in force: at
+// signup, and again whenever a new version is published. Until they do,
+// their authenticated requests are answered with a 451.
+
+const (
+	legalTerms   = "terms"
+	legalPrivacy = "privacy"
+)
+
+var legalKinds = []string{legalTerms, legalPrivacy}
+
+// legalVersions maps each kind of legal document to a version.
+type legalVersions map[string]int32
+
+// legalState is the versions in force, and the users known to have
+// accepted them. It is replaced whenever a new version is published, which
+// forgets every acceptance along with the old versions.
+type legalState struct {
+	versions legalVersions
+	accepted sync.Map // uuid.UUID → struct{}
+}
+
+// legalExempt are the routes a user who hasn't accepted the versions in
+// force can still use: enough to read the documents and accept them.
+var legalExempt = []string{
+	"GET /api/legal",
+	"GET /api/legal/{kind}",
+	"POST /api/login",
+	"POST /api/users",
+	"POST /api/users/accept-terms",
+}
+
+type legalRequiredResponse struct {
+	Error    string        `json:"error"`
+	Required legalVersions `json:"required"`
+}
+
+// reloadLegalDocuments refreshes the versions in force from the database.
+func (s *Server) reloadLegalDocuments(ctx context.Context) error {
+	docs, err := s.db.ListCurrentLegalDocuments(ctx)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		s.legal.Store(nil)
+		return nil
+	}
+	versions := make(legalVersions, len(docs))
+	for _, doc := range docs {
+		versions[doc.Kind] = doc.Version
+	}
+	if old := s.legal.Load(); old != nil && maps.Equal(old.versions, versions) {
+		return nil
+	}
+	s.legal.Store(&legalState{versions: versions})
+	return nil
+}
+
+// watchLegalDocuments reloads the versions in force whenever any instance
+// publishes one, using the events hub fed by Postgres NOTIFY.
+func (s *Server) watchLegalDocuments(ctx context.Context, hub *events.Hub) {
+	ch, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if e.Type != "legal_documents.changed" {
+				continue
+			}
+			if err := s.reloadLegalDocuments(ctx); err != nil {
+				fmt.Println("Error reloading legal documents:", err)
+			}
+		}
+	}
+}
+
+// outstandingLegal returns the versions in force that userID hasn't
+// accepted.
+func (s *Server) outstandingLegal(ctx context.Context, state *legalState, userID uuid.UUID) (legalVersions, error) {
+	if _, ok := state.accepted.Load(userID); ok {
+		return nil, nil
+	}
+	rows, err := s.db.ListUserLegalAcceptances(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	accepted := make(legalVersions, len(rows))
+	for _, row := range rows {
+		accepted[row.Kind] = row.Version
+	}
+	var outstanding legalVersions
+	for kind, version := range state.versions {
+		if accepted[kind] < version {
+			if outstanding == nil {
+				outstanding = legalVersions{}
+			}
+			outstanding[kind] = version
+		}
+	}
+	if outstanding == nil {
+		state.accepted.Store(userID, struct{}{})
+	}
+	return outstanding, nil
+}
+
+// middlewareRequireLegal turns away the authenticated requests of users
+// who haven't accepted the legal documents in force. Admins acting through
+// an impersonation token can't accept on a user's behalf, so they are let
+// through.
+func (s *Server) middlewareRequireLegal(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := s.legal.Load()
+		if state == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, pattern := mux.Handler(r); pattern == "" || slices.Contains(legalExempt, pattern) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, actorID, err := s.authenticateWithActor(r)
+		if err != nil || actorID != uuid.Nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		outstanding, err := s.outstandingLegal(r.Context(), state, userID)
+		if err != nil {
+			fmt.Println("Error checking legal acceptances:", err)
+			jsonResponse(w, http.StatusInternalServerError, errorResponse{Error: "Something went wrong"})
+			return
+		}
+		if outstanding != nil {
+			jsonResponse(w, http.StatusUnavailableForLegalReasons, legalRequiredResponse{
+				Error:    "Accept the current terms of service and privacy policy at POST /api/users/accept-terms",
+				Required: outstanding,
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// legalAcceptance is what a client sends to accept the legal documents:
+// the versions it showed the user.
+type legalAcceptance struct {
+	TermsVersion   int32 `json:"terms_version,omitempty"`
+	PrivacyVersion int32 `json:"privacy_version,omitempty"`
+}
+
+func (a legalAcceptance) versions() legalVersions {
+	versions := legalVersions{}
+	if a.TermsVersion != 0 {
+		versions[legalTerms] = a.TermsVersion
+	}
+	if a.PrivacyVersion != 0 {
+		versions[legalPrivacy] = a.PrivacyVersion
+	}
+	return versions
+}
+
+// checkLegalAcceptance reports whether accepting offered, on top of what
+// the user already accepted, leaves nothing outstanding. Accepting a
+// version that is no longer in force never does.
+func checkLegalAcceptance(state *legalState, outstanding, offered legalVersions) bool {
+	for kind, version := range offered {
+		if state.versions[kind] != version {
+			return false
+		}
+	}
+	for kind := range outstanding {
+		if _, ok := offered[kind]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// recordLegalAcceptance stores that userID accepted versions from r's IP.
+func (s *Server) recordLegalAcceptance(ctx context.Context, r *http.Request, userID uuid.UUID, versions legalVersions) error {
+	var ip sql.NullString
+	if addr := s.clientIP(r); addr.IsValid() {
+		ip = sql.NullString{String: addr.String(), Valid: true}
+	}
+	for kind, version := range versions {
+		err := s.db.AcceptLegalDocument(ctx, database.AcceptLegalDocumentParams{
+			UserID:  userID,
+			Kind:    kind,
+			Version: version,
+			Ip:      ip,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handlerLegalAccept records that the authenticated user accepted the
+// versions in force. Offering any other version is a 409, with the
+// versions to show the user instead.
+func (s *Server) handlerLegalAccept(w http.ResponseWriter, r *http.Request) {
+	userID, actorID, err := s.authenticateWithActor(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if actorID != uuid.Nil {
+		jsonResponse(w, http.StatusForbidden, "Only the user can accept the legal documents")
+		return
+	}
+	var req legalAcceptance
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+
+	state := s.legal.Load()
+	if state == nil {
+		jsonResponse(w, http.StatusNotFound, "No legal documents have been published")
+		return
+	}
+	ctx := r.Context()
+	outstanding, err := s.outstandingLegal(ctx, state, userID)
+	if err != nil {
+		fmt.Println("Error checking legal acceptances:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	offered := req.versions()
+	if !checkLegalAcceptance(state, outstanding, offered) {
+		jsonResponse(w, http.StatusConflict, legalRequiredResponse{
+			Error:    "These are not the versions in force",
+			Required: state.versions,
+		})
+		return
+	}
+	if err := s.recordLegalAcceptance(ctx, r, userID, offered); err != nil {
+		fmt.Println("Error recording legal acceptance:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	state.accepted.Store(userID, struct{}{})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type legalDocumentResponse struct {
+	Kind        string    `json:"kind"`
+	Version     int32     `json:"version"`
+	Body        string    `json:"body"`
+	PublishedAt Timestamp `json:"published_at"`
+}
+
+func newLegalDocumentResponse(doc database.LegalDocument) legalDocumentResponse {
+	return legalDocumentResponse{
+		Kind:        doc.Kind,
+		Version:     doc.Version,
+		Body:        doc.Body,
+		PublishedAt: Timestamp{doc.PublishedAt},
+	}
+}
+
+// handlerLegalList shows the legal documents in force.
+func (s *Server) handlerLegalList(w http.ResponseWriter, r *http.Request) {
+	docs, err := s.db.ListCurrentLegalDocuments(r.Context())
+	if err != nil {
+		fmt.Println("Error listing legal documents:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	response := make([]legalDocumentResponse, 0, len(docs))
+	for _, doc := range docs {
+		response = append(response, newLegalDocumentResponse(doc))
+	}
+	jsonResponse(w, http.StatusOK, response)
+}
+
+// handlerLegalGet shows the version of a legal document in force, or the
+// one asked for with ?version=.
+func (s *Server) handlerLegalGet(w http.ResponseWriter, r *http.Request) {
+	kind := r.PathValue("kind")
+	if !slices.Contains(legalKinds, kind) {
+		jsonResponse(w, http.StatusNotFound, "Unknown legal document")
+		return
+	}
+	ctx := r.Context()
+	var version int32
+	if v := r.URL.Query().Get("version"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 1 {
+			jsonResponse(w, http.StatusBadRequest, "version must be a positive integer")
+			return
+		}
+		version = int32(n)
+	} else if state := s.legal.Load(); state != nil {
+		version = state.versions[kind]
+	}
+	if version == 0 {
+		jsonResponse(w, http.StatusNotFound, "Legal document not found")
+		return
+	}
+
+	doc, err := s.db.GetLegalDocument(ctx, database.GetLegalDocumentParams{Kind: kind, Version: version})
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "Legal document not found")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, newLegalDocumentResponse(doc))
+}
+
+type legalPublishRequest struct {
+	Body string `json:"body"`
+}
+
+// handlerAdminLegalPublish publishes a new version of a legal document.
+// Every user must accept it before their next authenticated request.
+func (s *Server) handlerAdminLegalPublish(w http.ResponseWriter, r *http.Request) {
+	kind := r.PathValue("kind")
+	if !slices.Contains(legalKinds, kind) {
+		jsonResponse(w, http.StatusNotFound, "Unknown legal document: want "+strings.Join(legalKinds, " or "))
+		return
+	}
+	var req legalPublishRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		jsonResponse(w, http.StatusBadRequest, "body is required")
+		return
+	}
+
+	ctx := r.Context()
+	admin := adminFromContext(ctx)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	doc, err := tx.PublishLegalDocument(ctx, database.PublishLegalDocumentParams{
+		Kind:        kind,
+		Body:        req.Body,
+		PublishedBy: uuid.NullUUID{UUID: admin.ID, Valid: true},
+	})
+	if err != nil {
+		fmt.Println("Error publishing legal document:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	err = recordAudit(ctx, tx, admin.ID, "legal.publish", uuid.Nil, map[string]interface{}{
+		"kind":    doc.Kind,
+		"version": doc.Version,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	if err := s.reloadLegalDocuments(ctx); err != nil {
+		fmt.Println("Error reloading legal documents:", err)
+	}
+	jsonResponse(w, http.StatusCreated, newLegalDocumentResponse(doc))
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type legalStore struct {
+	fakeStore
+	docs     []database.LegalDocument
+	accepted []database.AcceptLegalDocumentParams
+	audits   []string
+}
+
+type legalTx struct {
+	*legalStore
+}
+
+func (s *legalStore) Begin(ctx context.Context) (Tx, error) {
+	return legalTx{s}, nil
+}
+
+func (tx legalTx) Commit() error   { return nil }
+func (tx legalTx) Rollback() error { return nil }
+
+func (s *legalStore) PublishLegalDocument(ctx context.Context, arg database.PublishLegalDocumentParams) (database.LegalDocument, error) {
+	doc := database.LegalDocument{Kind: arg.Kind, Version: 1, Body: arg.Body, PublishedAt: testNow, PublishedBy: arg.PublishedBy}
+	for _, d := range s.docs {
+		if d.Kind == arg.Kind && d.Version >= doc.Version {
+			doc.Version = d.Version + 1
+		}
+	}
+	s.docs = append(s.docs, doc)
+	return doc, nil
+}
+
+func (s *legalStore) ListCurrentLegalDocuments(ctx context.Context) ([]database.LegalDocument, error) {
+	current := map[string]database.LegalDocument{}
+	for _, d := range s.docs {
+		if d.Version > current[d.Kind].Version {
+			current[d.Kind] = d
+		}
+	}
+	var docs []database.LegalDocument
+	for _, d := range current {
+		docs = append(docs, d)
+	}
+	return docs, nil
+}
+
+func (s *legalStore) AcceptLegalDocument(ctx context.Context, arg database.AcceptLegalDocumentParams) error {
+	s.accepted = append(s.accepted, arg)
+	return nil
+}
+
+func (s *legalStore) ListUserLegalAcceptances(ctx context.Context, userID uuid.UUID) ([]database.ListUserLegalAcceptancesRow, error) {
+	latest := map[string]int32{}
+	for _, a := range s.accepted {
+		if a.UserID == userID && a.Version > latest[a.Kind] {
+			latest[a.Kind] = a.Version
+		}
+	}
+	var rows []database.ListUserLegalAcceptancesRow
+	for kind, version := range latest {
+		rows = append(rows, database.ListUserLegalAcceptancesRow{Kind: kind, Version: version})
+	}
+	return rows, nil
+}
+
+func (s *legalStore) CreateAuditLogEntry(ctx context.Context, arg database.CreateAuditLogEntryParams) error {
+	s.audits = append(s.audits, arg.Action)
+	return nil
+}
+
+func TestLegal_RequiresAcceptance(t *testing.T) {
+	admin := newTestUser(t, "admin@example.com", "pa55word")
+	admin.Role = RoleAdmin
+	user := newTestUser(t, "user@example.com", "pa55word")
+	store := &legalStore{
+		fakeStore: fakeStore{users: map[string]database.User{admin.Email: admin, user.Email: user}},
+		docs: []database.LegalDocument{
+			{Kind: legalTerms, Version: 1, Body: "Be nice."},
+			{Kind: legalPrivacy, Version: 1, Body: "We keep your chirps."},
+		},
+	}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	s := NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)})
+	if err := s.reloadLegalDocuments(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	h := NewRouter(s)
+
+	send := func(userID uuid.UUID, method, path, body string) *httptest.ResponseRecorder {
+		token, err := auth.MakeJWT(userID, cfg.JWTSecret, time.Hour)
+		if err != nil {
+			t.Fatalf("MakeJWT returned error: %v", err)
+		}
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(user.ID, http.MethodGet, "/api/chirps", "")
+	if rec.Code != http.StatusUnavailableForLegalReasons {
+		t.Fatalf("expected a 451 before accepting, got %d", rec.Code)
+	}
+	var required legalRequiredResponse
+	if err := json.NewDecoder(rec.Body).Decode(&required); err != nil {
+		t.Fatalf("decoding 451: %v", err)
+	}
+	if required.Required[legalTerms] != 1 || required.Required[legalPrivacy] != 1 {
+		t.Errorf("expected both documents required, got %v", required.Required)
+	}
+	if rec := do(h, http.MethodGet, "/api/chirps", ""); rec.Code != http.StatusOK {
+		t.Errorf("anonymous requests: expected 200, got %d", rec.Code)
+	}
+
+	if rec := send(user.ID, http.MethodPost, "/api/users/accept-terms", `{"terms_version":1}`); rec.Code != http.StatusConflict {
+		t.Errorf("accepting only the terms: expected 409, got %d", rec.Code)
+	}
+	if rec := send(user.ID, http.MethodPost, "/api/users/accept-terms", `{"terms_version":1,"privacy_version":1}`); rec.Code != http.StatusNoContent {
+		t.Fatalf("accepting: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(store.accepted) != 2 || !store.accepted[0].Ip.Valid {
+		t.Errorf("expected two acceptances with the IP, got %+v", store.accepted)
+	}
+	if rec := send(user.ID, http.MethodGet, "/api/chirps", ""); rec.Code != http.StatusOK {
+		t.Errorf("after accepting: expected 200, got %d", rec.Code)
+	}
+
+	// The admin has to accept too before publishing.
+	send(admin.ID, http.MethodPost, "/api/users/accept-terms", `{"terms_version":1,"privacy_version":1}`)
+	rec = send(admin.ID, http.MethodPost, "/admin/legal/terms", `{"body":"Be nicer."}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("publishing: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(store.audits) != 1 || store.audits[0] != "legal.publish" {
+		t.Errorf("expected the publish to be audited, got %v", store.audits)
+	}
+	rec = send(user.ID, http.MethodGet, "/api/chirps", "")
+	if rec.Code != http.StatusUnavailableForLegalReasons {
+		t.Fatalf("after a new version: expected 451, got %d", rec.Code)
+	}
+	required = legalRequiredResponse{}
+	json.NewDecoder(rec.Body).Decode(&required)
+	if len(required.Required) != 1 || required.Required[legalTerms] != 2 {
+		t.Errorf("expected only the new terms required, got %v", required.Required)
+	}
+	if rec := send(user.ID, http.MethodPost, "/api/users/accept-terms", `{"terms_version":1}`); rec.Code != http.StatusConflict {
+		t.Errorf("accepting the old terms: expected 409, got %d", rec.Code)
+	}
+	if rec := send(user.ID, http.MethodPost, "/api/users/accept-terms", `{"terms_version":2}`); rec.Code != http.StatusNoContent {
+		t.Errorf("accepting the new terms: expected 204, got %d", rec.Code)
+	}
+	if rec := send(user.ID, http.MethodGet, "/api/chirps", ""); rec.Code != http.StatusOK {
+		t.Errorf("after accepting again: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestLegal_SignupNeedsCurrentVersions(t *testing.T) {
+	store := &legalStore{docs: []database.LegalDocument{{Kind: legalTerms, Version: 3}}}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	s := NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)})
+	if err := s.reloadLegalDocuments(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	h := NewRouter(s)
+
+	for _, body := range []string{
+		`{"email":"new@example.com","password":"pa55word"}`,
+		`{"email":"new@example.com","password":"pa55word","terms_version":2}`,
+	} {
+		if rec := do(h, http.MethodPost, "/api/users", body); rec.Code != http.StatusConflict {
+			t.Errorf("%s: expected 409, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestCheckLegalAcceptance(t *testing.T) {
+	state := &legalState{versions: legalVersions{legalTerms: 2, legalPrivacy: 1}}
+	tests := []struct {
+		name        string
+		outstanding legalVersions
+		offered     legalVersions
+		want        bool
+	}{
+		{"everything", legalVersions{legalTerms: 2, legalPrivacy: 1}, legalVersions{legalTerms: 2, legalPrivacy: 1}, true},
+		{"only what is outstanding", legalVersions{legalTerms: 2}, legalVersions{legalTerms: 2}, true},
+		{"missing one", legalVersions{legalTerms: 2, legalPrivacy: 1}, legalVersions{legalTerms: 2}, false},
+		{"stale version", legalVersions{legalTerms: 2}, legalVersions{legalTerms: 1}, false},
+		{"unknown kind", nil, legalVersions{"cookies": 1}, false},
+	}
+	for _, tt := range tests {
+		if got := checkLegalAcceptance(state, tt.outstanding, tt.offered); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// linkScanTTL is how long a link's verdict is reused before the link
+	// is scanned again.
+	linkScanTTL     = 24 * time.Hour
+	linkScanTimeout = 30 * time.Second
+)
+
+// eventLinkScan checks the links in a chirp.
+const eventLinkScan = "links.scan"
+
+type linkScanEvent struct {
+	ChirpID uuid.UUID `json:"chirp_id"`
+	URLs    []string  `json:"urls"`
+}
+
+// recordChirpLinks records the links in a chirp through q and, when a
+// scanner is configured, an outbox event to check them. Pass the
+// transaction that writes the chirp.
+func (s *Server) recordChirpLinks(ctx context.Context, q database.Querier, chirpID uuid.UUID, urls []string) error {
+	for _, u := range urls {
+		if err := q.AddChirpLink(ctx, database.AddChirpLinkParams{Url: u, ChirpID: chirpID}); err != nil {
+			return err
+		}
+	}
+	if s.linkScanner == nil || len(urls) == 0 {
+		return nil
+	}
+	return recordEvent(ctx, q, eventLinkScan, linkScanEvent{ChirpID: chirpID, URLs: urls})
+}
+
+// scanChirpLinks checks a chirp's links, reusing verdicts younger than
+// linkScanTTL, and flags the chirp for moderators for each unsafe one.
+// A failed lookup fails the event, so the outbox relay retries it.
+func (s *Server) scanChirpLinks(ctx context.Context, tx Tx, ev linkScanEvent) error {
+	if s.linkScanner == nil {
+		// Scanning was turned off after the event was recorded.
+		return nil
+	}
+	scans, err := tx.GetLinkScans(ctx, ev.URLs)
+	if err != nil {
+		return err
+	}
+	threats := map[string]string{}
+	fresh := map[string]bool{}
+	now := s.clock.Now().UTC()
+	for _, scan := range scans {
+		if scan.CheckedAt.Valid && now.Sub(scan.CheckedAt.Time) < linkScanTTL {
+			fresh[scan.Url] = true
+			threats[scan.Url] = scan.Threat
+		}
+	}
+	var stale []string
+	for _, u := range ev.URLs {
+		if !fresh[u] {
+			stale = append(stale, u)
+		}
+	}
+
+	if len(stale) > 0 {
+		scanCtx, cancel := context.WithTimeout(ctx, linkScanTimeout)
+		found, err := s.linkScanner.Scan(scanCtx, stale)
+		cancel()
+		if err != nil {
+			return err
+		}
+		for _, u := range stale {
+			threats[u] = found[u]
+			if err := tx.RecordLinkScan(ctx, database.RecordLinkScanParams{Url: u, Threat: found[u]}); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, u := range ev.URLs {
+		if threats[u] == "" {
+			continue
+		}
+		err := tx.CreateContentFlag(ctx, database.CreateContentFlagParams{
+			ID:      uuid.New(),
+			ChirpID: ev.ChirpID,
+			Pattern: "link " + threats[u] + ": " + u,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handlerOutboundLink sends the visitor on to a link from a chirp, or
+// warns them first if the link was found unsafe. Only links that appear
+// in chirps are followed, so it isn't an open redirect.
+func (s *Server) handlerOutboundLink(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("url")
+	threat, err := s.db.GetLinkThreat(r.Context(), target)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error looking up link:", err)
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	// Verdicts change, so neither answer may be cached.
+	w.Header().Set("Cache-Control", "no-store")
+	if threat == "" {
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+	s.renderPage(w, "link_warning.html", map[string]string{
+		"URL":    target,
+		"Threat": threat,
+	})
+}
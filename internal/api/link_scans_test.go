@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+	"chirpy/internal/linkscan"
+
+	"github.com/google/uuid"
+)
+
+type linkScanStore struct {
+	fakeStore
+	scans  map[string]database.LinkScan
+	events []database.OutboxEvent
+	flags  []string
+}
+
+type linkScanTx struct {
+	*linkScanStore
+}
+
+func (s *linkScanStore) Begin(ctx context.Context) (Tx, error) {
+	return linkScanTx{s}, nil
+}
+
+func (tx linkScanTx) Commit() error   { return nil }
+func (tx linkScanTx) Rollback() error { return nil }
+
+func (s *linkScanStore) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	return database.Chirp{ID: arg.ID, CreatedAt: testNow, UpdatedAt: testNow, Body: arg.Body, UserID: arg.UserID}, nil
+}
+
+func (s *linkScanStore) AddChirpLink(ctx context.Context, arg database.AddChirpLinkParams) error {
+	if _, ok := s.scans[arg.Url]; !ok {
+		s.scans[arg.Url] = database.LinkScan{Url: arg.Url, CreatedAt: testNow}
+	}
+	return nil
+}
+
+func (s *linkScanStore) GetLinkScans(ctx context.Context, urls []string) ([]database.LinkScan, error) {
+	var scans []database.LinkScan
+	for _, u := range urls {
+		if scan, ok := s.scans[u]; ok {
+			scans = append(scans, scan)
+		}
+	}
+	return scans, nil
+}
+
+func (s *linkScanStore) RecordLinkScan(ctx context.Context, arg database.RecordLinkScanParams) error {
+	scan := s.scans[arg.Url]
+	scan.CheckedAt = sql.NullTime{Time: testNow, Valid: true}
+	scan.Threat = arg.Threat
+	s.scans[arg.Url] = scan
+	return nil
+}
+
+func (s *linkScanStore) GetLinkThreat(ctx context.Context, url string) (string, error) {
+	scan, ok := s.scans[url]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return scan.Threat, nil
+}
+
+func (s *linkScanStore) CreateContentFlag(ctx context.Context, arg database.CreateContentFlagParams) error {
+	s.flags = append(s.flags, arg.Pattern)
+	return nil
+}
+
+func (s *linkScanStore) EnqueueOutboxEvent(ctx context.Context, arg database.EnqueueOutboxEventParams) error {
+	s.events = append(s.events, database.OutboxEvent{ID: arg.ID, Kind: arg.Kind, Payload: arg.Payload, Status: outboxEventStatusPending})
+	return nil
+}
+
+func (s *linkScanStore) ClaimDueOutboxEvents(ctx context.Context, limit int32) ([]database.OutboxEvent, error) {
+	var due []database.OutboxEvent
+	for _, e := range s.events {
+		if e.Status == outboxEventStatusPending {
+			due = append(due, e)
+		}
+	}
+	return due, nil
+}
+
+func (s *linkScanStore) MarkOutboxEventSent(ctx context.Context, id uuid.UUID) error {
+	for i := range s.events {
+		if s.events[i].ID == id {
+			s.events[i].Status = "sent"
+		}
+	}
+	return nil
+}
+
+// countingScanner counts the URLs it is asked about.
+type countingScanner struct {
+	linkscan.Scanner
+	scanned int
+}
+
+func (c *countingScanner) Scan(ctx context.Context, urls []string) (map[string]string, error) {
+	c.scanned += len(urls)
+	return c.Scanner.Scan(ctx, urls)
+}
+
+func TestLinkScans(t *testing.T) {
+	author := newTestUser(t, "author@example.com", "pa55word")
+	store := &linkScanStore{
+		fakeStore: fakeStore{users: map[string]database.User{author.Email: author}},
+		scans:     map[string]database.LinkScan{},
+	}
+	scanner := &countingScanner{Scanner: linkscan.Blocklist{"evil.example"}}
+	s := NewServer(&config.Config{}, Deps{Store: store, Clock: fixedClock(testNow), LinkScanner: scanner})
+	h := NewRouter(s)
+	ctx := context.Background()
+
+	if _, _, err := s.createChirp(ctx, author.ID, "Try https://evil.example/prize or https://fine.example."); err != nil {
+		t.Fatalf("createChirp returned error: %v", err)
+	}
+	if len(store.events) != 1 || store.events[0].Kind != eventLinkScan {
+		t.Fatalf("expected a link scan event, got %+v", store.events)
+	}
+	if rec := do(h, http.MethodGet, "/out?url=https://evil.example/prize", ""); rec.Code != http.StatusFound {
+		t.Errorf("before the scan: expected a 302, got %d", rec.Code)
+	}
+
+	if _, err := s.relayOutboxBatch(ctx); err != nil {
+		t.Fatalf("relayOutboxBatch returned error: %v", err)
+	}
+	if len(store.flags) != 1 || store.flags[0] != "link BLOCKLISTED: https://evil.example/prize" {
+		t.Errorf("expected the chirp flagged for the unsafe link, got %v", store.flags)
+	}
+
+	rec := do(h, http.MethodGet, "/out?url=https://evil.example/prize", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "may be unsafe") {
+		t.Errorf("flagged link: expected the warning page, got %d: %s", rec.Code, rec.Body)
+	}
+	rec = do(h, http.MethodGet, "/out?url=https://fine.example", "")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://fine.example" {
+		t.Errorf("safe link: expected a 302, got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := do(h, http.MethodGet, "/out?url=https://elsewhere.example", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("link from no chirp: expected 404, got %d", rec.Code)
+	}
+
+	// A link scanned recently isn't looked up again.
+	if _, _, err := s.createChirp(ctx, author.ID, "Again: https://evil.example/prize"); err != nil {
+		t.Fatalf("createChirp returned error: %v", err)
+	}
+	if _, err := s.relayOutboxBatch(ctx); err != nil {
+		t.Fatalf("relayOutboxBatch returned error: %v", err)
+	}
+	if scanner.scanned != 2 || len(store.flags) != 2 {
+		t.Errorf("expected the verdict reused and the second chirp flagged, got %d scans and flags %v", scanner.scanned, store.flags)
+	}
+}
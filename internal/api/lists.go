@@ -0,0 +1,345 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxListNameLength        = 64
+	maxListDescriptionLength = 280
+	maxListMembers           = 500
+	listTimelinePageSize     = 50
+)
+
+type listResponse struct {
+	ID          uuid.UUID `json:"id"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Private     bool      `json:"private"`
+	CreatedAt   Timestamp `json:"created_at"`
+	UpdatedAt   Timestamp `json:"updated_at"`
+}
+
+func newListResponse(l database.List) listResponse {
+	return listResponse{
+		ID:          l.ID,
+		OwnerID:     l.OwnerID,
+		Name:        l.Name,
+		Description: l.Description,
+		Private:     l.Private,
+		CreatedAt:   Timestamp{l.CreatedAt},
+		UpdatedAt:   Timestamp{l.UpdatedAt},
+	}
+}
+
+type listRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+}
+
+// validate trims the name and reports what's wrong with the request, if
+// anything.
+func (req *listRequest) validate() string {
+	req.Name = strings.TrimSpace(req.Name)
+	switch {
+	case req.Name == "":
+		return "A list needs a name"
+	case len(req.Name) > maxListNameLength:
+		return fmt.Sprintf("List names can be at most %d characters", maxListNameLength)
+	case len(req.Description) > maxListDescriptionLength:
+		return fmt.Sprintf("List descriptions can be at most %d characters", maxListDescriptionLength)
+	}
+	return ""
+}
+
+// loadList looks up the list in the {listID} path parameter as viewerID
+// sees it. Private lists are only visible to their owner; anyone else gets
+// the same 404 as for a missing list. With ownerOnly, lists the viewer can
+// see but doesn't own are refused with a 403.
+func (s *Server) loadList(w http.ResponseWriter, r *http.Request, viewerID uuid.UUID, ownerOnly bool) (database.List, bool) {
+	listID, ok := pathUUID(w, r, "listID")
+	if !ok {
+		return database.List{}, false
+	}
+
+	list, err := s.db.GetList(r.Context(), listID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && list.Private && list.OwnerID != viewerID) {
+		jsonResponse(w, http.StatusNotFound, "List was not found.")
+		return database.List{}, false
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return database.List{}, false
+	}
+	if ownerOnly && list.OwnerID != viewerID {
+		jsonResponse(w, http.StatusForbidden, "You can't change this list")
+		return database.List{}, false
+	}
+	return list, true
+}
+
+func (s *Server) handlerListsCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req listRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		jsonResponse(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	list, err := s.db.CreateList(r.Context(), database.CreateListParams{
+		ID:          uuid.New(),
+		OwnerID:     userID,
+		Name:        req.Name,
+		Description: req.Description,
+		Private:     req.Private,
+	})
+	if err != nil {
+		fmt.Println("Error creating list:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusCreated, newListResponse(list))
+}
+
+// handlerListsMine lists the caller's own lists, private ones included.
+func (s *Server) handlerListsMine(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	lists, err := s.db.ListUserLists(r.Context(), userID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	resp := make([]listResponse, 0, len(lists))
+	for _, l := range lists {
+		resp = append(resp, newListResponse(l))
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (s *Server) handlerListsGet(w http.ResponseWriter, r *http.Request) {
+	list, ok := s.loadList(w, r, s.viewerID(r), false)
+	if !ok {
+		return
+	}
+	jsonResponse(w, http.StatusOK, newListResponse(list))
+}
+
+func (s *Server) handlerListsUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	list, ok := s.loadList(w, r, userID, true)
+	if !ok {
+		return
+	}
+
+	var req listRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		jsonResponse(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	list, err = s.db.UpdateList(r.Context(), database.UpdateListParams{
+		ID:          list.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Private:     req.Private,
+	})
+	if err != nil {
+		fmt.Println("Error updating list:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, newListResponse(list))
+}
+
+func (s *Server) handlerListsDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	list, ok := s.loadList(w, r, userID, true)
+	if !ok {
+		return
+	}
+
+	if err := s.db.DeleteList(r.Context(), list.ID); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type listMemberResponse struct {
+	ID       uuid.UUID `json:"id"`
+	Handle   string    `json:"handle,omitempty"`
+	Verified bool      `json:"verified"`
+	AddedAt  Timestamp `json:"added_at"`
+}
+
+func (s *Server) handlerListMembers(w http.ResponseWriter, r *http.Request) {
+	list, ok := s.loadList(w, r, s.viewerID(r), false)
+	if !ok {
+		return
+	}
+
+	members, err := s.db.ListListMembers(r.Context(), list.ID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	resp := make([]listMemberResponse, 0, len(members))
+	for _, m := range members {
+		resp = append(resp, listMemberResponse{
+			ID:       m.ID,
+			Handle:   m.Handle.String,
+			Verified: m.Verified,
+			AddedAt:  Timestamp{m.AddedAt},
+		})
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+// handlerListMembersAdd adds the {userID} account to a list. Adding someone
+// who is already a member succeeds without changing anything.
+func (s *Server) handlerListMembersAdd(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	list, ok := s.loadList(w, r, userID, true)
+	if !ok {
+		return
+	}
+	memberID, ok := pathUUID(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	if _, err := s.db.GetUserByID(ctx, memberID); err != nil {
+		jsonResponse(w, http.StatusNotFound, "User was not found.")
+		return
+	}
+	count, err := s.db.CountListMembers(ctx, list.ID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if count >= maxListMembers {
+		jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("A list can have at most %d members", maxListMembers))
+		return
+	}
+
+	err = s.db.AddListMember(ctx, database.AddListMemberParams{
+		ListID: list.ID,
+		UserID: memberID,
+	})
+	if err != nil {
+		fmt.Println("Error adding list member:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handlerListMembersRemove(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	list, ok := s.loadList(w, r, userID, true)
+	if !ok {
+		return
+	}
+	memberID, ok := pathUUID(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	removed, err := s.db.RemoveListMember(r.Context(), database.RemoveListMemberParams{
+		ListID: list.ID,
+		UserID: memberID,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if removed == 0 {
+		jsonResponse(w, http.StatusNotFound, "User is not on this list.")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerListTimeline returns the latest chirps from a list's members,
+// newest first.
+func (s *Server) handlerListTimeline(w http.ResponseWriter, r *http.Request) {
+	viewerID := s.viewerID(r)
+	list, ok := s.loadList(w, r, viewerID, false)
+	if !ok {
+		return
+	}
+
+	rows, err := s.db.ListListTimeline(r.Context(), database.ListListTimelineParams{
+		ListID:   list.ID,
+		ViewerID: viewerID,
+		RowLimit: listTimelinePageSize,
+	})
+	if err != nil {
+		fmt.Println("Error listing list timeline:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	resp := make([]chirpResponse, 0, len(rows))
+	for _, row := range rows {
+		resp = append(resp, chirpResponse{
+			ID:             row.ID,
+			CreatedAt:      Timestamp{row.CreatedAt},
+			UpdatedAt:      Timestamp{row.UpdatedAt},
+			Body:           row.Body,
+			UserID:         row.UserID,
+			AuthorVerified: row.AuthorVerified,
+			ShortURL:       s.shortURL(r.Context(), row.ID),
+			Sensitive:      row.Sensitive,
+			ContentWarning: row.ContentWarning,
+		})
+	}
+	if err := s.withChirpDetails(r.Context(), resp); err != nil {
+		fmt.Println("Error loading list timeline chirp details:", err)
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// listStore keeps lists and their members in memory.
+type listStore struct {
+	fakeStore
+	lists   map[uuid.UUID]database.List
+	members map[uuid.UUID][]uuid.UUID
+}
+
+func (l *listStore) CreateList(ctx context.Context, arg database.CreateListParams) (database.List, error) {
+	list := database.List{ID: arg.ID, OwnerID: arg.OwnerID, Name: arg.Name, Description: arg.Description, Private: arg.Private, CreatedAt: testNow, UpdatedAt: testNow}
+	l.lists[list.ID] = list
+	return list, nil
+}
+
+func (l *listStore) GetList(ctx context.Context, id uuid.UUID) (database.List, error) {
+	list, ok := l.lists[id]
+	if !ok {
+		return database.List{}, sql.ErrNoRows
+	}
+	return list, nil
+}
+
+func (l *listStore) CountListMembers(ctx context.Context, listID uuid.UUID) (int64, error) {
+	return int64(len(l.members[listID])), nil
+}
+
+func (l *listStore) AddListMember(ctx context.Context, arg database.AddListMemberParams) error {
+	l.members[arg.ListID] = append(l.members[arg.ListID], arg.UserID)
+	return nil
+}
+
+func (l *listStore) ListListTimeline(ctx context.Context, arg database.ListListTimelineParams) ([]database.ListListTimelineRow, error) {
+	var rows []database.ListListTimelineRow
+	for _, id := range l.members[arg.ListID] {
+		rows = append(rows, database.ListListTimelineRow{ID: uuid.New(), Body: "from a member", UserID: id})
+	}
+	return rows, nil
+}
+
+func (l *listStore) ListMediaForChirps(ctx context.Context, chirpIDs []uuid.UUID) ([]database.Medium, error) {
+	return nil, nil
+}
+
+func (l *listStore) ListReactionCountsForChirps(ctx context.Context, chirpIDs []uuid.UUID) ([]database.ListReactionCountsForChirpsRow, error) {
+	return nil, nil
+}
+
+func TestLists(t *testing.T) {
+	owner := newTestUser(t, "owner@example.com", "04234")
+	other := newTestUser(t, "other@example.com", "04234")
+	store := &listStore{
+		fakeStore: fakeStore{users: map[string]database.User{owner.Email: owner, other.Email: other}},
+		lists:     map[uuid.UUID]database.List{},
+		members:   map[uuid.UUID][]uuid.UUID{},
+	}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store}))
+	send := func(user database.User, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if user.ID != uuid.Nil {
+			token, err := auth.MakeJWT(user.ID, "test-secret", time.Hour)
+			if err != nil {
+				t.Fatalf("MakeJWT returned error: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send(owner, http.MethodPost, "/api/lists", `{"name":"  "}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a blank name, got %d", rec.Code)
+	}
+	rec := send(owner, http.MethodPost, "/api/lists", `{"name":"Go people","private":true}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var list listResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	base := "/api/lists/" + list.ID.String()
+
+	if rec := send(other, http.MethodGet, base, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected a private list to be hidden from others, got %d", rec.Code)
+	}
+	if rec := send(database.User{}, http.MethodGet, base+"/timeline", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected a private timeline to be hidden from anonymous viewers, got %d", rec.Code)
+	}
+	if rec := send(owner, http.MethodPut, base+"/members/"+uuid.NewString(), ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 adding an unknown user, got %d", rec.Code)
+	}
+	if rec := send(owner, http.MethodPut, base+"/members/"+other.ID.String(), ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 adding a member, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = send(owner, http.MethodGet, base+"/timeline", "")
+	var chirps []chirpResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &chirps); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || len(chirps) != 1 || chirps[0].UserID != other.ID {
+		t.Errorf("expected the member's chirp on the timeline, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// Once public, others can read the list but still not change it.
+	store.lists[list.ID] = database.List{ID: list.ID, OwnerID: owner.ID, Name: list.Name}
+	if rec := send(other, http.MethodGet, base+"/timeline", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected a public timeline to be readable, got %d", rec.Code)
+	}
+	if rec := send(other, http.MethodPut, base+"/members/"+other.ID.String(), ""); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 changing someone else's list, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"chirpy/internal/database"
+	"chirpy/internal/loader"
+
+	"github.com/google/uuid"
+)
+
+const loadersKey contextKey = "loaders"
+
+// requestLoaders batch the per-item lookups a response fans out into, so a
+// page of a hundred chirps costs one media query rather than a hundred.
+// They are built per request and cache for its lifetime only.
+type requestLoaders struct {
+	users           *loader.Loader[uuid.UUID, database.User]
+	chirpCounts     *loader.Loader[uuid.UUID, int64]
+	remoteFollowers *loader.Loader[uuid.UUID, int64]
+	// media is keyed by chirp, in attachment order.
+	media *loader.Loader[uuid.UUID, []database.Medium]
+	// reactions is keyed by chirp, counting each emoji.
+	reactions *loader.Loader[uuid.UUID, map[string]int64]
+}
+
+func (s *Server) newLoaders() *requestLoaders {
+	return &requestLoaders{
+		users: loader.New(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]database.User, error) {
+			users, err := s.db.GetUsersByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			res := make(map[uuid.UUID]database.User, len(users))
+			for _, u := range users {
+				res[u.ID] = u
+			}
+			return res, nil
+		}, loader.DefaultWait),
+		chirpCounts: loader.New(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]int64, error) {
+			rows, err := s.db.CountChirpsByUsers(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			res := make(map[uuid.UUID]int64, len(rows))
+			for _, row := range rows {
+				res[row.UserID] = row.ChirpCount
+			}
+			return res, nil
+		}, loader.DefaultWait),
+		remoteFollowers: loader.New(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]int64, error) {
+			rows, err := s.db.CountRemoteFollowersByUsers(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			res := make(map[uuid.UUID]int64, len(rows))
+			for _, row := range rows {
+				res[row.UserID] = row.FollowerCount
+			}
+			return res, nil
+		}, loader.DefaultWait),
+		media: loader.New(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]database.Medium, error) {
+			rows, err := s.db.ListMediaForChirps(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			res := make(map[uuid.UUID][]database.Medium)
+			for _, m := range rows {
+				res[m.ChirpID.UUID] = append(res[m.ChirpID.UUID], m)
+			}
+			return res, nil
+		}, loader.DefaultWait),
+		reactions: loader.New(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]map[string]int64, error) {
+			rows, err := s.db.ListReactionCountsForChirps(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			res := make(map[uuid.UUID]map[string]int64)
+			for _, row := range rows {
+				if res[row.ChirpID] == nil {
+					res[row.ChirpID] = make(map[string]int64)
+				}
+				res[row.ChirpID][row.Emoji] = row.ReactionCount
+			}
+			return res, nil
+		}, loader.DefaultWait),
+	}
+}
+
+// middlewareLoaders gives each request, and each request in a batch, its
+// own loaders, so nothing cached outlives the request that loaded it.
+func (s *Server) middlewareLoaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), loadersKey, s.newLoaders())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// loaders returns the request's loaders, or new ones for code running
+// outside a request.
+func (s *Server) loaders(ctx context.Context) *requestLoaders {
+	if l, ok := ctx.Value(loadersKey).(*requestLoaders); ok {
+		return l
+	}
+	return s.newLoaders()
+}
+
+// withChirpDetails fills in the media and reactions of a page of chirps,
+// with one query for each however long the page is.
+func (s *Server) withChirpDetails(ctx context.Context, chirps []chirpResponse) error {
+	ids := make([]uuid.UUID, len(chirps))
+	for i, c := range chirps {
+		ids[i] = c.ID
+	}
+	l := s.loaders(ctx)
+	media, err := l.media.LoadMany(ctx, ids)
+	if err != nil {
+		return err
+	}
+	reactions, err := l.reactions.LoadMany(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range chirps {
+		for _, m := range media[chirps[i].ID] {
+			chirps[i].Media = append(chirps[i].Media, s.newMediaResponse(m))
+		}
+		chirps[i].Reactions = reactions[chirps[i].ID]
+	}
+	return nil
+}
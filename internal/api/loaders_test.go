@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// detailStore has one image and a reaction on every chirp, and counts the
+// queries made for them. Each query waits latency, as a round trip to the
+// database would.
+type detailStore struct {
+	contractStore
+	latency time.Duration
+	queries atomic.Int64
+}
+
+func (d *detailStore) query() {
+	d.queries.Add(1)
+	time.Sleep(d.latency)
+}
+
+func (d *detailStore) chirpMedium(chirpID uuid.UUID) database.Medium {
+	return database.Medium{
+		ID:          chirpID,
+		ContentType: "image/png",
+		ChirpID:     uuid.NullUUID{UUID: chirpID, Valid: true},
+	}
+}
+
+func (d *detailStore) ListChirpMedia(ctx context.Context, chirpID uuid.NullUUID) ([]database.Medium, error) {
+	d.query()
+	return []database.Medium{d.chirpMedium(chirpID.UUID)}, nil
+}
+
+func (d *detailStore) ListMediaForChirps(ctx context.Context, chirpIDs []uuid.UUID) ([]database.Medium, error) {
+	d.query()
+	rows := make([]database.Medium, 0, len(chirpIDs))
+	for _, id := range chirpIDs {
+		rows = append(rows, d.chirpMedium(id))
+	}
+	return rows, nil
+}
+
+func (d *detailStore) ListChirpReactionCounts(ctx context.Context, chirpID uuid.UUID) ([]database.ListChirpReactionCountsRow, error) {
+	d.query()
+	return []database.ListChirpReactionCountsRow{{Emoji: "👍", ReactionCount: 2}}, nil
+}
+
+func (d *detailStore) ListReactionCountsForChirps(ctx context.Context, chirpIDs []uuid.UUID) ([]database.ListReactionCountsForChirpsRow, error) {
+	d.query()
+	rows := make([]database.ListReactionCountsForChirpsRow, 0, len(chirpIDs))
+	for _, id := range chirpIDs {
+		rows = append(rows, database.ListReactionCountsForChirpsRow{ChirpID: id, Emoji: "👍", ReactionCount: 2})
+	}
+	return rows, nil
+}
+
+func newDetailServer(store *detailStore) *Server {
+	return NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store, Clock: fixedClock(testNow)})
+}
+
+func chirpPage(n int) []chirpResponse {
+	page := make([]chirpResponse, n)
+	for i := range page {
+		page[i] = chirpResponse{ID: uuid.New()}
+	}
+	return page
+}
+
+func TestWithChirpDetails(t *testing.T) {
+	store := &detailStore{}
+	s := newDetailServer(store)
+	ctx := context.WithValue(context.Background(), loadersKey, s.newLoaders())
+
+	page := chirpPage(100)
+	if err := s.withChirpDetails(ctx, page); err != nil {
+		t.Fatalf("withChirpDetails returned error: %v", err)
+	}
+	if got := store.queries.Load(); got != 2 {
+		t.Fatalf("expected one media and one reactions query, got %d queries", got)
+	}
+	for _, c := range page {
+		if len(c.Media) != 1 || c.Media[0].ID != c.ID {
+			t.Fatalf("chirp %s has media %+v", c.ID, c.Media)
+		}
+		if c.Reactions["👍"] != 2 {
+			t.Fatalf("chirp %s has reactions %v", c.ID, c.Reactions)
+		}
+	}
+
+	// The request's loaders have them cached now.
+	if err := s.withChirpDetails(ctx, page[:10]); err != nil {
+		t.Fatalf("withChirpDetails returned error: %v", err)
+	}
+	if got := store.queries.Load(); got != 2 {
+		t.Fatalf("expected cached details, got %d queries", got)
+	}
+}
+
+// BenchmarkChirpDetails compares filling in a 100-chirp page's media and
+// reactions a chirp at a time with loading them for the whole page, with
+// each query taking 100µs.
+func BenchmarkChirpDetails(b *testing.B) {
+	const pageSize = 100
+	b.Run("per-chirp", func(b *testing.B) {
+		store := &detailStore{latency: 100 * time.Microsecond}
+		s := newDetailServer(store)
+		ctx := context.Background()
+		for b.Loop() {
+			for _, c := range chirpPage(pageSize) {
+				var err error
+				if c.Media, err = s.chirpMedia(ctx, c.ID); err != nil {
+					b.Fatal(err)
+				}
+				if c.Reactions, err = s.reactionCounts(ctx, c.ID); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		b.ReportMetric(float64(store.queries.Load())/float64(b.N), "queries/op")
+	})
+	b.Run("batched", func(b *testing.B) {
+		store := &detailStore{latency: 100 * time.Microsecond}
+		s := newDetailServer(store)
+		for b.Loop() {
+			ctx := context.WithValue(context.Background(), loadersKey, s.newLoaders())
+			if err := s.withChirpDetails(ctx, chirpPage(pageSize)); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(store.queries.Load())/float64(b.N), "queries/op")
+	})
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultNearbyRadiusKm = 10
+	maxNearbyRadiusKm     = 100
+	nearbyPageSize        = 50
+	earthRadiusKm         = 6371
+)
+
+var errLocationNotShared = errors.New("location sharing is off")
+
+// chirpLocation is where a chirp was posted from.
+type chirpLocation struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+func (l chirpLocation) valid() bool {
+	return l.Lat >= -90 && l.Lat <= 90 && l.Lon >= -180 && l.Lon <= 180 &&
+		!math.IsNaN(l.Lat) && !math.IsNaN(l.Lon)
+}
+
+// boundingBox returns latitude and longitude ranges that hold every point
+// within radiusKm of l. Near the poles and the antimeridian it falls back
+// to every longitude rather than wrapping.
+func (l chirpLocation) boundingBox(radiusKm float64) (minLat, maxLat, minLon, maxLon float64) {
+	dLat := radiusKm / earthRadiusKm * 180 / math.Pi
+	minLat, maxLat = l.Lat-dLat, l.Lat+dLat
+	if minLat <= -90 || maxLat >= 90 {
+		return max(minLat, -90), min(maxLat, 90), -180, 180
+	}
+	dLon := dLat / math.Cos(l.Lat*math.Pi/180)
+	minLon, maxLon = l.Lon-dLon, l.Lon+dLon
+	if minLon < -180 || maxLon > 180 {
+		return minLat, maxLat, -180, 180
+	}
+	return minLat, maxLat, minLon, maxLon
+}
+
+// checkLocationSharing reports errLocationNotShared unless userID has opted
+// in to geotagging their chirps.
+func (s *Server) checkLocationSharing(r *http.Request, userID uuid.UUID) error {
+	shared, err := s.db.GetLocationSharing(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+	if !shared {
+		return errLocationNotShared
+	}
+	return nil
+}
+
+type nearbyChirpResponse struct {
+	chirpResponse
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// handlerChirpsNearby lists geotagged chirps within ?radius= kilometres
+// (default 10, at most 100) of ?lat= and ?lon=, nearest first.
+func (s *Server) handlerChirpsNearby(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	lat, latErr := strconv.ParseFloat(q.Get("lat"), 64)
+	lon, lonErr := strconv.ParseFloat(q.Get("lon"), 64)
+	at := chirpLocation{Lat: lat, Lon: lon}
+	if latErr != nil || lonErr != nil || !at.valid() {
+		jsonResponse(w, http.StatusBadRequest, "lat and lon must be a valid latitude and longitude")
+		return
+	}
+	radius := float64(defaultNearbyRadiusKm)
+	if v := q.Get("radius"); v != "" {
+		var err error
+		radius, err = strconv.ParseFloat(v, 64)
+		if err != nil || !(radius > 0 && radius <= maxNearbyRadiusKm) {
+			jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("radius must be between 0 and %d kilometres", maxNearbyRadiusKm))
+			return
+		}
+	}
+
+	minLat, maxLat, minLon, maxLon := at.boundingBox(radius)
+	rows, err := s.db.ListNearbyChirps(r.Context(), database.ListNearbyChirpsParams{
+		Lat:      at.Lat,
+		Lon:      at.Lon,
+		MinLat:   minLat,
+		MaxLat:   maxLat,
+		MinLon:   minLon,
+		MaxLon:   maxLon,
+		ViewerID: s.viewerID(r),
+		RadiusKm: radius,
+		RowLimit: nearbyPageSize,
+	})
+	if err != nil {
+		fmt.Println("Error listing nearby chirps:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	resp := make([]nearbyChirpResponse, 0, len(rows))
+	for _, row := range rows {
+		resp = append(resp, nearbyChirpResponse{
+			chirpResponse: chirpResponse{
+				ID:             row.ID,
+				CreatedAt:      Timestamp{row.CreatedAt},
+				UpdatedAt:      Timestamp{row.UpdatedAt},
+				Body:           row.Body,
+				UserID:         row.UserID,
+				AuthorVerified: row.AuthorVerified,
+				ShortURL:       s.shortURL(r.Context(), row.ID),
+				Sensitive:      row.Sensitive,
+				ContentWarning: row.ContentWarning,
+				Location:       &chirpLocation{Lat: row.Latitude, Lon: row.Longitude},
+			},
+			DistanceKm: math.Round(row.DistanceKm*100) / 100,
+		})
+	}
+	jsonFieldsResponse(w, r, http.StatusOK, resp)
+}
+
+type locationPreferences struct {
+	ShareLocation bool `json:"share_location"`
+}
+
+func (s *Server) handlerLocationPreferencesGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	shared, err := s.db.GetLocationSharing(r.Context(), userID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, locationPreferences{ShareLocation: shared})
+}
+
+// handlerLocationPreferencesUpdate opts the user in to or out of
+// geotagging. Opting out also hides the chirps they geotagged before from
+// nearby searches.
+func (s *Server) handlerLocationPreferencesUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req locationPreferences
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+
+	err = s.db.SetLocationSharing(r.Context(), database.SetLocationSharingParams{
+		UserID:        userID,
+		ShareLocation: req.ShareLocation,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, req)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// locationStore keeps location preferences and chirp locations in memory.
+type locationStore struct {
+	contractStore
+	sharing   map[uuid.UUID]bool
+	locations []database.CreateChirpLocationParams
+	nearby    []database.ListNearbyChirpsParams
+}
+
+func (l *locationStore) GetLocationSharing(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return l.sharing[userID], nil
+}
+
+func (l *locationStore) CreateChirpLocation(ctx context.Context, arg database.CreateChirpLocationParams) error {
+	l.locations = append(l.locations, arg)
+	return nil
+}
+
+func (l *locationStore) ListNearbyChirps(ctx context.Context, arg database.ListNearbyChirpsParams) ([]database.ListNearbyChirpsRow, error) {
+	l.nearby = append(l.nearby, arg)
+	return []database.ListNearbyChirpsRow{{
+		ID:         contractChirpID,
+		CreatedAt:  testNow,
+		UpdatedAt:  testNow,
+		Body:       "Nearby",
+		UserID:     contractUserID,
+		Latitude:   arg.Lat,
+		Longitude:  arg.Lon,
+		DistanceKm: 1.23456,
+	}}, nil
+}
+
+func TestChirpsCreate_Location(t *testing.T) {
+	store := &locationStore{sharing: map[uuid.UUID]bool{}}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store, Clock: fixedClock(testNow)}))
+	body := `{"body":"Here","user_id":"` + contractUserID.String() + `","lat":51.5,"lon":-0.12}`
+
+	if rec := do(h, http.MethodPost, "/api/chirps", body); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 before opting in, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(h, http.MethodPost, "/api/chirps", `{"body":"Here","user_id":"`+contractUserID.String()+`","lat":51.5}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for lat without lon, got %d", rec.Code)
+	}
+
+	store.sharing[contractUserID] = true
+	rec := do(h, http.MethodPost, "/api/chirps", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp chirpResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Location == nil || resp.Location.Lat != 51.5 || resp.Location.Lon != -0.12 {
+		t.Errorf("expected the location in the response, got %+v", resp.Location)
+	}
+	if len(store.locations) != 1 || store.locations[0].ChirpID != contractChirpID {
+		t.Errorf("expected the location to be stored, got %+v", store.locations)
+	}
+}
+
+func TestChirpsNearby(t *testing.T) {
+	store := &locationStore{}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store, Clock: fixedClock(testNow)}))
+
+	for _, path := range []string{
+		"/api/chirps/nearby",
+		"/api/chirps/nearby?lat=91&lon=0",
+		"/api/chirps/nearby?lat=0&lon=abc",
+		"/api/chirps/nearby?lat=0&lon=0&radius=0",
+		"/api/chirps/nearby?lat=0&lon=0&radius=101",
+	} {
+		if rec := do(h, http.MethodGet, path, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, rec.Code)
+		}
+	}
+
+	rec := do(h, http.MethodGet, "/api/chirps/nearby?lat=51.5&lon=-0.12&radius=5", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp []nearbyChirpResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp) != 1 || resp[0].DistanceKm != 1.23 || resp[0].Location == nil {
+		t.Errorf("unexpected response %s", rec.Body)
+	}
+	if len(store.nearby) != 1 || store.nearby[0].RadiusKm != 5 || store.nearby[0].RowLimit != nearbyPageSize {
+		t.Errorf("unexpected query %+v", store.nearby)
+	}
+}
+
+func TestBoundingBox(t *testing.T) {
+	minLat, maxLat, minLon, maxLon := chirpLocation{Lat: 0, Lon: 0}.boundingBox(111.19)
+	if math.Abs(minLat+1) > 0.01 || math.Abs(maxLat-1) > 0.01 || math.Abs(minLon+1) > 0.01 || math.Abs(maxLon-1) > 0.01 {
+		t.Errorf("expected about one degree each way at the equator, got %v %v %v %v", minLat, maxLat, minLon, maxLon)
+	}
+
+	_, _, minLon, maxLon = chirpLocation{Lat: 60, Lon: 0}.boundingBox(111.19)
+	if math.Abs(maxLon-2) > 0.01 || math.Abs(minLon+2) > 0.01 {
+		t.Errorf("expected longitude to widen at 60°N, got %v %v", minLon, maxLon)
+	}
+
+	for _, l := range []chirpLocation{{Lat: 89.9, Lon: 0}, {Lat: 0, Lon: 179.9}} {
+		if _, _, minLon, maxLon := l.boundingBox(50); minLon != -180 || maxLon != 180 {
+			t.Errorf("%+v: expected every longitude, got %v %v", l, minLon, maxLon)
+		}
+	}
+}
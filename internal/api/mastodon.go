@@ -0,0 +1,369 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"html"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// The Mastodon client API subset maps chirps onto statuses and users onto
+// accounts, enough for existing Mastodon apps to read timelines and post.
+// Clients authenticate with a regular Chirpy access token.
+
+const (
+	mastodonDefaultLimit = 20
+	mastodonMaxLimit     = 40
+)
+
+type mastodonAccount struct {
+	ID             string        `json:"id"`
+	Username       string        `json:"username"`
+	Acct           string        `json:"acct"`
+	DisplayName    string        `json:"display_name"`
+	Locked         bool          `json:"locked"`
+	Bot            bool          `json:"bot"`
+	CreatedAt      string        `json:"created_at"`
+	Note           string        `json:"note"`
+	URL            string        `json:"url"`
+	Avatar         string        `json:"avatar"`
+	AvatarStatic   string        `json:"avatar_static"`
+	Header         string        `json:"header"`
+	HeaderStatic   string        `json:"header_static"`
+	FollowersCount int64         `json:"followers_count"`
+	FollowingCount int64         `json:"following_count"`
+	StatusesCount  int64         `json:"statuses_count"`
+	Emojis         []interface{} `json:"emojis"`
+	Fields         []interface{} `json:"fields"`
+}
+
+type mastodonStatus struct {
+	ID                 string          `json:"id"`
+	URI                string          `json:"uri"`
+	URL                string          `json:"url"`
+	CreatedAt          string          `json:"created_at"`
+	Account            mastodonAccount `json:"account"`
+	Content            string          `json:"content"`
+	Visibility         string          `json:"visibility"`
+	Sensitive          bool            `json:"sensitive"`
+	SpoilerText        string          `json:"spoiler_text"`
+	InReplyToID        *string         `json:"in_reply_to_id"`
+	InReplyToAccountID *string         `json:"in_reply_to_account_id"`
+	Reblog             *mastodonStatus `json:"reblog"`
+	Language           *string         `json:"language"`
+	RepliesCount       int64           `json:"replies_count"`
+	ReblogsCount       int64           `json:"reblogs_count"`
+	FavouritesCount    int64           `json:"favourites_count"`
+	MediaAttachments   []interface{}   `json:"media_attachments"`
+	Mentions           []interface{}   `json:"mentions"`
+	Tags               []interface{}   `json:"tags"`
+	Emojis             []interface{}   `json:"emojis"`
+	Card               interface{}     `json:"card"`
+	Poll               interface{}     `json:"poll"`
+}
+
+func mastodonError(w http.ResponseWriter, code int, msg string) {
+	jsonResponse(w, code, map[string]string{"error": msg})
+}
+
+// baseURL is PUBLIC_URL, or the URL the request came in on when it isn't
+// set. Mastodon clients expect absolute URLs.
+func (s *Server) baseURL(r *http.Request) string {
+	t := currentTenant(r.Context())
+	if s.config.PublicURL != "" {
+		return s.tenantURL(t)
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + tenantPath(r, t)
+}
+
+func mastodonTime(t time.Time) string {
+	return t.UTC().Format(timestampFormat)
+}
+
+// remoteFollowerCount is how many fediverse accounts follow userID. It's
+// only for display, so an error counts as none.
+func (s *Server) remoteFollowerCount(ctx context.Context, userID uuid.UUID) int64 {
+	followers, _ := s.db.CountRemoteFollowers(ctx, userID)
+	return followers
+}
+
+func (s *Server) newMastodonAccount(base string, u database.User, statuses, followers int64) mastodonAccount {
+	name := preferredUsername(u)
+	return mastodonAccount{
+		ID:             u.ID.String(),
+		Username:       name,
+		Acct:           name,
+		DisplayName:    name,
+		CreatedAt:      mastodonTime(u.CreatedAt),
+		URL:            s.appURL(base, "profile/@"+name),
+		Avatar:         s.avatarURL(base, u),
+		AvatarStatic:   s.avatarURL(base, u),
+		Header:         base + "/assets/logo.png",
+		HeaderStatic:   base + "/assets/logo.png",
+		FollowersCount: followers,
+		StatusesCount:  statuses,
+		Emojis:         []interface{}{},
+		Fields:         []interface{}{},
+	}
+}
+
+// mastodonStatuses renders chirps as statuses, loading every author, their
+// chirp counts and their followers in one query each. A content warning
+// becomes the status's spoiler text.
+func (s *Server) mastodonStatuses(ctx context.Context, base string, chirps []flaggedChirp) ([]mastodonStatus, error) {
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, c := range chirps {
+		if !seen[c.UserID] {
+			seen[c.UserID] = true
+			ids = append(ids, c.UserID)
+		}
+	}
+
+	l := s.loaders(ctx)
+	users, err := l.users.LoadMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := l.chirpCounts.LoadMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	followers, err := l.remoteFollowers.LoadMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	accounts := make(map[uuid.UUID]mastodonAccount, len(users))
+	for id, u := range users {
+		accounts[id] = s.newMastodonAccount(base, u, counts[id], followers[id])
+	}
+
+	statuses := make([]mastodonStatus, 0, len(chirps))
+	for _, c := range chirps {
+		statuses = append(statuses, mastodonStatus{
+			ID:               c.ID.String(),
+			URI:              base + "/ap/chirps/" + c.ID.String(),
+			URL:              base + "/api/v1/statuses/" + c.ID.String(),
+			CreatedAt:        mastodonTime(c.CreatedAt),
+			Account:          accounts[c.UserID],
+			Content:          "<p>" + html.EscapeString(c.Body) + "</p>",
+			Visibility:       "public",
+			Sensitive:        c.Sensitive,
+			SpoilerText:      c.Warning,
+			MediaAttachments: []interface{}{},
+			Mentions:         []interface{}{},
+			Tags:             []interface{}{},
+			Emojis:           []interface{}{},
+		})
+	}
+	return statuses, nil
+}
+
+func mastodonLimit(r *http.Request) int32 {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		return mastodonDefaultLimit
+	}
+	if limit > mastodonMaxLimit {
+		return mastodonMaxLimit
+	}
+	return int32(limit)
+}
+
+func (s *Server) handlerMastodonVerifyCredentials(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		mastodonError(w, http.StatusUnauthorized, "The access token is invalid")
+		return
+	}
+	user, err := s.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		mastodonError(w, http.StatusUnauthorized, "The access token is invalid")
+		return
+	}
+	counts, err := s.db.CountChirpsByUsers(r.Context(), []uuid.UUID{userID})
+	if err != nil {
+		mastodonError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	var statuses int64
+	if len(counts) > 0 {
+		statuses = counts[0].ChirpCount
+	}
+	jsonFieldsResponse(w, r, http.StatusOK, s.newMastodonAccount(s.baseURL(r), user, statuses, s.remoteFollowerCount(r.Context(), user.ID)))
+}
+
+func (s *Server) handlerMastodonAccount(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		mastodonError(w, http.StatusNotFound, "Record not found")
+		return
+	}
+	user, err := s.db.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && user.BannedAt.Valid) {
+		mastodonError(w, http.StatusNotFound, "Record not found")
+		return
+	}
+	if err != nil {
+		mastodonError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	var statuses int64
+	if !user.Shadowbanned || user.ID == s.viewerID(r) {
+		counts, err := s.db.CountChirpsByUsers(r.Context(), []uuid.UUID{userID})
+		if err != nil {
+			mastodonError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		if len(counts) > 0 {
+			statuses = counts[0].ChirpCount
+		}
+	}
+	jsonFieldsResponse(w, r, http.StatusOK, s.newMastodonAccount(s.baseURL(r), user, statuses, s.remoteFollowerCount(r.Context(), user.ID)))
+}
+
+// handlerMastodonTimeline serves both the home and public timelines.
+// Chirpy has no follow graph, so home is the public timeline for a signed-in
+// user.
+func (s *Server) handlerMastodonTimeline(requireAuth bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		viewer := uuid.Nil
+		if requireAuth {
+			userID, err := s.authenticate(r)
+			if err != nil {
+				mastodonError(w, http.StatusUnauthorized, "The access token is invalid")
+				return
+			}
+			viewer = userID
+		} else {
+			viewer = s.viewerID(r)
+		}
+
+		rows, err := s.db.ListRecentChirps(r.Context(), database.ListRecentChirpsParams{
+			ViewerID: viewer,
+			RowLimit: mastodonLimit(r),
+		})
+		if err != nil {
+			mastodonError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		chirps := make([]flaggedChirp, 0, len(rows))
+		for _, row := range rows {
+			chirps = append(chirps, newFlaggedChirp(database.GetVisibleChirpRow(row)))
+		}
+
+		statuses, err := s.mastodonStatuses(r.Context(), s.baseURL(r), chirps)
+		if err != nil {
+			mastodonError(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		jsonFieldsResponse(w, r, http.StatusOK, statuses)
+	}
+}
+
+func (s *Server) handlerMastodonStatus(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		mastodonError(w, http.StatusNotFound, "Record not found")
+		return
+	}
+	row, err := s.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: s.viewerID(r),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		mastodonError(w, http.StatusNotFound, "Record not found")
+		return
+	}
+	if err != nil {
+		mastodonError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	statuses, err := s.mastodonStatuses(r.Context(), s.baseURL(r), []flaggedChirp{newFlaggedChirp(row)})
+	if err != nil {
+		mastodonError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonFieldsResponse(w, r, http.StatusOK, statuses[0])
+}
+
+// handlerMastodonStatusCreate posts a chirp. Clients send the status either
+// as JSON or as a form, so both are accepted.
+func (s *Server) handlerMastodonStatusCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		mastodonError(w, http.StatusUnauthorized, "The access token is invalid")
+		return
+	}
+
+	var status string
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		// Clients send the other status parameters whether or not they
+		// are set. They are accepted and ignored, so strict decoding only
+		// catches misspellings.
+		var req struct {
+			Status      string          `json:"status"`
+			InReplyToID json.RawMessage `json:"in_reply_to_id"`
+			MediaIDs    json.RawMessage `json:"media_ids"`
+			Poll        json.RawMessage `json:"poll"`
+			Sensitive   json.RawMessage `json:"sensitive"`
+			SpoilerText json.RawMessage `json:"spoiler_text"`
+			Visibility  json.RawMessage `json:"visibility"`
+			Language    json.RawMessage `json:"language"`
+			ScheduledAt json.RawMessage `json:"scheduled_at"`
+		}
+		err := s.decodeJSON(r, &req)
+		var strictErr *strictJSONError
+		if errors.As(err, &strictErr) {
+			mastodonError(w, http.StatusBadRequest, strictErr.msg)
+			return
+		}
+		if err != nil {
+			mastodonError(w, http.StatusUnprocessableEntity, "Validation failed: invalid request body")
+			return
+		}
+		status = req.Status
+	} else {
+		status = r.FormValue("status")
+	}
+	if status == "" {
+		mastodonError(w, http.StatusUnprocessableEntity, "Validation failed: Text can't be blank")
+		return
+	}
+
+	chirp, _, err := s.createChirp(r.Context(), userID, status)
+	switch {
+	case errors.Is(err, errChirpTooLong):
+		mastodonError(w, http.StatusUnprocessableEntity, "Validation failed: Text character limit of 140 exceeded")
+		return
+	case errors.Is(err, errChirpBlocked):
+		mastodonError(w, http.StatusUnprocessableEntity, "Validation failed: Text contains blocked content")
+		return
+	case err != nil:
+		mastodonError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	statuses, err := s.mastodonStatuses(r.Context(), s.baseURL(r), []flaggedChirp{{Chirp: chirp}})
+	if err != nil {
+		mastodonError(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, statuses[0])
+}
@@ -0,0 +1,380 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/media"
+	"chirpy/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+// mediaReadTimeout replaces the server's read and request timeouts for
+// uploads, which may be videos on slow connections.
+const mediaReadTimeout = 5 * time.Minute
+
+const (
+	// signedMediaTTL is how long a signed link to private media works.
+	signedMediaTTL = time.Hour
+	// mediaURLPurpose scopes media link signatures so they can't be used
+	// for anything else signed with the JWT secret.
+	mediaURLPurpose = "media"
+)
+
+const (
+	// maxChirpMedia is how many files can be attached to one chirp.
+	maxChirpMedia = 4
+	// maxAltTextLength bounds the description of one file.
+	maxAltTextLength = 1500
+)
+
+var (
+	errTooManyMedia    = errors.New("too many media attachments")
+	errAltTextRequired = errors.New("alt text is required")
+	errAltTextTooLong  = errors.New("alt text is too long")
+	errMediaNotFound   = errors.New("media not found")
+)
+
+type mediaResponse struct {
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	// DurationMs is how long a video plays.
+	DurationMs *int32 `json:"duration_ms,omitempty"`
+	// AltText describes the file for readers who can't see it. It is set
+	// once the file is attached to a chirp.
+	AltText string `json:"alt_text,omitempty"`
+	// Width and Height are set for images, as are Variants, smaller copies
+	// to show instead of the original, once they are made. SrcSet lists
+	// them all by width, for an img srcset attribute.
+	Width     int32                  `json:"width,omitempty"`
+	Height    int32                  `json:"height,omitempty"`
+	Variants  []mediaVariantResponse `json:"variants,omitempty"`
+	SrcSet    string                 `json:"srcset,omitempty"`
+	CreatedAt Timestamp              `json:"created_at"`
+}
+
+// mediaAttachment is an uploaded file an author attaches to a new chirp.
+type mediaAttachment struct {
+	ID      uuid.UUID `json:"id"`
+	AltText string    `json:"alt_text,omitempty"`
+}
+
+func (s *Server) newMediaResponse(m database.Medium) mediaResponse {
+	resp := mediaResponse{
+		ID:          m.ID,
+		URL:         s.mediaURL(m, ""),
+		ContentType: m.ContentType,
+		Size:        m.Size,
+		AltText:     m.AltText,
+		Width:       m.Width.Int32,
+		Height:      m.Height.Int32,
+		CreatedAt:   Timestamp{m.CreatedAt},
+	}
+	if m.DurationMs.Valid {
+		resp.DurationMs = &m.DurationMs.Int32
+	}
+	if m.Width.Valid {
+		resp.Variants, resp.SrcSet = s.mediaVariantResponses(m)
+	}
+	return resp
+}
+
+// mediaPrivate reports whether m may only be fetched through a signed
+// link. Files are private until they are attached to a chirp, so only
+// their uploader sees drafts.
+func mediaPrivate(m database.Medium) bool {
+	return !m.ChirpID.Valid
+}
+
+// mediaURL links to m, or to its variant when one is named. Private media
+// gets a link that is signed and expires after signedMediaTTL, so a leaked
+// link stops working.
+func (s *Server) mediaURL(m database.Medium, variant string) string {
+	u := s.tenantURL(s.tenantByID(m.TenantID)) + "/media/" + m.ID.String()
+	if variant != "" {
+		u += "/" + variant
+	}
+	if !mediaPrivate(m) {
+		return u
+	}
+	expires := strconv.FormatInt(s.clock.Now().Add(signedMediaTTL).Unix(), 10)
+	q := url.Values{}
+	q.Set("expires", expires)
+	q.Set("sig", auth.SignValue(m.ID.String()+"."+expires, mediaURLPurpose, s.config.JWTSecret))
+	return u + "?" + q.Encode()
+}
+
+// checkMediaSignature checks a link made by mediaURL, returning when it
+// expires. The signature covers the expiry, so it can't be extended; it
+// covers the file's variants too.
+func (s *Server) checkMediaSignature(r *http.Request, id uuid.UUID) (time.Time, bool) {
+	query := r.URL.Query()
+	expires := query.Get("expires")
+	if !auth.CheckSignedValue(id.String()+"."+expires, mediaURLPurpose, query.Get("sig"), s.config.JWTSecret) {
+		return time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0), true
+}
+
+// checkAttachments validates the files userID wants to attach to a new
+// chirp, before the chirp is created. Alt text is trimmed in place, and
+// required when the config says so.
+func (s *Server) checkAttachments(ctx context.Context, userID uuid.UUID, attachments []mediaAttachment) error {
+	if len(attachments) > maxChirpMedia {
+		return errTooManyMedia
+	}
+	for i := range attachments {
+		a := &attachments[i]
+		a.AltText = strings.TrimSpace(a.AltText)
+		if a.AltText == "" && s.config.RequireAltText {
+			return errAltTextRequired
+		}
+		if len(a.AltText) > maxAltTextLength {
+			return errAltTextTooLong
+		}
+		m, err := s.db.GetMedia(ctx, a.ID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && (m.UserID != userID || m.ChirpID.Valid)) {
+			return errMediaNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachMedia attaches files checked by checkAttachments to a chirp that
+// has just been created, returning those it attached. A failure is logged
+// rather than returned because the chirp is already posted.
+func (s *Server) attachMedia(ctx context.Context, chirpID, userID uuid.UUID, attachments []mediaAttachment) []mediaResponse {
+	var attached []mediaResponse
+	for i, a := range attachments {
+		m, err := s.db.AttachMedia(ctx, database.AttachMediaParams{
+			ChirpID:  uuid.NullUUID{UUID: chirpID, Valid: true},
+			Position: int32(i),
+			AltText:  a.AltText,
+			ID:       a.ID,
+			UserID:   userID,
+		})
+		if err != nil {
+			fmt.Println("Error attaching media:", err)
+			continue
+		}
+		attached = append(attached, s.newMediaResponse(m))
+	}
+	return attached
+}
+
+// chirpMedia lists the files attached to a chirp, in order.
+func (s *Server) chirpMedia(ctx context.Context, chirpID uuid.UUID) ([]mediaResponse, error) {
+	rows, err := s.db.ListChirpMedia(ctx, uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	var attached []mediaResponse
+	for _, m := range rows {
+		attached = append(attached, s.newMediaResponse(m))
+	}
+	return attached, nil
+}
+
+// mediaKey is where a file, or with a name one of its variants, is kept
+// in storage.
+func mediaKey(id uuid.UUID, variant string) string {
+	if variant != "" {
+		return id.String() + "-" + variant
+	}
+	return id.String()
+}
+
+// handlerMediaUpload stores an image or short video sent as the request
+// body, typed by its Content-Type. Images are stored without their
+// metadata; videos as uploaded, once their type, size and duration check
+// out.
+func (s *Server) handlerMediaUpload(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	http.NewResponseController(w).SetReadDeadline(time.Now().Add(mediaReadTimeout))
+	noRequestTimeout(r)
+	r.Body = http.MaxBytesReader(w, r.Body, max(media.MaxBytes, media.MaxVideoBytes))
+
+	var body io.Reader
+	var size int64
+	var duration, width, height sql.NullInt32
+	if media.IsVideo(contentType) {
+		// Checking a video means reading around in it, so it is spooled to
+		// a temporary file first.
+		var tmp *os.File
+		tmp, err = os.CreateTemp("", "chirpy-upload-*")
+		if err != nil {
+			fmt.Println("Error creating media file:", err)
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		size, err = io.Copy(tmp, r.Body)
+		if err == nil {
+			var d time.Duration
+			d, err = media.CheckVideo(tmp, size, contentType)
+			duration = sql.NullInt32{Int32: int32(d.Milliseconds()), Valid: true}
+		}
+		if err == nil {
+			_, err = tmp.Seek(0, io.SeekStart)
+		}
+		body = tmp
+	} else {
+		var clean []byte
+		clean, err = media.Sanitize(r.Body, contentType)
+		if err == nil {
+			var w, h int
+			w, h, err = media.Dimensions(clean)
+			width = sql.NullInt32{Int32: int32(w), Valid: true}
+			height = sql.NullInt32{Int32: int32(h), Valid: true}
+		}
+		body, size = bytes.NewReader(clean), int64(len(clean))
+	}
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr), errors.Is(err, media.ErrTooLarge):
+		jsonResponse(w, http.StatusRequestEntityTooLarge, "File is too large")
+		return
+	case errors.Is(err, media.ErrUnsupportedType):
+		jsonResponse(w, http.StatusUnsupportedMediaType, "Upload a JPEG, PNG or GIF image, or an MP4 or WebM video")
+		return
+	case errors.Is(err, media.ErrTypeMismatch):
+		jsonResponse(w, http.StatusBadRequest, "File contents don't match its Content-Type")
+		return
+	case errors.Is(err, media.ErrTooLong):
+		jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("Videos can be at most %d seconds long", int(media.MaxVideoDuration.Seconds())))
+		return
+	case err != nil:
+		jsonResponse(w, http.StatusBadRequest, "Could not read the file")
+		return
+	}
+
+	ctx := r.Context()
+	id := uuid.New()
+	if err := s.storage.Put(ctx, mediaKey(id, ""), body, size, contentType); err != nil {
+		fmt.Println("Error storing media file:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	m, err := s.createMedia(ctx, database.CreateMediaParams{
+		ID:          id,
+		UserID:      userID,
+		ContentType: contentType,
+		Size:        size,
+		DurationMs:  duration,
+		Width:       width,
+		Height:      height,
+	})
+	if err != nil {
+		s.storage.Delete(ctx, mediaKey(id, ""))
+		fmt.Println("Error recording media:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusCreated, s.newMediaResponse(m))
+}
+
+// handlerMediaGet serves an uploaded file, or one of its variants. Range
+// requests are honoured, so players can seek in a video without
+// downloading all of it. Private files need an unexpired signed link.
+// Storage that can sign its own links is sent to instead.
+func (s *Server) handlerMediaGet(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("mediaID"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	ctx := r.Context()
+	m, err := s.db.GetMedia(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error looking up media:", err)
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	// Files never change once uploaded, but a signed link may only be
+	// cached by the browser that has it, and only until it expires.
+	cacheControl := immutableCacheControl
+	ttl := signedMediaTTL
+	if mediaPrivate(m) {
+		expires, ok := s.checkMediaSignature(r, m.ID)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		ttl = expires.Sub(s.clock.Now())
+		if ttl <= 0 {
+			http.Error(w, "Link has expired", http.StatusForbidden)
+			return
+		}
+		cacheControl = fmt.Sprintf("private, max-age=%d", int(ttl.Seconds()))
+	}
+	key, contentType := mediaKey(m.ID, ""), m.ContentType
+	if name := r.PathValue("variant"); name != "" {
+		v, ok := findMediaVariant(m, name)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		key, contentType = mediaKey(m.ID, v.Name), v.ContentType
+	}
+
+	signed, err := s.storage.SignedURL(ctx, key, ttl)
+	if err == nil {
+		http.Redirect(w, r, signed, http.StatusFound)
+		return
+	}
+	if !errors.Is(err, storage.ErrNoSignedURL) {
+		fmt.Println("Error signing media URL:", err)
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	f, err := s.storage.Get(ctx, key)
+	if err != nil {
+		fmt.Println("Error opening media file:", err)
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", cacheControl)
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", m.CreatedAt, rs)
+		return
+	}
+	io.Copy(w, f)
+}
@@ -0,0 +1,351 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+	"chirpy/internal/media"
+	"chirpy/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+type mediaStore struct {
+	contractStore
+	media  map[uuid.UUID]database.Medium
+	events []database.OutboxEvent
+}
+
+type mediaTx struct {
+	*mediaStore
+}
+
+func (s *mediaStore) Begin(ctx context.Context) (Tx, error) {
+	return mediaTx{s}, nil
+}
+
+func (tx mediaTx) Commit() error   { return nil }
+func (tx mediaTx) Rollback() error { return nil }
+
+func (s *mediaStore) EnqueueOutboxEvent(ctx context.Context, arg database.EnqueueOutboxEventParams) error {
+	s.events = append(s.events, database.OutboxEvent{ID: arg.ID, Kind: arg.Kind, Payload: arg.Payload, Status: outboxEventStatusPending})
+	return nil
+}
+
+func (s *mediaStore) ClaimDueOutboxEvents(ctx context.Context, limit int32) ([]database.OutboxEvent, error) {
+	var due []database.OutboxEvent
+	for _, e := range s.events {
+		if e.Status == outboxEventStatusPending {
+			due = append(due, e)
+		}
+	}
+	return due, nil
+}
+
+func (s *mediaStore) MarkOutboxEventSent(ctx context.Context, id uuid.UUID) error {
+	for i := range s.events {
+		if s.events[i].ID == id {
+			s.events[i].Status = "sent"
+		}
+	}
+	return nil
+}
+
+func (s *mediaStore) AttachMedia(ctx context.Context, arg database.AttachMediaParams) (database.Medium, error) {
+	m, ok := s.media[arg.ID]
+	if !ok || m.UserID != arg.UserID || m.ChirpID.Valid {
+		return database.Medium{}, sql.ErrNoRows
+	}
+	m.ChirpID, m.Position, m.AltText = arg.ChirpID, arg.Position, arg.AltText
+	s.media[m.ID] = m
+	return m, nil
+}
+
+func (s *mediaStore) CreateMedia(ctx context.Context, arg database.CreateMediaParams) (database.Medium, error) {
+	m := database.Medium{
+		ID:          arg.ID,
+		CreatedAt:   testNow,
+		UserID:      arg.UserID,
+		ContentType: arg.ContentType,
+		Size:        arg.Size,
+		DurationMs:  arg.DurationMs,
+		Width:       arg.Width,
+		Height:      arg.Height,
+		Variants:    json.RawMessage("[]"),
+	}
+	s.media[m.ID] = m
+	return m, nil
+}
+
+func (s *mediaStore) GetMedia(ctx context.Context, id uuid.UUID) (database.Medium, error) {
+	m, ok := s.media[id]
+	if !ok {
+		return database.Medium{}, sql.ErrNoRows
+	}
+	return m, nil
+}
+
+func (s *mediaStore) ListChirpMedia(ctx context.Context, chirpID uuid.NullUUID) ([]database.Medium, error) {
+	var attached []database.Medium
+	for _, m := range s.media {
+		if m.ChirpID == chirpID {
+			attached = append(attached, m)
+		}
+	}
+	sort.Slice(attached, func(i, j int) bool { return attached[i].Position < attached[j].Position })
+	return attached, nil
+}
+
+func (s *mediaStore) SetMediaVariants(ctx context.Context, arg database.SetMediaVariantsParams) error {
+	m := s.media[arg.ID]
+	m.Variants = arg.Variants
+	s.media[m.ID] = m
+	return nil
+}
+
+// testMP4 builds the boxes of an MP4 file that CheckVideo reads.
+func testMP4(seconds uint32) []byte {
+	box := func(typ string, body []byte) []byte {
+		b := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+		return append(append(b, typ...), body...)
+	}
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:], seconds*1000)
+	var b []byte
+	b = append(b, box("ftyp", []byte("mp42\x00\x00\x00\x00isom"))...)
+	b = append(b, box("mdat", make([]byte, 64))...)
+	return append(b, box("moov", box("mvhd", mvhd))...)
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return img.Bytes()
+}
+
+// newMediaServer serves uploads kept in files, or a temporary directory
+// when it is nil. upload sends a file as the contract user.
+func newMediaServer(t *testing.T, cfg *config.Config, files storage.Storage) (s *Server, h http.Handler, store *mediaStore, upload func(contentType string, body []byte) *httptest.ResponseRecorder) {
+	user := newTestUser(t, "user@example.com", "pa55word")
+	user.ID = contractUserID
+	store = &mediaStore{
+		contractStore: contractStore{fakeStore{users: map[string]database.User{user.Email: user}}},
+		media:         map[uuid.UUID]database.Medium{},
+	}
+	cfg.JWTSecret = "test-secret"
+	cfg.StaticDir = t.TempDir()
+	if files == nil {
+		files = storage.Local{Dir: t.TempDir()}
+	}
+	s = NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow), Storage: files})
+	h = NewRouter(s)
+	token, err := auth.MakeJWT(user.ID, cfg.JWTSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	upload = func(contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/media", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	return s, h, store, upload
+}
+
+func TestMedia_UploadAndServe(t *testing.T) {
+	_, h, _, upload := newMediaServer(t, &config.Config{}, nil)
+
+	img := testPNG(t, 4, 4)
+	if rec := upload("image/png", img); rec.Code != http.StatusCreated {
+		t.Errorf("image: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := upload("image/jpeg", img); rec.Code != http.StatusBadRequest {
+		t.Errorf("PNG sent as JPEG: expected 400, got %d", rec.Code)
+	}
+	if rec := upload("video/mp4", testMP4(90)); rec.Code != http.StatusBadRequest {
+		t.Errorf("long video: expected 400, got %d", rec.Code)
+	}
+	if rec := upload("application/pdf", []byte("%PDF-1.4")); rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("PDF: expected 415, got %d", rec.Code)
+	}
+
+	video := testMP4(5)
+	rec := upload("video/mp4", video)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("video: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp mediaResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.DurationMs == nil || *resp.DurationMs != 5000 {
+		t.Errorf("expected a duration of 5000ms, got %v", resp.DurationMs)
+	}
+
+	if rec := do(h, http.MethodGet, "/media/"+resp.ID.String(), ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unattached media without a signature: expected 404, got %d", rec.Code)
+	}
+	if rec := do(h, http.MethodGet, strings.Replace(resp.URL, "expires=", "expires=9", 1), ""); rec.Code != http.StatusNotFound {
+		t.Errorf("extended expiry: expected 404, got %d", rec.Code)
+	}
+	expired := strconv.FormatInt(testNow.Add(-time.Minute).Unix(), 10)
+	sig := auth.SignValue(resp.ID.String()+"."+expired, mediaURLPurpose, "test-secret")
+	if rec := do(h, http.MethodGet, "/media/"+resp.ID.String()+"?expires="+expired+"&sig="+sig, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("expired link: expected 403, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, resp.URL, nil)
+	req.Header.Set("Range", "bytes=0-3")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("range: expected 206, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "video/mp4" {
+		t.Errorf("expected video/mp4, got %q", ct)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "private, max-age=3600" {
+		t.Errorf("expected a private cache until the link expires, got %q", cc)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), video[:4]) {
+		t.Errorf("expected the first 4 bytes, got %x", rec.Body.Bytes())
+	}
+	if rec := do(h, http.MethodGet, "/media/"+uuid.NewString(), ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown media: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestMedia_AttachWithAltText(t *testing.T) {
+	_, h, store, upload := newMediaServer(t, &config.Config{RequireAltText: true}, nil)
+	uploadID := func(contentType string, body []byte) string {
+		rec := upload(contentType, body)
+		var resp mediaResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("upload: %d %s", rec.Code, rec.Body)
+		}
+		return resp.ID.String()
+	}
+	img, video := uploadID("image/png", testPNG(t, 4, 4)), uploadID("video/mp4", testMP4(5))
+	someoneElses := uuid.New()
+	store.media[someoneElses] = database.Medium{ID: someoneElses, UserID: uuid.New(), ContentType: "image/png"}
+	create := func(media string) *httptest.ResponseRecorder {
+		return do(h, http.MethodPost, "/api/chirps", `{"body":"Look","user_id":"`+contractUserID.String()+`","media":[`+media+`]}`)
+	}
+
+	if rec := create(`{"id":"` + img + `","alt_text":"  "}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing alt text: expected 400, got %d", rec.Code)
+	}
+	if rec := create(`{"id":"` + someoneElses.String() + `","alt_text":"A cat"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("another user's media: expected 400, got %d", rec.Code)
+	}
+	rec := create(`{"id":"` + img + `","alt_text":"A grey square"},{"id":"` + video + `","alt_text":"A short clip"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp chirpResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Media) != 2 || resp.Media[0].AltText != "A grey square" || resp.Media[1].AltText != "A short clip" {
+		t.Fatalf("expected both attachments with alt text, got %+v", resp.Media)
+	}
+	if rec := create(`{"id":"` + img + `","alt_text":"Again"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("attaching twice: expected 400, got %d", rec.Code)
+	}
+
+	if rec := do(h, http.MethodGet, resp.Media[0].URL, ""); rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != immutableCacheControl {
+		t.Errorf("attached media: expected a public 200, got %d %q", rec.Code, rec.Header().Get("Cache-Control"))
+	}
+
+	rec = do(h, http.MethodGet, "/api/chirps/"+contractChirpID.String(), "")
+	if !strings.Contains(rec.Body.String(), `"alt_text":"A grey square"`) {
+		t.Errorf("expected the chirp to include its media, got %s", rec.Body)
+	}
+	rec = do(h, http.MethodGet, "/chirps/"+contractChirpID.String(), "")
+	if !strings.Contains(rec.Body.String(), `<img src="http://example.com/media/`+img+`" alt="A grey square">`) {
+		t.Errorf("expected the permalink to show the image with its alt text, got %s", rec.Body)
+	}
+}
+
+func TestMedia_Variants(t *testing.T) {
+	s, h, store, upload := newMediaServer(t, &config.Config{}, nil)
+
+	rec := upload("image/png", testPNG(t, 2000, 1000))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp mediaResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Width != 2000 || resp.Height != 1000 || len(resp.Variants) != 0 {
+		t.Errorf("before the relay runs: expected the original's size and no variants, got %+v", resp)
+	}
+	if len(store.events) != 1 || store.events[0].Kind != eventMediaVariants {
+		t.Fatalf("expected a variants event, got %+v", store.events)
+	}
+	if _, err := s.relayOutboxBatch(context.Background()); err != nil {
+		t.Fatalf("relayOutboxBatch returned error: %v", err)
+	}
+
+	resp = s.newMediaResponse(store.media[resp.ID])
+	if len(resp.Variants) != 2 || resp.Variants[0].Name != "thumb" || resp.Variants[0].Width != 320 || resp.Variants[0].Height != 160 {
+		t.Fatalf("expected thumb and medium variants, got %+v", resp.Variants)
+	}
+	if !strings.HasSuffix(resp.SrcSet, " 2000w") || !strings.Contains(resp.SrcSet, " 320w, ") {
+		t.Errorf("expected a srcset from narrowest to widest, got %q", resp.SrcSet)
+	}
+	rec = do(h, http.MethodGet, resp.Variants[0].URL, "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("thumb: expected a 200 PNG, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if w, h, err := media.Dimensions(rec.Body.Bytes()); err != nil || w != 320 || h != 160 {
+		t.Errorf("thumb: got %dx%d, %v", w, h, err)
+	}
+	if rec := do(h, http.MethodGet, strings.Replace(resp.Variants[0].URL, "/thumb", "/huge", 1), ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown variant: expected 404, got %d", rec.Code)
+	}
+}
+
+// signingStorage hands out links to a bucket in front of a directory.
+type signingStorage struct {
+	storage.Local
+}
+
+func (s signingStorage) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "https://bucket.example/" + key + "?expires=" + expiry.String(), nil
+}
+
+func TestMedia_SignedStorage(t *testing.T) {
+	_, h, _, upload := newMediaServer(t, &config.Config{}, signingStorage{storage.Local{Dir: t.TempDir()}})
+
+	rec := upload("image/png", testPNG(t, 4, 4))
+	var resp mediaResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("upload: %d %s", rec.Code, rec.Body)
+	}
+	rec = do(h, http.MethodGet, resp.URL, "")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://bucket.example/"+resp.ID.String()+"?expires=1h0m0s" {
+		t.Errorf("expected a redirect to the bucket for the rest of the link's life, got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"chirpy/internal/database"
+	"chirpy/internal/media"
+
+	"github.com/google/uuid"
+)
+
+// eventMediaVariants makes the smaller copies of an uploaded image.
+const eventMediaVariants = "media.variants"
+
+type mediaVariantsEvent struct {
+	MediaID uuid.UUID `json:"media_id"`
+}
+
+// mediaVariant is a smaller copy of an image, as listed in the variants
+// column of its media row.
+type mediaVariant struct {
+	Name        string `json:"name"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ContentType string `json:"content_type"`
+}
+
+type mediaVariantResponse struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+func mediaVariants(m database.Medium) []mediaVariant {
+	var variants []mediaVariant
+	if err := json.Unmarshal(m.Variants, &variants); err != nil {
+		return nil
+	}
+	return variants
+}
+
+func findMediaVariant(m database.Medium, name string) (mediaVariant, bool) {
+	for _, v := range mediaVariants(m) {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return mediaVariant{}, false
+}
+
+// mediaVariantResponses lists an image's variants, and a srcset of them
+// and the original from narrowest to widest.
+func (s *Server) mediaVariantResponses(m database.Medium) ([]mediaVariantResponse, string) {
+	var variants []mediaVariantResponse
+	for _, v := range mediaVariants(m) {
+		variants = append(variants, mediaVariantResponse{
+			Name:   v.Name,
+			URL:    s.mediaURL(m, v.Name),
+			Width:  v.Width,
+			Height: v.Height,
+		})
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].Width < variants[j].Width })
+
+	var srcset []string
+	for _, v := range variants {
+		srcset = append(srcset, fmt.Sprintf("%s %dw", v.URL, v.Width))
+	}
+	srcset = append(srcset, fmt.Sprintf("%s %dw", s.mediaURL(m, ""), m.Width.Int32))
+	return variants, strings.Join(srcset, ", ")
+}
+
+// createMedia records an upload and, for an image, an outbox event to
+// make its variants, so they are made even if the server restarts first.
+func (s *Server) createMedia(ctx context.Context, params database.CreateMediaParams) (database.Medium, error) {
+	if !params.Width.Valid {
+		return s.db.CreateMedia(ctx, params)
+	}
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return database.Medium{}, err
+	}
+	defer tx.Rollback()
+
+	m, err := tx.CreateMedia(ctx, params)
+	if err != nil {
+		return database.Medium{}, err
+	}
+	if err := recordEvent(ctx, tx, eventMediaVariants, mediaVariantsEvent{MediaID: m.ID}); err != nil {
+		return database.Medium{}, err
+	}
+	return m, tx.Commit()
+}
+
+// makeMediaVariants scales an uploaded image down to media.VariantSizes,
+// stores the copies beside it and lists them on its row. Until then
+// clients are given the original.
+func (s *Server) makeMediaVariants(ctx context.Context, tx Tx, ev mediaVariantsEvent) error {
+	if s.storage == nil {
+		// Uploads were turned off after the event was recorded.
+		return nil
+	}
+	m, err := tx.GetMedia(ctx, ev.MediaID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The upload went with its user.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	f, err := s.storage.Get(ctx, mediaKey(m.ID, ""))
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	made, err := media.MakeVariants(data, m.ContentType)
+	if err != nil {
+		return err
+	}
+
+	variants := []mediaVariant{}
+	for _, v := range made {
+		// The copy isn't listed until the row is updated, so a retry can
+		// overwrite it.
+		err := s.storage.Put(ctx, mediaKey(m.ID, v.Name), bytes.NewReader(v.Data), int64(len(v.Data)), v.ContentType)
+		if err != nil {
+			return err
+		}
+		variants = append(variants, mediaVariant{
+			Name:        v.Name,
+			Width:       v.Width,
+			Height:      v.Height,
+			ContentType: v.ContentType,
+		})
+	}
+	list, err := json.Marshal(variants)
+	if err != nil {
+		return err
+	}
+	return tx.SetMediaVariants(ctx, database.SetMediaVariantsParams{ID: m.ID, Variants: list})
+}
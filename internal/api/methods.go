@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// routeMethods are the methods routes are registered with. The mux serves
+// HEAD with a route's GET handler, and OPTIONS is answered for every route.
+var routeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// allowedMethods lists the methods mux has a route for at r's path, in the
+// form of an Allow header. It's empty when nothing is routed there.
+func allowedMethods(mux *http.ServeMux, r *http.Request) string {
+	var allowed []string
+	for _, method := range routeMethods {
+		probe := &http.Request{Method: method, URL: r.URL, Host: r.Host, Header: r.Header}
+		if _, pattern := mux.Handler(probe); pattern != "" {
+			allowed = append(allowed, method)
+		}
+	}
+	if len(allowed) == 0 {
+		return ""
+	}
+	return strings.Join(append(allowed, http.MethodOptions), ", ")
+}
+
+// middlewareMethods answers OPTIONS from the registered routes, and makes
+// HEAD responses carry the Content-Length the GET response would have.
+func (s *Server) middlewareMethods(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodOptions:
+			allow := allowedMethods(mux, r)
+			if allow == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Allow", allow)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodHead:
+			hw := &headWriter{ResponseWriter: w}
+			next.ServeHTTP(hw, r)
+			hw.finish()
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// headWriter discards a HEAD response's body, counting it so the headers
+// can say how long it would have been. The headers are held back until the
+// handler returns.
+type headWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (hw *headWriter) WriteHeader(code int) {
+	if hw.status == 0 {
+		hw.status = code
+	}
+}
+
+func (hw *headWriter) Write(p []byte) (int, error) {
+	if hw.status == 0 {
+		hw.status = http.StatusOK
+	}
+	hw.written += int64(len(p))
+	return len(p), nil
+}
+
+func (hw *headWriter) Unwrap() http.ResponseWriter {
+	return hw.ResponseWriter
+}
+
+func (hw *headWriter) finish() {
+	if hw.status == 0 {
+		hw.status = http.StatusOK
+	}
+	h := hw.ResponseWriter.Header()
+	if h.Get("Content-Length") == "" && hw.status >= 200 && hw.status != http.StatusNoContent && hw.status != http.StatusNotModified {
+		h.Set("Content-Length", strconv.FormatInt(hw.written, 10))
+	}
+	hw.ResponseWriter.WriteHeader(hw.status)
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"chirpy/internal/config"
+)
+
+func TestHeadAndOptions(t *testing.T) {
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: &contractStore{}, Clock: fixedClock(testNow)}))
+	chirp := "/api/chirps/" + contractChirpID.String()
+
+	get := do(h, http.MethodGet, chirp, "")
+	head := do(h, http.MethodHead, chirp, "")
+	if head.Code != http.StatusOK || head.Body.Len() != 0 {
+		t.Fatalf("HEAD: expected 200 without a body, got %d with %d bytes", head.Code, head.Body.Len())
+	}
+	if got, want := head.Header().Get("Content-Length"), strconv.Itoa(get.Body.Len()); got != want {
+		t.Errorf("HEAD Content-Length = %q, want %s", got, want)
+	}
+	if head.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("HEAD should carry GET's headers, got %v", head.Header())
+	}
+
+	for _, tc := range []struct {
+		path  string
+		code  int
+		allow string
+	}{
+		{chirp, http.StatusNoContent, "GET, HEAD, PUT, DELETE, OPTIONS"},
+		{"/api/chirps", http.StatusNoContent, "GET, HEAD, POST, OPTIONS"},
+		{"/api/login", http.StatusNoContent, "POST, OPTIONS"},
+		{"/api/nowhere", http.StatusNotFound, ""},
+	} {
+		rec := do(h, http.MethodOptions, tc.path, "")
+		if rec.Code != tc.code || rec.Header().Get("Allow") != tc.allow {
+			t.Errorf("OPTIONS %s: got %d Allow %q, want %d Allow %q", tc.path, rec.Code, rec.Header().Get("Allow"), tc.code, tc.allow)
+		}
+	}
+}
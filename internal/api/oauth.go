@@ -0,0 +1,591 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// The scopes a third-party app can ask for. Tokens issued at /api/login
+// have no scopes and may do anything.
+const (
+	scopeRead  = "read"
+	scopeWrite = "write"
+)
+
+// scopeDescriptions is what the consent screen says each scope allows.
+var scopeDescriptions = map[string]string{
+	scopeRead:  "See chirps, profiles and lists, including your own",
+	scopeWrite: "Post, edit and delete chirps, react, and change your lists and preferences",
+}
+
+// oauthCodeTTL is how long an authorization code can be exchanged for.
+// The app exchanges it straight after the redirect.
+const oauthCodeTTL = 5 * time.Minute
+
+// oauthFirstPartyPrefixes are the paths app tokens can't reach at all:
+// administration, the OAuth endpoints themselves, app registration and
+// billing.
+var oauthFirstPartyPrefixes = []string{"/admin/", "/oauth/", appsPath, billingPath}
+
+// oauthFirstPartyOnly are routes app tokens may not call whatever their
+// scopes: they take over or hand on the account itself.
+var oauthFirstPartyOnly = []string{
+	"PUT /api/users/me/handle",
+	"GET /api/users/me/merges",
+	"POST /api/users/me/merges/{mergeID}/confirm",
+}
+
+// parseScopes splits a space-separated scope parameter. Unknown scopes are
+// an error; repeated ones are dropped.
+func parseScopes(raw string) ([]string, error) {
+	var scopes []string
+	for _, scope := range strings.Fields(raw) {
+		if _, ok := scopeDescriptions[scope]; !ok {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// validRedirectURI reports whether codes may be sent to raw: https, http
+// to the loopback interface for desktop apps, or a private-use scheme like
+// com.example.app for mobile apps (RFC 8252). Fragments aren't allowed.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Fragment != "" || u.Opaque != "" {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		host := u.Hostname()
+		if host == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	default:
+		return strings.Contains(u.Scheme, ".")
+	}
+}
+
+type oauthClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+}
+
+// maxOAuthClientNameLength is how long an app's name, shown on the
+// consent screen, may be.
+const maxOAuthClientNameLength = 100
+
+func (req *oauthClientRequest) validate() string {
+	req.Name = strings.TrimSpace(req.Name)
+	switch {
+	case req.Name == "":
+		return "An app needs a name"
+	case len(req.Name) > maxOAuthClientNameLength:
+		return fmt.Sprintf("App names can be at most %d characters", maxOAuthClientNameLength)
+	case len(req.RedirectURIs) == 0:
+		return "At least one redirect URI is required"
+	}
+	for _, uri := range req.RedirectURIs {
+		if !validRedirectURI(uri) {
+			return "Invalid redirect URI: " + uri
+		}
+	}
+	scopes, err := parseScopes(strings.Join(req.Scopes, " "))
+	if err != nil || len(scopes) == 0 {
+		return "Scopes must be one or more of read and write"
+	}
+	req.Scopes = scopes
+	return ""
+}
+
+type oauthClientResponse struct {
+	ID           uuid.UUID `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	CreatedAt    Timestamp `json:"created_at"`
+	// OwnerID is the developer who registered the app at /api/apps.
+	OwnerID *uuid.UUID `json:"owner_id,omitempty"`
+	// DailyQuota is the app's own quota, if an admin set one.
+	DailyQuota *int32 `json:"daily_quota,omitempty"`
+}
+
+func newOAuthClientResponse(c database.OauthClient) oauthClientResponse {
+	resp := oauthClientResponse{
+		ID:           c.ID,
+		Name:         c.Name,
+		RedirectURIs: c.RedirectUris,
+		Scopes:       c.Scopes,
+		CreatedAt:    Timestamp{c.CreatedAt},
+	}
+	if c.OwnerID.Valid {
+		resp.OwnerID = &c.OwnerID.UUID
+	}
+	if c.DailyQuota.Valid {
+		resp.DailyQuota = &c.DailyQuota.Int32
+	}
+	return resp
+}
+
+// handlerAdminOAuthClientsCreate registers a third-party app. Apps
+// registered by admins are public clients: they have no secret, and prove
+// they started the flow with PKCE alone.
+func (s *Server) handlerAdminOAuthClientsCreate(w http.ResponseWriter, r *http.Request) {
+	var req oauthClientRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		jsonResponse(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := r.Context()
+	admin := adminFromContext(ctx)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	client, err := tx.CreateOAuthClient(ctx, database.CreateOAuthClientParams{
+		ID:           uuid.New(),
+		Name:         req.Name,
+		RedirectUris: req.RedirectURIs,
+		Scopes:       req.Scopes,
+	})
+	if err != nil {
+		fmt.Println("Error creating OAuth client:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := recordAudit(ctx, tx, admin.ID, "oauth_client.create", client.ID, req); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusCreated, newOAuthClientResponse(client))
+}
+
+func (s *Server) handlerAdminOAuthClientsList(w http.ResponseWriter, r *http.Request) {
+	clients, err := s.db.ListOAuthClients(r.Context())
+	if err != nil {
+		fmt.Println("Error listing OAuth clients:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	resp := make([]oauthClientResponse, 0, len(clients))
+	for _, c := range clients {
+		resp = append(resp, newOAuthClientResponse(c))
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+// authorizeRequest is a checked request to /oauth/authorize.
+type authorizeRequest struct {
+	client      database.OauthClient
+	redirectURI string
+	scopes      []string
+	state       string
+	challenge   string
+}
+
+// authorizeError is why an authorization request can't go ahead. Until
+// the client and redirect URI are known to be good the user is shown the
+// error; after that it goes back to the app.
+type authorizeError struct {
+	code        string
+	description string
+	redirect    bool
+}
+
+// authorizeParams are the query parameters of /oauth/authorize, carried
+// through the consent form.
+var authorizeParams = []string{"response_type", "client_id", "redirect_uri", "scope", "state", "code_challenge", "code_challenge_method"}
+
+// parseAuthorizeRequest checks the parameters of an authorization request.
+// The redirect URI must be one the client registered, exactly.
+func (s *Server) parseAuthorizeRequest(r *http.Request, params url.Values) (authorizeRequest, *authorizeError) {
+	clientID, err := uuid.Parse(params.Get("client_id"))
+	if err != nil {
+		return authorizeRequest{}, &authorizeError{code: "invalid_request", description: "Unknown app"}
+	}
+	client, err := s.db.GetOAuthClient(r.Context(), clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return authorizeRequest{}, &authorizeError{code: "invalid_request", description: "Unknown app"}
+	}
+	if err != nil {
+		fmt.Println("Error getting OAuth client:", err)
+		return authorizeRequest{}, &authorizeError{code: "server_error", description: "Something went wrong"}
+	}
+	req := authorizeRequest{client: client, redirectURI: params.Get("redirect_uri"), state: params.Get("state")}
+	if !slices.Contains(client.RedirectUris, req.redirectURI) {
+		return authorizeRequest{}, &authorizeError{code: "invalid_request", description: "The app sent you to an address it didn't register"}
+	}
+
+	if params.Get("response_type") != "code" {
+		return req, &authorizeError{code: "unsupported_response_type", description: "Only response_type=code is supported", redirect: true}
+	}
+	scope := params.Get("scope")
+	if scope == "" {
+		scope = scopeRead
+	}
+	req.scopes, err = parseScopes(scope)
+	if err != nil {
+		return req, &authorizeError{code: "invalid_scope", description: err.Error(), redirect: true}
+	}
+	for _, scope := range req.scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return req, &authorizeError{code: "invalid_scope", description: "The app may not ask for " + scope, redirect: true}
+		}
+	}
+	req.challenge = params.Get("code_challenge")
+	if params.Get("code_challenge_method") != "S256" || len(req.challenge) != base64.RawURLEncoding.EncodedLen(sha256.Size) {
+		return req, &authorizeError{code: "invalid_request", description: "A code_challenge with code_challenge_method=S256 is required", redirect: true}
+	}
+	return req, nil
+}
+
+// redirectToApp sends the user back to the app's redirect URI with params
+// added to whatever query it already has.
+func redirectToApp(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "Invalid redirect URI", http.StatusBadRequest)
+		return
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusSeeOther)
+}
+
+// authorizeFailed answers an authorization request that can't go ahead.
+func authorizeFailed(w http.ResponseWriter, r *http.Request, req authorizeRequest, e *authorizeError) {
+	if !e.redirect {
+		code := http.StatusBadRequest
+		if e.code == "server_error" {
+			code = http.StatusInternalServerError
+		}
+		http.Error(w, e.description, code)
+		return
+	}
+	params := url.Values{"error": {e.code}, "error_description": {e.description}}
+	if req.state != "" {
+		params.Set("state", req.state)
+	}
+	redirectToApp(w, r, req.redirectURI, params)
+}
+
+// renderConsent shows the user what the app is asking for, and asks them
+// to sign in to allow it.
+func (s *Server) renderConsent(w http.ResponseWriter, r *http.Request, req authorizeRequest, params url.Values, email, errMsg string) {
+	hidden := make(map[string]string, len(authorizeParams))
+	for _, name := range authorizeParams {
+		if v := params.Get(name); v != "" {
+			hidden[name] = v
+		}
+	}
+	scopes := make([]string, 0, len(req.scopes))
+	for _, scope := range req.scopes {
+		scopes = append(scopes, scopeDescriptions[scope])
+	}
+
+	// The page takes a password, so it mustn't be framed or kept.
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.Header().Set("Cache-Control", "no-store")
+	s.renderPage(w, "oauth_consent.html", map[string]interface{}{
+		"ClientName": req.client.Name,
+		"Scopes":     scopes,
+		"Action":     r.URL.Path,
+		"Params":     hidden,
+		"Email":      email,
+		"Error":      errMsg,
+	})
+}
+
+// handlerOAuthAuthorize shows the consent screen of the authorization code
+// flow.
+func (s *Server) handlerOAuthAuthorize(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	req, authErr := s.parseAuthorizeRequest(r, params)
+	if authErr != nil {
+		authorizeFailed(w, r, req, authErr)
+		return
+	}
+	s.renderConsent(w, r, req, params, "", "")
+}
+
+// handlerOAuthAuthorizeDecide takes the user's answer from the consent
+// screen. Allowing needs their email and password, entered here rather
+// than in the app, and sends a single-use code back to the app.
+func (s *Server) handlerOAuthAuthorizeDecide(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	params := r.PostForm
+	req, authErr := s.parseAuthorizeRequest(r, params)
+	if authErr != nil {
+		authorizeFailed(w, r, req, authErr)
+		return
+	}
+	if params.Get("decision") != "allow" {
+		authorizeFailed(w, r, req, &authorizeError{code: "access_denied", description: "The user denied the request", redirect: true})
+		return
+	}
+
+	email := params.Get("email")
+	user, code, msg := s.checkCredentials(r, email, params.Get("password"))
+	if code != 0 {
+		s.renderConsent(w, r, req, params, email, msg)
+		return
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		fmt.Println("Error generating authorization code:", err)
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	authCode := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := s.clock.Now().UTC()
+	if err := s.db.DeleteExpiredOAuthCodes(r.Context(), now); err != nil {
+		fmt.Println("Error deleting expired authorization codes:", err)
+	}
+	err := s.db.CreateOAuthCode(r.Context(), database.CreateOAuthCodeParams{
+		CodeHash:      hashOAuthSecret(authCode),
+		ClientID:      req.client.ID,
+		UserID:        user.ID,
+		RedirectUri:   req.redirectURI,
+		Scopes:        req.scopes,
+		CodeChallenge: req.challenge,
+		ExpiresAt:     now.Add(oauthCodeTTL),
+	})
+	if err != nil {
+		fmt.Println("Error creating authorization code:", err)
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	resp := url.Values{"code": {authCode}}
+	if req.state != "" {
+		resp.Set("state", req.state)
+	}
+	redirectToApp(w, r, req.redirectURI, resp)
+}
+
+// hashOAuthSecret is how authorization codes and client secrets are
+// stored, so the tables alone can't be used to get tokens.
+func hashOAuthSecret(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// oauthError is an RFC 6749 error response.
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// authenticateOAuthClient identifies the client calling the token
+// endpoint, by HTTP Basic auth or the client_id and client_secret form
+// fields. Clients registered with a secret must present one that hasn't
+// been rotated out; public clients only name themselves.
+func (s *Server) authenticateOAuthClient(w http.ResponseWriter, r *http.Request) (database.OauthClient, bool) {
+	rawID, secret, basic := r.BasicAuth()
+	if !basic {
+		rawID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	invalid := func(description string) (database.OauthClient, bool) {
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+		}
+		jsonResponse(w, http.StatusUnauthorized, oauthError{Error: "invalid_client", Description: description})
+		return database.OauthClient{}, false
+	}
+
+	clientID, err := uuid.Parse(rawID)
+	if err != nil {
+		return invalid("Unknown client_id")
+	}
+	client, err := s.db.GetOAuthClient(r.Context(), clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return invalid("Unknown client_id")
+	}
+	if err != nil {
+		fmt.Println("Error getting OAuth client:", err)
+		jsonResponse(w, http.StatusInternalServerError, oauthError{Error: "server_error"})
+		return database.OauthClient{}, false
+	}
+
+	secrets, err := s.db.ListActiveOAuthClientSecrets(r.Context(), database.ListActiveOAuthClientSecretsParams{
+		ClientID:  client.ID,
+		ExpiresAt: sql.NullTime{Time: s.clock.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		fmt.Println("Error listing OAuth client secrets:", err)
+		jsonResponse(w, http.StatusInternalServerError, oauthError{Error: "server_error"})
+		return database.OauthClient{}, false
+	}
+	if len(secrets) == 0 && !client.OwnerID.Valid {
+		return client, true
+	}
+	hash := hashOAuthSecret(secret)
+	for _, cs := range secrets {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(cs.SecretHash)) == 1 {
+			return client, true
+		}
+	}
+	return invalid("Invalid client_secret")
+}
+
+// handlerOAuthToken exchanges an authorization code for an access token.
+// The app proves it started the flow with the PKCE code_verifier whose
+// S256 hash it sent to /oauth/authorize.
+func (s *Server) handlerOAuthToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if err := r.ParseForm(); err != nil {
+		jsonResponse(w, http.StatusBadRequest, oauthError{Error: "invalid_request", Description: "Invalid request body"})
+		return
+	}
+	form := r.PostForm
+	if form.Get("grant_type") != "authorization_code" {
+		jsonResponse(w, http.StatusBadRequest, oauthError{Error: "unsupported_grant_type", Description: "Only grant_type=authorization_code is supported"})
+		return
+	}
+	authCode, redirectURI, verifier := form.Get("code"), form.Get("redirect_uri"), form.Get("code_verifier")
+	if authCode == "" || redirectURI == "" || len(verifier) < 43 || len(verifier) > 128 {
+		jsonResponse(w, http.StatusBadRequest, oauthError{Error: "invalid_request", Description: "code, redirect_uri and a code_verifier of 43 to 128 characters are required"})
+		return
+	}
+	client, ok := s.authenticateOAuthClient(w, r)
+	if !ok {
+		return
+	}
+
+	// The code is used up whether or not the rest checks out, so a stolen
+	// code can't be tried twice.
+	code, err := s.db.ClaimOAuthCode(r.Context(), hashOAuthSecret(authCode))
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusBadRequest, oauthError{Error: "invalid_grant", Description: "The code is invalid or has been used"})
+		return
+	}
+	if err != nil {
+		fmt.Println("Error claiming authorization code:", err)
+		jsonResponse(w, http.StatusInternalServerError, oauthError{Error: "server_error"})
+		return
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	switch {
+	case code.ClientID != client.ID, code.RedirectUri != redirectURI:
+		jsonResponse(w, http.StatusBadRequest, oauthError{Error: "invalid_grant", Description: "The code was issued to another client or redirect_uri"})
+		return
+	case !code.ExpiresAt.After(s.clock.Now()):
+		jsonResponse(w, http.StatusBadRequest, oauthError{Error: "invalid_grant", Description: "The code has expired"})
+		return
+	case subtle.ConstantTimeCompare([]byte(challenge), []byte(code.CodeChallenge)) != 1:
+		jsonResponse(w, http.StatusBadRequest, oauthError{Error: "invalid_grant", Description: "The code_verifier doesn't match the code_challenge"})
+		return
+	}
+
+	token, err := s.tokenIssuer(r.Context()).IssueForClient(code.UserID, client.ID.String(), code.Scopes, AccessTokenTTL)
+	if err != nil {
+		fmt.Println("Error issuing app token:", err)
+		jsonResponse(w, http.StatusInternalServerError, oauthError{Error: "server_error"})
+		return
+	}
+	err = s.db.CreateOAuthToken(r.Context(), database.CreateOAuthTokenParams{
+		ID:        uuid.New(),
+		ClientID:  client.ID,
+		UserID:    code.UserID,
+		Scopes:    code.Scopes,
+		ExpiresAt: s.clock.Now().UTC().Add(AccessTokenTTL),
+	})
+	if err != nil {
+		fmt.Println("Error recording app token:", err)
+		jsonResponse(w, http.StatusInternalServerError, oauthError{Error: "server_error"})
+		return
+	}
+	jsonResponse(w, http.StatusOK, oauthTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(AccessTokenTTL / time.Second),
+		Scope:       strings.Join(code.Scopes, " "),
+	})
+}
+
+// middlewareOAuthScopes keeps app tokens to their scopes: read for GET and
+// HEAD (and for a batch, whose subrequests are checked one by one), write
+// for everything else. App tokens can't reach oauthFirstPartyPrefixes or
+// the routes in oauthFirstPartyOnly at all.
+func (s *Server) middlewareOAuthScopes(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := s.authenticateClaims(r)
+		if err != nil || claims.ClientID == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		_, pattern := mux.Handler(r)
+		firstParty := slices.ContainsFunc(oauthFirstPartyPrefixes, func(prefix string) bool {
+			return strings.HasPrefix(r.URL.Path, prefix)
+		})
+		if firstParty || slices.Contains(oauthFirstPartyOnly, pattern) {
+			jsonResponse(w, http.StatusForbidden, errorResponse{Error: "Apps can't use this endpoint"})
+			return
+		}
+		needed := scopeWrite
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || pattern == "POST "+batchPath {
+			needed = scopeRead
+		}
+		if !slices.Contains(claims.Scopes, needed) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, needed))
+			jsonResponse(w, http.StatusForbidden, errorResponse{Error: "The token lacks the " + needed + " scope"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
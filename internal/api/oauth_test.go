@@ -0,0 +1,371 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type oauthStore struct {
+	fakeStore
+	clients map[uuid.UUID]database.OauthClient
+	codes   map[string]database.OauthAuthorizationCode
+	secrets []database.OauthClientSecret
+	tokens  []database.OauthToken
+	usage   map[uuid.UUID]map[time.Time]*database.OauthClientUsage
+}
+
+type oauthTx struct {
+	*oauthStore
+}
+
+func (s *oauthStore) Begin(ctx context.Context) (Tx, error) {
+	return oauthTx{s}, nil
+}
+
+func (tx oauthTx) Commit() error   { return nil }
+func (tx oauthTx) Rollback() error { return nil }
+
+func (s *oauthStore) CreateOAuthClient(ctx context.Context, arg database.CreateOAuthClientParams) (database.OauthClient, error) {
+	c := database.OauthClient{
+		ID:           arg.ID,
+		Name:         arg.Name,
+		RedirectUris: arg.RedirectUris,
+		Scopes:       arg.Scopes,
+		CreatedAt:    testNow,
+		OwnerID:      arg.OwnerID,
+	}
+	s.clients[c.ID] = c
+	return c, nil
+}
+
+func (s *oauthStore) ListOAuthClientsByOwner(ctx context.Context, ownerID uuid.NullUUID) ([]database.OauthClient, error) {
+	var clients []database.OauthClient
+	for _, c := range s.clients {
+		if c.OwnerID == ownerID {
+			clients = append(clients, c)
+		}
+	}
+	return clients, nil
+}
+
+func (s *oauthStore) CreateOAuthClientSecret(ctx context.Context, arg database.CreateOAuthClientSecretParams) error {
+	s.secrets = append(s.secrets, database.OauthClientSecret{ID: arg.ID, ClientID: arg.ClientID, SecretHash: arg.SecretHash, CreatedAt: testNow})
+	return nil
+}
+
+func (s *oauthStore) ExpireOAuthClientSecrets(ctx context.Context, arg database.ExpireOAuthClientSecretsParams) error {
+	for i, cs := range s.secrets {
+		if cs.ClientID == arg.ClientID && (!cs.ExpiresAt.Valid || cs.ExpiresAt.Time.After(arg.ExpiresAt.Time)) {
+			s.secrets[i].ExpiresAt = arg.ExpiresAt
+		}
+	}
+	return nil
+}
+
+func (s *oauthStore) ListActiveOAuthClientSecrets(ctx context.Context, arg database.ListActiveOAuthClientSecretsParams) ([]database.OauthClientSecret, error) {
+	var secrets []database.OauthClientSecret
+	for _, cs := range s.secrets {
+		if cs.ClientID == arg.ClientID && (!cs.ExpiresAt.Valid || cs.ExpiresAt.Time.After(arg.ExpiresAt.Time)) {
+			secrets = append(secrets, cs)
+		}
+	}
+	return secrets, nil
+}
+
+func (s *oauthStore) CreateOAuthToken(ctx context.Context, arg database.CreateOAuthTokenParams) error {
+	s.tokens = append(s.tokens, database.OauthToken{ID: arg.ID, ClientID: arg.ClientID, UserID: arg.UserID, Scopes: arg.Scopes, CreatedAt: testNow, ExpiresAt: arg.ExpiresAt})
+	return nil
+}
+
+func (s *oauthStore) ListOAuthTokens(ctx context.Context, arg database.ListOAuthTokensParams) ([]database.OauthToken, error) {
+	var tokens []database.OauthToken
+	for _, t := range s.tokens {
+		if t.ClientID == arg.ClientID {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens, nil
+}
+
+func (s *oauthStore) GetOAuthClient(ctx context.Context, id uuid.UUID) (database.OauthClient, error) {
+	c, ok := s.clients[id]
+	if !ok {
+		return database.OauthClient{}, sql.ErrNoRows
+	}
+	return c, nil
+}
+
+func (s *oauthStore) DeleteExpiredOAuthCodes(ctx context.Context, expiresAt time.Time) error {
+	return nil
+}
+
+func (s *oauthStore) CreateOAuthCode(ctx context.Context, arg database.CreateOAuthCodeParams) error {
+	s.codes[arg.CodeHash] = database.OauthAuthorizationCode{
+		CodeHash:      arg.CodeHash,
+		ClientID:      arg.ClientID,
+		UserID:        arg.UserID,
+		RedirectUri:   arg.RedirectUri,
+		Scopes:        arg.Scopes,
+		CodeChallenge: arg.CodeChallenge,
+		ExpiresAt:     arg.ExpiresAt,
+	}
+	return nil
+}
+
+func (s *oauthStore) ClaimOAuthCode(ctx context.Context, codeHash string) (database.OauthAuthorizationCode, error) {
+	c, ok := s.codes[codeHash]
+	if !ok || c.UsedAt.Valid {
+		return database.OauthAuthorizationCode{}, sql.ErrNoRows
+	}
+	c.UsedAt = sql.NullTime{Time: testNow, Valid: true}
+	s.codes[codeHash] = c
+	return c, nil
+}
+
+func TestOAuthAuthorizationCodeFlow(t *testing.T) {
+	user := newTestUser(t, "saul@example.com", "04234")
+	client := database.OauthClient{
+		ID:           uuid.New(),
+		Name:         "Chirpdeck",
+		RedirectUris: []string{"https://chirpdeck.example/callback?v=2"},
+		Scopes:       []string{scopeRead, scopeWrite},
+	}
+	store := &oauthStore{
+		fakeStore: fakeStore{users: map[string]database.User{user.Email: user}},
+		clients:   map[uuid.UUID]database.OauthClient{client.ID: client},
+		codes:     map[string]database.OauthAuthorizationCode{},
+	}
+	cfg := &config.Config{JWTSecret: "test-secret", IntrospectionKey: "sidecar-key"}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)}))
+
+	verifier := strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ID.String()},
+		"redirect_uri":          {client.RedirectUris[0]},
+		"scope":                 {scopeRead},
+		"state":                 {"xyz"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	decide := func(extra url.Values) *httptest.ResponseRecorder {
+		form := url.Values{}
+		for k, v := range params {
+			form[k] = v
+		}
+		for k, v := range extra {
+			form[k] = v
+		}
+		return post("/oauth/authorize", form)
+	}
+	redirected := func(rec *httptest.ResponseRecorder) url.Values {
+		t.Helper()
+		if rec.Code != http.StatusSeeOther {
+			t.Fatalf("expected a 303 back to the app, got %d: %s", rec.Code, rec.Body)
+		}
+		loc, err := url.Parse(rec.Header().Get("Location"))
+		if err != nil || !strings.HasPrefix(loc.String(), "https://chirpdeck.example/callback?") {
+			t.Fatalf("unexpected redirect %q", rec.Header().Get("Location"))
+		}
+		q := loc.Query()
+		if q.Get("v") != "2" || q.Get("state") != "xyz" {
+			t.Fatalf("redirect %q dropped the query or state", loc)
+		}
+		return q
+	}
+
+	rec := do(h, http.MethodGet, "/oauth/authorize?"+params.Encode(), "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Chirpdeck") || rec.Header().Get("X-Frame-Options") != "DENY" {
+		t.Fatalf("expected an unframeable consent screen, got %d: %s", rec.Code, rec.Body)
+	}
+
+	bad := url.Values{}
+	for k, v := range params {
+		bad[k] = v
+	}
+	bad.Set("redirect_uri", "https://evil.example/callback")
+	if rec := do(h, http.MethodGet, "/oauth/authorize?"+bad.Encode(), ""); rec.Code != http.StatusBadRequest || rec.Header().Get("Location") != "" {
+		t.Fatalf("expected an unregistered redirect_uri to be refused without a redirect, got %d", rec.Code)
+	}
+
+	if q := redirected(decide(url.Values{"decision": {"deny"}})); q.Get("error") != "access_denied" || q.Get("code") != "" {
+		t.Fatalf("expected access_denied, got %v", q)
+	}
+	if rec := decide(url.Values{"decision": {"allow"}, "email": {user.Email}, "password": {"wrong"}}); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Incorrect email or password") {
+		t.Fatalf("expected the consent screen again for a wrong password, got %d", rec.Code)
+	}
+
+	code := redirected(decide(url.Values{"decision": {"allow"}, "email": {user.Email}, "password": {"04234"}})).Get("code")
+	if code == "" {
+		t.Fatal("expected a code")
+	}
+	exchange := func(verifier string) *httptest.ResponseRecorder {
+		return post("/oauth/token", url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {client.RedirectUris[0]},
+			"client_id":     {client.ID.String()},
+			"code_verifier": {verifier},
+		})
+	}
+
+	rec = exchange(verifier)
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("expected an uncacheable 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var tok oauthTokenResponse
+	if err := json.NewDecoder(rec.Body).Decode(&tok); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if tok.TokenType != "Bearer" || tok.Scope != scopeRead || tok.AccessToken == "" {
+		t.Fatalf("unexpected token response %+v", tok)
+	}
+
+	if rec := exchange(verifier); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_grant") {
+		t.Fatalf("expected a reused code to be refused, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// A read token can read but not write, and never reaches first-party
+	// only routes.
+	authed := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+tok.AccessToken)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := authed(http.MethodGet, "/api/users/"+user.ID.String()); rec.Code == http.StatusForbidden {
+		t.Fatalf("expected a read token to read, got %d: %s", rec.Code, rec.Body)
+	}
+	rec = authed(http.MethodDelete, "/api/chirps/"+uuid.NewString())
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Header().Get("WWW-Authenticate"), "insufficient_scope") {
+		t.Fatalf("expected insufficient_scope for a write, got %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+	if rec := authed(http.MethodGet, "/api/users/me/merges"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected first-party only routes to be refused, got %d", rec.Code)
+	}
+
+	form := url.Values{"token": {tok.AccessToken}}
+	req := httptest.NewRequest(http.MethodPost, "/api/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("sidecar", "sidecar-key")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var introspected introspectionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&introspected); err != nil {
+		t.Fatalf("decoding introspection: %v", err)
+	}
+	if !introspected.Active || introspected.ClientID != client.ID.String() || introspected.Scope != scopeRead {
+		t.Fatalf("unexpected introspection %+v", introspected)
+	}
+}
+
+func TestOAuthToken_WrongVerifier(t *testing.T) {
+	clientID := uuid.New()
+	sum := sha256.Sum256([]byte(strings.Repeat("a", 43)))
+	store := &oauthStore{
+		clients: map[uuid.UUID]database.OauthClient{clientID: {ID: clientID, RedirectUris: []string{"https://app.example/cb"}}},
+		codes: map[string]database.OauthAuthorizationCode{
+			hashOAuthSecret("the-code"): {
+				ClientID:      clientID,
+				UserID:        uuid.New(),
+				RedirectUri:   "https://app.example/cb",
+				Scopes:        []string{scopeRead},
+				CodeChallenge: base64.RawURLEncoding.EncodeToString(sum[:]),
+				ExpiresAt:     testNow.Add(time.Minute),
+			},
+		},
+	}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store, Clock: fixedClock(testNow)}))
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {"the-code"},
+		"redirect_uri":  {"https://app.example/cb"},
+		"client_id":     {clientID.String()},
+		"code_verifier": {strings.Repeat("b", 43)},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_grant") {
+		t.Fatalf("expected invalid_grant, got %d: %s", rec.Code, rec.Body)
+	}
+	if !store.codes[hashOAuthSecret("the-code")].UsedAt.Valid {
+		t.Fatal("expected the code to be used up by the failed attempt")
+	}
+}
+
+func TestValidRedirectURI(t *testing.T) {
+	tests := map[string]bool{
+		"https://app.example/callback":   true,
+		"http://127.0.0.1:8123/callback": true,
+		"http://localhost/callback":      true,
+		"com.example.app:/callback":      true,
+		"http://app.example/callback":    false,
+		"https://app.example/cb#frag":    false,
+		"javascript:alert(1)":            false,
+		"not a url":                      false,
+	}
+	for uri, want := range tests {
+		if got := validRedirectURI(uri); got != want {
+			t.Errorf("validRedirectURI(%q) = %v, want %v", uri, got, want)
+		}
+	}
+}
+
+func (s *oauthStore) RecordOAuthClientRequest(ctx context.Context, arg database.RecordOAuthClientRequestParams) (database.RecordOAuthClientRequestRow, error) {
+	if s.usage == nil {
+		s.usage = map[uuid.UUID]map[time.Time]*database.OauthClientUsage{}
+	}
+	if s.usage[arg.ClientID] == nil {
+		s.usage[arg.ClientID] = map[time.Time]*database.OauthClientUsage{}
+	}
+	u := s.usage[arg.ClientID][arg.Day]
+	if u == nil {
+		u = &database.OauthClientUsage{ClientID: arg.ClientID, Day: arg.Day}
+		s.usage[arg.ClientID][arg.Day] = u
+	}
+	u.Requests++
+	return database.RecordOAuthClientRequestRow{Requests: u.Requests, DailyQuota: s.clients[arg.ClientID].DailyQuota}, nil
+}
+
+func (s *oauthStore) RecordOAuthClientRejection(ctx context.Context, arg database.RecordOAuthClientRejectionParams) error {
+	u := s.usage[arg.ClientID][arg.Day]
+	u.Requests--
+	u.Rejected++
+	return nil
+}
+
+func (s *oauthStore) ListOAuthClientUsage(ctx context.Context, arg database.ListOAuthClientUsageParams) ([]database.OauthClientUsage, error) {
+	var rows []database.OauthClientUsage
+	for day, u := range s.usage[arg.ClientID] {
+		if !day.Before(arg.Since) {
+			rows = append(rows, *u)
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	oembedDefaultWidth = 550
+	oembedCacheAge     = 3600
+)
+
+type oembedResponse struct {
+	Version      string `json:"version"`
+	Type         string `json:"type"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	AuthorName   string `json:"author_name"`
+	AuthorURL    string `json:"author_url"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       *int   `json:"height"`
+	CacheAge     int    `json:"cache_age"`
+}
+
+// oembedTemplate renders the embed snippet. html/template escapes the chirp
+// body and names, so the snippet is safe to drop into a third-party page.
+var oembedTemplate = template.Must(template.New("oembed").Parse(
+	`<blockquote class="chirpy-embed" data-chirp-id="{{.ID}}"><p>{{.Body}}</p>&mdash; {{.Author}} <a href="{{.Permalink}}">{{.Date}}</a></blockquote>`,
+))
+
+// chirpIDFromPermalink extracts the chirp ID from a /chirps/{id} URL on
+// this server.
+func (s *Server) chirpIDFromPermalink(r *http.Request, raw string) (uuid.UUID, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return uuid.Nil, false
+	}
+	host := s.publicHost()
+	if host == "" {
+		host = r.Host
+	}
+	if !strings.EqualFold(u.Host, host) {
+		return uuid.Nil, false
+	}
+
+	id, ok := strings.CutPrefix(u.Path, "/chirps/")
+	if !ok {
+		return uuid.Nil, false
+	}
+	chirpID, err := uuid.Parse(strings.TrimSuffix(id, "/"))
+	return chirpID, err == nil
+}
+
+// handlerOEmbed implements the oEmbed provider endpoint for chirp
+// permalinks. Only the JSON format is supported.
+func (s *Server) handlerOEmbed(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if format := query.Get("format"); format != "" && format != "json" {
+		http.Error(w, "Only the json format is supported", http.StatusNotImplemented)
+		return
+	}
+
+	chirpID, ok := s.chirpIDFromPermalink(r, query.Get("url"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	chirp, err := s.db.GetVisibleChirp(ctx, database.GetVisibleChirpParams{ID: chirpID})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	author, err := s.db.GetUserByID(ctx, chirp.UserID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	base := s.baseURL(r)
+	authorName := preferredUsername(author)
+	authorURL := s.appURL(base, "profile/@"+authorName)
+	permalink := base + "/chirps/" + chirp.ID.String()
+
+	var snippet bytes.Buffer
+	err = oembedTemplate.Execute(&snippet, map[string]string{
+		"ID":        chirp.ID.String(),
+		"Body":      chirp.Body,
+		"Author":    "@" + authorName,
+		"Permalink": permalink,
+		"Date":      chirp.CreatedAt.UTC().Format("January 2, 2006"),
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	width := oembedDefaultWidth
+	if maxWidth, err := strconv.Atoi(query.Get("maxwidth")); err == nil && maxWidth > 0 && maxWidth < width {
+		width = maxWidth
+	}
+
+	jsonResponse(w, http.StatusOK, oembedResponse{
+		Version:      "1.0",
+		Type:         "rich",
+		ProviderName: siteName(ctx),
+		ProviderURL:  base,
+		AuthorName:   authorName,
+		AuthorURL:    authorURL,
+		HTML:         snippet.String(),
+		Width:        width,
+		CacheAge:     oembedCacheAge,
+	})
+}
@@ -0,0 +1,639 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Organization roles. Owners manage the members and can delete the
+// organization's chirps; posters can only post as it.
+const (
+	orgRoleOwner  = "owner"
+	orgRolePoster = "poster"
+)
+
+const (
+	maxOrgNameLength = 64
+	// orgInviteTTL is how long an invited user has to accept.
+	orgInviteTTL      = 14 * 24 * time.Hour
+	orgChirpsPageSize = 50
+)
+
+func validOrgRole(role string) bool {
+	return role == orgRoleOwner || role == orgRolePoster
+}
+
+type orgResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Handle    string    `json:"handle"`
+	Name      string    `json:"name"`
+	CreatedAt Timestamp `json:"created_at"`
+	// Role is the caller's, on responses listing their own organizations.
+	Role string `json:"role,omitempty"`
+}
+
+type orgMemberResponse struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Handle   string    `json:"handle,omitempty"`
+	Role     string    `json:"role"`
+	JoinedAt Timestamp `json:"joined_at"`
+}
+
+type orgInviteResponse struct {
+	ID        uuid.UUID `json:"id"`
+	OrgID     uuid.UUID `json:"org_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Role      string    `json:"role"`
+	CreatedAt Timestamp `json:"created_at"`
+	ExpiresAt Timestamp `json:"expires_at"`
+}
+
+func newOrgInviteResponse(inv database.OrganizationInvite) orgInviteResponse {
+	return orgInviteResponse{
+		ID:        inv.ID,
+		OrgID:     inv.OrgID,
+		UserID:    inv.UserID,
+		Role:      inv.Role,
+		CreatedAt: Timestamp{inv.CreatedAt},
+		ExpiresAt: Timestamp{inv.ExpiresAt},
+	}
+}
+
+// orgRole returns userID's role in the organization orgID, or "" if they
+// aren't a member or orgID isn't an organization.
+func (s *Server) orgRole(ctx context.Context, q database.Querier, orgID, userID uuid.UUID) (string, error) {
+	role, err := q.GetOrganizationRole(ctx, database.GetOrganizationRoleParams{
+		OrgID:  orgID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+// requireOrgRole authenticates the caller and checks they hold one of roles
+// in the organization in the {orgID} path parameter. Non-members get a 404,
+// so membership isn't revealed.
+func (s *Server) requireOrgRole(w http.ResponseWriter, r *http.Request, roles ...string) (orgID, userID uuid.UUID, role string, ok bool) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return uuid.Nil, uuid.Nil, "", false
+	}
+	orgID, ok = pathUUID(w, r, "orgID")
+	if !ok {
+		return uuid.Nil, uuid.Nil, "", false
+	}
+	role, err = s.orgRole(r.Context(), s.db, orgID, userID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return uuid.Nil, uuid.Nil, "", false
+	}
+	if role == "" {
+		jsonResponse(w, http.StatusNotFound, "Organization was not found.")
+		return uuid.Nil, uuid.Nil, "", false
+	}
+	if !slices.Contains(roles, role) {
+		jsonResponse(w, http.StatusForbidden, "Only the organization's owners can do that")
+		return uuid.Nil, uuid.Nil, "", false
+	}
+	return orgID, userID, role, true
+}
+
+type orgRequest struct {
+	Handle string `json:"handle"`
+	Name   string `json:"name"`
+}
+
+// handlerOrgsCreate creates an organization with the caller as its owner.
+// The organization is an account of its own, holding the handle, that
+// nobody can sign in to.
+func (s *Server) handlerOrgsCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var req orgRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	handle, ok := normalizeHandle(req.Handle)
+	if !ok {
+		jsonResponse(w, http.StatusBadRequest, "Handle must be 3-30 letters, digits or underscores")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	switch {
+	case req.Name == "":
+		jsonResponse(w, http.StatusBadRequest, "An organization needs a name")
+		return
+	case len(req.Name) > maxOrgNameLength:
+		jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("Organization names can be at most %d characters", maxOrgNameLength))
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	// Without a password hash the account can't be signed in to. The
+	// handle is set separately so old handles still reserved by others
+	// are respected.
+	id := uuid.New()
+	_, err = tx.CreateUser(ctx, database.CreateUserParams{
+		ID:    id,
+		Email: fmt.Sprintf("org-%s@invalid", id),
+	})
+	if err != nil {
+		fmt.Println("Error creating organization account:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	account, err := tx.ChangeUserHandle(ctx, database.ChangeUserHandleParams{
+		Handle: nullString(handle),
+		ID:     id,
+	})
+	var pqErr *pq.Error
+	if errors.Is(err, sql.ErrNoRows) || (errors.As(err, &pqErr) && pqErr.Constraint == "users_handle_key") {
+		jsonResponse(w, http.StatusConflict, "Handle is already taken")
+		return
+	}
+	if err != nil {
+		fmt.Println("Error setting organization handle:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	org, err := tx.CreateOrganization(ctx, database.CreateOrganizationParams{
+		UserID:    id,
+		Name:      req.Name,
+		CreatedBy: uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		fmt.Println("Error creating organization:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	err = tx.AddOrganizationMember(ctx, database.AddOrganizationMemberParams{
+		OrgID:  id,
+		UserID: userID,
+		Role:   orgRoleOwner,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	s.typeaheadCache.invalidate()
+	jsonResponse(w, http.StatusCreated, orgResponse{
+		ID:        org.UserID,
+		Handle:    account.Handle.String,
+		Name:      org.Name,
+		CreatedAt: Timestamp{org.CreatedAt},
+		Role:      orgRoleOwner,
+	})
+}
+
+func (s *Server) handlerOrgsGet(w http.ResponseWriter, r *http.Request) {
+	orgID, ok := pathUUID(w, r, "orgID")
+	if !ok {
+		return
+	}
+	org, err := s.db.GetOrganization(r.Context(), orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "Organization was not found.")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, orgResponse{
+		ID:        org.UserID,
+		Handle:    org.Handle.String,
+		Name:      org.Name,
+		CreatedAt: Timestamp{org.CreatedAt},
+	})
+}
+
+// handlerOrgsMine lists the organizations the caller belongs to, with
+// their role in each.
+func (s *Server) handlerOrgsMine(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	orgs, err := s.db.ListUserOrganizations(r.Context(), userID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	resp := make([]orgResponse, 0, len(orgs))
+	for _, o := range orgs {
+		resp = append(resp, orgResponse{
+			ID:     o.UserID,
+			Handle: o.Handle.String,
+			Name:   o.Name,
+			Role:   o.Role,
+		})
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+func (s *Server) handlerOrgMembersList(w http.ResponseWriter, r *http.Request) {
+	orgID, _, _, ok := s.requireOrgRole(w, r, orgRoleOwner, orgRolePoster)
+	if !ok {
+		return
+	}
+	members, err := s.db.ListOrganizationMembers(r.Context(), orgID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	resp := make([]orgMemberResponse, 0, len(members))
+	for _, m := range members {
+		resp = append(resp, orgMemberResponse{
+			UserID:   m.UserID,
+			Handle:   m.Handle.String,
+			Role:     m.Role,
+			JoinedAt: Timestamp{m.JoinedAt},
+		})
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+type orgInviteRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+	Role   string    `json:"role"`
+}
+
+// handlerOrgInvitesCreate invites a user to the organization. Nothing
+// changes until they accept; inviting them again replaces the invite.
+func (s *Server) handlerOrgInvitesCreate(w http.ResponseWriter, r *http.Request) {
+	orgID, userID, _, ok := s.requireOrgRole(w, r, orgRoleOwner)
+	if !ok {
+		return
+	}
+	var req orgInviteRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	if req.Role == "" {
+		req.Role = orgRolePoster
+	}
+	if !validOrgRole(req.Role) {
+		jsonResponse(w, http.StatusBadRequest, "role must be owner or poster")
+		return
+	}
+	if req.UserID == orgID {
+		jsonResponse(w, http.StatusBadRequest, "An organization can't be its own member")
+		return
+	}
+
+	ctx := r.Context()
+	invitee, err := s.db.GetUserByID(ctx, req.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "User was not found.")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if invitee.BannedAt.Valid {
+		jsonResponse(w, http.StatusConflict, "Banned users can't be invited")
+		return
+	}
+	if role, err := s.orgRole(ctx, s.db, orgID, invitee.ID); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	} else if role != "" {
+		jsonResponse(w, http.StatusConflict, "User is already a member; change their role instead")
+		return
+	}
+
+	invite, err := s.db.CreateOrganizationInvite(ctx, database.CreateOrganizationInviteParams{
+		ID:        uuid.New(),
+		OrgID:     orgID,
+		UserID:    invitee.ID,
+		Role:      req.Role,
+		InvitedBy: uuid.NullUUID{UUID: userID, Valid: true},
+		ExpiresAt: s.clock.Now().UTC().Add(orgInviteTTL),
+	})
+	if err != nil {
+		fmt.Println("Error creating organization invite:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusCreated, newOrgInviteResponse(invite))
+}
+
+// handlerOrgInvitesMine lists the caller's pending invites.
+func (s *Server) handlerOrgInvitesMine(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	invites, err := s.db.ListUserOrganizationInvites(r.Context(), database.ListUserOrganizationInvitesParams{
+		UserID:    userID,
+		ExpiresAt: s.clock.Now().UTC(),
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	resp := make([]orgInviteResponse, 0, len(invites))
+	for _, inv := range invites {
+		resp = append(resp, newOrgInviteResponse(inv))
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+// handlerOrgInviteAccept makes the caller a member with the role they were
+// invited with.
+func (s *Server) handlerOrgInviteAccept(w http.ResponseWriter, r *http.Request) {
+	userID, actorID, err := s.authenticateWithActor(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Couldn't validate token")
+		return
+	}
+	if actorID != uuid.Nil {
+		jsonResponse(w, http.StatusForbidden, "Impersonation tokens can't accept invites")
+		return
+	}
+	inviteID, ok := pathUUID(w, r, "inviteID")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	invite, err := tx.ClaimOrganizationInvite(ctx, database.ClaimOrganizationInviteParams{
+		ID:     inviteID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "Invite was not found.")
+		return
+	}
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if !invite.ExpiresAt.After(s.clock.Now().UTC()) {
+		jsonResponse(w, http.StatusGone, "This invite has expired")
+		return
+	}
+	err = tx.AddOrganizationMember(ctx, database.AddOrganizationMemberParams{
+		OrgID:  invite.OrgID,
+		UserID: userID,
+		Role:   invite.Role,
+	})
+	if err != nil {
+		fmt.Println("Error adding organization member:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type orgRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// handlerOrgMemberRoleSet changes a member's role. An organization always
+// keeps at least one owner.
+func (s *Server) handlerOrgMemberRoleSet(w http.ResponseWriter, r *http.Request) {
+	orgID, _, _, ok := s.requireOrgRole(w, r, orgRoleOwner)
+	if !ok {
+		return
+	}
+	memberID, ok := pathUUID(w, r, "userID")
+	if !ok {
+		return
+	}
+	var req orgRoleRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	if !validOrgRole(req.Role) {
+		jsonResponse(w, http.StatusBadRequest, "role must be owner or poster")
+		return
+	}
+
+	s.changeOrgMembership(w, r, orgID, memberID, func(ctx context.Context, tx Tx) (int64, error) {
+		return tx.SetOrganizationRole(ctx, database.SetOrganizationRoleParams{
+			OrgID:  orgID,
+			UserID: memberID,
+			Role:   req.Role,
+		})
+	}, req.Role != orgRoleOwner)
+}
+
+// handlerOrgMemberRemove removes a member. Owners can remove anyone;
+// members can remove themselves. The last owner can't leave.
+func (s *Server) handlerOrgMemberRemove(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	orgID, ok := pathUUID(w, r, "orgID")
+	if !ok {
+		return
+	}
+	memberID, ok := pathUUID(w, r, "userID")
+	if !ok {
+		return
+	}
+	if memberID != userID {
+		if orgID, _, _, ok = s.requireOrgRole(w, r, orgRoleOwner); !ok {
+			return
+		}
+	}
+
+	s.changeOrgMembership(w, r, orgID, memberID, func(ctx context.Context, tx Tx) (int64, error) {
+		return tx.RemoveOrganizationMember(ctx, database.RemoveOrganizationMemberParams{
+			OrgID:  orgID,
+			UserID: memberID,
+		})
+	}, true)
+}
+
+// changeOrgMembership runs change on memberID's membership in a
+// transaction. When demotesOwner, it refuses to take away the last owner.
+func (s *Server) changeOrgMembership(w http.ResponseWriter, r *http.Request, orgID, memberID uuid.UUID, change func(context.Context, Tx) (int64, error), demotesOwner bool) {
+	ctx := r.Context()
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	if demotesOwner {
+		owners, err := tx.ListOrganizationOwnersForUpdate(ctx, orgID)
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		if len(owners) == 1 && owners[0] == memberID {
+			jsonResponse(w, http.StatusConflict, "An organization needs at least one owner")
+			return
+		}
+	}
+	changed, err := change(ctx, tx)
+	if err != nil {
+		fmt.Println("Error changing organization membership:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if changed == 0 {
+		jsonResponse(w, http.StatusNotFound, "User isn't a member of this organization")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerOrgChirpsCreate posts a chirp as the organization. Everyone sees
+// the organization as its author; the posting member is recorded for the
+// other members, and returned here as posted_by.
+func (s *Server) handlerOrgChirpsCreate(w http.ResponseWriter, r *http.Request) {
+	orgID, userID, _, ok := s.requireOrgRole(w, r, orgRoleOwner, orgRolePoster)
+	if !ok {
+		return
+	}
+	var req struct {
+		Body           string `json:"body"`
+		Sensitive      bool   `json:"sensitive"`
+		ContentWarning string `json:"content_warning"`
+	}
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	cw, err := newContentWarning(req.Sensitive, req.ContentWarning)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, "Content warning is too long")
+		return
+	}
+
+	ctx := r.Context()
+	chirp, author, err := s.createChirp(ctx, orgID, req.Body)
+	switch {
+	case errors.Is(err, errChirpTooLong):
+		jsonResponse(w, http.StatusBadRequest, "Chirp is too long")
+		return
+	case errors.Is(err, errChirpBlocked):
+		jsonResponse(w, http.StatusBadRequest, "Chirp contains blocked content")
+		return
+	case err != nil:
+		fmt.Println("Error creating chirp:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	postedBy := uuid.NullUUID{UUID: userID, Valid: true}
+	err = s.db.RecordOrganizationChirp(ctx, database.RecordOrganizationChirpParams{
+		ChirpID:  chirp.ID,
+		OrgID:    orgID,
+		PostedBy: postedBy,
+	})
+	if err != nil {
+		fmt.Println("Error recording organization chirp:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if !s.markSensitive(ctx, chirp.ID, cw) {
+		cw = contentWarning{}
+	}
+
+	jsonResponse(w, http.StatusCreated, chirpResponse{
+		ID:             chirp.ID,
+		CreatedAt:      Timestamp{chirp.CreatedAt},
+		UpdatedAt:      Timestamp{chirp.UpdatedAt},
+		Body:           chirp.Body,
+		UserID:         chirp.UserID,
+		AuthorVerified: author.Verified,
+		ShortURL:       s.shortURL(ctx, chirp.ID),
+		Sensitive:      cw.Sensitive,
+		ContentWarning: cw.Warning,
+		PostedBy:       &userID,
+	})
+}
+
+// handlerOrgChirpsList shows members the organization's recent chirps with
+// who posted each.
+func (s *Server) handlerOrgChirpsList(w http.ResponseWriter, r *http.Request) {
+	orgID, _, _, ok := s.requireOrgRole(w, r, orgRoleOwner, orgRolePoster)
+	if !ok {
+		return
+	}
+	rows, err := s.db.ListOrganizationChirps(r.Context(), database.ListOrganizationChirpsParams{
+		OrgID:    orgID,
+		RowLimit: orgChirpsPageSize,
+	})
+	if err != nil {
+		fmt.Println("Error listing organization chirps:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	resp := make([]chirpResponse, 0, len(rows))
+	for _, row := range rows {
+		c := chirpResponse{
+			ID:        row.ID,
+			CreatedAt: Timestamp{row.CreatedAt},
+			UpdatedAt: Timestamp{row.UpdatedAt},
+			Body:      row.Body,
+			UserID:    row.UserID,
+			ShortURL:  s.shortURL(r.Context(), row.ID),
+		}
+		if row.PostedBy.Valid {
+			c.PostedBy = &row.PostedBy.UUID
+		}
+		resp = append(resp, c)
+	}
+	if err := s.withChirpDetails(r.Context(), resp); err != nil {
+		fmt.Println("Error loading organization chirp details:", err)
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
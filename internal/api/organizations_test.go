@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// orgStore keeps one organization's members and invites in memory. The
+// organization is contractUserID, so contractStore's chirps are its own.
+type orgStore struct {
+	contractStore
+	roles   map[uuid.UUID]string
+	invites map[uuid.UUID]database.OrganizationInvite
+	posted  []database.RecordOrganizationChirpParams
+}
+
+type orgTx struct {
+	*orgStore
+}
+
+func (s *orgStore) Begin(ctx context.Context) (Tx, error) {
+	return orgTx{s}, nil
+}
+
+func (tx orgTx) Commit() error   { return nil }
+func (tx orgTx) Rollback() error { return nil }
+
+func (s *orgStore) GetOrganizationRole(ctx context.Context, arg database.GetOrganizationRoleParams) (string, error) {
+	role, ok := s.roles[arg.UserID]
+	if arg.OrgID != contractUserID || !ok {
+		return "", sql.ErrNoRows
+	}
+	return role, nil
+}
+
+func (s *orgStore) CreateOrganizationInvite(ctx context.Context, arg database.CreateOrganizationInviteParams) (database.OrganizationInvite, error) {
+	inv := database.OrganizationInvite{
+		ID:        arg.ID,
+		OrgID:     arg.OrgID,
+		UserID:    arg.UserID,
+		Role:      arg.Role,
+		InvitedBy: arg.InvitedBy,
+		CreatedAt: testNow,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	s.invites[inv.ID] = inv
+	return inv, nil
+}
+
+func (s *orgStore) ClaimOrganizationInvite(ctx context.Context, arg database.ClaimOrganizationInviteParams) (database.OrganizationInvite, error) {
+	inv, ok := s.invites[arg.ID]
+	if !ok || inv.UserID != arg.UserID {
+		return database.OrganizationInvite{}, sql.ErrNoRows
+	}
+	delete(s.invites, arg.ID)
+	return inv, nil
+}
+
+func (s *orgStore) AddOrganizationMember(ctx context.Context, arg database.AddOrganizationMemberParams) error {
+	s.roles[arg.UserID] = arg.Role
+	return nil
+}
+
+func (s *orgStore) ListOrganizationOwnersForUpdate(ctx context.Context, orgID uuid.UUID) ([]uuid.UUID, error) {
+	var owners []uuid.UUID
+	for id, role := range s.roles {
+		if role == orgRoleOwner {
+			owners = append(owners, id)
+		}
+	}
+	return owners, nil
+}
+
+func (s *orgStore) SetOrganizationRole(ctx context.Context, arg database.SetOrganizationRoleParams) (int64, error) {
+	if _, ok := s.roles[arg.UserID]; !ok {
+		return 0, nil
+	}
+	s.roles[arg.UserID] = arg.Role
+	return 1, nil
+}
+
+func (s *orgStore) RemoveOrganizationMember(ctx context.Context, arg database.RemoveOrganizationMemberParams) (int64, error) {
+	if _, ok := s.roles[arg.UserID]; !ok {
+		return 0, nil
+	}
+	delete(s.roles, arg.UserID)
+	return 1, nil
+}
+
+func (s *orgStore) RecordOrganizationChirp(ctx context.Context, arg database.RecordOrganizationChirpParams) error {
+	s.posted = append(s.posted, arg)
+	return nil
+}
+
+func TestOrganizations(t *testing.T) {
+	owner := newTestUser(t, "owner@example.com", "04234")
+	poster := newTestUser(t, "poster@example.com", "04234")
+	outsider := newTestUser(t, "outsider@example.com", "04234")
+	store := &orgStore{
+		contractStore: contractStore{fakeStore{users: map[string]database.User{
+			owner.Email: owner, poster.Email: poster, outsider.Email: outsider,
+		}}},
+		roles:   map[uuid.UUID]string{owner.ID: orgRoleOwner},
+		invites: map[uuid.UUID]database.OrganizationInvite{},
+	}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store, Clock: fixedClock(testNow)}))
+	send := func(user database.User, method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		token, err := auth.MakeJWT(user.ID, "test-secret", time.Hour)
+		if err != nil {
+			t.Fatalf("MakeJWT returned error: %v", err)
+		}
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	org := "/api/orgs/" + contractUserID.String()
+
+	if rec := send(outsider, http.MethodPost, org+"/chirps", `{"body":"hi"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("non-members posting: expected 404, got %d", rec.Code)
+	}
+	if rec := send(owner, http.MethodPost, org+"/invites", `{"user_id":"`+poster.ID.String()+`","role":"admin"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("inviting with an unknown role: expected 400, got %d", rec.Code)
+	}
+	rec := send(owner, http.MethodPost, org+"/invites", `{"user_id":"`+poster.ID.String()+`"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("inviting: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var invite orgInviteResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &invite); err != nil {
+		t.Fatal(err)
+	}
+	if invite.Role != orgRolePoster {
+		t.Errorf("invites should default to poster, got %q", invite.Role)
+	}
+	if rec := send(outsider, http.MethodPost, "/api/users/me/org-invites/"+invite.ID.String()+"/accept", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("accepting someone else's invite: expected 404, got %d", rec.Code)
+	}
+	if rec := send(poster, http.MethodPost, "/api/users/me/org-invites/"+invite.ID.String()+"/accept", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("accepting: expected 204, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = send(poster, http.MethodPost, org+"/chirps", `{"body":"Hello from the team"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("posting as the organization: expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var chirp chirpResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &chirp); err != nil {
+		t.Fatal(err)
+	}
+	if chirp.UserID != contractUserID || chirp.PostedBy == nil || *chirp.PostedBy != poster.ID {
+		t.Errorf("expected the organization as author and the poster as posted_by, got %+v", chirp)
+	}
+	if len(store.posted) != 1 || store.posted[0].PostedBy.UUID != poster.ID {
+		t.Errorf("expected the posting member to be recorded, got %+v", store.posted)
+	}
+
+	for _, step := range []struct {
+		name   string
+		user   database.User
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"posters can't invite", poster, http.MethodPost, org + "/invites", `{"user_id":"` + outsider.ID.String() + `"}`, http.StatusForbidden},
+		{"posters can't change roles", poster, http.MethodPut, org + "/members/" + poster.ID.String(), `{"role":"owner"}`, http.StatusForbidden},
+		{"the last owner can't step down", owner, http.MethodPut, org + "/members/" + owner.ID.String(), `{"role":"poster"}`, http.StatusConflict},
+		{"the last owner can't leave", owner, http.MethodDelete, org + "/members/" + owner.ID.String(), "", http.StatusConflict},
+		{"owners promote members", owner, http.MethodPut, org + "/members/" + poster.ID.String(), `{"role":"owner"}`, http.StatusNoContent},
+		{"with another owner, owners can leave", owner, http.MethodDelete, org + "/members/" + owner.ID.String(), "", http.StatusNoContent},
+		{"removing a non-member", poster, http.MethodDelete, org + "/members/" + outsider.ID.String(), "", http.StatusNotFound},
+	} {
+		if rec := send(step.user, step.method, step.path, step.body); rec.Code != step.want {
+			t.Fatalf("%s: expected %d, got %d: %s", step.name, step.want, rec.Code, rec.Body)
+		}
+	}
+	if len(store.roles) != 1 || store.roles[poster.ID] != orgRoleOwner {
+		t.Errorf("expected the poster to be the only owner, got %v", store.roles)
+	}
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"chirpy/internal/activitypub"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	outboxEventStatusPending = "pending"
+	outboxEventStatusDead    = "dead"
+
+	outboxEventBatchSize    = 20
+	outboxEventPollInterval = 2 * time.Second
+	// maxOutboxEventAttempts is how many times an event is tried before it
+	// is dead-lettered. With the backoff below that spans most of a day.
+	maxOutboxEventAttempts = 12
+	outboxEventBaseBackoff = 30 * time.Second
+	outboxEventMaxBackoff  = 6 * time.Hour
+)
+
+// Outbox event kinds.
+const (
+	// eventChirpCreated fans a new chirp out to its author's remote
+	// followers, as one delivery event per inbox.
+	eventChirpCreated = "chirp.created"
+	// eventActivityDelivery signs and posts an activity to one inbox.
+	eventActivityDelivery = "activity.deliver"
+)
+
+type chirpCreatedEvent struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type activityDeliveryEvent struct {
+	UserID   uuid.UUID       `json:"user_id"`
+	Inbox    string          `json:"inbox"`
+	Activity json.RawMessage `json:"activity"`
+}
+
+// recordEvent adds an event to the outbox through q. Pass the transaction
+// that makes the write the event is about, so the event exists exactly
+// when the write does.
+func recordEvent(ctx context.Context, q database.Querier, kind string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return q.EnqueueOutboxEvent(ctx, database.EnqueueOutboxEventParams{
+		ID:      uuid.New(),
+		Kind:    kind,
+		Payload: data,
+	})
+}
+
+// recordChirpCreated records that chirp should be federated.
+func recordChirpCreated(ctx context.Context, q database.Querier, chirp database.Chirp) error {
+	return recordEvent(ctx, q, eventChirpCreated, chirpCreatedEvent{
+		ChirpID:   chirp.ID,
+		UserID:    chirp.UserID,
+		Body:      chirp.Body,
+		CreatedAt: chirp.CreatedAt,
+	})
+}
+
+// recordDelivery records that userID's activity should be delivered to
+// inbox.
+func recordDelivery(ctx context.Context, q database.Querier, userID uuid.UUID, inbox string, activity interface{}) error {
+	data, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	return recordEvent(ctx, q, eventActivityDelivery, activityDeliveryEvent{
+		UserID:   userID,
+		Inbox:    inbox,
+		Activity: data,
+	})
+}
+
+func outboxEventBackoff(attempts int32) time.Duration {
+	d := outboxEventBaseBackoff
+	for i := int32(1); i < attempts && d < outboxEventMaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, outboxEventMaxBackoff)
+}
+
+// runOutboxRelay publishes due outbox events until ctx is done. Like the
+// email worker, it claims rows with SKIP LOCKED so servers don't publish
+// the same event twice at once. An event is marked sent in the
+// transaction that claimed it, so a crash after publishing but before the
+// commit publishes it again: delivery is at least once, and receivers
+// drop repeats by activity ID.
+func (s *Server) runOutboxRelay(ctx context.Context) {
+	ticker := time.NewTicker(outboxEventPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := s.relayOutboxBatch(ctx)
+			if err != nil {
+				fmt.Println("Error relaying outbox events:", err)
+				break
+			}
+			if n < outboxEventBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) relayOutboxBatch(ctx context.Context) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	events, err := tx.ClaimDueOutboxEvents(ctx, outboxEventBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range events {
+		// A failed query aborts the transaction, so the update below fails
+		// too and the whole batch is retried.
+		pubErr := s.publishEvent(ctx, tx, e)
+		if pubErr == nil {
+			err = tx.MarkOutboxEventSent(ctx, e.ID)
+		} else {
+			attempts := e.Attempts + 1
+			status := outboxEventStatusPending
+			if attempts >= maxOutboxEventAttempts {
+				status = outboxEventStatusDead
+			}
+			err = tx.MarkOutboxEventFailed(ctx, database.MarkOutboxEventFailedParams{
+				ID:            e.ID,
+				Status:        status,
+				LastError:     pubErr.Error(),
+				NextAttemptAt: s.clock.Now().UTC().Add(outboxEventBackoff(attempts)),
+			})
+			if err == nil && status == outboxEventStatusDead {
+				err = recordDeadLetter(ctx, tx, deadLetterOutboxEvent, e.ID.String(), e.Kind, attempts, pubErr.Error())
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(events), tx.Commit()
+}
+
+// publishEvent carries out one event. Events it derives from e go into tx,
+// so they commit along with e being marked sent.
+func (s *Server) publishEvent(ctx context.Context, tx Tx, e database.OutboxEvent) error {
+	switch e.Kind {
+	case eventChirpCreated:
+		var ev chirpCreatedEvent
+		if err := json.Unmarshal(e.Payload, &ev); err != nil {
+			return err
+		}
+		return s.fanOutChirp(ctx, tx, ev)
+	case eventActivityDelivery:
+		var ev activityDeliveryEvent
+		if err := json.Unmarshal(e.Payload, &ev); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(ctx, federationTaskTimeout)
+		defer cancel()
+		key, err := s.signingKey(ctx, ev.UserID)
+		if err != nil {
+			return err
+		}
+		keyID := s.actorURI(ev.UserID) + "#main-key"
+		return activitypub.Deliver(ctx, s.federationClient, ev.Inbox, ev.Activity, keyID, key)
+	case eventLinkScan:
+		var ev linkScanEvent
+		if err := json.Unmarshal(e.Payload, &ev); err != nil {
+			return err
+		}
+		return s.scanChirpLinks(ctx, tx, ev)
+	case eventMediaVariants:
+		var ev mediaVariantsEvent
+		if err := json.Unmarshal(e.Payload, &ev); err != nil {
+			return err
+		}
+		return s.makeMediaVariants(ctx, tx, ev)
+	default:
+		return fmt.Errorf("unknown outbox event kind %q", e.Kind)
+	}
+}
+
+// fanOutChirp records a delivery of the chirp's Create activity to every
+// remote server with followers of its author. Each inbox is retried on its
+// own, so one server being down doesn't hold up the rest.
+func (s *Server) fanOutChirp(ctx context.Context, tx Tx, ev chirpCreatedEvent) error {
+	inboxes, err := tx.ListRemoteFollowerInboxes(ctx, ev.UserID)
+	if err != nil {
+		return err
+	}
+	if len(inboxes) == 0 {
+		return nil
+	}
+
+	activity, err := s.newCreateActivity(database.Chirp{
+		ID:        ev.ChirpID,
+		CreatedAt: ev.CreatedAt,
+		UpdatedAt: ev.CreatedAt,
+		Body:      ev.Body,
+		UserID:    ev.UserID,
+	})
+	if err != nil {
+		return err
+	}
+	activity.Context = activitypub.DefaultContext()
+
+	for _, inbox := range inboxes {
+		if err := recordDelivery(ctx, tx, ev.UserID, inbox, activity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"chirpy/internal/activitypub"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// outboxStore keeps chirps and outbox events in memory. Writes made in a
+// transaction only land on Commit.
+type outboxStore struct {
+	fakeStore
+	key        database.ActorKey
+	inboxes    []string
+	chirps     []database.Chirp
+	events     []database.OutboxEvent
+	enqueueErr error
+}
+
+type outboxTx struct {
+	*outboxStore
+	writes []func()
+}
+
+func (s *outboxStore) Begin(ctx context.Context) (Tx, error) {
+	return &outboxTx{outboxStore: s}, nil
+}
+
+func (s *outboxStore) GetActorKey(ctx context.Context, userID uuid.UUID) (database.ActorKey, error) {
+	return s.key, nil
+}
+
+func (s *outboxStore) ListRemoteFollowerInboxes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return s.inboxes, nil
+}
+
+func (s *outboxStore) ClaimDueOutboxEvents(ctx context.Context, limit int32) ([]database.OutboxEvent, error) {
+	var due []database.OutboxEvent
+	for _, e := range s.events {
+		if e.Status == outboxEventStatusPending && e.Attempts == 0 {
+			due = append(due, e)
+		}
+	}
+	return due, nil
+}
+
+func (s *outboxStore) event(id uuid.UUID) *database.OutboxEvent {
+	for i := range s.events {
+		if s.events[i].ID == id {
+			return &s.events[i]
+		}
+	}
+	return nil
+}
+
+func (tx *outboxTx) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.Chirp, error) {
+	chirp := database.Chirp{ID: arg.ID, CreatedAt: testNow, UpdatedAt: testNow, Body: arg.Body, UserID: arg.UserID}
+	tx.writes = append(tx.writes, func() { tx.chirps = append(tx.chirps, chirp) })
+	return chirp, nil
+}
+
+func (tx *outboxTx) EnqueueOutboxEvent(ctx context.Context, arg database.EnqueueOutboxEventParams) error {
+	if tx.enqueueErr != nil {
+		return tx.enqueueErr
+	}
+	e := database.OutboxEvent{ID: arg.ID, Kind: arg.Kind, Payload: arg.Payload, Status: outboxEventStatusPending}
+	tx.writes = append(tx.writes, func() { tx.events = append(tx.events, e) })
+	return nil
+}
+
+func (tx *outboxTx) MarkOutboxEventSent(ctx context.Context, id uuid.UUID) error {
+	tx.writes = append(tx.writes, func() {
+		e := tx.event(id)
+		e.Status = "sent"
+		e.Attempts++
+	})
+	return nil
+}
+
+func (tx *outboxTx) MarkOutboxEventFailed(ctx context.Context, arg database.MarkOutboxEventFailedParams) error {
+	tx.writes = append(tx.writes, func() {
+		e := tx.event(arg.ID)
+		e.Status = arg.Status
+		e.Attempts++
+		e.LastError = arg.LastError
+		e.NextAttemptAt = arg.NextAttemptAt
+	})
+	return nil
+}
+
+func (tx *outboxTx) Commit() error {
+	for _, write := range tx.writes {
+		write()
+	}
+	tx.writes = nil
+	return nil
+}
+
+func (tx *outboxTx) Rollback() error {
+	tx.writes = nil
+	return nil
+}
+
+func TestOutbox_FederatesChirps(t *testing.T) {
+	var delivered atomic.Int32
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered.Add(1)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	privatePEM, publicPEM, err := activitypub.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	author := newTestUser(t, "author@example.com", "pa55word")
+	store := &outboxStore{
+		fakeStore: fakeStore{users: map[string]database.User{author.Email: author}},
+		key:       database.ActorKey{UserID: author.ID, PublicKeyPem: publicPEM, PrivateKeyPem: privatePEM},
+		inboxes:   []string{up.URL + "/inbox", down.URL + "/inbox"},
+	}
+	cfg := &config.Config{JWTSecret: "test-secret", PublicURL: "https://chirpy.example"}
+	s := NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)})
+
+	chirp, _, err := s.createChirp(context.Background(), author.ID, "Hello, fediverse")
+	if err != nil {
+		t.Fatalf("createChirp returned error: %v", err)
+	}
+	if len(store.chirps) != 1 || len(store.events) != 1 || store.events[0].Kind != eventChirpCreated {
+		t.Fatalf("expected the chirp and its event to commit together, got %d chirps and %+v", len(store.chirps), store.events)
+	}
+
+	// The first pass fans the chirp out into a delivery per inbox.
+	if n, err := s.relayOutboxBatch(context.Background()); err != nil || n != 1 {
+		t.Fatalf("relayOutboxBatch = %d, %v", n, err)
+	}
+	if len(store.events) != 3 || store.events[0].Status != "sent" {
+		t.Fatalf("expected the chirp event sent and two deliveries queued, got %+v", store.events)
+	}
+
+	// The second delivers them; the server that is down is retried later.
+	if n, err := s.relayOutboxBatch(context.Background()); err != nil || n != 2 {
+		t.Fatalf("relayOutboxBatch = %d, %v", n, err)
+	}
+	if delivered.Load() != 1 {
+		t.Errorf("expected one delivery to succeed, got %d", delivered.Load())
+	}
+	for _, e := range store.events[1:] {
+		if e.Kind != eventActivityDelivery || e.Attempts != 1 {
+			t.Errorf("expected one delivery attempt, got %+v", e)
+		}
+	}
+	failed := store.events[2]
+	if failed.Status != outboxEventStatusPending || failed.LastError == "" || !failed.NextAttemptAt.Equal(testNow.Add(outboxEventBaseBackoff)) {
+		t.Errorf("expected the failed delivery to be retried after a backoff, got %+v", failed)
+	}
+	if chirp.ID != store.chirps[0].ID {
+		t.Errorf("created chirp %s, stored %s", chirp.ID, store.chirps[0].ID)
+	}
+}
+
+func TestOutbox_RollsBackChirpWithoutEvent(t *testing.T) {
+	author := newTestUser(t, "author@example.com", "pa55word")
+	store := &outboxStore{
+		fakeStore:  fakeStore{users: map[string]database.User{author.Email: author}},
+		enqueueErr: errors.New("outbox unavailable"),
+	}
+	cfg := &config.Config{JWTSecret: "test-secret", PublicURL: "https://chirpy.example"}
+	s := NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)})
+
+	if _, _, err := s.createChirp(context.Background(), author.ID, "Hello, fediverse"); err == nil {
+		t.Fatal("expected an error when the event can't be recorded")
+	}
+	if len(store.chirps) != 0 {
+		t.Errorf("expected the chirp to be rolled back, got %+v", store.chirps)
+	}
+}
+
+func TestOutboxEventBackoff(t *testing.T) {
+	if got := outboxEventBackoff(1); got != outboxEventBaseBackoff {
+		t.Errorf("first retry after %v, want %v", got, outboxEventBaseBackoff)
+	}
+	if got := outboxEventBackoff(maxOutboxEventAttempts); got != outboxEventMaxBackoff {
+		t.Errorf("last retry after %v, want %v", got, outboxEventMaxBackoff)
+	}
+}
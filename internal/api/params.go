@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// paramError is the body of a 400 for a malformed path parameter.
+type paramError struct {
+	Error string `json:"error"`
+	Param string `json:"param"`
+	Value string `json:"value"`
+}
+
+// pathUUID parses the path parameter name, such as "chirpID", as a UUID.
+// When it is malformed, pathUUID answers 400 naming the parameter and
+// returns false; the caller should just return.
+func pathUUID(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	value := r.PathValue(name)
+	id, err := uuid.Parse(value)
+	if err != nil {
+		jsonResponse(w, http.StatusBadRequest, paramError{
+			Error: "Invalid " + strings.TrimSuffix(name, "ID") + " ID",
+			Param: name,
+			Value: value,
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
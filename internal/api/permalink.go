@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"chirpy/internal/database"
+	"chirpy/internal/media"
+
+	"github.com/google/uuid"
+)
+
+var permalinkTemplate = template.Must(template.New("permalink").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Title}}</title>
+  <meta name="description" content="{{.Body}}">
+  <link rel="canonical" href="{{.URL}}">
+  <link rel="alternate" type="application/json+oembed" href="{{.OEmbedURL}}" title="{{.Title}}">
+  <meta property="og:type" content="article">
+  <meta property="og:site_name" content="{{.SiteName}}">
+  <meta property="og:title" content="{{.Title}}">
+  <meta property="og:description" content="{{.Body}}">
+  <meta property="og:url" content="{{.URL}}">
+  <meta property="og:image" content="{{.Image}}">
+  <meta property="article:published_time" content="{{.Published}}">
+  <meta name="twitter:card" content="summary">
+  <meta name="twitter:title" content="{{.Title}}">
+  <meta name="twitter:description" content="{{.Body}}">
+  <meta name="twitter:image" content="{{.Image}}">
+  <style>
+    body { font-family: sans-serif; max-width: 36rem; margin: 3rem auto; padding: 0 1rem; color: #222; }
+    blockquote { margin: 0; padding: 1rem 1.25rem; border: 1px solid #ddd; border-radius: 8px; }
+    .body { font-size: 1.25rem; white-space: pre-wrap; }
+    .meta { color: #666; font-size: 0.9rem; }
+    .media img, .media video { max-width: 100%; border-radius: 4px; }
+  </style>
+</head>
+<body>
+  <blockquote>
+    <p class="meta"><a href="{{.AuthorURL}}">@{{.Author}}</a>{{if .Verified}} &#10003;{{end}}</p>
+    <p class="body">{{.Body}}</p>
+    {{- range .Media}}
+    <p class="media">{{if .Video}}<video src="{{.URL}}" controls aria-label="{{.AltText}}"></video>{{else}}<img src="{{.URL}}" alt="{{.AltText}}">{{end}}</p>
+    {{- end}}
+    <p class="meta"><time datetime="{{.Published}}">{{.Date}}</time></p>
+  </blockquote>
+</body>
+</html>
+`))
+
+type permalinkPage struct {
+	SiteName  string
+	Title     string
+	Body      string
+	URL       string
+	OEmbedURL string
+	Image     string
+	Author    string
+	AuthorURL string
+	Verified  bool
+	Published string
+	Date      string
+	Media     []permalinkMedia
+}
+
+type permalinkMedia struct {
+	URL     string
+	AltText string
+	Video   bool
+}
+
+// handlerChirpPermalink serves a chirp as a small HTML page whose Open
+// Graph and Twitter Card tags let chat apps unfurl shared links.
+func (s *Server) handlerChirpPermalink(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	chirp, err := s.db.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: s.viewerID(r),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	author, err := s.db.GetUserByID(ctx, chirp.UserID)
+	if err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	attached, err := s.db.ListChirpMedia(ctx, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	if err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+
+	base := s.baseURL(r)
+	name := preferredUsername(author)
+	permalink := base + "/chirps/" + chirp.ID.String()
+	page := permalinkPage{
+		SiteName:  siteName(ctx),
+		Title:     fmt.Sprintf("@%s on %s", name, siteName(ctx)),
+		Body:      chirp.Body,
+		URL:       permalink,
+		OEmbedURL: base + "/api/oembed?url=" + url.QueryEscape(permalink),
+		Image:     base + "/assets/logo.png",
+		Author:    name,
+		AuthorURL: s.appURL(base, "profile/@"+name),
+		Verified:  chirp.AuthorVerified,
+		Published: chirp.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+		Date:      chirp.CreatedAt.UTC().Format("3:04 PM · Jan 2, 2006"),
+	}
+	if logo := currentTenant(ctx).Config.LogoURL; logo != "" {
+		page.Image = logo
+	}
+	for _, m := range attached {
+		page.Media = append(page.Media, permalinkMedia{
+			URL:     base + "/media/" + m.ID.String(),
+			AltText: m.AltText,
+			Video:   media.IsVideo(m.ContentType),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := permalinkTemplate.Execute(&buf, page); err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"chirpy/internal/database"
+	"chirpy/internal/linkscan"
+)
+
+const (
+	maxChirpLength    = 140
+	maxRedChirpLength = 280
+)
+
+const chirpyRedUserKey contextKey = "chirpyRedUser"
+
+// plan is what a user's membership entitles them to. It is always worked
+// out from the users.is_chirpy_red column, never from anything the client
+// sends.
+type plan struct {
+	ChirpyRed      bool
+	MaxChirpLength int
+	CanEditChirps  bool
+	NoAds          bool
+}
+
+func planFor(u database.User) plan {
+	if u.IsChirpyRed {
+		return plan{
+			ChirpyRed:      true,
+			MaxChirpLength: maxRedChirpLength,
+			CanEditChirps:  true,
+			NoAds:          true,
+		}
+	}
+	return plan{MaxChirpLength: maxChirpLength}
+}
+
+// middlewareRequireChirpyRed rejects requests that aren't made with an
+// access token belonging to a Chirpy Red member. The member is available to
+// the wrapped handler through chirpyRedFromContext.
+func (s *Server) middlewareRequireChirpyRed(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := s.authenticate(r)
+		if err != nil {
+			jsonResponse(w, http.StatusUnauthorized, "Couldn't validate token")
+			return
+		}
+
+		user, err := s.db.GetUserByID(r.Context(), userID)
+		if err != nil || !planFor(user).ChirpyRed {
+			jsonResponse(w, http.StatusForbidden, "Chirpy Red membership required")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), chirpyRedUserKey, user)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+func chirpyRedFromContext(ctx context.Context) database.User {
+	user, _ := ctx.Value(chirpyRedUserKey).(database.User)
+	return user
+}
+
+type chirpEditRequest struct {
+	Body string `json:"body"`
+}
+
+// handlerChirpsEdit replaces the body of one of the member's own chirps.
+// The new body goes through the same checks as a new chirp.
+func (s *Server) handlerChirpsEdit(w http.ResponseWriter, r *http.Request) {
+	user := chirpyRedFromContext(r.Context())
+	chirpID, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	var req chirpEditRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+
+	body, flagged, err := s.prepareChirpBody(req.Body, s.maxChirpLength(user))
+	switch {
+	case errors.Is(err, errChirpTooLong):
+		jsonResponse(w, http.StatusBadRequest, "Chirp is too long")
+		return
+	case errors.Is(err, errChirpBlocked):
+		jsonResponse(w, http.StatusBadRequest, "Chirp contains blocked content")
+		return
+	}
+
+	ctx := r.Context()
+	chirp, err := s.db.UpdateChirpBody(ctx, database.UpdateChirpBodyParams{
+		ID:     chirpID,
+		Body:   body,
+		UserID: user.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		jsonResponse(w, http.StatusNotFound, "Chirp was not found.")
+		return
+	}
+	if err != nil {
+		fmt.Println("Error editing chirp:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	s.flagChirp(ctx, chirp.ID, flagged)
+	if err := s.recordChirpLinks(ctx, s.db, chirp.ID, linkscan.ExtractURLs(chirp.Body)); err != nil {
+		fmt.Println("Error recording chirp links:", err)
+	}
+	s.responseCache.invalidate()
+
+	jsonResponse(w, http.StatusOK, chirpResponse{
+		ID:             chirp.ID,
+		CreatedAt:      Timestamp{chirp.CreatedAt},
+		UpdatedAt:      Timestamp{chirp.UpdatedAt},
+		Body:           chirp.Body,
+		UserID:         chirp.UserID,
+		AuthorVerified: user.Verified,
+		ShortURL:       s.shortURL(r.Context(), chirp.ID),
+	})
+}
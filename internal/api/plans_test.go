@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+)
+
+// editStore edits chirps owned by contractUserID.
+type editStore struct {
+	contractStore
+	edits []database.UpdateChirpBodyParams
+}
+
+func (e *editStore) UpdateChirpBody(ctx context.Context, arg database.UpdateChirpBodyParams) (database.Chirp, error) {
+	if arg.ID != contractChirpID || arg.UserID != contractUserID {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	e.edits = append(e.edits, arg)
+	return e.fixtureChirp(arg.Body), nil
+}
+
+func TestChirpsEdit_RequiresChirpyRed(t *testing.T) {
+	user := database.User{ID: contractUserID, Email: "red@example.com"}
+	store := &editStore{contractStore: contractStore{fakeStore{users: map[string]database.User{user.Email: user}}}}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store}))
+	token, err := auth.MakeJWT(user.ID, "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	edit := func(id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/chirps/"+id, strings.NewReader(`{"body":"`+body+`"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := edit(contractChirpID.String(), "edited"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a free user, got %d: %s", rec.Code, rec.Body)
+	}
+
+	user.IsChirpyRed = true
+	store.users[user.Email] = user
+	long := strings.Repeat("a", maxChirpLength+1)
+	rec := edit(contractChirpID.String(), long)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp chirpResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Body != long || len(store.edits) != 1 {
+		t.Errorf("expected the long body to be saved, got %q and %d edits", resp.Body, len(store.edits))
+	}
+
+	if rec := edit(contractChirpID.String(), strings.Repeat("a", maxRedChirpLength+1)); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 past the Chirpy Red limit, got %d", rec.Code)
+	}
+	if rec := edit(contractUserID.String(), "edited"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for someone else's chirp, got %d", rec.Code)
+	}
+}
+
+func TestChirpsCreate_PlanLength(t *testing.T) {
+	user := database.User{ID: contractUserID, Email: "red@example.com"}
+	store := &contractStore{fakeStore{users: map[string]database.User{user.Email: user}}}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store}))
+	body := `{"body":"` + strings.Repeat("a", 200) + `","user_id":"` + contractUserID.String() + `","is_chirpy_red":true}`
+
+	// The plan comes from the users table, so a client claiming Chirpy Red
+	// doesn't get longer chirps.
+	if rec := do(h, http.MethodPost, "/api/chirps", body); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a free user, got %d", rec.Code)
+	}
+
+	user.IsChirpyRed = true
+	store.users[user.Email] = user
+	if rec := do(h, http.MethodPost, "/api/chirps", body); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for a Chirpy Red member, got %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"net/http"
+
+	"chirpy/internal/database"
+)
+
+// Who may send a user direct messages.
+const (
+	dmEveryone  = "everyone"
+	dmFollowers = "followers"
+	dmNone      = "none"
+)
+
+// userPreferences gathers the user's privacy and notification settings.
+// Some are kept with the feature they belong to, and have endpoints of
+// their own as well.
+type userPreferences struct {
+	DirectMessages     string             `json:"direct_messages"`
+	EmailNotifications emailNotifications `json:"email_notifications"`
+	SensitiveContent   string             `json:"sensitive_content"`
+	ShareLocation      bool               `json:"share_location"`
+	// Discoverable users appear in search, suggestions and sitemaps.
+	Discoverable bool `json:"discoverable"`
+}
+
+type emailNotifications struct {
+	NewFollower bool `json:"new_follower"`
+	// Digest is how often the digest is sent: daily, weekly or off.
+	Digest string `json:"digest"`
+}
+
+func newUserPreferences(p database.GetUserPreferencesRow) userPreferences {
+	return userPreferences{
+		DirectMessages: p.DmPermission,
+		EmailNotifications: emailNotifications{
+			NewFollower: p.EmailNewFollower,
+			Digest:      p.DigestFrequency,
+		},
+		SensitiveContent: p.SensitiveContent,
+		ShareLocation:    p.ShareLocation,
+		Discoverable:     p.Discoverable,
+	}
+}
+
+// userPreferencesPatch is a PATCH body: only the fields present change.
+type userPreferencesPatch struct {
+	DirectMessages     *string `json:"direct_messages"`
+	EmailNotifications *struct {
+		NewFollower *bool   `json:"new_follower"`
+		Digest      *string `json:"digest"`
+	} `json:"email_notifications"`
+	SensitiveContent *string `json:"sensitive_content"`
+	ShareLocation    *bool   `json:"share_location"`
+	Discoverable     *bool   `json:"discoverable"`
+}
+
+// apply sets the fields present in p on prefs, returning a message for the
+// first invalid one.
+func (p userPreferencesPatch) apply(prefs *database.GetUserPreferencesRow) string {
+	if p.DirectMessages != nil {
+		switch *p.DirectMessages {
+		case dmEveryone, dmFollowers, dmNone:
+			prefs.DmPermission = *p.DirectMessages
+		default:
+			return "direct_messages must be everyone, followers or none"
+		}
+	}
+	if e := p.EmailNotifications; e != nil {
+		if e.NewFollower != nil {
+			prefs.EmailNewFollower = *e.NewFollower
+		}
+		if e.Digest != nil {
+			if !validDigestFrequency(*e.Digest) {
+				return "email_notifications.digest must be daily, weekly or off"
+			}
+			prefs.DigestFrequency = *e.Digest
+		}
+	}
+	if p.SensitiveContent != nil {
+		switch *p.SensitiveContent {
+		case sensitiveExpand, sensitiveCollapse, sensitiveHide:
+			prefs.SensitiveContent = *p.SensitiveContent
+		default:
+			return "sensitive_content must be expand, collapse or hide"
+		}
+	}
+	if p.ShareLocation != nil {
+		prefs.ShareLocation = *p.ShareLocation
+	}
+	if p.Discoverable != nil {
+		prefs.Discoverable = *p.Discoverable
+	}
+	return ""
+}
+
+func (s *Server) handlerPreferencesGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	prefs, err := s.db.GetUserPreferences(r.Context(), userID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, newUserPreferences(prefs))
+}
+
+// handlerPreferencesUpdate changes the preferences present in the body and
+// returns all of them. Each table is only written if one of its settings
+// changed, in one transaction.
+func (s *Server) handlerPreferencesUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req userPreferencesPatch
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+
+	ctx := r.Context()
+	old, err := s.db.GetUserPreferences(ctx, userID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	prefs := old
+	if msg := req.apply(&prefs); msg != "" {
+		jsonResponse(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+
+	if prefs.DmPermission != old.DmPermission || prefs.EmailNewFollower != old.EmailNewFollower || prefs.Discoverable != old.Discoverable {
+		err = tx.SetUserPreferences(ctx, database.SetUserPreferencesParams{
+			UserID:           userID,
+			DmPermission:     prefs.DmPermission,
+			EmailNewFollower: prefs.EmailNewFollower,
+			Discoverable:     prefs.Discoverable,
+		})
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	}
+	if prefs.DigestFrequency != old.DigestFrequency {
+		err = tx.SetDigestFrequency(ctx, database.SetDigestFrequencyParams{
+			UserID:    userID,
+			Frequency: prefs.DigestFrequency,
+		})
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	}
+	if prefs.SensitiveContent != old.SensitiveContent {
+		err = tx.SetSensitiveContentPreference(ctx, database.SetSensitiveContentPreferenceParams{
+			UserID:           userID,
+			SensitiveContent: prefs.SensitiveContent,
+		})
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	}
+	if prefs.ShareLocation != old.ShareLocation {
+		err = tx.SetLocationSharing(ctx, database.SetLocationSharingParams{
+			UserID:        userID,
+			ShareLocation: prefs.ShareLocation,
+		})
+		if err != nil {
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	// Other servers' typeahead caches catch up when their entries expire.
+	if prefs.Discoverable != old.Discoverable {
+		s.typeaheadCache.invalidate()
+	}
+	jsonResponse(w, http.StatusOK, newUserPreferences(prefs))
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type preferencesStore struct {
+	fakeStore
+	prefs  database.GetUserPreferencesRow
+	writes []string
+}
+
+type preferencesTx struct {
+	*preferencesStore
+}
+
+func (s *preferencesStore) Begin(ctx context.Context) (Tx, error) {
+	return preferencesTx{s}, nil
+}
+
+func (tx preferencesTx) Commit() error   { return nil }
+func (tx preferencesTx) Rollback() error { return nil }
+
+func (s *preferencesStore) GetUserPreferences(ctx context.Context, id uuid.UUID) (database.GetUserPreferencesRow, error) {
+	return s.prefs, nil
+}
+
+func (s *preferencesStore) SetUserPreferences(ctx context.Context, arg database.SetUserPreferencesParams) error {
+	s.prefs.DmPermission = arg.DmPermission
+	s.prefs.EmailNewFollower = arg.EmailNewFollower
+	s.prefs.Discoverable = arg.Discoverable
+	s.writes = append(s.writes, "user_preferences")
+	return nil
+}
+
+func (s *preferencesStore) SetDigestFrequency(ctx context.Context, arg database.SetDigestFrequencyParams) error {
+	s.prefs.DigestFrequency = arg.Frequency
+	s.writes = append(s.writes, "digest_preferences")
+	return nil
+}
+
+func (s *preferencesStore) SetSensitiveContentPreference(ctx context.Context, arg database.SetSensitiveContentPreferenceParams) error {
+	s.prefs.SensitiveContent = arg.SensitiveContent
+	s.writes = append(s.writes, "content_preferences")
+	return nil
+}
+
+func TestPreferences_Patch(t *testing.T) {
+	user := newTestUser(t, "user@example.com", "pa55word")
+	store := &preferencesStore{
+		fakeStore: fakeStore{users: map[string]database.User{user.Email: user}},
+		prefs: database.GetUserPreferencesRow{
+			DmPermission:     dmEveryone,
+			EmailNewFollower: true,
+			Discoverable:     true,
+			DigestFrequency:  digestWeekly,
+			SensitiveContent: sensitiveCollapse,
+		},
+	}
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	h := NewRouter(NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)}))
+	token, err := auth.MakeJWT(user.ID, cfg.JWTSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	send := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/users/me/preferences", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(http.MethodPatch, `{"direct_messages":"followers","email_notifications":{"digest":"off"},"discoverable":false}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var got userPreferences
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding preferences: %v", err)
+	}
+	want := userPreferences{
+		DirectMessages:     dmFollowers,
+		EmailNotifications: emailNotifications{NewFollower: true, Digest: digestOff},
+		SensitiveContent:   sensitiveCollapse,
+		Discoverable:       false,
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if strings.Join(store.writes, ",") != "user_preferences,digest_preferences" {
+		t.Errorf("expected only the changed tables written, got %v", store.writes)
+	}
+
+	for _, body := range []string{
+		`{"direct_messages":"friends"}`,
+		`{"email_notifications":{"digest":"hourly"}}`,
+		`{"sensitive_content":"blur"}`,
+	} {
+		if rec := send(http.MethodPatch, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+	if rec := do(h, http.MethodGet, "/api/users/me/preferences", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous: expected 401, got %d", rec.Code)
+	}
+}
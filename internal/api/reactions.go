@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// reactions are the emoji users may react with.
+func (s *Server) reactions() []string {
+	if len(s.config.Reactions) == 0 {
+		return config.DefaultReactions
+	}
+	return s.config.Reactions
+}
+
+// reactionCounts returns how many users reacted to chirpID with each emoji.
+func (s *Server) reactionCounts(ctx context.Context, chirpID uuid.UUID) (map[string]int64, error) {
+	rows, err := s.db.ListChirpReactionCounts(ctx, chirpID)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Emoji] = row.ReactionCount
+	}
+	return counts, nil
+}
+
+func (s *Server) handlerReactionsList(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, http.StatusOK, struct {
+		Reactions []string `json:"reactions"`
+	}{s.reactions()})
+}
+
+type reactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+type reactionResponse struct {
+	Reactions map[string]int64 `json:"reactions"`
+}
+
+// handlerChirpReact sets the caller's reaction to a chirp, replacing any
+// reaction they had before. Reactions reach the event stream as
+// chirp.reacted events.
+func (s *Server) handlerChirpReact(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Couldn't validate token")
+		return
+	}
+	chirpID, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	var req reactionRequest
+	if err := s.decodeJSON(r, &req); err != nil {
+		jsonResponse(w, http.StatusBadRequest, decodeErrorMessage(err, "Invalid request body"))
+		return
+	}
+	if !slices.Contains(s.reactions(), req.Emoji) {
+		jsonResponse(w, http.StatusBadRequest, "Unsupported reaction")
+		return
+	}
+
+	ctx := r.Context()
+	_, err = s.db.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+		ID:       chirpID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusNotFound, "Chirp was not found.")
+		return
+	}
+
+	err = s.db.UpsertReaction(ctx, database.UpsertReactionParams{
+		ChirpID: chirpID,
+		UserID:  userID,
+		Emoji:   req.Emoji,
+	})
+	if err != nil {
+		fmt.Println("Error saving reaction:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	s.respondReactionCounts(w, r, chirpID)
+}
+
+func (s *Server) handlerChirpUnreact(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Couldn't validate token")
+		return
+	}
+	chirpID, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	removed, err := s.db.DeleteReaction(r.Context(), database.DeleteReactionParams{
+		ChirpID: chirpID,
+		UserID:  userID,
+	})
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	if removed == 0 {
+		jsonResponse(w, http.StatusNotFound, "You haven't reacted to this chirp")
+		return
+	}
+	s.respondReactionCounts(w, r, chirpID)
+}
+
+func (s *Server) respondReactionCounts(w http.ResponseWriter, r *http.Request, chirpID uuid.UUID) {
+	counts, err := s.reactionCounts(r.Context(), chirpID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	jsonResponse(w, http.StatusOK, reactionResponse{Reactions: counts})
+}
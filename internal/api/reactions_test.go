@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// reactionStore keeps each user's reaction to the contract chirp.
+type reactionStore struct {
+	contractStore
+	reactions map[uuid.UUID]string
+}
+
+func (s *reactionStore) UpsertReaction(ctx context.Context, arg database.UpsertReactionParams) error {
+	s.reactions[arg.UserID] = arg.Emoji
+	return nil
+}
+
+func (s *reactionStore) DeleteReaction(ctx context.Context, arg database.DeleteReactionParams) (int64, error) {
+	if _, ok := s.reactions[arg.UserID]; !ok {
+		return 0, nil
+	}
+	delete(s.reactions, arg.UserID)
+	return 1, nil
+}
+
+func (s *reactionStore) ListChirpReactionCounts(ctx context.Context, chirpID uuid.UUID) ([]database.ListChirpReactionCountsRow, error) {
+	counts := make(map[string]int64)
+	for _, emoji := range s.reactions {
+		counts[emoji]++
+	}
+	var rows []database.ListChirpReactionCountsRow
+	for emoji, n := range counts {
+		rows = append(rows, database.ListChirpReactionCountsRow{Emoji: emoji, ReactionCount: n})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Emoji < rows[j].Emoji })
+	return rows, nil
+}
+
+func TestReactions(t *testing.T) {
+	store := &reactionStore{reactions: map[uuid.UUID]string{uuid.New(): "🔥"}}
+	cfg := &config.Config{JWTSecret: "test-secret", Reactions: []string{"🔥", "👀"}}
+	h := NewRouter(NewServer(cfg, Deps{Store: store}))
+	user := uuid.New()
+	token, err := auth.MakeJWT(user, "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	path := "/api/chirps/" + contractChirpID.String() + "/reaction"
+
+	if rec := send(http.MethodPut, path, `{"emoji":"👍"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an emoji outside the configured set, got %d", rec.Code)
+	}
+	if rec := send(http.MethodPut, "/api/chirps/"+uuid.NewString()+"/reaction", `{"emoji":"🔥"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown chirp, got %d", rec.Code)
+	}
+
+	send(http.MethodPut, path, `{"emoji":"👀"}`)
+	rec := send(http.MethodPut, path, `{"emoji":"🔥"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp reactionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Reactions) != 1 || resp.Reactions["🔥"] != 2 {
+		t.Errorf("expected reacting again to replace the first reaction, got %v", resp.Reactions)
+	}
+
+	rec = send(http.MethodGet, "/api/chirps/"+contractChirpID.String(), "")
+	var chirp chirpResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &chirp); err != nil {
+		t.Fatal(err)
+	}
+	if chirp.Reactions["🔥"] != 2 {
+		t.Errorf("expected the counts on the chirp, got %v", chirp.Reactions)
+	}
+
+	if rec := send(http.MethodDelete, path, ""); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 removing a reaction, got %d", rec.Code)
+	}
+	if rec := send(http.MethodDelete, path, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 removing a reaction twice, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	suggestionHashtagWindow = 30 * 24 * time.Hour
+	suggestionsPerUser      = 50
+	suggestionsPageSize     = 20
+)
+
+// refreshSuggestions replaces every user's suggestions in one transaction,
+// so readers see either the old set or the new one.
+func (s *Server) refreshSuggestions(ctx context.Context) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.DeleteUserSuggestions(ctx); err != nil {
+		return err
+	}
+	_, err = tx.RefreshUserSuggestions(ctx, database.RefreshUserSuggestionsParams{
+		Since:        s.clock.Now().UTC().Add(-suggestionHashtagWindow),
+		PerUserLimit: suggestionsPerUser,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type suggestionResponse struct {
+	ID                 uuid.UUID `json:"id"`
+	Handle             string    `json:"handle,omitempty"`
+	Verified           bool      `json:"verified"`
+	MutualCount        int32     `json:"mutual_count"`
+	SharedHashtagCount int32     `json:"shared_hashtag_count"`
+}
+
+// handlerRecommendedUsers suggests accounts to add to your lists, drawn
+// from the lists of the accounts you list and from shared hashtags.
+func (s *Server) handlerRecommendedUsers(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Couldn't validate token")
+		return
+	}
+
+	rows, err := s.db.ListUserSuggestions(r.Context(), database.ListUserSuggestionsParams{
+		UserID: userID,
+		Limit:  suggestionsPageSize,
+	})
+	if err != nil {
+		fmt.Println("Error listing suggestions:", err)
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+
+	resp := make([]suggestionResponse, 0, len(rows))
+	for _, row := range rows {
+		resp = append(resp, suggestionResponse{
+			ID:                 row.ID,
+			Handle:             row.Handle.String,
+			Verified:           row.Verified,
+			MutualCount:        row.MutualCount,
+			SharedHashtagCount: row.SharedHashtagCount,
+		})
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
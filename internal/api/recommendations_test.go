@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// suggestionStore serves a fixed set of suggestions.
+type suggestionStore struct {
+	fakeStore
+	rows []database.ListUserSuggestionsRow
+	args []database.ListUserSuggestionsParams
+}
+
+func (s *suggestionStore) ListUserSuggestions(ctx context.Context, arg database.ListUserSuggestionsParams) ([]database.ListUserSuggestionsRow, error) {
+	s.args = append(s.args, arg)
+	return s.rows, nil
+}
+
+func TestRecommendedUsers(t *testing.T) {
+	suggested := uuid.New()
+	store := &suggestionStore{rows: []database.ListUserSuggestionsRow{{
+		ID:                 suggested,
+		Handle:             sql.NullString{String: "gopher", Valid: true},
+		MutualCount:        3,
+		SharedHashtagCount: 1,
+	}}}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store}))
+
+	if rec := do(h, http.MethodGet, "/api/recommendations/users", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", rec.Code)
+	}
+
+	user := uuid.New()
+	token, err := auth.MakeJWT(user, "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/recommendations/users", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var resp []suggestionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp) != 1 || resp[0].ID != suggested || resp[0].Handle != "gopher" || resp[0].MutualCount != 3 {
+		t.Errorf("unexpected suggestions: %+v", resp)
+	}
+	if len(store.args) != 1 || store.args[0].UserID != user || store.args[0].Limit != suggestionsPageSize {
+		t.Errorf("expected the caller's suggestions to be read, got %+v", store.args)
+	}
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// responseCache keeps serialized responses of hot anonymous GET endpoints
+// for a short TTL, evicting the least recently used once maxBytes of
+// bodies are held. Chirp writes clear it, so the TTL only bounds staleness
+// for changes it isn't told about, such as deletes on other instances.
+type responseCache struct {
+	ttl      time.Duration
+	maxBytes int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // front is most recently used
+	size    int
+	// generation changes on every invalidation, so a response computed
+	// before a write isn't stored after it.
+	generation uint64
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type cachedResponse struct {
+	key     string
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+func newResponseCache(ttl time.Duration, maxBytes int) *responseCache {
+	return &responseCache{
+		ttl:      ttl,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (c *responseCache) get(key string, now time.Time) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	resp := el.Value.(*cachedResponse)
+	if !now.Before(resp.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return resp, true
+}
+
+// put stores resp unless the cache was invalidated since generation was
+// read.
+func (c *responseCache) put(resp *cachedResponse, generation uint64) {
+	if len(resp.body) > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if el, ok := c.entries[resp.key]; ok {
+		c.remove(el)
+	}
+	c.entries[resp.key] = c.lru.PushFront(resp)
+	c.size += len(resp.body)
+	for c.size > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *responseCache) remove(el *list.Element) {
+	resp := c.lru.Remove(el).(*cachedResponse)
+	delete(c.entries, resp.key)
+	c.size -= len(resp.body)
+}
+
+func (c *responseCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// invalidate drops every entry. It is safe to call on a nil cache.
+func (c *responseCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	clear(c.entries)
+	c.lru.Init()
+	c.size = 0
+}
+
+type responseCacheStats struct {
+	Hits    int64
+	Misses  int64
+	Entries int
+	Bytes   int
+}
+
+func (c *responseCache) stats() responseCacheStats {
+	if c == nil {
+		return responseCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return responseCacheStats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Entries: len(c.entries),
+		Bytes:   c.size,
+	}
+}
+
+// middlewareResponseCache serves anonymous GET requests from the response
+// cache. Authenticated requests always reach next, since what they see
+// depends on who is asking.
+func (s *Server) middlewareResponseCache(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := s.responseCache
+		if c == nil || r.Method != http.MethodGet || r.Header.Get("Authorization") != "" {
+			next(w, r)
+			return
+		}
+
+		// Links in responses are built from the Host header.
+		key := cacheKey(r.Context(), r.Host+r.URL.RequestURI())
+		if resp, ok := c.get(key, s.clock.Now()); ok {
+			c.hits.Add(1)
+			for k, v := range resp.header {
+				w.Header()[k] = slices.Clone(v)
+			}
+			w.Header().Set("X-Cache", "HIT")
+			w.WriteHeader(resp.status)
+			w.Write(resp.body)
+			return
+		}
+
+		c.misses.Add(1)
+		generation := c.currentGeneration()
+		w.Header().Set("X-Cache", "MISS")
+		rec := &cacheRecorder{ResponseWriter: w, limit: c.maxBytes}
+		next(rec, r)
+		if rec.status == http.StatusOK && !rec.skip {
+			header := rec.header
+			header.Del("X-Cache")
+			c.put(&cachedResponse{
+				key:     key,
+				status:  rec.status,
+				header:  header,
+				body:    rec.body.Bytes(),
+				expires: s.clock.Now().Add(c.ttl),
+			}, generation)
+		}
+	}
+}
+
+// cacheRecorder passes a response through while keeping a copy of it, up
+// to limit bytes, so streamed responses still stream.
+type cacheRecorder struct {
+	http.ResponseWriter
+	limit  int
+	status int
+	header http.Header
+	body   bytes.Buffer
+	// skip is set when the response outgrew limit or turned out to be
+	// broken after its status was sent.
+	skip bool
+}
+
+func (rec *cacheRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+		rec.header = rec.ResponseWriter.Header().Clone()
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *cacheRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if !rec.skip {
+		if rec.body.Len()+len(p) > rec.limit {
+			rec.skip = true
+			rec.body = bytes.Buffer{}
+		} else {
+			rec.body.Write(p)
+		}
+	}
+	return rec.ResponseWriter.Write(p)
+}
+
+func (rec *cacheRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// skipResponseCache keeps the response being written to w out of the
+// response cache. Handlers call it when a response fails after its status
+// went out.
+func skipResponseCache(w http.ResponseWriter) {
+	if rec, ok := w.(*cacheRecorder); ok {
+		rec.skip = true
+	}
+}
+
+// invalidateOnChirpEvents clears the response cache whenever any instance
+// creates a chirp.
+func (s *Server) invalidateOnChirpEvents(ctx context.Context) {
+	ch, unsubscribe := s.hub.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if e.Type == "chirp.created" {
+				s.responseCache.invalidate()
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+
+	"github.com/google/uuid"
+)
+
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	return c.now
+}
+
+func newCachingServer(store *fakeStore, clock Clock) *Server {
+	cfg := &config.Config{ResponseCacheTTL: 5 * time.Second, ResponseCacheMaxBytes: 1 << 20}
+	return NewServer(cfg, Deps{Store: store, Clock: clock})
+}
+
+func TestResponseCache_HitsUntilExpiry(t *testing.T) {
+	store := &fakeStore{chirps: []database.GetChirpsRow{{ID: uuid.New(), Body: "first"}}}
+	clock := &manualClock{now: testNow}
+	s := newCachingServer(store, clock)
+	h := NewRouter(s)
+
+	rec := do(h, http.MethodGet, "/api/chirps", "")
+	if rec.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("expected a miss, got %q", rec.Header().Get("X-Cache"))
+	}
+
+	store.chirps = append(store.chirps, database.GetChirpsRow{ID: uuid.New(), Body: "second"})
+	rec = do(h, http.MethodGet, "/api/chirps", "")
+	if rec.Header().Get("X-Cache") != "HIT" || strings.Contains(rec.Body.String(), "second") {
+		t.Fatalf("expected the cached response, got %q: %s", rec.Header().Get("X-Cache"), rec.Body)
+	}
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("cached response lost its headers: %v", rec.Header())
+	}
+
+	// Query strings are part of the key.
+	if rec := do(h, http.MethodGet, "/api/chirps?page=2", ""); rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected a miss for another query string")
+	}
+
+	clock.now = clock.now.Add(5 * time.Second)
+	rec = do(h, http.MethodGet, "/api/chirps", "")
+	if rec.Header().Get("X-Cache") != "MISS" || !strings.Contains(rec.Body.String(), "second") {
+		t.Fatalf("expected a fresh response after the TTL, got %q: %s", rec.Header().Get("X-Cache"), rec.Body)
+	}
+
+	stats := s.responseCache.stats()
+	if stats.Hits != 1 || stats.Misses != 3 || stats.Entries != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestResponseCache_SkipsAuthenticatedAndFailedRequests(t *testing.T) {
+	store := &fakeStore{}
+	h := NewRouter(newCachingServer(store, fixedClock(testNow)))
+	do(h, http.MethodGet, "/api/chirps", "")
+
+	// What a signed-in user sees depends on who they are.
+	store.chirps = []database.GetChirpsRow{{ID: uuid.New(), Body: "fresh"}}
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+	req.Header.Set("Authorization", "Bearer whatever")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Header().Get("X-Cache") != "" || !strings.Contains(rec.Body.String(), "fresh") {
+		t.Errorf("expected the cache to be bypassed, got %q: %s", rec.Header().Get("X-Cache"), rec.Body)
+	}
+
+	// A listing that fails halfway must not be replayed.
+	h = NewRouter(newCachingServer(&fakeStore{chirps: store.chirps, chirpsErr: errors.New("boom")}, fixedClock(testNow)))
+	do(h, http.MethodGet, "/api/chirps", "")
+	if rec := do(h, http.MethodGet, "/api/chirps", ""); rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("expected the truncated response to stay out of the cache")
+	}
+}
+
+func TestResponseCache_Invalidation(t *testing.T) {
+	store := &fakeStore{}
+	s := newCachingServer(store, fixedClock(testNow))
+	h := NewRouter(s)
+
+	do(h, http.MethodGet, "/api/chirps", "")
+	s.responseCache.invalidate()
+	if rec := do(h, http.MethodGet, "/api/chirps", ""); rec.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("expected a miss after invalidation")
+	}
+
+	// A response computed before an invalidation isn't stored after it.
+	generation := s.responseCache.currentGeneration()
+	s.responseCache.invalidate()
+	s.responseCache.put(&cachedResponse{key: "stale", status: http.StatusOK, expires: testNow.Add(time.Hour)}, generation)
+	if _, ok := s.responseCache.get("stale", testNow); ok {
+		t.Error("stale response was stored")
+	}
+}
+
+func TestResponseCache_InvalidatesOnChirpEvents(t *testing.T) {
+	hub := events.NewHub()
+	cfg := &config.Config{ResponseCacheTTL: time.Minute, ResponseCacheMaxBytes: 1 << 20}
+	s := NewServer(cfg, Deps{Store: &fakeStore{}, Clock: fixedClock(testNow), Hub: hub})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		s.invalidateOnChirpEvents(ctx)
+		close(done)
+	}()
+
+	s.responseCache.put(&cachedResponse{key: "k", status: http.StatusOK, expires: testNow.Add(time.Hour)}, 0)
+	deadline := time.Now().Add(2 * time.Second)
+	for s.responseCache.stats().Entries != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("chirp.created did not clear the cache")
+		}
+		hub.Publish(events.Event{Type: "chirp.created", Data: json.RawMessage(`{}`)})
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestResponseCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newResponseCache(time.Minute, 10)
+	put := func(key string, size int) {
+		c.put(&cachedResponse{key: key, body: make([]byte, size), expires: testNow.Add(time.Minute)}, 0)
+	}
+	put("a", 4)
+	put("b", 4)
+	c.get("a", testNow) // a is now more recent than b
+	put("c", 4)
+
+	if _, ok := c.get("b", testNow); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.get(key, testNow); !ok {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+	if stats := c.stats(); stats.Bytes != 8 {
+		t.Errorf("expected 8 bytes held, got %d", stats.Bytes)
+	}
+
+	put("huge", 11)
+	if _, ok := c.get("huge", testNow); ok {
+		t.Error("a response larger than the cache was stored")
+	}
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+)
+
+// retentionBatchSize bounds each purge statement, so clearing a backlog
+// doesn't hold locks on a large table for long.
+const retentionBatchSize = 1000
+
+// retentionRule removes one kind of data once it is older than its
+// configured age.
+type retentionRule struct {
+	name   string
+	maxAge func(config.RetentionConfig) time.Duration
+	count  func(ctx context.Context, q database.Querier, before time.Time) (int64, error)
+	purge  func(ctx context.Context, q database.Querier, before time.Time) (int64, error)
+}
+
+var retentionRules = []retentionRule{
+	{
+		// Purging a chirp takes its locations, reactions and the rest
+		// with it.
+		name:   "deleted_chirps",
+		maxAge: func(c config.RetentionConfig) time.Duration { return c.DeletedChirps },
+		count: func(ctx context.Context, q database.Querier, before time.Time) (int64, error) {
+			return q.CountExpiredDeletedChirps(ctx, before)
+		},
+		purge: func(ctx context.Context, q database.Querier, before time.Time) (int64, error) {
+			return q.PurgeDeletedChirps(ctx, database.PurgeDeletedChirpsParams{Before: before, RowLimit: retentionBatchSize})
+		},
+	},
+	{
+		name:   "emails",
+		maxAge: func(c config.RetentionConfig) time.Duration { return c.Emails },
+		count: func(ctx context.Context, q database.Querier, before time.Time) (int64, error) {
+			return q.CountExpiredEmails(ctx, before)
+		},
+		purge: func(ctx context.Context, q database.Querier, before time.Time) (int64, error) {
+			return q.PurgeEmails(ctx, database.PurgeEmailsParams{Before: before, RowLimit: retentionBatchSize})
+		},
+	},
+	{
+		name:   "outbox_events",
+		maxAge: func(c config.RetentionConfig) time.Duration { return c.OutboxEvents },
+		count: func(ctx context.Context, q database.Querier, before time.Time) (int64, error) {
+			return q.CountExpiredOutboxEvents(ctx, before)
+		},
+		purge: func(ctx context.Context, q database.Querier, before time.Time) (int64, error) {
+			return q.PurgeOutboxEvents(ctx, database.PurgeOutboxEventsParams{Before: before, RowLimit: retentionBatchSize})
+		},
+	},
+	{
+		name:   "ip_activity",
+		maxAge: func(c config.RetentionConfig) time.Duration { return c.IPActivity },
+		count: func(ctx context.Context, q database.Querier, before time.Time) (int64, error) {
+			return q.CountExpiredIPActivity(ctx, before)
+		},
+		purge: func(ctx context.Context, q database.Querier, before time.Time) (int64, error) {
+			return q.PurgeIPActivity(ctx, database.PurgeIPActivityParams{Before: before, RowLimit: retentionBatchSize})
+		},
+	},
+	{
+		name:   "analytics_events",
+		maxAge: func(c config.RetentionConfig) time.Duration { return c.AnalyticsEvents },
+		count: func(ctx context.Context, q database.Querier, before time.Time) (int64, error) {
+			return q.CountExpiredAnalyticsEvents(ctx, before)
+		},
+		purge: func(ctx context.Context, q database.Querier, before time.Time) (int64, error) {
+			return q.PurgeAnalyticsEvents(ctx, database.PurgeAnalyticsEventsParams{Before: before, RowLimit: retentionBatchSize})
+		},
+	},
+	{
+		name:   "request_captures",
+		maxAge: func(c config.RetentionConfig) time.Duration { return c.RequestCaptures },
+		count: func(ctx context.Context, q database.Querier, before time.Time) (int64, error) {
+			return q.CountExpiredRequestCaptures(ctx, before)
+		},
+		purge: func(ctx context.Context, q database.Querier, before time.Time) (int64, error) {
+			return q.PurgeRequestCaptures(ctx, database.PurgeRequestCapturesParams{Before: before, RowLimit: retentionBatchSize})
+		},
+	},
+}
+
+// retentionStats counts what the cleanup job has removed since the server
+// started, for the admin metrics page.
+type retentionStats struct {
+	mu      sync.Mutex
+	lastRun time.Time
+	dryRun  bool
+	// removed is rows removed by rule; in a dry run, rows that would have
+	// been removed by the last run.
+	removed map[string]int64
+}
+
+type retentionRuleStats struct {
+	Name    string
+	Removed int64
+}
+
+type retentionSnapshot struct {
+	LastRun time.Time
+	DryRun  bool
+	Rules   []retentionRuleStats
+}
+
+func (rs *retentionStats) record(now time.Time, dryRun bool, removed map[string]int64) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	// Dry runs report only their own counts, and don't linger once real
+	// runs start.
+	if rs.removed == nil || dryRun || rs.dryRun {
+		rs.removed = make(map[string]int64)
+	}
+	rs.lastRun = now
+	rs.dryRun = dryRun
+	for name, n := range removed {
+		rs.removed[name] += n
+	}
+}
+
+func (rs *retentionStats) snapshot() retentionSnapshot {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	snap := retentionSnapshot{LastRun: rs.lastRun, DryRun: rs.dryRun}
+	for _, rule := range retentionRules {
+		snap.Rules = append(snap.Rules, retentionRuleStats{Name: rule.name, Removed: rs.removed[rule.name]})
+	}
+	return snap
+}
+
+// applyRetention removes everything past its retention age, a batch at a
+// time, or in a dry run only counts it. Rules with a zero age are skipped.
+func (s *Server) applyRetention(ctx context.Context) error {
+	cfg := s.config.Retention
+	now := s.clock.Now().UTC()
+	removed := make(map[string]int64)
+	defer func() { s.retention.record(now, cfg.DryRun, removed) }()
+
+	for _, rule := range retentionRules {
+		age := rule.maxAge(cfg)
+		if age <= 0 {
+			continue
+		}
+		before := now.Add(-age)
+
+		if cfg.DryRun {
+			n, err := rule.count(ctx, s.db, before)
+			if err != nil {
+				return fmt.Errorf("%s: %w", rule.name, err)
+			}
+			removed[rule.name] = n
+			fmt.Printf("Retention dry run: %d %s past retention\n", n, rule.name)
+			continue
+		}
+		for {
+			n, err := rule.purge(ctx, s.db, before)
+			if err != nil {
+				return fmt.Errorf("%s: %w", rule.name, err)
+			}
+			removed[rule.name] += n
+			if n < retentionBatchSize {
+				break
+			}
+		}
+		if removed[rule.name] > 0 {
+			fmt.Printf("Retention: removed %d %s\n", removed[rule.name], rule.name)
+		}
+	}
+	return nil
+}
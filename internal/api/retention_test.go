@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+)
+
+// retentionStore has a backlog of expired chirps and emails and nothing
+// else to purge.
+type retentionStore struct {
+	fakeStore
+	expiredChirps int64
+	expiredEmails int64
+	chirpsBefore  time.Time
+}
+
+func (s *retentionStore) CountExpiredDeletedChirps(ctx context.Context, before time.Time) (int64, error) {
+	return s.expiredChirps, nil
+}
+
+func (s *retentionStore) CountExpiredEmails(ctx context.Context, before time.Time) (int64, error) {
+	return s.expiredEmails, nil
+}
+
+func (s *retentionStore) PurgeDeletedChirps(ctx context.Context, arg database.PurgeDeletedChirpsParams) (int64, error) {
+	s.chirpsBefore = arg.Before
+	n := min(s.expiredChirps, int64(arg.RowLimit))
+	s.expiredChirps -= n
+	return n, nil
+}
+
+func (s *retentionStore) PurgeEmails(ctx context.Context, arg database.PurgeEmailsParams) (int64, error) {
+	n := min(s.expiredEmails, int64(arg.RowLimit))
+	s.expiredEmails -= n
+	return n, nil
+}
+
+func TestApplyRetention(t *testing.T) {
+	store := &retentionStore{expiredChirps: retentionBatchSize + 5, expiredEmails: 3}
+	cfg := &config.Config{Retention: config.RetentionConfig{
+		DeletedChirps: 30 * 24 * time.Hour,
+		Emails:        90 * 24 * time.Hour,
+		DryRun:        true,
+	}}
+	s := NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)})
+
+	if err := s.applyRetention(context.Background()); err != nil {
+		t.Fatalf("applyRetention returned error: %v", err)
+	}
+	if store.expiredChirps != retentionBatchSize+5 || store.expiredEmails != 3 {
+		t.Fatalf("expected a dry run to remove nothing, %d chirps and %d emails left", store.expiredChirps, store.expiredEmails)
+	}
+	if snap := s.retention.snapshot(); !snap.DryRun || snap.Rules[0].Removed != retentionBatchSize+5 {
+		t.Errorf("expected the dry run to report what it would remove, got %+v", snap)
+	}
+
+	cfg.Retention.DryRun = false
+	if err := s.applyRetention(context.Background()); err != nil {
+		t.Fatalf("applyRetention returned error: %v", err)
+	}
+	if store.expiredChirps != 0 || store.expiredEmails != 0 {
+		t.Errorf("expected every batch to be purged, %d chirps and %d emails left", store.expiredChirps, store.expiredEmails)
+	}
+	if want := testNow.Add(-30 * 24 * time.Hour); !store.chirpsBefore.Equal(want) {
+		t.Errorf("expected chirps deleted before %v to be purged, got %v", want, store.chirpsBefore)
+	}
+
+	snap := s.retention.snapshot()
+	removed := make(map[string]int64)
+	for _, rule := range snap.Rules {
+		removed[rule.Name] = rule.Removed
+	}
+	if snap.DryRun || removed["deleted_chirps"] != retentionBatchSize+5 || removed["emails"] != 3 || removed["ip_activity"] != 0 {
+		t.Errorf("unexpected retention stats: %+v", snap)
+	}
+}
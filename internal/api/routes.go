@@ -0,0 +1,309 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Router is a Server's HTTP handler. It remembers its route patterns so
+// they can be listed.
+type Router struct {
+	serveMux *http.ServeMux
+	handler  http.Handler
+	patterns []string
+	// subrequests serves the requests of a batch. It's the handler without
+	// the middleware the batch itself already went through.
+	subrequests http.Handler
+}
+
+func (r *Router) Handle(pattern string, handler http.Handler) {
+	r.patterns = append(r.patterns, pattern)
+	r.serveMux.Handle(pattern, handler)
+}
+
+func (r *Router) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	r.patterns = append(r.patterns, pattern)
+	r.serveMux.HandleFunc(pattern, handler)
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.handler.ServeHTTP(w, req)
+}
+
+// Patterns lists the registered patterns in registration order.
+func (r *Router) Patterns() []string {
+	return slices.Clone(r.patterns)
+}
+
+// errorResponse is the body of an error that isn't specific to a handler.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// jsonMuxErrors answers requests the mux has no route for with JSON
+// instead of net/http's plain text. The mux still sets the Allow header of
+// a 405.
+type jsonMuxErrors struct {
+	mux *http.ServeMux
+}
+
+func (m jsonMuxErrors) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := m.mux.Handler(r); pattern != "" {
+		m.mux.ServeHTTP(w, r)
+		return
+	}
+	m.mux.ServeHTTP(&muxErrorWriter{ResponseWriter: w}, r)
+}
+
+// muxErrorWriter replaces the plain-text body of the mux's own 404 and 405
+// responses.
+type muxErrorWriter struct {
+	http.ResponseWriter
+	replaced bool
+}
+
+func (w *muxErrorWriter) WriteHeader(code int) {
+	var msg string
+	switch code {
+	case http.StatusNotFound:
+		msg = "Not found"
+	case http.StatusMethodNotAllowed:
+		msg = "Method not allowed"
+	default:
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.replaced = true
+	w.Header().Del("X-Content-Type-Options")
+	jsonResponse(w.ResponseWriter, code, errorResponse{Error: msg})
+}
+
+func (w *muxErrorWriter) Write(p []byte) (int, error) {
+	if w.replaced {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// NewRouter registers every HTTP endpoint of s. Optional features only get
+// routes when they are configured.
+func NewRouter(s *Server) *Router {
+	mux := &Router{serveMux: http.NewServeMux()}
+
+	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("GET /readyz", s.handlerReadiness)
+	mux.HandleFunc("GET /api/version", s.handlerVersion)
+
+	static := s.newStaticHandler()
+	app := s.middlewareMetricsInc(http.StripPrefix(s.appPrefix(), static))
+	mux.Handle(s.appPrefix(), app)
+	mux.HandleFunc("GET "+s.appPrefix()+"profile/{handle}", s.handlerProfilePage(app))
+	mux.HandleFunc("GET /api/assets/manifest", static.handlerManifest)
+	mux.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(http.Dir("assets/"))))
+	mux.HandleFunc("GET /admin/metrics", s.adminMetricsHandler)
+	mux.HandleFunc("POST /admin/reset", s.adminResetHandler)
+	mux.HandleFunc("POST /admin/reset/{scope}", s.handlerAdminResetScoped)
+	mux.HandleFunc("GET /admin/content-flags", s.middlewareRequireAdmin(s.handlerAdminContentFlagsList))
+	mux.HandleFunc("GET /admin/content-rules", s.middlewareRequireAdmin(s.handlerAdminContentRulesList))
+	mux.HandleFunc("POST /admin/content-rules", s.middlewareRequireAdmin(s.handlerAdminContentRulesCreate))
+	mux.HandleFunc("DELETE /admin/content-rules/{ruleID}", s.middlewareRequireAdmin(s.handlerAdminContentRulesDelete))
+	mux.HandleFunc("GET /admin/jobs", s.middlewareRequireAdmin(s.handlerAdminJobs))
+	mux.HandleFunc("GET /admin/dead-letters", s.middlewareRequireAdmin(s.handlerAdminDeadLettersList))
+	mux.HandleFunc("POST /admin/dead-letters/{deadLetterID}/retry", s.middlewareRequireAdmin(s.handlerAdminDeadLetterRetry))
+	mux.HandleFunc("GET /admin/emails/failed", s.middlewareRequireAdmin(s.handlerAdminEmailsFailed))
+	mux.HandleFunc("POST /admin/emails/{emailID}/retry", s.middlewareRequireAdmin(s.handlerAdminEmailRetry))
+	mux.HandleFunc("GET /admin/audit", s.middlewareRequireAdmin(s.handlerAdminAuditList))
+	mux.HandleFunc("POST /admin/impersonate/{userID}", s.middlewareRequireAdmin(s.handlerAdminImpersonate))
+	mux.HandleFunc("GET /admin/ips", s.middlewareRequireAdmin(s.handlerAdminIPsList))
+	mux.HandleFunc("GET /admin/ips/blocks", s.middlewareRequireAdmin(s.handlerAdminIPBlocksList))
+	mux.HandleFunc("POST /admin/ips/blocks", s.middlewareRequireAdmin(s.handlerAdminIPBlocksCreate))
+	mux.HandleFunc("DELETE /admin/ips/blocks/{blockID}", s.middlewareRequireAdmin(s.handlerAdminIPBlocksDelete))
+	mux.HandleFunc("GET /admin/stats", s.middlewareRequireAdmin(s.handlerAdminStats))
+	mux.HandleFunc("GET /admin/runtime", s.middlewareRequireAdmin(s.handlerAdminRuntime))
+	mux.HandleFunc("GET /admin/captures", s.middlewareRequireAdmin(s.handlerAdminCapturesList))
+	mux.HandleFunc("GET /admin/captures/{captureID}", s.middlewareRequireAdmin(s.handlerAdminCaptureGet))
+	mux.HandleFunc("GET /admin/captures/rules", s.middlewareRequireAdmin(s.handlerAdminCaptureRulesList))
+	mux.HandleFunc("POST /admin/captures/rules", s.middlewareRequireAdmin(s.handlerAdminCaptureRulesCreate(mux)))
+	mux.HandleFunc("POST /admin/captures/rules/{ruleID}/stop", s.middlewareRequireAdmin(s.handlerAdminCaptureRuleStop))
+	s.debugHandlers(mux)
+	if s.config.Platform == "dev" {
+		mux.HandleFunc("GET "+chaosPath, s.handlerAdminChaosGet)
+		mux.HandleFunc("PUT "+chaosPath, s.handlerAdminChaosSet(mux))
+		mux.HandleFunc("DELETE "+chaosPath, s.handlerAdminChaosClear)
+	}
+	mux.HandleFunc("POST /admin/legal/{kind}", s.middlewareRequireAdmin(s.handlerAdminLegalPublish))
+	mux.HandleFunc("GET /admin/users", s.middlewareRequireAdmin(s.handlerAdminUsersList))
+	mux.HandleFunc("POST /admin/users/{userID}/ban", s.middlewareRequireAdmin(s.handlerAdminUserBan))
+	mux.HandleFunc("POST /admin/users/{userID}/suspend", s.middlewareRequireAdmin(s.handlerAdminUserSuspend))
+	mux.HandleFunc("POST /admin/users/{userID}/unban", s.middlewareRequireAdmin(s.handlerAdminUserUnban))
+	mux.HandleFunc("POST /admin/users/{userID}/chirps/purge", s.middlewareRequireAdmin(s.handlerAdminUserChirpsPurge))
+	mux.HandleFunc("POST /admin/users/{userID}/shadowban", s.middlewareRequireAdmin(s.handlerAdminUserShadowban))
+	mux.HandleFunc("POST /admin/users/{userID}/unshadowban", s.middlewareRequireAdmin(s.handlerAdminUserUnshadowban))
+	mux.HandleFunc("POST /admin/users/{userID}/verify", s.middlewareRequireAdmin(s.handlerAdminUserVerify))
+	mux.HandleFunc("POST /admin/users/{userID}/unverify", s.middlewareRequireAdmin(s.handlerAdminUserUnverify))
+	mux.HandleFunc("POST /admin/users/{userID}/upgrade", s.middlewareRequireAdmin(s.handlerAdminUserUpgrade))
+	mux.HandleFunc("POST /admin/users/{userID}/downgrade", s.middlewareRequireAdmin(s.handlerAdminUserDowngrade))
+	mux.HandleFunc("POST /admin/users/{userID}/merge", s.middlewareRequireAdmin(s.handlerAdminUserMerge))
+	mux.HandleFunc("POST /admin/oauth/clients", s.middlewareRequireAdmin(s.handlerAdminOAuthClientsCreate))
+	mux.HandleFunc("GET /admin/oauth/clients", s.middlewareRequireAdmin(s.handlerAdminOAuthClientsList))
+	mux.HandleFunc("PUT /admin/oauth/clients/{clientID}/quota", s.middlewareRequireAdmin(s.handlerAdminOAuthClientQuota))
+	if s.federationEnabled() {
+		mux.HandleFunc("GET /ap/users/{userID}", s.handlerAPActor)
+		mux.HandleFunc("GET /ap/users/{userID}/outbox", s.handlerAPOutbox)
+		mux.HandleFunc("GET /ap/users/{userID}/followers", s.handlerAPFollowers)
+		mux.HandleFunc("POST /ap/users/{userID}/inbox", s.handlerAPInbox)
+		mux.HandleFunc("GET /ap/chirps/{chirpID}", s.handlerAPNote)
+		mux.HandleFunc("GET /.well-known/webfinger", s.handlerWebFinger)
+	}
+	if s.config.SCIMToken != "" {
+		mux.HandleFunc("GET /scim/v2/Users", s.middlewareRequireSCIMToken(s.handlerSCIMUsersList))
+		mux.HandleFunc("POST /scim/v2/Users", s.middlewareRequireSCIMToken(s.handlerSCIMUsersCreate))
+		mux.HandleFunc("GET /scim/v2/Users/{userID}", s.middlewareRequireSCIMToken(s.handlerSCIMUserGet))
+		mux.HandleFunc("PATCH /scim/v2/Users/{userID}", s.middlewareRequireSCIMToken(s.handlerSCIMUserPatch))
+		mux.HandleFunc("DELETE /scim/v2/Users/{userID}", s.middlewareRequireSCIMToken(s.handlerSCIMUserDelete))
+	}
+	if s.config.IntrospectionKey != "" {
+		mux.HandleFunc("POST /api/introspect", s.handlerIntrospect)
+	}
+	// Sitemaps need absolute URLs, so like federation they require PUBLIC_URL.
+	if s.config.PublicURL != "" {
+		mux.HandleFunc("GET /sitemap.xml", s.handlerSitemapIndex)
+		mux.HandleFunc("GET /sitemaps/{page}", s.handlerSitemapPage)
+	}
+	if s.billing != nil {
+		mux.HandleFunc("POST "+billingPath+"/checkout", s.handlerBillingCheckout)
+		mux.HandleFunc("GET "+billingPath+"/subscription", s.handlerBillingSubscription)
+		mux.HandleFunc("POST "+billingPath+"/cancel", s.handlerBillingCancel)
+		mux.HandleFunc("POST "+billingPath+"/resume", s.handlerBillingResume)
+		mux.HandleFunc("POST /api/stripe/webhooks", s.handlerStripeWebhook)
+	}
+	if s.storage != nil {
+		mux.HandleFunc("POST /api/media", s.handlerMediaUpload)
+		mux.HandleFunc("GET /media/{mediaID}", s.handlerMediaGet)
+		mux.HandleFunc("GET /media/{mediaID}/{variant}", s.handlerMediaGet)
+	}
+	mux.HandleFunc("GET /api/avatars/{file}", s.handlerAvatar)
+	mux.HandleFunc("GET /api/site", s.handlerSite)
+	mux.HandleFunc("POST /api/chirps", s.handlerChirpsCreate)
+	mux.HandleFunc("POST /api/chirps/batch", s.handlerChirpsBatch)
+	mux.HandleFunc("GET /api/chirps/{chirpID}", s.handlerGetChirp)
+	mux.HandleFunc("GET /api/chirps", s.middlewareResponseCache(s.handlerChirpsList))
+	mux.HandleFunc("GET /api/chirps/stream", s.handlerChirpsStream)
+	mux.HandleFunc("GET /api/chirps/nearby", s.handlerChirpsNearby)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", s.middlewareRequireChirpyRed(s.handlerChirpsEdit))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", s.handlerChirpsDelete)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}/reaction", s.handlerChirpReact)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/reaction", s.handlerChirpUnreact)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/clicks", s.handlerChirpLinkClicks)
+	mux.HandleFunc("GET /c/{code}", s.handlerShortLink)
+	mux.HandleFunc("GET /out", s.handlerOutboundLink)
+	mux.HandleFunc("GET /api/reactions", s.handlerReactionsList)
+	mux.HandleFunc("POST /api/users", s.createUserHandler)
+	mux.HandleFunc("GET /api/users/{userID}", s.handlerUsersGet)
+	mux.HandleFunc("POST /api/users/accept-terms", s.handlerLegalAccept)
+	mux.HandleFunc("GET /api/legal", s.handlerLegalList)
+	mux.HandleFunc("GET /api/legal/{kind}", s.handlerLegalGet)
+	mux.HandleFunc("POST /api/lists", s.handlerListsCreate)
+	mux.HandleFunc("GET /api/lists", s.handlerListsMine)
+	mux.HandleFunc("GET /api/lists/{listID}", s.handlerListsGet)
+	mux.HandleFunc("PUT /api/lists/{listID}", s.handlerListsUpdate)
+	mux.HandleFunc("DELETE /api/lists/{listID}", s.handlerListsDelete)
+	mux.HandleFunc("GET /api/lists/{listID}/members", s.handlerListMembers)
+	mux.HandleFunc("PUT /api/lists/{listID}/members/{userID}", s.handlerListMembersAdd)
+	mux.HandleFunc("DELETE /api/lists/{listID}/members/{userID}", s.handlerListMembersRemove)
+	mux.HandleFunc("GET /api/lists/{listID}/timeline", s.handlerListTimeline)
+	mux.HandleFunc("GET /api/recommendations/users", s.handlerRecommendedUsers)
+	mux.HandleFunc("GET /api/search/typeahead", s.handlerSearchTypeahead)
+	mux.HandleFunc("POST /api/communities", s.handlerCommunitiesCreate)
+	mux.HandleFunc("GET /api/communities/{slug}", s.handlerCommunitiesGet)
+	mux.HandleFunc("POST /api/communities/{slug}/join", s.handlerCommunitiesJoin)
+	mux.HandleFunc("POST /api/communities/{slug}/leave", s.handlerCommunitiesLeave)
+	mux.HandleFunc("POST /api/communities/{slug}/chirps", s.handlerCommunityChirpsCreate)
+	mux.HandleFunc("GET /api/communities/{slug}/chirps", s.handlerCommunityChirpsList)
+	mux.HandleFunc("DELETE /api/communities/{slug}/chirps/{chirpID}", s.handlerCommunityChirpsRemove)
+	mux.HandleFunc("PUT /api/communities/{slug}/moderators/{userID}", s.handlerCommunityModeratorsAdd)
+	mux.HandleFunc("DELETE /api/communities/{slug}/moderators/{userID}", s.handlerCommunityModeratorsRemove)
+	mux.HandleFunc("POST /api/orgs", s.handlerOrgsCreate)
+	mux.HandleFunc("GET /api/orgs/{orgID}", s.handlerOrgsGet)
+	mux.HandleFunc("GET /api/orgs/{orgID}/members", s.handlerOrgMembersList)
+	mux.HandleFunc("PUT /api/orgs/{orgID}/members/{userID}", s.handlerOrgMemberRoleSet)
+	mux.HandleFunc("DELETE /api/orgs/{orgID}/members/{userID}", s.handlerOrgMemberRemove)
+	mux.HandleFunc("POST /api/orgs/{orgID}/invites", s.handlerOrgInvitesCreate)
+	mux.HandleFunc("POST /api/orgs/{orgID}/chirps", s.handlerOrgChirpsCreate)
+	mux.HandleFunc("GET /api/orgs/{orgID}/chirps", s.handlerOrgChirpsList)
+	mux.HandleFunc("GET /api/users/me/chirps/export", s.handlerChirpsExport)
+	mux.HandleFunc("GET /api/users/me/digest", s.handlerDigestPreferencesGet)
+	mux.HandleFunc("PUT /api/users/me/digest", s.handlerDigestPreferencesUpdate)
+	mux.HandleFunc("GET /api/users/me/location", s.handlerLocationPreferencesGet)
+	mux.HandleFunc("PUT /api/users/me/location", s.handlerLocationPreferencesUpdate)
+	mux.HandleFunc("PUT /api/users/me/handle", s.handlerHandleChange)
+	mux.HandleFunc("GET /api/users/me/merges", s.handlerAccountMergesList)
+	mux.HandleFunc("POST /api/users/me/merges/{mergeID}/confirm", s.handlerAccountMergeConfirm)
+	mux.HandleFunc("GET /api/users/me/orgs", s.handlerOrgsMine)
+	mux.HandleFunc("GET /api/users/me/org-invites", s.handlerOrgInvitesMine)
+	mux.HandleFunc("POST /api/users/me/org-invites/{inviteID}/accept", s.handlerOrgInviteAccept)
+	mux.HandleFunc("GET /api/users/me/preferences", s.handlerPreferencesGet)
+	mux.HandleFunc("PATCH /api/users/me/preferences", s.handlerPreferencesUpdate)
+	mux.HandleFunc("GET /api/users/me/content", s.handlerContentPreferencesGet)
+	mux.HandleFunc("PUT /api/users/me/content", s.handlerContentPreferencesUpdate)
+	mux.HandleFunc("GET /api/digests/unsubscribe", s.handlerDigestUnsubscribe)
+	mux.HandleFunc("POST /api/digests/unsubscribe", s.handlerDigestUnsubscribe)
+	mux.HandleFunc("POST /api/import/twitter", s.handlerImportTwitter)
+	mux.HandleFunc("GET /api/import/jobs/{jobID}", s.handlerImportJobGet)
+	mux.HandleFunc("POST /api/login", s.handlerLogin)
+	mux.HandleFunc("GET /oauth/authorize", s.handlerOAuthAuthorize)
+	mux.HandleFunc("POST /oauth/authorize", s.handlerOAuthAuthorizeDecide)
+	mux.HandleFunc("POST /oauth/token", s.handlerOAuthToken)
+	mux.HandleFunc("POST "+appsPath, s.handlerAppsCreate)
+	mux.HandleFunc("GET "+appsPath, s.handlerAppsMine)
+	mux.HandleFunc("GET "+appsPath+"/{clientID}", s.handlerAppsGet)
+	mux.HandleFunc("POST "+appsPath+"/{clientID}/secret", s.handlerAppSecretRotate)
+	mux.HandleFunc("GET "+appsPath+"/{clientID}/tokens", s.handlerAppTokensList)
+	mux.HandleFunc("GET "+appsPath+"/{clientID}/usage", s.handlerAppUsage)
+	mux.HandleFunc("GET /api/v1/accounts/verify_credentials", s.handlerMastodonVerifyCredentials)
+	mux.HandleFunc("GET /api/v1/accounts/{id}", s.handlerMastodonAccount)
+	mux.HandleFunc("GET /api/v1/timelines/home", s.handlerMastodonTimeline(true))
+	mux.HandleFunc("GET /api/v1/timelines/public", s.middlewareResponseCache(s.handlerMastodonTimeline(false)))
+	mux.HandleFunc("POST /api/v1/statuses", s.handlerMastodonStatusCreate)
+	mux.HandleFunc("GET /api/v1/statuses/{id}", s.handlerMastodonStatus)
+	mux.HandleFunc("GET /api/oembed", s.handlerOEmbed)
+	mux.HandleFunc("GET /chirps/{chirpID}", s.handlerChirpPermalink)
+	mux.HandleFunc("POST /api/graphql", s.handlerGraphQL(s.newGraphQLSchema()))
+	mux.HandleFunc("POST "+batchPath, s.handlerBatch(mux))
+
+	// These stay up while the database breakers are open.
+	dbFree := []string{
+		"GET /api/healthz",
+		"GET /readyz",
+		"GET /api/version",
+		"GET /admin/metrics",
+		"GET " + chaosPath,
+		"PUT " + chaosPath,
+		"DELETE " + chaosPath,
+		s.appPrefix(),
+		"GET /api/assets/manifest",
+		"/assets/",
+	}
+	// Wrapped from the inside out: the tenant is resolved first, then the
+	// request timeout starts.
+	var h http.Handler = jsonMuxErrors{mux.serveMux}
+	h = s.middlewareLoaders(h)
+	h = s.middlewareImpersonationAudit(h)
+	h = s.middlewareOAuthScopes(mux.serveMux, h)
+	h = s.middlewareAppQuota(h)
+	h = s.middlewareDeprecation(mux.serveMux, deprecatedRoutes, h)
+	h = s.middlewareRequireLegal(mux.serveMux, h)
+	h = s.middlewareCapture(mux.serveMux, h)
+	h = s.middlewareDBBreaker(mux.serveMux, dbFree, h)
+	h = s.middlewareBlockIPs(h)
+	h = s.middlewareChaos(mux.serveMux, h)
+	h = s.middlewareMethods(mux.serveMux, h)
+	mux.subrequests = h
+	h = s.middlewareRequestTimeout(h)
+	mux.handler = s.middlewareTenant(h)
+	return mux
+}
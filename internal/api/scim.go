@@ -0,0 +1,270 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/scim"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	scimDeactivationReason = "Deactivated via SCIM"
+	scimDefaultCount       = 100
+)
+
+func scimResponse(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func scimError(w http.ResponseWriter, code int, scimType, detail string) {
+	scimResponse(w, code, scim.NewError(code, scimType, detail))
+}
+
+// middlewareRequireSCIMToken checks the provisioning API key that identity
+// providers send as a bearer token. It is separate from user tokens so a
+// leaked key can only provision accounts.
+func (s *Server) middlewareRequireSCIMToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.SCIMToken)) != 1 {
+			scimError(w, http.StatusUnauthorized, "", "Invalid provisioning token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) newSCIMUser(r *http.Request, u database.User) scim.User {
+	active := !u.BannedAt.Valid
+	return scim.User{
+		Schemas:  []string{scim.SchemaUser},
+		ID:       u.ID.String(),
+		UserName: u.Email,
+		Active:   &active,
+		Emails:   []scim.Email{{Value: u.Email, Primary: true}},
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Created:      u.CreatedAt,
+			LastModified: u.UpdatedAt,
+			Location:     s.baseURL(r) + "/scim/v2/Users/" + u.ID.String(),
+		},
+	}
+}
+
+// setUserActive maps SCIM's active flag onto bans. Deactivating an already
+// banned user keeps the original ban and its reason.
+func (s *Server) setUserActive(r *http.Request, user database.User, active bool) (database.User, error) {
+	switch {
+	case active && user.BannedAt.Valid:
+		defer s.responseCache.invalidate()
+		return s.db.UnbanUser(r.Context(), user.ID)
+	case !active && !user.BannedAt.Valid:
+		defer s.responseCache.invalidate()
+		return s.db.BanUser(r.Context(), database.BanUserParams{
+			ID:               user.ID,
+			ModerationReason: scimDeactivationReason,
+		})
+	}
+	return user, nil
+}
+
+// randomPassword gives provisioned users a password nobody knows. They sign
+// in through whatever the identity provider sets up, or reset it.
+func randomPassword() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (s *Server) handlerSCIMUsersList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var users []database.User
+	if filter := query.Get("filter"); filter != "" {
+		email, err := scim.ParseEmailFilter(filter)
+		if err != nil {
+			scimError(w, http.StatusBadRequest, "invalidFilter", "Only userName and emails eq filters are supported")
+			return
+		}
+		user, err := s.db.GetUserByEmail(r.Context(), email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			scimError(w, http.StatusInternalServerError, "", "Something went wrong")
+			return
+		}
+		if err == nil {
+			users = append(users, user)
+		}
+	} else {
+		all, err := s.db.ListUsers(r.Context())
+		if err != nil {
+			scimError(w, http.StatusInternalServerError, "", "Something went wrong")
+			return
+		}
+		users = all
+	}
+
+	// startIndex is 1-based.
+	startIndex, err := strconv.Atoi(query.Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil || count < 0 {
+		count = scimDefaultCount
+	}
+
+	page := users[min(startIndex-1, len(users)):]
+	page = page[:min(count, len(page))]
+
+	resp := scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: len(users),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    make([]scim.User, 0, len(page)),
+	}
+	for _, u := range page {
+		resp.Resources = append(resp.Resources, s.newSCIMUser(r, u))
+	}
+	scimResponse(w, http.StatusOK, resp)
+}
+
+func (s *Server) handlerSCIMUsersCreate(w http.ResponseWriter, r *http.Request) {
+	var req scim.User
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+
+	email := req.PrimaryEmail()
+	if !IsValidEmail(email) {
+		scimError(w, http.StatusBadRequest, "invalidValue", "userName or emails must contain a valid email address")
+		return
+	}
+
+	password := req.Password
+	if password == "" {
+		var err error
+		if password, err = randomPassword(); err != nil {
+			scimError(w, http.StatusInternalServerError, "", "Something went wrong")
+			return
+		}
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		scimError(w, http.StatusInternalServerError, "", "Something went wrong")
+		return
+	}
+
+	user, err := s.db.CreateUser(r.Context(), database.CreateUserParams{
+		ID:             uuid.New(),
+		Email:          email,
+		HashedPassword: hash,
+	})
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		scimError(w, http.StatusConflict, "uniqueness", "A user with this email already exists")
+		return
+	}
+	if err != nil {
+		fmt.Println("Error provisioning user:", err)
+		scimError(w, http.StatusInternalServerError, "", "Something went wrong")
+		return
+	}
+
+	if req.Active != nil && !*req.Active {
+		if user, err = s.setUserActive(r, user, false); err != nil {
+			scimError(w, http.StatusInternalServerError, "", "Something went wrong")
+			return
+		}
+	}
+
+	resp := s.newSCIMUser(r, user)
+	w.Header().Set("Location", resp.Meta.Location)
+	scimResponse(w, http.StatusCreated, resp)
+}
+
+// scimUser loads the user named by the {userID} path value, writing a SCIM
+// error if there isn't one.
+func (s *Server) scimUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		scimError(w, http.StatusNotFound, "", "User not found")
+		return database.User{}, false
+	}
+	user, err := s.db.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		scimError(w, http.StatusNotFound, "", "User not found")
+		return database.User{}, false
+	}
+	if err != nil {
+		scimError(w, http.StatusInternalServerError, "", "Something went wrong")
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func (s *Server) handlerSCIMUserGet(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.scimUser(w, r)
+	if !ok {
+		return
+	}
+	scimResponse(w, http.StatusOK, s.newSCIMUser(r, user))
+}
+
+// handlerSCIMUserPatch supports the one change identity providers make
+// routinely: toggling "active" to deactivate or restore an account.
+func (s *Server) handlerSCIMUserPatch(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.scimUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req scim.PatchOp
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scimError(w, http.StatusBadRequest, "invalidSyntax", "Invalid request body")
+		return
+	}
+	active, set, err := req.Active()
+	if err != nil {
+		scimError(w, http.StatusBadRequest, "invalidPath", err.Error())
+		return
+	}
+	if set {
+		if user, err = s.setUserActive(r, user, active); err != nil {
+			scimError(w, http.StatusInternalServerError, "", "Something went wrong")
+			return
+		}
+	}
+	scimResponse(w, http.StatusOK, s.newSCIMUser(r, user))
+}
+
+// handlerSCIMUserDelete deactivates rather than deletes, so a user removed
+// from the identity provider by mistake keeps their chirps.
+func (s *Server) handlerSCIMUserDelete(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.scimUser(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.setUserActive(r, user, false); err != nil {
+		scimError(w, http.StatusInternalServerError, "", "Something went wrong")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,330 @@
+// Package api implements Chirpy's HTTP and gRPC APIs and the background
+// jobs that support them.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"chirpy/internal/analytics"
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/contentfilter"
+	"chirpy/internal/counter"
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+	"chirpy/internal/ipblock"
+	"chirpy/internal/linkscan"
+	"chirpy/internal/mail"
+	"chirpy/internal/scheduler"
+	"chirpy/internal/storage"
+	"chirpy/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+// Store is the database as the handlers see it.
+type Store interface {
+	database.Querier
+	// EachChirp streams the rows of GetChirps to fn.
+	EachChirp(ctx context.Context, viewerID uuid.UUID, fn func(database.GetChirpsRow) error) error
+	// Begin starts a transaction. Callers must Commit or Rollback it.
+	Begin(ctx context.Context) (Tx, error)
+	Ping(ctx context.Context) error
+	Stats() sql.DBStats
+}
+
+// Tx runs queries in a transaction.
+type Tx interface {
+	database.Querier
+	Commit() error
+	Rollback() error
+}
+
+// Clock tells the time, so tests can fix it. Implementations return UTC.
+type Clock interface {
+	Now() time.Time
+}
+
+// TokenIssuer issues and validates access tokens.
+type TokenIssuer interface {
+	// Issue issues a token for userID that carries profile.
+	Issue(userID uuid.UUID, profile auth.Profile, ttl time.Duration) (string, error)
+	// IssueImpersonation issues a token for userID that records actorID as
+	// the real caller.
+	IssueImpersonation(userID, actorID uuid.UUID, ttl time.Duration) (string, error)
+	// IssueForClient issues a token for userID that the third-party app
+	// clientID may use within scopes.
+	IssueForClient(userID uuid.UUID, clientID string, scopes []string, ttl time.Duration) (string, error)
+	// Validate returns the token's claims.
+	Validate(token string) (auth.Claims, error)
+}
+
+// Mailer sends email. The email worker is its only caller; everything else
+// queues messages in the database.
+type Mailer interface {
+	Send(ctx context.Context, msg mail.Message) error
+}
+
+// Deps are a Server's collaborators. Clock, Tokens, Mailer and Analytics
+// have defaults; Store is required.
+type Deps struct {
+	Store  Store
+	Clock  Clock
+	Tokens TokenIssuer
+	Mailer Mailer
+	// Hub delivers chirp events. Without one, streams only see chirps
+	// created by this process.
+	Hub *events.Hub
+	// Analytics receives product events. Without it they are dropped.
+	Analytics analytics.Recorder
+	// Static is the frontend served under APP_PREFIX unless STATIC_DIR
+	// points elsewhere. Without either, there is no frontend.
+	Static fs.FS
+	// Locker keeps periodic jobs to one server at a time. Without it, every
+	// server runs them.
+	Locker scheduler.Locker
+	// Hits counts visits to the frontend. Without it, each server counts
+	// its own.
+	Hits counter.Counter
+	// Breakers turn requests away while the database is failing. Pass the
+	// same ones to NewSQLStore. Without them, requests always go through.
+	Breakers *DBBreakers
+	// SlowQueries counts slow queries for the metrics page. Pass the same
+	// one to NewSQLStore.
+	SlowQueries *SlowQueries
+	// LinkScanner checks the links in new chirps. Without it, links are
+	// recorded but never flagged.
+	LinkScanner linkscan.Scanner
+	// Storage keeps uploaded media. Without it, uploads are off.
+	Storage storage.Storage
+	// Billing sells Chirpy Red subscriptions. Without it, billing is off.
+	Billing Billing
+}
+
+type Server struct {
+	hits          counter.Counter
+	db            Store
+	breakers      *DBBreakers
+	slowQueries   *SlowQueries
+	config        *config.Config
+	clock         Clock
+	tokens        TokenIssuer
+	mailer        Mailer
+	hub           *events.Hub
+	analytics     analytics.Recorder
+	static        fs.FS
+	statsCache    statsCache
+	ipBlocks      ipblock.List
+	contentFilter atomic.Pointer[contentfilter.Filter]
+	// chaosRules are the faults chaos mode injects, or nil.
+	chaosRules atomic.Pointer[[]chaosRule]
+	// captureRules are the active request capture rules, or nil.
+	captureRules atomic.Pointer[[]database.CaptureRule]
+	// legal is the legal documents in force, or nil before any are
+	// published.
+	legal atomic.Pointer[legalState]
+	// tenants are the communities served alongside the default one, or
+	// nil outside multi-tenant mode.
+	tenants  atomic.Pointer[tenant.Set]
+	sitemaps sitemapStore
+	// responseCache is nil when disabled.
+	responseCache *responseCache
+	// typeaheadCache holds recent typeahead results by query.
+	typeaheadCache *responseCache
+	retention      retentionStats
+	deprecations   deprecationUsage
+	// jobs runs the periodic jobs once Start is called.
+	jobs      *scheduler.Scheduler
+	startedAt time.Time
+
+	federationClient *http.Client
+	linkScanner      linkscan.Scanner
+	storage          storage.Storage
+	billing          Billing
+}
+
+func NewServer(cfg *config.Config, deps Deps) *Server {
+	s := &Server{
+		db:          deps.Store,
+		config:      cfg,
+		clock:       deps.Clock,
+		tokens:      deps.Tokens,
+		mailer:      deps.Mailer,
+		hub:         deps.Hub,
+		analytics:   deps.Analytics,
+		static:      deps.Static,
+		hits:        deps.Hits,
+		breakers:    deps.Breakers,
+		slowQueries: deps.SlowQueries,
+
+		typeaheadCache:   newResponseCache(typeaheadCacheTTL, typeaheadCacheMaxBytes),
+		federationClient: &http.Client{Timeout: 15 * time.Second},
+		linkScanner:      deps.LinkScanner,
+		storage:          deps.Storage,
+		billing:          deps.Billing,
+	}
+	if s.clock == nil {
+		s.clock = SystemClock{}
+	}
+	s.startedAt = s.clock.Now()
+	s.jobs = scheduler.New(s.clock.Now, deps.Locker)
+	if s.tokens == nil {
+		s.tokens = JWTIssuer{Secret: cfg.JWTSecret, Leeway: cfg.JWTLeeway}
+	}
+	if s.mailer == nil {
+		s.mailer = &mail.LogSender{From: cfg.Mail.From}
+	}
+	if s.hub == nil {
+		s.hub = events.NewHub()
+	}
+	if s.analytics == nil {
+		s.analytics = analytics.Discard{}
+	}
+	if s.hits == nil {
+		s.hits = &counter.Local{}
+	}
+	if cfg.ResponseCacheTTL > 0 {
+		s.responseCache = newResponseCache(cfg.ResponseCacheTTL, cfg.ResponseCacheMaxBytes)
+	}
+	return s
+}
+
+// Start loads the tenants, IP blocks, content rules, capture rules and
+// legal documents, then starts the background jobs. They run until ctx is
+// done.
+func (s *Server) Start(ctx context.Context) {
+	if err := s.reloadTenants(ctx); err != nil {
+		fmt.Println("Error loading tenants:", err)
+	}
+	if err := s.reloadIPBlocks(ctx); err != nil {
+		fmt.Println("Error loading IP blocks:", err)
+	}
+	if err := s.reloadContentRules(ctx); err != nil {
+		fmt.Println("Error loading content rules:", err)
+	}
+	if err := s.reloadCaptureRules(ctx); err != nil {
+		fmt.Println("Error loading capture rules:", err)
+	}
+	if err := s.reloadLegalDocuments(ctx); err != nil {
+		fmt.Println("Error loading legal documents:", err)
+	}
+	go s.watchTenants(ctx, s.hub)
+	go s.watchContentRules(ctx, s.hub)
+	go s.watchIPBlocks(ctx, s.hub)
+	go s.watchCaptureRules(ctx, s.hub)
+	go s.watchLegalDocuments(ctx, s.hub)
+	go s.runEmailWorker(ctx)
+	go s.runOutboxRelay(ctx)
+	if s.responseCache != nil {
+		go s.invalidateOnChirpEvents(ctx)
+	}
+	s.scheduleJobs()
+	s.jobs.Start(ctx)
+}
+
+// NewSQLStore is the Store backed by a Postgres connection pool. Queries
+// report their outcomes to breakers, and their durations to slow, if there
+// are any.
+func NewSQLStore(db *sql.DB, breakers *DBBreakers, slow *SlowQueries) Store {
+	return sqlStore{Queries: database.New(wrapDB(db, breakers, slow)), db: db, breakers: breakers, slow: slow}
+}
+
+// NewTenantSQLStore is the Store for multi-tenant mode: each query runs on
+// the pool of its context's tenant, whose connections see only that
+// tenant's rows, and on db when the context has none.
+func NewTenantSQLStore(pools *tenant.Pools, db *sql.DB, breakers *DBBreakers, slow *SlowQueries) Store {
+	return sqlStore{Queries: database.New(wrapDB(pools, breakers, slow)), db: db, pools: pools, breakers: breakers, slow: slow}
+}
+
+// wrapDB adds slow query logging, the breakers, and chaos mode's dropped
+// connections to db.
+func wrapDB(db database.DBTX, breakers *DBBreakers, slow *SlowQueries) database.DBTX {
+	if slow != nil {
+		db = slowQueryDB{db: db, slow: slow, now: time.Now}
+	}
+	if breakers != nil {
+		db = breakerDB{db: db, breakers: breakers}
+	}
+	return chaosDB{db: db}
+}
+
+type sqlStore struct {
+	*database.Queries
+	db *sql.DB
+	// pools is nil outside multi-tenant mode.
+	pools    *tenant.Pools
+	breakers *DBBreakers
+	slow     *SlowQueries
+}
+
+func (s sqlStore) Begin(ctx context.Context) (Tx, error) {
+	ctx = chaosContext(ctx)
+	db := s.db
+	if s.pools != nil {
+		db = s.pools.DB(ctx)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		s.breakers.record(ctx, queryWrite, err)
+		return nil, err
+	}
+	return sqlTx{Queries: database.New(wrapDB(tx, s.breakers, s.slow)), tx: tx}, nil
+}
+
+func (s sqlStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s sqlStore) Stats() sql.DBStats {
+	return s.db.Stats()
+}
+
+type sqlTx struct {
+	*database.Queries
+	tx *sql.Tx
+}
+
+func (t sqlTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t sqlTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// SystemClock is the real time, in UTC. Times are stored in TIMESTAMP
+// columns, which drop the zone, so everything written must be UTC.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// JWTIssuer issues HS256 tokens signed with Secret, and accepts them
+// within Leeway of their exp and nbf.
+type JWTIssuer struct {
+	Secret string
+	Leeway time.Duration
+}
+
+func (j JWTIssuer) Issue(userID uuid.UUID, profile auth.Profile, ttl time.Duration) (string, error) {
+	return auth.MakeJWT(userID, j.Secret, ttl, auth.WithProfile(profile))
+}
+
+func (j JWTIssuer) IssueImpersonation(userID, actorID uuid.UUID, ttl time.Duration) (string, error) {
+	return auth.MakeImpersonationJWT(userID, actorID, j.Secret, ttl)
+}
+
+func (j JWTIssuer) IssueForClient(userID uuid.UUID, clientID string, scopes []string, ttl time.Duration) (string, error) {
+	return auth.MakeJWT(userID, j.Secret, ttl, auth.WithScopes(clientID, scopes))
+}
+
+func (j JWTIssuer) Validate(token string) (auth.Claims, error) {
+	return auth.ValidateJWT(token, j.Secret, j.Leeway)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"chirpy/internal/database"
+	"chirpy/internal/shortlink"
+
+	"github.com/google/uuid"
+)
+
+// shortURL is chirpID's short link, for share buttons.
+func (s *Server) shortURL(ctx context.Context, chirpID uuid.UUID) string {
+	return s.siteURL(ctx) + "/c/" + shortlink.Encode(chirpID)
+}
+
+// referrerHost is the host a request was referred from, or "" if the
+// Referer header is missing or not an absolute URL.
+func referrerHost(r *http.Request) string {
+	u, err := url.Parse(r.Referer())
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// handlerShortLink counts a click on a chirp's short link and sends the
+// visitor on to the chirp. The redirect is temporary so that browsers
+// come back, and get counted, every time.
+func (s *Server) handlerShortLink(w http.ResponseWriter, r *http.Request) {
+	low, high, ok := shortlink.Decode(r.PathValue("code"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	chirpID, err := s.db.RecordShortLinkClick(r.Context(), database.RecordShortLinkClickParams{
+		Referrer: referrerHost(r),
+		Low:      low,
+		High:     high,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		fmt.Println("Error recording short link click:", err)
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, s.chirpPermalink(r.Context(), chirpID.String()), http.StatusFound)
+}
+
+type linkClicksResponse struct {
+	Referrer string `json:"referrer"`
+	Clicks   int64  `json:"clicks"`
+}
+
+// handlerChirpLinkClicks shows a chirp's author where visits to its short
+// link came from, most first.
+func (s *Server) handlerChirpLinkClicks(w http.ResponseWriter, r *http.Request) {
+	userID, err := s.authenticate(r)
+	if err != nil {
+		jsonResponse(w, http.StatusUnauthorized, "Couldn't validate token")
+		return
+	}
+	chirpID, ok := pathUUID(w, r, "chirpID")
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	chirp, err := s.db.GetChirp(ctx, chirpID)
+	if err != nil {
+		jsonResponse(w, http.StatusNotFound, "Chirp was not found.")
+		return
+	}
+	if chirp.UserID != userID {
+		jsonResponse(w, http.StatusForbidden, "Only the author can see a chirp's clicks")
+		return
+	}
+
+	rows, err := s.db.ListChirpLinkClicks(ctx, chirpID)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	resp := make([]linkClicksResponse, 0, len(rows))
+	for _, row := range rows {
+		resp = append(resp, linkClicksResponse{Referrer: row.Referrer, Clicks: row.Clicks})
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type shortLinkStore struct {
+	fakeStore
+	chirp  database.Chirp
+	clicks map[string]int64
+}
+
+func (s *shortLinkStore) RecordShortLinkClick(ctx context.Context, arg database.RecordShortLinkClickParams) (uuid.UUID, error) {
+	id := s.chirp.ID
+	if bytes.Compare(arg.Low[:], id[:]) > 0 || bytes.Compare(id[:], arg.High[:]) > 0 {
+		return uuid.Nil, sql.ErrNoRows
+	}
+	s.clicks[arg.Referrer]++
+	return id, nil
+}
+
+func (s *shortLinkStore) GetChirp(ctx context.Context, id uuid.UUID) (database.Chirp, error) {
+	if id != s.chirp.ID {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+	return s.chirp, nil
+}
+
+func (s *shortLinkStore) ListChirpLinkClicks(ctx context.Context, chirpID uuid.UUID) ([]database.ListChirpLinkClicksRow, error) {
+	var rows []database.ListChirpLinkClicksRow
+	for referrer, clicks := range s.clicks {
+		rows = append(rows, database.ListChirpLinkClicksRow{Referrer: referrer, Clicks: clicks})
+	}
+	return rows, nil
+}
+
+func TestShortLinks(t *testing.T) {
+	author := newTestUser(t, "author@example.com", "pa55word")
+	reader := newTestUser(t, "reader@example.com", "pa55word")
+	store := &shortLinkStore{
+		fakeStore: fakeStore{users: map[string]database.User{author.Email: author, reader.Email: reader}},
+		chirp:     database.Chirp{ID: uuid.New(), UserID: author.ID},
+		clicks:    map[string]int64{},
+	}
+	cfg := &config.Config{JWTSecret: "test-secret", PublicURL: "https://chirpy.example"}
+	s := NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)})
+	h := NewRouter(s)
+
+	short := s.shortURL(context.Background(), store.chirp.ID)
+	path := strings.TrimPrefix(short, cfg.PublicURL)
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Referer", "https://News.example/front?page=2")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://chirpy.example/chirps/"+store.chirp.ID.String() {
+		t.Fatalf("expected a 302 to the chirp, got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+	do(h, http.MethodGet, path, "")
+	if store.clicks["news.example"] != 1 || store.clicks[""] != 1 {
+		t.Errorf("expected one referred and one direct click, got %v", store.clicks)
+	}
+
+	for _, p := range []string{"/c/Ab3xY", "/c/00000000000"} {
+		if rec := do(h, http.MethodGet, p, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: expected 404, got %d", p, rec.Code)
+		}
+	}
+
+	send := func(userID uuid.UUID) *httptest.ResponseRecorder {
+		token, err := auth.MakeJWT(userID, cfg.JWTSecret, time.Hour)
+		if err != nil {
+			t.Fatalf("MakeJWT returned error: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+store.chirp.ID.String()+"/clicks", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := send(reader.ID); rec.Code != http.StatusForbidden {
+		t.Errorf("another user's clicks: expected 403, got %d", rec.Code)
+	}
+	rec = send(author.ID)
+	var clicks []linkClicksResponse
+	if err := json.NewDecoder(rec.Body).Decode(&clicks); err != nil || len(clicks) != 2 {
+		t.Errorf("expected two referrers, got %d: %v", rec.Code, clicks)
+	}
+}
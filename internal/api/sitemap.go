@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"chirpy/internal/sitemap"
+	"chirpy/internal/tenant"
+)
+
+// sitemapStore holds the most recently generated sitemaps. Requests are
+// served from memory; the sitemaps job rebuilds it on a schedule.
+type sitemapStore struct {
+	mu  sync.RWMutex
+	set sitemap.Set
+}
+
+func (s *sitemapStore) load() sitemap.Set {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.set
+}
+
+func (s *sitemapStore) store(set sitemap.Set) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set = set
+}
+
+func (s *Server) profileURL(handle string) string {
+	return s.appURL(s.config.PublicURL, "profile/@"+handle)
+}
+
+func (s *Server) chirpPermalink(ctx context.Context, chirpID string) string {
+	return s.siteURL(ctx) + "/chirps/" + chirpID
+}
+
+// buildSitemaps lists every public profile and chirp. Banned and
+// shadowbanned users are left out, as are users without a handle since
+// they have no profile page.
+func (s *Server) buildSitemaps(ctx context.Context) (sitemap.Set, error) {
+	users, err := s.db.ListSitemapUsers(ctx)
+	if err != nil {
+		return sitemap.Set{}, err
+	}
+	chirps, err := s.db.ListSitemapChirps(ctx)
+	if err != nil {
+		return sitemap.Set{}, err
+	}
+
+	urls := make([]sitemap.URL, 0, len(users)+len(chirps))
+	for _, u := range users {
+		urls = append(urls, sitemap.URL{Loc: s.profileURL(u.Handle.String), LastMod: u.UpdatedAt})
+	}
+	for _, c := range chirps {
+		urls = append(urls, sitemap.URL{Loc: s.chirpPermalink(ctx, c.ID.String()), LastMod: c.UpdatedAt})
+	}
+
+	return sitemap.Build(urls, sitemap.MaxURLs, func(n int) string {
+		return s.config.PublicURL + "/sitemaps/" + strconv.Itoa(n) + ".xml"
+	})
+}
+
+// refreshSitemaps rebuilds the sitemaps. A failed build keeps serving the
+// previous set. In multi-tenant mode they cover the default community
+// only.
+func (s *Server) refreshSitemaps(ctx context.Context) error {
+	if s.config.MultiTenant {
+		ctx = tenant.NewContext(ctx, tenant.Tenant{})
+	}
+	set, err := s.buildSitemaps(ctx)
+	if err != nil {
+		return err
+	}
+	s.sitemaps.store(set)
+	return nil
+}
+
+func writeSitemap(w http.ResponseWriter, b []byte) {
+	if b == nil {
+		http.Error(w, "Sitemap is not ready yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+func (s *Server) handlerSitemapIndex(w http.ResponseWriter, r *http.Request) {
+	if !currentTenant(r.Context()).IsDefault() {
+		http.NotFound(w, r)
+		return
+	}
+	writeSitemap(w, s.sitemaps.load().Index)
+}
+
+func (s *Server) handlerSitemapPage(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(strings.TrimSuffix(r.PathValue("page"), ".xml"))
+	pages := s.sitemaps.load().Pages
+	if err != nil || n < 1 || n > len(pages) || !currentTenant(r.Context()).IsDefault() {
+		http.NotFound(w, r)
+		return
+	}
+	writeSitemap(w, pages[n-1])
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// SlowQueries logs and counts the queries that take longer than a
+// threshold. The SQL store times every query against it.
+type SlowQueries struct {
+	threshold time.Duration
+	// log is where slow queries are reported; fmt.Println by default.
+	log func(a ...interface{})
+
+	mu     sync.Mutex
+	counts map[string]*slowQueryStats
+}
+
+type slowQueryStats struct {
+	Name    string
+	Count   int64
+	Slowest time.Duration
+}
+
+// NewSlowQueries logs queries slower than threshold, or returns nil, which
+// turns logging off, when threshold is zero.
+func NewSlowQueries(threshold time.Duration) *SlowQueries {
+	if threshold <= 0 {
+		return nil
+	}
+	return &SlowQueries{
+		threshold: threshold,
+		log:       func(a ...interface{}) { fmt.Println(a...) },
+		counts:    make(map[string]*slowQueryStats),
+	}
+}
+
+// record notes a query that ran for elapsed.
+func (sq *SlowQueries) record(query string, args []interface{}, elapsed time.Duration) {
+	if sq == nil || elapsed < sq.threshold {
+		return
+	}
+	name := queryName(query)
+	sq.log("Slow query:", name, "took", elapsed.Round(time.Millisecond), "with", redactArgs(args))
+
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	st, ok := sq.counts[name]
+	if !ok {
+		st = &slowQueryStats{Name: name}
+		sq.counts[name] = st
+	}
+	st.Count++
+	st.Slowest = max(st.Slowest, elapsed)
+}
+
+// snapshot lists the queries that have been slow, most often slow first.
+func (sq *SlowQueries) snapshot() []slowQueryStats {
+	if sq == nil {
+		return nil
+	}
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	stats := make([]slowQueryStats, 0, len(sq.counts))
+	for _, st := range sq.counts {
+		stats = append(stats, *st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// queryName is the sqlc name of query, from its "-- name:" comment, or
+// "unnamed" for SQL written by hand.
+func queryName(query string) string {
+	line, _, _ := strings.Cut(query, "\n")
+	if rest, ok := strings.CutPrefix(line, "-- name: "); ok {
+		name, _, _ := strings.Cut(rest, " ")
+		return name
+	}
+	return "unnamed"
+}
+
+// redactArgs describes a query's parameters for the log. IDs, numbers,
+// flags and times are kept, as they help find the slow case; text and
+// bytes could be anyone's email or password hash, so only their size is.
+func redactArgs(args []interface{}) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = "$" + strconv.Itoa(i+1) + "=" + redactArg(arg)
+	}
+	return "[" + strings.Join(parts, " ") + "]"
+}
+
+func redactArg(arg interface{}) string {
+	switch a := arg.(type) {
+	case nil:
+		return "NULL"
+	case uuid.UUID:
+		return a.String()
+	case uuid.NullUUID:
+		if !a.Valid {
+			return "NULL"
+		}
+		return a.UUID.String()
+	case bool, int, int32, int64, float64:
+		return fmt.Sprint(a)
+	case time.Time:
+		return a.UTC().Format(time.RFC3339Nano)
+	case string:
+		return "<" + strconv.Itoa(len(a)) + " bytes>"
+	case []byte:
+		return "<" + strconv.Itoa(len(a)) + " bytes>"
+	case driver.Valuer:
+		// sql.NullString and friends, and arrays.
+		if v, err := a.Value(); err == nil {
+			return redactArg(v)
+		}
+	}
+	return fmt.Sprintf("<%T>", arg)
+}
+
+// slowQueryDB times each query run on db. For queries returning rows,
+// that's the time to the first row, not to reading them all.
+type slowQueryDB struct {
+	db   database.DBTX
+	slow *SlowQueries
+	now  func() time.Time
+}
+
+func (s slowQueryDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := s.now()
+	res, err := s.db.ExecContext(ctx, query, args...)
+	s.slow.record(query, args, s.now().Sub(start))
+	return res, err
+}
+
+func (s slowQueryDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return s.db.PrepareContext(ctx, query)
+}
+
+func (s slowQueryDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := s.now()
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	s.slow.record(query, args, s.now().Sub(start))
+	return rows, err
+}
+
+func (s slowQueryDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := s.now()
+	row := s.db.QueryRowContext(ctx, query, args...)
+	s.slow.record(query, args, s.now().Sub(start))
+	return row
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSlowQueries(t *testing.T) {
+	slow := NewSlowQueries(100 * time.Millisecond)
+	var logged []string
+	slow.log = func(a ...interface{}) { logged = append(logged, fmt.Sprintln(a...)) }
+
+	// Each query takes as long as the next entry of durations.
+	durations := []time.Duration{250 * time.Millisecond, 20 * time.Millisecond, 100 * time.Millisecond}
+	now := testNow
+	calls := 0
+	db := slowQueryDB{db: downDB{err: errors.New("down")}, slow: slow, now: func() time.Time {
+		if calls%2 == 1 {
+			now = now.Add(durations[calls/2])
+		}
+		calls++
+		return now
+	}}
+
+	id := uuid.MustParse("6f1a2b3c-0000-4000-8000-000000000009")
+	db.QueryContext(context.Background(), testReadQuery, id, sql.NullString{String: "saul@example.com", Valid: true}, int32(50))
+	db.ExecContext(context.Background(), "-- name: DeleteChirp :exec\nDELETE FROM chirps WHERE id = $1\n", id)
+	db.QueryContext(context.Background(), "SELECT 1", "hunter2", nil, testNow)
+
+	want := []string{
+		"Slow query: GetChirps took 250ms with [$1=6f1a2b3c-0000-4000-8000-000000000009 $2=<16 bytes> $3=50]\n",
+		"Slow query: unnamed took 100ms with [$1=<7 bytes> $2=NULL $3=2025-06-01T12:00:00Z]\n",
+	}
+	if strings.Join(logged, "") != strings.Join(want, "") {
+		t.Errorf("logged:\n%s\nwant:\n%s", strings.Join(logged, ""), strings.Join(want, ""))
+	}
+	for _, line := range logged {
+		if strings.Contains(line, "saul@example.com") || strings.Contains(line, "hunter2") {
+			t.Errorf("text parameters must be redacted: %s", line)
+		}
+	}
+
+	stats := slow.snapshot()
+	if len(stats) != 2 || stats[0] != (slowQueryStats{Name: "GetChirps", Count: 1, Slowest: 250 * time.Millisecond}) || stats[1].Name != "unnamed" {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	if NewSlowQueries(0) != nil {
+		t.Error("a zero threshold should turn slow query logging off")
+	}
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"chirpy/internal/config"
+	"chirpy/internal/fingerprint"
+)
+
+// appPrefix is the frontend's URL path, e.g. "/app/".
+func (s *Server) appPrefix() string {
+	return config.NormalizePrefix(s.config.AppPrefix)
+}
+
+// appURL links to path within the frontend, relative to base.
+func (s *Server) appURL(base, path string) string {
+	return base + s.appPrefix() + path
+}
+
+// immutableCacheControl lets browsers keep fingerprinted files forever;
+// a changed file gets a new name.
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// staticHandler serves the frontend. Every file is also
+// served under its fingerprinted name with a long-lived cache header;
+// everything else, index.html in particular, must be revalidated.
+type staticHandler struct {
+	root     http.FileSystem
+	files    http.Handler
+	manifest *fingerprint.Manifest
+}
+
+// newStaticHandler fingerprints the frontend. Files added or changed
+// later are served, but not under a fingerprinted name until the next
+// restart.
+func (s *Server) newStaticHandler() *staticHandler {
+	fsys := s.static
+	if s.config.StaticDir != "" {
+		fsys = os.DirFS(s.config.StaticDir)
+	}
+	if fsys == nil {
+		fsys = noFiles{}
+	}
+	manifest, err := fingerprint.Build(fsys)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Println("Error fingerprinting static files:", err)
+	}
+	root := noDotfiles{http.FS(fsys)}
+	return &staticHandler{root: root, files: http.FileServer(root), manifest: manifest}
+}
+
+// ServeHTTP answers requests for paths that don't exist with index.html,
+// so a client-side router can handle deep links like /app/profile/@bob.
+// Paths with an extension are assumed to be assets and still 404 when
+// missing, as do dotfiles.
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := path.Clean("/" + r.URL.Path)
+	if original, ok := h.manifest.Resolve(strings.TrimPrefix(name, "/")); ok {
+		w.Header().Set("Cache-Control", immutableCacheControl)
+		r = r.Clone(r.Context())
+		r.URL.Path = "/" + original
+		h.files.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	if f, err := h.root.Open(name); err == nil {
+		f.Close()
+		h.files.ServeHTTP(w, r)
+		return
+	}
+	if hidden(name) || path.Ext(name) != "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	index, err := h.root.Open("/index.html")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer index.Close()
+	info, err := index.Stat()
+	if err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, r, "index.html", info.ModTime(), index)
+}
+
+// handlerManifest tells the frontend the fingerprinted name of each static
+// file, relative to APP_PREFIX.
+func (h *staticHandler) handlerManifest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-cache")
+	jsonResponse(w, http.StatusOK, h.manifest.Names())
+}
+
+// hidden reports whether any element of name starts with a dot.
+func hidden(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// noDotfiles hides files and directories whose name starts with a dot,
+// such as .env and .git, as if they did not exist.
+type noDotfiles struct {
+	fs http.FileSystem
+}
+
+func (d noDotfiles) Open(name string) (http.File, error) {
+	if hidden(name) {
+		return nil, fs.ErrNotExist
+	}
+	f, err := d.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return dotfileHidingFile{f}, nil
+}
+
+// dotfileHidingFile leaves dotfiles out of directory listings.
+type dotfileHidingFile struct {
+	http.File
+}
+
+func (f dotfileHidingFile) Readdir(n int) ([]fs.FileInfo, error) {
+	infos, err := f.File.Readdir(n)
+	visible := infos[:0]
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Name(), ".") {
+			visible = append(visible, info)
+		}
+	}
+	return visible, err
+}
+
+// noFiles is an empty file system, for running without a frontend.
+type noFiles struct{}
+
+func (noFiles) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"chirpy/internal/config"
+)
+
+func newStaticTestServer(t *testing.T, prefix string) http.Handler {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"index.html":       "<h1>Chirpy</h1>",
+		".env":             "JWT_SECRET=hunter2",
+		".git/config":      "[core]",
+		"docs/guide.txt":   "guide",
+		"docs/.draft.txt":  "draft",
+		"docs/.hidden/a.x": "hidden",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := &config.Config{StaticDir: dir, AppPrefix: prefix}
+	return NewRouter(NewServer(cfg, Deps{Store: &fakeStore{}}))
+}
+
+func TestStatic_ServesStaticDir(t *testing.T) {
+	h := newStaticTestServer(t, "")
+	rec := do(h, http.MethodGet, "/app/", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<h1>Chirpy</h1>") {
+		t.Fatalf("expected index.html, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = do(h, http.MethodGet, "/app/docs/", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "guide.txt") {
+		t.Fatalf("expected a listing with guide.txt, got %d: %s", rec.Code, rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), ".draft") || strings.Contains(rec.Body.String(), ".hidden") {
+		t.Errorf("listing shows dotfiles: %s", rec.Body)
+	}
+}
+
+func TestStatic_HidesDotfiles(t *testing.T) {
+	h := newStaticTestServer(t, "")
+	for _, path := range []string{"/app/.env", "/app/.git/config", "/app/docs/.draft.txt", "/app/docs/.hidden/a.x"} {
+		if rec := do(h, http.MethodGet, path, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: expected 404, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestStatic_AppPrefix(t *testing.T) {
+	h := newStaticTestServer(t, "client")
+	if rec := do(h, http.MethodGet, "/client/docs/guide.txt", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 under the configured prefix, got %d", rec.Code)
+	}
+	if rec := do(h, http.MethodGet, "/app/docs/guide.txt", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 under the default prefix, got %d", rec.Code)
+	}
+}
+
+func TestStatic_SPAFallback(t *testing.T) {
+	h := newStaticTestServer(t, "")
+	for _, path := range []string{"/app/profile/@bob", "/app/settings/", "/app/docs/missing"} {
+		rec := do(h, http.MethodGet, path, "")
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<h1>Chirpy</h1>") {
+			t.Errorf("GET %s: expected index.html, got %d: %s", path, rec.Code, rec.Body)
+		}
+	}
+	for _, path := range []string{"/app/main.js", "/app/docs/missing.css", "/app/.git/HEAD"} {
+		if rec := do(h, http.MethodGet, path, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: expected 404 for a missing asset, got %d", path, rec.Code)
+		}
+	}
+	if rec := do(h, http.MethodGet, "/app/docs/guide.txt", ""); rec.Body.String() != "guide" {
+		t.Errorf("existing files must still be served, got %q", rec.Body)
+	}
+}
+
+func TestStatic_Fingerprinting(t *testing.T) {
+	h := newStaticTestServer(t, "")
+
+	rec := do(h, http.MethodGet, "/api/assets/manifest", "")
+	var manifest map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&manifest); err != nil {
+		t.Fatalf("decoding manifest: %v", err)
+	}
+	hashed, ok := manifest["docs/guide.txt"]
+	if !ok || !strings.HasPrefix(hashed, "docs/guide.") || hashed == "docs/guide.txt" {
+		t.Fatalf("unexpected manifest: %v", manifest)
+	}
+	for name := range manifest {
+		if strings.Contains(name, "/.") || strings.HasPrefix(name, ".") || name == "index.html" {
+			t.Errorf("manifest lists %s", name)
+		}
+	}
+
+	rec = do(h, http.MethodGet, "/app/"+hashed, "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "guide" {
+		t.Fatalf("GET fingerprinted name: %d %q", rec.Code, rec.Body)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != immutableCacheControl {
+		t.Errorf("fingerprinted file has Cache-Control %q", cc)
+	}
+
+	for _, path := range []string{"/app/", "/app/profile/@bob", "/app/docs/guide.txt"} {
+		if cc := do(h, http.MethodGet, path, "").Header().Get("Cache-Control"); cc != "no-cache" {
+			t.Errorf("GET %s: Cache-Control %q, want no-cache", path, cc)
+		}
+	}
+}
+
+func TestStatic_EmbeddedFrontend(t *testing.T) {
+	static := fstest.MapFS{"index.html": {Data: []byte("<h1>Embedded</h1>")}}
+	h := NewRouter(NewServer(&config.Config{}, Deps{Store: &fakeStore{}, Static: static}))
+	for _, path := range []string{"/app/", "/app/profile/@bob"} {
+		if rec := do(h, http.MethodGet, path, ""); !strings.Contains(rec.Body.String(), "<h1>Embedded</h1>") {
+			t.Errorf("GET %s: expected the embedded index.html, got %d: %s", path, rec.Code, rec.Body)
+		}
+	}
+
+	// STATIC_DIR takes precedence over the embedded files.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>Disk</h1>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h = NewRouter(NewServer(&config.Config{StaticDir: dir}, Deps{Store: &fakeStore{}, Static: static}))
+	if rec := do(h, http.MethodGet, "/app/", ""); !strings.Contains(rec.Body.String(), "<h1>Disk</h1>") {
+		t.Errorf("expected index.html from STATIC_DIR, got %s", rec.Body)
+	}
+
+	h = NewRouter(NewServer(&config.Config{}, Deps{Store: &fakeStore{}}))
+	if rec := do(h, http.MethodGet, "/app/", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without a frontend, got %d", rec.Code)
+	}
+}
+
+func TestAdminMetrics(t *testing.T) {
+	h := newStaticTestServer(t, "")
+	do(h, http.MethodGet, "/app/", "")
+	do(h, http.MethodGet, "/app/docs/guide.txt", "")
+
+	rec := do(h, http.MethodGet, "/admin/metrics", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("unexpected response: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{"visited 2 times", "Open: 1"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics page lacks %q:\n%s", want, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// devTemplateDir is where DEV_ASSETS reads page templates from, relative
+// to the repository root.
+const devTemplateDir = "internal/api/templates"
+
+var pageTemplates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// renderPage writes the named page template as HTML. With DEV_ASSETS the
+// template is parsed from disk on every call.
+func (s *Server) renderPage(w http.ResponseWriter, name string, data interface{}) {
+	t := pageTemplates
+	if s.config.DevAssets {
+		var err error
+		if t, err = template.ParseFS(os.DirFS(devTemplateDir), name); err != nil {
+			fmt.Println("Error parsing page template:", err)
+			http.Error(w, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+		fmt.Println("Error rendering page template:", err)
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+	"chirpy/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+// defaultSiteName is the default community's name, and every tenant's
+// until it is given one.
+const defaultSiteName = "Chirpy"
+
+// reloadTenants loads the tenants table. It does nothing unless
+// MULTI_TENANT is set, so every request belongs to the default community.
+func (s *Server) reloadTenants(ctx context.Context) error {
+	if !s.config.MultiTenant {
+		return nil
+	}
+	rows, err := s.db.ListTenants(ctx)
+	if err != nil {
+		return err
+	}
+	tenants := make([]tenant.Tenant, 0, len(rows))
+	for _, row := range rows {
+		tenants = append(tenants, newTenant(row))
+	}
+	s.tenants.Store(tenant.NewSet(tenants))
+	return nil
+}
+
+func newTenant(row database.Tenant) tenant.Tenant {
+	t := tenant.Tenant{
+		ID:       row.ID,
+		Slug:     row.Slug,
+		Hostname: row.Hostname.String,
+		Name:     row.Name,
+	}
+	if err := json.Unmarshal(row.Config, &t.Config); err != nil {
+		// The tenant is still served, with the deployment's settings.
+		fmt.Println("Error reading config of tenant", row.Slug+":", err)
+	}
+	return t
+}
+
+// watchTenants reloads the tenants whenever any instance changes them,
+// using the events hub fed by Postgres NOTIFY.
+func (s *Server) watchTenants(ctx context.Context, hub *events.Hub) {
+	ch, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if e.Type != "tenants.changed" {
+				continue
+			}
+			if err := s.reloadTenants(ctx); err != nil {
+				fmt.Println("Error reloading tenants:", err)
+			}
+		}
+	}
+}
+
+// middlewareTenant works out which community a request is for and puts it
+// in the request's context, which scopes every query the request makes.
+// Requests under /t/{slug}/ have the prefix removed, so the same routes
+// serve every tenant.
+func (s *Server) middlewareTenant(next http.Handler) http.Handler {
+	if !s.config.MultiTenant {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, prefix, ok := s.tenants.Load().Resolve(r.Host, r.URL.Path)
+		if !ok {
+			jsonResponse(w, http.StatusNotFound, "Community was not found.")
+			return
+		}
+		r = r.WithContext(tenant.NewContext(r.Context(), t))
+		if prefix != "" {
+			u := *r.URL
+			u.Path = strings.TrimPrefix(u.Path, prefix)
+			if u.Path == "" {
+				u.Path = "/"
+			}
+			u.RawPath = ""
+			r.URL = &u
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// currentTenant is the tenant ctx is scoped to, or the default community.
+func currentTenant(ctx context.Context) tenant.Tenant {
+	t, _ := tenant.FromContext(ctx)
+	return t
+}
+
+// tenantByID is the tenant rows with id belong to. Rows without one are
+// the default community's.
+func (s *Server) tenantByID(id uuid.NullUUID) tenant.Tenant {
+	if !id.Valid {
+		return tenant.Tenant{}
+	}
+	t, _ := s.tenants.Load().ByID(id.UUID)
+	return t
+}
+
+// tenantURL is PUBLIC_URL as seen by t: its own hostname, with PUBLIC_URL's
+// scheme, or its path under PUBLIC_URL.
+func (s *Server) tenantURL(t tenant.Tenant) string {
+	if t.IsDefault() {
+		return s.config.PublicURL
+	}
+	if t.Hostname != "" {
+		scheme := "https"
+		if u, err := url.Parse(s.config.PublicURL); err == nil && u.Scheme != "" {
+			scheme = u.Scheme
+		}
+		return scheme + "://" + t.Hostname
+	}
+	return s.config.PublicURL + tenant.PathPrefix + t.Slug
+}
+
+// siteURL is tenantURL for the tenant ctx is scoped to.
+func (s *Server) siteURL(ctx context.Context) string {
+	return s.tenantURL(currentTenant(ctx))
+}
+
+// tenantPath is the prefix r arrived under, for tenants reached on the
+// main hostname.
+func tenantPath(r *http.Request, t tenant.Tenant) string {
+	if t.IsDefault() {
+		return ""
+	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if t.Hostname != "" && strings.EqualFold(host, t.Hostname) {
+		return ""
+	}
+	return tenant.PathPrefix + t.Slug
+}
+
+// siteName is what the community ctx is scoped to is called.
+func siteName(ctx context.Context) string {
+	if t := currentTenant(ctx); t.Name != "" {
+		return t.Name
+	}
+	return defaultSiteName
+}
+
+// maxChirpLength is how long u's chirps may be: their plan's limit, unless
+// their tenant overrides it.
+func (s *Server) maxChirpLength(u database.User) int {
+	p := planFor(u)
+	cfg := s.tenantByID(u.TenantID).Config
+	if p.ChirpyRed && cfg.MaxRedChirpLength > 0 {
+		return cfg.MaxRedChirpLength
+	}
+	if !p.ChirpyRed && cfg.MaxChirpLength > 0 {
+		return cfg.MaxChirpLength
+	}
+	return p.MaxChirpLength
+}
+
+// tokenIssuer issues the access tokens of the community ctx is scoped to.
+// Each tenant signs with its own key derived from JWT_SECRET, so a token
+// from one community is refused by every other, even for a user ID that
+// exists in both.
+func (s *Server) tokenIssuer(ctx context.Context) TokenIssuer {
+	t := currentTenant(ctx)
+	if t.IsDefault() {
+		return s.tokens
+	}
+	mac := hmac.New(sha256.New, []byte(s.config.JWTSecret))
+	mac.Write([]byte("tenant:" + t.ID.String()))
+	return JWTIssuer{Secret: hex.EncodeToString(mac.Sum(nil)), Leeway: s.config.JWTLeeway}
+}
+
+// cacheKey keeps the entries of different tenants apart in the caches,
+// since a path under /t/{slug}/ is served with the prefix removed.
+func cacheKey(ctx context.Context, key string) string {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return key
+	}
+	return t.Key() + " " + key
+}
+
+// eventTenant is the tenant a chirp event's row belongs to.
+type eventTenant struct {
+	TenantID uuid.NullUUID `json:"tenant_id"`
+}
+
+// inTenant reports whether the row e carries belongs to the tenant ctx is
+// scoped to. Outside multi-tenant mode every event does.
+func inTenant(ctx context.Context, e events.Event) bool {
+	t, ok := tenant.FromContext(ctx)
+	if !ok {
+		return true
+	}
+	var et eventTenant
+	if err := json.Unmarshal(e.Data, &et); err != nil {
+		return false
+	}
+	return et.TenantID.UUID == t.ID
+}
+
+// eachTenant runs fn once for the default community and once for each
+// tenant, each time scoped to it, so a job sees one community at a time.
+// Outside multi-tenant mode it runs fn once, unscoped.
+func (s *Server) eachTenant(ctx context.Context, fn func(context.Context) error) error {
+	if !s.config.MultiTenant {
+		return fn(ctx)
+	}
+	errs := []error{fn(tenant.NewContext(ctx, tenant.Tenant{}))}
+	for _, t := range s.tenants.Load().All() {
+		if err := fn(tenant.NewContext(ctx, t)); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", t.Slug, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type siteResponse struct {
+	Name              string `json:"name"`
+	LogoURL           string `json:"logo_url,omitempty"`
+	AccentColor       string `json:"accent_color,omitempty"`
+	MaxChirpLength    int    `json:"max_chirp_length"`
+	MaxRedChirpLength int    `json:"max_red_chirp_length"`
+}
+
+// handlerSite describes the community the request is for, so the frontend
+// can brand itself and check chirp lengths before posting.
+func (s *Server) handlerSite(w http.ResponseWriter, r *http.Request) {
+	t := currentTenant(r.Context())
+	resp := siteResponse{
+		Name:              siteName(r.Context()),
+		LogoURL:           t.Config.LogoURL,
+		AccentColor:       t.Config.AccentColor,
+		MaxChirpLength:    s.maxChirpLength(database.User{TenantID: uuid.NullUUID{UUID: t.ID, Valid: !t.IsDefault()}}),
+		MaxRedChirpLength: s.maxChirpLength(database.User{TenantID: uuid.NullUUID{UUID: t.ID, Valid: !t.IsDefault()}, IsChirpyRed: true}),
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
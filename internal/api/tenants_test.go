@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+	"chirpy/internal/tenant"
+
+	"github.com/google/uuid"
+)
+
+// tenantStore keeps one set of users per tenant, as the row-level security
+// policies would show them.
+type tenantStore struct {
+	fakeStore
+	tenants []database.Tenant
+	byKey   map[string]map[string]database.User
+}
+
+func (s *tenantStore) ListTenants(ctx context.Context) ([]database.Tenant, error) {
+	return s.tenants, nil
+}
+
+func (s *tenantStore) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	t, _ := tenant.FromContext(ctx)
+	user, ok := s.byKey[t.Key()][email]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func TestMultiTenant(t *testing.T) {
+	acme := database.Tenant{
+		ID:       uuid.New(),
+		Slug:     "acme",
+		Hostname: sql.NullString{String: "chirps.acme.example", Valid: true},
+		Name:     "Acme",
+		Config:   json.RawMessage(`{"accent_color":"#ff6600","max_chirp_length":500}`),
+	}
+	birds := database.Tenant{ID: uuid.New(), Slug: "birds", Name: "Birds", Config: json.RawMessage(`{}`)}
+	user := newTestUser(t, "saul@example.com", "04234")
+	user.TenantID = uuid.NullUUID{UUID: birds.ID, Valid: true}
+	store := &tenantStore{
+		tenants: []database.Tenant{acme, birds},
+		byKey: map[string]map[string]database.User{
+			birds.ID.String(): {user.Email: user},
+		},
+	}
+	cfg := &config.Config{JWTSecret: "test-secret", MultiTenant: true, PublicURL: "https://chirpy.example"}
+	s := NewServer(cfg, Deps{Store: store, Clock: fixedClock(testNow)})
+	if err := s.reloadTenants(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	h := NewRouter(s)
+
+	site := func(host, path string) (int, siteResponse) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var resp siteResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+	if code, resp := site("chirpy.example", "/api/site"); code != http.StatusOK || resp.Name != defaultSiteName || resp.MaxChirpLength != maxChirpLength {
+		t.Errorf("default community: got %d %+v", code, resp)
+	}
+	if code, resp := site("chirps.acme.example", "/api/site"); code != http.StatusOK || resp.Name != "Acme" || resp.AccentColor != "#ff6600" || resp.MaxChirpLength != 500 || resp.MaxRedChirpLength != maxRedChirpLength {
+		t.Errorf("tenant by hostname: got %d %+v", code, resp)
+	}
+	if code, resp := site("chirpy.example", "/t/birds/api/site"); code != http.StatusOK || resp.Name != "Birds" {
+		t.Errorf("tenant by path: got %d %+v", code, resp)
+	}
+	if code, _ := site("chirpy.example", "/t/nobody/api/site"); code != http.StatusNotFound {
+		t.Errorf("unknown tenant: expected 404, got %d", code)
+	}
+
+	// Users exist in their own community only.
+	if rec := do(h, http.MethodPost, "/api/login", `{"email":"saul@example.com","password":"04234"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("signing in to the default community: expected 401, got %d", rec.Code)
+	}
+	rec := do(h, http.MethodPost, "/t/birds/api/login", `{"email":"saul@example.com","password":"04234"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("signing in to the tenant: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp UserResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := auth.ValidateJWTWithActor(resp.Token, cfg.JWTSecret, 0); err == nil {
+		t.Error("a tenant's token must not validate in the default community")
+	}
+	birdsCtx := tenant.NewContext(context.Background(), newTenant(birds))
+	if claims, err := s.tokenIssuer(birdsCtx).Validate(resp.Token); err != nil || claims.UserID != user.ID {
+		t.Errorf("the token should validate in its own tenant: id=%s err=%v", claims.UserID, err)
+	}
+	acmeCtx := tenant.NewContext(context.Background(), newTenant(acme))
+	if _, err := s.tokenIssuer(acmeCtx).Validate(resp.Token); err == nil {
+		t.Error("a tenant's token must not validate in another tenant")
+	}
+
+	if got := s.siteURL(birdsCtx); got != "https://chirpy.example/t/birds" {
+		t.Errorf("siteURL = %q", got)
+	}
+	if got := s.siteURL(acmeCtx); got != "https://chirps.acme.example" {
+		t.Errorf("siteURL = %q", got)
+	}
+
+	chirp := events.Event{Type: "chirp.created", Data: json.RawMessage(`{"id":"` + uuid.NewString() + `","tenant_id":"` + birds.ID.String() + `"}`)}
+	if !inTenant(birdsCtx, chirp) || inTenant(acmeCtx, chirp) || inTenant(tenant.NewContext(context.Background(), tenant.Tenant{}), chirp) {
+		t.Error("chirp events should only reach their own tenant's streams")
+	}
+}
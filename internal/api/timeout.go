@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// errRequestTimeout is the cause of a request context cancelled by
+// middlewareRequestTimeout.
+var errRequestTimeout = errors.New("request timed out")
+
+type requestTimerKey struct{}
+
+// middlewareRequestTimeout cancels a request's context once it has run for
+// HTTP_REQUEST_TIMEOUT, so the queries it started stop holding pool
+// connections. Long-lived responses lift it with noRequestTimeout.
+func (s *Server) middlewareRequestTimeout(next http.Handler) http.Handler {
+	timeout := s.config.HTTP.RequestTimeout
+	if timeout <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A timer rather than context.WithTimeout, whose deadline can't be
+		// lifted once set.
+		ctx, cancel := context.WithCancelCause(r.Context())
+		defer cancel(nil)
+		timer := time.AfterFunc(timeout, func() { cancel(errRequestTimeout) })
+		defer timer.Stop()
+
+		ctx = context.WithValue(ctx, requestTimerKey{}, timer)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// noRequestTimeout lifts the request timeout for a long-lived response,
+// which still ends when the client goes away.
+func noRequestTimeout(r *http.Request) {
+	if timer, ok := r.Context().Value(requestTimerKey{}).(*time.Timer); ok {
+		timer.Stop()
+	}
+}
+
+// clientGone reports whether r's context was cancelled because the client
+// went away, which isn't worth logging, rather than because it timed out.
+func clientGone(r *http.Request) bool {
+	return errors.Is(context.Cause(r.Context()), context.Canceled)
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// endlessStore streams chirps until its context is cancelled, the way
+// database/sql rows stop once their query's context is done.
+type endlessStore struct {
+	fakeStore
+	rows  int
+	cause error
+}
+
+func (e *endlessStore) EachChirp(ctx context.Context, viewerID uuid.UUID, fn func(database.GetChirpsRow) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			e.cause = context.Cause(ctx)
+			return ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+		e.rows++
+		if err := fn(database.GetChirpsRow{ID: uuid.New(), Body: "again"}); err != nil {
+			return err
+		}
+	}
+}
+
+func TestRequestTimeout_CancelsQueries(t *testing.T) {
+	store := &endlessStore{}
+	cfg := &config.Config{HTTP: config.HTTPConfig{RequestTimeout: 20 * time.Millisecond}}
+	h := NewRouter(NewServer(cfg, Deps{Store: store}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		do(h, http.MethodGet, "/api/chirps", "")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listing kept running past the request timeout")
+	}
+	if store.cause != errRequestTimeout {
+		t.Errorf("expected the query to stop for the timeout, got %v", store.cause)
+	}
+}
+
+func TestRequestTimeout_ClientDisconnect(t *testing.T) {
+	store := &endlessStore{}
+	h := NewRouter(NewServer(&config.Config{}, Deps{Store: store}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil).WithContext(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("listing kept running after the client went away")
+	}
+	if store.cause != context.Canceled || store.rows == 0 {
+		t.Errorf("expected the listing to stream then stop on disconnect, got %d rows and %v", store.rows, store.cause)
+	}
+}
+
+func TestNoRequestTimeout(t *testing.T) {
+	cfg := &config.Config{HTTP: config.HTTPConfig{RequestTimeout: 10 * time.Millisecond}}
+	s := NewServer(cfg, Deps{Store: &fakeStore{}})
+
+	var lifted, timed error
+	s.middlewareRequestTimeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		noRequestTimeout(r)
+		time.Sleep(30 * time.Millisecond)
+		lifted = r.Context().Err()
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	s.middlewareRequestTimeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		timed = context.Cause(r.Context())
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if lifted != nil {
+		t.Errorf("expected a lifted timeout to leave the context alone, got %v", lifted)
+	}
+	if timed != errRequestTimeout {
+		t.Errorf("expected the request to time out, got %v", timed)
+	}
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+)
+
+// timestampFormat is how every time in an API response is written: RFC 3339
+// in UTC with exactly three fractional digits, e.g.
+// 2025-06-01T12:00:00.000Z. Clients can compare timestamps as strings.
+const timestampFormat = "2006-01-02T15:04:05.000Z"
+
+// Timestamp is a time.Time that marshals in timestampFormat whatever its
+// location. Response DTOs use it in place of time.Time.
+type Timestamp struct {
+	time.Time
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	b := make([]byte, 0, len(timestampFormat)+2)
+	b = append(b, '"')
+	b = t.UTC().AppendFormat(b, timestampFormat)
+	return append(b, '"'), nil
+}
+
+// nullTimestamp is nil when t isn't set, for fields that marshal as null.
+func nullTimestamp(t sql.NullTime) *Timestamp {
+	if !t.Valid {
+		return nil
+	}
+	return &Timestamp{t.Time}
+}
+
+// notModified sets Last-Modified to lastModified and reports whether the
+// request's If-Modified-Since makes the response unnecessary, in which case
+// it has answered 304 and the caller should just return. HTTP dates only
+// have whole seconds, so lastModified is truncated to compare them.
+func notModified(w http.ResponseWriter, r *http.Request, lastModified time.Time) bool {
+	if lastModified.IsZero() {
+		return false
+	}
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.After(since) {
+		return false
+	}
+	// A 304 carries no body, so these would describe nothing.
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
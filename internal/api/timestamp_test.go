@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+)
+
+func TestTimestamp_MarshalJSON(t *testing.T) {
+	paris := time.FixedZone("CEST", 2*60*60)
+	tests := []struct {
+		in   time.Time
+		want string
+	}{
+		{testNow, `"2025-06-01T12:00:00.000Z"`},
+		{time.Date(2025, 6, 1, 14, 0, 0, 123456789, paris), `"2025-06-01T12:00:00.123Z"`},
+		{time.Time{}, `"0001-01-01T00:00:00.000Z"`},
+	}
+	for _, tt := range tests {
+		got, err := json.Marshal(Timestamp{tt.in})
+		if err != nil {
+			t.Fatalf("Marshal(%v) returned error: %v", tt.in, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Marshal(%v) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+
+	var ts struct {
+		At *Timestamp `json:"at"`
+	}
+	if got, _ := json.Marshal(ts); string(got) != `{"at":null}` {
+		t.Errorf("nil timestamp marshalled as %s", got)
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	paris := time.FixedZone("CEST", 2*60*60)
+	// 12:00:00.750 UTC, stored in another zone with sub-second precision.
+	updated := time.Date(2025, 6, 1, 14, 0, 0, 750_000_000, paris)
+
+	tests := []struct {
+		name   string
+		method string
+		since  string
+		want   bool
+	}{
+		{"no If-Modified-Since", http.MethodGet, "", false},
+		{"same second", http.MethodGet, "Sun, 01 Jun 2025 12:00:00 GMT", true},
+		{"later", http.MethodGet, "Sun, 01 Jun 2025 12:00:01 GMT", true},
+		{"a second earlier", http.MethodGet, "Sun, 01 Jun 2025 11:59:59 GMT", false},
+		{"RFC 850 date", http.MethodGet, "Sunday, 01-Jun-25 12:00:00 GMT", true},
+		{"asctime date", http.MethodGet, "Sun Jun  1 12:00:00 2025", true},
+		{"local time instead of GMT", http.MethodGet, "Sun, 01 Jun 2025 14:00:00 +0200", false},
+		{"HEAD", http.MethodHead, "Sun, 01 Jun 2025 12:00:00 GMT", true},
+		{"not a GET", http.MethodPut, "Sun, 01 Jun 2025 12:00:00 GMT", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/chirps/x", nil)
+		if tt.since != "" {
+			req.Header.Set("If-Modified-Since", tt.since)
+		}
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Content-Type", "application/json")
+		got := notModified(rec, req, updated)
+		if got != tt.want {
+			t.Errorf("%s: notModified = %v, want %v", tt.name, got, tt.want)
+		}
+		if lm := rec.Header().Get("Last-Modified"); lm != "Sun, 01 Jun 2025 12:00:00 GMT" {
+			t.Errorf("%s: Last-Modified = %q", tt.name, lm)
+		}
+		if got && (rec.Code != http.StatusNotModified || rec.Header().Get("Content-Type") != "") {
+			t.Errorf("%s: expected a bare 304, got %d %v", tt.name, rec.Code, rec.Header())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	if notModified(rec, httptest.NewRequest(http.MethodGet, "/", nil), time.Time{}) || rec.Header().Get("Last-Modified") != "" {
+		t.Error("an unknown modification time shouldn't set Last-Modified")
+	}
+}
+
+func TestLastModifiedEndpoints(t *testing.T) {
+	user := newTestUser(t, "saul@example.com", "04234")
+	user.UpdatedAt = testNow.Add(1500 * time.Millisecond)
+	store := &contractStore{fakeStore{users: map[string]database.User{user.Email: user}}}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store, Clock: fixedClock(testNow)}))
+
+	for _, tc := range []struct {
+		path, lastModified string
+	}{
+		{"/api/chirps/" + contractChirpID.String(), "Sun, 01 Jun 2025 12:00:00 GMT"},
+		{"/api/users/" + user.ID.String(), "Sun, 01 Jun 2025 12:00:01 GMT"},
+	} {
+		rec := do(h, http.MethodGet, tc.path, "")
+		if rec.Code != http.StatusOK || rec.Header().Get("Last-Modified") != tc.lastModified {
+			t.Fatalf("GET %s: got %d, Last-Modified %q", tc.path, rec.Code, rec.Header().Get("Last-Modified"))
+		}
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("If-Modified-Since", rec.Header().Get("Last-Modified"))
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("GET %s revalidated: expected an empty 304, got %d: %s", tc.path, rec.Code, rec.Body)
+		}
+	}
+	if rec := do(h, http.MethodGet, "/api/users/"+contractChirpID.String(), ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown user: expected 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxTypeaheadLength     = 30
+	typeaheadLimit         = 5
+	typeaheadCacheTTL      = 30 * time.Second
+	typeaheadCacheMaxBytes = 1 << 20
+)
+
+// likeEscaper escapes the LIKE wildcards, so a query matches only as a
+// literal prefix.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type typeaheadUser struct {
+	ID       uuid.UUID `json:"id"`
+	Handle   string    `json:"handle"`
+	Verified bool      `json:"verified"`
+}
+
+type typeaheadHashtag struct {
+	Tag        string `json:"tag"`
+	ChirpCount int32  `json:"chirp_count"`
+}
+
+type typeaheadResponse struct {
+	Users    []typeaheadUser    `json:"users"`
+	Hashtags []typeaheadHashtag `json:"hashtags"`
+}
+
+// handlerSearchTypeahead completes ?q= to handles and hashtags as the user
+// types. A leading @ asks for handles only and a leading # for hashtags
+// only. Results don't depend on who is asking, so recent ones are served
+// from memory for every caller.
+func (s *Server) handlerSearchTypeahead(w http.ResponseWriter, r *http.Request) {
+	q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	sigil, prefix := "", q
+	if strings.HasPrefix(q, "@") || strings.HasPrefix(q, "#") {
+		sigil, prefix = q[:1], q[1:]
+	}
+	if prefix == "" || len(prefix) > maxTypeaheadLength {
+		jsonResponse(w, http.StatusBadRequest, fmt.Sprintf("q must be 1 to %d characters", maxTypeaheadLength))
+		return
+	}
+
+	c := s.typeaheadCache
+	key := cacheKey(r.Context(), q)
+	if resp, ok := c.get(key, s.clock.Now()); ok {
+		c.hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Cache", "HIT")
+		w.WriteHeader(resp.status)
+		w.Write(resp.body)
+		return
+	}
+	c.misses.Add(1)
+	generation := c.currentGeneration()
+
+	ctx := r.Context()
+	resp := typeaheadResponse{Users: []typeaheadUser{}, Hashtags: []typeaheadHashtag{}}
+	escaped := likeEscaper.Replace(prefix)
+	if sigil != "#" {
+		rows, err := s.db.SearchHandlesByPrefix(ctx, database.SearchHandlesByPrefixParams{
+			Prefix:   escaped,
+			RowLimit: typeaheadLimit,
+		})
+		if err != nil {
+			fmt.Println("Error searching handles:", err)
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		for _, row := range rows {
+			resp.Users = append(resp.Users, typeaheadUser{
+				ID:       row.ID,
+				Handle:   row.Handle.String,
+				Verified: row.Verified,
+			})
+		}
+	}
+	if sigil != "@" {
+		rows, err := s.db.SearchHashtagsByPrefix(ctx, database.SearchHashtagsByPrefixParams{
+			Prefix:   escaped,
+			RowLimit: typeaheadLimit,
+		})
+		if err != nil {
+			fmt.Println("Error searching hashtags:", err)
+			jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+			return
+		}
+		for _, row := range rows {
+			resp.Hashtags = append(resp.Hashtags, typeaheadHashtag{
+				Tag:        row.Tag,
+				ChirpCount: row.ChirpCount,
+			})
+		}
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		jsonResponse(w, http.StatusInternalServerError, "Something went wrong")
+		return
+	}
+	c.put(&cachedResponse{
+		key:     key,
+		status:  http.StatusOK,
+		body:    body,
+		expires: s.clock.Now().Add(typeaheadCacheTTL),
+	}, generation)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Cache", "MISS")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// typeaheadStore records the prefixes it is asked for.
+type typeaheadStore struct {
+	fakeStore
+	handlePrefixes  []string
+	hashtagPrefixes []string
+}
+
+func (s *typeaheadStore) SearchHandlesByPrefix(ctx context.Context, arg database.SearchHandlesByPrefixParams) ([]database.SearchHandlesByPrefixRow, error) {
+	s.handlePrefixes = append(s.handlePrefixes, arg.Prefix)
+	return []database.SearchHandlesByPrefixRow{{
+		ID:     uuid.New(),
+		Handle: sql.NullString{String: "gopher", Valid: true},
+	}}, nil
+}
+
+func (s *typeaheadStore) SearchHashtagsByPrefix(ctx context.Context, arg database.SearchHashtagsByPrefixParams) ([]database.SearchHashtagsByPrefixRow, error) {
+	s.hashtagPrefixes = append(s.hashtagPrefixes, arg.Prefix)
+	return []database.SearchHashtagsByPrefixRow{{Tag: "golang", ChirpCount: 7}}, nil
+}
+
+func TestSearchTypeahead(t *testing.T) {
+	store := &typeaheadStore{}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: store, Clock: fixedClock(testNow)}))
+
+	for _, q := range []string{"", "@", "%23"} {
+		if rec := do(h, http.MethodGet, "/api/search/typeahead?q="+q, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("q=%q: expected 400, got %d", q, rec.Code)
+		}
+	}
+
+	rec := do(h, http.MethodGet, "/api/search/typeahead?q=Go", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp typeaheadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Users) != 1 || resp.Users[0].Handle != "gopher" || len(resp.Hashtags) != 1 || resp.Hashtags[0].ChirpCount != 7 {
+		t.Errorf("expected a user and a hashtag, got %+v", resp)
+	}
+
+	if rec := do(h, http.MethodGet, "/api/search/typeahead?q=go", ""); rec.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("expected a repeated query to be served from memory, got X-Cache %q", rec.Header().Get("X-Cache"))
+	}
+	if len(store.handlePrefixes) != 1 || len(store.hashtagPrefixes) != 1 {
+		t.Errorf("expected one lookup of each kind, got %v and %v", store.handlePrefixes, store.hashtagPrefixes)
+	}
+
+	do(h, http.MethodGet, "/api/search/typeahead?q=@go_", "")
+	if len(store.hashtagPrefixes) != 1 {
+		t.Errorf("expected @ to search handles only, got hashtag lookups %v", store.hashtagPrefixes)
+	}
+	if got := store.handlePrefixes[len(store.handlePrefixes)-1]; got != `go\_` {
+		t.Errorf("expected LIKE wildcards to be escaped, got %q", got)
+	}
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"chirpy/internal/buildinfo"
+)
+
+type versionResponse struct {
+	Version   string     `json:"version"`
+	Commit    string     `json:"commit,omitempty"`
+	BuildTime *Timestamp `json:"build_time,omitempty"`
+	GoVersion string     `json:"go_version"`
+	Uptime    string     `json:"uptime"`
+}
+
+// handlerVersion says which build is serving, so bug reports can name it.
+// It's public: the version is no secret, and it's most wanted when the
+// reporter can't sign in.
+func (s *Server) handlerVersion(w http.ResponseWriter, r *http.Request) {
+	info := buildinfo.Get()
+	resp := versionResponse{
+		Version:   info.Version,
+		Commit:    info.Commit,
+		GoVersion: info.GoVersion,
+		Uptime:    s.clock.Now().Sub(s.startedAt).Round(time.Second).String(),
+	}
+	if !info.BuildTime.IsZero() {
+		resp.BuildTime = &Timestamp{info.BuildTime}
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
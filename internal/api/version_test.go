@@ -0,0 +1,33 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"chirpy/internal/buildinfo"
+	"chirpy/internal/config"
+)
+
+func TestVersion(t *testing.T) {
+	clock := &manualClock{now: testNow}
+	h := NewRouter(NewServer(&config.Config{JWTSecret: "test-secret"}, Deps{Store: &fakeStore{}, Clock: clock}))
+	clock.now = testNow.Add(90*time.Minute + 2*time.Second)
+
+	rec := do(h, http.MethodGet, "/api/version", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp versionResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	info := buildinfo.Get()
+	if resp.Version != info.Version || resp.Commit != info.Commit || resp.GoVersion != info.GoVersion {
+		t.Errorf("response %+v doesn't match the build %+v", resp, info)
+	}
+	if resp.Uptime != "1h30m2s" {
+		t.Errorf("expected uptime 1h30m2s, got %q", resp.Uptime)
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"chirpy/internal/activitypub"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+type webFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+type webFingerResponse struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases,omitempty"`
+	Links   []webFingerLink `json:"links"`
+}
+
+// preferredUsername is the name a user is known by off-server: their handle
+// when they have one, otherwise their ID.
+func preferredUsername(u database.User) string {
+	if u.Handle.Valid {
+		return u.Handle.String
+	}
+	return u.ID.String()
+}
+
+func (s *Server) publicHost() string {
+	u, err := url.Parse(s.config.PublicURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// handlerWebFinger resolves acct:name@domain to a local user's actor and
+// profile URLs. name may be a handle or a user ID.
+func (s *Server) handlerWebFinger(w http.ResponseWriter, r *http.Request) {
+	resource := r.URL.Query().Get("resource")
+	acct, ok := strings.CutPrefix(resource, "acct:")
+	if !ok {
+		http.Error(w, "resource must be an acct: URI", http.StatusBadRequest)
+		return
+	}
+
+	at := strings.LastIndex(acct, "@")
+	if at <= 0 {
+		http.Error(w, "resource must be an acct: URI", http.StatusBadRequest)
+		return
+	}
+	name, domain := strings.TrimPrefix(acct[:at], "@"), acct[at+1:]
+
+	host := s.publicHost()
+	if !strings.EqualFold(domain, host) {
+		http.NotFound(w, r)
+		return
+	}
+
+	var user database.User
+	var err error
+	if id, parseErr := uuid.Parse(name); parseErr == nil {
+		user, err = s.db.GetUserByID(r.Context(), id)
+	} else {
+		user, _, err = s.userByHandle(r.Context(), strings.ToLower(name))
+	}
+	if err != nil || user.BannedAt.Valid || user.Shadowbanned {
+		http.NotFound(w, r)
+		return
+	}
+
+	actor := s.actorURI(user.ID)
+	resp := webFingerResponse{
+		Subject: "acct:" + preferredUsername(user) + "@" + host,
+		Aliases: []string{actor},
+		Links: []webFingerLink{
+			{Rel: "self", Type: activitypub.ContentType, Href: actor},
+		},
+	}
+	if user.Handle.Valid {
+		resp.Links = append(resp.Links, webFingerLink{
+			Rel:  "http://webfinger.net/rel/profile-page",
+			Type: "text/html",
+			Href: s.profileURL(user.Handle.String),
+		})
+	}
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, "Something went wrong", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jrd+json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
@@ -1,7 +1,12 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/alexedwards/argon2id"
@@ -27,13 +32,47 @@ func CheckPasswordHash(password, hash string) (bool, error) {
 	return check, nil
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+// Profile is what a token can say about its user besides who they are, so
+// requests can be authorized without looking the user up. It's a snapshot
+// from when the token was issued: a change of role or membership shows in
+// the user's next token.
+type Profile struct {
+	Email       string `json:"email,omitempty"`
+	Role        string `json:"role,omitempty"`
+	IsChirpyRed bool   `json:"is_chirpy_red,omitempty"`
+}
+
+// TokenOption adds claims to a token made by MakeJWT.
+type TokenOption func(*chirpyClaims)
+
+// WithProfile embeds p in the token.
+func WithProfile(p Profile) TokenOption {
+	return func(c *chirpyClaims) {
+		c.Profile = p
+	}
+}
+
+// WithScopes marks the token as issued to a third-party app, clientID,
+// and limits it to scopes.
+func WithScopes(clientID string, scopes []string) TokenOption {
+	return func(c *chirpyClaims) {
+		c.ClientID = clientID
+		c.Scope = strings.Join(scopes, " ")
+	}
+}
+
+func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration, opts ...TokenOption) (string, error) {
 	now := time.Now().UTC()
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		Subject:   userID.String(),
-		IssuedAt:  jwt.NewNumericDate(now),
-		ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+	claims := chirpyClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+		},
+	}
+	for _, opt := range opts {
+		opt(&claims)
 	}
 
 	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -46,7 +85,62 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 	return signed, nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+// actorClaim identifies who is really acting when a token is issued on
+// another user's behalf (the RFC 8693 "act" claim).
+type actorClaim struct {
+	Subject string `json:"sub"`
+}
+
+type chirpyClaims struct {
+	jwt.RegisteredClaims
+	Profile
+	Actor *actorClaim `json:"act,omitempty"`
+	// ClientID and Scope are the RFC 9068 claims of tokens issued to
+	// third-party apps. Scope is space-separated.
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+}
+
+// Claims are what a valid token says.
+type Claims struct {
+	UserID uuid.UUID
+	// ActorID is the admin behind an impersonation token, or uuid.Nil.
+	ActorID uuid.UUID
+	// Profile is nil for tokens issued without one, such as impersonation
+	// tokens and those made by the CLI.
+	Profile *Profile
+	// ClientID is the third-party app the token was issued to, and Scopes
+	// what it may do. Both are empty for Chirpy's own tokens, which may do
+	// anything their user can.
+	ClientID string
+	Scopes   []string
+	// IssuedAt and ExpiresAt are zero when the token doesn't say.
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// MakeImpersonationJWT issues a token for userID that records actorID as the
+// real caller, so support staff can reproduce what the user sees.
+func MakeImpersonationJWT(userID, actorID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	now := time.Now().UTC()
+	claims := chirpyClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+		},
+		Actor: &actorClaim{Subject: actorID.String()},
+	}
+
+	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return tok.SignedString([]byte(tokenSecret))
+}
+
+// ValidateJWT checks a token and returns its claims. leeway allows for
+// clock skew with whoever minted it: tokens are accepted that far past
+// their exp, or ahead of their nbf.
+func ValidateJWT(tokenString, tokenSecret string, leeway time.Duration) (Claims, error) {
 	keyFunc := func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
@@ -54,21 +148,97 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 		return []byte(tokenSecret), nil
 	}
 
-	claims := &jwt.RegisteredClaims{}
+	claims := &chirpyClaims{}
 	_, err := jwt.ParseWithClaims(tokenString, claims, keyFunc,
 		jwt.WithIssuer("chirpy"), // enforce issuer
 		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithLeeway(leeway),
 	)
 	if err != nil {
-		return uuid.Nil, err
+		return Claims{}, err
 	}
 
 	if claims.Subject == "" {
-		return uuid.Nil, errors.New("subject claim missing")
+		return Claims{}, errors.New("subject claim missing")
 	}
 	uid, parseErr := uuid.Parse(claims.Subject)
 	if parseErr != nil {
-		return uuid.Nil, errors.New("subject is not a valid UUID")
+		return Claims{}, errors.New("subject is not a valid UUID")
+	}
+	res := Claims{UserID: uid, ClientID: claims.ClientID, Scopes: strings.Fields(claims.Scope)}
+	if claims.IssuedAt != nil {
+		res.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		res.ExpiresAt = claims.ExpiresAt.Time
+	}
+	if claims.Profile != (Profile{}) {
+		res.Profile = &claims.Profile
+	}
+
+	if claims.Actor == nil {
+		return res, nil
+	}
+	actor, parseErr := uuid.Parse(claims.Actor.Subject)
+	if parseErr != nil {
+		return Claims{}, errors.New("actor is not a valid UUID")
+	}
+	res.ActorID = actor
+	return res, nil
+}
+
+// ValidateJWTWithActor validates the token like ValidateJWT and returns
+// its user and the impersonating actor, which is uuid.Nil for ordinary
+// tokens.
+func ValidateJWTWithActor(tokenString, tokenSecret string, leeway time.Duration) (uuid.UUID, uuid.UUID, error) {
+	claims, err := ValidateJWT(tokenString, tokenSecret, leeway)
+	return claims.UserID, claims.ActorID, err
+}
+
+// DecodeJWTClaims returns a token's claims without checking its signature
+// or expiry. It is for debugging tools only; never trust its result.
+func DecodeJWTClaims(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(tokenString, claims)
+	if err != nil {
+		return nil, err
 	}
-	return uid, nil
+	return claims, nil
+}
+
+// GetBearerToken extracts the token from an "Authorization: Bearer <token>" header.
+func GetBearerToken(headers http.Header) (string, error) {
+	authHeader := headers.Get("Authorization")
+	if authHeader == "" {
+		return "", errors.New("authorization header missing")
+	}
+
+	scheme, token, found := strings.Cut(authHeader, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", errors.New("authorization header is not a bearer token")
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", errors.New("bearer token empty")
+	}
+	return token, nil
+}
+
+// SignValue returns an HMAC over value, scoped to purpose so a signature
+// made for one kind of link can't be replayed on another. It is meant for
+// links that must work without signing in, such as unsubscribe links.
+func SignValue(value, purpose, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// CheckSignedValue reports whether signature is SignValue's output for
+// value and purpose.
+func CheckSignedValue(value, purpose, signature, secret string) bool {
+	want := SignValue(value, purpose, secret)
+	return hmac.Equal([]byte(signature), []byte(want))
 }
@@ -3,6 +3,7 @@ package auth
 
 import (
 	"errors"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
@@ -26,12 +27,41 @@ func TestMakeAndValidateJWT_Success(t *testing.T) {
 	}
 
 	// ---- validate the token -----------------------------------------------
-	gotID, err := ValidateJWT(token, secret)
+	claims, err := ValidateJWT(token, secret, 0)
 	if err != nil {
 		t.Fatalf("ValidateJWT returned error: %v", err)
 	}
-	if gotID != userID {
-		t.Fatalf("expected UUID %s, got %s", userID, gotID)
+	if claims.UserID != userID {
+		t.Fatalf("expected UUID %s, got %s", userID, claims.UserID)
+	}
+	if claims.Profile != nil {
+		t.Fatalf("expected no profile, got %+v", claims.Profile)
+	}
+}
+
+func TestMakeJWT_WithProfile(t *testing.T) {
+	secret := "test-secret"
+	userID := uuid.New()
+	profile := Profile{Email: "saul@example.com", Role: "admin", IsChirpyRed: true}
+
+	token, err := MakeJWT(userID, secret, time.Hour, WithProfile(profile))
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+	claims, err := ValidateJWT(token, secret, 0)
+	if err != nil {
+		t.Fatalf("ValidateJWT returned error: %v", err)
+	}
+	if claims.UserID != userID || claims.Profile == nil || *claims.Profile != profile {
+		t.Fatalf("expected user %s with %+v, got %+v", userID, profile, claims)
+	}
+
+	raw, err := DecodeJWTClaims(token)
+	if err != nil {
+		t.Fatalf("DecodeJWTClaims returned error: %v", err)
+	}
+	if raw["email"] != profile.Email || raw["role"] != profile.Role || raw["is_chirpy_red"] != true {
+		t.Fatalf("expected the profile as top-level claims, got %v", raw)
 	}
 }
 
@@ -46,7 +76,7 @@ func TestValidateJWT_Expired(t *testing.T) {
 		t.Fatalf("MakeJWT failed: %v", err)
 	}
 
-	_, err = ValidateJWT(token, secret)
+	_, err = ValidateJWT(token, secret, 0)
 	if err == nil {
 		t.Fatalf("expected error for expired token, got nil")
 	}
@@ -56,6 +86,59 @@ func TestValidateJWT_Expired(t *testing.T) {
 	}
 }
 
+func TestValidateJWT_Leeway(t *testing.T) {
+	secret := "test-secret"
+	userID := uuid.New()
+	now := time.Now().UTC()
+	sign := func(nbf, exp time.Duration) string {
+		t.Helper()
+		claims := jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now.Add(nbf)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(exp)),
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return token
+	}
+
+	// Claims are whole seconds, so each case stays a couple of seconds
+	// clear of the boundary it tests.
+	tests := []struct {
+		name    string
+		nbf     time.Duration
+		exp     time.Duration
+		leeway  time.Duration
+		wantErr error
+	}{
+		{"valid", -time.Minute, time.Minute, 0, nil},
+		{"not yet valid", 3 * time.Second, time.Minute, 0, jwt.ErrTokenNotValidYet},
+		{"not yet valid within leeway", 3 * time.Second, time.Minute, 5 * time.Second, nil},
+		{"not yet valid beyond leeway", 8 * time.Second, time.Minute, 5 * time.Second, jwt.ErrTokenNotValidYet},
+		{"expired", -time.Minute, -3 * time.Second, 0, jwt.ErrTokenExpired},
+		{"expired within leeway", -time.Minute, -3 * time.Second, 5 * time.Second, nil},
+		{"expired beyond leeway", -time.Minute, -8 * time.Second, 5 * time.Second, jwt.ErrTokenExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ValidateJWT(sign(tt.nbf, tt.exp), secret, tt.leeway)
+			if tt.wantErr == nil {
+				if err != nil || claims.UserID != userID {
+					t.Fatalf("expected %s, got %s (err %v)", userID, claims.UserID, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestValidateJWT_WrongSecret(t *testing.T) {
 	correct := "correct-secret"
 	wrong := "wrong-secret"
@@ -67,7 +150,7 @@ func TestValidateJWT_WrongSecret(t *testing.T) {
 		t.Fatalf("MakeJWT failed: %v", err)
 	}
 
-	_, err = ValidateJWT(token, wrong)
+	_, err = ValidateJWT(token, wrong, 0)
 	if err == nil {
 		t.Fatalf("expected signature validation error, got nil")
 	}
@@ -92,7 +175,7 @@ func TestValidateJWT_MissingSubject(t *testing.T) {
 		t.Fatalf("failed to sign token: %v", err)
 	}
 
-	_, err = ValidateJWT(tokenStr, secret)
+	_, err = ValidateJWT(tokenStr, secret, 0)
 	if err == nil {
 		t.Fatalf("expected error for missing subject, got nil")
 	}
@@ -100,3 +183,167 @@ func TestValidateJWT_MissingSubject(t *testing.T) {
 		t.Fatalf("unexpected error for missing subject: %v", err)
 	}
 }
+
+func TestImpersonationJWT_CarriesActor(t *testing.T) {
+	secret := "test-secret"
+	userID := uuid.New()
+	adminID := uuid.New()
+
+	token, err := MakeImpersonationJWT(userID, adminID, secret, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("MakeImpersonationJWT failed: %v", err)
+	}
+
+	gotUser, gotActor, err := ValidateJWTWithActor(token, secret, 0)
+	if err != nil {
+		t.Fatalf("ValidateJWTWithActor returned error: %v", err)
+	}
+	if gotUser != userID {
+		t.Fatalf("expected subject %s, got %s", userID, gotUser)
+	}
+	if gotActor != adminID {
+		t.Fatalf("expected actor %s, got %s", adminID, gotActor)
+	}
+
+	// Plain validation still resolves to the impersonated user.
+	claims, err := ValidateJWT(token, secret, 0)
+	if err != nil || claims.UserID != userID {
+		t.Fatalf("ValidateJWT: expected %s, got %s (err %v)", userID, claims.UserID, err)
+	}
+}
+
+func TestValidateJWTWithActor_OrdinaryToken(t *testing.T) {
+	secret := "test-secret"
+	userID := uuid.New()
+
+	token, err := MakeJWT(userID, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+
+	_, actor, err := ValidateJWTWithActor(token, secret, 0)
+	if err != nil {
+		t.Fatalf("ValidateJWTWithActor returned error: %v", err)
+	}
+	if actor != uuid.Nil {
+		t.Fatalf("expected no actor, got %s", actor)
+	}
+}
+
+func TestDecodeJWTClaims(t *testing.T) {
+	userID := uuid.New()
+	token, err := MakeJWT(userID, "supersecret", -time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT returned error: %v", err)
+	}
+
+	// Expired and checked without the secret, but still decodable.
+	claims, err := DecodeJWTClaims(token)
+	if err != nil {
+		t.Fatalf("DecodeJWTClaims returned error: %v", err)
+	}
+	if claims["sub"] != userID.String() || claims["iss"] != "chirpy" {
+		t.Fatalf("unexpected claims: %v", claims)
+	}
+
+	if _, err := DecodeJWTClaims("not-a-jwt"); err == nil {
+		t.Fatal("expected an error for a malformed token")
+	}
+}
+
+func TestGetBearerToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    string
+		wantErr bool
+	}{
+		{name: "valid", header: "Bearer abc.def.ghi", want: "abc.def.ghi"},
+		{name: "case-insensitive scheme", header: "bearer abc", want: "abc"},
+		{name: "missing header", header: "", wantErr: true},
+		{name: "wrong scheme", header: "Basic abc", wantErr: true},
+		{name: "no token", header: "Bearer ", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tc.header != "" {
+				headers.Set("Authorization", tc.header)
+			}
+
+			got, err := GetBearerToken(headers)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got token %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetBearerToken returned error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSignedValue(t *testing.T) {
+	const secret = "supersecret"
+	sig := SignValue("user-1", "unsubscribe", secret)
+
+	if !CheckSignedValue("user-1", "unsubscribe", sig, secret) {
+		t.Fatal("expected signature to verify")
+	}
+	if CheckSignedValue("user-2", "unsubscribe", sig, secret) {
+		t.Fatal("signature verified for a different value")
+	}
+	if CheckSignedValue("user-1", "reset", sig, secret) {
+		t.Fatal("signature verified for a different purpose")
+	}
+	if CheckSignedValue("user-1", "unsubscribe", sig, "othersecret") {
+		t.Fatal("signature verified with a different secret")
+	}
+}
+
+func FuzzValidateJWT(f *testing.F) {
+	good, err := MakeJWT(uuid.New(), "secret", time.Hour)
+	if err != nil {
+		f.Fatalf("MakeJWT returned error: %v", err)
+	}
+	f.Add(good)
+	f.Add("")
+	f.Add("a.b.c")
+	f.Add("eyJhbGciOiJub25lIn0.eyJzdWIiOiIxIn0.")
+	f.Add(strings.Repeat(".", 64))
+	f.Add("\xff\xfe.\x00.‮")
+
+	f.Fuzz(func(t *testing.T, token string) {
+		userID, actorID, err := ValidateJWTWithActor(token, "secret", 0)
+		if err == nil && userID == uuid.Nil {
+			t.Errorf("accepted %q without a subject", token)
+		}
+		if err != nil && (userID != uuid.Nil || actorID != uuid.Nil) {
+			t.Errorf("returned IDs alongside error %v", err)
+		}
+		DecodeJWTClaims(token)
+	})
+}
+
+func FuzzGetBearerToken(f *testing.F) {
+	f.Add("Bearer abc")
+	f.Add("bearer   abc  ")
+	f.Add("Bearer")
+	f.Add("Basic Zm9vOmJhcg==")
+	f.Add("Bearer \xff\xfe")
+
+	f.Fuzz(func(t *testing.T, header string) {
+		h := http.Header{}
+		h.Set("Authorization", header)
+		token, err := GetBearerToken(h)
+		if err == nil && (token == "" || token != strings.TrimSpace(token)) {
+			t.Errorf("GetBearerToken(%q) = %q", header, token)
+		}
+	})
+}
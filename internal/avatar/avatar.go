@@ -0,0 +1,107 @@
+// Package avatar makes the pictures shown for users who haven't uploaded
+// one: identicons drawn from a seed, or Gravatar links.
+package avatar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// cells is how many squares an identicon has along each side.
+	cells = 5
+	// cellSize and margin are in pixels.
+	cellSize = 70
+	margin   = 35
+	// Size is the width and height of an identicon.
+	Size = cells*cellSize + 2*margin
+)
+
+var background = color.RGBA{0xF0, 0xF0, 0xF0, 0xFF}
+
+// Identicon draws a symmetric pattern of squares in one colour, both
+// picked from seed, so the same seed always gives the same picture.
+func Identicon(seed []byte) *image.Paletted {
+	sum := sha256.Sum256(seed)
+	// The hue comes from the first bytes and the pattern from the rest, so
+	// the two don't go together.
+	hue := float64(uint16(sum[0])<<8|uint16(sum[1])) / 65536 * 360
+	img := image.NewPaletted(image.Rect(0, 0, Size, Size), color.Palette{background, hsl(hue, 0.55, 0.55)})
+
+	bits := sum[2:]
+	for col := 0; col < (cells+1)/2; col++ {
+		for row := 0; row < cells; row++ {
+			n := col*cells + row
+			if bits[n/8]>>(n%8)&1 == 0 {
+				continue
+			}
+			fill(img, col, row)
+			fill(img, cells-1-col, row)
+		}
+	}
+	return img
+}
+
+func fill(img *image.Paletted, col, row int) {
+	x0, y0 := margin+col*cellSize, margin+row*cellSize
+	for y := y0; y < y0+cellSize; y++ {
+		for x := x0; x < x0+cellSize; x++ {
+			img.SetColorIndex(x, y, 1)
+		}
+	}
+}
+
+// hsl converts a hue in degrees, saturation and lightness to RGB.
+func hsl(h, s, l float64) color.RGBA {
+	c := (1 - abs(2*l-1)) * s
+	hp := h / 60
+	x := c * (1 - abs(mod2(hp)-1))
+	var r, g, b float64
+	switch {
+	case hp < 1:
+		r, g = c, x
+	case hp < 2:
+		r, g = x, c
+	case hp < 3:
+		g, b = c, x
+	case hp < 4:
+		g, b = x, c
+	case hp < 5:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := l - c/2
+	return color.RGBA{uint8((r + m) * 255), uint8((g + m) * 255), uint8((b + m) * 255), 0xFF}
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+func mod2(f float64) float64 {
+	return f - 2*float64(int(f/2))
+}
+
+// GravatarURL links to the Gravatar for email. Gravatar sends fallback
+// instead for addresses without one; it must be a public URL, and when it
+// is empty Gravatar draws its own identicon.
+func GravatarURL(email, fallback string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	q := url.Values{}
+	q.Set("s", strconv.Itoa(Size))
+	if fallback != "" {
+		q.Set("d", fallback)
+	} else {
+		q.Set("d", "identicon")
+	}
+	return "https://gravatar.com/avatar/" + hex.EncodeToString(sum[:]) + "?" + q.Encode()
+}
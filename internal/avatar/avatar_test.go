@@ -0,0 +1,48 @@
+package avatar
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestIdenticon(t *testing.T) {
+	a, b := Identicon([]byte("alice")), Identicon([]byte("bob"))
+	if !bytes.Equal(a.Pix, Identicon([]byte("alice")).Pix) {
+		t.Error("expected the same seed to draw the same identicon")
+	}
+	if bytes.Equal(a.Pix, b.Pix) && a.Palette[1] == b.Palette[1] {
+		t.Error("expected different seeds to draw different identicons")
+	}
+	for y := 0; y < Size; y += cellSize / 2 {
+		for x := 0; x < Size/2; x += cellSize / 2 {
+			if a.ColorIndexAt(x, y) != a.ColorIndexAt(Size-1-x, y) {
+				t.Fatalf("expected a mirrored pattern, (%d,%d) differs", x, y)
+			}
+		}
+	}
+	if a.ColorIndexAt(margin/2, margin/2) != 0 {
+		t.Error("expected the margin to be background")
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() > 4096 {
+		t.Errorf("expected a small PNG, got %d bytes", buf.Len())
+	}
+}
+
+func TestGravatarURL(t *testing.T) {
+	got := GravatarURL(" Alice@Example.com ", "https://chirpy.example/api/avatars/1.png")
+	if got != GravatarURL("alice@example.com", "https://chirpy.example/api/avatars/1.png") {
+		t.Error("expected the address to be normalised before hashing")
+	}
+	if strings.Contains(got, "alice") || !strings.Contains(got, "d=https%3A%2F%2Fchirpy.example%2Fapi%2Favatars%2F1.png") {
+		t.Errorf("unexpected URL %s", got)
+	}
+	if !strings.Contains(GravatarURL("a@example.com", ""), "d=identicon") {
+		t.Error("expected Gravatar's identicon without a fallback")
+	}
+}
@@ -0,0 +1,136 @@
+// Package breaker implements a circuit breaker: after enough consecutive
+// failures it opens and turns callers away at once, instead of letting each
+// of them wait on something that is down, then lets a single probe through
+// now and then to see whether it has recovered.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Allow while the breaker is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is where a breaker is in its cycle.
+type State int
+
+const (
+	// Closed lets everything through.
+	Closed State = iota
+	// Open turns everything away until the cooldown has passed.
+	Open
+	// HalfOpen lets one probe through at a time. Its success closes the
+	// breaker and its failure opens it again.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker is safe for concurrent use. Callers ask Allow before doing the
+// work, and report how it went with Success or Failure. Outcomes count
+// whoever reports them, so work done without asking still trips or heals
+// the breaker.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	// changedAt is when the breaker last opened, or when the current probe
+	// was let through.
+	changedAt time.Time
+	trips     int64
+	rejected  int64
+}
+
+// New returns a closed breaker that opens after threshold consecutive
+// failures and probes again after cooldown. A threshold below one never
+// opens.
+func New(threshold int, cooldown time.Duration, now func() time.Time) *Breaker {
+	if now == nil {
+		now = time.Now
+	}
+	return &Breaker{threshold: threshold, cooldown: cooldown, now: now}
+}
+
+// Allow returns ErrOpen if the work should not be tried, and how long
+// until it is worth trying again. Once an open breaker has cooled down,
+// the next caller becomes the probe. A probe that never reports back is
+// replaced after another cooldown.
+func (b *Breaker) Allow() (retryAfter time.Duration, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Closed {
+		return 0, nil
+	}
+	if wait := b.changedAt.Add(b.cooldown).Sub(b.now()); wait > 0 {
+		b.rejected++
+		return wait, ErrOpen
+	}
+	b.state = HalfOpen
+	b.changedAt = b.now()
+	return 0, nil
+}
+
+// Success closes the breaker and clears its failures.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	if b.state == HalfOpen {
+		b.state = Closed
+	}
+}
+
+// Failure counts a failure, opening the breaker at the threshold or when
+// a probe fails.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	switch {
+	case b.state == HalfOpen:
+	case b.state == Closed && b.threshold > 0 && b.failures >= b.threshold:
+	default:
+		return
+	}
+	b.state = Open
+	b.changedAt = b.now()
+	b.trips++
+}
+
+// Stats describe a breaker at a point in time.
+type Stats struct {
+	State State
+	// Failures is the current run of consecutive failures.
+	Failures int
+	// Trips is how many times the breaker has opened.
+	Trips int64
+	// Rejected is how many callers Allow has turned away.
+	Rejected int64
+}
+
+func (b *Breaker) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return Stats{State: b.state, Failures: b.failures, Trips: b.trips, Rejected: b.rejected}
+}
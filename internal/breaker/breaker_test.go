@@ -0,0 +1,82 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func TestBreaker_Trips(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)}
+	b := New(3, 10*time.Second, clock.now)
+
+	b.Failure()
+	b.Failure()
+	b.Success()
+	b.Failure()
+	b.Failure()
+	if _, err := b.Allow(); err != nil {
+		t.Fatalf("a success should reset the failures, got %v", err)
+	}
+	b.Failure()
+	if got := b.Stats(); got.State != Open || got.Trips != 1 {
+		t.Fatalf("expected the third failure in a row to open the breaker, got %+v", got)
+	}
+
+	clock.t = clock.t.Add(4 * time.Second)
+	retryAfter, err := b.Allow()
+	if err != ErrOpen || retryAfter != 6*time.Second {
+		t.Errorf("Allow while open = %v, %v", retryAfter, err)
+	}
+	if got := b.Stats().Rejected; got != 1 {
+		t.Errorf("expected one rejection, got %d", got)
+	}
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)}
+	b := New(1, 10*time.Second, clock.now)
+	b.Failure()
+
+	clock.t = clock.t.Add(10 * time.Second)
+	if _, err := b.Allow(); err != nil {
+		t.Fatalf("expected a probe after the cooldown, got %v", err)
+	}
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Errorf("expected one probe at a time, got %v", err)
+	}
+
+	// A failed probe opens the breaker for another cooldown.
+	b.Failure()
+	if got := b.Stats(); got.State != Open || got.Trips != 2 {
+		t.Fatalf("expected the failed probe to reopen the breaker, got %+v", got)
+	}
+
+	// A probe that never reports back is replaced.
+	clock.t = clock.t.Add(10 * time.Second)
+	if _, err := b.Allow(); err != nil {
+		t.Fatalf("expected a probe, got %v", err)
+	}
+	clock.t = clock.t.Add(10 * time.Second)
+	if _, err := b.Allow(); err != nil {
+		t.Fatalf("expected the silent probe to be replaced, got %v", err)
+	}
+
+	b.Success()
+	if got := b.Stats(); got.State != Closed || got.Failures != 0 {
+		t.Errorf("expected a successful probe to close the breaker, got %+v", got)
+	}
+}
+
+func TestBreaker_NoThreshold(t *testing.T) {
+	b := New(0, time.Second, nil)
+	for range 100 {
+		b.Failure()
+	}
+	if _, err := b.Allow(); err != nil {
+		t.Errorf("a breaker without a threshold should never open, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+// Package buildinfo describes the running build, so a bug report can be
+// tied to the exact code it came from. Release builds set the version,
+// commit and build time with the linker:
+//
+//	go build -ldflags "\
+//	    -X chirpy/internal/buildinfo.version=1.4.0 \
+//	    -X chirpy/internal/buildinfo.commit=$(git rev-parse HEAD) \
+//	    -X chirpy/internal/buildinfo.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without them, the commit and time come from the VCS details go build
+// stamps into binaries built inside a git checkout.
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Set with -ldflags -X; see the package comment.
+var (
+	version   string
+	commit    string
+	buildTime string
+)
+
+// devVersion is the version of builds that weren't given one.
+const devVersion = "0.0.0-dev"
+
+// Info is what's known about a build.
+type Info struct {
+	// Version is a semantic version, without a leading v.
+	Version string
+	// Commit is the git commit built, ending in -dirty if the checkout
+	// had uncommitted changes. Empty if unknown.
+	Commit string
+	// BuildTime is when the binary was built, or for VCS details, when
+	// the commit was made. Zero if unknown.
+	BuildTime time.Time
+	GoVersion string
+}
+
+// Get returns the running build's Info.
+var Get = sync.OnceValue(func() Info {
+	bi, _ := debug.ReadBuildInfo()
+	return read(version, commit, buildTime, bi)
+})
+
+// read makes an Info from the linker-set values, falling back to bi, which
+// may be nil.
+func read(version, commit, buildTime string, bi *debug.BuildInfo) Info {
+	info := Info{
+		Version:   strings.TrimPrefix(version, "v"),
+		Commit:    commit,
+		GoVersion: runtime.Version(),
+	}
+	info.BuildTime, _ = time.Parse(time.RFC3339, buildTime)
+
+	if bi != nil {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = strings.TrimPrefix(bi.Main.Version, "v")
+		}
+		if info.Commit == "" && info.BuildTime.IsZero() {
+			var modified bool
+			for _, s := range bi.Settings {
+				switch s.Key {
+				case "vcs.revision":
+					info.Commit = s.Value
+				case "vcs.time":
+					info.BuildTime, _ = time.Parse(time.RFC3339, s.Value)
+				case "vcs.modified":
+					modified = s.Value == "true"
+				}
+			}
+			if modified && info.Commit != "" {
+				info.Commit += "-dirty"
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = devVersion
+	}
+	return info
+}
+
+// String describes the build in one line, for logs.
+func (i Info) String() string {
+	commit := i.Commit
+	if commit == "" {
+		commit = "unknown"
+	}
+	built := "unknown"
+	if !i.BuildTime.IsZero() {
+		built = i.BuildTime.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprintf("chirpy %s (commit %s, built %s, %s)", i.Version, commit, built, i.GoVersion)
+}
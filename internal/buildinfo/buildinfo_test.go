@@ -0,0 +1,74 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+func TestRead(t *testing.T) {
+	vcs := &debug.BuildInfo{
+		Main: debug.Module{Version: "(devel)"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "4f1c2d9"},
+			{Key: "vcs.time", Value: "2026-10-01T09:30:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	tests := []struct {
+		name                       string
+		version, commit, buildTime string
+		bi                         *debug.BuildInfo
+		want                       Info
+	}{
+		{
+			name:    "ldflags",
+			version: "v1.4.0", commit: "abc123", buildTime: "2026-10-16T08:00:00Z",
+			bi: vcs,
+			want: Info{
+				Version:   "1.4.0",
+				Commit:    "abc123",
+				BuildTime: time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "vcs stamp",
+			bi:   vcs,
+			want: Info{
+				Version:   devVersion,
+				Commit:    "4f1c2d9-dirty",
+				BuildTime: time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "go install",
+			bi:   &debug.BuildInfo{Main: debug.Module{Version: "v1.3.2"}},
+			want: Info{Version: "1.3.2"},
+		},
+		{
+			name: "nothing known",
+			want: Info{Version: devVersion},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := read(tt.version, tt.commit, tt.buildTime, tt.bi)
+			tt.want.GoVersion = runtime.Version()
+			if got != tt.want {
+				t.Fatalf("read() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInfoString(t *testing.T) {
+	i := Info{Version: "1.4.0", Commit: "abc123", BuildTime: time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC), GoVersion: "go1.27.1"}
+	if got, want := i.String(), "chirpy 1.4.0 (commit abc123, built 2026-10-16T08:00:00Z, go1.27.1)"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+	if got, want := (Info{Version: devVersion, GoVersion: "go1.27.1"}).String(), "chirpy 0.0.0-dev (commit unknown, built unknown, go1.27.1)"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
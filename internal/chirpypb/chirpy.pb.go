@@ -0,0 +1,554 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: chirpy.proto
+
+package chirpypb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type User struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Handle        string                 `protobuf:"bytes,2,opt,name=handle,proto3" json:"handle,omitempty"`
+	Verified      bool                   `protobuf:"varint,3,opt,name=verified,proto3" json:"verified,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *User) Reset() {
+	*x = User{}
+	mi := &file_chirpy_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *User) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*User) ProtoMessage() {}
+
+func (x *User) ProtoReflect() protoreflect.Message {
+	mi := &file_chirpy_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use User.ProtoReflect.Descriptor instead.
+func (*User) Descriptor() ([]byte, []int) {
+	return file_chirpy_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *User) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *User) GetHandle() string {
+	if x != nil {
+		return x.Handle
+	}
+	return ""
+}
+
+func (x *User) GetVerified() bool {
+	if x != nil {
+		return x.Verified
+	}
+	return false
+}
+
+func (x *User) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type Chirp struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Body           string                 `protobuf:"bytes,2,opt,name=body,proto3" json:"body,omitempty"`
+	UserId         string                 `protobuf:"bytes,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	AuthorVerified bool                   `protobuf:"varint,4,opt,name=author_verified,json=authorVerified,proto3" json:"author_verified,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// Sensitive chirps should be shown collapsed, behind content_warning
+	// when the author gave one.
+	Sensitive      bool   `protobuf:"varint,7,opt,name=sensitive,proto3" json:"sensitive,omitempty"`
+	ContentWarning string `protobuf:"bytes,8,opt,name=content_warning,json=contentWarning,proto3" json:"content_warning,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Chirp) Reset() {
+	*x = Chirp{}
+	mi := &file_chirpy_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Chirp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Chirp) ProtoMessage() {}
+
+func (x *Chirp) ProtoReflect() protoreflect.Message {
+	mi := &file_chirpy_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Chirp.ProtoReflect.Descriptor instead.
+func (*Chirp) Descriptor() ([]byte, []int) {
+	return file_chirpy_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Chirp) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Chirp) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+func (x *Chirp) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Chirp) GetAuthorVerified() bool {
+	if x != nil {
+		return x.AuthorVerified
+	}
+	return false
+}
+
+func (x *Chirp) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Chirp) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Chirp) GetSensitive() bool {
+	if x != nil {
+		return x.Sensitive
+	}
+	return false
+}
+
+func (x *Chirp) GetContentWarning() string {
+	if x != nil {
+		return x.ContentWarning
+	}
+	return ""
+}
+
+type CreateChirpRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Body          string                 `protobuf:"bytes,1,opt,name=body,proto3" json:"body,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateChirpRequest) Reset() {
+	*x = CreateChirpRequest{}
+	mi := &file_chirpy_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateChirpRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateChirpRequest) ProtoMessage() {}
+
+func (x *CreateChirpRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chirpy_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateChirpRequest.ProtoReflect.Descriptor instead.
+func (*CreateChirpRequest) Descriptor() ([]byte, []int) {
+	return file_chirpy_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateChirpRequest) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+type GetTimelineRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Defaults to 20; capped at 100.
+	Limit         int32 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTimelineRequest) Reset() {
+	*x = GetTimelineRequest{}
+	mi := &file_chirpy_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTimelineRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTimelineRequest) ProtoMessage() {}
+
+func (x *GetTimelineRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chirpy_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTimelineRequest.ProtoReflect.Descriptor instead.
+func (*GetTimelineRequest) Descriptor() ([]byte, []int) {
+	return file_chirpy_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetTimelineRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type GetTimelineResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Chirps        []*Chirp               `protobuf:"bytes,1,rep,name=chirps,proto3" json:"chirps,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTimelineResponse) Reset() {
+	*x = GetTimelineResponse{}
+	mi := &file_chirpy_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTimelineResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTimelineResponse) ProtoMessage() {}
+
+func (x *GetTimelineResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_chirpy_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTimelineResponse.ProtoReflect.Descriptor instead.
+func (*GetTimelineResponse) Descriptor() ([]byte, []int) {
+	return file_chirpy_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetTimelineResponse) GetChirps() []*Chirp {
+	if x != nil {
+		return x.Chirps
+	}
+	return nil
+}
+
+type GetUserRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Lookup:
+	//
+	//	*GetUserRequest_Id
+	//	*GetUserRequest_Handle
+	Lookup        isGetUserRequest_Lookup `protobuf_oneof:"lookup"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUserRequest) Reset() {
+	*x = GetUserRequest{}
+	mi := &file_chirpy_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUserRequest) ProtoMessage() {}
+
+func (x *GetUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chirpy_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUserRequest.ProtoReflect.Descriptor instead.
+func (*GetUserRequest) Descriptor() ([]byte, []int) {
+	return file_chirpy_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetUserRequest) GetLookup() isGetUserRequest_Lookup {
+	if x != nil {
+		return x.Lookup
+	}
+	return nil
+}
+
+func (x *GetUserRequest) GetId() string {
+	if x != nil {
+		if x, ok := x.Lookup.(*GetUserRequest_Id); ok {
+			return x.Id
+		}
+	}
+	return ""
+}
+
+func (x *GetUserRequest) GetHandle() string {
+	if x != nil {
+		if x, ok := x.Lookup.(*GetUserRequest_Handle); ok {
+			return x.Handle
+		}
+	}
+	return ""
+}
+
+type isGetUserRequest_Lookup interface {
+	isGetUserRequest_Lookup()
+}
+
+type GetUserRequest_Id struct {
+	Id string `protobuf:"bytes,1,opt,name=id,proto3,oneof"`
+}
+
+type GetUserRequest_Handle struct {
+	Handle string `protobuf:"bytes,2,opt,name=handle,proto3,oneof"`
+}
+
+func (*GetUserRequest_Id) isGetUserRequest_Lookup() {}
+
+func (*GetUserRequest_Handle) isGetUserRequest_Lookup() {}
+
+type StreamChirpsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamChirpsRequest) Reset() {
+	*x = StreamChirpsRequest{}
+	mi := &file_chirpy_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamChirpsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamChirpsRequest) ProtoMessage() {}
+
+func (x *StreamChirpsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chirpy_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamChirpsRequest.ProtoReflect.Descriptor instead.
+func (*StreamChirpsRequest) Descriptor() ([]byte, []int) {
+	return file_chirpy_proto_rawDescGZIP(), []int{6}
+}
+
+var File_chirpy_proto protoreflect.FileDescriptor
+
+const file_chirpy_proto_rawDesc = "" +
+	"\n" +
+	"\fchirpy.proto\x12\tchirpy.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x85\x01\n" +
+	"\x04User\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06handle\x18\x02 \x01(\tR\x06handle\x12\x1a\n" +
+	"\bverified\x18\x03 \x01(\bR\bverified\x129\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\xaa\x02\n" +
+	"\x05Chirp\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04body\x18\x02 \x01(\tR\x04body\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\tR\x06userId\x12'\n" +
+	"\x0fauthor_verified\x18\x04 \x01(\bR\x0eauthorVerified\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x1c\n" +
+	"\tsensitive\x18\a \x01(\bR\tsensitive\x12'\n" +
+	"\x0fcontent_warning\x18\b \x01(\tR\x0econtentWarning\"(\n" +
+	"\x12CreateChirpRequest\x12\x12\n" +
+	"\x04body\x18\x01 \x01(\tR\x04body\"*\n" +
+	"\x12GetTimelineRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\"?\n" +
+	"\x13GetTimelineResponse\x12(\n" +
+	"\x06chirps\x18\x01 \x03(\v2\x10.chirpy.v1.ChirpR\x06chirps\"F\n" +
+	"\x0eGetUserRequest\x12\x10\n" +
+	"\x02id\x18\x01 \x01(\tH\x00R\x02id\x12\x18\n" +
+	"\x06handle\x18\x02 \x01(\tH\x00R\x06handleB\b\n" +
+	"\x06lookup\"\x15\n" +
+	"\x13StreamChirpsRequest2\x98\x02\n" +
+	"\rChirpyService\x12>\n" +
+	"\vCreateChirp\x12\x1d.chirpy.v1.CreateChirpRequest\x1a\x10.chirpy.v1.Chirp\x12L\n" +
+	"\vGetTimeline\x12\x1d.chirpy.v1.GetTimelineRequest\x1a\x1e.chirpy.v1.GetTimelineResponse\x125\n" +
+	"\aGetUser\x12\x19.chirpy.v1.GetUserRequest\x1a\x0f.chirpy.v1.User\x12B\n" +
+	"\fStreamChirps\x12\x1e.chirpy.v1.StreamChirpsRequest\x1a\x10.chirpy.v1.Chirp0\x01B\x1aZ\x18chirpy/internal/chirpypbb\x06proto3"
+
+var (
+	file_chirpy_proto_rawDescOnce sync.Once
+	file_chirpy_proto_rawDescData []byte
+)
+
+func file_chirpy_proto_rawDescGZIP() []byte {
+	file_chirpy_proto_rawDescOnce.Do(func() {
+		file_chirpy_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_chirpy_proto_rawDesc), len(file_chirpy_proto_rawDesc)))
+	})
+	return file_chirpy_proto_rawDescData
+}
+
+var file_chirpy_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_chirpy_proto_goTypes = []any{
+	(*User)(nil),                  // 0: chirpy.v1.User
+	(*Chirp)(nil),                 // 1: chirpy.v1.Chirp
+	(*CreateChirpRequest)(nil),    // 2: chirpy.v1.CreateChirpRequest
+	(*GetTimelineRequest)(nil),    // 3: chirpy.v1.GetTimelineRequest
+	(*GetTimelineResponse)(nil),   // 4: chirpy.v1.GetTimelineResponse
+	(*GetUserRequest)(nil),        // 5: chirpy.v1.GetUserRequest
+	(*StreamChirpsRequest)(nil),   // 6: chirpy.v1.StreamChirpsRequest
+	(*timestamppb.Timestamp)(nil), // 7: google.protobuf.Timestamp
+}
+var file_chirpy_proto_depIdxs = []int32{
+	7, // 0: chirpy.v1.User.created_at:type_name -> google.protobuf.Timestamp
+	7, // 1: chirpy.v1.Chirp.created_at:type_name -> google.protobuf.Timestamp
+	7, // 2: chirpy.v1.Chirp.updated_at:type_name -> google.protobuf.Timestamp
+	1, // 3: chirpy.v1.GetTimelineResponse.chirps:type_name -> chirpy.v1.Chirp
+	2, // 4: chirpy.v1.ChirpyService.CreateChirp:input_type -> chirpy.v1.CreateChirpRequest
+	3, // 5: chirpy.v1.ChirpyService.GetTimeline:input_type -> chirpy.v1.GetTimelineRequest
+	5, // 6: chirpy.v1.ChirpyService.GetUser:input_type -> chirpy.v1.GetUserRequest
+	6, // 7: chirpy.v1.ChirpyService.StreamChirps:input_type -> chirpy.v1.StreamChirpsRequest
+	1, // 8: chirpy.v1.ChirpyService.CreateChirp:output_type -> chirpy.v1.Chirp
+	4, // 9: chirpy.v1.ChirpyService.GetTimeline:output_type -> chirpy.v1.GetTimelineResponse
+	0, // 10: chirpy.v1.ChirpyService.GetUser:output_type -> chirpy.v1.User
+	1, // 11: chirpy.v1.ChirpyService.StreamChirps:output_type -> chirpy.v1.Chirp
+	8, // [8:12] is the sub-list for method output_type
+	4, // [4:8] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_chirpy_proto_init() }
+func file_chirpy_proto_init() {
+	if File_chirpy_proto != nil {
+		return
+	}
+	file_chirpy_proto_msgTypes[5].OneofWrappers = []any{
+		(*GetUserRequest_Id)(nil),
+		(*GetUserRequest_Handle)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_chirpy_proto_rawDesc), len(file_chirpy_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_chirpy_proto_goTypes,
+		DependencyIndexes: file_chirpy_proto_depIdxs,
+		MessageInfos:      file_chirpy_proto_msgTypes,
+	}.Build()
+	File_chirpy_proto = out.File
+	file_chirpy_proto_goTypes = nil
+	file_chirpy_proto_depIdxs = nil
+}
@@ -0,0 +1,539 @@
+// Package config loads Chirpy's settings from the environment.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"chirpy/internal/analytics"
+	"chirpy/internal/linkscan"
+	"chirpy/internal/mail"
+	"chirpy/internal/scheduler"
+	"chirpy/internal/storage"
+	"chirpy/internal/stripe"
+
+	"github.com/joho/godotenv"
+)
+
+const (
+	// DefaultStaticDir is where the frontend lives in the source tree.
+	DefaultStaticDir = "web"
+	DefaultAppPrefix = "/app/"
+
+	DefaultResponseCacheTTL      = 5 * time.Second
+	DefaultResponseCacheMaxBytes = 16 << 20
+	DefaultSlowQueryThreshold    = 500 * time.Millisecond
+	DefaultJWTLeeway             = 5 * time.Second
+	DefaultAppDailyQuota         = 10000
+
+	// maxReactionLength bounds each configured reaction, in bytes. It fits
+	// emoji built from several code points, such as flags and families.
+	maxReactionLength = 32
+)
+
+// DefaultReactions are the emoji users can react with when REACTIONS is
+// unset.
+var DefaultReactions = []string{"👍", "❤️", "😂", "😮", "😢", "🎉"}
+
+// STRICT_JSON values.
+const (
+	// StrictJSONV1 decodes /api/v1 request bodies strictly. It is the
+	// default.
+	StrictJSONV1  = "v1"
+	StrictJSONAll = "all"
+	StrictJSONOff = "off"
+)
+
+type Config struct {
+	DBURL     string `json:"db_url"`
+	Port      string `json:"port"`
+	Platform  string `json:"platform"`
+	JWTSecret string `json:"-"`
+	// JWTLeeway is how much clock skew is allowed when checking a token's
+	// exp and nbf, for tokens minted by services whose clocks run ahead.
+	JWTLeeway time.Duration `json:"jwt_leeway"`
+	// TrustProxyHeaders makes client IP detection honour X-Forwarded-For.
+	// Only enable it behind a proxy that overwrites the header.
+	TrustProxyHeaders bool `json:"trust_proxy_headers"`
+	// PublicURL is the externally visible base URL, e.g.
+	// https://chirpy.example. Federation is disabled when it is empty.
+	PublicURL string `json:"public_url"`
+	// GRPCPort enables the internal gRPC service on its own port.
+	GRPCPort string `json:"grpc_port"`
+	// SCIMToken is the provisioning API key for /scim/v2. SCIM is
+	// disabled when it is empty.
+	SCIMToken string `json:"-"`
+	// IntrospectionKey is the API key services present to
+	// /api/introspect to check access tokens without holding JWT_SECRET.
+	// Introspection is disabled when it is empty.
+	IntrospectionKey string `json:"-"`
+	// AppDailyQuota is how many requests each third-party app may make a
+	// UTC day with its tokens, across all its users, unless an admin gave
+	// it a quota of its own. Zero means no limit.
+	AppDailyQuota int `json:"app_daily_quota"`
+	// StaticDir is a directory to serve under AppPrefix instead of the
+	// frontend built into the binary. Only files in it are exposed, and
+	// never dotfiles.
+	StaticDir string `json:"static_dir"`
+	// RequireAltText rejects chirps attaching media without alt text.
+	RequireAltText bool `json:"require_alt_text"`
+	// Gravatar shows users their Gravatar, falling back to an identicon,
+	// rather than only the identicon. It makes a hash of each user's email
+	// address public.
+	Gravatar bool `json:"gravatar"`
+	// AppPrefix is the URL path of the frontend, with leading and trailing
+	// slashes.
+	AppPrefix string `json:"app_prefix"`
+	// DevAssets reads the frontend, page templates and email templates from
+	// the source tree on every request instead of the copies built into the
+	// binary, so edits show up without a rebuild. Run from the repository
+	// root when it is set.
+	DevAssets bool `json:"dev_assets"`
+	// MultiTenant serves the communities in the tenants table alongside
+	// the default one, each on its hostname or under /t/{slug}/. The
+	// database role must not bypass row-level security.
+	MultiTenant bool `json:"multi_tenant"`
+	// ResponseCacheTTL is how long anonymous responses of hot listings are
+	// reused. Zero disables the response cache.
+	ResponseCacheTTL time.Duration `json:"response_cache_ttl"`
+	// ResponseCacheMaxBytes bounds the total size of cached responses.
+	ResponseCacheMaxBytes int `json:"response_cache_max_bytes"`
+	// StrictJSON chooses which endpoints reject request bodies with
+	// unknown fields or trailing data: StrictJSONV1, StrictJSONAll or
+	// StrictJSONOff.
+	StrictJSON string `json:"strict_json"`
+	// HTTP bounds how long clients may take and how much they may send.
+	HTTP HTTPConfig `json:"http"`
+	// DBBreaker configures the circuit breakers that turn requests away
+	// while the database is failing.
+	DBBreaker BreakerConfig `json:"db_breaker"`
+	// SlowQueryThreshold is how long a query may take before it's logged
+	// and counted as slow. Zero turns slow query logging off.
+	SlowQueryThreshold time.Duration `json:"slow_query_threshold"`
+	// Analytics configures where product events go. ANALYTICS_SINK is
+	// "postgres", "jsonl" (to ANALYTICS_FILE) or "http" (to ANALYTICS_URL);
+	// analytics are off when it is unset.
+	Analytics analytics.Config `json:"analytics"`
+	// Reactions are the emoji users can react to chirps with, read from the
+	// comma-separated REACTIONS. Load fills in DefaultReactions.
+	Reactions []string `json:"reactions"`
+	// Schedules are when the periodic jobs run.
+	Schedules Schedules `json:"schedules"`
+	// Retention decides how long old data is kept before the cleanup job
+	// removes it.
+	Retention RetentionConfig `json:"retention"`
+	// LinkScan configures checking the links in chirps: LINK_BLOCKLIST is
+	// comma-separated domains, and LINK_SCAN_URL or LINK_SCAN_KEY enable a
+	// Safe Browsing lookup. Links aren't checked when all are unset.
+	LinkScan linkscan.Config `json:"link_scan"`
+	// Media is where uploaded images and videos are stored. MEDIA_STORAGE
+	// is "local" (the default when MEDIA_DIR is set) or "s3", configured
+	// by the S3_ variables; uploads are off when both are unset.
+	Media storage.Config `json:"media"`
+	// Mail configures outgoing email. MAIL_PROVIDER is "log" (the
+	// default, which only logs messages) or "smtp".
+	Mail mail.Config `json:"-"`
+	// Stripe sells Chirpy Red subscriptions through Stripe Checkout. It is
+	// configured by the STRIPE_ variables; billing is off when
+	// STRIPE_SECRET_KEY is unset.
+	Stripe stripe.Config `json:"stripe"`
+}
+
+// Load reads the configuration from the environment, after loading a .env
+// file if there is one.
+func Load() (*Config, error) {
+	err := godotenv.Load()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	cfg := &Config{
+		DBURL:             utcSession(os.Getenv("DB_URL")),
+		Port:              os.Getenv("PORT"),
+		Platform:          os.Getenv("PLATFORM"),
+		JWTSecret:         os.Getenv("JWT_SECRET"),
+		TrustProxyHeaders: os.Getenv("TRUST_PROXY_HEADERS") == "true",
+		PublicURL:         strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/"),
+		GRPCPort:          os.Getenv("GRPC_PORT"),
+		SCIMToken:         os.Getenv("SCIM_TOKEN"),
+		IntrospectionKey:  os.Getenv("INTROSPECTION_KEY"),
+		StaticDir:         os.Getenv("STATIC_DIR"),
+		RequireAltText:    os.Getenv("REQUIRE_ALT_TEXT") == "true",
+		Gravatar:          os.Getenv("GRAVATAR") == "true",
+		AppPrefix:         os.Getenv("APP_PREFIX"),
+		DevAssets:         os.Getenv("DEV_ASSETS") == "true",
+		MultiTenant:       os.Getenv("MULTI_TENANT") == "true",
+		StrictJSON:        os.Getenv("STRICT_JSON"),
+		Analytics: analytics.Config{
+			Sink: os.Getenv("ANALYTICS_SINK"),
+			File: os.Getenv("ANALYTICS_FILE"),
+			URL:  os.Getenv("ANALYTICS_URL"),
+		},
+		LinkScan: linkscan.Config{
+			Blocklist:       parseList(os.Getenv("LINK_BLOCKLIST")),
+			SafeBrowsingURL: os.Getenv("LINK_SCAN_URL"),
+			SafeBrowsingKey: os.Getenv("LINK_SCAN_KEY"),
+		},
+		Media: storage.Config{
+			Backend: os.Getenv("MEDIA_STORAGE"),
+			Dir:     os.Getenv("MEDIA_DIR"),
+			S3: storage.S3Config{
+				Endpoint:  os.Getenv("S3_ENDPOINT"),
+				Region:    os.Getenv("S3_REGION"),
+				Bucket:    os.Getenv("S3_BUCKET"),
+				PathStyle: os.Getenv("S3_PATH_STYLE") == "true",
+				AccessKey: os.Getenv("S3_ACCESS_KEY"),
+				SecretKey: os.Getenv("S3_SECRET_KEY"),
+			},
+		},
+		Stripe: stripe.Config{
+			SecretKey:     os.Getenv("STRIPE_SECRET_KEY"),
+			WebhookSecret: os.Getenv("STRIPE_WEBHOOK_SECRET"),
+			PriceID:       os.Getenv("STRIPE_PRICE_ID"),
+			APIURL:        os.Getenv("STRIPE_API_URL"),
+			SuccessURL:    os.Getenv("STRIPE_SUCCESS_URL"),
+			CancelURL:     os.Getenv("STRIPE_CANCEL_URL"),
+		},
+		Mail: mail.Config{
+			Provider: os.Getenv("MAIL_PROVIDER"),
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		},
+	}
+
+	if cfg.Media.Backend == "" && cfg.Media.Dir != "" {
+		cfg.Media.Backend = "local"
+	}
+	if cfg.JWTLeeway, err = durationEnv("JWT_LEEWAY", DefaultJWTLeeway); err != nil {
+		return nil, err
+	}
+	if cfg.AppDailyQuota, err = intEnv("APP_DAILY_QUOTA", DefaultAppDailyQuota, 0); err != nil {
+		return nil, err
+	}
+	if cfg.ResponseCacheTTL, err = durationEnv("RESPONSE_CACHE_TTL", DefaultResponseCacheTTL); err != nil {
+		return nil, err
+	}
+	if cfg.ResponseCacheMaxBytes, err = intEnv("RESPONSE_CACHE_MAX_BYTES", DefaultResponseCacheMaxBytes, 1); err != nil {
+		return nil, err
+	}
+	if cfg.HTTP, err = loadHTTPConfig(); err != nil {
+		return nil, err
+	}
+	if cfg.DBBreaker, err = loadBreakerConfig(); err != nil {
+		return nil, err
+	}
+	if cfg.SlowQueryThreshold, err = durationEnv("SLOW_QUERY_THRESHOLD", DefaultSlowQueryThreshold); err != nil {
+		return nil, err
+	}
+	if cfg.Reactions, err = parseReactions(os.Getenv("REACTIONS")); err != nil {
+		return nil, err
+	}
+	if cfg.Retention, err = loadRetentionConfig(); err != nil {
+		return nil, err
+	}
+	if cfg.Schedules, err = loadSchedules(); err != nil {
+		return nil, err
+	}
+
+	switch cfg.StrictJSON {
+	case "":
+		cfg.StrictJSON = StrictJSONV1
+	case StrictJSONV1, StrictJSONAll, StrictJSONOff:
+	default:
+		return nil, fmt.Errorf("STRICT_JSON must be %q, %q or %q, got %q", StrictJSONV1, StrictJSONAll, StrictJSONOff, cfg.StrictJSON)
+	}
+
+	if cfg.Port == "" {
+		cfg.Port = "8080"
+	}
+	if cfg.StaticDir == "" && cfg.DevAssets {
+		cfg.StaticDir = DefaultStaticDir
+	}
+	cfg.AppPrefix = NormalizePrefix(cfg.AppPrefix)
+
+	// Checkout returns users to the frontend unless told otherwise.
+	if cfg.PublicURL != "" {
+		app := strings.TrimSuffix(cfg.PublicURL, "/") + cfg.AppPrefix
+		if cfg.Stripe.SuccessURL == "" {
+			cfg.Stripe.SuccessURL = app + "?checkout=success"
+		}
+		if cfg.Stripe.CancelURL == "" {
+			cfg.Stripe.CancelURL = app + "?checkout=cancelled"
+		}
+	}
+	if err := cfg.Stripe.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// utcSession sets the session time zone of connections made with the
+// Postgres connection string dsn to UTC, unless it already sets one. NOW()
+// then fills TIMESTAMP columns in UTC whatever the server's TimeZone.
+func utcSession(dsn string) string {
+	if dsn == "" {
+		return ""
+	}
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			// Leave it for the driver to report.
+			return dsn
+		}
+		q := u.Query()
+		if q.Get("timezone") == "" {
+			q.Set("timezone", "UTC")
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+	for _, field := range strings.Fields(dsn) {
+		if strings.HasPrefix(field, "timezone=") {
+			return dsn
+		}
+	}
+	return dsn + " timezone=UTC"
+}
+
+// parseList splits a comma-separated value, dropping empty entries.
+func parseList(v string) []string {
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// parseReactions splits the comma-separated REACTIONS value, or returns
+// DefaultReactions when it is empty.
+func parseReactions(v string) ([]string, error) {
+	if strings.TrimSpace(v) == "" {
+		return DefaultReactions, nil
+	}
+	var reactions []string
+	seen := make(map[string]bool)
+	for _, r := range strings.Split(v, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" || seen[r] {
+			continue
+		}
+		if len(r) > maxReactionLength {
+			return nil, fmt.Errorf("REACTIONS entries must be at most %d bytes, got %q", maxReactionLength, r)
+		}
+		seen[r] = true
+		reactions = append(reactions, r)
+	}
+	return reactions, nil
+}
+
+// NormalizePrefix returns prefix with a leading and trailing slash, or
+// DefaultAppPrefix when it is empty.
+func NormalizePrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return DefaultAppPrefix
+	}
+	return "/" + prefix + "/"
+}
+
+// HTTPConfig configures the HTTP server. Zero durations mean no timeout;
+// Load fills in the defaults.
+type HTTPConfig struct {
+	// ReadHeaderTimeout is what stops slowloris: clients that trickle
+	// headers to hold connections open.
+	ReadHeaderTimeout time.Duration `json:"read_header_timeout"`
+	ReadTimeout       time.Duration `json:"read_timeout"`
+	// WriteTimeout does not apply to streaming endpoints such as the chirp
+	// event stream and exports, which lift it per request.
+	WriteTimeout time.Duration `json:"write_timeout"`
+	IdleTimeout  time.Duration `json:"idle_timeout"`
+	// RequestTimeout cancels a request's database work once it has run
+	// this long. Streaming endpoints lift it per request.
+	RequestTimeout time.Duration `json:"request_timeout"`
+	MaxHeaderBytes int           `json:"max_header_bytes"`
+	// MaxConnections caps concurrently open connections. Zero is no cap.
+	MaxConnections int `json:"max_connections"`
+}
+
+func loadHTTPConfig() (HTTPConfig, error) {
+	var c HTTPConfig
+	var err error
+	if c.ReadHeaderTimeout, err = durationEnv("HTTP_READ_HEADER_TIMEOUT", 5*time.Second); err != nil {
+		return c, err
+	}
+	if c.ReadTimeout, err = durationEnv("HTTP_READ_TIMEOUT", 30*time.Second); err != nil {
+		return c, err
+	}
+	if c.WriteTimeout, err = durationEnv("HTTP_WRITE_TIMEOUT", 60*time.Second); err != nil {
+		return c, err
+	}
+	if c.IdleTimeout, err = durationEnv("HTTP_IDLE_TIMEOUT", 120*time.Second); err != nil {
+		return c, err
+	}
+	if c.RequestTimeout, err = durationEnv("HTTP_REQUEST_TIMEOUT", 30*time.Second); err != nil {
+		return c, err
+	}
+	if c.MaxHeaderBytes, err = intEnv("HTTP_MAX_HEADER_BYTES", 64<<10, 1); err != nil {
+		return c, err
+	}
+	if c.MaxConnections, err = intEnv("HTTP_MAX_CONNECTIONS", 0, 0); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// BreakerConfig configures a circuit breaker.
+type BreakerConfig struct {
+	// Threshold is how many failures in a row open the breaker. Zero
+	// never opens it.
+	Threshold int `json:"threshold"`
+	// Cooldown is how long an open breaker turns work away before it lets
+	// a probe through.
+	Cooldown time.Duration `json:"cooldown"`
+}
+
+func loadBreakerConfig() (BreakerConfig, error) {
+	var c BreakerConfig
+	var err error
+	if c.Threshold, err = intEnv("DB_BREAKER_THRESHOLD", 5, 0); err != nil {
+		return c, err
+	}
+	if c.Cooldown, err = durationEnv("DB_BREAKER_COOLDOWN", 10*time.Second); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// RetentionConfig sets how old data may get before the cleanup job removes
+// it. A zero age keeps that data forever.
+type RetentionConfig struct {
+	// DeletedChirps is how long soft-deleted chirps are kept before they
+	// are purged for good.
+	DeletedChirps time.Duration `json:"deleted_chirps"`
+	// Emails is how long sent and dead emails are kept.
+	Emails time.Duration `json:"emails"`
+	// OutboxEvents is how long published and dead outbox events are kept.
+	OutboxEvents time.Duration `json:"outbox_events"`
+	// IPActivity is how long an IP's signup and login failure counts are
+	// kept after it was last seen.
+	IPActivity      time.Duration `json:"ip_activity"`
+	AnalyticsEvents time.Duration `json:"analytics_events"`
+	// RequestCaptures is how long captured requests are kept for replay.
+	RequestCaptures time.Duration `json:"request_captures"`
+	// DryRun makes the cleanup job count what it would remove without
+	// removing it.
+	DryRun bool `json:"dry_run"`
+}
+
+func loadRetentionConfig() (RetentionConfig, error) {
+	const day = 24 * time.Hour
+	c := RetentionConfig{DryRun: os.Getenv("RETENTION_DRY_RUN") == "true"}
+	var err error
+	if c.DeletedChirps, err = durationEnv("RETENTION_DELETED_CHIRPS", 30*day); err != nil {
+		return c, err
+	}
+	if c.Emails, err = durationEnv("RETENTION_EMAILS", 90*day); err != nil {
+		return c, err
+	}
+	if c.OutboxEvents, err = durationEnv("RETENTION_OUTBOX_EVENTS", 7*day); err != nil {
+		return c, err
+	}
+	if c.IPActivity, err = durationEnv("RETENTION_IP_ACTIVITY", 90*day); err != nil {
+		return c, err
+	}
+	if c.AnalyticsEvents, err = durationEnv("RETENTION_ANALYTICS_EVENTS", 0); err != nil {
+		return c, err
+	}
+	if c.RequestCaptures, err = durationEnv("RETENTION_REQUEST_CAPTURES", 7*day); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// Schedules holds a scheduler spec for each periodic job, such as
+// "@every 1h" or "30 3 * * *", read from SCHEDULE_<JOB>. "off" stops a job.
+type Schedules struct {
+	Digests     string `json:"digests"`
+	Sitemaps    string `json:"sitemaps"`
+	Suggestions string `json:"suggestions"`
+	Retention   string `json:"retention"`
+	// HashtagCounts recounts hashtag usage, which deletes let drift.
+	HashtagCounts string `json:"hashtag_counts"`
+	// Jitter delays each scheduled run by up to this long, so servers
+	// started together don't run jobs in lockstep.
+	Jitter time.Duration `json:"jitter"`
+}
+
+func loadSchedules() (Schedules, error) {
+	c := Schedules{
+		Digests:       scheduleEnv("SCHEDULE_DIGESTS", "@every 1h"),
+		Sitemaps:      scheduleEnv("SCHEDULE_SITEMAPS", "@every 1h"),
+		Suggestions:   scheduleEnv("SCHEDULE_SUGGESTIONS", "@every 6h"),
+		Retention:     scheduleEnv("SCHEDULE_RETENTION", "@every 1h"),
+		HashtagCounts: scheduleEnv("SCHEDULE_HASHTAG_COUNTS", "@daily"),
+	}
+	for name, spec := range map[string]string{
+		"SCHEDULE_DIGESTS":        c.Digests,
+		"SCHEDULE_SITEMAPS":       c.Sitemaps,
+		"SCHEDULE_SUGGESTIONS":    c.Suggestions,
+		"SCHEDULE_RETENTION":      c.Retention,
+		"SCHEDULE_HASHTAG_COUNTS": c.HashtagCounts,
+	} {
+		if spec == scheduler.Off {
+			continue
+		}
+		if _, err := scheduler.Parse(spec); err != nil {
+			return c, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	var err error
+	if c.Jitter, err = durationEnv("SCHEDULE_JITTER", 30*time.Second); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+func scheduleEnv(name, def string) string {
+	if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+		return v
+	}
+	return def
+}
+
+// durationEnv reads a non-negative duration such as "5s" from the
+// environment variable name, or returns def when it is unset.
+func durationEnv(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative duration such as 5s, got %q", name, v)
+	}
+	return d, nil
+}
+
+// intEnv reads an integer no smaller than least from the environment variable
+// name, or returns def when it is unset.
+func intEnv(name string, def, least int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < least {
+		return 0, fmt.Errorf("%s must be an integer of at least %d, got %q", name, least, v)
+	}
+	return n, nil
+}
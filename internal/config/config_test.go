@@ -0,0 +1,151 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadHTTPConfig_Defaults(t *testing.T) {
+	c, err := loadHTTPConfig()
+	if err != nil {
+		t.Fatalf("loadHTTPConfig returned error: %v", err)
+	}
+	want := HTTPConfig{
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		RequestTimeout:    30 * time.Second,
+		MaxHeaderBytes:    64 << 10,
+	}
+	if c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+}
+
+func TestLoadHTTPConfig_FromEnv(t *testing.T) {
+	t.Setenv("HTTP_READ_HEADER_TIMEOUT", "2s")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "0")
+	t.Setenv("HTTP_MAX_CONNECTIONS", "500")
+	c, err := loadHTTPConfig()
+	if err != nil {
+		t.Fatalf("loadHTTPConfig returned error: %v", err)
+	}
+	if c.ReadHeaderTimeout != 2*time.Second || c.WriteTimeout != 0 || c.MaxConnections != 500 {
+		t.Errorf("unexpected config: %+v", c)
+	}
+}
+
+func TestLoadHTTPConfig_Invalid(t *testing.T) {
+	for name, value := range map[string]string{
+		"HTTP_READ_TIMEOUT":     "soon",
+		"HTTP_IDLE_TIMEOUT":     "-1s",
+		"HTTP_MAX_HEADER_BYTES": "0",
+		"HTTP_MAX_CONNECTIONS":  "-5",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := loadHTTPConfig(); err == nil {
+				t.Errorf("expected an error for %s=%q", name, value)
+			}
+		})
+	}
+}
+
+func TestLoadBreakerConfig(t *testing.T) {
+	c, err := loadBreakerConfig()
+	if err != nil {
+		t.Fatalf("loadBreakerConfig returned error: %v", err)
+	}
+	if want := (BreakerConfig{Threshold: 5, Cooldown: 10 * time.Second}); c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+
+	t.Setenv("DB_BREAKER_THRESHOLD", "0")
+	t.Setenv("DB_BREAKER_COOLDOWN", "1m")
+	if c, err = loadBreakerConfig(); err != nil {
+		t.Fatalf("loadBreakerConfig returned error: %v", err)
+	}
+	if want := (BreakerConfig{Threshold: 0, Cooldown: time.Minute}); c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+
+	t.Setenv("DB_BREAKER_THRESHOLD", "-1")
+	if _, err := loadBreakerConfig(); err == nil {
+		t.Error("expected an error for a negative threshold")
+	}
+}
+
+func TestLoadRetentionConfig(t *testing.T) {
+	t.Setenv("RETENTION_EMAILS", "0")
+	t.Setenv("RETENTION_ANALYTICS_EVENTS", "8760h")
+	t.Setenv("RETENTION_DRY_RUN", "true")
+	c, err := loadRetentionConfig()
+	if err != nil {
+		t.Fatalf("loadRetentionConfig returned error: %v", err)
+	}
+	want := RetentionConfig{
+		DeletedChirps:   30 * 24 * time.Hour,
+		OutboxEvents:    7 * 24 * time.Hour,
+		IPActivity:      90 * 24 * time.Hour,
+		AnalyticsEvents: 365 * 24 * time.Hour,
+		RequestCaptures: 7 * 24 * time.Hour,
+		DryRun:          true,
+	}
+	if c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+
+	t.Setenv("RETENTION_DELETED_CHIRPS", "30d")
+	if _, err := loadRetentionConfig(); err == nil {
+		t.Error("expected an error for a duration Go can't parse")
+	}
+}
+
+func TestLoadSchedules(t *testing.T) {
+	t.Setenv("SCHEDULE_DIGESTS", "0 8 * * *")
+	t.Setenv("SCHEDULE_SITEMAPS", "off")
+	c, err := loadSchedules()
+	if err != nil {
+		t.Fatalf("loadSchedules returned error: %v", err)
+	}
+	if c.Digests != "0 8 * * *" || c.Sitemaps != "off" || c.Retention != "@every 1h" || c.Jitter != 30*time.Second {
+		t.Errorf("unexpected schedules: %+v", c)
+	}
+
+	t.Setenv("SCHEDULE_RETENTION", "every hour")
+	if _, err := loadSchedules(); err == nil {
+		t.Error("expected an error for a malformed schedule")
+	}
+}
+
+func TestUTCSession(t *testing.T) {
+	tests := map[string]string{
+		"": "",
+		"postgres://chirpy@localhost:5432/chirpy?sslmode=disable": "postgres://chirpy@localhost:5432/chirpy?sslmode=disable&timezone=UTC",
+		"postgresql://localhost/chirpy":                           "postgresql://localhost/chirpy?timezone=UTC",
+		"postgres://localhost/chirpy?timezone=Europe%2FParis":     "postgres://localhost/chirpy?timezone=Europe%2FParis",
+		"host=localhost dbname=chirpy sslmode=disable":            "host=localhost dbname=chirpy sslmode=disable timezone=UTC",
+		"host=localhost timezone=America/New_York":                "host=localhost timezone=America/New_York",
+	}
+	for dsn, want := range tests {
+		if got := utcSession(dsn); got != want {
+			t.Errorf("utcSession(%q) = %q, want %q", dsn, got, want)
+		}
+	}
+}
+
+func TestParseReactions(t *testing.T) {
+	if got, err := parseReactions(""); err != nil || !slices.Equal(got, DefaultReactions) {
+		t.Errorf("expected the default reactions, got %v, %v", got, err)
+	}
+	got, err := parseReactions(" 👍 ,🔥,,👍")
+	if err != nil || !slices.Equal(got, []string{"👍", "🔥"}) {
+		t.Errorf("expected trimmed, deduplicated reactions, got %v, %v", got, err)
+	}
+	if _, err := parseReactions(strings.Repeat("x", maxReactionLength+1)); err == nil {
+		t.Error("expected an error for an overlong reaction")
+	}
+}
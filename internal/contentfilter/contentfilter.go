@@ -0,0 +1,130 @@
+package contentfilter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type Kind string
+
+const (
+	// KindWord matches a whole word, ignoring case.
+	KindWord Kind = "word"
+	// KindRegex matches a Go regular expression as written.
+	KindRegex Kind = "regex"
+	// KindDomain matches a domain and any of its subdomains.
+	KindDomain Kind = "domain"
+)
+
+type Action string
+
+const (
+	ActionReject Action = "reject"
+	ActionMask   Action = "mask"
+	ActionFlag   Action = "flag"
+)
+
+const mask = "****"
+
+type Rule struct {
+	ID      uuid.UUID
+	Kind    Kind
+	Pattern string
+	Action  Action
+}
+
+type compiledRule struct {
+	Rule
+	re *regexp.Regexp
+}
+
+// Filter applies a fixed set of rules. It is immutable once built, so a
+// single instance can be shared by concurrent requests and swapped out
+// wholesale when rules change.
+type Filter struct {
+	rules []compiledRule
+}
+
+// Result describes what the filter did to a chirp body.
+type Result struct {
+	Body     string
+	Rejected bool
+	Flagged  []Rule
+}
+
+func compile(r Rule) (*regexp.Regexp, error) {
+	if strings.TrimSpace(r.Pattern) == "" {
+		return nil, errors.New("pattern must not be empty")
+	}
+
+	switch r.Kind {
+	case KindWord:
+		return regexp.Compile(`(?i)\b` + regexp.QuoteMeta(r.Pattern) + `\b`)
+	case KindRegex:
+		return regexp.Compile(r.Pattern)
+	case KindDomain:
+		domain := strings.TrimPrefix(strings.ToLower(r.Pattern), ".")
+		return regexp.Compile(`(?i)\b(?:[a-z0-9-]+\.)*` + regexp.QuoteMeta(domain) + `\b`)
+	default:
+		return nil, fmt.Errorf("unknown rule kind %q", r.Kind)
+	}
+}
+
+// Validate reports whether r can be compiled and has a known action.
+func Validate(r Rule) error {
+	switch r.Action {
+	case ActionReject, ActionMask, ActionFlag:
+	default:
+		return fmt.Errorf("unknown rule action %q", r.Action)
+	}
+	_, err := compile(r)
+	return err
+}
+
+// New builds a filter from rules. Rules that fail validation are skipped and
+// returned alongside the filter.
+func New(rules []Rule) (*Filter, []Rule) {
+	f := &Filter{}
+	var invalid []Rule
+	for _, r := range rules {
+		if err := Validate(r); err != nil {
+			invalid = append(invalid, r)
+			continue
+		}
+		re, _ := compile(r)
+		f.rules = append(f.rules, compiledRule{Rule: r, re: re})
+	}
+	return f, invalid
+}
+
+// Apply runs every rule over body. Any matching reject rule rejects the body
+// outright; otherwise mask rules are applied and flag rules are reported.
+// A nil filter leaves the body untouched.
+func (f *Filter) Apply(body string) Result {
+	res := Result{Body: body}
+	if f == nil {
+		return res
+	}
+
+	for _, r := range f.rules {
+		if r.Action == ActionReject && r.re.MatchString(body) {
+			return Result{Body: body, Rejected: true}
+		}
+	}
+
+	for _, r := range f.rules {
+		switch r.Action {
+		case ActionMask:
+			res.Body = r.re.ReplaceAllString(res.Body, mask)
+		case ActionFlag:
+			if r.re.MatchString(body) {
+				res.Flagged = append(res.Flagged, r.Rule)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,72 @@
+package contentfilter
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFilter_Apply(t *testing.T) {
+	f, invalid := New([]Rule{
+		{ID: uuid.New(), Kind: KindWord, Pattern: "darn", Action: ActionMask},
+		{ID: uuid.New(), Kind: KindDomain, Pattern: "spam.example", Action: ActionReject},
+		{ID: uuid.New(), Kind: KindRegex, Pattern: `\d{3}-\d{4}`, Action: ActionFlag},
+	})
+	if len(invalid) != 0 {
+		t.Fatalf("unexpected invalid rules: %v", invalid)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantBody string
+		reject   bool
+		flags    int
+	}{
+		{name: "clean", body: "hello world", wantBody: "hello world"},
+		{name: "mask word", body: "Darn it, darn!", wantBody: "**** it, ****!"},
+		{name: "no partial word", body: "darning socks", wantBody: "darning socks"},
+		{name: "reject domain", body: "visit https://spam.example/x", reject: true},
+		{name: "reject subdomain", body: "see www.SPAM.example", reject: true},
+		{name: "other domain", body: "notspam.example.org is fine", wantBody: "notspam.example.org is fine"},
+		{name: "flag regex", body: "call 555-1234", wantBody: "call 555-1234", flags: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res := f.Apply(tc.body)
+			if res.Rejected != tc.reject {
+				t.Fatalf("Rejected = %v, want %v", res.Rejected, tc.reject)
+			}
+			if tc.reject {
+				return
+			}
+			if res.Body != tc.wantBody {
+				t.Fatalf("Body = %q, want %q", res.Body, tc.wantBody)
+			}
+			if len(res.Flagged) != tc.flags {
+				t.Fatalf("got %d flags, want %d", len(res.Flagged), tc.flags)
+			}
+		})
+	}
+}
+
+func TestNew_SkipsInvalidRules(t *testing.T) {
+	_, invalid := New([]Rule{
+		{Kind: KindRegex, Pattern: "(unclosed", Action: ActionFlag},
+		{Kind: "phrase", Pattern: "x", Action: ActionFlag},
+		{Kind: KindWord, Pattern: "x", Action: "delete"},
+		{Kind: KindWord, Pattern: " ", Action: ActionMask},
+		{Kind: KindWord, Pattern: "ok", Action: ActionMask},
+	})
+	if len(invalid) != 4 {
+		t.Fatalf("expected 4 invalid rules, got %d", len(invalid))
+	}
+}
+
+func TestFilter_NilIsNoop(t *testing.T) {
+	var f *Filter
+	if res := f.Apply("anything"); res.Body != "anything" || res.Rejected {
+		t.Fatalf("nil filter changed the body: %+v", res)
+	}
+}
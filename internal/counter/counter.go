@@ -0,0 +1,115 @@
+// Package counter keeps counts, such as page hits, that every server adds
+// to. A Shared counter buffers increments in memory and adds them to a
+// database row from Run, so counting never waits on the database.
+package counter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"chirpy/internal/database"
+)
+
+// flushTimeout bounds a single write to the store.
+const flushTimeout = 10 * time.Second
+
+// Counter is a count that only goes up until it is reset.
+type Counter interface {
+	// Add must not block.
+	Add(n int64)
+	Value(ctx context.Context) (int64, error)
+	Reset(ctx context.Context) error
+}
+
+// Local counts in this process only, for a single server.
+type Local struct {
+	n atomic.Int64
+}
+
+func (c *Local) Add(n int64) {
+	c.n.Add(n)
+}
+
+func (c *Local) Value(ctx context.Context) (int64, error) {
+	return c.n.Load(), nil
+}
+
+func (c *Local) Reset(ctx context.Context) error {
+	c.n.Store(0)
+	return nil
+}
+
+// Store is where shared counts are kept.
+type Store interface {
+	AddSharedCounter(ctx context.Context, arg database.AddSharedCounterParams) error
+	GetSharedCounter(ctx context.Context, name string) (int64, error)
+	ResetSharedCounter(ctx context.Context, name string) error
+}
+
+// Shared is a Counter kept in a Store under name.
+type Shared struct {
+	store   Store
+	name    string
+	pending atomic.Int64
+}
+
+func NewShared(store Store, name string) *Shared {
+	return &Shared{store: store, name: name}
+}
+
+func (c *Shared) Add(n int64) {
+	c.pending.Add(n)
+}
+
+// Value is the stored count plus what this server hasn't flushed yet.
+// Other servers' increments show up once they flush.
+func (c *Shared) Value(ctx context.Context) (int64, error) {
+	n, err := c.store.GetSharedCounter(ctx, c.name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	return n + c.pending.Load(), nil
+}
+
+// Reset zeroes the count. Increments other servers haven't flushed yet
+// still land afterwards.
+func (c *Shared) Reset(ctx context.Context) error {
+	c.pending.Store(0)
+	return c.store.ResetSharedCounter(ctx, c.name)
+}
+
+// Run flushes pending increments to the store every interval. When ctx is
+// done it flushes once more and returns.
+func (c *Shared) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.flush(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+			c.flush(ctx)
+		}
+	}
+}
+
+func (c *Shared) flush(ctx context.Context) {
+	n := c.pending.Swap(0)
+	if n == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, flushTimeout)
+	defer cancel()
+	err := c.store.AddSharedCounter(ctx, database.AddSharedCounterParams{Name: c.name, Value: n})
+	if err != nil {
+		// Keep the increments for the next flush.
+		c.pending.Add(n)
+		fmt.Printf("Error flushing counter %s: %v\n", c.name, err)
+	}
+}
@@ -0,0 +1,100 @@
+package counter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"chirpy/internal/database"
+)
+
+type memStore struct {
+	mu     sync.Mutex
+	values map[string]int64
+	err    error
+}
+
+func (s *memStore) AddSharedCounter(ctx context.Context, arg database.AddSharedCounterParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.values[arg.Name] += arg.Value
+	return nil
+}
+
+func (s *memStore) GetSharedCounter(ctx context.Context, name string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.values[name]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return n, nil
+}
+
+func (s *memStore) ResetSharedCounter(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, name)
+	return nil
+}
+
+func TestShared(t *testing.T) {
+	ctx := context.Background()
+	store := &memStore{values: make(map[string]int64)}
+	a, b := NewShared(store, "hits"), NewShared(store, "hits")
+
+	a.Add(2)
+	b.Add(3)
+	if n, err := a.Value(ctx); err != nil || n != 2 {
+		t.Errorf("before flushing: got %d, %v; want only this server's 2", n, err)
+	}
+
+	a.flush(ctx)
+	b.flush(ctx)
+	for _, c := range []*Shared{a, b} {
+		if n, _ := c.Value(ctx); n != 5 {
+			t.Errorf("after flushing: got %d, want 5", n)
+		}
+	}
+
+	store.err = errors.New("database is down")
+	a.Add(1)
+	a.flush(ctx)
+	store.err = nil
+	a.flush(ctx)
+	if n, _ := b.Value(ctx); n != 6 {
+		t.Errorf("expected a failed flush to be retried, got %d", n)
+	}
+
+	if err := a.Reset(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := b.Value(ctx); n != 0 {
+		t.Errorf("after reset: got %d", n)
+	}
+}
+
+func TestShared_RunFlushesOnShutdown(t *testing.T) {
+	store := &memStore{values: make(map[string]int64)}
+	c := NewShared(store, "hits")
+	c.Add(4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx, time.Hour)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	if store.values["hits"] != 4 {
+		t.Errorf("expected pending hits to be flushed on shutdown, got %d", store.values["hits"])
+	}
+}
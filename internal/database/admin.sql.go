@@ -9,6 +9,24 @@ import (
 	"context"
 )
 
+const deleteAllChirps = `-- name: DeleteAllChirps :exec
+DELETE FROM chirps
+`
+
+func (q *Queries) DeleteAllChirps(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllChirps)
+	return err
+}
+
+const deleteAllIPActivity = `-- name: DeleteAllIPActivity :exec
+DELETE FROM ip_activity
+`
+
+func (q *Queries) DeleteAllIPActivity(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteAllIPActivity)
+	return err
+}
+
 const deleteAllUsers = `-- name: DeleteAllUsers :exec
 DELETE FROM users
 `
@@ -7,10 +7,51 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countChirpsByUsers = `-- name: CountChirpsByUsers :many
+SELECT
+  user_id,
+  COUNT(*) AS chirp_count
+FROM chirps
+WHERE user_id = ANY($1::uuid[])
+  AND deleted_at IS NULL
+GROUP BY user_id
+`
+
+type CountChirpsByUsersRow struct {
+	UserID     uuid.UUID
+	ChirpCount int64
+}
+
+func (q *Queries) CountChirpsByUsers(ctx context.Context, userIds []uuid.UUID) ([]CountChirpsByUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, countChirpsByUsers, pq.Array(userIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountChirpsByUsersRow
+	for rows.Next() {
+		var i CountChirpsByUsersRow
+		if err := rows.Scan(&i.UserID, &i.ChirpCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createChirp = `-- name: CreateChirp :one
 INSERT INTO chirps(id, created_at, updated_at, body, user_id)
 VALUES(
@@ -20,7 +61,7 @@ VALUES(
   $2,
   $3
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, tenant_id
 `
 
 type CreateChirpParams struct {
@@ -38,19 +79,84 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.DeletedAt,
+		&i.TenantID,
 	)
 	return i, err
 }
 
+const createChirps = `-- name: CreateChirps :many
+INSERT INTO chirps(id, created_at, updated_at, body, user_id)
+SELECT
+  unnest($1::uuid[]),
+  NOW(),
+  NOW(),
+  unnest($2::text[]),
+  $3
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, tenant_id
+`
+
+type CreateChirpsParams struct {
+	Ids    []uuid.UUID
+	Bodies []string
+	UserID uuid.UUID
+}
+
+// Inserts one chirp per element of ids and bodies, which must be the same
+// length, in a single statement.
+func (q *Queries) CreateChirps(ctx context.Context, arg CreateChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, createChirps, pq.Array(arg.Ids), pq.Array(arg.Bodies), arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteChirp = `-- name: DeleteChirp :exec
+DELETE FROM chirps
+WHERE id = $1
+`
+
+func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteChirp, id)
+	return err
+}
+
 const getChirp = `-- name: GetChirp :one
 SELECT
   id,
   created_at,
   updated_at,
   body,
-  user_id
+  user_id,
+  deleted_at,
+  tenant_id
 FROM chirps
 WHERE id = $1
+  AND deleted_at IS NULL
 `
 
 func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -62,23 +168,285 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.DeletedAt,
+		&i.TenantID,
 	)
 	return i, err
 }
 
 const getChirps = `-- name: GetChirps :many
+SELECT
+  chirps.id,
+  chirps.created_at,
+  chirps.updated_at,
+  chirps.body,
+  chirps.user_id,
+  chirps.deleted_at,
+  users.verified AS author_verified,
+  chirp_content_warnings.chirp_id IS NOT NULL AS sensitive,
+  COALESCE(chirp_content_warnings.warning, '') AS content_warning
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_content_warnings ON chirp_content_warnings.chirp_id = chirps.id
+WHERE chirps.deleted_at IS NULL
+  AND users.banned_at IS NULL
+  AND (NOT users.shadowbanned OR chirps.user_id = $1)
+  AND (
+    chirp_content_warnings.chirp_id IS NULL
+    OR chirps.user_id = $1
+    OR NOT EXISTS (
+      SELECT 1
+      FROM content_preferences
+      WHERE content_preferences.user_id = $1
+        AND content_preferences.sensitive_content = 'hide'
+    )
+  )
+ORDER BY chirps.created_at ASC
+`
+
+type GetChirpsRow struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	DeletedAt      sql.NullTime
+	AuthorVerified bool
+	Sensitive      bool
+	ContentWarning string
+}
+
+func (q *Queries) GetChirps(ctx context.Context, viewerID uuid.UUID) ([]GetChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirps, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpsRow
+	for rows.Next() {
+		var i GetChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.AuthorVerified,
+			&i.Sensitive,
+			&i.ContentWarning,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVisibleChirp = `-- name: GetVisibleChirp :one
+SELECT
+  chirps.id,
+  chirps.created_at,
+  chirps.updated_at,
+  chirps.body,
+  chirps.user_id,
+  chirps.deleted_at,
+  users.verified AS author_verified,
+  chirp_content_warnings.chirp_id IS NOT NULL AS sensitive,
+  COALESCE(chirp_content_warnings.warning, '') AS content_warning
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_content_warnings ON chirp_content_warnings.chirp_id = chirps.id
+WHERE chirps.id = $1
+  AND chirps.deleted_at IS NULL
+  AND users.banned_at IS NULL
+  AND (NOT users.shadowbanned OR chirps.user_id = $2)
+`
+
+type GetVisibleChirpParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.UUID
+}
+
+type GetVisibleChirpRow struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	DeletedAt      sql.NullTime
+	AuthorVerified bool
+	Sensitive      bool
+	ContentWarning string
+}
+
+func (q *Queries) GetVisibleChirp(ctx context.Context, arg GetVisibleChirpParams) (GetVisibleChirpRow, error) {
+	row := q.db.QueryRowContext(ctx, getVisibleChirp, arg.ID, arg.ViewerID)
+	var i GetVisibleChirpRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.DeletedAt,
+		&i.AuthorVerified,
+		&i.Sensitive,
+		&i.ContentWarning,
+	)
+	return i, err
+}
+
+const listRecentChirps = `-- name: ListRecentChirps :many
+SELECT
+  chirps.id,
+  chirps.created_at,
+  chirps.updated_at,
+  chirps.body,
+  chirps.user_id,
+  chirps.deleted_at,
+  users.verified AS author_verified,
+  chirp_content_warnings.chirp_id IS NOT NULL AS sensitive,
+  COALESCE(chirp_content_warnings.warning, '') AS content_warning
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_content_warnings ON chirp_content_warnings.chirp_id = chirps.id
+WHERE chirps.deleted_at IS NULL
+  AND users.banned_at IS NULL
+  AND (NOT users.shadowbanned OR chirps.user_id = $1)
+  AND (
+    chirp_content_warnings.chirp_id IS NULL
+    OR chirps.user_id = $1
+    OR NOT EXISTS (
+      SELECT 1
+      FROM content_preferences
+      WHERE content_preferences.user_id = $1
+        AND content_preferences.sensitive_content = 'hide'
+    )
+  )
+ORDER BY chirps.created_at DESC
+LIMIT $2
+`
+
+type ListRecentChirpsParams struct {
+	ViewerID uuid.UUID
+	RowLimit int32
+}
+
+type ListRecentChirpsRow struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	DeletedAt      sql.NullTime
+	AuthorVerified bool
+	Sensitive      bool
+	ContentWarning string
+}
+
+func (q *Queries) ListRecentChirps(ctx context.Context, arg ListRecentChirpsParams) ([]ListRecentChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listRecentChirps, arg.ViewerID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRecentChirpsRow
+	for rows.Next() {
+		var i ListRecentChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.AuthorVerified,
+			&i.Sensitive,
+			&i.ContentWarning,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSitemapChirps = `-- name: ListSitemapChirps :many
+SELECT
+  chirps.id,
+  chirps.updated_at
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.deleted_at IS NULL
+  AND users.banned_at IS NULL
+  AND NOT users.shadowbanned
+ORDER BY chirps.created_at ASC
+`
+
+type ListSitemapChirpsRow struct {
+	ID        uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) ListSitemapChirps(ctx context.Context) ([]ListSitemapChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSitemapChirps)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSitemapChirpsRow
+	for rows.Next() {
+		var i ListSitemapChirpsRow
+		if err := rows.Scan(&i.ID, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserChirps = `-- name: ListUserChirps :many
 SELECT
   id,
   created_at,
   updated_at,
   body,
-  user_id
+  user_id,
+  deleted_at,
+  tenant_id
 FROM chirps
-ORDER BY created_at ASC
+WHERE user_id = $1
+  AND deleted_at IS NULL
+ORDER BY created_at DESC
+LIMIT $2
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type ListUserChirpsParams struct {
+	UserID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) ListUserChirps(ctx context.Context, arg ListUserChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listUserChirps, arg.UserID, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +460,8 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.DeletedAt,
+			&i.TenantID,
 		); err != nil {
 			return nil, err
 		}
@@ -105,3 +475,206 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	}
 	return items, nil
 }
+
+const listUserChirpsAfter = `-- name: ListUserChirpsAfter :many
+SELECT
+  id,
+  created_at,
+  updated_at,
+  body,
+  user_id,
+  deleted_at,
+  tenant_id
+FROM chirps
+WHERE user_id = $1
+  AND deleted_at IS NULL
+  AND (created_at, id) > ($2::timestamp, $3::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT $4
+`
+
+type ListUserChirpsAfterParams struct {
+	UserID         uuid.UUID
+	AfterCreatedAt time.Time
+	AfterID        uuid.UUID
+	RowLimit       int32
+}
+
+func (q *Queries) ListUserChirpsAfter(ctx context.Context, arg ListUserChirpsAfterParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listUserChirpsAfter,
+		arg.UserID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVisibleUserChirps = `-- name: ListVisibleUserChirps :many
+SELECT
+  chirps.id,
+  chirps.created_at,
+  chirps.updated_at,
+  chirps.body,
+  chirps.user_id,
+  chirps.deleted_at,
+  users.verified AS author_verified,
+  (chirp_content_warnings.chirp_id IS NOT NULL)::boolean AS sensitive,
+  COALESCE(chirp_content_warnings.warning, '') AS content_warning
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_content_warnings ON chirp_content_warnings.chirp_id = chirps.id
+WHERE chirps.user_id = $1
+  AND chirps.deleted_at IS NULL
+  AND users.banned_at IS NULL
+  AND (NOT users.shadowbanned OR chirps.user_id = $2)
+  AND (
+    chirp_content_warnings.chirp_id IS NULL
+    OR chirps.user_id = $2
+    OR NOT EXISTS (
+      SELECT 1
+      FROM content_preferences
+      WHERE content_preferences.user_id = $2
+        AND content_preferences.sensitive_content = 'hide'
+    )
+  )
+ORDER BY chirps.created_at DESC
+LIMIT $3
+`
+
+type ListVisibleUserChirpsParams struct {
+	UserID   uuid.UUID
+	ViewerID uuid.UUID
+	RowLimit int32
+}
+
+type ListVisibleUserChirpsRow struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Body           string
+	UserID         uuid.UUID
+	DeletedAt      sql.NullTime
+	AuthorVerified bool
+	Sensitive      bool
+	ContentWarning string
+}
+
+// A user's chirps, newest first, as viewer may see them: nothing from
+// banned users, nothing from shadowbanned ones but to themselves, and no
+// sensitive chirps for viewers who hide them.
+func (q *Queries) ListVisibleUserChirps(ctx context.Context, arg ListVisibleUserChirpsParams) ([]ListVisibleUserChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listVisibleUserChirps, arg.UserID, arg.ViewerID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListVisibleUserChirpsRow
+	for rows.Next() {
+		var i ListVisibleUserChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.AuthorVerified,
+			&i.Sensitive,
+			&i.ContentWarning,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const softDeleteUserChirpsBatch = `-- name: SoftDeleteUserChirpsBatch :execrows
+UPDATE chirps
+SET deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id IN (
+  SELECT id
+  FROM chirps
+  WHERE user_id = $1
+    AND deleted_at IS NULL
+  LIMIT $2
+)
+`
+
+type SoftDeleteUserChirpsBatchParams struct {
+	UserID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) SoftDeleteUserChirpsBatch(ctx context.Context, arg SoftDeleteUserChirpsBatchParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeleteUserChirpsBatch, arg.UserID, arg.Limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2,
+    updated_at = NOW()
+WHERE id = $1
+  AND user_id = $3
+  AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, body, user_id, deleted_at, tenant_id
+`
+
+type UpdateChirpBodyParams struct {
+	ID     uuid.UUID
+	Body   string
+	UserID uuid.UUID
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body, arg.UserID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.DeletedAt,
+		&i.TenantID,
+	)
+	return i, err
+}
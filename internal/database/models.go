@@ -5,23 +5,496 @@
 package database
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AccountMerge struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	ExpiresAt         time.Time
+	RequestedBy       uuid.NullUUID
+	SourceID          uuid.UUID
+	TargetID          uuid.UUID
+	SourceConfirmedAt sql.NullTime
+	TargetConfirmedAt sql.NullTime
+	CompletedAt       sql.NullTime
+}
+
+type ActorKey struct {
+	UserID        uuid.UUID
+	CreatedAt     time.Time
+	PublicKeyPem  string
+	PrivateKeyPem string
+}
+
+type AnalyticsEvent struct {
+	ID         uuid.UUID
+	Name       string
+	UserID     uuid.NullUUID
+	Properties json.RawMessage
+	OccurredAt time.Time
+}
+
+type AuditLog struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ActorID   uuid.UUID
+	Action    string
+	TargetID  uuid.NullUUID
+	Details   json.RawMessage
+	TenantID  uuid.NullUUID
+}
+
+type BillingCustomer struct {
+	UserID           uuid.UUID
+	StripeCustomerID string
+	CreatedAt        time.Time
+}
+
+type BillingSubscription struct {
+	// Stripe's subscription ID.
+	ID                string
+	UserID            uuid.UUID
+	StripeCustomerID  string
+	Status            string
+	PriceID           string
+	CancelAtPeriodEnd bool
+	CurrentPeriodEnd  sql.NullTime
+	TrialEnd          sql.NullTime
+	CanceledAt        sql.NullTime
+	// When Stripe created the subscription.
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// When the last event applied was sent. Stripe doesn't deliver in
+	// order, so older events arriving late are ignored.
+	EventCreatedAt time.Time
+	EndedAt        sql.NullTime
+	MonthlyAmount  int64
+}
+
+type CaptureRule struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	CreatedBy   uuid.NullUUID
+	Route       sql.NullString
+	UserID      uuid.NullUUID
+	ExpiresAt   time.Time
+	MaxCaptures int32
+}
+
+type ChirpContentWarning struct {
+	ChirpID uuid.UUID
+	Warning string
+}
+
+type ChirpLinkClick struct {
+	ChirpID       uuid.UUID
+	Referrer      string
+	Clicks        int64
+	LastClickedAt time.Time
+}
+
+type ChirpLink struct {
+	ChirpID uuid.UUID
+	Url     string
+}
+
+type ChirpLocation struct {
+	ChirpID   uuid.UUID
+	Latitude  float64
+	Longitude float64
+}
+
 type Chirp struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Body      string
 	UserID    uuid.UUID
+	DeletedAt sql.NullTime
+	TenantID  uuid.NullUUID
+}
+
+type Community struct {
+	ID          uuid.UUID
+	Slug        string
+	Name        string
+	Description string
+	CreatedBy   uuid.NullUUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	TenantID    uuid.NullUUID
+}
+
+type CommunityChirp struct {
+	ChirpID     uuid.UUID
+	CommunityID uuid.UUID
+}
+
+type CommunityMember struct {
+	CommunityID uuid.UUID
+	UserID      uuid.UUID
+	// member, moderator or owner.
+	Role     string
+	JoinedAt time.Time
+}
+
+type ContentFlag struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ChirpID   uuid.UUID
+	RuleID    uuid.NullUUID
+	Pattern   string
+}
+
+type ContentPreference struct {
+	UserID uuid.UUID
+	// expand, collapse or hide.
+	SensitiveContent string
+	UpdatedAt        time.Time
+}
+
+type ContentRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Kind      string
+	Pattern   string
+	Action    string
+	CreatedBy uuid.NullUUID
+}
+
+type DeadLetter struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Source    string
+	SourceID  string
+	Summary   string
+	Attempts  int32
+	LastError string
+	RetriedAt sql.NullTime
+}
+
+type DigestPreference struct {
+	UserID     uuid.UUID
+	Frequency  string
+	LastSentAt sql.NullTime
+	UpdatedAt  time.Time
+}
+
+type Email struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Template      string
+	ToAddress     string
+	Subject       string
+	TextBody      string
+	HtmlBody      string
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     string
+	SentAt        sql.NullTime
+}
+
+type HandleHistory struct {
+	Handle    string
+	UserID    uuid.UUID
+	ChangedAt time.Time
+	ExpiresAt time.Time
+}
+
+type Hashtag struct {
+	Tag        string
+	ChirpCount int32
+	LastUsedAt time.Time
+}
+
+type ImportJob struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Source    string
+	Status    string
+	Total     int32
+	Imported  int32
+	Skipped   int32
+	Error     sql.NullString
+}
+
+type ImportedTweet struct {
+	UserID  uuid.UUID
+	TweetID string
+	ChirpID uuid.UUID
+}
+
+type IpActivity struct {
+	Ip            string
+	Signups       int32
+	LoginFailures int32
+	FirstSeenAt   time.Time
+	LastSeenAt    time.Time
+}
+
+type IpBlock struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Cidr      string
+	Reason    string
+	CreatedBy uuid.NullUUID
+}
+
+type LegalAcceptance struct {
+	UserID     uuid.UUID
+	Kind       string
+	Version    int32
+	AcceptedAt time.Time
+	Ip         sql.NullString
+}
+
+type LegalDocument struct {
+	Kind        string
+	Version     int32
+	Body        string
+	PublishedAt time.Time
+	PublishedBy uuid.NullUUID
+}
+
+type LinkScan struct {
+	Url       string
+	CreatedAt time.Time
+	CheckedAt sql.NullTime
+	Threat    string
+}
+
+type List struct {
+	ID          uuid.UUID
+	OwnerID     uuid.UUID
+	Name        string
+	Description string
+	Private     bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	TenantID    uuid.NullUUID
+}
+
+type ListMember struct {
+	ListID  uuid.UUID
+	UserID  uuid.UUID
+	AddedAt time.Time
+}
+
+type LocationPreference struct {
+	UserID        uuid.UUID
+	ShareLocation bool
+	UpdatedAt     time.Time
+}
+
+type Medium struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UserID      uuid.UUID
+	ContentType string
+	Size        int64
+	DurationMs  sql.NullInt32
+	ChirpID     uuid.NullUUID
+	Position    int32
+	AltText     string
+	Width       sql.NullInt32
+	Height      sql.NullInt32
+	Variants    json.RawMessage
+	TenantID    uuid.NullUUID
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash    string
+	ClientID    uuid.UUID
+	UserID      uuid.UUID
+	RedirectUri string
+	Scopes      []string
+	// The S256 PKCE challenge the token request's verifier must match.
+	CodeChallenge string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	UsedAt        sql.NullTime
+}
+
+type OauthClient struct {
+	ID   uuid.UUID
+	Name string
+	// Where codes may be sent; a request's redirect_uri must match one
+	// exactly.
+	RedirectUris []string
+	// The most the app may ask for.
+	Scopes    []string
+	CreatedAt time.Time
+	OwnerID   uuid.NullUUID
+	// An app's own daily request quota, overriding APP_DAILY_QUOTA.
+	DailyQuota sql.NullInt32
+}
+
+type OauthClientSecret struct {
+	ID         uuid.UUID
+	ClientID   uuid.UUID
+	SecretHash string
+	CreatedAt  time.Time
+	// NULL until the secret is rotated out.
+	ExpiresAt sql.NullTime
+}
+
+// Requests made with each app's tokens, per UTC day. Requests turned away
+// for being over quota are counted as rejected instead.
+type OauthClientUsage struct {
+	ClientID uuid.UUID
+	Day      time.Time
+	Requests int64
+	Rejected int64
+}
+
+type OauthToken struct {
+	ID        uuid.UUID
+	ClientID  uuid.UUID
+	UserID    uuid.UUID
+	Scopes    []string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type Organization struct {
+	UserID    uuid.UUID
+	Name      string
+	CreatedBy uuid.NullUUID
+	CreatedAt time.Time
+}
+
+type OrganizationChirp struct {
+	ChirpID  uuid.UUID
+	OrgID    uuid.UUID
+	PostedBy uuid.NullUUID
+}
+
+type OrganizationInvite struct {
+	ID        uuid.UUID
+	OrgID     uuid.UUID
+	UserID    uuid.UUID
+	Role      string
+	InvitedBy uuid.NullUUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type OrganizationMember struct {
+	OrgID    uuid.UUID
+	UserID   uuid.UUID
+	Role     string
+	JoinedAt time.Time
+}
+
+type OutboxEvent struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Kind          string
+	Payload       json.RawMessage
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     string
+	SentAt        sql.NullTime
+}
+
+type Reaction struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	Emoji     string
+	CreatedAt time.Time
+}
+
+type RemoteFollower struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	ActorUri  string
+	InboxUri  string
+}
+
+type RequestCapture struct {
+	ID                    uuid.UUID
+	CreatedAt             time.Time
+	RuleID                uuid.UUID
+	UserID                uuid.NullUUID
+	Method                string
+	Url                   string
+	Route                 string
+	RequestHeaders        json.RawMessage
+	RequestBody           []byte
+	RequestBodyTruncated  bool
+	Status                int32
+	ResponseHeaders       json.RawMessage
+	ResponseBody          []byte
+	ResponseBodyTruncated bool
+	DurationMs            int32
+}
+
+type SharedCounter struct {
+	Name      string
+	Value     int64
+	UpdatedAt time.Time
+}
+
+type StripeEvent struct {
+	ID         string
+	Type       string
+	ReceivedAt time.Time
+}
+
+type Tenant struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Slug      string
+	Hostname  sql.NullString
+	Name      string
+	Config    json.RawMessage
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Email            string
+	HashedPassword   string
+	Role             string
+	BannedAt         sql.NullTime
+	SuspendedUntil   sql.NullTime
+	ModerationReason string
+	Shadowbanned     bool
+	Verified         bool
+	Handle           sql.NullString
+	IsChirpyRed      bool
+	TenantID         uuid.NullUUID
+}
+
+type UserPreference struct {
+	UserID           uuid.UUID
+	DmPermission     string
+	EmailNewFollower bool
+	Discoverable     bool
+	UpdatedAt        time.Time
+}
+
+type UserSuggestion struct {
+	UserID          uuid.UUID
+	SuggestedUserID uuid.UUID
+	// How many of the accounts user_id lists also list the suggestion.
+	MutualCount int32
+	// How many hashtags both used recently.
+	SharedHashtagCount int32
+	ComputedAt         time.Time
 }
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// queryDriver stands in for Postgres well enough to run the generated
+// code: a statement takes as many arguments as its highest $n, and a query
+// returns one row with a made-up value for each column its SELECT or
+// RETURNING list names. Scanning that row catches destinations that don't
+// line up with the columns.
+type queryDriver struct{}
+
+func (queryDriver) Open(string) (driver.Conn, error) { return queryConn{}, nil }
+
+type queryConn struct{}
+
+func (queryConn) Prepare(query string) (driver.Stmt, error) { return queryStmt{query}, nil }
+func (queryConn) Close() error                              { return nil }
+func (queryConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no transactions") }
+
+type queryStmt struct {
+	query string
+}
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+func (s queryStmt) NumInput() int {
+	n := 0
+	for _, m := range placeholder.FindAllStringSubmatch(s.query, -1) {
+		i, _ := strconv.Atoi(m[1])
+		n = max(n, i)
+	}
+	return n
+}
+
+func (s queryStmt) Close() error { return nil }
+
+func (s queryStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (s queryStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &queryRows{columns: resultColumns(s.query)}, nil
+}
+
+// resultColumns names the columns of the first SELECT or RETURNING list.
+func resultColumns(query string) []string {
+	for _, line := range strings.Split(query, "\n") {
+		var list string
+		if rest, ok := strings.CutPrefix(line, "SELECT "); ok {
+			list, _, _ = strings.Cut(rest, " FROM ")
+		} else if rest, ok := strings.CutPrefix(line, "RETURNING "); ok {
+			list = rest
+		} else {
+			continue
+		}
+		var columns []string
+		depth, start := 0, 0
+		for i, c := range list + "," {
+			switch c {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			case ',':
+				if depth == 0 {
+					expr := strings.TrimSpace(list[start:min(i, len(list))])
+					if _, alias, ok := strings.Cut(expr, ") AS "); ok {
+						expr = alias
+					}
+					columns = append(columns, expr)
+					start = i + 1
+				}
+			}
+		}
+		return columns
+	}
+	return nil
+}
+
+type queryRows struct {
+	columns []string
+	done    bool
+}
+
+func (r *queryRows) Columns() []string { return r.columns }
+func (r *queryRows) Close() error      { return nil }
+
+func (r *queryRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	for i, column := range r.columns {
+		dest[i] = columnValue(column)
+	}
+	return nil
+}
+
+var (
+	testID  = uuid.MustParse("7b3e6c1e-4f7a-4c55-9d2b-2f0c5a8e9d10")
+	testNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func columnValue(column string) driver.Value {
+	switch {
+	case column == "id" || strings.HasSuffix(column, "_id"):
+		return testID.String()
+	case column == "day" || strings.HasSuffix(column, "_at"):
+		return testNow
+	case column == "scopes" || column == "redirect_uris":
+		return []byte("{read,write}")
+	case column == "daily_quota" || column == "requests" || column == "rejected":
+		return int64(7)
+	}
+	return column
+}
+
+func init() {
+	sql.Register("querytest", queryDriver{})
+}
+
+func newTestQueries(t *testing.T) *Queries {
+	t.Helper()
+	db, err := sql.Open("querytest", "")
+	if err != nil {
+		t.Fatalf("opening test driver: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return New(db)
+}
+
+func TestOAuthClientQueries(t *testing.T) {
+	q := newTestQueries(t)
+	ctx := context.Background()
+	want := OauthClient{
+		ID:           testID,
+		Name:         "name",
+		RedirectUris: []string{"read", "write"},
+		Scopes:       []string{"read", "write"},
+		CreatedAt:    testNow,
+		OwnerID:      uuid.NullUUID{UUID: testID, Valid: true},
+		DailyQuota:   sql.NullInt32{Int32: 7, Valid: true},
+	}
+	check := func(name string, got OauthClient, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s returned error: %v", name, err)
+		}
+		if got.ID != want.ID || got.Name != want.Name || len(got.Scopes) != 2 || !got.CreatedAt.Equal(want.CreatedAt) ||
+			got.OwnerID != want.OwnerID || got.DailyQuota != want.DailyQuota {
+			t.Fatalf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+
+	clients, err := q.ListOAuthClients(ctx)
+	if err != nil || len(clients) != 1 {
+		t.Fatalf("ListOAuthClients: got %d clients, %v", len(clients), err)
+	}
+	check("ListOAuthClients", clients[0], nil)
+	clients, err = q.ListOAuthClientsByOwner(ctx, want.OwnerID)
+	if err != nil || len(clients) != 1 {
+		t.Fatalf("ListOAuthClientsByOwner: got %d clients, %v", len(clients), err)
+	}
+	check("ListOAuthClientsByOwner", clients[0], nil)
+
+	client, err := q.GetOAuthClient(ctx, testID)
+	check("GetOAuthClient", client, err)
+	client, err = q.CreateOAuthClient(ctx, CreateOAuthClientParams{
+		ID:           testID,
+		Name:         "name",
+		RedirectUris: want.RedirectUris,
+		Scopes:       want.Scopes,
+		OwnerID:      want.OwnerID,
+	})
+	check("CreateOAuthClient", client, err)
+	client, err = q.SetOAuthClientQuota(ctx, SetOAuthClientQuotaParams{ID: testID, DailyQuota: want.DailyQuota})
+	check("SetOAuthClientQuota", client, err)
+
+	usage, err := q.RecordOAuthClientRequest(ctx, RecordOAuthClientRequestParams{ClientID: testID, Day: testNow})
+	if err != nil || usage.Requests != 7 || usage.DailyQuota != want.DailyQuota {
+		t.Fatalf("RecordOAuthClientRequest: got %+v, %v", usage, err)
+	}
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// This file is not generated: sqlc only emits queries that collect every
+// row into a slice.
+
+// EachChirp runs GetChirps and calls fn with each row as it is scanned,
+// stopping at the first error fn returns. The connection stays checked out
+// of the pool until EachChirp returns.
+func (q *Queries) EachChirp(ctx context.Context, viewerID uuid.UUID, fn func(GetChirpsRow) error) error {
+	rows, err := q.db.QueryContext(ctx, getChirps, viewerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i GetChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.DeletedAt,
+			&i.AuthorVerified,
+			&i.Sensitive,
+			&i.ContentWarning,
+		); err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	return rows.Err()
+}
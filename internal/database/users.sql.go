@@ -7,30 +7,40 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users(id, created_at, updated_at, email, hashed_password)
+INSERT INTO users(id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
   $1,
   NOW(),
   NOW(),
   $2,
-  $3
+  $3,
+  $4
 )
-RETURNING id, created_at, updated_at, email, hashed_password
+RETURNING id, created_at, updated_at, email, hashed_password, role, banned_at, suspended_until, moderation_reason, shadowbanned, verified, handle, is_chirpy_red, tenant_id
 `
 
 type CreateUserParams struct {
 	ID             uuid.UUID
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.ID, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser,
+		arg.ID,
+		arg.Email,
+		arg.HashedPassword,
+		arg.Handle,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -38,6 +48,15 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.Role,
+		&i.BannedAt,
+		&i.SuspendedUntil,
+		&i.ModerationReason,
+		&i.Shadowbanned,
+		&i.Verified,
+		&i.Handle,
+		&i.IsChirpyRed,
+		&i.TenantID,
 	)
 	return i, err
 }
@@ -48,8 +67,16 @@ SELECT
   created_at,
   updated_at,
   email,
-  hashed_password
-  
+  hashed_password,
+  role,
+  banned_at,
+  suspended_until,
+  moderation_reason,
+  shadowbanned,
+  verified,
+  handle,
+  is_chirpy_red,
+  tenant_id
 FROM users
 WHERE email = $1
 `
@@ -63,6 +90,295 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.Role,
+		&i.BannedAt,
+		&i.SuspendedUntil,
+		&i.ModerationReason,
+		&i.Shadowbanned,
+		&i.Verified,
+		&i.Handle,
+		&i.IsChirpyRed,
+		&i.TenantID,
+	)
+	return i, err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT
+  id,
+  created_at,
+  updated_at,
+  email,
+  hashed_password,
+  role,
+  banned_at,
+  suspended_until,
+  moderation_reason,
+  shadowbanned,
+  verified,
+  handle,
+  is_chirpy_red,
+  tenant_id
+FROM users
+WHERE handle = $1
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Role,
+		&i.BannedAt,
+		&i.SuspendedUntil,
+		&i.ModerationReason,
+		&i.Shadowbanned,
+		&i.Verified,
+		&i.Handle,
+		&i.IsChirpyRed,
+		&i.TenantID,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT
+  id,
+  created_at,
+  updated_at,
+  email,
+  hashed_password,
+  role,
+  banned_at,
+  suspended_until,
+  moderation_reason,
+  shadowbanned,
+  verified,
+  handle,
+  is_chirpy_red,
+  tenant_id
+FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Role,
+		&i.BannedAt,
+		&i.SuspendedUntil,
+		&i.ModerationReason,
+		&i.Shadowbanned,
+		&i.Verified,
+		&i.Handle,
+		&i.IsChirpyRed,
+		&i.TenantID,
+	)
+	return i, err
+}
+
+const getUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT
+  id,
+  created_at,
+  updated_at,
+  email,
+  hashed_password,
+  role,
+  banned_at,
+  suspended_until,
+  moderation_reason,
+  shadowbanned,
+  verified,
+  handle,
+  is_chirpy_red,
+  tenant_id
+FROM users
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.Role,
+			&i.BannedAt,
+			&i.SuspendedUntil,
+			&i.ModerationReason,
+			&i.Shadowbanned,
+			&i.Verified,
+			&i.Handle,
+			&i.IsChirpyRed,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSitemapUsers = `-- name: ListSitemapUsers :many
+SELECT
+  id,
+  handle,
+  updated_at
+FROM users
+WHERE handle IS NOT NULL
+  AND banned_at IS NULL
+  AND NOT shadowbanned
+  AND NOT EXISTS (
+    SELECT 1 FROM user_preferences
+    WHERE user_preferences.user_id = users.id AND NOT user_preferences.discoverable
+  )
+ORDER BY created_at ASC
+`
+
+type ListSitemapUsersRow struct {
+	ID        uuid.UUID
+	Handle    sql.NullString
+	UpdatedAt time.Time
+}
+
+func (q *Queries) ListSitemapUsers(ctx context.Context) ([]ListSitemapUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSitemapUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSitemapUsersRow
+	for rows.Next() {
+		var i ListSitemapUsersRow
+		if err := rows.Scan(&i.ID, &i.Handle, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT
+  id,
+  created_at,
+  updated_at,
+  email,
+  hashed_password,
+  role,
+  banned_at,
+  suspended_until,
+  moderation_reason,
+  shadowbanned,
+  verified,
+  handle,
+  is_chirpy_red,
+  tenant_id
+FROM users
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.Role,
+			&i.BannedAt,
+			&i.SuspendedUntil,
+			&i.ModerationReason,
+			&i.Shadowbanned,
+			&i.Verified,
+			&i.Handle,
+			&i.IsChirpyRed,
+			&i.TenantID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setUserChirpyRed = `-- name: SetUserChirpyRed :one
+UPDATE users
+SET is_chirpy_red = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, role, banned_at, suspended_until, moderation_reason, shadowbanned, verified, handle, is_chirpy_red, tenant_id
+`
+
+type SetUserChirpyRedParams struct {
+	ID          uuid.UUID
+	IsChirpyRed bool
+}
+
+func (q *Queries) SetUserChirpyRed(ctx context.Context, arg SetUserChirpyRedParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserChirpyRed, arg.ID, arg.IsChirpyRed)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Role,
+		&i.BannedAt,
+		&i.SuspendedUntil,
+		&i.ModerationReason,
+		&i.Shadowbanned,
+		&i.Verified,
+		&i.Handle,
+		&i.IsChirpyRed,
+		&i.TenantID,
 	)
 	return i, err
 }
@@ -0,0 +1,58 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Event is a single real-time message delivered to subscribers.
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Hub fans events out to every local subscriber. Events reach the hub via the
+// Postgres listener, so every instance sees writes made by any other instance.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[chan Event]struct{})}
+}
+
+// Subscribe registers a new subscriber. The returned func must be called to
+// release it once the consumer goes away.
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// Publish delivers e to all subscribers. Slow subscribers whose buffer is full
+// miss the event rather than blocking everyone else.
+func (h *Hub) Publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
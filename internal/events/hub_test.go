@@ -0,0 +1,47 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHub_PublishReachesSubscribers(t *testing.T) {
+	hub := NewHub()
+	a, unsubA := hub.Subscribe()
+	defer unsubA()
+	b, unsubB := hub.Subscribe()
+	defer unsubB()
+
+	hub.Publish(Event{Type: "chirp.created", Data: json.RawMessage(`{}`)})
+
+	for _, ch := range []<-chan Event{a, b} {
+		e := <-ch
+		if e.Type != "chirp.created" {
+			t.Fatalf("expected chirp.created, got %q", e.Type)
+		}
+	}
+}
+
+func TestHub_UnsubscribeClosesChannel(t *testing.T) {
+	hub := NewHub()
+	ch, unsub := hub.Subscribe()
+	unsub()
+	unsub() // must be safe to call twice
+
+	if _, ok := <-ch; ok {
+		t.Fatalf("expected channel to be closed")
+	}
+
+	// Publishing after the only subscriber left must not panic.
+	hub.Publish(Event{Type: "chirp.created"})
+}
+
+func TestHub_SlowSubscriberDoesNotBlock(t *testing.T) {
+	hub := NewHub()
+	_, unsub := hub.Subscribe()
+	defer unsub()
+
+	for i := 0; i < 100; i++ {
+		hub.Publish(Event{Type: "chirp.created"})
+	}
+}
@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel is the Postgres NOTIFY channel the database triggers publish to.
+const Channel = "chirpy_events"
+
+// Listen subscribes to Channel on a dedicated connection and publishes every
+// notification to hub until ctx is cancelled.
+func Listen(ctx context.Context, dbURL string, hub *Hub) error {
+	reportProblem := func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Println("events listener:", err)
+		}
+	}
+
+	l := pq.NewListener(dbURL, 10*time.Second, time.Minute, reportProblem)
+	defer l.Close()
+
+	if err := l.Listen(Channel); err != nil {
+		return err
+	}
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n := <-l.Notify:
+			// A nil notification means the connection was re-established;
+			// anything sent while it was down is lost.
+			if n == nil {
+				continue
+			}
+			var e Event
+			if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+				log.Println("events listener: bad payload:", err)
+				continue
+			}
+			hub.Publish(e)
+		case <-ping.C:
+			go l.Ping()
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package fieldset
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// structField is a field as encoding/json sees it.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+type structFields struct {
+	list  []structField
+	index map[string]int
+}
+
+var fieldCache sync.Map // reflect.Type -> structFields
+
+// jsonFields lists the fields encoding/json would write for t, in order.
+// Untagged embedded structs have their fields promoted, with shallower
+// fields winning.
+func jsonFields(t reflect.Type) structFields {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.(structFields)
+	}
+	var candidates []structField
+	collectFields(t, nil, &candidates)
+	// Shallowest first, so the fields that win a name are seen first.
+	slices.SortStableFunc(candidates, func(a, b structField) int {
+		return len(a.index) - len(b.index)
+	})
+	fields := structFields{index: make(map[string]int)}
+	for _, f := range candidates {
+		if _, ok := fields.index[f.name]; !ok {
+			fields.index[f.name] = 0
+			fields.list = append(fields.list, f)
+		}
+	}
+	slices.SortFunc(fields.list, func(a, b structField) int {
+		return slices.Compare(a.index, b.index)
+	})
+	for i, f := range fields.list {
+		fields.index[f.name] = i
+	}
+	f, _ := fieldCache.LoadOrStore(t, fields)
+	return f.(structFields)
+}
+
+func collectFields(t reflect.Type, index []int, fields *[]structField) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		fieldIndex := append(slices.Clone(index), i)
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				collectFields(ft, fieldIndex, fields)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		*fields = append(*fields, structField{
+			name:      name,
+			index:     fieldIndex,
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+}
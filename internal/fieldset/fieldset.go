@@ -0,0 +1,228 @@
+// Package fieldset trims JSON responses down to the fields a client asked
+// for, as in ?fields=id,body,account.username. Fields are named by their
+// json struct tags, and dotted paths select inside nested objects.
+package fieldset
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Set is a parsed field selection. A nil Set selects everything.
+type Set map[string]Set
+
+// all marks a field selected as a whole, as opposed to some of its fields.
+var all = Set{}
+
+// Parse parses a comma-separated list of dotted field paths. Selecting a
+// field as a whole also selects any of its fields listed separately.
+func Parse(s string) (Set, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	set := Set{}
+	for _, path := range strings.Split(s, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		names := strings.Split(path, ".")
+		node := set
+		for i, name := range names {
+			if name == "" {
+				return nil, fmt.Errorf("invalid field %q", path)
+			}
+			if i == len(names)-1 {
+				node[name] = all
+				break
+			}
+			child, ok := node[name]
+			if ok && isAll(child) {
+				break
+			}
+			if !ok {
+				child = Set{}
+				node[name] = child
+			}
+			node = child
+		}
+	}
+	return set, nil
+}
+
+func isAll(s Set) bool {
+	return s != nil && len(s) == 0
+}
+
+// Project returns the parts of v selected by set, as a value that marshals
+// to the same JSON v would with the other fields left out. Fields keep
+// their order. It's an error to name a field v's type doesn't have, so
+// typos don't silently produce empty objects.
+func Project(v interface{}, set Set) (interface{}, error) {
+	if set == nil {
+		return v, nil
+	}
+	return project(reflect.ValueOf(v), set, "")
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+func project(v reflect.Value, set Set, prefix string) (interface{}, error) {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Type().Implements(marshalerType) || reflect.PointerTo(v.Type()).Implements(marshalerType) {
+		return nil, fmt.Errorf("field %q has no fields to select", strings.TrimSuffix(prefix, "."))
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			// Still check the fields against the element type.
+			elem := v.Type().Elem()
+			for elem.Kind() == reflect.Pointer {
+				elem = elem.Elem()
+			}
+			if _, err := project(reflect.New(elem).Elem(), set, prefix); err != nil {
+				return nil, err
+			}
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			item, err := project(v.Index(i), set, prefix)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if v.IsNil() {
+			return nil, nil
+		}
+		var obj object
+		for _, name := range slices.Sorted(maps.Keys(set)) {
+			sub := set[name]
+			value := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !value.IsValid() {
+				continue
+			}
+			f, err := selectField(value, sub, prefix+name)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, field{name, f})
+		}
+		return obj, nil
+	case reflect.Struct:
+		fields := jsonFields(v.Type())
+		for name := range set {
+			if _, ok := fields.index[name]; !ok {
+				return nil, fmt.Errorf("unknown field %q", prefix+name)
+			}
+		}
+		var obj object
+		for _, f := range fields.list {
+			sub, ok := set[f.name]
+			if !ok {
+				continue
+			}
+			value, ok := fieldByIndex(v, f.index)
+			if !ok || (f.omitEmpty && isEmpty(value)) {
+				continue
+			}
+			projected, err := selectField(value, sub, prefix+f.name)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, field{f.name, projected})
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("field %q has no fields to select", strings.TrimSuffix(prefix, "."))
+}
+
+func selectField(v reflect.Value, sub Set, path string) (interface{}, error) {
+	if isAll(sub) {
+		return v.Interface(), nil
+	}
+	return project(v, sub, path+".")
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex, but reports a nil embedded
+// pointer instead of panicking.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmpty is encoding/json's notion of an empty value for omitempty.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// object is a JSON object that keeps its fields in order.
+type object []field
+
+type field struct {
+	name  string
+	value interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,85 @@
+package fieldset
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type account struct {
+	Handle string `json:"handle"`
+	Bio    string `json:"bio,omitempty"`
+}
+
+type base struct {
+	ID   int    `json:"id"`
+	Body string `json:"body"`
+}
+
+type chirp struct {
+	base
+	Author    account          `json:"author"`
+	CreatedAt time.Time        `json:"created_at"`
+	Card      *account         `json:"card,omitempty"`
+	Counts    map[string]int64 `json:"counts,omitempty"`
+	secret    string
+	Skipped   string `json:"-"`
+}
+
+func TestProject(t *testing.T) {
+	c := chirp{
+		base:      base{ID: 7, Body: "hi <there>"},
+		Author:    account{Handle: "saul", Bio: "lawyer"},
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Counts:    map[string]int64{"likes": 3, "replies": 1},
+	}
+	for _, tc := range []struct {
+		fields string
+		want   string
+	}{
+		{"", `{"id":7,"body":"hi \u003cthere\u003e","author":{"handle":"saul","bio":"lawyer"},"created_at":"2026-01-02T03:04:05Z","counts":{"likes":3,"replies":1}}`},
+		{"body,id", `{"id":7,"body":"hi \u003cthere\u003e"}`},
+		{"id, author.handle", `{"id":7,"author":{"handle":"saul"}}`},
+		{"author.handle,author", `{"author":{"handle":"saul","bio":"lawyer"}}`},
+		{"author,author.handle", `{"author":{"handle":"saul","bio":"lawyer"}}`},
+		{"card,created_at", `{"created_at":"2026-01-02T03:04:05Z"}`},
+		{"counts.replies,counts.shares", `{"counts":{"replies":1}}`},
+	} {
+		set, err := Parse(tc.fields)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.fields, err)
+		}
+		got, err := Project(c, set)
+		if err != nil {
+			t.Fatalf("Project(%q): %v", tc.fields, err)
+		}
+		b, err := json.Marshal(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.want {
+			t.Errorf("fields=%q:\n got %s\nwant %s", tc.fields, b, tc.want)
+		}
+	}
+
+	set, _ := Parse("id")
+	got, err := Project([]*chirp{&c, nil}, set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := json.Marshal(got); string(b) != `[{"id":7},null]` {
+		t.Errorf("slices are projected element by element, got %s", b)
+	}
+}
+
+func TestProjectErrors(t *testing.T) {
+	for _, fields := range []string{"nope", "author.nope", "created_at.year", "secret", "Skipped", "id..body"} {
+		set, err := Parse(fields)
+		if err == nil {
+			_, err = Project(chirp{}, set)
+		}
+		if err == nil {
+			t.Errorf("fields=%q: expected an error", fields)
+		}
+	}
+}
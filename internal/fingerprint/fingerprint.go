@@ -0,0 +1,107 @@
+// Package fingerprint gives static files content-addressed names, such as
+// style.3f2a9c1b.css for style.css, so they can be cached forever: a
+// changed file gets a new name.
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"strings"
+)
+
+// hashLen is the number of hex digits of the SHA-256 kept in a name.
+const hashLen = 8
+
+// Manifest maps file names to their fingerprinted names and back. The zero
+// value is an empty manifest.
+type Manifest struct {
+	names  map[string]string
+	hashed map[string]string
+}
+
+// Build fingerprints every file in fsys except dotfiles, whose names are
+// never exposed, and index.html files, which must keep their names.
+func Build(fsys fs.FS) (*Manifest, error) {
+	m := &Manifest{names: map[string]string{}, hashed: map[string]string{}}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || d.Name() == "index.html" {
+			return nil
+		}
+
+		sum, err := hashFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		hashed := Name(name, sum)
+		m.names[name] = hashed
+		m.hashed[hashed] = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:hashLen], nil
+}
+
+// Name inserts hash before the extension of name: "css/app.css" becomes
+// "css/app.<hash>.css".
+func Name(name, hash string) string {
+	ext := path.Ext(name)
+	if ext == path.Base(name) {
+		// A name like "LICENSE" or ".well-known" has no extension to keep.
+		ext = ""
+	}
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// Lookup returns the fingerprinted name of a file.
+func (m *Manifest) Lookup(name string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	hashed, ok := m.names[name]
+	return hashed, ok
+}
+
+// Resolve returns the file behind a fingerprinted name.
+func (m *Manifest) Resolve(hashed string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	name, ok := m.hashed[hashed]
+	return name, ok
+}
+
+// Names returns a copy of the file name to fingerprinted name mapping.
+func (m *Manifest) Names() map[string]string {
+	if m == nil || m.names == nil {
+		return map[string]string{}
+	}
+	return maps.Clone(m.names)
+}
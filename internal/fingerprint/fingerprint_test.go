@@ -0,0 +1,76 @@
+package fingerprint
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestName(t *testing.T) {
+	tests := map[string]string{
+		"style.css":          "style.abc123.css",
+		"js/app.min.js":      "js/app.min.abc123.js",
+		"LICENSE":            "LICENSE.abc123",
+		"img.d/logo":         "img.d/logo.abc123",
+		"fonts/.hidden.woff": "fonts/.hidden.abc123.woff",
+	}
+	for name, want := range tests {
+		if got := Name(name, "abc123"); got != want {
+			t.Errorf("Name(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("<html>")},
+		"css/style.css":   {Data: []byte("body {}")},
+		"css/copy.css":    {Data: []byte("body {}")},
+		"js/app.js":       {Data: []byte("alert(1)")},
+		".env":            {Data: []byte("SECRET=1")},
+		".git/config":     {Data: []byte("[core]")},
+		"docs/index.html": {Data: []byte("<html>")},
+	}
+	m, err := Build(fsys)
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+
+	names := m.Names()
+	if len(names) != 3 {
+		t.Fatalf("expected 3 fingerprinted files, got %v", names)
+	}
+	hashed, ok := m.Lookup("css/style.css")
+	if !ok || hashed != "css/style.62368a1a.css" {
+		t.Fatalf("Lookup(css/style.css) = %q, %v", hashed, ok)
+	}
+	if copyName, _ := m.Lookup("css/copy.css"); copyName != "css/copy.62368a1a.css" {
+		t.Errorf("identical contents should hash alike, got %q", copyName)
+	}
+	if name, ok := m.Resolve(hashed); !ok || name != "css/style.css" {
+		t.Errorf("Resolve(%q) = %q, %v", hashed, name, ok)
+	}
+	for _, name := range []string{"index.html", "docs/index.html", ".env", ".git/config"} {
+		if _, ok := m.Lookup(name); ok {
+			t.Errorf("%s should not be fingerprinted", name)
+		}
+	}
+
+	// The manifest can't be changed through Names.
+	names["js/app.js"] = "evil.js"
+	if got, _ := m.Lookup("js/app.js"); got == "evil.js" {
+		t.Error("Names returned the manifest's own map")
+	}
+}
+
+func TestNilManifest(t *testing.T) {
+	var m *Manifest
+	if _, ok := m.Lookup("a.css"); ok {
+		t.Error("nil manifest found a name")
+	}
+	if _, ok := m.Resolve("a.1.css"); ok {
+		t.Error("nil manifest resolved a name")
+	}
+	if len(m.Names()) != 0 {
+		t.Error("nil manifest has names")
+	}
+}
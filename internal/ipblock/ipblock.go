@@ -0,0 +1,61 @@
+package ipblock
+
+import (
+	"net/netip"
+	"sync"
+)
+
+// List is a concurrency-safe set of blocked CIDR ranges. It is replaced
+// wholesale whenever the stored blocks change.
+type List struct {
+	mu       sync.RWMutex
+	prefixes []netip.Prefix
+}
+
+// ParseCIDR accepts either a CIDR ("10.0.0.0/8") or a bare address, which is
+// treated as a single-host range.
+func ParseCIDR(s string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+	}
+
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return prefix.Masked(), nil
+}
+
+// Set replaces the blocked ranges. Entries that fail to parse are skipped and
+// returned so the caller can report them.
+func (l *List) Set(cidrs []string) (invalid []string) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, c := range cidrs {
+		p, err := ParseCIDR(c)
+		if err != nil {
+			invalid = append(invalid, c)
+			continue
+		}
+		prefixes = append(prefixes, p)
+	}
+
+	l.mu.Lock()
+	l.prefixes = prefixes
+	l.mu.Unlock()
+	return invalid
+}
+
+// Blocked reports whether addr falls inside any blocked range.
+func (l *List) Blocked(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, p := range l.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
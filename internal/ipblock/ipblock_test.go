@@ -0,0 +1,63 @@
+package ipblock
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestParseCIDR(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "10.0.0.0/8", want: "10.0.0.0/8"},
+		{in: "10.1.2.3/8", want: "10.0.0.0/8"},
+		{in: "192.168.1.7", want: "192.168.1.7/32"},
+		{in: "2001:db8::/32", want: "2001:db8::/32"},
+		{in: "::ffff:1.2.3.4", want: "1.2.3.4/32"},
+		{in: "not-an-ip", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		got, err := ParseCIDR(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("ParseCIDR(%q): expected error, got %s", tc.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ParseCIDR(%q) returned error: %v", tc.in, err)
+		}
+		if got.String() != tc.want {
+			t.Fatalf("ParseCIDR(%q): expected %s, got %s", tc.in, tc.want, got)
+		}
+	}
+}
+
+func TestList_Blocked(t *testing.T) {
+	var l List
+	invalid := l.Set([]string{"10.0.0.0/8", "203.0.113.5", "garbage"})
+	if len(invalid) != 1 || invalid[0] != "garbage" {
+		t.Fatalf("expected garbage to be reported invalid, got %v", invalid)
+	}
+
+	for addr, want := range map[string]bool{
+		"10.20.30.40":     true,
+		"203.0.113.5":     true,
+		"203.0.113.6":     false,
+		"::ffff:10.0.0.1": true,
+		"2001:db8::1":     false,
+		"192.168.100.100": false,
+	} {
+		if got := l.Blocked(netip.MustParseAddr(addr)); got != want {
+			t.Fatalf("Blocked(%s) = %v, want %v", addr, got, want)
+		}
+	}
+
+	l.Set(nil)
+	if l.Blocked(netip.MustParseAddr("10.0.0.1")) {
+		t.Fatalf("expected empty list to block nothing")
+	}
+}
@@ -0,0 +1,87 @@
+// Package jsonstream writes JSON arrays an element at a time, so a large
+// listing never has to be held in memory.
+package jsonstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// maxPooledBuffer keeps one huge element from pinning its buffer forever.
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// Array writes a JSON array to an io.Writer. The output is byte-for-byte
+// what json.Encoder produces for the equivalent slice, trailing newline
+// included.
+type Array struct {
+	w   io.Writer
+	buf *bytes.Buffer
+	enc *json.Encoder
+	n   int
+	err error
+}
+
+func NewArray(w io.Writer) *Array {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	return &Array{w: w, buf: buf, enc: json.NewEncoder(buf)}
+}
+
+// Write appends v to the array. Once a write fails, every later call
+// returns the same error.
+func (a *Array) Write(v interface{}) error {
+	if a.err != nil {
+		return a.err
+	}
+
+	a.buf.Reset()
+	if a.n == 0 {
+		a.buf.WriteByte('[')
+	} else {
+		a.buf.WriteByte(',')
+	}
+	if err := a.enc.Encode(v); err != nil {
+		// Nothing reached w, so the array is still intact.
+		return err
+	}
+	a.buf.Truncate(a.buf.Len() - 1) // Encode's newline
+
+	if _, err := a.w.Write(a.buf.Bytes()); err != nil {
+		a.err = err
+		return err
+	}
+	a.n++
+	return nil
+}
+
+// Len is the number of elements written so far. While it is zero nothing
+// has reached the writer, so an error response can still be sent instead.
+func (a *Array) Len() int {
+	return a.n
+}
+
+// Close ends the array, writing "[]" if there were no elements, and
+// releases its buffer. The Array must not be used afterwards.
+func (a *Array) Close() error {
+	if a.buf != nil {
+		if a.buf.Cap() <= maxPooledBuffer {
+			a.buf.Reset()
+			bufferPool.Put(a.buf)
+		}
+		a.buf, a.enc = nil, nil
+	}
+	if a.err != nil {
+		return a.err
+	}
+	end := "]\n"
+	if a.n == 0 {
+		end = "[]\n"
+	}
+	_, a.err = io.WriteString(a.w, end)
+	return a.err
+}
@@ -0,0 +1,113 @@
+package jsonstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type row struct {
+	ID        int       `json:"id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func makeRow(i int) row {
+	return row{ID: i, Body: "Chirp <" + strings.Repeat("x", i%140) + ">", CreatedAt: time.Unix(int64(i), 0).UTC()}
+}
+
+func rows(n int) []row {
+	out := make([]row, n)
+	for i := range out {
+		out[i] = makeRow(i)
+	}
+	return out
+}
+
+const benchmarkRows = 100_000
+
+func TestArray_MatchesEncoder(t *testing.T) {
+	for _, n := range []int{0, 1, 3, 1000} {
+		var want bytes.Buffer
+		if err := json.NewEncoder(&want).Encode(rows(n)); err != nil {
+			t.Fatal(err)
+		}
+
+		var got bytes.Buffer
+		a := NewArray(&got)
+		for _, r := range rows(n) {
+			if err := a.Write(r); err != nil {
+				t.Fatalf("Write returned error: %v", err)
+			}
+		}
+		if err := a.Close(); err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+		if a.Len() != n {
+			t.Errorf("Len() = %d, want %d", a.Len(), n)
+		}
+		if got.String() != want.String() {
+			t.Errorf("%d rows: got %q, want %q", n, got.String(), want.String())
+		}
+	}
+}
+
+func TestArray_UnencodableElementIsSkipped(t *testing.T) {
+	var got bytes.Buffer
+	a := NewArray(&got)
+	a.Write(1)
+	if err := a.Write(func() {}); err == nil {
+		t.Fatal("expected an error for an unencodable value")
+	}
+	a.Write(2)
+	a.Close()
+	if got.String() != "[1,2]\n" {
+		t.Errorf("got %q", got.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestArray_WriteErrorSticks(t *testing.T) {
+	a := NewArray(failingWriter{})
+	first := a.Write(1)
+	if first == nil {
+		t.Fatal("expected the write to fail")
+	}
+	if err := a.Write(2); err != first {
+		t.Errorf("expected the first error again, got %v", err)
+	}
+	if err := a.Close(); err != first {
+		t.Errorf("expected Close to return the first error, got %v", err)
+	}
+}
+
+// BenchmarkArray writes rows as they are produced, like a handler
+// scanning a result set.
+func BenchmarkArray(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		a := NewArray(io.Discard)
+		for i := range benchmarkRows {
+			a.Write(makeRow(i))
+		}
+		a.Close()
+	}
+}
+
+// BenchmarkEncodeSlice is the baseline: collect every row, then encode the
+// slice in one go.
+func BenchmarkEncodeSlice(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		json.NewEncoder(io.Discard).Encode(rows(benchmarkRows))
+	}
+}
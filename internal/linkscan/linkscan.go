@@ -0,0 +1,219 @@
+// Package linkscan finds the links in chirps and checks them against
+// lists of unsafe sites: a configured domain blocklist, and a lookup
+// service speaking the Safe Browsing v4 API.
+package linkscan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultSafeBrowsingURL is Google's lookup endpoint, used when only a key
+// is configured.
+const DefaultSafeBrowsingURL = "https://safebrowsing.googleapis.com/v4/threatMatches:find"
+
+// ThreatBlocklisted is the threat Blocklist reports.
+const ThreatBlocklisted = "BLOCKLISTED"
+
+// MaxURLs bounds the links taken from one text, so a chirp can't make the
+// scanner do unbounded work.
+const MaxURLs = 10
+
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+`)
+
+// ExtractURLs returns the distinct http and https links in text, in the
+// order they appear, without the punctuation that usually ends a sentence
+// around them.
+func ExtractURLs(text string) []string {
+	var urls []string
+	seen := map[string]bool{}
+	for _, match := range urlPattern.FindAllString(text, -1) {
+		match = strings.TrimRight(match, ".,;:!?'\")]}")
+		u, err := url.Parse(match)
+		if err != nil || u.Host == "" || seen[match] {
+			continue
+		}
+		seen[match] = true
+		urls = append(urls, match)
+		if len(urls) == MaxURLs {
+			break
+		}
+	}
+	return urls
+}
+
+// Scanner reports which of urls are unsafe, mapped to the kind of threat.
+// Safe URLs are left out.
+type Scanner interface {
+	Scan(ctx context.Context, urls []string) (map[string]string, error)
+}
+
+// Config selects and configures the Scanners.
+type Config struct {
+	// Blocklist is domains whose links, including their subdomains', are
+	// unsafe.
+	Blocklist []string `json:"blocklist"`
+	// SafeBrowsingURL is a Safe Browsing v4 threatMatches:find endpoint.
+	// It defaults to DefaultSafeBrowsingURL when SafeBrowsingKey is set.
+	SafeBrowsingURL string `json:"safe_browsing_url"`
+	SafeBrowsingKey string `json:"-"`
+}
+
+// New builds the Scanner described by cfg, or returns nil when scanning
+// is off.
+func New(cfg Config) Scanner {
+	var chain Chain
+	if len(cfg.Blocklist) > 0 {
+		chain = append(chain, Blocklist(cfg.Blocklist))
+	}
+	endpoint := cfg.SafeBrowsingURL
+	if endpoint == "" && cfg.SafeBrowsingKey != "" {
+		endpoint = DefaultSafeBrowsingURL
+	}
+	if endpoint != "" {
+		chain = append(chain, &SafeBrowsing{
+			URL:    endpoint,
+			Key:    cfg.SafeBrowsingKey,
+			Client: &http.Client{Timeout: 10 * time.Second},
+		})
+	}
+	switch len(chain) {
+	case 0:
+		return nil
+	case 1:
+		return chain[0]
+	default:
+		return chain
+	}
+}
+
+// Chain asks each Scanner in turn; a URL is unsafe if any of them says so.
+type Chain []Scanner
+
+func (c Chain) Scan(ctx context.Context, urls []string) (map[string]string, error) {
+	threats := map[string]string{}
+	for _, s := range c {
+		found, err := s.Scan(ctx, urls)
+		if err != nil {
+			return nil, err
+		}
+		for u, threat := range found {
+			if _, ok := threats[u]; !ok {
+				threats[u] = threat
+			}
+		}
+	}
+	return threats, nil
+}
+
+// Blocklist is domains whose links, and their subdomains' links, are
+// unsafe.
+type Blocklist []string
+
+func (b Blocklist) Scan(ctx context.Context, urls []string) (map[string]string, error) {
+	threats := map[string]string{}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		for _, domain := range b {
+			domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				threats[raw] = ThreatBlocklisted
+				break
+			}
+		}
+	}
+	return threats, nil
+}
+
+// SafeBrowsing looks URLs up with the Safe Browsing v4 threatMatches:find
+// API, or a service compatible with it.
+type SafeBrowsing struct {
+	URL    string
+	Key    string
+	Client *http.Client
+}
+
+type sbEntry struct {
+	URL string `json:"url"`
+}
+
+type sbRequest struct {
+	Client struct {
+		ClientID      string `json:"clientId"`
+		ClientVersion string `json:"clientVersion"`
+	} `json:"client"`
+	ThreatInfo struct {
+		ThreatTypes      []string  `json:"threatTypes"`
+		PlatformTypes    []string  `json:"platformTypes"`
+		ThreatEntryTypes []string  `json:"threatEntryTypes"`
+		ThreatEntries    []sbEntry `json:"threatEntries"`
+	} `json:"threatInfo"`
+}
+
+type sbResponse struct {
+	Matches []struct {
+		ThreatType string  `json:"threatType"`
+		Threat     sbEntry `json:"threat"`
+	} `json:"matches"`
+}
+
+func (s *SafeBrowsing) Scan(ctx context.Context, urls []string) (map[string]string, error) {
+	var body sbRequest
+	body.Client.ClientID = "chirpy"
+	body.Client.ClientVersion = "1.0"
+	body.ThreatInfo.ThreatTypes = []string{"MALWARE", "SOCIAL_ENGINEERING", "UNWANTED_SOFTWARE", "POTENTIALLY_HARMFUL_APPLICATION"}
+	body.ThreatInfo.PlatformTypes = []string{"ANY_PLATFORM"}
+	body.ThreatInfo.ThreatEntryTypes = []string{"URL"}
+	for _, u := range urls {
+		body.ThreatInfo.ThreatEntries = append(body.ThreatInfo.ThreatEntries, sbEntry{URL: u})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := s.URL
+	if s.Key != "" {
+		endpoint += "?key=" + url.QueryEscape(s.Key)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		// Leave out the URL, which has the key in it.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return nil, fmt.Errorf("linkscan: lookup failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("linkscan: lookup service responded %s", resp.Status)
+	}
+
+	var result sbResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("linkscan: decoding lookup response: %w", err)
+	}
+	threats := map[string]string{}
+	for _, m := range result.Matches {
+		threats[m.Threat.URL] = m.ThreatType
+	}
+	return threats, nil
+}
@@ -0,0 +1,89 @@
+package linkscan
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractURLs(t *testing.T) {
+	got := ExtractURLs(`see https://a.example/x?y=1, and (http://b.example/). Again: https://a.example/x?y=1! Not ftp://c.example or https:// alone`)
+	want := []string{"https://a.example/x?y=1", "http://b.example/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	many := strings.Repeat("https://x.example/ ", MaxURLs+5)
+	if got := ExtractURLs(many); len(got) != 1 {
+		t.Errorf("expected duplicates dropped, got %d", len(got))
+	}
+}
+
+func TestBlocklist(t *testing.T) {
+	b := Blocklist{"evil.example", ".Malware.example"}
+	got, err := b.Scan(context.Background(), []string{
+		"https://evil.example/a",
+		"https://WWW.malware.example",
+		"https://notevil.example/",
+		"https://evil.example.org/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"https://evil.example/a":      ThreatBlocklisted,
+		"https://WWW.malware.example": ThreatBlocklisted,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSafeBrowsing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "k3y" {
+			http.Error(w, "bad key", http.StatusForbidden)
+			return
+		}
+		var req sbRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		var resp sbResponse
+		for _, e := range req.ThreatInfo.ThreatEntries {
+			if strings.Contains(e.URL, "phish") {
+				resp.Matches = append(resp.Matches, struct {
+					ThreatType string  `json:"threatType"`
+					Threat     sbEntry `json:"threat"`
+				}{"SOCIAL_ENGINEERING", e})
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	s := New(Config{SafeBrowsingURL: srv.URL, SafeBrowsingKey: "k3y", Blocklist: []string{"evil.example"}})
+	got, err := s.Scan(context.Background(), []string{"https://phish.example/login", "https://evil.example/", "https://fine.example/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"https://phish.example/login": "SOCIAL_ENGINEERING",
+		"https://evil.example/":       ThreatBlocklisted,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	bad := &SafeBrowsing{URL: srv.URL, Key: "wrong", Client: srv.Client()}
+	if _, err := bad.Scan(context.Background(), []string{"https://fine.example/"}); err == nil {
+		t.Error("expected an error from a failed lookup")
+	}
+	if New(Config{}) != nil {
+		t.Error("expected no scanner without configuration")
+	}
+}
@@ -0,0 +1,155 @@
+package loader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultWait is how long a loader collects keys before issuing a batch.
+const DefaultWait = 2 * time.Millisecond
+
+// BatchFunc fetches values for many keys at once. Keys with no value are
+// simply left out of the returned map.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+type result[V any] struct {
+	value V
+	found bool
+	err   error
+}
+
+type batch[K comparable, V any] struct {
+	keys []K
+	done chan struct{}
+	res  map[K]V
+	err  error
+}
+
+// Loader coalesces concurrent Load calls made within a short window into one
+// BatchFunc call and caches the results. A Loader is meant to live for a
+// single request, so the cache never goes stale.
+type Loader[K comparable, V any] struct {
+	fetch BatchFunc[K, V]
+	wait  time.Duration
+
+	mu      sync.Mutex
+	cache   map[K]*result[V]
+	pending *batch[K, V]
+}
+
+func New[K comparable, V any](fetch BatchFunc[K, V], wait time.Duration) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch: fetch,
+		wait:  wait,
+		cache: make(map[K]*result[V]),
+	}
+}
+
+// Load returns the value for key. found is false when the batch function
+// had no value for it.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (value V, found bool, err error) {
+	l.mu.Lock()
+	if r, ok := l.cache[key]; ok {
+		l.mu.Unlock()
+		return r.value, r.found, r.err
+	}
+
+	b := l.pending
+	if b == nil {
+		b = &batch[K, V]{done: make(chan struct{})}
+		l.pending = b
+		time.AfterFunc(l.wait, func() { l.dispatch(ctx, b) })
+	}
+	if !contains(b.keys, key) {
+		b.keys = append(b.keys, key)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return value, false, ctx.Err()
+	}
+
+	if b.err != nil {
+		return value, false, b.err
+	}
+	value, found = b.res[key]
+	return value, found, nil
+}
+
+// LoadMany returns the values for keys, fetching every key not already
+// cached in one BatchFunc call made straight away. It's for code that knows
+// all its keys up front, such as a handler rendering a page of results.
+// Keys with no value are left out of the map.
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys []K) (map[K]V, error) {
+	values := make(map[K]V, len(keys))
+	var missing []K
+	seen := make(map[K]bool, len(keys))
+	l.mu.Lock()
+	for _, k := range keys {
+		if r, ok := l.cache[k]; ok {
+			if r.err != nil {
+				l.mu.Unlock()
+				return nil, r.err
+			}
+			if r.found {
+				values[k] = r.value
+			}
+			continue
+		}
+		if !seen[k] {
+			seen[k] = true
+			missing = append(missing, k)
+		}
+	}
+	l.mu.Unlock()
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	res, err := l.fetch(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	for _, k := range missing {
+		v, ok := res[k]
+		l.cache[k] = &result[V]{value: v, found: ok}
+		if ok {
+			values[k] = v
+		}
+	}
+	l.mu.Unlock()
+	return values, nil
+}
+
+func (l *Loader[K, V]) dispatch(ctx context.Context, b *batch[K, V]) {
+	l.mu.Lock()
+	if l.pending == b {
+		l.pending = nil
+	}
+	keys := b.keys
+	l.mu.Unlock()
+
+	b.res, b.err = l.fetch(ctx, keys)
+
+	l.mu.Lock()
+	for _, k := range keys {
+		v, ok := b.res[k]
+		l.cache[k] = &result[V]{value: v, found: ok, err: b.err}
+	}
+	l.mu.Unlock()
+
+	close(b.done)
+}
+
+func contains[K comparable](keys []K, key K) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLoader_BatchesConcurrentLoads(t *testing.T) {
+	var calls atomic.Int32
+	var gotKeys []int
+	l := New(func(ctx context.Context, keys []int) (map[int]string, error) {
+		calls.Add(1)
+		gotKeys = keys
+		res := make(map[int]string)
+		for _, k := range keys {
+			if k != 3 {
+				res[k] = "v" + string(rune('0'+k))
+			}
+		}
+		return res, nil
+	}, DefaultWait)
+
+	var wg sync.WaitGroup
+	for _, k := range []int{1, 2, 2, 3} {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			v, found, err := l.Load(context.Background(), k)
+			if err != nil {
+				t.Errorf("Load(%d) returned error: %v", k, err)
+			}
+			if k == 3 && found {
+				t.Errorf("expected key 3 to be missing")
+			}
+			if k != 3 && v != "v"+string(rune('0'+k)) {
+				t.Errorf("Load(%d) = %q", k, v)
+			}
+		}(k)
+	}
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 batch, got %d", calls.Load())
+	}
+	if len(gotKeys) != 3 {
+		t.Fatalf("expected 3 distinct keys, got %v", gotKeys)
+	}
+
+	// Cached values don't trigger another batch.
+	if _, _, err := l.Load(context.Background(), 1); err != nil {
+		t.Fatalf("cached Load returned error: %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected cached load, got %d batches", calls.Load())
+	}
+}
+
+func TestLoader_PropagatesErrors(t *testing.T) {
+	boom := errors.New("boom")
+	l := New(func(ctx context.Context, keys []string) (map[string]int, error) {
+		return nil, boom
+	}, DefaultWait)
+
+	if _, _, err := l.Load(context.Background(), "a"); !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+}
+
+func TestLoader_LoadManyFetchesOnlyMissingKeys(t *testing.T) {
+	var batches [][]int
+	l := New(func(ctx context.Context, keys []int) (map[int]int, error) {
+		batches = append(batches, keys)
+		res := make(map[int]int)
+		for _, k := range keys {
+			if k != 3 {
+				res[k] = k * 10
+			}
+		}
+		return res, nil
+	}, DefaultWait)
+
+	if _, _, err := l.Load(context.Background(), 1); err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	got, err := l.LoadMany(context.Background(), []int{1, 2, 2, 3, 4})
+	if err != nil {
+		t.Fatalf("LoadMany returned error: %v", err)
+	}
+	if len(got) != 3 || got[1] != 10 || got[2] != 20 || got[4] != 40 {
+		t.Fatalf("LoadMany = %v", got)
+	}
+	if len(batches) != 2 || len(batches[1]) != 3 {
+		t.Fatalf("expected a second batch of keys 2, 3 and 4, got %v", batches)
+	}
+
+	// Everything, including the missing key, is cached now.
+	if _, err := l.LoadMany(context.Background(), []int{2, 3}); err != nil {
+		t.Fatalf("cached LoadMany returned error: %v", err)
+	}
+	if len(batches) != 2 {
+		t.Fatalf("expected cached load, got %d batches", len(batches))
+	}
+}
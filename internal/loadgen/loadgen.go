@@ -0,0 +1,200 @@
+// Package loadgen drives a weighted mix of operations at a target rate and
+// reports latency percentiles per operation.
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mix picks operation names in proportion to their weights.
+type Mix struct {
+	names      []string
+	cumulative []int
+}
+
+// ParseMix parses "name=weight,..." such as "create=1,list=3". Weights are
+// non-negative integers and at least one must be positive.
+func ParseMix(s string) (Mix, error) {
+	var m Mix
+	total := 0
+	for _, part := range strings.Split(s, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || name == "" {
+			return Mix{}, fmt.Errorf("loadgen: %q is not name=weight", part)
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return Mix{}, fmt.Errorf("loadgen: weight of %q must be a non-negative integer", name)
+		}
+		if w == 0 {
+			continue
+		}
+		total += w
+		m.names = append(m.names, name)
+		m.cumulative = append(m.cumulative, total)
+	}
+	if total == 0 {
+		return Mix{}, errors.New("loadgen: mix has no positive weights")
+	}
+	return m, nil
+}
+
+// Names lists the operations with a positive weight.
+func (m Mix) Names() []string {
+	return m.names
+}
+
+// Pick returns an operation name.
+func (m Mix) Pick(r *rand.Rand) string {
+	n := r.IntN(m.cumulative[len(m.cumulative)-1])
+	i := sort.SearchInts(m.cumulative, n+1)
+	return m.names[i]
+}
+
+// Op performs one request. worker identifies the goroutine calling it, so
+// ops can keep per-worker state such as which synthetic user to act as.
+type Op func(ctx context.Context, worker int) error
+
+type Options struct {
+	// RPS is the target rate of operations started per second.
+	RPS         float64
+	Duration    time.Duration
+	Concurrency int
+	Mix         Mix
+	Ops         map[string]Op
+	// Seed makes the sequence of picked operations repeatable.
+	Seed uint64
+}
+
+// Report is the outcome of a run. Dropped counts operations that were due
+// while every worker was busy; a high number means the target rate was not
+// reached and the latencies understate the backlog.
+type Report struct {
+	Elapsed time.Duration
+	Dropped int
+	Ops     []OpStats
+}
+
+type OpStats struct {
+	Name   string
+	Count  int
+	Errors int
+	P50    time.Duration
+	P90    time.Duration
+	P99    time.Duration
+	Max    time.Duration
+}
+
+type result struct {
+	op      string
+	latency time.Duration
+	err     error
+}
+
+// Run starts operations at opts.RPS for opts.Duration or until ctx is done,
+// then waits for the ones in flight.
+func Run(ctx context.Context, opts Options) (Report, error) {
+	if opts.RPS <= 0 || opts.Concurrency < 1 {
+		return Report{}, errors.New("loadgen: RPS and Concurrency must be positive")
+	}
+	for _, name := range opts.Mix.Names() {
+		if opts.Ops[name] == nil {
+			return Report{}, fmt.Errorf("loadgen: no operation named %q", name)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	jobs := make(chan string, opts.Concurrency)
+	results := make(chan result, opts.Concurrency)
+
+	var workers sync.WaitGroup
+	for i := range opts.Concurrency {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for name := range jobs {
+				start := time.Now()
+				// In-flight requests may finish after the deadline.
+				err := opts.Ops[name](context.WithoutCancel(ctx), i)
+				results <- result{op: name, latency: time.Since(start), err: err}
+			}
+		}()
+	}
+
+	latencies := make(map[string][]time.Duration)
+	errorCounts := make(map[string]int)
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for r := range results {
+			latencies[r.op] = append(latencies[r.op], r.latency)
+			if r.err != nil {
+				errorCounts[r.op]++
+			}
+		}
+	}()
+
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed))
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.RPS))
+	defer ticker.Stop()
+
+	start := time.Now()
+	dropped := 0
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+			select {
+			case jobs <- opts.Mix.Pick(rng):
+			default:
+				dropped++
+			}
+		}
+	}
+	close(jobs)
+	workers.Wait()
+	close(results)
+	<-collected
+
+	report := Report{Elapsed: time.Since(start), Dropped: dropped}
+	for _, name := range opts.Mix.Names() {
+		lat := latencies[name]
+		sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+		stats := OpStats{
+			Name:   name,
+			Count:  len(lat),
+			Errors: errorCounts[name],
+			P50:    Percentile(lat, 50),
+			P90:    Percentile(lat, 90),
+			P99:    Percentile(lat, 99),
+		}
+		if len(lat) > 0 {
+			stats.Max = lat[len(lat)-1]
+		}
+		report.Ops = append(report.Ops, stats)
+	}
+	return report, nil
+}
+
+// Percentile returns the nearest-rank p-th percentile of sorted, or zero
+// when it is empty.
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(float64(len(sorted))*p/100)) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
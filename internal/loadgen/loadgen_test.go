@@ -0,0 +1,104 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestParseMix(t *testing.T) {
+	m, err := ParseMix("create=1, list=3,timeline=0")
+	if err != nil {
+		t.Fatalf("ParseMix returned error: %v", err)
+	}
+	if names := m.Names(); len(names) != 2 || names[0] != "create" || names[1] != "list" {
+		t.Fatalf("unexpected names %v", names)
+	}
+
+	counts := map[string]int{}
+	r := rand.New(rand.NewPCG(1, 1))
+	for range 4000 {
+		counts[m.Pick(r)]++
+	}
+	if counts["timeline"] != 0 {
+		t.Errorf("picked a zero-weight op %d times", counts["timeline"])
+	}
+	if ratio := float64(counts["list"]) / float64(counts["create"]); ratio < 2.5 || ratio > 3.5 {
+		t.Errorf("expected about 3 lists per create, got %v", counts)
+	}
+
+	for _, bad := range []string{"", "create", "create=-1", "create=x", "create=0"} {
+		if _, err := ParseMix(bad); err == nil {
+			t.Errorf("ParseMix(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	tests := map[float64]time.Duration{
+		50:  50 * time.Millisecond,
+		90:  90 * time.Millisecond,
+		99:  99 * time.Millisecond,
+		100: 100 * time.Millisecond,
+	}
+	for p, want := range tests {
+		if got := Percentile(sorted, p); got != want {
+			t.Errorf("Percentile(%v) = %v, want %v", p, got, want)
+		}
+	}
+	if got := Percentile(nil, 50); got != 0 {
+		t.Errorf("Percentile of nothing = %v, want 0", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	mix, err := ParseMix("ok=1,fail=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Run(context.Background(), Options{
+		RPS:         200,
+		Duration:    200 * time.Millisecond,
+		Concurrency: 4,
+		Mix:         mix,
+		Ops: map[string]Op{
+			"ok":   func(context.Context, int) error { return nil },
+			"fail": func(context.Context, int) error { return errors.New("boom") },
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	total := 0
+	for _, op := range report.Ops {
+		total += op.Count
+		switch op.Name {
+		case "ok":
+			if op.Errors != 0 {
+				t.Errorf("ok op reported %d errors", op.Errors)
+			}
+		case "fail":
+			if op.Errors != op.Count {
+				t.Errorf("fail op reported %d errors for %d calls", op.Errors, op.Count)
+			}
+		}
+	}
+	if total == 0 {
+		t.Fatal("expected some operations to run")
+	}
+}
+
+func TestRun_UnknownOp(t *testing.T) {
+	mix, _ := ParseMix("missing=1")
+	_, err := Run(context.Background(), Options{RPS: 1, Duration: time.Millisecond, Concurrency: 1, Mix: mix})
+	if err == nil {
+		t.Fatal("expected an error for an op without an implementation")
+	}
+}
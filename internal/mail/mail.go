@@ -0,0 +1,147 @@
+// Package mail renders Chirpy's email templates and sends messages through
+// a pluggable Sender.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	// Text is required; HTML is optional and sent as an alternative part.
+	Text string
+	HTML string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects and configures a Sender.
+type Config struct {
+	// Provider is "smtp" or "log". It defaults to "log".
+	Provider string
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// From is used when a message doesn't set its own.
+	From string
+}
+
+// New builds the Sender described by cfg.
+func New(cfg Config) (Sender, error) {
+	switch cfg.Provider {
+	case "", "log":
+		return &LogSender{From: cfg.From}, nil
+	case "smtp":
+		if cfg.Host == "" {
+			return nil, errors.New("mail: SMTP host is required")
+		}
+		if _, err := mail.ParseAddress(cfg.From); err != nil {
+			return nil, fmt.Errorf("mail: invalid from address: %w", err)
+		}
+		port := cfg.Port
+		if port == "" {
+			port = "587"
+		}
+		return &SMTPSender{
+			Addr:     cfg.Host + ":" + port,
+			Host:     cfg.Host,
+			Username: cfg.Username,
+			Password: cfg.Password,
+			From:     cfg.From,
+		}, nil
+	default:
+		return nil, fmt.Errorf("mail: unknown provider %q", cfg.Provider)
+	}
+}
+
+// LogSender writes messages to the log instead of sending them. It's the
+// default so development setups never email real people.
+type LogSender struct {
+	From string
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	from := msg.From
+	if from == "" {
+		from = s.From
+	}
+	log.Printf("mail: from=%s to=%s subject=%q\n%s", from, strings.Join(msg.To, ","), msg.Subject, msg.Text)
+	return nil
+}
+
+// Build renders msg as an RFC 5322 message with a multipart/alternative
+// body when it has HTML.
+func Build(msg Message, now time.Time) ([]byte, error) {
+	if len(msg.To) == 0 {
+		return nil, errors.New("mail: message has no recipients")
+	}
+	for _, h := range append([]string{msg.From, msg.Subject}, msg.To...) {
+		if strings.ContainsAny(h, "\r\n") {
+			return nil, errors.New("mail: header contains a line break")
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@chirpy>\r\n", randomToken())
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&b, msg.Text); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	}
+
+	boundary := "chirpy-" + randomToken()
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&b, part.body); err != nil {
+			return nil, err
+		}
+		b.WriteString("\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return b.Bytes(), nil
+}
+
+func writeQuotedPrintable(b *bytes.Buffer, s string) error {
+	w := quotedprintable.NewWriter(b)
+	if _, err := w.Write([]byte(s)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func randomToken() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
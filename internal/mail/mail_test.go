@@ -0,0 +1,165 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuild_Alternative(t *testing.T) {
+	raw, err := Build(Message{
+		From:    "Chirpy <noreply@chirpy.example>",
+		To:      []string{"ada@example.com"},
+		Subject: "Héllo",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	}, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+
+	msg := string(raw)
+	for _, want := range []string{
+		"To: ada@example.com\r\n",
+		"Subject: =?utf-8?q?H=C3=A9llo?=\r\n",
+		"Content-Type: multipart/alternative;",
+		"plain body",
+		"<p>html body</p>",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message is missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestBuild_RejectsHeaderInjection(t *testing.T) {
+	_, err := Build(Message{
+		From:    "noreply@chirpy.example",
+		To:      []string{"ada@example.com"},
+		Subject: "hi\r\nBcc: everyone@example.com",
+		Text:    "body",
+	}, time.Now())
+	if err == nil {
+		t.Fatal("expected an error for a subject with a line break")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if s, err := New(Config{}); err != nil {
+		t.Fatalf("New with defaults returned error: %v", err)
+	} else if _, ok := s.(*LogSender); !ok {
+		t.Fatalf("expected a LogSender by default, got %T", s)
+	}
+	if _, err := New(Config{Provider: "smtp"}); err == nil {
+		t.Fatal("expected an error for SMTP without a host")
+	}
+	if _, err := New(Config{Provider: "pigeon"}); err == nil {
+		t.Fatal("expected an error for an unknown provider")
+	}
+}
+
+func TestRender(t *testing.T) {
+	msg, err := Render(TemplateNewFollower, "ada@example.com", TemplateData{
+		Name:     "ada",
+		SiteURL:  "https://chirpy.example",
+		Link:     "https://social.example/@grace",
+		Follower: "<grace>",
+	})
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+
+	if msg.Subject != "<grace> followed you on Chirpy" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "<grace> is now following you.") {
+		t.Errorf("unexpected text body:\n%s", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "&lt;grace&gt;") || strings.Contains(msg.HTML, "<grace>") {
+		t.Errorf("expected the follower to be escaped in HTML:\n%s", msg.HTML)
+	}
+	if !strings.Contains(msg.HTML, "https://chirpy.example") {
+		t.Errorf("expected the layout footer in HTML:\n%s", msg.HTML)
+	}
+}
+
+func TestRender_AllTemplates(t *testing.T) {
+	for _, name := range []string{TemplateWelcome, TemplateVerify, TemplateReset, TemplateNewFollower, TemplateDigest} {
+		msg, err := Render(name, "ada@example.com", TemplateData{Name: "ada", Link: "https://chirpy.example/x"})
+		if err != nil {
+			t.Errorf("Render(%s) returned error: %v", name, err)
+			continue
+		}
+		if msg.Subject == "" || msg.Text == "" || msg.HTML == "" {
+			t.Errorf("Render(%s) left a part empty: %+v", name, msg)
+		}
+	}
+	if _, err := Render("nope", "ada@example.com", TemplateData{}); err == nil {
+		t.Error("expected an error for an unknown template")
+	}
+}
+
+func TestRender_Digest(t *testing.T) {
+	msg, err := Render(TemplateDigest, "ada@example.com", TemplateData{
+		Name:      "ada",
+		SiteURL:   "https://chirpy.example",
+		Period:    "weekly",
+		Followers: []string{"https://social.example/users/grace"},
+		Chirps: []DigestChirp{
+			{Author: "linus", Body: "hello <world>", URL: "https://chirpy.example/chirps/1"},
+		},
+		UnsubscribeURL: "https://chirpy.example/api/digests/unsubscribe?user=1&sig=x",
+	})
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+
+	if msg.Subject != "Your weekly Chirpy digest" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+	for _, want := range []string{"https://social.example/users/grace", "@linus: hello <world>", "unsubscribe?user=1&sig=x"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("expected %q in text body:\n%s", want, msg.Text)
+		}
+	}
+	if !strings.Contains(msg.HTML, "hello &lt;world&gt;") {
+		t.Errorf("expected the chirp to be escaped in HTML:\n%s", msg.HTML)
+	}
+	if !strings.Contains(msg.HTML, ">Unsubscribe</a>") {
+		t.Errorf("expected an unsubscribe link in HTML:\n%s", msg.HTML)
+	}
+}
+
+func TestSetTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"welcome.txt":  `{{define "subject"}}Hi from disk{{end}}Hello {{.Name}}`,
+		"welcome.html": `{{define "content"}}<p>Hello {{.Name}}</p>{{end}}`,
+		"layout.html":  `<main>{{template "content" .}}</main>`,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	SetTemplateDir(dir)
+	t.Cleanup(func() { SetTemplateDir("") })
+
+	msg, err := Render(TemplateWelcome, "ada@example.com", TemplateData{Name: "ada"})
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	if msg.Subject != "Hi from disk" || msg.HTML != "<main><p>Hello ada</p></main>" {
+		t.Errorf("templates were not read from disk: %+v", msg)
+	}
+
+	// Edits apply to the next message without a restart.
+	if err := os.WriteFile(filepath.Join(dir, "welcome.txt"), []byte(`{{define "subject"}}Edited{{end}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if msg, _ := Render(TemplateWelcome, "ada@example.com", TemplateData{}); msg.Subject != "Edited" {
+		t.Errorf("expected the edited subject, got %q", msg.Subject)
+	}
+}
@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"context"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPSender delivers through an SMTP relay. net/smtp upgrades to TLS with
+// STARTTLS when the server offers it, and only sends credentials over TLS
+// or to localhost.
+type SMTPSender struct {
+	Addr     string
+	Host     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = s.From
+	}
+	body, err := Build(msg, time.Now())
+	if err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	// net/smtp has no context support, so run it aside and stop waiting
+	// when ctx ends.
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.Addr, auth, from.Address, msg.To, body)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,132 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Template names.
+const (
+	TemplateWelcome     = "welcome"
+	TemplateVerify      = "verify"
+	TemplateReset       = "reset"
+	TemplateNewFollower = "new_follower"
+	TemplateDigest      = "digest"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// TemplateData is what the templates can refer to. Fields a template
+// doesn't use may be left empty.
+type TemplateData struct {
+	// Name is how the recipient is addressed.
+	Name    string
+	SiteURL string
+	// Link is the call to action: a verification or reset URL, or the
+	// follower's profile.
+	Link     string
+	Follower string
+
+	// Period, Followers and Chirps fill in the digest.
+	Period    string
+	Followers []string
+	Chirps    []DigestChirp
+	// UnsubscribeURL, when set, adds an unsubscribe link to the footer.
+	UnsubscribeURL string
+}
+
+// DigestChirp is one chirp as listed in a digest.
+type DigestChirp struct {
+	Author string
+	Body   string
+	URL    string
+}
+
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+var templateNames = []string{TemplateWelcome, TemplateVerify, TemplateReset, TemplateNewFollower, TemplateDigest}
+
+var templates = mustParseTemplates()
+
+// templateDir, when set, is read on every Render instead of the embedded
+// templates.
+var templateDir string
+
+// SetTemplateDir makes Render read templates from dir on every call, so
+// edits show up without a rebuild. It is for development; call it before
+// sending mail.
+func SetTemplateDir(dir string) {
+	templateDir = dir
+}
+
+func mustParseTemplates() map[string]emailTemplate {
+	sub, err := fs.Sub(templateFS, "templates")
+	if err != nil {
+		panic(err)
+	}
+	parsed := make(map[string]emailTemplate, len(templateNames))
+	for _, name := range templateNames {
+		t, err := parseTemplate(sub, name)
+		if err != nil {
+			panic(err)
+		}
+		parsed[name] = t
+	}
+	return parsed
+}
+
+// Each template has a text/template file defining "subject" and the plain
+// body, and an html/template file defining "content" for the shared layout.
+func parseTemplate(fsys fs.FS, name string) (emailTemplate, error) {
+	text, err := texttemplate.ParseFS(fsys, name+".txt")
+	if err != nil {
+		return emailTemplate{}, err
+	}
+	html, err := htmltemplate.ParseFS(fsys, "layout.html", name+".html")
+	if err != nil {
+		return emailTemplate{}, err
+	}
+	return emailTemplate{text: text, html: html}, nil
+}
+
+// Render builds the message for the named template, addressed to to.
+func Render(name, to string, data TemplateData) (Message, error) {
+	t, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("mail: unknown template %q", name)
+	}
+	if templateDir != "" {
+		var err error
+		if t, err = parseTemplate(os.DirFS(templateDir), name); err != nil {
+			return Message{}, err
+		}
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := t.text.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := t.html.ExecuteTemplate(&html, "layout.html", data); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		To:      []string{to},
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,208 @@
+// Package media checks uploads before they are stored, starting with
+// whether a file really is the type it claims. Images are decoded and
+// encoded again, which leaves EXIF, GPS and other metadata behind; videos
+// are checked for size and duration.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+)
+
+const (
+	// MaxJPEGQuality caps the quality JPEGs are encoded at.
+	MaxJPEGQuality = 85
+	// MaxPixels bounds an image's width times height, so a small file
+	// can't decode into gigabytes.
+	MaxPixels = 40_000_000
+	// MaxBytes bounds the size of an upload.
+	MaxBytes = 20 << 20
+)
+
+var (
+	// ErrUnsupportedType is returned for content types other than JPEG,
+	// PNG and GIF.
+	ErrUnsupportedType = errors.New("media: unsupported image type")
+	// ErrTypeMismatch is returned when a file's contents aren't of the
+	// type it was uploaded as.
+	ErrTypeMismatch = errors.New("media: file contents don't match the content type")
+	// ErrTooLarge is returned for files over MaxBytes and images over
+	// MaxPixels.
+	ErrTooLarge = errors.New("media: image is too large")
+)
+
+// Sanitize reads an image uploaded as contentType and returns it encoded
+// again, without metadata. JPEGs are turned upright first, since their
+// EXIF orientation is dropped with the rest.
+func Sanitize(r io.Reader, contentType string) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, ErrUnsupportedType
+	}
+	data, err := io.ReadAll(io.LimitReader(r, MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxBytes {
+		return nil, ErrTooLarge
+	}
+	if http.DetectContentType(data) != contentType {
+		return nil, ErrTypeMismatch
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("media: decoding image: %w", err)
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	var buf bytes.Buffer
+	switch contentType {
+	case "image/jpeg":
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("media: decoding image: %w", err)
+		}
+		img = orient(img, jpegOrientation(data))
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: MaxJPEGQuality})
+		if err != nil {
+			return nil, err
+		}
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("media: decoding image: %w", err)
+		}
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+	case "image/gif":
+		// Keep every frame of an animation; comments and other extensions
+		// are dropped.
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("media: decoding image: %w", err)
+		}
+		if err := gif.EncodeAll(&buf, g); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG, from 1 (upright)
+// to 8. It returns 1 if there is none or it can't be read.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// Start of scan or end of image: the metadata is all before.
+			return 1
+		}
+		size := int(data[i+2])<<8 | int(data[i+3])
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// exifOrientation finds the orientation tag in the first IFD of a TIFF
+// structure.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var u16 func([]byte) int
+	var u32 func([]byte) int
+	switch string(tiff[:2]) {
+	case "II":
+		u16 = func(b []byte) int { return int(b[0]) | int(b[1])<<8 }
+		u32 = func(b []byte) int { return u16(b) | u16(b[2:])<<16 }
+	case "MM":
+		u16 = func(b []byte) int { return int(b[0])<<8 | int(b[1]) }
+		u32 = func(b []byte) int { return u16(b)<<16 | u16(b[2:]) }
+	default:
+		return 1
+	}
+	ifd := u32(tiff[4:])
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := u16(tiff[ifd:])
+	for n := 0; n < entries; n++ {
+		e := ifd + 2 + n*12
+		if e+12 > len(tiff) {
+			return 1
+		}
+		if u16(tiff[e:]) == 0x0112 {
+			if o := u16(tiff[e+8:]); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient turns img upright given its EXIF orientation.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// Orientations 5 to 8 swap the width and height.
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise to view
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise to view
+				dx, dy = y, w-1-x
+			}
+			dst.SetRGBA(dx, dy, src.RGBAAt(x, y))
+		}
+	}
+	return dst
+}
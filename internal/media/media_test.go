@@ -0,0 +1,82 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// withEXIF inserts an APP1 segment after a JPEG's start of image marker,
+// with the given orientation and a GPS marker string to look for.
+func withEXIF(t *testing.T, jpg []byte, orientation byte) []byte {
+	t.Helper()
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // big endian, first IFD at 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0, // orientation, SHORT
+		0, 0, 0, 0, // no next IFD
+	}
+	tiff = append(tiff, "GPS 51.5007N 0.1246W"...)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	size := len(payload) + 2
+	segment := append([]byte{0xFF, 0xE1, byte(size >> 8), byte(size)}, payload...)
+	return append(append(append([]byte{}, jpg[:2]...), segment...), jpg[2:]...)
+}
+
+func TestSanitize_JPEG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for x := 0; x < 20; x++ {
+		for y := 0; y < 20; y++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	upload := withEXIF(t, buf.Bytes(), 6)
+	if jpegOrientation(upload) != 6 {
+		t.Fatalf("expected orientation 6, got %d", jpegOrientation(upload))
+	}
+
+	clean, err := Sanitize(bytes.NewReader(upload), "image/jpeg")
+	if err != nil {
+		t.Fatalf("Sanitize returned error: %v", err)
+	}
+	if bytes.Contains(clean, []byte("Exif")) || bytes.Contains(clean, []byte("GPS")) {
+		t.Error("expected the metadata to be stripped")
+	}
+	out, err := jpeg.Decode(bytes.NewReader(clean))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := out.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+		t.Errorf("expected the image turned upright to 20x40, got %dx%d", b.Dx(), b.Dy())
+	}
+	// Rotated clockwise, the white left half ends up on top.
+	if r, _, _, _ := out.At(10, 5).RGBA(); r < 0xE000 {
+		t.Errorf("expected white at the top, got %v", out.At(10, 5))
+	}
+}
+
+func TestSanitize_Rejects(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2)))
+
+	if _, err := Sanitize(bytes.NewReader(buf.Bytes()), "image/jpeg"); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("PNG sent as JPEG: expected ErrTypeMismatch, got %v", err)
+	}
+	if _, err := Sanitize(bytes.NewReader([]byte("<svg onload=alert(1)>")), "image/svg+xml"); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("SVG: expected ErrUnsupportedType, got %v", err)
+	}
+	if _, err := Sanitize(bytes.NewReader([]byte("GIF89a not really")), "image/gif"); err == nil {
+		t.Error("expected a truncated GIF to fail to decode")
+	}
+	if clean, err := Sanitize(bytes.NewReader(buf.Bytes()), "image/png"); err != nil || len(clean) == 0 {
+		t.Errorf("PNG: expected it re-encoded, got %v", err)
+	}
+}
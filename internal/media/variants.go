@@ -0,0 +1,114 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+)
+
+// VariantSizes are the smaller copies made of each image, by name, with
+// the longest side each is scaled to fit.
+var VariantSizes = []struct {
+	Name    string
+	MaxSide int
+}{
+	{"thumb", 320},
+	{"medium", 1280},
+}
+
+// Variant is a smaller copy of an image.
+type Variant struct {
+	Name        string
+	Width       int
+	Height      int
+	ContentType string
+	Data        []byte
+}
+
+// Dimensions returns the width and height of an image.
+func Dimensions(data []byte) (int, int, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, fmt.Errorf("media: decoding image: %w", err)
+	}
+	return cfg.Width, cfg.Height, nil
+}
+
+// MakeVariants scales an image stored by Sanitize down to each of
+// VariantSizes, skipping sizes the image already fits. JPEGs stay JPEGs;
+// PNGs and GIFs become PNGs, with only the first frame of an animation.
+func MakeVariants(data []byte, contentType string) ([]Variant, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("media: decoding image: %w", err)
+	}
+	b := img.Bounds()
+	var variants []Variant
+	for _, size := range VariantSizes {
+		w, h := fit(b.Dx(), b.Dy(), size.MaxSide)
+		if w == b.Dx() && h == b.Dy() {
+			continue
+		}
+		v := Variant{Name: size.Name, Width: w, Height: h}
+		var buf bytes.Buffer
+		scaled := scale(img, w, h)
+		if contentType == "image/jpeg" {
+			v.ContentType = "image/jpeg"
+			err = jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: MaxJPEGQuality})
+		} else {
+			v.ContentType = "image/png"
+			err = png.Encode(&buf, scaled)
+		}
+		if err != nil {
+			return nil, err
+		}
+		v.Data = buf.Bytes()
+		variants = append(variants, v)
+	}
+	return variants, nil
+}
+
+// fit returns w and h scaled down, keeping their ratio, so neither is over
+// maxSide.
+func fit(w, h, maxSide int) (int, int) {
+	if w <= maxSide && h <= maxSide {
+		return w, h
+	}
+	if w >= h {
+		return maxSide, max(1, h*maxSide/w)
+	}
+	return max(1, w*maxSide/h), maxSide
+}
+
+// scale shrinks img to w by h, averaging the source pixels that fall in
+// each destination pixel.
+func scale(img image.Image, w, h int) *image.RGBA {
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := b.Dx(), b.Dy()
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := src.RGBAAt(sx, sy)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					bl += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), uint8(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,58 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestMakeVariants(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2000, 1000))
+	for x := 0; x < 1000; x++ {
+		for y := 0; y < 1000; y++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	variants, err := MakeVariants(buf.Bytes(), "image/png")
+	if err != nil {
+		t.Fatalf("MakeVariants returned error: %v", err)
+	}
+	if len(variants) != 2 {
+		t.Fatalf("expected 2 variants, got %d", len(variants))
+	}
+	for i, want := range []struct{ w, h int }{{320, 160}, {1280, 640}} {
+		v := variants[i]
+		if v.Width != want.w || v.Height != want.h || v.ContentType != "image/png" {
+			t.Errorf("%s: got %dx%d %s, want %dx%d image/png", v.Name, v.Width, v.Height, v.ContentType, want.w, want.h)
+		}
+		w, h, err := Dimensions(v.Data)
+		if err != nil || w != v.Width || h != v.Height {
+			t.Errorf("%s: encoded as %dx%d, %v", v.Name, w, h, err)
+		}
+	}
+	thumb, err := png.Decode(bytes.NewReader(variants[0].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := thumb.At(10, 10).RGBA(); r>>8 != 0xFF {
+		t.Errorf("expected the left half to stay white, got red %d", r>>8)
+	}
+	if r, _, _, _ := thumb.At(300, 10).RGBA(); r>>8 != 0 {
+		t.Errorf("expected the right half to stay black, got red %d", r>>8)
+	}
+
+	buf.Reset()
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 100, 50))); err != nil {
+		t.Fatal(err)
+	}
+	if small, err := MakeVariants(buf.Bytes(), "image/png"); err != nil || len(small) != 0 {
+		t.Errorf("expected no variants of a small image, got %d, %v", len(small), err)
+	}
+}
@@ -0,0 +1,240 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"time"
+)
+
+const (
+	// MaxVideoBytes bounds the size of a video upload.
+	MaxVideoBytes = 50 << 20
+	// MaxVideoDuration bounds how long a video may play.
+	MaxVideoDuration = 60 * time.Second
+)
+
+// ErrTooLong is returned for videos over MaxVideoDuration, and for videos
+// whose duration can't be found.
+var ErrTooLong = errors.New("media: video is too long")
+
+// IsVideo reports whether contentType is a video type CheckVideo accepts.
+func IsVideo(contentType string) bool {
+	return contentType == "video/mp4" || contentType == "video/webm"
+}
+
+// CheckVideo checks that the size bytes of r are a video of contentType,
+// MP4 or WebM, within MaxVideoBytes and MaxVideoDuration. It returns the
+// video's duration. Videos are stored as uploaded, so unlike images their
+// metadata is kept.
+func CheckVideo(r io.ReaderAt, size int64, contentType string) (time.Duration, error) {
+	if !IsVideo(contentType) {
+		return 0, ErrUnsupportedType
+	}
+	if size > MaxVideoBytes {
+		return 0, ErrTooLarge
+	}
+	head := make([]byte, min(size, 512))
+	if _, err := r.ReadAt(head, 0); err != nil && err != io.EOF {
+		return 0, err
+	}
+	if http.DetectContentType(head) != contentType {
+		return 0, ErrTypeMismatch
+	}
+
+	var d time.Duration
+	var ok bool
+	if contentType == "video/mp4" {
+		d, ok = mp4Duration(r, size)
+	} else {
+		d, ok = webmDuration(r, size)
+	}
+	if !ok || d > MaxVideoDuration {
+		return 0, ErrTooLong
+	}
+	return d, nil
+}
+
+// mp4Duration reads the duration from the movie header (moov/mvhd box).
+func mp4Duration(r io.ReaderAt, size int64) (time.Duration, bool) {
+	moov, moovSize, ok := findBox(r, 0, size, "moov")
+	if !ok {
+		return 0, false
+	}
+	mvhd, mvhdSize, ok := findBox(r, moov, moovSize, "mvhd")
+	if !ok || mvhdSize < 32 {
+		return 0, false
+	}
+	buf := make([]byte, 32)
+	if _, err := r.ReadAt(buf, mvhd); err != nil {
+		return 0, false
+	}
+	var timescale, duration uint64
+	if buf[0] == 1 {
+		// Version 1: 64-bit creation and modification times and duration.
+		timescale = uint64(binary.BigEndian.Uint32(buf[20:]))
+		duration = binary.BigEndian.Uint64(buf[24:])
+	} else {
+		timescale = uint64(binary.BigEndian.Uint32(buf[12:]))
+		duration = uint64(binary.BigEndian.Uint32(buf[16:]))
+	}
+	if timescale == 0 {
+		return 0, false
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second)), true
+}
+
+// findBox finds the box named typ among those between offset and
+// offset+size, returning where its contents start and how long they are.
+func findBox(r io.ReaderAt, offset, size int64, typ string) (int64, int64, bool) {
+	end := offset + size
+	header := make([]byte, 16)
+	for offset+8 <= end {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return 0, 0, false
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header))
+		headerSize := int64(8)
+		switch boxSize {
+		case 0:
+			// The box runs to the end.
+			boxSize = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return 0, 0, false
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:]))
+			headerSize = 16
+		}
+		if boxSize < headerSize || offset+boxSize > end {
+			return 0, 0, false
+		}
+		if string(header[4:8]) == typ {
+			return offset + headerSize, boxSize - headerSize, true
+		}
+		offset += boxSize
+	}
+	return 0, 0, false
+}
+
+// EBML element IDs on the way to a WebM file's duration.
+const (
+	ebmlSegment       = 0x18538067
+	ebmlInfo          = 0x1549A966
+	ebmlTimecodeScale = 0x2AD7B1
+	ebmlDuration      = 0x4489
+)
+
+// webmDuration reads Segment/Info/Duration, which is in units of the
+// segment's TimecodeScale nanoseconds.
+func webmDuration(r io.ReaderAt, size int64) (time.Duration, bool) {
+	segment, segmentSize, ok := findElement(r, 0, size, ebmlSegment)
+	if !ok {
+		return 0, false
+	}
+	info, infoSize, ok := findElement(r, segment, segmentSize, ebmlInfo)
+	if !ok {
+		return 0, false
+	}
+
+	scale := uint64(1_000_000)
+	if at, n, ok := findElement(r, info, infoSize, ebmlTimecodeScale); ok && n >= 1 && n <= 8 {
+		buf := make([]byte, n)
+		if _, err := r.ReadAt(buf, at); err != nil {
+			return 0, false
+		}
+		scale = 0
+		for _, b := range buf {
+			scale = scale<<8 | uint64(b)
+		}
+	}
+	at, n, ok := findElement(r, info, infoSize, ebmlDuration)
+	if !ok {
+		return 0, false
+	}
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, at); err != nil {
+		return 0, false
+	}
+	var units float64
+	switch n {
+	case 4:
+		units = float64(math.Float32frombits(binary.BigEndian.Uint32(buf)))
+	case 8:
+		units = math.Float64frombits(binary.BigEndian.Uint64(buf))
+	default:
+		return 0, false
+	}
+	if units < 0 || math.IsNaN(units) {
+		return 0, false
+	}
+	return time.Duration(units * float64(scale)), true
+}
+
+// findElement finds the EBML element with id among those between offset
+// and offset+size, returning where its data starts and how long it is.
+func findElement(r io.ReaderAt, offset, size int64, id uint32) (int64, int64, bool) {
+	end := offset + size
+	for offset < end {
+		elemID, idLen, ok := readVint(r, offset, true)
+		if !ok {
+			return 0, 0, false
+		}
+		dataSize, sizeLen, ok := readVint(r, offset+idLen, false)
+		if !ok {
+			return 0, 0, false
+		}
+		data := offset + idLen + sizeLen
+		if dataSize < 0 || data+dataSize > end {
+			// An unknown or overlong size runs to the end of the parent.
+			dataSize = end - data
+		}
+		if uint32(elemID) == id {
+			return data, dataSize, true
+		}
+		offset = data + dataSize
+	}
+	return 0, 0, false
+}
+
+// readVint reads an EBML variable-length integer at offset. IDs keep their
+// length marker bit; sizes drop it, and a size of all ones, meaning
+// unknown, is returned as -1.
+func readVint(r io.ReaderAt, offset int64, keepMarker bool) (int64, int64, bool) {
+	var first [1]byte
+	if _, err := r.ReadAt(first[:], offset); err != nil {
+		return 0, 0, false
+	}
+	length := int64(1)
+	for mask := byte(0x80); first[0]&mask == 0; mask >>= 1 {
+		length++
+		if length > 8 {
+			return 0, 0, false
+		}
+	}
+	buf := make([]byte, length)
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return 0, 0, false
+	}
+	if !keepMarker {
+		buf[0] &^= 0x80 >> (length - 1)
+	}
+	var v uint64
+	allOnes := true
+	for i, b := range buf {
+		v = v<<8 | uint64(b)
+		full := byte(0xFF)
+		if i == 0 {
+			full = 0xFF >> length
+		}
+		if b != full {
+			allOnes = false
+		}
+	}
+	if !keepMarker && allOnes {
+		return -1, length, true
+	}
+	return int64(v), length, true
+}
@@ -0,0 +1,63 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func box(typ string, contents ...[]byte) []byte {
+	body := bytes.Join(contents, nil)
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(b, typ...), body...)
+}
+
+func testMP4(timescale, duration uint32) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], timescale)
+	binary.BigEndian.PutUint32(mvhd[16:], duration)
+	return bytes.Join([][]byte{
+		box("ftyp", []byte("mp42\x00\x00\x00\x00isom")),
+		box("mdat", make([]byte, 64)),
+		box("moov", box("mvhd", mvhd)),
+	}, nil)
+}
+
+func testWebM(seconds float64) []byte {
+	duration := binary.BigEndian.AppendUint64([]byte{0x44, 0x89, 0x88}, math.Float64bits(seconds*1000))
+	info := append([]byte{0x2A, 0xD7, 0xB1, 0x83, 0x0F, 0x42, 0x40}, duration...)
+	var b []byte
+	b = append(b, 0x1A, 0x45, 0xDF, 0xA3, 0x87, 0x42, 0x82, 0x84)
+	b = append(b, "webm"...)
+	// A segment of unknown size, as live encoders write.
+	b = append(b, 0x18, 0x53, 0x80, 0x67, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+	b = append(b, 0x15, 0x49, 0xA9, 0x66, 0x80|byte(len(info)))
+	return append(b, info...)
+}
+
+func TestCheckVideo(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		want        time.Duration
+		err         error
+	}{
+		{"mp4", testMP4(1000, 5000), "video/mp4", 5 * time.Second, nil},
+		{"long mp4", testMP4(600, 600*61), "video/mp4", 0, ErrTooLong},
+		{"webm", testWebM(12), "video/webm", 12 * time.Second, nil},
+		{"long webm", testWebM(90), "video/webm", 0, ErrTooLong},
+		{"webm sent as mp4", testWebM(12), "video/mp4", 0, ErrTypeMismatch},
+		{"quicktime", testMP4(1000, 5000), "video/quicktime", 0, ErrUnsupportedType},
+		{"no movie header", testMP4(1000, 5000)[:60], "video/mp4", 0, ErrTooLong},
+	}
+	for _, tt := range tests {
+		got, err := CheckVideo(bytes.NewReader(tt.data), int64(len(tt.data)), tt.contentType)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("%s: got %v, %v; want %v, %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package migrate applies goose-format SQL migrations. It records versions
+// in goose's own goose_db_version table, so databases migrated with the
+// goose CLI and with this package can be mixed freely.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const versionTable = "goose_db_version"
+
+// Migration is one numbered schema file.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load reads every NNN_name.sql file in fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(names))
+	seen := make(map[int64]string)
+	for _, name := range names {
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("migrate: %s: name must start with a version number", name)
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migrate: %s: name must start with a version number", name)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migrate: %s and %s share version %d", other, name, version)
+		}
+		seen[version] = name
+
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		up, down, err := Parse(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %s: %w", name, err)
+		}
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    path.Base(name),
+			Up:      up,
+			Down:    down,
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Parse splits a migration file into its Up and Down sections. Each
+// section runs as a single multi-statement Exec, so goose's
+// StatementBegin/StatementEnd markers need no special handling.
+func Parse(src string) (up, down string, err error) {
+	var upLines, downLines []string
+	var section *[]string
+	for _, line := range strings.Split(src, "\n") {
+		switch strings.TrimSpace(line) {
+		case "-- +goose Up":
+			section = &upLines
+			continue
+		case "-- +goose Down":
+			section = &downLines
+			continue
+		}
+		if section != nil {
+			*section = append(*section, line)
+		}
+	}
+	if upLines == nil {
+		return "", "", fmt.Errorf("missing -- +goose Up")
+	}
+	return strings.TrimSpace(strings.Join(upLines, "\n")), strings.TrimSpace(strings.Join(downLines, "\n")), nil
+}
+
+func ensureVersionTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS `+versionTable+` (
+    id SERIAL PRIMARY KEY,
+    version_id BIGINT NOT NULL,
+    is_applied BOOLEAN NOT NULL,
+    tstamp TIMESTAMP DEFAULT NOW()
+)`)
+	return err
+}
+
+// Applied returns the versions recorded as applied.
+func Applied(ctx context.Context, db *sql.DB) (map[int64]bool, error) {
+	if err := ensureVersionTable(ctx, db); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT version_id, is_applied FROM `+versionTable+` ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		if err := rows.Scan(&version, &isApplied); err != nil {
+			return nil, err
+		}
+		if version == 0 {
+			continue
+		}
+		if isApplied {
+			applied[version] = true
+		} else {
+			delete(applied, version)
+		}
+	}
+	return applied, rows.Err()
+}
+
+// Up applies every pending migration in order, each in its own
+// transaction, and returns the ones it applied.
+func Up(ctx context.Context, db *sql.DB, migrations []Migration) ([]Migration, error) {
+	applied, err := Applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		err := run(ctx, db, m.Up, `INSERT INTO `+versionTable+` (version_id, is_applied) VALUES ($1, TRUE)`, m.Version)
+		if err != nil {
+			return ran, fmt.Errorf("migrate: %s: %w", m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Down rolls back the most recently applied migration. It returns false
+// when nothing is applied.
+func Down(ctx context.Context, db *sql.DB, migrations []Migration) (Migration, bool, error) {
+	applied, err := Applied(ctx, db)
+	if err != nil {
+		return Migration{}, false, err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if !applied[m.Version] {
+			continue
+		}
+		err := run(ctx, db, m.Down, `DELETE FROM `+versionTable+` WHERE version_id = $1`, m.Version)
+		if err != nil {
+			return m, false, fmt.Errorf("migrate: %s: %w", m.Name, err)
+		}
+		return m, true, nil
+	}
+	return Migration{}, false, nil
+}
+
+func run(ctx context.Context, db *sql.DB, script, record string, version int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if script != "" {
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,83 @@
+package migrate
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParse(t *testing.T) {
+	up, down, err := Parse(`-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION f() RETURNS int AS $$ SELECT 1; $$ LANGUAGE sql;
+-- +goose StatementEnd
+CREATE TABLE t (id INT);
+
+-- +goose Down
+DROP TABLE t;
+`)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if !strings.HasPrefix(up, "-- +goose StatementBegin\nCREATE FUNCTION") || !strings.HasSuffix(up, "CREATE TABLE t (id INT);") {
+		t.Errorf("unexpected up section:\n%s", up)
+	}
+	if down != "DROP TABLE t;" {
+		t.Errorf("unexpected down section:\n%s", down)
+	}
+
+	if _, _, err := Parse("CREATE TABLE t (id INT);"); err == nil {
+		t.Error("expected an error without an Up marker")
+	}
+}
+
+func TestLoad_SortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"010_b.sql": {Data: []byte("-- +goose Up\nSELECT 10;")},
+		"002_a.sql": {Data: []byte("-- +goose Up\nSELECT 2;")},
+		"README.md": {Data: []byte("not a migration")},
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 2 || migrations[1].Version != 10 {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+	if migrations[1].Name != "010_b.sql" || migrations[1].Up != "SELECT 10;" {
+		t.Errorf("unexpected migration: %+v", migrations[1])
+	}
+}
+
+func TestLoad_Rejects(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"no version": {"users.sql": {Data: []byte("-- +goose Up\nSELECT 1;")}},
+		"duplicate": {
+			"001_a.sql": {Data: []byte("-- +goose Up\nSELECT 1;")},
+			"1_b.sql":   {Data: []byte("-- +goose Up\nSELECT 1;")},
+		},
+		"no up": {"001_a.sql": {Data: []byte("SELECT 1;")}},
+	}
+	for name, fsys := range tests {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoad_RepoSchema(t *testing.T) {
+	migrations, err := Load(os.DirFS("../../sql/schema"))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("expected version %d, got %s", i+1, m.Name)
+		}
+		if m.Down == "" {
+			t.Errorf("%s has no down section", m.Name)
+		}
+	}
+}
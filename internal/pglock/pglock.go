@@ -0,0 +1,58 @@
+// Package pglock provides locks shared by every server on a database,
+// built on Postgres session advisory locks.
+package pglock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"hash/fnv"
+)
+
+// Locker takes advisory locks on db.
+type Locker struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Locker {
+	return &Locker{db: db}
+}
+
+// Key is the advisory lock key for name.
+func Key(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// TryLock takes the lock called name without waiting. ok is false when
+// another session holds it. On success the lock is held by a connection
+// set aside from the pool until release is called. If the server dies
+// first, Postgres releases the lock when the connection drops.
+func (l *Locker) TryLock(ctx context.Context, name string) (release func(), ok bool, err error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	key := Key(name)
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !ok {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	release = func() {
+		// The caller's context may be done by now, and the lock must be
+		// released regardless.
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			// Closing a connection that still holds the lock could hand it
+			// back to the pool, so throw it away instead.
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+	return release, true, nil
+}
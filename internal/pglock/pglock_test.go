@@ -0,0 +1,12 @@
+package pglock
+
+import "testing"
+
+func TestKey(t *testing.T) {
+	if Key("job:retention") != Key("job:retention") {
+		t.Error("Key is not stable")
+	}
+	if Key("job:retention") == Key("job:digests") {
+		t.Error("different names share a key")
+	}
+}
@@ -0,0 +1,233 @@
+// Package scheduler runs periodic jobs inside the server process on
+// cron-like schedules.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Job is a task to run on a schedule.
+type Job struct {
+	Name string
+	// Spec is when the job runs, in the syntax Parse accepts, or Off.
+	Spec string
+	// Jitter delays each scheduled run by a random amount up to Jitter, so
+	// servers started together don't all run the job at once.
+	Jitter time.Duration
+	// RunAtStart runs the job as soon as the scheduler starts, as well as
+	// on its schedule.
+	RunAtStart bool
+	// PerInstance runs the job on every server rather than on whichever
+	// takes the lock first, for work whose result lives in memory.
+	PerInstance bool
+	Run         func(ctx context.Context) error
+}
+
+// Locker takes locks shared by every server, so that a job runs on only
+// one of them at a time.
+type Locker interface {
+	// TryLock takes the lock called name without waiting. ok is false when
+	// someone else holds it. release must be called once ok is true.
+	TryLock(ctx context.Context, name string) (release func(), ok bool, err error)
+}
+
+// Status is what a job last did and when it runs next.
+type Status struct {
+	Name    string
+	Spec    string
+	Running bool
+	Runs    int64
+	// Failures counts runs that returned an error or panicked.
+	Failures int64
+	// Skips counts runs that were due while another server held the job's
+	// lock.
+	Skips        int64
+	LastStarted  time.Time
+	LastDuration time.Duration
+	LastError    string
+	// NextRun is zero when the job is off or the scheduler isn't running.
+	NextRun time.Time
+}
+
+// Scheduler runs each of its jobs in its own goroutine. A job never runs
+// twice at once: a run that is due while the previous one is still going
+// waits for it to finish. With a Locker, that holds across servers too,
+// except that a run is skipped rather than delayed when another server is
+// running the job.
+type Scheduler struct {
+	now    func() time.Time
+	locker Locker
+
+	mu   sync.Mutex
+	jobs []*entry
+	// ctx is the context Start was called with, which triggered runs
+	// share.
+	ctx context.Context
+}
+
+type entry struct {
+	job      Job
+	schedule Schedule // nil when the job is off
+
+	// running is held for the length of a run.
+	running sync.Mutex
+
+	mu     sync.Mutex
+	status Status
+}
+
+// New returns a Scheduler that reads the time from now, or from the system
+// clock when now is nil. Without a locker, every server runs every job.
+func New(now func() time.Time, locker Locker) *Scheduler {
+	if now == nil {
+		now = time.Now
+	}
+	return &Scheduler{now: now, locker: locker}
+}
+
+// Add registers job. It fails on a malformed spec or a name already in use.
+func (s *Scheduler) Add(job Job) error {
+	e := &entry{job: job, status: Status{Name: job.Name, Spec: job.Spec}}
+	if job.Spec != Off {
+		schedule, err := Parse(job.Spec)
+		if err != nil {
+			return fmt.Errorf("job %s: %w", job.Name, err)
+		}
+		e.schedule = schedule
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, other := range s.jobs {
+		if other.job.Name == job.Name {
+			return fmt.Errorf("job %s is already registered", job.Name)
+		}
+	}
+	s.jobs = append(s.jobs, e)
+	return nil
+}
+
+// Start runs the registered jobs until ctx is done. Jobs added afterwards
+// don't run.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx = ctx
+	for _, e := range s.jobs {
+		if e.schedule != nil {
+			go s.loop(ctx, e)
+		}
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	if e.job.RunAtStart {
+		s.run(ctx, e)
+	}
+	for {
+		next := e.schedule.Next(s.now())
+		if next.IsZero() {
+			return
+		}
+		if e.job.Jitter > 0 {
+			next = next.Add(rand.N(e.job.Jitter))
+		}
+		e.mu.Lock()
+		e.status.NextRun = next
+		e.mu.Unlock()
+
+		timer := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.run(ctx, e)
+	}
+}
+
+// run runs the job once and records how it went. A panic counts as a
+// failure rather than taking the server down.
+func (s *Scheduler) run(ctx context.Context, e *entry) {
+	e.running.Lock()
+	defer e.running.Unlock()
+
+	if s.locker != nil && !e.job.PerInstance {
+		release, ok, err := s.locker.TryLock(ctx, "job:"+e.job.Name)
+		if err != nil {
+			fmt.Printf("Error locking job %s: %v\n", e.job.Name, err)
+		}
+		if !ok {
+			e.mu.Lock()
+			e.status.Skips++
+			e.mu.Unlock()
+			return
+		}
+		defer release()
+	}
+
+	start := s.now()
+	e.mu.Lock()
+	e.status.Running = true
+	e.status.LastStarted = start
+	e.mu.Unlock()
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return e.job.Run(ctx)
+	}()
+	if err != nil {
+		fmt.Printf("Error running job %s: %v\n", e.job.Name, err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status.Running = false
+	e.status.Runs++
+	e.status.LastDuration = s.now().Sub(start)
+	e.status.LastError = ""
+	if err != nil {
+		e.status.Failures++
+		e.status.LastError = err.Error()
+	}
+}
+
+// Trigger runs the named job now, in the background, whatever its
+// schedule. Like a scheduled run, it waits for a run already in progress.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx == nil {
+		return errors.New("scheduler is not running")
+	}
+	for _, e := range s.jobs {
+		if e.job.Name == name {
+			go s.run(s.ctx, e)
+			return nil
+		}
+	}
+	return fmt.Errorf("no job named %s", name)
+}
+
+// Status reports on every job in the order they were added.
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		e.mu.Lock()
+		statuses = append(statuses, e.status)
+		e.mu.Unlock()
+	}
+	return statuses
+}
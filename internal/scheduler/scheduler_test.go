@@ -0,0 +1,205 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParse_Cron(t *testing.T) {
+	from := time.Date(2025, time.June, 1, 12, 34, 56, 0, time.UTC) // a Sunday
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, time.June, 1, 12, 35, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, time.June, 1, 13, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, time.June, 2, 0, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, time.June, 1, 12, 45, 0, 0, time.UTC)},
+		{"30 3 * * 1-5", time.Date(2025, time.June, 2, 3, 30, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches.
+		{"0 9 15 * 3", time.Date(2025, time.June, 4, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2025, time.June, 8, 9, 0, 0, 0, time.UTC)},
+		{"5,10 12 * * *", time.Date(2025, time.June, 2, 12, 5, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		schedule, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.spec, err)
+			continue
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParse_Every(t *testing.T) {
+	schedule, err := Parse("@every 90m")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	from := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	if got := schedule.Next(from); !got.Equal(from.Add(90 * time.Minute)) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "@every", "@every 1ms", "@monthly", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "MON * * * *"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	s := New(nil, nil)
+	var runs, failures atomic.Int32
+	if err := s.Add(Job{Name: "tick", Spec: "@every 1s", RunAtStart: true, Run: func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Job{Name: "broken", Spec: "@every 1s", RunAtStart: true, Run: func(ctx context.Context) error {
+		if failures.Add(1) == 1 {
+			panic("boom")
+		}
+		return errors.New("still broken")
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Job{Name: "idle", Spec: Off, Run: func(ctx context.Context) error {
+		t.Error("a job that is off ran")
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Job{Name: "tick", Spec: "@daily"}); err == nil {
+		t.Error("expected an error adding a second job with the same name")
+	}
+	if err := s.Add(Job{Name: "bad", Spec: "whenever"}); err == nil {
+		t.Error("expected an error for a malformed spec")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runs.Load() < 2 || failures.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("jobs didn't run on schedule: %d runs, %d failures", runs.Load(), failures.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	statuses := s.Status()
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 jobs, got %+v", statuses)
+	}
+	broken := statuses[1]
+	if broken.Name != "broken" || broken.Failures < 2 || broken.LastError == "" || broken.NextRun.IsZero() {
+		t.Errorf("expected the broken job's failures to be recorded, got %+v", broken)
+	}
+	if idle := statuses[2]; idle.Runs != 0 || !idle.NextRun.IsZero() {
+		t.Errorf("expected the idle job never to be scheduled, got %+v", idle)
+	}
+}
+
+func TestScheduler_Trigger(t *testing.T) {
+	s := New(nil, nil)
+	ran := make(chan struct{})
+	if err := s.Add(Job{Name: "manual", Spec: Off, Run: func(ctx context.Context) error {
+		close(ran)
+		return nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Trigger("manual"); err == nil {
+		t.Error("expected an error triggering a job before Start")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	if err := s.Trigger("missing"); err == nil {
+		t.Error("expected an error triggering an unknown job")
+	}
+	if err := s.Trigger("manual"); err != nil {
+		t.Fatalf("Trigger returned error: %v", err)
+	}
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the triggered job didn't run")
+	}
+}
+
+// memLocker is a Locker shared by schedulers in one process.
+type memLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+func (l *memLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[name] {
+		return nil, false, nil
+	}
+	l.held[name] = true
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.held, name)
+	}, true, nil
+}
+
+func TestScheduler_Locker(t *testing.T) {
+	locker := &memLocker{held: make(map[string]bool)}
+	started, finish := make(chan struct{}), make(chan struct{})
+	var runs atomic.Int32
+	job := func(ctx context.Context) error {
+		if runs.Add(1) == 1 {
+			close(started)
+			<-finish
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first, second := New(nil, locker), New(nil, locker)
+	for _, s := range []*Scheduler{first, second} {
+		if err := s.Add(Job{Name: "singleton", Spec: Off, Run: job}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Add(Job{Name: "everywhere", Spec: Off, PerInstance: true, Run: func(ctx context.Context) error { return nil }}); err != nil {
+			t.Fatal(err)
+		}
+		s.Start(ctx)
+	}
+
+	if err := first.Trigger("singleton"); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	second.run(ctx, second.jobs[0])
+	second.run(ctx, second.jobs[1])
+	close(finish)
+
+	statuses := second.Status()
+	if statuses[0].Skips != 1 || statuses[0].Runs != 0 {
+		t.Errorf("expected the second server to skip the locked job, got %+v", statuses[0])
+	}
+	if statuses[1].Skips != 0 || statuses[1].Runs != 1 {
+		t.Errorf("expected a per-instance job to run regardless, got %+v", statuses[1])
+	}
+}
@@ -0,0 +1,180 @@
+// Package scim holds the SCIM 2.0 (RFC 7643/7644) wire types and the small
+// parts of the protocol Chirpy supports: equality filters on a user's
+// email and PATCH operations on "active".
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	ContentType = "application/scim+json"
+)
+
+type Email struct {
+	Value   string `json:"value"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location,omitempty"`
+}
+
+type User struct {
+	Schemas    []string `json:"schemas"`
+	ID         string   `json:"id,omitempty"`
+	ExternalID string   `json:"externalId,omitempty"`
+	UserName   string   `json:"userName"`
+	Active     *bool    `json:"active,omitempty"`
+	Emails     []Email  `json:"emails,omitempty"`
+	Password   string   `json:"password,omitempty"`
+	Meta       *Meta    `json:"meta,omitempty"`
+}
+
+// PrimaryEmail is the address Chirpy stores for the user: the primary
+// email, the first email, or the userName, in that order.
+func (u User) PrimaryEmail() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return u.UserName
+}
+
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []User   `json:"Resources"`
+}
+
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func NewError(status int, scimType, detail string) Error {
+	return Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+var ErrUnsupportedFilter = errors.New("unsupported filter")
+
+var filterPattern = regexp.MustCompile(`(?i)^\s*(username|emails\.value|emails)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// bracketFilter matches the emails[value eq "..."] form some identity
+// providers send.
+var bracketFilter = regexp.MustCompile(`(?i)^\s*emails\[\s*value\s+eq\s+"((?:[^"\\]|\\.)*)"\s*\]\s*$`)
+
+// ParseEmailFilter returns the address from a filter that matches users by
+// userName or email. Chirpy uses the email as the userName, so both forms
+// look up the same thing.
+func ParseEmailFilter(filter string) (string, error) {
+	var quoted string
+	if m := filterPattern.FindStringSubmatch(filter); m != nil {
+		quoted = m[2]
+	} else if m := bracketFilter.FindStringSubmatch(filter); m != nil {
+		quoted = m[1]
+	} else {
+		return "", ErrUnsupportedFilter
+	}
+
+	value, err := strconv.Unquote(`"` + quoted + `"`)
+	if err != nil {
+		return "", ErrUnsupportedFilter
+	}
+	return value, nil
+}
+
+type PatchOp struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Active returns the value the patch assigns to "active", and whether it
+// assigns one at all. It accepts both {"path":"active","value":false} and
+// {"value":{"active":false}}, with booleans or the strings "True"/"False"
+// that Azure AD sends. Operations on other attributes are an error.
+func (p PatchOp) Active() (active bool, ok bool, err error) {
+	for _, op := range p.Operations {
+		switch strings.ToLower(op.Op) {
+		case "replace", "add":
+		default:
+			return false, false, fmt.Errorf("unsupported op %q", op.Op)
+		}
+
+		var raw json.RawMessage
+		switch {
+		case strings.EqualFold(op.Path, "active"):
+			raw = op.Value
+		case op.Path == "":
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &fields); err != nil {
+				return false, false, fmt.Errorf("invalid value: %w", err)
+			}
+			for k, v := range fields {
+				if !strings.EqualFold(k, "active") {
+					return false, false, fmt.Errorf("unsupported attribute %q", k)
+				}
+				raw = v
+			}
+			if raw == nil {
+				continue
+			}
+		default:
+			return false, false, fmt.Errorf("unsupported path %q", op.Path)
+		}
+
+		active, err = parseBool(raw)
+		if err != nil {
+			return false, false, err
+		}
+		ok = true
+	}
+	return active, ok, nil
+}
+
+func parseBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, fmt.Errorf("active must be a boolean, got %s", raw)
+}
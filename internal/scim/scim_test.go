@@ -0,0 +1,76 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseEmailFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{`userName eq "ada@example.com"`, "ada@example.com"},
+		{`USERNAME EQ "ada@example.com"`, "ada@example.com"},
+		{`emails.value eq "ada@example.com"`, "ada@example.com"},
+		{`emails[value eq "ada@example.com"]`, "ada@example.com"},
+		{`userName eq "quote\"d@example.com"`, `quote"d@example.com`},
+	}
+	for _, tt := range tests {
+		got, err := ParseEmailFilter(tt.filter)
+		if err != nil {
+			t.Errorf("ParseEmailFilter(%q) returned error: %v", tt.filter, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseEmailFilter(%q) = %q, want %q", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestParseEmailFilter_Unsupported(t *testing.T) {
+	for _, filter := range []string{
+		`displayName eq "Ada"`,
+		`userName sw "ada"`,
+		`userName eq "a" and active eq true`,
+	} {
+		if _, err := ParseEmailFilter(filter); !errors.Is(err, ErrUnsupportedFilter) {
+			t.Errorf("ParseEmailFilter(%q): expected ErrUnsupportedFilter, got %v", filter, err)
+		}
+	}
+}
+
+func TestPatchOp_Active(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"path form", `{"Operations":[{"op":"replace","path":"active","value":false}]}`, false},
+		{"value form", `{"Operations":[{"op":"replace","value":{"active":true}}]}`, true},
+		{"azure strings", `{"Operations":[{"op":"Replace","path":"active","value":"False"}]}`, false},
+	}
+	for _, tt := range tests {
+		var p PatchOp
+		if err := json.Unmarshal([]byte(tt.body), &p); err != nil {
+			t.Fatal(err)
+		}
+		active, ok, err := p.Active()
+		if err != nil || !ok {
+			t.Errorf("%s: Active() = %v, %v, %v", tt.name, active, ok, err)
+			continue
+		}
+		if active != tt.want {
+			t.Errorf("%s: expected active=%v", tt.name, tt.want)
+		}
+	}
+}
+
+func TestPatchOp_ActiveRejectsOtherAttributes(t *testing.T) {
+	var p PatchOp
+	json.Unmarshal([]byte(`{"Operations":[{"op":"replace","path":"displayName","value":"Ada"}]}`), &p)
+	if _, _, err := p.Active(); err == nil {
+		t.Fatal("expected an error for an unsupported path")
+	}
+}
@@ -0,0 +1,47 @@
+// Package shortlink derives short codes, such as 4Gq0cXb1Lzs, from
+// random UUIDs. A code is the UUID's first eight bytes in base 62, so it
+// needs no storage: the UUIDs it may stand for are one contiguous range.
+package shortlink
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// codeLen is enough base 62 digits for any uint64.
+const codeLen = 11
+
+// Encode returns the code for id.
+func Encode(id uuid.UUID) string {
+	n := binary.BigEndian.Uint64(id[:8])
+	var b [codeLen]byte
+	for i := codeLen - 1; i >= 0; i-- {
+		b[i] = alphabet[n%62]
+		n /= 62
+	}
+	return string(b[:])
+}
+
+// Decode returns the lowest and highest UUIDs code may stand for. ok is
+// false if code was not made by Encode.
+func Decode(code string) (low, high uuid.UUID, ok bool) {
+	if len(code) != codeLen {
+		return uuid.Nil, uuid.Nil, false
+	}
+	var n uint64
+	for i := 0; i < len(code); i++ {
+		d := strings.IndexByte(alphabet, code[i])
+		if d < 0 || n > (1<<64-1-uint64(d))/62 {
+			return uuid.Nil, uuid.Nil, false
+		}
+		n = n*62 + uint64(d)
+	}
+	binary.BigEndian.PutUint64(low[:8], n)
+	binary.BigEndian.PutUint64(high[:8], n)
+	binary.BigEndian.PutUint64(high[8:], 1<<64-1)
+	return low, high, true
+}
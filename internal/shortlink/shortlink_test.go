@@ -0,0 +1,37 @@
+package shortlink
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestEncode_RoundTrip(t *testing.T) {
+	for _, id := range []uuid.UUID{
+		uuid.Nil,
+		uuid.Max,
+		uuid.MustParse("0123456789ab4def8123456789abcdef"),
+		uuid.New(),
+	} {
+		code := Encode(id)
+		low, high, ok := Decode(code)
+		if !ok {
+			t.Fatalf("Decode(%q) failed", code)
+		}
+		if bytes.Compare(low[:], id[:]) > 0 || bytes.Compare(id[:], high[:]) > 0 {
+			t.Errorf("%s: %s is outside %s..%s", code, id, low, high)
+		}
+	}
+	if got := Encode(uuid.Max); got != "LygHa16AHYF" {
+		t.Errorf("Encode(Max) = %q", got)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	for _, code := range []string{"", "Ab3xY", "LygHa16AHY-", "LygHa16AHYG", "zzzzzzzzzzz", "LygHa16AHYF0"} {
+		if _, _, ok := Decode(code); ok {
+			t.Errorf("Decode(%q) succeeded", code)
+		}
+	}
+}
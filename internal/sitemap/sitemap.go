@@ -0,0 +1,98 @@
+// Package sitemap renders sitemaps.org XML sitemaps and sitemap indexes.
+package sitemap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// MaxURLs is the most URLs the protocol allows in one sitemap file.
+const MaxURLs = 50000
+
+const xmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type urlEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type urlSet struct {
+	XMLName xml.Name   `xml:"urlset"`
+	Xmlns   string     `xml:"xmlns,attr"`
+	URLs    []urlEntry `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name   `xml:"sitemapindex"`
+	Xmlns    string     `xml:"xmlns,attr"`
+	Sitemaps []urlEntry `xml:"sitemap"`
+}
+
+// Set is a rendered sitemap index and the pages it points to.
+type Set struct {
+	Index []byte
+	Pages [][]byte
+}
+
+// Build splits urls into pages of at most pageSize and renders them with an
+// index. pageURL returns the absolute URL of page n, counting from 1.
+func Build(urls []URL, pageSize int, pageURL func(n int) string) (Set, error) {
+	if pageSize <= 0 || pageSize > MaxURLs {
+		pageSize = MaxURLs
+	}
+
+	var set Set
+	index := sitemapIndex{Xmlns: xmlns, Sitemaps: []urlEntry{}}
+	// An empty site still gets one (empty) page so the index is valid.
+	pages := max(1, (len(urls)+pageSize-1)/pageSize)
+	for n := range pages {
+		start := n * pageSize
+		end := min(start+pageSize, len(urls))
+		page := urlSet{Xmlns: xmlns, URLs: make([]urlEntry, 0, end-start)}
+		var newest time.Time
+		for _, u := range urls[start:end] {
+			page.URLs = append(page.URLs, urlEntry{Loc: u.Loc, LastMod: formatTime(u.LastMod)})
+			if u.LastMod.After(newest) {
+				newest = u.LastMod
+			}
+		}
+
+		b, err := render(page)
+		if err != nil {
+			return Set{}, fmt.Errorf("rendering sitemap page: %w", err)
+		}
+		set.Pages = append(set.Pages, b)
+		index.Sitemaps = append(index.Sitemaps, urlEntry{
+			Loc:     pageURL(len(set.Pages)),
+			LastMod: formatTime(newest),
+		})
+	}
+
+	b, err := render(index)
+	if err != nil {
+		return Set{}, fmt.Errorf("rendering sitemap index: %w", err)
+	}
+	set.Index = b
+	return set, nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func render(v interface{}) ([]byte, error) {
+	b, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
@@ -0,0 +1,54 @@
+package sitemap
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func pageURL(n int) string {
+	return "https://chirpy.example/sitemaps/" + strconv.Itoa(n) + ".xml"
+}
+
+func TestBuild_Paginates(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	urls := []URL{
+		{Loc: "https://chirpy.example/a", LastMod: older},
+		{Loc: "https://chirpy.example/b", LastMod: newer},
+		{Loc: "https://chirpy.example/c?x=1&y=2", LastMod: older},
+	}
+
+	set, err := Build(urls, 2, pageURL)
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if len(set.Pages) != 2 {
+		t.Fatalf("expected 2 pages, got %d", len(set.Pages))
+	}
+
+	index := string(set.Index)
+	if !strings.Contains(index, "<loc>https://chirpy.example/sitemaps/2.xml</loc>") {
+		t.Errorf("index is missing page 2:\n%s", index)
+	}
+	if !strings.Contains(index, "<lastmod>2024-02-01T00:00:00Z</lastmod>") {
+		t.Errorf("index should use the newest lastmod of page 1:\n%s", index)
+	}
+	if !strings.Contains(string(set.Pages[1]), "c?x=1&amp;y=2") {
+		t.Errorf("expected escaped URL in page 2:\n%s", set.Pages[1])
+	}
+}
+
+func TestBuild_Empty(t *testing.T) {
+	set, err := Build(nil, 0, pageURL)
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if len(set.Pages) != 1 {
+		t.Fatalf("expected a single empty page, got %d", len(set.Pages))
+	}
+	if !strings.Contains(string(set.Pages[0]), "<urlset") {
+		t.Errorf("expected an urlset, got:\n%s", set.Pages[0])
+	}
+}
@@ -0,0 +1,70 @@
+// Package storage keeps uploaded files, on local disk or in an
+// S3-compatible bucket, under flat keys chosen by the caller.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned by Get for keys with nothing stored.
+	ErrNotFound = errors.New("storage: object not found")
+	// ErrNoSignedURL is returned by SignedURL from backends that can't
+	// hand files out directly, whose files must be served through Get.
+	ErrNoSignedURL = errors.New("storage: backend has no signed URLs")
+)
+
+// Storage stores files by key.
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing anything
+	// there.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens what is stored under key. The reader is an io.ReadSeeker
+	// when the backend can seek.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a link that fetches key straight from the backend
+	// until expiry has passed.
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// Config selects and configures the backend.
+type Config struct {
+	// Backend is "local" or "s3". Storage is off when it is empty.
+	Backend string `json:"backend"`
+	// Dir is where the local backend keeps files. It is created if it
+	// doesn't exist.
+	Dir string `json:"dir"`
+	// S3 configures the s3 backend.
+	S3 S3Config `json:"s3"`
+}
+
+// New builds the Storage described by cfg, or returns nil when storage is
+// off.
+func New(cfg Config) (Storage, error) {
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case "local":
+		if cfg.Dir == "" {
+			return nil, errors.New("storage: the local backend needs a directory")
+		}
+		if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+			return nil, err
+		}
+		return Local{Dir: cfg.Dir}, nil
+	case "s3":
+		if cfg.S3.Endpoint == "" || cfg.S3.Bucket == "" {
+			return nil, errors.New("storage: the s3 backend needs an endpoint and a bucket")
+		}
+		return NewS3(cfg.S3), nil
+	default:
+		return nil, fmt.Errorf("storage: unknown backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,319 @@
+// Package stripe is the little of Stripe's API that Chirpy Red billing
+// needs: creating Checkout sessions and verifying webhook events.
+package stripe
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is Stripe's API, used when Config.APIURL is empty.
+const DefaultAPIURL = "https://api.stripe.com"
+
+// DefaultWebhookTolerance is how old a webhook's signature may be, so a
+// captured request can't be replayed later.
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// Config configures billing through Stripe. Billing is off when SecretKey
+// is empty.
+type Config struct {
+	SecretKey string `json:"-"`
+	// WebhookSecret is the signing secret of the webhook endpoint.
+	WebhookSecret string `json:"-"`
+	// PriceID is the recurring price of Chirpy Red.
+	PriceID string `json:"price_id"`
+	// APIURL defaults to DefaultAPIURL.
+	APIURL string `json:"api_url"`
+	// SuccessURL and CancelURL are where Checkout sends the user after
+	// paying or giving up. They default to the frontend under PUBLIC_URL.
+	SuccessURL string `json:"success_url"`
+	CancelURL  string `json:"cancel_url"`
+}
+
+// Validate reports settings billing can't work without.
+func (c Config) Validate() error {
+	if c.SecretKey == "" {
+		return nil
+	}
+	if c.WebhookSecret == "" || c.PriceID == "" {
+		return errors.New("STRIPE_WEBHOOK_SECRET and STRIPE_PRICE_ID are required with STRIPE_SECRET_KEY")
+	}
+	if c.SuccessURL == "" || c.CancelURL == "" {
+		return errors.New("STRIPE_SUCCESS_URL and STRIPE_CANCEL_URL, or PUBLIC_URL, are required with STRIPE_SECRET_KEY")
+	}
+	return nil
+}
+
+// Client calls Stripe's API.
+type Client struct {
+	URL    string
+	Key    string
+	Client *http.Client
+}
+
+// New returns a Client for cfg, or nil when billing is off.
+func New(cfg Config) *Client {
+	if cfg.SecretKey == "" {
+		return nil
+	}
+	endpoint := cfg.APIURL
+	if endpoint == "" {
+		endpoint = DefaultAPIURL
+	}
+	return &Client{
+		URL:    strings.TrimSuffix(endpoint, "/"),
+		Key:    cfg.SecretKey,
+		Client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Error is an error response from Stripe.
+type Error struct {
+	Status  int
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("stripe: %d %s: %s", e.Status, e.Type, e.Message)
+}
+
+// post sends form to path and decodes the response into out.
+func (c *Client) post(ctx context.Context, path string, form url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.Key, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("stripe: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		var body struct {
+			Error Error `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return fmt.Errorf("stripe: %s", resp.Status)
+		}
+		body.Error.Status = resp.StatusCode
+		return &body.Error
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("stripe: decoding response: %w", err)
+	}
+	return nil
+}
+
+// CheckoutParams describes a Checkout session for a subscription.
+type CheckoutParams struct {
+	PriceID string
+	// CustomerID is the user's existing customer, if they have one.
+	// Otherwise Checkout creates one with CustomerEmail.
+	CustomerID    string
+	CustomerEmail string
+	// UserID is kept as the session's client_reference_id and in the
+	// subscription's metadata, so webhooks can tell whose it is.
+	UserID     string
+	SuccessURL string
+	CancelURL  string
+}
+
+// CheckoutSession is a Checkout session, or the object of a
+// checkout.session.completed event.
+type CheckoutSession struct {
+	ID                string `json:"id"`
+	URL               string `json:"url"`
+	Customer          string `json:"customer"`
+	Subscription      string `json:"subscription"`
+	ClientReferenceID string `json:"client_reference_id"`
+}
+
+// CreateCheckoutSession starts a Checkout session subscribing to p.PriceID.
+// The user pays at the session's URL.
+func (c *Client) CreateCheckoutSession(ctx context.Context, p CheckoutParams) (CheckoutSession, error) {
+	form := url.Values{
+		"mode":                                 {"subscription"},
+		"line_items[0][price]":                 {p.PriceID},
+		"line_items[0][quantity]":              {"1"},
+		"success_url":                          {p.SuccessURL},
+		"cancel_url":                           {p.CancelURL},
+		"client_reference_id":                  {p.UserID},
+		"subscription_data[metadata][user_id]": {p.UserID},
+	}
+	if p.CustomerID != "" {
+		form.Set("customer", p.CustomerID)
+	} else if p.CustomerEmail != "" {
+		form.Set("customer_email", p.CustomerEmail)
+	}
+	var session CheckoutSession
+	err := c.post(ctx, "/v1/checkout/sessions", form, &session)
+	return session, err
+}
+
+// Subscription is the object of customer.subscription.* events.
+type Subscription struct {
+	ID                string            `json:"id"`
+	Customer          string            `json:"customer"`
+	Status            string            `json:"status"`
+	Metadata          map[string]string `json:"metadata"`
+	CancelAtPeriodEnd bool              `json:"cancel_at_period_end"`
+	CurrentPeriodEnd  int64             `json:"current_period_end"`
+	TrialEnd          int64             `json:"trial_end"`
+	CanceledAt        int64             `json:"canceled_at"`
+	// EndedAt is when the subscription actually ended, which for one
+	// cancelled at period end is after CanceledAt.
+	EndedAt int64 `json:"ended_at"`
+	Created int64 `json:"created"`
+	Items   struct {
+		Data []SubscriptionItem `json:"data"`
+	} `json:"items"`
+}
+
+// SubscriptionItem is one price a subscription pays for.
+type SubscriptionItem struct {
+	Quantity int64 `json:"quantity"`
+	Price    struct {
+		ID string `json:"id"`
+		// UnitAmount is in the smallest unit of the currency.
+		UnitAmount int64 `json:"unit_amount"`
+		Recurring  struct {
+			Interval      string `json:"interval"`
+			IntervalCount int64  `json:"interval_count"`
+		} `json:"recurring"`
+	} `json:"price"`
+}
+
+// Entitled reports whether the subscription should give its user Chirpy
+// Red. Past-due subscriptions still do while Stripe retries the payment.
+func (s Subscription) Entitled() bool {
+	switch s.Status {
+	case "active", "trialing", "past_due":
+		return true
+	}
+	return false
+}
+
+// PriceID is the price of the subscription's first item.
+func (s Subscription) PriceID() string {
+	if len(s.Items.Data) == 0 {
+		return ""
+	}
+	return s.Items.Data[0].Price.ID
+}
+
+// SetCancelAtPeriodEnd schedules the subscription to end when its current
+// period does, or with cancel false takes that back.
+func (c *Client) SetCancelAtPeriodEnd(ctx context.Context, subscriptionID string, cancel bool) (Subscription, error) {
+	form := url.Values{"cancel_at_period_end": {strconv.FormatBool(cancel)}}
+	var sub Subscription
+	err := c.post(ctx, "/v1/subscriptions/"+url.PathEscape(subscriptionID), form, &sub)
+	return sub, err
+}
+
+// MonthlyAmount is what the subscription brings in a month, in the
+// smallest unit of its currency. Prices billed by the day, week or year
+// are spread evenly over months.
+func (s Subscription) MonthlyAmount() int64 {
+	var total float64
+	for _, item := range s.Items.Data {
+		amount := float64(item.Price.UnitAmount * max(item.Quantity, 1))
+		count := float64(max(item.Price.Recurring.IntervalCount, 1))
+		switch item.Price.Recurring.Interval {
+		case "day":
+			total += amount * 365 / 12 / count
+		case "week":
+			total += amount * 52 / 12 / count
+		case "month":
+			total += amount / count
+		case "year":
+			total += amount / 12 / count
+		}
+	}
+	return int64(math.Round(total))
+}
+
+// Event is a webhook event. Data.Object is decoded according to Type.
+type Event struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// ErrInvalidSignature is returned for webhooks whose Stripe-Signature
+// doesn't check out.
+var ErrInvalidSignature = errors.New("stripe: invalid webhook signature")
+
+// ConstructEvent verifies the Stripe-Signature header of a webhook against
+// secret and decodes the payload. Signatures older than tolerance at now
+// are refused.
+func ConstructEvent(payload []byte, header, secret string, now time.Time, tolerance time.Duration) (Event, error) {
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			t, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return Event{}, ErrInvalidSignature
+			}
+			timestamp = t
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return Event{}, ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return Event{}, ErrInvalidSignature
+	}
+
+	expected := Sign(payload, secret, timestamp)
+	valid := false
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			valid = true
+		}
+	}
+	if !valid {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return Event{}, fmt.Errorf("stripe: decoding event: %w", err)
+	}
+	return event, nil
+}
+
+// Sign is the v1 signature of payload sent at timestamp, as Stripe
+// computes it.
+func Sign(payload []byte, secret string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
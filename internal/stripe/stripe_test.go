@@ -0,0 +1,139 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestConstructEvent(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	payload := []byte(`{"id":"evt_1","type":"customer.subscription.updated","created":1760616000,"data":{"object":{"id":"sub_1"}}}`)
+	header := func(ts time.Time, sig string) string {
+		return fmt.Sprintf("t=%d,v1=%s", ts.Unix(), sig)
+	}
+	good := Sign(payload, "whsec_test", now.Unix())
+
+	event, err := ConstructEvent(payload, header(now, good), "whsec_test", now, DefaultWebhookTolerance)
+	if err != nil {
+		t.Fatalf("ConstructEvent returned error: %v", err)
+	}
+	if event.ID != "evt_1" || event.Type != "customer.subscription.updated" || string(event.Data.Object) != `{"id":"sub_1"}` {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	// Stripe sends one v1 signature per active secret while one is rolled.
+	both := fmt.Sprintf("t=%d,v1=%s,v1=%s", now.Unix(), Sign(payload, "whsec_old", now.Unix()), good)
+	if _, err := ConstructEvent(payload, both, "whsec_test", now, DefaultWebhookTolerance); err != nil {
+		t.Fatalf("expected any matching signature to do, got %v", err)
+	}
+
+	invalid := map[string]string{
+		"wrong secret": header(now, Sign(payload, "whsec_other", now.Unix())),
+		"stale":        header(now.Add(-10*time.Minute), Sign(payload, "whsec_test", now.Add(-10*time.Minute).Unix())),
+		"no signature": fmt.Sprintf("t=%d", now.Unix()),
+		"empty":        "",
+		"tampered":     header(now, Sign([]byte(`{"id":"evt_2"}`), "whsec_test", now.Unix())),
+	}
+	for name, h := range invalid {
+		if _, err := ConstructEvent(payload, h, "whsec_test", now, DefaultWebhookTolerance); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", name, err)
+		}
+	}
+}
+
+func TestCreateCheckoutSession(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, _, _ := r.BasicAuth(); key != "sk_test" || r.URL.Path != "/v1/checkout/sessions" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":{"type":"invalid_request_error","message":"Invalid API Key"}}`)
+			return
+		}
+		r.ParseForm()
+		if r.PostForm.Get("mode") != "subscription" || r.PostForm.Get("line_items[0][price]") != "price_red" ||
+			r.PostForm.Get("customer") != "cus_1" || r.PostForm.Get("subscription_data[metadata][user_id]") != "user-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error":{"type":"invalid_request_error","message":"unexpected form %v"}}`, r.PostForm)
+			return
+		}
+		fmt.Fprint(w, `{"id":"cs_1","url":"https://checkout.stripe.com/c/cs_1"}`)
+	}))
+	defer srv.Close()
+
+	c := New(Config{SecretKey: "sk_test", APIURL: srv.URL})
+	params := CheckoutParams{PriceID: "price_red", CustomerID: "cus_1", UserID: "user-1"}
+	session, err := c.CreateCheckoutSession(context.Background(), params)
+	if err != nil {
+		t.Fatalf("CreateCheckoutSession returned error: %v", err)
+	}
+	if session.ID != "cs_1" || session.URL != "https://checkout.stripe.com/c/cs_1" {
+		t.Fatalf("unexpected session %+v", session)
+	}
+
+	c.Key = "sk_wrong"
+	var stripeErr *Error
+	if _, err := c.CreateCheckoutSession(context.Background(), params); !errors.As(err, &stripeErr) || stripeErr.Status != http.StatusUnauthorized {
+		t.Fatalf("expected a 401 Error, got %v", err)
+	}
+}
+
+func TestSetCancelAtPeriodEnd(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.URL.Path != "/v1/subscriptions/sub_1" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"type":"invalid_request_error","message":"No such subscription"}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"id":"sub_1","status":"active","cancel_at_period_end":%s}`, r.PostForm.Get("cancel_at_period_end"))
+	}))
+	defer srv.Close()
+
+	c := New(Config{SecretKey: "sk_test", APIURL: srv.URL})
+	for _, cancel := range []bool{true, false} {
+		sub, err := c.SetCancelAtPeriodEnd(context.Background(), "sub_1", cancel)
+		if err != nil {
+			t.Fatalf("SetCancelAtPeriodEnd returned error: %v", err)
+		}
+		if sub.CancelAtPeriodEnd != cancel || !sub.Entitled() {
+			t.Fatalf("unexpected subscription %+v", sub)
+		}
+	}
+	var stripeErr *Error
+	if _, err := c.SetCancelAtPeriodEnd(context.Background(), "sub_2", true); !errors.As(err, &stripeErr) || stripeErr.Status != http.StatusNotFound {
+		t.Fatalf("expected a 404 Error, got %v", err)
+	}
+}
+
+func TestMonthlyAmount(t *testing.T) {
+	item := func(amount, quantity int64, interval string, count int64) SubscriptionItem {
+		var i SubscriptionItem
+		i.Quantity = quantity
+		i.Price.UnitAmount = amount
+		i.Price.Recurring.Interval = interval
+		i.Price.Recurring.IntervalCount = count
+		return i
+	}
+	tests := map[string]struct {
+		items []SubscriptionItem
+		want  int64
+	}{
+		"monthly":   {[]SubscriptionItem{item(499, 1, "month", 1)}, 499},
+		"yearly":    {[]SubscriptionItem{item(4800, 1, "year", 1)}, 400},
+		"quarterly": {[]SubscriptionItem{item(1200, 1, "month", 3)}, 400},
+		"weekly":    {[]SubscriptionItem{item(300, 1, "week", 1)}, 1300},
+		"seats":     {[]SubscriptionItem{item(499, 3, "month", 1)}, 1497},
+		"none":      {nil, 0},
+	}
+	for name, tt := range tests {
+		var sub Subscription
+		sub.Items.Data = tt.items
+		if got := sub.MonthlyAmount(); got != tt.want {
+			t.Errorf("%s: got %d, want %d", name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Setting is the Postgres setting the row-level security policies read:
+// a tenant's ID, "default" for the default community, or empty to see
+// every tenant.
+const Setting = "chirpy.tenant_id"
+
+const (
+	// maxConnsPerTenant keeps one busy tenant from taking the whole
+	// database.
+	maxConnsPerTenant = 10
+	maxIdlePerTenant  = 2
+	tenantIdleTimeout = 5 * time.Minute
+)
+
+// Pools keeps a connection pool per tenant, each opened with Setting
+// already set, so nothing run on its connections can see another tenant's
+// rows. It is a database.DBTX choosing the pool from the context.
+type Pools struct {
+	base *sql.DB
+	dsn  string
+
+	mu    sync.Mutex
+	pools map[string]*sql.DB
+}
+
+// NewPools serves work without a tenant from base, and opens pools for
+// tenants with dsn, which must be the connection string base was opened
+// with.
+func NewPools(base *sql.DB, dsn string) (*Pools, error) {
+	if _, err := withSetting(dsn, "default"); err != nil {
+		return nil, err
+	}
+	return &Pools{base: base, dsn: dsn, pools: map[string]*sql.DB{}}, nil
+}
+
+// withSetting adds Setting to the startup options of the Postgres
+// connection string dsn.
+func withSetting(dsn, value string) (string, error) {
+	option := "-c " + Setting + "=" + value
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+		q := u.Query()
+		q.Set("options", strings.TrimSpace(q.Get("options")+" "+option))
+		u.RawQuery = q.Encode()
+		dsn = u.String()
+	} else {
+		for _, field := range strings.Fields(dsn) {
+			if strings.HasPrefix(field, "options=") {
+				return "", errors.New("tenant: DB_URL can't set options in multi-tenant mode unless it is a URL")
+			}
+		}
+		dsn += " options='" + option + "'"
+	}
+	if _, err := pq.NewConnector(dsn); err != nil {
+		return "", err
+	}
+	return dsn, nil
+}
+
+// DB returns the pool for ctx's tenant, or the base pool when ctx has
+// none.
+func (p *Pools) DB(ctx context.Context) *sql.DB {
+	t, ok := FromContext(ctx)
+	if !ok {
+		return p.base
+	}
+	key := t.Key()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if db, ok := p.pools[key]; ok {
+		return db
+	}
+	dsn, err := withSetting(p.dsn, key)
+	if err != nil {
+		// NewPools checked the connection string, and keys are IDs.
+		panic(err)
+	}
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		panic(err)
+	}
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(maxConnsPerTenant)
+	db.SetMaxIdleConns(maxIdlePerTenant)
+	db.SetConnMaxIdleTime(tenantIdleTimeout)
+	p.pools[key] = db
+	return db
+}
+
+func (p *Pools) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.DB(ctx).ExecContext(ctx, query, args...)
+}
+
+func (p *Pools) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.DB(ctx).PrepareContext(ctx, query)
+}
+
+func (p *Pools) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.DB(ctx).QueryContext(ctx, query, args...)
+}
+
+func (p *Pools) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.DB(ctx).QueryRowContext(ctx, query, args...)
+}
+
+// Close closes the tenants' pools, leaving the base pool to its owner.
+func (p *Pools) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var errs []error
+	for key, db := range p.pools {
+		errs = append(errs, db.Close())
+		delete(p.pools, key)
+	}
+	return errors.Join(errs...)
+}
+
+// CheckRowSecurity fails if db's role bypasses row-level security, as
+// superusers and table owners granted BYPASSRLS do, which would let every
+// tenant see every other's rows.
+func CheckRowSecurity(ctx context.Context, db *sql.DB) error {
+	var bypass bool
+	err := db.QueryRowContext(ctx, `SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user`).Scan(&bypass)
+	if err != nil {
+		return err
+	}
+	if bypass {
+		return errors.New("tenant: the database role bypasses row-level security; connect as an ordinary role in multi-tenant mode")
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+// Package tenant hosts several Chirpy communities from one deployment.
+// Each is a row in the tenants table, reached on its own hostname or under
+// /t/{slug}/ on the main one; everything else is the default community.
+//
+// The tenant-scoped tables carry a tenant_id, and Postgres row-level
+// security shows a connection only the rows of the tenant it was opened
+// for, so a query that forgets to filter still can't see another
+// community. Pools opens those connections.
+package tenant
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// PathPrefix is where tenants without a hostname of their own are served.
+const PathPrefix = "/t/"
+
+// Tenant is one community.
+type Tenant struct {
+	// ID is uuid.Nil for the default community, whose rows have no
+	// tenant_id.
+	ID       uuid.UUID
+	Slug     string
+	Hostname string
+	Name     string
+	Config   Config
+}
+
+// Config overrides the deployment's settings for one tenant. Zero values
+// keep the deployment's.
+type Config struct {
+	LogoURL     string `json:"logo_url,omitempty"`
+	AccentColor string `json:"accent_color,omitempty"`
+	// MaxChirpLength and MaxRedChirpLength replace the plan limits for
+	// members without and with Chirpy Red.
+	MaxChirpLength    int `json:"max_chirp_length,omitempty"`
+	MaxRedChirpLength int `json:"max_red_chirp_length,omitempty"`
+}
+
+// IsDefault reports whether t is the default community.
+func (t Tenant) IsDefault() bool {
+	return t.ID == uuid.Nil
+}
+
+// Key names t's rows to Postgres, and keeps its entries apart in caches.
+func (t Tenant) Key() string {
+	if t.IsDefault() {
+		return "default"
+	}
+	return t.ID.String()
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying t. Queries made with it see only t's
+// rows.
+func NewContext(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// WithoutTenant returns ctx carrying no tenant, for work on behalf of the
+// deployment that arrived on a tenant's request, like a payment provider's
+// webhook. Queries made with it see every tenant's rows, so it must only
+// be used with queries keyed by an ID that already names the row.
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, nil)
+}
+
+// FromContext returns the tenant ctx carries. ok is false for work not
+// done on behalf of a tenant, such as the background jobs, which see
+// every tenant's rows.
+func FromContext(ctx context.Context) (t Tenant, ok bool) {
+	t, ok = ctx.Value(contextKey{}).(Tenant)
+	return t, ok
+}
+
+// Set is the tenants as loaded from the database. It is never modified,
+// so it can be shared between requests.
+type Set struct {
+	byID   map[uuid.UUID]Tenant
+	byHost map[string]Tenant
+	bySlug map[string]Tenant
+}
+
+func NewSet(tenants []Tenant) *Set {
+	s := &Set{
+		byID:   make(map[uuid.UUID]Tenant, len(tenants)),
+		byHost: make(map[string]Tenant, len(tenants)),
+		bySlug: make(map[string]Tenant, len(tenants)),
+	}
+	for _, t := range tenants {
+		s.byID[t.ID] = t
+		s.bySlug[t.Slug] = t
+		if t.Hostname != "" {
+			s.byHost[strings.ToLower(t.Hostname)] = t
+		}
+	}
+	return s
+}
+
+// ByID returns the tenant with id. A nil Set has no tenants.
+func (s *Set) ByID(id uuid.UUID) (Tenant, bool) {
+	if s == nil {
+		return Tenant{}, false
+	}
+	t, ok := s.byID[id]
+	return t, ok
+}
+
+// All returns every tenant, not counting the default community.
+func (s *Set) All() []Tenant {
+	if s == nil {
+		return nil
+	}
+	all := make([]Tenant, 0, len(s.byID))
+	for _, t := range s.byID {
+		all = append(all, t)
+	}
+	return all
+}
+
+// Resolve picks the tenant for a request to host and path: the one with
+// that hostname, else the one named by a /t/{slug}/ prefix, which is
+// returned so the caller can strip it. Requests matching neither belong to
+// the default community. ok is false when the prefix names no tenant.
+func (s *Set) Resolve(host, path string) (t Tenant, prefix string, ok bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if s != nil {
+		if t, ok := s.byHost[strings.ToLower(host)]; ok {
+			return t, "", true
+		}
+	}
+	rest, found := strings.CutPrefix(path, PathPrefix)
+	if !found {
+		return Tenant{}, "", true
+	}
+	slug, _, _ := strings.Cut(rest, "/")
+	if s == nil {
+		return Tenant{}, "", false
+	}
+	t, ok = s.bySlug[slug]
+	if !ok {
+		return Tenant{}, "", false
+	}
+	return t, PathPrefix + slug, true
+}
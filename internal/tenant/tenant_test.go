@@ -0,0 +1,70 @@
+package tenant
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestResolve(t *testing.T) {
+	acme := Tenant{ID: uuid.New(), Slug: "acme", Hostname: "chirps.acme.example"}
+	birds := Tenant{ID: uuid.New(), Slug: "birds"}
+	set := NewSet([]Tenant{acme, birds})
+
+	for _, tc := range []struct {
+		host, path string
+		want       Tenant
+		prefix     string
+		ok         bool
+	}{
+		{"chirps.acme.example", "/api/chirps", acme, "", true},
+		{"Chirps.Acme.Example:8080", "/api/chirps", acme, "", true},
+		{"chirpy.example", "/t/birds/api/chirps", birds, "/t/birds", true},
+		{"chirpy.example", "/t/birds", birds, "/t/birds", true},
+		{"chirpy.example", "/t/acme/app/", acme, "/t/acme", true},
+		{"chirpy.example", "/api/chirps", Tenant{}, "", true},
+		{"chirpy.example", "/t/nobody/api/chirps", Tenant{}, "", false},
+	} {
+		got, prefix, ok := set.Resolve(tc.host, tc.path)
+		if got.ID != tc.want.ID || prefix != tc.prefix || ok != tc.ok {
+			t.Errorf("Resolve(%q, %q) = %s, %q, %v; want %s, %q, %v",
+				tc.host, tc.path, got.Slug, prefix, ok, tc.want.Slug, tc.prefix, tc.ok)
+		}
+	}
+
+	var none *Set
+	if got, _, ok := none.Resolve("chirpy.example", "/api/chirps"); !ok || !got.IsDefault() {
+		t.Error("a nil Set should serve the default community")
+	}
+}
+
+func TestWithSetting(t *testing.T) {
+	got, err := withSetting("postgres://chirpy@localhost/chirpy?sslmode=disable&timezone=UTC", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options := u.Query().Get("options"); options != "-c chirpy.tenant_id=default" {
+		t.Errorf("options = %q", options)
+	}
+	if u.Query().Get("sslmode") != "disable" {
+		t.Errorf("lost the other parameters: %s", got)
+	}
+
+	got, err = withSetting("host=localhost dbname=chirpy timezone=UTC", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(got, " options='-c chirpy.tenant_id=default'") {
+		t.Errorf("got %q", got)
+	}
+
+	if _, err := withSetting("host=localhost options='-c search_path=x'", "default"); err == nil {
+		t.Error("expected an error for a connection string that already sets options")
+	}
+}
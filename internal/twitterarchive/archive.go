@@ -0,0 +1,119 @@
+// Package twitterarchive reads tweets out of a Twitter/X data export.
+package twitterarchive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// createdAtLayout is the format of tweet.created_at in archives.
+const createdAtLayout = "Mon Jan 02 15:04:05 -0700 2006"
+
+// tweetsFile matches the tweet data files in an archive. Older exports call
+// it tweet.js; large ones are split into tweets-part1.js and so on.
+var tweetsFile = regexp.MustCompile(`^(?:.*/)?data/tweets?(?:-part\d+)?\.js$`)
+
+var ErrNoTweets = errors.New("archive contains no tweets file")
+
+type Tweet struct {
+	ID        string
+	Text      string
+	CreatedAt time.Time
+	// Retweet is true for plain retweets, whose text isn't the user's own.
+	Retweet bool
+}
+
+type archiveEntry struct {
+	Tweet struct {
+		IDStr     string `json:"id_str"`
+		FullText  string `json:"full_text"`
+		CreatedAt string `json:"created_at"`
+		Retweeted bool   `json:"retweeted"`
+	} `json:"tweet"`
+}
+
+// ParseTweetsJS parses the contents of a tweets.js file. The file is a
+// JavaScript assignment ("window.YTD.tweets.part0 = [...]") wrapping a JSON
+// array.
+func ParseTweetsJS(data []byte) ([]Tweet, error) {
+	start := bytes.IndexByte(data, '[')
+	if start < 0 {
+		return nil, errors.New("tweets file has no JSON array")
+	}
+
+	var entries []archiveEntry
+	if err := json.Unmarshal(data[start:], &entries); err != nil {
+		return nil, fmt.Errorf("parsing tweets file: %w", err)
+	}
+
+	tweets := make([]Tweet, 0, len(entries))
+	for _, e := range entries {
+		createdAt, err := time.Parse(createdAtLayout, e.Tweet.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("tweet %s: %w", e.Tweet.IDStr, err)
+		}
+		tweets = append(tweets, Tweet{
+			ID: e.Tweet.IDStr,
+			// Archives keep the HTML escaping tweets were served with.
+			Text:      html.UnescapeString(e.Tweet.FullText),
+			CreatedAt: createdAt.UTC(),
+			Retweet:   e.Tweet.Retweeted || strings.HasPrefix(e.Tweet.FullText, "RT @"),
+		})
+	}
+	return tweets, nil
+}
+
+// ParseZip reads every tweets file in an archive ZIP. Tweets are returned
+// oldest first.
+func ParseZip(r io.ReaderAt, size int64) ([]Tweet, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var tweets []Tweet
+	found := false
+	for _, f := range zr.File {
+		if !tweetsFile.MatchString(f.Name) {
+			continue
+		}
+		found = true
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		part, err := ParseTweetsJS(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		tweets = append(tweets, part...)
+	}
+	if !found {
+		return nil, ErrNoTweets
+	}
+
+	SortOldestFirst(tweets)
+	return tweets, nil
+}
+
+func SortOldestFirst(tweets []Tweet) {
+	sort.SliceStable(tweets, func(i, j int) bool {
+		return tweets[i].CreatedAt.Before(tweets[j].CreatedAt)
+	})
+}
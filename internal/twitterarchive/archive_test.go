@@ -0,0 +1,95 @@
+package twitterarchive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+const sampleTweetsJS = `window.YTD.tweets.part0 = [
+  {
+    "tweet" : {
+      "id_str" : "2",
+      "full_text" : "second &amp; newer",
+      "created_at" : "Thu Oct 11 09:00:00 +0000 2018",
+      "retweeted" : false
+    }
+  },
+  {
+    "tweet" : {
+      "id_str" : "1",
+      "full_text" : "RT @someone: not mine",
+      "created_at" : "Wed Oct 10 20:19:24 +0000 2018",
+      "retweeted" : false
+    }
+  }
+]`
+
+func TestParseTweetsJS(t *testing.T) {
+	tweets, err := ParseTweetsJS([]byte(sampleTweetsJS))
+	if err != nil {
+		t.Fatalf("ParseTweetsJS returned error: %v", err)
+	}
+	if len(tweets) != 2 {
+		t.Fatalf("expected 2 tweets, got %d", len(tweets))
+	}
+
+	first := tweets[0]
+	if first.ID != "2" || first.Text != "second & newer" || first.Retweet {
+		t.Errorf("unexpected first tweet: %+v", first)
+	}
+	want := time.Date(2018, 10, 11, 9, 0, 0, 0, time.UTC)
+	if !first.CreatedAt.Equal(want) {
+		t.Errorf("expected created_at %v, got %v", want, first.CreatedAt)
+	}
+	if !tweets[1].Retweet {
+		t.Errorf("expected RT to be marked as a retweet")
+	}
+}
+
+func TestParseTweetsJS_Invalid(t *testing.T) {
+	if _, err := ParseTweetsJS([]byte("window.YTD = nothing")); err == nil {
+		t.Fatal("expected error for a file without an array")
+	}
+}
+
+func TestParseZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"data/tweets.js":    sampleTweetsJS,
+		"data/tweetdeck.js": "window.YTD.tweetdeck.part0 = []",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	tweets, err := ParseZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ParseZip returned error: %v", err)
+	}
+	if len(tweets) != 2 {
+		t.Fatalf("expected 2 tweets, got %d", len(tweets))
+	}
+	if tweets[0].ID != "1" {
+		t.Errorf("expected oldest tweet first, got %s", tweets[0].ID)
+	}
+}
+
+func TestParseZip_NoTweets(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	zw.Create("data/account.js")
+	zw.Close()
+
+	_, err := ParseZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if !errors.Is(err, ErrNoTweets) {
+		t.Fatalf("expected ErrNoTweets, got %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"chirpy/internal/api"
+	"chirpy/internal/config"
+	"chirpy/internal/loadgen"
+)
+
+// loadtestClient talks to a running server as a set of synthetic users.
+type loadtestClient struct {
+	baseURL string
+	http    *http.Client
+	tokens  []string
+}
+
+func (c *loadtestClient) do(ctx context.Context, method, path, token string, body interface{}, wantStatus int) ([]byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	// Read the whole body so the timing includes the transfer.
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != wantStatus {
+		return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return respBody, nil
+}
+
+// register signs up n users with random credentials and logs each in.
+// They accept whichever legal documents are in force.
+func (c *loadtestClient) register(ctx context.Context, n int) error {
+	run := make([]byte, 4)
+	if _, err := rand.Read(run); err != nil {
+		return err
+	}
+	body, err := c.do(ctx, http.MethodGet, "/api/legal", "", nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	var legal []struct {
+		Kind    string `json:"kind"`
+		Version int32  `json:"version"`
+	}
+	if err := json.Unmarshal(body, &legal); err != nil {
+		return err
+	}
+	for i := range n {
+		password := make([]byte, 16)
+		if _, err := rand.Read(password); err != nil {
+			return err
+		}
+		creds := api.UserRequest{
+			Email:    fmt.Sprintf("loadtest-%s-%d@example.com", hex.EncodeToString(run), i),
+			Password: hex.EncodeToString(password),
+		}
+		for _, doc := range legal {
+			switch doc.Kind {
+			case "terms":
+				creds.TermsVersion = doc.Version
+			case "privacy":
+				creds.PrivacyVersion = doc.Version
+			}
+		}
+		if _, err := c.do(ctx, http.MethodPost, "/api/users", "", creds, http.StatusCreated); err != nil {
+			return err
+		}
+		body, err := c.do(ctx, http.MethodPost, "/api/login", "", creds, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var user api.UserResponse
+		if err := json.Unmarshal(body, &user); err != nil {
+			return err
+		}
+		if user.Token == "" {
+			return errors.New("login response has no token")
+		}
+		c.tokens = append(c.tokens, user.Token)
+	}
+	return nil
+}
+
+func (c *loadtestClient) ops() map[string]loadgen.Op {
+	token := func(worker int) string { return c.tokens[worker%len(c.tokens)] }
+	return map[string]loadgen.Op{
+		"create": func(ctx context.Context, worker int) error {
+			body := map[string]string{"body": "Load test chirp at " + time.Now().UTC().Format(time.RFC3339Nano)}
+			_, err := c.do(ctx, http.MethodPost, "/api/chirps", token(worker), body, http.StatusCreated)
+			return err
+		},
+		"list": func(ctx context.Context, worker int) error {
+			_, err := c.do(ctx, http.MethodGet, "/api/chirps", token(worker), nil, http.StatusOK)
+			return err
+		},
+		"timeline": func(ctx context.Context, worker int) error {
+			_, err := c.do(ctx, http.MethodGet, "/api/v1/timelines/home?limit=20", token(worker), nil, http.StatusOK)
+			return err
+		},
+	}
+}
+
+// cmdLoadtest drives realistic traffic against a running server and
+// reports latency percentiles per operation. It creates real users and
+// chirps, so point it at a disposable database.
+func cmdLoadtest(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	baseURL := flags.String("url", "http://127.0.0.1:"+cfg.Port, "server to load")
+	users := flags.Int("users", 10, "synthetic users to register")
+	rps := flags.Float64("rps", 50, "target requests per second")
+	duration := flags.Duration("duration", 30*time.Second, "how long to generate load")
+	concurrency := flags.Int("concurrency", 20, "maximum requests in flight")
+	mixFlag := flags.String("mix", "create=1,list=5,timeline=4", "weighted mix of create, list and timeline")
+	seed := flags.Uint64("seed", uint64(time.Now().UnixNano()), "seed for the operation mix")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %q", flags.Args())
+	}
+	if *users < 1 {
+		return errors.New("--users must be at least 1")
+	}
+	mix, err := loadgen.ParseMix(*mixFlag)
+	if err != nil {
+		return err
+	}
+
+	client := &loadtestClient{
+		baseURL: strings.TrimSuffix(*baseURL, "/"),
+		http: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency},
+		},
+	}
+
+	ctx := context.Background()
+	fmt.Printf("registering %d users at %s\n", *users, client.baseURL)
+	if err := client.register(ctx, *users); err != nil {
+		return fmt.Errorf("registering users: %w", err)
+	}
+
+	fmt.Printf("running %s at %g rps\n", *duration, *rps)
+	report, err := loadgen.Run(ctx, loadgen.Options{
+		RPS:         *rps,
+		Duration:    *duration,
+		Concurrency: *concurrency,
+		Mix:         mix,
+		Ops:         client.ops(),
+		Seed:        *seed,
+	})
+	if err != nil {
+		return err
+	}
+	printLoadtestReport(os.Stdout, report)
+	return nil
+}
+
+func printLoadtestReport(w io.Writer, report loadgen.Report) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "op\tcount\terrors\tp50\tp90\tp99\tmax\t")
+	total := 0
+	for _, op := range report.Ops {
+		total += op.Count
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t\n", op.Name, op.Count, op.Errors,
+			op.P50.Round(time.Microsecond), op.P90.Round(time.Microsecond),
+			op.P99.Round(time.Microsecond), op.Max.Round(time.Microsecond))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d requests in %s (%.1f rps)\n", total, report.Elapsed.Round(time.Millisecond),
+		float64(total)/report.Elapsed.Seconds())
+	if report.Dropped > 0 {
+		fmt.Fprintf(w, "%d requests were dropped because all workers were busy; raise --concurrency or lower --rps\n", report.Dropped)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"chirpy/internal/api"
+	"chirpy/internal/auth"
+	"chirpy/internal/config"
+	"chirpy/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// replaySkipHeaders are not copied from a capture: the client sets them
+// itself, or they were redacted when captured.
+var replaySkipHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Content-Length", "Connection", "Accept-Encoding"}
+
+// cmdReplay sends captured requests to a local instance and reports where
+// its responses differ from the captured ones. Credentials are redacted in
+// captures, so requests from a user are replayed with a token minted for
+// that user with JWT_SECRET, unless --token is given.
+func cmdReplay(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	baseURL := flags.String("url", "http://127.0.0.1:"+cfg.Port, "server to replay against")
+	ruleFlag := flags.String("rule", "", "replay every capture of this rule, oldest first")
+	token := flags.String("token", "", "send this access token instead of minting one per user")
+	verbose := flags.Bool("v", false, "print the response body of each mismatch")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if (*ruleFlag == "") == (flags.NArg() == 0) {
+		return errors.New("want --rule ID or capture IDs, but not both")
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	queries := database.New(db)
+
+	ctx := context.Background()
+	var captures []database.RequestCapture
+	if *ruleFlag != "" {
+		ruleID, err := uuid.Parse(*ruleFlag)
+		if err != nil {
+			return errors.New("--rule must be a UUID")
+		}
+		if captures, err = queries.ListRuleCaptures(ctx, ruleID); err != nil {
+			return err
+		}
+	} else {
+		for _, arg := range flags.Args() {
+			id, err := uuid.Parse(arg)
+			if err != nil {
+				return fmt.Errorf("%q is not a capture ID", arg)
+			}
+			c, err := queries.GetRequestCapture(ctx, id)
+			if err != nil {
+				return fmt.Errorf("loading capture %s: %w", id, err)
+			}
+			captures = append(captures, c)
+		}
+	}
+	if len(captures) == 0 {
+		return errors.New("no captures to replay")
+	}
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		// Redirects are part of what was captured.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CAPTURE\tREQUEST\tCAPTURED\tREPLAYED\tBODY")
+	mismatches := 0
+	for _, c := range captures {
+		status, body, err := replayCapture(ctx, client, cfg, strings.TrimSuffix(*baseURL, "/"), *token, c)
+		if err != nil {
+			return fmt.Errorf("replaying %s: %w", c.ID, err)
+		}
+		same := replayBodyMatches(c, body)
+		if status != int(c.Status) || !same {
+			mismatches++
+		}
+		verdict := "same"
+		if !same {
+			verdict = "differs"
+		}
+		fmt.Fprintf(w, "%s\t%s %s\t%d\t%d\t%s\n", c.ID, c.Method, c.Url, c.Status, status, verdict)
+		if *verbose && !same {
+			fmt.Fprintf(w, "\tcaptured: %s\n\treplayed: %s\n", c.ResponseBody, body)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if mismatches > 0 {
+		return fmt.Errorf("%d of %d replayed requests differ", mismatches, len(captures))
+	}
+	return nil
+}
+
+// replayCapture sends c to baseURL and returns the response.
+func replayCapture(ctx context.Context, client *http.Client, cfg *config.Config, baseURL, token string, c database.RequestCapture) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, c.Method, baseURL+c.Url, bytes.NewReader(c.RequestBody))
+	if err != nil {
+		return 0, nil, err
+	}
+	var headers http.Header
+	if err := json.Unmarshal(c.RequestHeaders, &headers); err != nil {
+		return 0, nil, fmt.Errorf("decoding headers: %w", err)
+	}
+	for name, values := range headers {
+		if containsHeader(replaySkipHeaders, name) {
+			continue
+		}
+		req.Header[name] = values
+	}
+
+	if token == "" && c.UserID.Valid {
+		if cfg.JWTSecret == "" {
+			return 0, nil, errors.New("JWT_SECRET is not set, so pass --token")
+		}
+		if token, err = auth.MakeJWT(c.UserID.UUID, cfg.JWTSecret, api.AccessTokenTTL); err != nil {
+			return 0, nil, err
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+// replayBodyMatches compares only what was kept of a truncated body. The
+// bodies of responses that were redacted or dropped when captured rarely
+// match, and are worth reading with -v.
+func replayBodyMatches(c database.RequestCapture, body []byte) bool {
+	if c.ResponseBodyTruncated {
+		return bytes.HasPrefix(body, c.ResponseBody)
+	}
+	return bytes.Equal(body, c.ResponseBody)
+}
+
+func containsHeader(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,2 +1,8 @@
 -- name: DeleteAllUsers :exec
 DELETE FROM users;
+
+-- name: DeleteAllChirps :exec
+DELETE FROM chirps;
+
+-- name: DeleteAllIPActivity :exec
+DELETE FROM ip_activity;
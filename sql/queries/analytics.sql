@@ -0,0 +1,9 @@
+-- name: RecordAnalyticsEvent :exec
+INSERT INTO analytics_events(id, name, user_id, properties, occurred_at)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5
+);
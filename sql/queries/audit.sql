@@ -0,0 +1,29 @@
+-- name: CreateAuditLogEntry :exec
+INSERT INTO audit_log(id, created_at, actor_id, action, target_id, details)
+VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4,
+  $5
+);
+
+-- name: ListAuditLog :many
+SELECT
+  id,
+  created_at,
+  actor_id,
+  action,
+  target_id,
+  details,
+  tenant_id
+FROM audit_log
+WHERE (sqlc.narg(actor_id)::uuid IS NULL OR actor_id = sqlc.narg(actor_id))
+  AND (sqlc.narg(action)::text IS NULL OR action = sqlc.narg(action))
+  AND (sqlc.narg(target_id)::uuid IS NULL OR target_id = sqlc.narg(target_id))
+  AND (sqlc.narg(since)::timestamp IS NULL OR created_at >= sqlc.narg(since))
+  AND (sqlc.narg(until)::timestamp IS NULL OR created_at < sqlc.narg(until))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit)
+OFFSET sqlc.arg(row_offset);
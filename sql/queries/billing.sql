@@ -0,0 +1,98 @@
+-- name: UpsertBillingCustomer :exec
+INSERT INTO billing_customers(user_id, stripe_customer_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id) DO UPDATE
+SET stripe_customer_id = EXCLUDED.stripe_customer_id;
+
+-- name: GetBillingCustomer :one
+SELECT * FROM billing_customers
+WHERE user_id = $1;
+
+-- name: GetBillingCustomerByStripeID :one
+SELECT * FROM billing_customers
+WHERE stripe_customer_id = $1;
+
+-- name: UpsertBillingSubscription :one
+-- Returns no rows when the stored state came from a newer event.
+INSERT INTO billing_subscriptions(id, user_id, stripe_customer_id, status, price_id, cancel_at_period_end, current_period_end, trial_end, canceled_at, ended_at, monthly_amount, created_at, updated_at, event_created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), $13)
+ON CONFLICT (id) DO UPDATE
+SET status = EXCLUDED.status,
+    price_id = EXCLUDED.price_id,
+    cancel_at_period_end = EXCLUDED.cancel_at_period_end,
+    current_period_end = EXCLUDED.current_period_end,
+    trial_end = EXCLUDED.trial_end,
+    canceled_at = EXCLUDED.canceled_at,
+    ended_at = EXCLUDED.ended_at,
+    monthly_amount = EXCLUDED.monthly_amount,
+    updated_at = NOW(),
+    event_created_at = EXCLUDED.event_created_at
+WHERE billing_subscriptions.event_created_at <= EXCLUDED.event_created_at
+RETURNING *;
+
+-- name: GetCurrentBillingSubscription :one
+-- The user's newest subscription that still entitles them to Chirpy Red.
+SELECT * FROM billing_subscriptions
+WHERE user_id = $1
+  AND status IN ('active', 'trialing', 'past_due')
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: RecordStripeEvent :execrows
+INSERT INTO stripe_events(id, type, received_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (id) DO NOTHING;
+
+-- name: WeeklyBillingMetrics :many
+-- Each week from the one since falls in, as it stood at the week's end or
+-- at until, whichever is sooner. A subscription pays once any trial is
+-- over; incomplete ones never started and don't count.
+WITH weeks AS (
+  SELECT
+    week AS week_start,
+    LEAST(week + INTERVAL '1 week', sqlc.arg(until)::timestamp) AS week_end
+  FROM generate_series(date_trunc('week', sqlc.arg(since)::timestamp), sqlc.arg(until)::timestamp, INTERVAL '1 week') AS week
+), subs AS (
+  SELECT created_at, trial_end, ended_at, monthly_amount FROM billing_subscriptions
+  WHERE status NOT IN ('incomplete', 'incomplete_expired')
+)
+SELECT
+  weeks.week_start::date AS week,
+  COUNT(*) FILTER (
+    WHERE subs.trial_end IS NOT NULL AND subs.created_at >= weeks.week_start
+  )::bigint AS trials_started,
+  COUNT(*) FILTER (
+    WHERE subs.trial_end >= weeks.week_start AND subs.trial_end < weeks.week_end
+  )::bigint AS trials_ended,
+  COUNT(*) FILTER (
+    WHERE subs.trial_end >= weeks.week_start AND subs.trial_end < weeks.week_end
+      AND (subs.ended_at IS NULL OR subs.ended_at > subs.trial_end)
+  )::bigint AS trials_converted,
+  COUNT(*) FILTER (
+    WHERE subs.trial_end IS NULL AND subs.created_at >= weeks.week_start
+  )::bigint AS new_subscriptions,
+  COUNT(*) FILTER (
+    WHERE subs.ended_at >= weeks.week_start AND subs.ended_at < weeks.week_end
+      AND (subs.trial_end IS NULL OR subs.trial_end < subs.ended_at)
+  )::bigint AS churned,
+  COUNT(*) FILTER (
+    WHERE subs.created_at < weeks.week_start
+      AND (subs.ended_at IS NULL OR subs.ended_at > weeks.week_start)
+      AND (subs.trial_end IS NULL OR subs.trial_end <= weeks.week_start)
+  )::bigint AS paying_at_start,
+  COUNT(*) FILTER (
+    WHERE (subs.ended_at IS NULL OR subs.ended_at > weeks.week_end)
+      AND subs.trial_end > weeks.week_end
+  )::bigint AS trialing,
+  COUNT(*) FILTER (
+    WHERE (subs.ended_at IS NULL OR subs.ended_at > weeks.week_end)
+      AND (subs.trial_end IS NULL OR subs.trial_end <= weeks.week_end)
+  )::bigint AS paying,
+  COALESCE(SUM(subs.monthly_amount) FILTER (
+    WHERE (subs.ended_at IS NULL OR subs.ended_at > weeks.week_end)
+      AND (subs.trial_end IS NULL OR subs.trial_end <= weeks.week_end)
+  ), 0)::bigint AS mrr
+FROM weeks
+LEFT JOIN subs ON subs.created_at < weeks.week_end
+GROUP BY weeks.week_start
+ORDER BY weeks.week_start ASC;
@@ -0,0 +1,69 @@
+-- name: CreateCaptureRule :one
+INSERT INTO capture_rules(id, created_at, created_by, route, user_id, expires_at, max_captures)
+VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4,
+  $5,
+  $6
+)
+RETURNING *;
+
+-- name: ListActiveCaptureRules :many
+SELECT
+  id,
+  created_at,
+  created_by,
+  route,
+  user_id,
+  expires_at,
+  max_captures
+FROM capture_rules
+WHERE expires_at > NOW()
+ORDER BY created_at ASC;
+
+-- name: StopCaptureRule :one
+UPDATE capture_rules
+SET expires_at = NOW()
+WHERE id = $1
+  AND expires_at > NOW()
+RETURNING *;
+
+-- name: CreateRequestCapture :execrows
+-- Nothing is stored once the rule has captured all it may.
+INSERT INTO request_captures(id, created_at, rule_id, user_id, method, url, route, request_headers, request_body, request_body_truncated, status, response_headers, response_body, response_body_truncated, duration_ms)
+SELECT $1, NOW(), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+FROM capture_rules
+WHERE capture_rules.id = $2
+  AND (SELECT COUNT(*) FROM request_captures WHERE request_captures.rule_id = $2) < capture_rules.max_captures;
+
+-- name: ListRequestCaptures :many
+SELECT
+  id,
+  created_at,
+  rule_id,
+  user_id,
+  method,
+  url,
+  route,
+  status,
+  duration_ms
+FROM request_captures
+WHERE sqlc.narg(rule_id)::uuid IS NULL OR rule_id = sqlc.narg(rule_id)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit)
+OFFSET sqlc.arg(row_offset);
+
+-- name: GetRequestCapture :one
+SELECT *
+FROM request_captures
+WHERE id = $1;
+
+-- name: ListRuleCaptures :many
+-- A rule's captures in the order they were made, for replaying.
+SELECT *
+FROM request_captures
+WHERE rule_id = $1
+ORDER BY created_at ASC, id ASC;
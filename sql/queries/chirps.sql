@@ -9,23 +9,216 @@ VALUES(
 )
 RETURNING *;
 
+-- name: CreateChirps :many
+-- Inserts one chirp per element of ids and bodies, which must be the same
+-- length, in a single statement.
+INSERT INTO chirps(id, created_at, updated_at, body, user_id)
+SELECT
+  unnest(sqlc.arg(ids)::uuid[]),
+  NOW(),
+  NOW(),
+  unnest(sqlc.arg(bodies)::text[]),
+  sqlc.arg(user_id)
+RETURNING *;
+
 -- name: GetChirps :many
+SELECT
+  chirps.id,
+  chirps.created_at,
+  chirps.updated_at,
+  chirps.body,
+  chirps.user_id,
+  chirps.deleted_at,
+  users.verified AS author_verified,
+  chirp_content_warnings.chirp_id IS NOT NULL AS sensitive,
+  COALESCE(chirp_content_warnings.warning, '') AS content_warning
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_content_warnings ON chirp_content_warnings.chirp_id = chirps.id
+WHERE chirps.deleted_at IS NULL
+  AND users.banned_at IS NULL
+  AND (NOT users.shadowbanned OR chirps.user_id = sqlc.arg(viewer_id))
+  AND (
+    chirp_content_warnings.chirp_id IS NULL
+    OR chirps.user_id = sqlc.arg(viewer_id)
+    OR NOT EXISTS (
+      SELECT 1
+      FROM content_preferences
+      WHERE content_preferences.user_id = sqlc.arg(viewer_id)
+        AND content_preferences.sensitive_content = 'hide'
+    )
+  )
+ORDER BY chirps.created_at ASC;
+
+-- name: GetChirp :one
 SELECT
   id,
   created_at,
   updated_at,
   body,
-  user_id
+  user_id,
+  deleted_at,
+  tenant_id
 FROM chirps
-ORDER BY created_at ASC;
+WHERE id = $1
+  AND deleted_at IS NULL;
 
--- name: GetChirp :one
+
+-- name: DeleteChirp :exec
+DELETE FROM chirps
+WHERE id = $1;
+
+-- name: GetVisibleChirp :one
+SELECT
+  chirps.id,
+  chirps.created_at,
+  chirps.updated_at,
+  chirps.body,
+  chirps.user_id,
+  chirps.deleted_at,
+  users.verified AS author_verified,
+  chirp_content_warnings.chirp_id IS NOT NULL AS sensitive,
+  COALESCE(chirp_content_warnings.warning, '') AS content_warning
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_content_warnings ON chirp_content_warnings.chirp_id = chirps.id
+WHERE chirps.id = sqlc.arg(id)
+  AND chirps.deleted_at IS NULL
+  AND users.banned_at IS NULL
+  AND (NOT users.shadowbanned OR chirps.user_id = sqlc.arg(viewer_id));
+
+-- name: SoftDeleteUserChirpsBatch :execrows
+UPDATE chirps
+SET deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id IN (
+  SELECT id
+  FROM chirps
+  WHERE user_id = $1
+    AND deleted_at IS NULL
+  LIMIT $2
+);
+
+-- name: ListUserChirps :many
 SELECT
   id,
   created_at,
   updated_at,
   body,
-  user_id
+  user_id,
+  deleted_at,
+  tenant_id
 FROM chirps
-WHERE id = $1;
+WHERE user_id = $1
+  AND deleted_at IS NULL
+ORDER BY created_at DESC
+LIMIT $2;
 
+-- name: CountChirpsByUsers :many
+SELECT
+  user_id,
+  COUNT(*) AS chirp_count
+FROM chirps
+WHERE user_id = ANY(sqlc.arg(user_ids)::uuid[])
+  AND deleted_at IS NULL
+GROUP BY user_id;
+
+-- name: ListRecentChirps :many
+SELECT
+  chirps.id,
+  chirps.created_at,
+  chirps.updated_at,
+  chirps.body,
+  chirps.user_id,
+  chirps.deleted_at,
+  users.verified AS author_verified,
+  chirp_content_warnings.chirp_id IS NOT NULL AS sensitive,
+  COALESCE(chirp_content_warnings.warning, '') AS content_warning
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_content_warnings ON chirp_content_warnings.chirp_id = chirps.id
+WHERE chirps.deleted_at IS NULL
+  AND users.banned_at IS NULL
+  AND (NOT users.shadowbanned OR chirps.user_id = sqlc.arg(viewer_id))
+  AND (
+    chirp_content_warnings.chirp_id IS NULL
+    OR chirps.user_id = sqlc.arg(viewer_id)
+    OR NOT EXISTS (
+      SELECT 1
+      FROM content_preferences
+      WHERE content_preferences.user_id = sqlc.arg(viewer_id)
+        AND content_preferences.sensitive_content = 'hide'
+    )
+  )
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListVisibleUserChirps :many
+-- A user's chirps, newest first, as viewer may see them: nothing from
+-- banned users, nothing from shadowbanned ones but to themselves, and no
+-- sensitive chirps for viewers who hide them.
+SELECT
+  chirps.id,
+  chirps.created_at,
+  chirps.updated_at,
+  chirps.body,
+  chirps.user_id,
+  chirps.deleted_at,
+  users.verified AS author_verified,
+  (chirp_content_warnings.chirp_id IS NOT NULL)::boolean AS sensitive,
+  COALESCE(chirp_content_warnings.warning, '') AS content_warning
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_content_warnings ON chirp_content_warnings.chirp_id = chirps.id
+WHERE chirps.user_id = sqlc.arg(user_id)
+  AND chirps.deleted_at IS NULL
+  AND users.banned_at IS NULL
+  AND (NOT users.shadowbanned OR chirps.user_id = sqlc.arg(viewer_id))
+  AND (
+    chirp_content_warnings.chirp_id IS NULL
+    OR chirps.user_id = sqlc.arg(viewer_id)
+    OR NOT EXISTS (
+      SELECT 1
+      FROM content_preferences
+      WHERE content_preferences.user_id = sqlc.arg(viewer_id)
+        AND content_preferences.sensitive_content = 'hide'
+    )
+  )
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListUserChirpsAfter :many
+SELECT
+  id,
+  created_at,
+  updated_at,
+  body,
+  user_id,
+  deleted_at,
+  tenant_id
+FROM chirps
+WHERE user_id = sqlc.arg(user_id)
+  AND deleted_at IS NULL
+  AND (created_at, id) > (sqlc.arg(after_created_at)::timestamp, sqlc.arg(after_id)::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListSitemapChirps :many
+SELECT
+  chirps.id,
+  chirps.updated_at
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.deleted_at IS NULL
+  AND users.banned_at IS NULL
+  AND NOT users.shadowbanned
+ORDER BY chirps.created_at ASC;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2,
+    updated_at = NOW()
+WHERE id = $1
+  AND user_id = $3
+  AND deleted_at IS NULL
+RETURNING *;
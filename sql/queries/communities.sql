@@ -0,0 +1,120 @@
+-- name: CreateCommunity :one
+-- Creates the community and makes its creator the owner in one statement.
+WITH community AS (
+  INSERT INTO communities(id, slug, name, description, created_by, created_at, updated_at)
+  VALUES (
+    sqlc.arg(id),
+    sqlc.arg(slug),
+    sqlc.arg(name),
+    sqlc.arg(description),
+    sqlc.arg(created_by),
+    NOW(),
+    NOW()
+  )
+  RETURNING *
+), owner AS (
+  INSERT INTO community_members(community_id, user_id, role, joined_at)
+  SELECT id, created_by, 'owner', NOW()
+  FROM community
+)
+SELECT
+  id,
+  slug,
+  name,
+  description,
+  created_by,
+  created_at,
+  updated_at,
+  tenant_id
+FROM community;
+
+-- name: GetCommunityBySlug :one
+SELECT
+  id,
+  slug,
+  name,
+  description,
+  created_by,
+  created_at,
+  updated_at,
+  tenant_id
+FROM communities
+WHERE slug = $1;
+
+-- name: CountCommunityMembers :one
+SELECT COUNT(*)
+FROM community_members
+WHERE community_id = $1;
+
+-- name: GetCommunityRole :one
+SELECT role
+FROM community_members
+WHERE community_id = $1
+  AND user_id = $2;
+
+-- name: JoinCommunity :exec
+INSERT INTO community_members(community_id, user_id, role, joined_at)
+VALUES (
+  $1,
+  $2,
+  'member',
+  NOW()
+)
+ON CONFLICT (community_id, user_id) DO NOTHING;
+
+-- name: LeaveCommunity :execrows
+DELETE FROM community_members
+WHERE community_id = $1
+  AND user_id = $2
+  AND role <> 'owner';
+
+-- name: SetCommunityRole :execrows
+-- Changes a member's role. The owner's role can't be changed.
+UPDATE community_members
+SET role = $3
+WHERE community_id = $1
+  AND user_id = $2
+  AND role <> 'owner';
+
+-- name: AddCommunityChirp :exec
+INSERT INTO community_chirps(chirp_id, community_id)
+VALUES (
+  $1,
+  $2
+);
+
+-- name: RemoveCommunityChirp :execrows
+DELETE FROM community_chirps
+WHERE chirp_id = $1
+  AND community_id = $2;
+
+-- name: ListCommunityChirps :many
+SELECT
+  chirps.id,
+  chirps.created_at,
+  chirps.updated_at,
+  chirps.body,
+  chirps.user_id,
+  users.verified AS author_verified,
+  chirp_content_warnings.chirp_id IS NOT NULL AS sensitive,
+  COALESCE(chirp_content_warnings.warning, '') AS content_warning
+FROM community_chirps
+JOIN chirps ON chirps.id = community_chirps.chirp_id
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_content_warnings ON chirp_content_warnings.chirp_id = chirps.id
+WHERE community_chirps.community_id = sqlc.arg(community_id)
+  AND chirps.deleted_at IS NULL
+  AND users.banned_at IS NULL
+  AND (NOT users.shadowbanned OR chirps.user_id = sqlc.arg(viewer_id))
+  AND (
+    chirp_content_warnings.chirp_id IS NULL
+    OR chirps.user_id = sqlc.arg(viewer_id)
+    OR NOT EXISTS (
+      SELECT 1
+      FROM content_preferences
+      WHERE content_preferences.user_id = sqlc.arg(viewer_id)
+        AND content_preferences.sensitive_content = 'hide'
+    )
+  )
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,51 @@
+-- name: ListContentRules :many
+SELECT
+  id,
+  created_at,
+  kind,
+  pattern,
+  action,
+  created_by
+FROM content_rules
+ORDER BY created_at ASC;
+
+-- name: CreateContentRule :one
+INSERT INTO content_rules(id, created_at, kind, pattern, action, created_by)
+VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4,
+  $5
+)
+RETURNING *;
+
+-- name: DeleteContentRule :one
+DELETE FROM content_rules
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateContentFlag :exec
+INSERT INTO content_flags(id, created_at, chirp_id, rule_id, pattern)
+VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4
+);
+
+-- name: ListContentFlags :many
+SELECT
+  content_flags.id,
+  content_flags.created_at,
+  content_flags.chirp_id,
+  content_flags.rule_id,
+  content_flags.pattern,
+  chirps.body AS chirp_body,
+  chirps.user_id AS author_id
+FROM content_flags
+JOIN chirps ON chirps.id = content_flags.chirp_id
+ORDER BY content_flags.created_at DESC
+LIMIT $1;
@@ -0,0 +1,23 @@
+-- name: CreateChirpContentWarning :exec
+INSERT INTO chirp_content_warnings(chirp_id, warning)
+VALUES (
+  $1,
+  $2
+);
+
+-- name: GetSensitiveContentPreference :one
+SELECT COALESCE(
+  (SELECT sensitive_content FROM content_preferences WHERE user_id = $1),
+  'collapse'
+)::text AS sensitive_content;
+
+-- name: SetSensitiveContentPreference :exec
+INSERT INTO content_preferences(user_id, sensitive_content, updated_at)
+VALUES (
+  $1,
+  $2,
+  NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET sensitive_content = EXCLUDED.sensitive_content,
+    updated_at = NOW();
@@ -0,0 +1,19 @@
+-- name: AddSharedCounter :exec
+INSERT INTO shared_counters(name, value, updated_at)
+VALUES (
+  $1,
+  $2,
+  NOW()
+)
+ON CONFLICT (name) DO UPDATE
+SET value = shared_counters.value + EXCLUDED.value,
+    updated_at = NOW();
+
+-- name: GetSharedCounter :one
+SELECT value
+FROM shared_counters
+WHERE name = $1;
+
+-- name: ResetSharedCounter :exec
+DELETE FROM shared_counters
+WHERE name = $1;
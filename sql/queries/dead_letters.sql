@@ -0,0 +1,52 @@
+-- name: RecordDeadLetter :exec
+-- A source with an open entry adds its attempts to it rather than opening
+-- another.
+INSERT INTO dead_letters(id, created_at, updated_at, source, source_id, summary, attempts, last_error)
+VALUES (
+  $1,
+  NOW(),
+  NOW(),
+  $2,
+  $3,
+  $4,
+  $5,
+  $6
+)
+ON CONFLICT (source, source_id) WHERE retried_at IS NULL DO UPDATE
+SET attempts = dead_letters.attempts + EXCLUDED.attempts,
+    last_error = EXCLUDED.last_error,
+    updated_at = NOW();
+
+-- name: ListDeadLetters :many
+SELECT
+  id,
+  created_at,
+  updated_at,
+  source,
+  source_id,
+  summary,
+  attempts,
+  last_error,
+  retried_at
+FROM dead_letters
+WHERE retried_at IS NULL
+  AND (sqlc.narg(source)::text IS NULL OR source = sqlc.narg(source))
+ORDER BY updated_at DESC, id DESC
+LIMIT sqlc.arg(row_limit)
+OFFSET sqlc.arg(row_offset);
+
+-- name: ClaimDeadLetter :one
+UPDATE dead_letters
+SET retried_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+  AND retried_at IS NULL
+RETURNING *;
+
+-- name: ResolveDeadLetter :exec
+UPDATE dead_letters
+SET retried_at = NOW(),
+    updated_at = NOW()
+WHERE source = $1
+  AND source_id = $2
+  AND retried_at IS NULL;
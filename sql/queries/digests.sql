@@ -0,0 +1,72 @@
+-- name: GetDigestFrequency :one
+SELECT COALESCE(
+  (SELECT frequency FROM digest_preferences WHERE user_id = $1),
+  'weekly'
+)::text AS frequency;
+
+-- name: SetDigestFrequency :exec
+INSERT INTO digest_preferences(user_id, frequency, updated_at)
+VALUES (
+  $1,
+  $2,
+  NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET frequency = EXCLUDED.frequency,
+    updated_at = NOW();
+
+-- name: ListDueDigests :many
+SELECT
+  users.id,
+  users.email,
+  users.handle,
+  COALESCE(digest_preferences.frequency, 'weekly')::text AS frequency,
+  digest_preferences.last_sent_at
+FROM users
+LEFT JOIN digest_preferences ON digest_preferences.user_id = users.id
+WHERE users.banned_at IS NULL
+  AND COALESCE(digest_preferences.frequency, 'weekly') <> 'off'
+  AND (
+    digest_preferences.last_sent_at IS NULL
+    OR (digest_preferences.frequency = 'daily' AND digest_preferences.last_sent_at <= NOW() - INTERVAL '1 day')
+    OR (COALESCE(digest_preferences.frequency, 'weekly') = 'weekly' AND digest_preferences.last_sent_at <= NOW() - INTERVAL '7 days')
+  )
+ORDER BY users.id
+LIMIT $1;
+
+-- name: ClaimDigest :execrows
+INSERT INTO digest_preferences(user_id, last_sent_at, updated_at)
+VALUES (
+  sqlc.arg(user_id),
+  NOW(),
+  NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET last_sent_at = NOW(),
+    updated_at = NOW()
+WHERE digest_preferences.last_sent_at IS NOT DISTINCT FROM sqlc.narg(previous_sent_at);
+
+-- name: ListRemoteFollowersSince :many
+SELECT actor_uri
+FROM remote_followers
+WHERE user_id = $1
+  AND created_at > $2
+ORDER BY created_at DESC
+LIMIT $3;
+
+-- name: ListDigestChirps :many
+SELECT
+  chirps.id,
+  chirps.created_at,
+  chirps.body,
+  chirps.user_id,
+  users.handle AS author_handle
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.deleted_at IS NULL
+  AND chirps.created_at > sqlc.arg(since)
+  AND chirps.user_id <> sqlc.arg(recipient_id)
+  AND users.banned_at IS NULL
+  AND NOT users.shadowbanned
+ORDER BY users.verified DESC, chirps.created_at DESC
+LIMIT sqlc.arg(row_limit);
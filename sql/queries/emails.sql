@@ -0,0 +1,81 @@
+-- name: EnqueueEmail :exec
+INSERT INTO emails(id, created_at, updated_at, template, to_address, subject, text_body, html_body, next_attempt_at)
+VALUES (
+  $1,
+  NOW(),
+  NOW(),
+  $2,
+  $3,
+  $4,
+  $5,
+  $6,
+  NOW()
+);
+
+-- name: ClaimDueEmails :many
+SELECT
+  id,
+  created_at,
+  updated_at,
+  template,
+  to_address,
+  subject,
+  text_body,
+  html_body,
+  status,
+  attempts,
+  next_attempt_at,
+  last_error,
+  sent_at
+FROM emails
+WHERE status = 'pending'
+  AND next_attempt_at <= NOW()
+ORDER BY next_attempt_at ASC
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkEmailSent :exec
+UPDATE emails
+SET status = 'sent',
+    attempts = attempts + 1,
+    sent_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkEmailFailed :exec
+UPDATE emails
+SET status = sqlc.arg(status),
+    attempts = attempts + 1,
+    last_error = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id);
+
+-- name: ListDeadEmails :many
+SELECT
+  id,
+  created_at,
+  updated_at,
+  template,
+  to_address,
+  subject,
+  text_body,
+  html_body,
+  status,
+  attempts,
+  next_attempt_at,
+  last_error,
+  sent_at
+FROM emails
+WHERE status = 'dead'
+ORDER BY updated_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: RetryEmail :execrows
+UPDATE emails
+SET status = 'pending',
+    attempts = 0,
+    next_attempt_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+  AND status = 'dead';
@@ -0,0 +1,53 @@
+-- name: GetActorKey :one
+SELECT
+  user_id,
+  created_at,
+  public_key_pem,
+  private_key_pem
+FROM actor_keys
+WHERE user_id = $1;
+
+-- name: CreateActorKey :exec
+INSERT INTO actor_keys(user_id, created_at, public_key_pem, private_key_pem)
+VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3
+)
+ON CONFLICT (user_id) DO NOTHING;
+
+-- name: UpsertRemoteFollower :exec
+INSERT INTO remote_followers(id, created_at, user_id, actor_uri, inbox_uri)
+VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4
+)
+ON CONFLICT (user_id, actor_uri) DO UPDATE
+SET inbox_uri = EXCLUDED.inbox_uri;
+
+-- name: DeleteRemoteFollower :exec
+DELETE FROM remote_followers
+WHERE user_id = $1
+  AND actor_uri = $2;
+
+-- name: ListRemoteFollowerInboxes :many
+SELECT DISTINCT inbox_uri
+FROM remote_followers
+WHERE user_id = $1;
+
+-- name: CountRemoteFollowers :one
+SELECT COUNT(*)
+FROM remote_followers
+WHERE user_id = $1;
+
+-- name: CountRemoteFollowersByUsers :many
+SELECT
+  user_id,
+  COUNT(*) AS follower_count
+FROM remote_followers
+WHERE user_id = ANY(sqlc.arg(user_ids)::uuid[])
+GROUP BY user_id;
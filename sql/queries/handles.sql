@@ -0,0 +1,49 @@
+-- name: ChangeUserHandle :one
+-- Fails with no rows while another user's old handle is reserved.
+UPDATE users
+SET handle = sqlc.arg(handle),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+  AND NOT EXISTS (
+    SELECT 1 FROM handle_history
+    WHERE handle_history.handle = sqlc.arg(handle)
+      AND handle_history.user_id <> sqlc.arg(id)
+      AND handle_history.expires_at > NOW()
+  )
+RETURNING *;
+
+-- name: RecordHandleChange :exec
+-- An expired reservation of the same handle is taken over.
+INSERT INTO handle_history(handle, user_id, changed_at, expires_at)
+VALUES ($1, $2, NOW(), $3)
+ON CONFLICT (handle) DO UPDATE
+SET user_id = EXCLUDED.user_id,
+    changed_at = EXCLUDED.changed_at,
+    expires_at = EXCLUDED.expires_at;
+
+-- name: ReleaseHandle :exec
+-- A user taking back one of their old handles no longer needs it reserved.
+DELETE FROM handle_history
+WHERE handle = $1 AND user_id = $2;
+
+-- name: GetHandleRedirect :one
+-- The user an old handle still leads to.
+SELECT users.handle
+FROM handle_history
+JOIN users ON users.id = handle_history.user_id
+WHERE handle_history.handle = $1
+  AND handle_history.expires_at > NOW()
+  AND users.handle IS NOT NULL;
+
+-- name: GetLastHandleChange :one
+SELECT changed_at
+FROM handle_history
+WHERE user_id = $1
+ORDER BY changed_at DESC
+LIMIT 1;
+
+-- name: IsHandleReserved :one
+SELECT EXISTS (
+  SELECT 1 FROM handle_history
+  WHERE handle = $1 AND expires_at > NOW()
+);
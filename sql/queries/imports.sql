@@ -0,0 +1,61 @@
+-- name: CreateImportJob :one
+INSERT INTO import_jobs(id, created_at, updated_at, user_id, source, status, total)
+VALUES (
+  $1,
+  NOW(),
+  NOW(),
+  $2,
+  $3,
+  'running',
+  $4
+)
+RETURNING *;
+
+-- name: GetImportJob :one
+SELECT
+  id,
+  created_at,
+  updated_at,
+  user_id,
+  source,
+  status,
+  total,
+  imported,
+  skipped,
+  error
+FROM import_jobs
+WHERE id = $1;
+
+-- name: UpdateImportJobProgress :exec
+UPDATE import_jobs
+SET imported = $2,
+    skipped = $3,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: FinishImportJob :exec
+UPDATE import_jobs
+SET status = $2,
+    imported = $3,
+    skipped = $4,
+    error = $5,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkImportTransaction :exec
+SELECT set_config('chirpy.importing', 'on', true);
+
+-- name: RecordImportedTweet :execrows
+INSERT INTO imported_tweets(user_id, tweet_id, chirp_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, tweet_id) DO NOTHING;
+
+-- name: ImportChirp :exec
+INSERT INTO chirps(id, created_at, updated_at, body, user_id)
+VALUES (
+  $1,
+  $2,
+  $2,
+  $3,
+  $4
+);
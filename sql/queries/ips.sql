@@ -0,0 +1,50 @@
+-- name: RecordIPSignup :exec
+INSERT INTO ip_activity(ip, signups, login_failures, first_seen_at, last_seen_at)
+VALUES ($1, 1, 0, NOW(), NOW())
+ON CONFLICT (ip) DO UPDATE
+SET signups = ip_activity.signups + 1,
+    last_seen_at = NOW();
+
+-- name: RecordIPLoginFailure :exec
+INSERT INTO ip_activity(ip, signups, login_failures, first_seen_at, last_seen_at)
+VALUES ($1, 0, 1, NOW(), NOW())
+ON CONFLICT (ip) DO UPDATE
+SET login_failures = ip_activity.login_failures + 1,
+    last_seen_at = NOW();
+
+-- name: ListIPActivity :many
+SELECT
+  ip,
+  signups,
+  login_failures,
+  first_seen_at,
+  last_seen_at
+FROM ip_activity
+ORDER BY signups + login_failures DESC, last_seen_at DESC
+LIMIT $1;
+
+-- name: CreateIPBlock :one
+INSERT INTO ip_blocks(id, created_at, cidr, reason, created_by)
+VALUES (
+  $1,
+  NOW(),
+  $2,
+  $3,
+  $4
+)
+RETURNING *;
+
+-- name: ListIPBlocks :many
+SELECT
+  id,
+  created_at,
+  cidr,
+  reason,
+  created_by
+FROM ip_blocks
+ORDER BY created_at ASC;
+
+-- name: DeleteIPBlock :one
+DELETE FROM ip_blocks
+WHERE id = $1
+RETURNING *;
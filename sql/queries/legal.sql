@@ -0,0 +1,34 @@
+-- name: PublishLegalDocument :one
+-- The new document gets the next version of its kind. Two publishes racing
+-- for the same version fail on the primary key rather than both landing.
+INSERT INTO legal_documents(kind, version, body, published_at, published_by)
+SELECT sqlc.arg(kind), COALESCE(MAX(version), 0) + 1, sqlc.arg(body), NOW(), sqlc.arg(published_by)
+FROM legal_documents
+WHERE kind = sqlc.arg(kind)
+RETURNING *;
+
+-- name: ListCurrentLegalDocuments :many
+SELECT DISTINCT ON (kind)
+  kind,
+  version,
+  body,
+  published_at,
+  published_by
+FROM legal_documents
+ORDER BY kind, version DESC;
+
+-- name: GetLegalDocument :one
+SELECT * FROM legal_documents
+WHERE kind = $1 AND version = $2;
+
+-- name: AcceptLegalDocument :exec
+INSERT INTO legal_acceptances(user_id, kind, version, accepted_at, ip)
+VALUES ($1, $2, $3, NOW(), $4)
+ON CONFLICT (user_id, kind, version) DO NOTHING;
+
+-- name: ListUserLegalAcceptances :many
+-- The latest version of each kind the user has accepted.
+SELECT kind, MAX(version)::integer AS version
+FROM legal_acceptances
+WHERE user_id = $1
+GROUP BY kind;
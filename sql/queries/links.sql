@@ -0,0 +1,27 @@
+-- name: AddChirpLink :exec
+-- Records that the chirp links to url, which is scanned if it is new.
+WITH scan AS (
+  INSERT INTO link_scans(url, created_at)
+  VALUES (sqlc.arg(url), NOW())
+  ON CONFLICT (url) DO NOTHING
+)
+INSERT INTO chirp_links(chirp_id, url)
+VALUES (sqlc.arg(chirp_id), sqlc.arg(url))
+ON CONFLICT DO NOTHING;
+
+-- name: GetLinkScans :many
+SELECT url, created_at, checked_at, threat
+FROM link_scans
+WHERE url = ANY(sqlc.arg(urls)::text[]);
+
+-- name: GetLinkThreat :one
+-- No rows means the link isn't in any chirp.
+SELECT threat
+FROM link_scans
+WHERE url = $1;
+
+-- name: RecordLinkScan :exec
+UPDATE link_scans
+SET checked_at = NOW(),
+    threat = $2
+WHERE url = $1;
@@ -0,0 +1,113 @@
+-- name: CreateList :one
+INSERT INTO lists(id, owner_id, name, description, private, created_at, updated_at)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  NOW(),
+  NOW()
+)
+RETURNING *;
+
+-- name: GetList :one
+SELECT
+  id,
+  owner_id,
+  name,
+  description,
+  private,
+  created_at,
+  updated_at,
+  tenant_id
+FROM lists
+WHERE id = $1;
+
+-- name: ListUserLists :many
+SELECT
+  id,
+  owner_id,
+  name,
+  description,
+  private,
+  created_at,
+  updated_at,
+  tenant_id
+FROM lists
+WHERE owner_id = $1
+ORDER BY created_at ASC;
+
+-- name: UpdateList :one
+UPDATE lists
+SET name = $2,
+    description = $3,
+    private = $4,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteList :exec
+DELETE FROM lists
+WHERE id = $1;
+
+-- name: AddListMember :exec
+INSERT INTO list_members(list_id, user_id, added_at)
+VALUES (
+  $1,
+  $2,
+  NOW()
+)
+ON CONFLICT (list_id, user_id) DO NOTHING;
+
+-- name: RemoveListMember :execrows
+DELETE FROM list_members
+WHERE list_id = $1
+  AND user_id = $2;
+
+-- name: CountListMembers :one
+SELECT COUNT(*)
+FROM list_members
+WHERE list_id = $1;
+
+-- name: ListListMembers :many
+SELECT
+  users.id,
+  users.handle,
+  users.verified,
+  list_members.added_at
+FROM list_members
+JOIN users ON users.id = list_members.user_id
+WHERE list_members.list_id = $1
+ORDER BY list_members.added_at ASC;
+
+-- name: ListListTimeline :many
+SELECT
+  chirps.id,
+  chirps.created_at,
+  chirps.updated_at,
+  chirps.body,
+  chirps.user_id,
+  users.verified AS author_verified,
+  chirp_content_warnings.chirp_id IS NOT NULL AS sensitive,
+  COALESCE(chirp_content_warnings.warning, '') AS content_warning
+FROM list_members
+JOIN chirps ON chirps.user_id = list_members.user_id
+JOIN users ON users.id = chirps.user_id
+LEFT JOIN chirp_content_warnings ON chirp_content_warnings.chirp_id = chirps.id
+WHERE list_members.list_id = sqlc.arg(list_id)
+  AND chirps.deleted_at IS NULL
+  AND users.banned_at IS NULL
+  AND (NOT users.shadowbanned OR chirps.user_id = sqlc.arg(viewer_id))
+  AND (
+    chirp_content_warnings.chirp_id IS NULL
+    OR chirps.user_id = sqlc.arg(viewer_id)
+    OR NOT EXISTS (
+      SELECT 1
+      FROM content_preferences
+      WHERE content_preferences.user_id = sqlc.arg(viewer_id)
+        AND content_preferences.sensitive_content = 'hide'
+    )
+  )
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,82 @@
+-- name: GetLocationSharing :one
+SELECT COALESCE(
+  (SELECT share_location FROM location_preferences WHERE user_id = $1),
+  FALSE
+)::boolean AS share_location;
+
+-- name: SetLocationSharing :exec
+INSERT INTO location_preferences(user_id, share_location, updated_at)
+VALUES (
+  $1,
+  $2,
+  NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET share_location = EXCLUDED.share_location,
+    updated_at = NOW();
+
+-- name: CreateChirpLocation :exec
+INSERT INTO chirp_locations(chirp_id, latitude, longitude)
+VALUES (
+  $1,
+  $2,
+  $3
+);
+
+-- name: ListNearbyChirps :many
+-- Authors who stop sharing their location drop out of the results, along
+-- with the chirps they geotagged before.
+SELECT
+  id,
+  created_at,
+  updated_at,
+  body,
+  user_id,
+  author_verified,
+  sensitive,
+  content_warning,
+  latitude,
+  longitude,
+  distance_km
+FROM (
+  SELECT
+    chirps.id,
+    chirps.created_at,
+    chirps.updated_at,
+    chirps.body,
+    chirps.user_id,
+    users.verified AS author_verified,
+    chirp_content_warnings.chirp_id IS NOT NULL AS sensitive,
+    COALESCE(chirp_content_warnings.warning, '') AS content_warning,
+    chirp_locations.latitude,
+    chirp_locations.longitude,
+    (6371 * 2 * ASIN(SQRT(
+      POWER(SIN(RADIANS(chirp_locations.latitude - sqlc.arg(lat)::float8) / 2), 2)
+      + COS(RADIANS(sqlc.arg(lat)::float8)) * COS(RADIANS(chirp_locations.latitude))
+      * POWER(SIN(RADIANS(chirp_locations.longitude - sqlc.arg(lon)::float8) / 2), 2)
+    )))::float8 AS distance_km
+  FROM chirp_locations
+  JOIN chirps ON chirps.id = chirp_locations.chirp_id
+  JOIN users ON users.id = chirps.user_id
+  LEFT JOIN chirp_content_warnings ON chirp_content_warnings.chirp_id = chirps.id
+  JOIN location_preferences ON location_preferences.user_id = chirps.user_id
+  WHERE chirp_locations.latitude BETWEEN sqlc.arg(min_lat)::float8 AND sqlc.arg(max_lat)::float8
+    AND chirp_locations.longitude BETWEEN sqlc.arg(min_lon)::float8 AND sqlc.arg(max_lon)::float8
+    AND location_preferences.share_location
+    AND chirps.deleted_at IS NULL
+    AND users.banned_at IS NULL
+    AND (NOT users.shadowbanned OR chirps.user_id = sqlc.arg(viewer_id))
+    AND (
+      chirp_content_warnings.chirp_id IS NULL
+      OR chirps.user_id = sqlc.arg(viewer_id)
+      OR NOT EXISTS (
+        SELECT 1
+        FROM content_preferences
+        WHERE content_preferences.user_id = sqlc.arg(viewer_id)
+          AND content_preferences.sensitive_content = 'hide'
+      )
+    )
+) AS nearby
+WHERE distance_km <= sqlc.arg(radius_km)::float8
+ORDER BY distance_km, created_at DESC
+LIMIT sqlc.arg(row_limit);
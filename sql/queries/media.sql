@@ -0,0 +1,30 @@
+-- name: AttachMedia :one
+-- Fails with no rows if the media is someone else's or already attached.
+UPDATE media
+SET chirp_id = $1, position = $2, alt_text = $3
+WHERE id = $4 AND user_id = $5 AND chirp_id IS NULL
+RETURNING *;
+
+-- name: CreateMedia :one
+INSERT INTO media(id, created_at, user_id, content_type, size, duration_ms, width, height)
+VALUES ($1, NOW(), $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetMedia :one
+SELECT * FROM media
+WHERE id = $1;
+
+-- name: ListChirpMedia :many
+SELECT * FROM media
+WHERE chirp_id = $1
+ORDER BY position;
+
+-- name: SetMediaVariants :exec
+UPDATE media
+SET variants = $2
+WHERE id = $1;
+
+-- name: ListMediaForChirps :many
+SELECT * FROM media
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_id, position;
@@ -0,0 +1,142 @@
+-- name: CreateAccountMerge :one
+INSERT INTO account_merges(id, created_at, expires_at, requested_by, source_id, target_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetAccountMergeForUpdate :one
+-- Locks the row so two confirmations can't both carry out the merge.
+SELECT *
+FROM account_merges
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ListPendingAccountMerges :many
+SELECT *
+FROM account_merges
+WHERE (source_id = $1 OR target_id = $1)
+  AND completed_at IS NULL
+  AND expires_at > $2
+ORDER BY created_at;
+
+-- name: ConfirmAccountMerge :one
+UPDATE account_merges
+SET source_confirmed_at = $2,
+    target_confirmed_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: CompleteAccountMerge :one
+UPDATE account_merges
+SET completed_at = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: MoveUserChirps :execrows
+UPDATE chirps
+SET user_id = sqlc.arg(target_id)
+WHERE user_id = sqlc.arg(source_id);
+
+-- name: MoveUserMedia :execrows
+UPDATE media
+SET user_id = sqlc.arg(target_id)
+WHERE user_id = sqlc.arg(source_id);
+
+-- name: MoveUserReactions :execrows
+-- Where both accounts reacted to a chirp the target's reaction stays and
+-- the source's is left for DeleteUserReactions.
+UPDATE reactions
+SET user_id = sqlc.arg(target_id)
+WHERE user_id = sqlc.arg(source_id)
+  AND NOT EXISTS (
+    SELECT 1 FROM reactions mine
+    WHERE mine.chirp_id = reactions.chirp_id
+      AND mine.user_id = sqlc.arg(target_id)
+  );
+
+-- name: DeleteUserReactions :exec
+DELETE FROM reactions
+WHERE user_id = $1;
+
+-- name: MoveUserRemoteFollowers :execrows
+-- Followers of both accounts keep their existing follow of the target.
+UPDATE remote_followers
+SET user_id = sqlc.arg(target_id)
+WHERE user_id = sqlc.arg(source_id)
+  AND NOT EXISTS (
+    SELECT 1 FROM remote_followers mine
+    WHERE mine.actor_uri = remote_followers.actor_uri
+      AND mine.user_id = sqlc.arg(target_id)
+  );
+
+-- name: DeleteUserRemoteFollowers :exec
+DELETE FROM remote_followers
+WHERE user_id = $1;
+
+-- name: MoveUserLists :execrows
+UPDATE lists
+SET owner_id = sqlc.arg(target_id),
+    updated_at = NOW()
+WHERE owner_id = sqlc.arg(source_id);
+
+-- name: MoveUserListMemberships :execrows
+-- Lists that already have the target keep that membership.
+UPDATE list_members
+SET user_id = sqlc.arg(target_id)
+WHERE user_id = sqlc.arg(source_id)
+  AND NOT EXISTS (
+    SELECT 1 FROM list_members mine
+    WHERE mine.list_id = list_members.list_id
+      AND mine.user_id = sqlc.arg(target_id)
+  );
+
+-- name: DeleteUserListMemberships :exec
+DELETE FROM list_members
+WHERE user_id = $1;
+
+-- name: TombstoneUser :one
+-- Frees the email address and handle, and bans the account so it can't
+-- sign in again.
+UPDATE users
+SET email = sqlc.arg(email),
+    hashed_password = '',
+    handle = NULL,
+    role = 'user',
+    banned_at = NOW(),
+    moderation_reason = sqlc.arg(moderation_reason),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: MergeUserCommunityRoles :exec
+-- In communities both accounts belong to, the target takes the source's
+-- role where it ranks higher.
+UPDATE community_members
+SET role = theirs.role
+FROM community_members theirs
+WHERE community_members.user_id = sqlc.arg(target_id)
+  AND theirs.user_id = sqlc.arg(source_id)
+  AND theirs.community_id = community_members.community_id
+  AND CASE theirs.role WHEN 'owner' THEN 3 WHEN 'moderator' THEN 2 ELSE 1 END
+    > CASE community_members.role WHEN 'owner' THEN 3 WHEN 'moderator' THEN 2 ELSE 1 END;
+
+-- name: MoveUserCommunityMemberships :execrows
+-- Communities that already have the target keep that membership, with
+-- the role MergeUserCommunityRoles settled.
+UPDATE community_members
+SET user_id = sqlc.arg(target_id)
+WHERE community_members.user_id = sqlc.arg(source_id)
+  AND NOT EXISTS (
+    SELECT 1 FROM community_members mine
+    WHERE mine.community_id = community_members.community_id
+      AND mine.user_id = sqlc.arg(target_id)
+  );
+
+-- name: DeleteUserCommunityMemberships :exec
+DELETE FROM community_members
+WHERE user_id = $1;
+
+-- name: MoveUserCommunities :execrows
+UPDATE communities
+SET created_by = sqlc.arg(target_id),
+    updated_at = NOW()
+WHERE created_by = sqlc.arg(source_id);
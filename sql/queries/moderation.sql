@@ -0,0 +1,46 @@
+-- name: BanUser :one
+UPDATE users
+SET banned_at = NOW(),
+    suspended_until = NULL,
+    moderation_reason = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserVerified :one
+UPDATE users
+SET verified = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SuspendUser :one
+UPDATE users
+SET suspended_until = $2,
+    moderation_reason = $3,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnbanUser :one
+UPDATE users
+SET banned_at = NULL,
+    suspended_until = NULL,
+    moderation_reason = '',
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserShadowbanned :one
+UPDATE users
+SET shadowbanned = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,88 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients(id, name, redirect_uris, scopes, created_at, owner_id)
+VALUES ($1, $2, $3, $4, NOW(), $5)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1;
+
+-- name: ListOAuthClients :many
+SELECT * FROM oauth_clients
+ORDER BY created_at, id;
+
+-- name: ListOAuthClientsByOwner :many
+SELECT * FROM oauth_clients
+WHERE owner_id = $1
+ORDER BY created_at, id;
+
+-- name: CreateOAuthCode :exec
+INSERT INTO oauth_authorization_codes(code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW(), $7);
+
+-- name: ClaimOAuthCode :one
+-- Marks the code used as it is read, so it can only be exchanged once.
+UPDATE oauth_authorization_codes
+SET used_at = NOW()
+WHERE code_hash = $1
+  AND used_at IS NULL
+RETURNING *;
+
+-- name: DeleteExpiredOAuthCodes :exec
+DELETE FROM oauth_authorization_codes
+WHERE expires_at < $1;
+
+-- name: CreateOAuthClientSecret :exec
+INSERT INTO oauth_client_secrets(id, client_id, secret_hash, created_at)
+VALUES ($1, $2, $3, NOW());
+
+-- name: ExpireOAuthClientSecrets :exec
+-- Sets the expiry of the client's secrets that would outlive expires_at.
+UPDATE oauth_client_secrets
+SET expires_at = $2
+WHERE client_id = $1
+  AND (expires_at IS NULL OR expires_at > $2);
+
+-- name: ListActiveOAuthClientSecrets :many
+SELECT * FROM oauth_client_secrets
+WHERE client_id = $1
+  AND (expires_at IS NULL OR expires_at > $2)
+ORDER BY created_at DESC;
+
+-- name: CreateOAuthToken :exec
+INSERT INTO oauth_tokens(id, client_id, user_id, scopes, created_at, expires_at)
+VALUES ($1, $2, $3, $4, NOW(), $5);
+
+-- name: ListOAuthTokens :many
+SELECT * FROM oauth_tokens
+WHERE client_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit)
+OFFSET sqlc.arg(row_offset);
+
+-- name: RecordOAuthClientRequest :one
+-- Counts a request by the app today, returning the count so far and the
+-- app's own quota.
+INSERT INTO oauth_client_usage(client_id, day, requests)
+VALUES ($1, $2, 1)
+ON CONFLICT (client_id, day) DO UPDATE
+SET requests = oauth_client_usage.requests + 1
+RETURNING requests, (SELECT daily_quota FROM oauth_clients WHERE id = $1) AS daily_quota;
+
+-- name: RecordOAuthClientRejection :exec
+-- Moves a request RecordOAuthClientRequest counted to the rejected count.
+UPDATE oauth_client_usage
+SET requests = requests - 1, rejected = rejected + 1
+WHERE client_id = $1 AND day = $2;
+
+-- name: ListOAuthClientUsage :many
+SELECT * FROM oauth_client_usage
+WHERE client_id = $1
+  AND day >= sqlc.arg(since)
+ORDER BY day;
+
+-- name: SetOAuthClientQuota :one
+UPDATE oauth_clients
+SET daily_quota = $2
+WHERE id = $1
+RETURNING *;
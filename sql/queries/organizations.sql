@@ -0,0 +1,113 @@
+-- name: CreateOrganization :one
+INSERT INTO organizations(user_id, name, created_by, created_at)
+VALUES ($1, $2, $3, NOW())
+RETURNING *;
+
+-- name: GetOrganization :one
+SELECT
+  organizations.user_id,
+  organizations.name,
+  organizations.created_at,
+  users.handle
+FROM organizations
+JOIN users ON users.id = organizations.user_id
+WHERE organizations.user_id = $1;
+
+-- name: GetOrganizationRole :one
+SELECT role
+FROM organization_members
+WHERE org_id = $1
+  AND user_id = $2;
+
+-- name: AddOrganizationMember :exec
+INSERT INTO organization_members(org_id, user_id, role, joined_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (org_id, user_id) DO UPDATE SET role = EXCLUDED.role;
+
+-- name: SetOrganizationRole :execrows
+UPDATE organization_members
+SET role = $3
+WHERE org_id = $1
+  AND user_id = $2;
+
+-- name: RemoveOrganizationMember :execrows
+DELETE FROM organization_members
+WHERE org_id = $1
+  AND user_id = $2;
+
+-- name: ListOrganizationOwnersForUpdate :many
+-- Locks the owners, so two owners demoting each other can't leave the
+-- organization with none.
+SELECT user_id
+FROM organization_members
+WHERE org_id = $1
+  AND role = 'owner'
+FOR UPDATE;
+
+-- name: ListOrganizationMembers :many
+SELECT
+  organization_members.user_id,
+  users.handle,
+  organization_members.role,
+  organization_members.joined_at
+FROM organization_members
+JOIN users ON users.id = organization_members.user_id
+WHERE organization_members.org_id = $1
+ORDER BY organization_members.joined_at, organization_members.user_id;
+
+-- name: ListUserOrganizations :many
+SELECT
+  organizations.user_id,
+  organizations.name,
+  users.handle,
+  organization_members.role
+FROM organization_members
+JOIN organizations ON organizations.user_id = organization_members.org_id
+JOIN users ON users.id = organizations.user_id
+WHERE organization_members.user_id = $1
+ORDER BY organizations.name, organizations.user_id;
+
+-- name: CreateOrganizationInvite :one
+INSERT INTO organization_invites(id, org_id, user_id, role, invited_by, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, NOW(), $6)
+ON CONFLICT (org_id, user_id) DO UPDATE
+SET id = EXCLUDED.id,
+    role = EXCLUDED.role,
+    invited_by = EXCLUDED.invited_by,
+    created_at = EXCLUDED.created_at,
+    expires_at = EXCLUDED.expires_at
+RETURNING *;
+
+-- name: ListUserOrganizationInvites :many
+SELECT *
+FROM organization_invites
+WHERE user_id = $1
+  AND expires_at > $2
+ORDER BY created_at;
+
+-- name: ClaimOrganizationInvite :one
+-- Deletes the invite as it is used, so it can only be accepted once.
+DELETE FROM organization_invites
+WHERE id = $1
+  AND user_id = $2
+RETURNING *;
+
+-- name: RecordOrganizationChirp :exec
+INSERT INTO organization_chirps(chirp_id, org_id, posted_by)
+VALUES ($1, $2, $3);
+
+-- name: ListOrganizationChirps :many
+-- The organization's recent chirps with the member who posted each.
+SELECT
+  chirps.id,
+  chirps.created_at,
+  chirps.updated_at,
+  chirps.body,
+  chirps.user_id,
+  organization_chirps.posted_by
+FROM organization_chirps
+JOIN chirps ON chirps.id = organization_chirps.chirp_id
+WHERE organization_chirps.org_id = sqlc.arg(org_id)
+  AND chirps.deleted_at IS NULL
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,55 @@
+-- name: EnqueueOutboxEvent :exec
+INSERT INTO outbox_events(id, created_at, updated_at, kind, payload, next_attempt_at)
+VALUES (
+  $1,
+  NOW(),
+  NOW(),
+  $2,
+  $3,
+  NOW()
+);
+
+-- name: ClaimDueOutboxEvents :many
+SELECT
+  id,
+  created_at,
+  updated_at,
+  kind,
+  payload,
+  status,
+  attempts,
+  next_attempt_at,
+  last_error,
+  sent_at
+FROM outbox_events
+WHERE status = 'pending'
+  AND next_attempt_at <= NOW()
+ORDER BY next_attempt_at ASC
+LIMIT $1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventSent :exec
+UPDATE outbox_events
+SET status = 'sent',
+    attempts = attempts + 1,
+    sent_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox_events
+SET status = sqlc.arg(status),
+    attempts = attempts + 1,
+    last_error = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id);
+
+-- name: RetryOutboxEvent :execrows
+UPDATE outbox_events
+SET status = 'pending',
+    attempts = 0,
+    next_attempt_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+  AND status = 'dead';
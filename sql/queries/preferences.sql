@@ -0,0 +1,31 @@
+-- name: GetUserPreferences :one
+-- Every preference, from whichever table keeps it, with the defaults
+-- filled in.
+SELECT
+  COALESCE(user_preferences.dm_permission, 'everyone')::text AS dm_permission,
+  COALESCE(user_preferences.email_new_follower, TRUE)::boolean AS email_new_follower,
+  COALESCE(user_preferences.discoverable, TRUE)::boolean AS discoverable,
+  COALESCE(digest_preferences.frequency, 'weekly')::text AS digest_frequency,
+  COALESCE(content_preferences.sensitive_content, 'collapse')::text AS sensitive_content,
+  COALESCE(location_preferences.share_location, FALSE)::boolean AS share_location
+FROM users
+LEFT JOIN user_preferences ON user_preferences.user_id = users.id
+LEFT JOIN digest_preferences ON digest_preferences.user_id = users.id
+LEFT JOIN content_preferences ON content_preferences.user_id = users.id
+LEFT JOIN location_preferences ON location_preferences.user_id = users.id
+WHERE users.id = $1;
+
+-- name: SetUserPreferences :exec
+INSERT INTO user_preferences(user_id, dm_permission, email_new_follower, discoverable, updated_at)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET dm_permission = EXCLUDED.dm_permission,
+    email_new_follower = EXCLUDED.email_new_follower,
+    discoverable = EXCLUDED.discoverable,
+    updated_at = NOW();
@@ -0,0 +1,35 @@
+-- name: UpsertReaction :exec
+INSERT INTO reactions(chirp_id, user_id, emoji, created_at)
+VALUES (
+  $1,
+  $2,
+  $3,
+  NOW()
+)
+ON CONFLICT (chirp_id, user_id) DO UPDATE
+SET emoji = EXCLUDED.emoji,
+    created_at = NOW();
+
+-- name: DeleteReaction :execrows
+DELETE FROM reactions
+WHERE chirp_id = $1
+  AND user_id = $2;
+
+-- name: ListChirpReactionCounts :many
+SELECT
+  emoji,
+  COUNT(*) AS reaction_count
+FROM reactions
+WHERE chirp_id = $1
+GROUP BY emoji
+ORDER BY reaction_count DESC, emoji;
+
+-- name: ListReactionCountsForChirps :many
+SELECT
+  chirp_id,
+  emoji,
+  COUNT(*) AS reaction_count
+FROM reactions
+WHERE chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+GROUP BY chirp_id, emoji
+ORDER BY chirp_id, reaction_count DESC, emoji;
@@ -0,0 +1,109 @@
+-- name: DeleteUserSuggestions :exec
+DELETE FROM user_suggestions;
+
+-- name: RefreshUserSuggestions :execrows
+-- Adding someone to one of your lists stands in for following them.
+-- Candidates are the accounts listed by the accounts you list, and the
+-- accounts that used the same hashtags as you since the given time. Each
+-- user keeps only their best candidates.
+WITH follows AS (
+  SELECT DISTINCT
+    lists.user_id AS follower_id,
+    list_members.user_id AS followed_id
+  FROM lists
+  JOIN list_members ON list_members.list_id = lists.id
+  WHERE list_members.user_id <> lists.user_id
+),
+friends_of_friends AS (
+  SELECT
+    mine.follower_id AS user_id,
+    theirs.followed_id AS suggested_user_id,
+    COUNT(DISTINCT mine.followed_id) AS mutual_count
+  FROM follows AS mine
+  JOIN follows AS theirs ON theirs.follower_id = mine.followed_id
+  WHERE theirs.followed_id <> mine.follower_id
+  GROUP BY mine.follower_id, theirs.followed_id
+),
+hashtags AS (
+  SELECT DISTINCT
+    chirps.user_id,
+    LOWER(tag[1]) AS tag
+  FROM chirps
+  CROSS JOIN LATERAL regexp_matches(chirps.body, '#(\w+)', 'g') AS tag
+  WHERE chirps.deleted_at IS NULL
+    AND chirps.created_at > sqlc.arg(since)::timestamp
+),
+shared_hashtags AS (
+  SELECT
+    mine.user_id,
+    theirs.user_id AS suggested_user_id,
+    COUNT(*) AS shared_hashtag_count
+  FROM hashtags AS mine
+  JOIN hashtags AS theirs ON theirs.tag = mine.tag AND theirs.user_id <> mine.user_id
+  GROUP BY mine.user_id, theirs.user_id
+),
+candidates AS (
+  SELECT
+    COALESCE(friends_of_friends.user_id, shared_hashtags.user_id) AS user_id,
+    COALESCE(friends_of_friends.suggested_user_id, shared_hashtags.suggested_user_id) AS suggested_user_id,
+    COALESCE(friends_of_friends.mutual_count, 0) AS mutual_count,
+    COALESCE(shared_hashtags.shared_hashtag_count, 0) AS shared_hashtag_count
+  FROM friends_of_friends
+  FULL JOIN shared_hashtags
+    ON shared_hashtags.user_id = friends_of_friends.user_id
+    AND shared_hashtags.suggested_user_id = friends_of_friends.suggested_user_id
+),
+ranked AS (
+  SELECT
+    candidates.*,
+    ROW_NUMBER() OVER (
+      PARTITION BY candidates.user_id
+      ORDER BY candidates.mutual_count DESC, candidates.shared_hashtag_count DESC, candidates.suggested_user_id
+    ) AS rank
+  FROM candidates
+  JOIN users ON users.id = candidates.suggested_user_id
+  WHERE users.banned_at IS NULL
+    AND NOT users.shadowbanned
+    AND NOT EXISTS (
+      SELECT 1
+      FROM follows
+      WHERE follows.follower_id = candidates.user_id
+        AND follows.followed_id = candidates.suggested_user_id
+    )
+)
+INSERT INTO user_suggestions(user_id, suggested_user_id, mutual_count, shared_hashtag_count, computed_at)
+SELECT
+  user_id,
+  suggested_user_id,
+  mutual_count,
+  shared_hashtag_count,
+  NOW()
+FROM ranked
+WHERE rank <= sqlc.arg(per_user_limit)::int;
+
+-- name: ListUserSuggestions :many
+-- Accounts listed since the last refresh are left out.
+SELECT
+  users.id,
+  users.handle,
+  users.verified,
+  user_suggestions.mutual_count,
+  user_suggestions.shared_hashtag_count
+FROM user_suggestions
+JOIN users ON users.id = user_suggestions.suggested_user_id
+WHERE user_suggestions.user_id = $1
+  AND users.banned_at IS NULL
+  AND NOT users.shadowbanned
+  AND NOT EXISTS (
+    SELECT 1 FROM user_preferences
+    WHERE user_preferences.user_id = users.id AND NOT user_preferences.discoverable
+  )
+  AND NOT EXISTS (
+    SELECT 1
+    FROM lists
+    JOIN list_members ON list_members.list_id = lists.id
+    WHERE lists.user_id = user_suggestions.user_id
+      AND list_members.user_id = user_suggestions.suggested_user_id
+  )
+ORDER BY user_suggestions.mutual_count DESC, user_suggestions.shared_hashtag_count DESC, users.id
+LIMIT $2;
@@ -0,0 +1,87 @@
+-- name: CountExpiredDeletedChirps :one
+SELECT COUNT(*)
+FROM chirps
+WHERE deleted_at < sqlc.arg(before)::timestamp;
+
+-- name: PurgeDeletedChirps :execrows
+DELETE FROM chirps
+WHERE id IN (
+  SELECT id
+  FROM chirps
+  WHERE deleted_at < sqlc.arg(before)::timestamp
+  LIMIT sqlc.arg(row_limit)
+);
+
+-- name: CountExpiredEmails :one
+SELECT COUNT(*)
+FROM emails
+WHERE status IN ('sent', 'dead')
+  AND updated_at < sqlc.arg(before);
+
+-- name: PurgeEmails :execrows
+DELETE FROM emails
+WHERE id IN (
+  SELECT id
+  FROM emails
+  WHERE status IN ('sent', 'dead')
+    AND updated_at < sqlc.arg(before)
+  LIMIT sqlc.arg(row_limit)
+);
+
+-- name: CountExpiredIPActivity :one
+SELECT COUNT(*)
+FROM ip_activity
+WHERE last_seen_at < sqlc.arg(before);
+
+-- name: PurgeIPActivity :execrows
+DELETE FROM ip_activity
+WHERE ip IN (
+  SELECT ip
+  FROM ip_activity
+  WHERE last_seen_at < sqlc.arg(before)
+  LIMIT sqlc.arg(row_limit)
+);
+
+-- name: CountExpiredAnalyticsEvents :one
+SELECT COUNT(*)
+FROM analytics_events
+WHERE occurred_at < sqlc.arg(before);
+
+-- name: PurgeAnalyticsEvents :execrows
+DELETE FROM analytics_events
+WHERE id IN (
+  SELECT id
+  FROM analytics_events
+  WHERE occurred_at < sqlc.arg(before)
+  LIMIT sqlc.arg(row_limit)
+);
+
+-- name: CountExpiredOutboxEvents :one
+SELECT COUNT(*)
+FROM outbox_events
+WHERE status IN ('sent', 'dead')
+  AND updated_at < sqlc.arg(before);
+
+-- name: PurgeOutboxEvents :execrows
+DELETE FROM outbox_events
+WHERE id IN (
+  SELECT id
+  FROM outbox_events
+  WHERE status IN ('sent', 'dead')
+    AND updated_at < sqlc.arg(before)
+  LIMIT sqlc.arg(row_limit)
+);
+
+-- name: CountExpiredRequestCaptures :one
+SELECT COUNT(*)
+FROM request_captures
+WHERE created_at < sqlc.arg(before);
+
+-- name: PurgeRequestCaptures :execrows
+DELETE FROM request_captures
+WHERE id IN (
+  SELECT id
+  FROM request_captures
+  WHERE created_at < sqlc.arg(before)
+  LIMIT sqlc.arg(row_limit)
+);
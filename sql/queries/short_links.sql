@@ -0,0 +1,20 @@
+-- name: RecordShortLinkClick :one
+-- Counts a click on the oldest live chirp whose ID is between low and
+-- high, returning its ID, or no rows if there is none.
+INSERT INTO chirp_link_clicks(chirp_id, referrer, clicks, last_clicked_at)
+SELECT id, sqlc.arg(referrer), 1, NOW()
+FROM chirps
+WHERE id BETWEEN sqlc.arg(low)::uuid AND sqlc.arg(high)::uuid
+  AND deleted_at IS NULL
+ORDER BY created_at
+LIMIT 1
+ON CONFLICT (chirp_id, referrer) DO UPDATE
+SET clicks = chirp_link_clicks.clicks + 1,
+    last_clicked_at = EXCLUDED.last_clicked_at
+RETURNING chirp_id;
+
+-- name: ListChirpLinkClicks :many
+SELECT referrer, clicks
+FROM chirp_link_clicks
+WHERE chirp_id = $1
+ORDER BY clicks DESC, referrer;
@@ -0,0 +1,24 @@
+-- name: CountUsers :one
+SELECT COUNT(*) FROM users;
+
+-- name: CountChirps :one
+SELECT COUNT(*) FROM chirps;
+
+-- name: DailySignups :many
+SELECT
+  date_trunc('day', created_at)::date AS day,
+  COUNT(*) AS signups
+FROM users
+WHERE created_at >= sqlc.arg(since)
+GROUP BY day
+ORDER BY day ASC;
+
+-- name: DailyChirpActivity :many
+SELECT
+  date_trunc('day', created_at)::date AS day,
+  COUNT(*) AS chirps,
+  COUNT(DISTINCT user_id) AS active_users
+FROM chirps
+WHERE created_at >= sqlc.arg(since)
+GROUP BY day
+ORDER BY day ASC;
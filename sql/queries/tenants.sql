@@ -0,0 +1,18 @@
+-- name: ListTenants :many
+SELECT * FROM tenants
+ORDER BY slug;
+
+-- name: GetTenantBySlug :one
+SELECT * FROM tenants
+WHERE slug = $1;
+
+-- name: CreateTenant :one
+INSERT INTO tenants(id, created_at, updated_at, slug, hostname, name, config)
+VALUES ($1, NOW(), NOW(), $2, $3, $4, $5)
+RETURNING *;
+
+-- name: UpdateTenant :one
+UPDATE tenants
+SET hostname = $2, name = $3, config = $4, updated_at = NOW()
+WHERE slug = $1
+RETURNING *;
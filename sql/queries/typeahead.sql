@@ -0,0 +1,46 @@
+-- name: SearchHandlesByPrefix :many
+-- prefix must have LIKE wildcards escaped.
+SELECT
+  id,
+  handle,
+  verified
+FROM users
+WHERE handle LIKE sqlc.arg(prefix)::text || '%'
+  AND banned_at IS NULL
+  AND NOT shadowbanned
+  AND NOT EXISTS (
+    SELECT 1 FROM user_preferences
+    WHERE user_preferences.user_id = users.id AND NOT user_preferences.discoverable
+  )
+ORDER BY verified DESC, handle
+LIMIT sqlc.arg(row_limit);
+
+-- name: SearchHashtagsByPrefix :many
+-- prefix must have LIKE wildcards escaped.
+SELECT
+  tag,
+  chirp_count
+FROM hashtags
+WHERE tag LIKE sqlc.arg(prefix)::text || '%'
+  AND chirp_count > 0
+ORDER BY chirp_count DESC, tag
+LIMIT sqlc.arg(row_limit);
+
+-- name: ReconcileHashtagCounts :execrows
+-- Deleting a chirp doesn't decrement its hashtags, so this recounts every
+-- tag from the chirps that are left.
+WITH actual AS (
+  SELECT
+    LOWER(match[1]) AS tag,
+    COUNT(DISTINCT chirps.id) AS chirp_count
+  FROM chirps
+  CROSS JOIN LATERAL regexp_matches(chirps.body, '#(\w+)', 'g') AS match
+  WHERE chirps.deleted_at IS NULL
+  GROUP BY LOWER(match[1])
+)
+UPDATE hashtags
+SET chirp_count = COALESCE(actual.chirp_count, 0)
+FROM hashtags AS existing
+LEFT JOIN actual ON actual.tag = existing.tag
+WHERE hashtags.tag = existing.tag
+  AND hashtags.chirp_count <> COALESCE(actual.chirp_count, 0);
@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users(id, created_at, updated_at, email, hashed_password)
+INSERT INTO users(id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
   $1,
   NOW(),
   NOW(),
   $2,
-  $3
+  $3,
+  $4
 )
 RETURNING *;
 
@@ -15,7 +16,113 @@ SELECT
   created_at,
   updated_at,
   email,
-  hashed_password
-  
+  hashed_password,
+  role,
+  banned_at,
+  suspended_until,
+  moderation_reason,
+  shadowbanned,
+  verified,
+  handle,
+  is_chirpy_red,
+  tenant_id
 FROM users
 WHERE email = $1;
+
+-- name: GetUserByHandle :one
+SELECT
+  id,
+  created_at,
+  updated_at,
+  email,
+  hashed_password,
+  role,
+  banned_at,
+  suspended_until,
+  moderation_reason,
+  shadowbanned,
+  verified,
+  handle,
+  is_chirpy_red,
+  tenant_id
+FROM users
+WHERE handle = $1;
+
+-- name: GetUserByID :one
+SELECT
+  id,
+  created_at,
+  updated_at,
+  email,
+  hashed_password,
+  role,
+  banned_at,
+  suspended_until,
+  moderation_reason,
+  shadowbanned,
+  verified,
+  handle,
+  is_chirpy_red,
+  tenant_id
+FROM users
+WHERE id = $1;
+
+-- name: ListUsers :many
+SELECT
+  id,
+  created_at,
+  updated_at,
+  email,
+  hashed_password,
+  role,
+  banned_at,
+  suspended_until,
+  moderation_reason,
+  shadowbanned,
+  verified,
+  handle,
+  is_chirpy_red,
+  tenant_id
+FROM users
+ORDER BY created_at ASC;
+
+-- name: GetUsersByIDs :many
+SELECT
+  id,
+  created_at,
+  updated_at,
+  email,
+  hashed_password,
+  role,
+  banned_at,
+  suspended_until,
+  moderation_reason,
+  shadowbanned,
+  verified,
+  handle,
+  is_chirpy_red,
+  tenant_id
+FROM users
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: ListSitemapUsers :many
+SELECT
+  id,
+  handle,
+  updated_at
+FROM users
+WHERE handle IS NOT NULL
+  AND banned_at IS NULL
+  AND NOT shadowbanned
+  AND NOT EXISTS (
+    SELECT 1 FROM user_preferences
+    WHERE user_preferences.user_id = users.id AND NOT user_preferences.discoverable
+  )
+ORDER BY created_at ASC;
+
+-- name: SetUserChirpyRed :one
+UPDATE users
+SET is_chirpy_red = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
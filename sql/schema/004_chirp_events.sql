@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION notify_chirp_created() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify(
+    'chirpy_events',
+    json_build_object('type', 'chirp.created', 'data', row_to_json(NEW))::text
+  );
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_notify_insert
+AFTER INSERT ON chirps
+FOR EACH ROW EXECUTE FUNCTION notify_chirp_created();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS chirps_notify_insert ON chirps;
+DROP FUNCTION IF EXISTS notify_chirp_created();
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    actor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    action TEXT NOT NULL,
+    target_id UUID,
+    details JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_log;
+
+ALTER TABLE users
+DROP COLUMN role;
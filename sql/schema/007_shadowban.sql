@@ -0,0 +1,36 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN shadowbanned BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Shadowbanned authors' chirps must not reach other users in real time.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_chirp_created() RETURNS trigger AS $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM users WHERE id = NEW.user_id AND shadowbanned) THEN
+    RETURN NEW;
+  END IF;
+
+  PERFORM pg_notify(
+    'chirpy_events',
+    json_build_object('type', 'chirp.created', 'data', row_to_json(NEW))::text
+  );
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_chirp_created() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify(
+    'chirpy_events',
+    json_build_object('type', 'chirp.created', 'data', row_to_json(NEW))::text
+  );
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+ALTER TABLE users
+DROP COLUMN shadowbanned;
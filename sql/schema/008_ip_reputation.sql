@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE ip_activity (
+    ip TEXT PRIMARY KEY,
+    signups INTEGER NOT NULL DEFAULT 0,
+    login_failures INTEGER NOT NULL DEFAULT 0,
+    first_seen_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE ip_blocks (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    cidr TEXT NOT NULL UNIQUE,
+    reason TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS ip_blocks;
+DROP TABLE IF EXISTS ip_activity;
@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE INDEX audit_log_actor_id_idx ON audit_log (actor_id, created_at);
+CREATE INDEX audit_log_target_id_idx ON audit_log (target_id, created_at);
+CREATE INDEX audit_log_action_idx ON audit_log (action, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS audit_log_action_idx;
+DROP INDEX IF EXISTS audit_log_target_id_idx;
+DROP INDEX IF EXISTS audit_log_actor_id_idx;
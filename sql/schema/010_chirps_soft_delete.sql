@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX chirps_user_id_idx ON chirps (user_id) WHERE deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_user_id_idx;
+
+ALTER TABLE chirps
+DROP COLUMN deleted_at;
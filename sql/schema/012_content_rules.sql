@@ -0,0 +1,40 @@
+-- +goose Up
+CREATE TABLE content_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('word', 'regex', 'domain')),
+    pattern TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('reject', 'mask', 'flag')),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE content_flags (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    rule_id UUID REFERENCES content_rules(id) ON DELETE SET NULL,
+    pattern TEXT NOT NULL
+);
+
+-- Let every instance know when the rules change so they reload their cache.
+-- +goose StatementBegin
+CREATE FUNCTION notify_content_rules_changed() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify(
+    'chirpy_events',
+    json_build_object('type', 'content_rules.changed', 'data', '{}'::json)::text
+  );
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER content_rules_notify_change
+AFTER INSERT OR UPDATE OR DELETE ON content_rules
+FOR EACH STATEMENT EXECUTE FUNCTION notify_content_rules_changed();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS content_rules_notify_change ON content_rules;
+DROP FUNCTION IF EXISTS notify_content_rules_changed();
+DROP TABLE IF EXISTS content_flags;
+DROP TABLE IF EXISTS content_rules;
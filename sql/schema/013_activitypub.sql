@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE actor_keys (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    public_key_pem TEXT NOT NULL,
+    private_key_pem TEXT NOT NULL
+);
+
+CREATE TABLE remote_followers (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_uri TEXT NOT NULL,
+    inbox_uri TEXT NOT NULL,
+    UNIQUE (user_id, actor_uri)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS remote_followers;
+DROP TABLE IF EXISTS actor_keys;
@@ -0,0 +1,66 @@
+-- +goose Up
+CREATE TABLE import_jobs (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source TEXT NOT NULL,
+    status TEXT NOT NULL,
+    total INTEGER NOT NULL DEFAULT 0,
+    imported INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX import_jobs_user_id_idx ON import_jobs (user_id);
+
+-- imported_tweets remembers which tweets already became chirps so a
+-- re-uploaded archive doesn't duplicate them.
+CREATE TABLE imported_tweets (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tweet_id TEXT NOT NULL,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+    PRIMARY KEY (user_id, tweet_id)
+);
+
+-- Imported chirps are history, not news: transactions that set
+-- chirpy.importing don't announce their inserts.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_chirp_created() RETURNS trigger AS $$
+BEGIN
+  IF current_setting('chirpy.importing', true) = 'on' THEN
+    RETURN NEW;
+  END IF;
+
+  IF EXISTS (SELECT 1 FROM users WHERE id = NEW.user_id AND shadowbanned) THEN
+    RETURN NEW;
+  END IF;
+
+  PERFORM pg_notify(
+    'chirpy_events',
+    json_build_object('type', 'chirp.created', 'data', row_to_json(NEW))::text
+  );
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_chirp_created() RETURNS trigger AS $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM users WHERE id = NEW.user_id AND shadowbanned) THEN
+    RETURN NEW;
+  END IF;
+
+  PERFORM pg_notify(
+    'chirpy_events',
+    json_build_object('type', 'chirp.created', 'data', row_to_json(NEW))::text
+  );
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TABLE IF EXISTS imported_tweets;
+DROP TABLE IF EXISTS import_jobs;
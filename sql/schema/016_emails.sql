@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE emails (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    template TEXT NOT NULL,
+    to_address TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    text_body TEXT NOT NULL,
+    html_body TEXT NOT NULL,
+    -- pending until sent; dead once it has run out of attempts.
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP
+);
+
+CREATE INDEX emails_pending_idx ON emails (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX emails_dead_idx ON emails (updated_at) WHERE status = 'dead';
+
+-- +goose Down
+DROP TABLE IF EXISTS emails;
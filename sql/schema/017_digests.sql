@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE digest_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- daily, weekly or off. Users without a row get the weekly digest.
+    frequency TEXT NOT NULL DEFAULT 'weekly',
+    last_sent_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX remote_followers_created_at_idx ON remote_followers (user_id, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS remote_followers_created_at_idx;
+DROP TABLE IF EXISTS digest_preferences;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE analytics_events (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    -- No foreign key: events outlive the accounts they mention.
+    user_id UUID,
+    properties JSONB NOT NULL DEFAULT '{}',
+    occurred_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX analytics_events_name_occurred_at_idx ON analytics_events (name, occurred_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS analytics_events;
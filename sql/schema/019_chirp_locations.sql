@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE location_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    share_location BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE chirp_locations (
+    chirp_id UUID PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+    latitude DOUBLE PRECISION NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION NOT NULL CHECK (longitude BETWEEN -180 AND 180)
+);
+
+-- Nearby searches narrow to a bounding box on this index before computing
+-- great-circle distances.
+CREATE INDEX chirp_locations_latitude_longitude_idx ON chirp_locations (latitude, longitude);
+
+-- +goose Down
+DROP TABLE IF EXISTS chirp_locations;
+DROP TABLE IF EXISTS location_preferences;
@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE communities (
+    id UUID PRIMARY KEY,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE community_members (
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- member, moderator or owner.
+    role TEXT NOT NULL DEFAULT 'member',
+    joined_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (community_id, user_id)
+);
+
+-- A chirp belongs to at most one community. Removing it from the community
+-- leaves the chirp itself alone.
+CREATE TABLE community_chirps (
+    chirp_id UUID PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+    community_id UUID NOT NULL REFERENCES communities(id) ON DELETE CASCADE
+);
+
+CREATE INDEX community_chirps_community_id_idx ON community_chirps (community_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS community_chirps;
+DROP TABLE IF EXISTS community_members;
+DROP TABLE IF EXISTS communities;
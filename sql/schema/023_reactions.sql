@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE reactions (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    -- One reaction per user per chirp; reacting again replaces it.
+    PRIMARY KEY (chirp_id, user_id)
+);
+
+-- +goose StatementBegin
+CREATE FUNCTION notify_chirp_reacted() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify(
+    'chirpy_events',
+    json_build_object('type', 'chirp.reacted', 'data', row_to_json(NEW))::text
+  );
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER reactions_notify_upsert
+AFTER INSERT OR UPDATE ON reactions
+FOR EACH ROW EXECUTE FUNCTION notify_chirp_reacted();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS reactions_notify_upsert ON reactions;
+DROP FUNCTION IF EXISTS notify_chirp_reacted();
+DROP TABLE IF EXISTS reactions;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- A row marks the chirp as sensitive. The warning is optional text shown in
+-- place of the body until the reader expands it.
+CREATE TABLE chirp_content_warnings (
+    chirp_id UUID PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+    warning TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE content_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- expand, collapse or hide.
+    sensitive_content TEXT NOT NULL DEFAULT 'collapse'
+        CHECK (sensitive_content IN ('expand', 'collapse', 'hide')),
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS content_preferences;
+DROP TABLE IF EXISTS chirp_content_warnings;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Who-to-follow suggestions, rebuilt periodically by the server.
+CREATE TABLE user_suggestions (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    suggested_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- How many of the accounts user_id lists also list the suggestion.
+    mutual_count INTEGER NOT NULL,
+    -- How many hashtags both used recently.
+    shared_hashtag_count INTEGER NOT NULL,
+    computed_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, suggested_user_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_suggestions;
@@ -0,0 +1,47 @@
+-- +goose Up
+-- Handles are stored lowercase; text_pattern_ops lets LIKE 'prefix%' use
+-- the index whatever the database collation.
+CREATE INDEX users_handle_prefix_idx ON users (handle text_pattern_ops);
+
+-- Every hashtag used in a chirp, lowercased, for typeahead.
+CREATE TABLE hashtags (
+    tag TEXT PRIMARY KEY,
+    chirp_count INTEGER NOT NULL DEFAULT 0,
+    last_used_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX hashtags_tag_prefix_idx ON hashtags (tag text_pattern_ops);
+
+INSERT INTO hashtags(tag, chirp_count, last_used_at)
+SELECT tag, COUNT(*), MAX(created_at)
+FROM (
+  SELECT DISTINCT chirps.id, chirps.created_at, LOWER(match[1]) AS tag
+  FROM chirps
+  CROSS JOIN LATERAL regexp_matches(chirps.body, '#(\w+)', 'g') AS match
+  WHERE chirps.deleted_at IS NULL
+) AS used
+GROUP BY tag;
+
+-- +goose StatementBegin
+CREATE FUNCTION count_chirp_hashtags() RETURNS trigger AS $$
+BEGIN
+  INSERT INTO hashtags(tag, chirp_count, last_used_at)
+  SELECT DISTINCT LOWER(match[1]), 1, NEW.created_at
+  FROM regexp_matches(NEW.body, '#(\w+)', 'g') AS match
+  ON CONFLICT (tag) DO UPDATE
+  SET chirp_count = hashtags.chirp_count + 1,
+      last_used_at = GREATEST(hashtags.last_used_at, EXCLUDED.last_used_at);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_count_hashtags
+AFTER INSERT ON chirps
+FOR EACH ROW EXECUTE FUNCTION count_chirp_hashtags();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS chirps_count_hashtags ON chirps;
+DROP FUNCTION IF EXISTS count_chirp_hashtags();
+DROP TABLE IF EXISTS hashtags;
+DROP INDEX IF EXISTS users_handle_prefix_idx;
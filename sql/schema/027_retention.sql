@@ -0,0 +1,12 @@
+-- +goose Up
+-- The cleanup job finds expired rows through these.
+CREATE INDEX chirps_deleted_at_idx ON chirps (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX emails_sent_idx ON emails (updated_at) WHERE status = 'sent';
+CREATE INDEX ip_activity_last_seen_at_idx ON ip_activity (last_seen_at);
+CREATE INDEX analytics_events_occurred_at_idx ON analytics_events (occurred_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS analytics_events_occurred_at_idx;
+DROP INDEX IF EXISTS ip_activity_last_seen_at_idx;
+DROP INDEX IF EXISTS emails_sent_idx;
+DROP INDEX IF EXISTS chirps_deleted_at_idx;
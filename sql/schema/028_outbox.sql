@@ -0,0 +1,25 @@
+-- +goose Up
+-- outbox_events holds the side effects of a write, such as federation
+-- deliveries, inserted in the same transaction as the write. The relay
+-- publishes them afterwards, so a crash can neither lose an event nor
+-- publish one for a write that rolled back.
+CREATE TABLE outbox_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    kind TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    -- pending until published; dead once it has run out of attempts.
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP
+);
+
+CREATE INDEX outbox_events_pending_idx ON outbox_events (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX outbox_events_dead_idx ON outbox_events (updated_at) WHERE status = 'dead';
+CREATE INDEX outbox_events_sent_idx ON outbox_events (updated_at) WHERE status = 'sent';
+
+-- +goose Down
+DROP TABLE IF EXISTS outbox_events;
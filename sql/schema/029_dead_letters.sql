@@ -0,0 +1,32 @@
+-- +goose Up
+-- dead_letters collects work that gave up: emails and outbox events that
+-- ran out of attempts, and failing jobs. An entry is open until an
+-- operator retries it.
+CREATE TABLE dead_letters (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    -- email, outbox_event or job.
+    source TEXT NOT NULL,
+    -- The email or outbox event ID, or the job name.
+    source_id TEXT NOT NULL,
+    summary TEXT NOT NULL,
+    attempts INTEGER NOT NULL,
+    last_error TEXT NOT NULL,
+    retried_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX dead_letters_open_idx ON dead_letters (source, source_id) WHERE retried_at IS NULL;
+
+INSERT INTO dead_letters(id, created_at, updated_at, source, source_id, summary, attempts, last_error)
+SELECT id, updated_at, updated_at, 'email', id::text, template || ' email to ' || to_address, attempts, last_error
+FROM emails
+WHERE status = 'dead';
+
+INSERT INTO dead_letters(id, created_at, updated_at, source, source_id, summary, attempts, last_error)
+SELECT id, updated_at, updated_at, 'outbox_event', id::text, kind, attempts, last_error
+FROM outbox_events
+WHERE status = 'dead';
+
+-- +goose Down
+DROP TABLE IF EXISTS dead_letters;
@@ -0,0 +1,30 @@
+-- +goose Up
+-- shared_counters holds counts every server adds to, such as page hits.
+CREATE TABLE shared_counters (
+    name TEXT PRIMARY KEY,
+    value BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Let every instance know when the IP blocks change so they reload their
+-- blocklist.
+-- +goose StatementBegin
+CREATE FUNCTION notify_ip_blocks_changed() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify(
+    'chirpy_events',
+    json_build_object('type', 'ip_blocks.changed', 'data', '{}'::json)::text
+  );
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER ip_blocks_notify_change
+AFTER INSERT OR UPDATE OR DELETE ON ip_blocks
+FOR EACH STATEMENT EXECUTE FUNCTION notify_ip_blocks_changed();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS ip_blocks_notify_change ON ip_blocks;
+DROP FUNCTION IF EXISTS notify_ip_blocks_changed();
+DROP TABLE IF EXISTS shared_counters;
@@ -0,0 +1,61 @@
+-- +goose Up
+-- capture_rules switch on request capture for a route, a user or both,
+-- until the rule expires or has captured max_captures requests.
+CREATE TABLE capture_rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    -- A route pattern such as 'GET /api/chirps/{chirpID}'.
+    route TEXT,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    max_captures INTEGER NOT NULL,
+    CHECK (route IS NOT NULL OR user_id IS NOT NULL)
+);
+
+-- request_captures hold sanitized request/response pairs: secret headers
+-- and body fields are redacted and bodies are cut short.
+CREATE TABLE request_captures (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    rule_id UUID NOT NULL REFERENCES capture_rules(id) ON DELETE CASCADE,
+    user_id UUID,
+    method TEXT NOT NULL,
+    -- The path and query string.
+    url TEXT NOT NULL,
+    route TEXT NOT NULL,
+    request_headers JSONB NOT NULL,
+    request_body BYTEA NOT NULL,
+    request_body_truncated BOOLEAN NOT NULL,
+    status INTEGER NOT NULL,
+    response_headers JSONB NOT NULL,
+    response_body BYTEA NOT NULL,
+    response_body_truncated BOOLEAN NOT NULL,
+    duration_ms INTEGER NOT NULL
+);
+
+CREATE INDEX request_captures_rule_idx ON request_captures (rule_id, created_at);
+CREATE INDEX request_captures_created_at_idx ON request_captures (created_at);
+
+-- Let every instance know when the capture rules change.
+-- +goose StatementBegin
+CREATE FUNCTION notify_capture_rules_changed() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify(
+    'chirpy_events',
+    json_build_object('type', 'capture_rules.changed', 'data', '{}'::json)::text
+  );
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER capture_rules_notify_change
+AFTER INSERT OR UPDATE OR DELETE ON capture_rules
+FOR EACH STATEMENT EXECUTE FUNCTION notify_capture_rules_changed();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS capture_rules_notify_change ON capture_rules;
+DROP FUNCTION IF EXISTS notify_capture_rules_changed();
+DROP TABLE IF EXISTS request_captures;
+DROP TABLE IF EXISTS capture_rules;
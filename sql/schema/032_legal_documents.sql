@@ -0,0 +1,46 @@
+-- +goose Up
+-- legal_documents hold every published version of the terms of service
+-- and the privacy policy. The highest version of each kind is in force.
+CREATE TABLE legal_documents (
+    kind TEXT NOT NULL CHECK (kind IN ('terms', 'privacy')),
+    version INTEGER NOT NULL,
+    body TEXT NOT NULL,
+    published_at TIMESTAMP NOT NULL,
+    published_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    PRIMARY KEY (kind, version)
+);
+
+-- legal_acceptances record each version a user accepted, when, and from
+-- which IP.
+CREATE TABLE legal_acceptances (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    accepted_at TIMESTAMP NOT NULL,
+    ip TEXT,
+    PRIMARY KEY (user_id, kind, version),
+    FOREIGN KEY (kind, version) REFERENCES legal_documents(kind, version)
+);
+
+-- Let every instance know when a new version is published.
+-- +goose StatementBegin
+CREATE FUNCTION notify_legal_documents_changed() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify(
+    'chirpy_events',
+    json_build_object('type', 'legal_documents.changed', 'data', '{}'::json)::text
+  );
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER legal_documents_notify_change
+AFTER INSERT OR UPDATE OR DELETE ON legal_documents
+FOR EACH STATEMENT EXECUTE FUNCTION notify_legal_documents_changed();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS legal_documents_notify_change ON legal_documents;
+DROP FUNCTION IF EXISTS notify_legal_documents_changed();
+DROP TABLE IF EXISTS legal_acceptances;
+DROP TABLE IF EXISTS legal_documents;
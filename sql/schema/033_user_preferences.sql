@@ -0,0 +1,17 @@
+-- +goose Up
+-- Privacy and notification settings without a table of their own. Users
+-- without a row get the defaults.
+CREATE TABLE user_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- Who may send the user direct messages: everyone, followers or none.
+    dm_permission TEXT NOT NULL DEFAULT 'everyone'
+        CHECK (dm_permission IN ('everyone', 'followers', 'none')),
+    email_new_follower BOOLEAN NOT NULL DEFAULT TRUE,
+    -- Undiscoverable users are left out of search, suggestions and
+    -- sitemaps. Their profiles and chirps are still public.
+    discoverable BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_preferences;
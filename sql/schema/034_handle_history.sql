@@ -0,0 +1,15 @@
+-- +goose Up
+-- handle_history keeps the handles users have changed away from. Until a
+-- row expires, the old handle leads to its user's profile and nobody else
+-- can take it.
+CREATE TABLE handle_history (
+    handle TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    changed_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX handle_history_user_id_idx ON handle_history (user_id, changed_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS handle_history;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- chirp_link_clicks counts visits to each chirp's short link by the host
+-- that referred them; '' counts visits without a referrer.
+CREATE TABLE chirp_link_clicks (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    referrer TEXT NOT NULL,
+    clicks BIGINT NOT NULL DEFAULT 0,
+    last_clicked_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, referrer)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS chirp_link_clicks;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- link_scans is what the link scanner found for each link posted in a
+-- chirp. threat is '' for a safe link, or the kind of threat; checked_at
+-- is NULL until the link has been scanned.
+CREATE TABLE link_scans (
+    url TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    checked_at TIMESTAMP,
+    threat TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE chirp_links (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    PRIMARY KEY (chirp_id, url)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS chirp_links;
+DROP TABLE IF EXISTS link_scans;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- media is uploaded images and videos. The files live in MEDIA_DIR, named
+-- by id.
+CREATE TABLE media (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    -- duration_ms is how long a video plays; NULL for images.
+    duration_ms INTEGER
+);
+
+CREATE INDEX media_user_id_idx ON media (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS media;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Media is attached to a chirp once, in position order, with alt text
+-- describing it for readers who can't see it.
+ALTER TABLE media
+    ADD COLUMN chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+    ADD COLUMN position INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN alt_text TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX media_chirp_id_idx ON media (chirp_id, position);
+
+-- +goose Down
+DROP INDEX IF EXISTS media_chirp_id_idx;
+ALTER TABLE media
+    DROP COLUMN IF EXISTS alt_text,
+    DROP COLUMN IF EXISTS position,
+    DROP COLUMN IF EXISTS chirp_id;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Images record their size, and the smaller copies made of them once the
+-- outbox relay has scaled them: a JSON array of name, width, height and
+-- content_type.
+ALTER TABLE media
+    ADD COLUMN width INTEGER,
+    ADD COLUMN height INTEGER,
+    ADD COLUMN variants JSONB NOT NULL DEFAULT '[]';
+
+-- +goose Down
+ALTER TABLE media
+    DROP COLUMN IF EXISTS variants,
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS width;
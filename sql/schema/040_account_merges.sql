@@ -0,0 +1,22 @@
+-- +goose Up
+-- An admin proposes folding source into target; it happens once the
+-- owners of both accounts have confirmed. Completed rows are kept as the
+-- record of where the source account went.
+CREATE TABLE account_merges (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    source_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source_confirmed_at TIMESTAMP,
+    target_confirmed_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    CHECK (source_id <> target_id)
+);
+
+CREATE INDEX account_merges_source_id_idx ON account_merges (source_id);
+CREATE INDEX account_merges_target_id_idx ON account_merges (target_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS account_merges;
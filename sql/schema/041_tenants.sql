@@ -0,0 +1,157 @@
+-- +goose Up
+-- tenants are the communities hosted alongside the default one, which has
+-- no row. config holds a tenant's overrides of the deployment's settings.
+CREATE TABLE tenants (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    slug TEXT NOT NULL UNIQUE CHECK (slug ~ '^[a-z0-9][a-z0-9-]*$'),
+    hostname TEXT UNIQUE,
+    name TEXT NOT NULL,
+    config JSONB NOT NULL DEFAULT '{}'
+);
+
+-- Let every instance know when a tenant is added or reconfigured.
+-- +goose StatementBegin
+CREATE FUNCTION notify_tenants_changed() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify(
+    'chirpy_events',
+    json_build_object('type', 'tenants.changed', 'data', '{}'::json)::text
+  );
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER tenants_notify_change
+AFTER INSERT OR UPDATE OR DELETE ON tenants
+FOR EACH STATEMENT EXECUTE FUNCTION notify_tenants_changed();
+
+-- A connection opened for a tenant sets chirpy.tenant_id to its ID, or to
+-- 'default' for the default community. Connections that don't set it, like
+-- the migrations and background jobs, see every tenant.
+-- +goose StatementBegin
+CREATE FUNCTION chirpy_tenant_scoped() RETURNS boolean AS $$
+  SELECT COALESCE(current_setting('chirpy.tenant_id', true), '') <> '';
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION chirpy_tenant_id() RETURNS uuid AS $$
+  SELECT NULLIF(NULLIF(current_setting('chirpy.tenant_id', true), ''), 'default')::uuid;
+$$ LANGUAGE sql STABLE;
+-- +goose StatementEnd
+
+-- Rows written without a tenant, by the background jobs, belong to the
+-- tenant of the user they hang off, named by the column in TG_ARGV[0].
+-- +goose StatementBegin
+CREATE FUNCTION chirpy_inherit_tenant() RETURNS trigger AS $$
+DECLARE
+  owner_id uuid;
+BEGIN
+  IF NEW.tenant_id IS NULL AND NOT chirpy_tenant_scoped() THEN
+    owner_id := (to_jsonb(NEW) ->> TG_ARGV[0])::uuid;
+    IF owner_id IS NOT NULL THEN
+      NEW.tenant_id := (SELECT tenant_id FROM users WHERE id = owner_id);
+    END IF;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Every table holding a community's own content gets a tenant_id, and row
+-- level security keeps a scoped connection to its tenant's rows. FORCE
+-- applies the policies to the tables' owner too; roles with BYPASSRLS and
+-- superusers still skip them, which the server refuses to run as. Tables
+-- hanging off these, like reactions and list members, are reached through
+-- them.
+-- +goose StatementBegin
+DO $$
+DECLARE
+  t text;
+BEGIN
+  FOREACH t IN ARRAY ARRAY['users', 'chirps', 'lists', 'communities', 'media', 'audit_log'] LOOP
+    EXECUTE format('ALTER TABLE %I ADD COLUMN tenant_id UUID DEFAULT chirpy_tenant_id() REFERENCES tenants(id) ON DELETE CASCADE', t);
+    EXECUTE format('CREATE INDEX %I ON %I (tenant_id)', t || '_tenant_id_idx', t);
+    EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', t);
+    EXECUTE format('ALTER TABLE %I FORCE ROW LEVEL SECURITY', t);
+    EXECUTE format(
+      'CREATE POLICY tenant_isolation ON %I USING (NOT chirpy_tenant_scoped() OR tenant_id IS NOT DISTINCT FROM chirpy_tenant_id()) WITH CHECK (NOT chirpy_tenant_scoped() OR tenant_id IS NOT DISTINCT FROM chirpy_tenant_id())',
+      t
+    );
+  END LOOP;
+END;
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_inherit_tenant BEFORE INSERT ON chirps
+FOR EACH ROW EXECUTE FUNCTION chirpy_inherit_tenant('user_id');
+CREATE TRIGGER media_inherit_tenant BEFORE INSERT ON media
+FOR EACH ROW EXECUTE FUNCTION chirpy_inherit_tenant('user_id');
+CREATE TRIGGER lists_inherit_tenant BEFORE INSERT ON lists
+FOR EACH ROW EXECUTE FUNCTION chirpy_inherit_tenant('owner_id');
+CREATE TRIGGER communities_inherit_tenant BEFORE INSERT ON communities
+FOR EACH ROW EXECUTE FUNCTION chirpy_inherit_tenant('created_by');
+CREATE TRIGGER audit_log_inherit_tenant BEFORE INSERT ON audit_log
+FOR EACH ROW EXECUTE FUNCTION chirpy_inherit_tenant('actor_id');
+
+-- Reactions have no tenant of their own, so the event carries the chirp's
+-- for the streams to filter on. chirp.created events carry the chirp row,
+-- which now includes it.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_chirp_reacted() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify(
+    'chirpy_events',
+    json_build_object(
+      'type', 'chirp.reacted',
+      'data', to_jsonb(NEW) || jsonb_build_object('tenant_id', (SELECT tenant_id FROM chirps WHERE id = NEW.chirp_id))
+    )::text
+  );
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_chirp_reacted() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify(
+    'chirpy_events',
+    json_build_object('type', 'chirp.reacted', 'data', row_to_json(NEW))::text
+  );
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS chirps_inherit_tenant ON chirps;
+DROP TRIGGER IF EXISTS media_inherit_tenant ON media;
+DROP TRIGGER IF EXISTS lists_inherit_tenant ON lists;
+DROP TRIGGER IF EXISTS communities_inherit_tenant ON communities;
+DROP TRIGGER IF EXISTS audit_log_inherit_tenant ON audit_log;
+
+-- +goose StatementBegin
+DO $$
+DECLARE
+  t text;
+BEGIN
+  FOREACH t IN ARRAY ARRAY['users', 'chirps', 'lists', 'communities', 'media', 'audit_log'] LOOP
+    EXECUTE format('DROP POLICY IF EXISTS tenant_isolation ON %I', t);
+    EXECUTE format('ALTER TABLE %I NO FORCE ROW LEVEL SECURITY', t);
+    EXECUTE format('ALTER TABLE %I DISABLE ROW LEVEL SECURITY', t);
+    EXECUTE format('ALTER TABLE %I DROP COLUMN IF EXISTS tenant_id', t);
+  END LOOP;
+END;
+$$;
+-- +goose StatementEnd
+
+DROP FUNCTION IF EXISTS chirpy_inherit_tenant();
+DROP FUNCTION IF EXISTS chirpy_tenant_id();
+DROP FUNCTION IF EXISTS chirpy_tenant_scoped();
+DROP TRIGGER IF EXISTS tenants_notify_change ON tenants;
+DROP FUNCTION IF EXISTS notify_tenants_changed();
+DROP TABLE IF EXISTS tenants;
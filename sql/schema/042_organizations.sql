@@ -0,0 +1,51 @@
+-- +goose Up
+-- An organization is a user account nobody signs in to: it has the shared
+-- handle and profile, and its members post as it. Its row here is what
+-- makes the account an organization.
+CREATE TABLE organizations (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE organization_members (
+    org_id UUID NOT NULL REFERENCES organizations(user_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Owners manage the members; posters can only post.
+    role TEXT NOT NULL CHECK (role IN ('owner', 'poster')),
+    joined_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX organization_members_user_id_idx ON organization_members (user_id);
+
+-- Inviting someone again replaces their pending invite.
+CREATE TABLE organization_invites (
+    id UUID PRIMARY KEY,
+    org_id UUID NOT NULL REFERENCES organizations(user_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'poster')),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    UNIQUE (org_id, user_id)
+);
+
+CREATE INDEX organization_invites_user_id_idx ON organization_invites (user_id);
+
+-- The member behind each chirp an organization posted. Chirps show the
+-- organization as their author; only its members see who wrote them.
+CREATE TABLE organization_chirps (
+    chirp_id UUID PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+    org_id UUID NOT NULL REFERENCES organizations(user_id) ON DELETE CASCADE,
+    posted_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX organization_chirps_org_id_idx ON organization_chirps (org_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS organization_chirps;
+DROP TABLE IF EXISTS organization_invites;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
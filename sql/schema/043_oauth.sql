@@ -0,0 +1,34 @@
+-- +goose Up
+-- Third-party apps that may ask users for access tokens through the
+-- authorization code flow. They are public clients, proving themselves
+-- with PKCE rather than a secret.
+CREATE TABLE oauth_clients (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    -- Where codes may be sent; a request's redirect_uri must match one
+    -- exactly.
+    redirect_uris TEXT[] NOT NULL,
+    -- The most the app may ask for.
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Codes are kept as SHA-256 hashes and can be exchanged once.
+CREATE TABLE oauth_authorization_codes (
+    code_hash TEXT PRIMARY KEY,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    -- The S256 PKCE challenge the token request's verifier must match.
+    code_challenge TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX oauth_authorization_codes_expires_at_idx ON oauth_authorization_codes (expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP TABLE IF EXISTS oauth_clients;
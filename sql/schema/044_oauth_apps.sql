@@ -0,0 +1,37 @@
+-- +goose Up
+-- Apps registered by developers through /api/apps rather than by an
+-- admin. They are confidential clients with secrets.
+ALTER TABLE oauth_clients ADD COLUMN owner_id UUID REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX oauth_clients_owner_id_idx ON oauth_clients (owner_id);
+
+-- Secrets are kept as SHA-256 hashes. Rotating one gives the old secret
+-- an expiry, so the app can be redeployed with the new one.
+CREATE TABLE oauth_client_secrets (
+    id UUID PRIMARY KEY,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    secret_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    -- NULL until the secret is rotated out.
+    expires_at TIMESTAMP
+);
+
+CREATE INDEX oauth_client_secrets_client_id_idx ON oauth_client_secrets (client_id);
+
+-- The access tokens issued to apps, so developers can see who uses them.
+CREATE TABLE oauth_tokens (
+    id UUID PRIMARY KEY,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX oauth_tokens_client_id_created_at_idx ON oauth_tokens (client_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS oauth_tokens;
+DROP TABLE IF EXISTS oauth_client_secrets;
+DROP INDEX IF EXISTS oauth_clients_owner_id_idx;
+ALTER TABLE oauth_clients DROP COLUMN IF EXISTS owner_id;
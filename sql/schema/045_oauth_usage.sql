@@ -0,0 +1,17 @@
+-- +goose Up
+-- An app's own daily request quota, overriding APP_DAILY_QUOTA.
+ALTER TABLE oauth_clients ADD COLUMN daily_quota INTEGER;
+
+-- Requests made with each app's tokens, per UTC day. Requests turned away
+-- for being over quota are counted as rejected instead.
+CREATE TABLE oauth_client_usage (
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    rejected BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (client_id, day)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS oauth_client_usage;
+ALTER TABLE oauth_clients DROP COLUMN IF EXISTS daily_quota;
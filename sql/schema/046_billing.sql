@@ -0,0 +1,41 @@
+-- +goose Up
+-- The Stripe customer paying for each user's Chirpy Red.
+CREATE TABLE billing_customers (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    stripe_customer_id TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Chirpy Red subscriptions as Stripe last described them.
+CREATE TABLE billing_subscriptions (
+    -- Stripe's subscription ID.
+    id TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    stripe_customer_id TEXT NOT NULL,
+    status TEXT NOT NULL,
+    price_id TEXT NOT NULL,
+    cancel_at_period_end BOOLEAN NOT NULL DEFAULT FALSE,
+    current_period_end TIMESTAMP,
+    trial_end TIMESTAMP,
+    canceled_at TIMESTAMP,
+    -- When Stripe created the subscription.
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    -- When the last event applied was sent. Stripe doesn't deliver in
+    -- order, so older events arriving late are ignored.
+    event_created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX billing_subscriptions_user_id_idx ON billing_subscriptions (user_id);
+
+-- Webhook events already handled. Stripe delivers at least once.
+CREATE TABLE stripe_events (
+    id TEXT PRIMARY KEY,
+    type TEXT NOT NULL,
+    received_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS stripe_events;
+DROP TABLE IF EXISTS billing_subscriptions;
+DROP TABLE IF EXISTS billing_customers;
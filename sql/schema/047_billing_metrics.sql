@@ -0,0 +1,19 @@
+-- +goose Up
+-- Subscriptions record when they actually ended, which for one cancelled
+-- at period end is after canceled_at, and what they bring in a month in
+-- the smallest unit of the price's currency, for the revenue metrics.
+ALTER TABLE billing_subscriptions
+    ADD COLUMN ended_at TIMESTAMP,
+    ADD COLUMN monthly_amount BIGINT NOT NULL DEFAULT 0;
+
+UPDATE billing_subscriptions
+SET ended_at = COALESCE(canceled_at, updated_at)
+WHERE status = 'canceled';
+
+CREATE INDEX billing_subscriptions_created_at_idx ON billing_subscriptions (created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS billing_subscriptions_created_at_idx;
+ALTER TABLE billing_subscriptions
+    DROP COLUMN IF EXISTS monthly_amount,
+    DROP COLUMN IF EXISTS ended_at;